@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/agent"
+)
+
+// exitBudget is the process exit code when a run stops on a budget limit.
+const exitBudget = 2
+
+// modelBudgetFlag collects repeatable -model-budget model=USD values.
+type modelBudgetFlag map[string]float64
+
+func (f modelBudgetFlag) String() string {
+	models := make([]string, 0, len(f))
+	for model := range f {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	parts := make([]string, len(models))
+	for i, model := range models {
+		parts[i] = fmt.Sprintf("%s=%g", model, f[model])
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f modelBudgetFlag) Set(value string) error {
+	model, usd, ok := strings.Cut(value, "=")
+	model = strings.TrimSpace(model)
+	if !ok || model == "" {
+		return fmt.Errorf("want model=USD, got %q", value)
+	}
+	limit, err := strconv.ParseFloat(strings.TrimSpace(usd), 64)
+	if err != nil || limit <= 0 {
+		return fmt.Errorf("budget for %s must be a positive number of USD, got %q", model, usd)
+	}
+	f[model] = limit
+	return nil
+}
+
+// budgetExitMessage describes why the run hit its budget, or returns "" if
+// it ended for another reason.
+func budgetExitMessage(query *agent.Query, config *agent.AgentConfig) string {
+	if query.GetExitReason() != agent.ExitMaxBudget {
+		return ""
+	}
+	spent := query.TotalCostUSD()
+	if model := query.State().ModelBudgetExceeded; model != "" {
+		return fmt.Sprintf("model %s exceeded its budget of $%.4f (session total $%.4f)",
+			model, config.ModelBudgets[model], spent)
+	}
+	return fmt.Sprintf("max budget exceeded: $%.4f of $%.4f", spent, config.MaxBudgetUSD)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestModelBudgetFlag(t *testing.T) {
+	budgets := modelBudgetFlag{}
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(budgets, "model-budget", "")
+
+	err := fs.Parse([]string{"-model-budget", "claude-opus-4-5=2.5", "-model-budget", " gpt-4o = 0.75 "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 2 || budgets["claude-opus-4-5"] != 2.5 || budgets["gpt-4o"] != 0.75 {
+		t.Errorf("budgets = %v", budgets)
+	}
+	if got, want := budgets.String(), "claude-opus-4-5=2.5,gpt-4o=0.75"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestModelBudgetFlag_Invalid(t *testing.T) {
+	for _, value := range []string{"gpt-4o", "=1", "gpt-4o=abc", "gpt-4o=0", "gpt-4o=-1"} {
+		if err := (modelBudgetFlag{}).Set(value); err == nil {
+			t.Errorf("Set(%q) should fail", value)
+		}
+	}
+}
@@ -14,9 +14,28 @@
 //	-prompt      Prompt text (if empty, reads all of stdin)
 //	-cwd         Working directory for tools (default: current directory)
 //	-max-turns   Maximum agentic loop turns (default: 100)
+//	-max-budget-usd       Stop once the run has cost this many USD (default: unlimited)
+//	-max-thinking-tokens  Thinking token limit per LLM call (default: model default)
+//	-model-budget         Per-model USD budget as model=USD (repeatable)
 //	-skills-dir  Directory containing skill subdirs with SKILL.md files (optional)
 //	-mcp-config  Path to JSON file with MCP server configurations (optional)
 //	-multi-turn  Enable multi-turn REPL mode (read follow-up prompts from stdin)
+//	-allow-tools Comma-separated built-in tools to register (default: the 6 core tools)
+//	-deny-tools  Comma-separated tools to withhold, including MCP and skill tools
+//	             ("mcp__server" covers a whole server; globs allowed)
+//	-extra-tools Comma-separated optional tools to add: WebFetch, WebSearch,
+//	             TodoWrite, Agent
+//	-output      Output format: "text" (default) prints the final assistant text;
+//	             "json" prints one JSON object with the result, exit reason, turn
+//	             count, cost, token usage and tool calls (NDJSON, one line per
+//	             turn, with -multi-turn)
+//
+// A run stopped by -max-budget-usd or -model-budget exits with status 2 and
+// the reason on stderr.
+//
+// On SIGINT or SIGTERM the session shuts down gracefully: background tasks
+// are stopped and the final result is still written. A second signal exits
+// immediately.
 package main
 
 import (
@@ -29,25 +48,69 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/jg-phare/goat/pkg/agent"
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/mcp"
 	"github.com/jg-phare/goat/pkg/prompt"
+	"github.com/jg-phare/goat/pkg/subagent"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
 func main() {
+	// Set instead of calling os.Exit after the run, so deferred cleanup
+	// (MCP servers, signal handling) still happens first.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	promptFlag := flag.String("prompt", "", "Prompt text (reads stdin if empty)")
 	cwdFlag := flag.String("cwd", "", "Working directory for tools (default: current directory)")
 	maxTurns := flag.Int("max-turns", 100, "Maximum agentic loop turns")
+	maxBudget := flag.Float64("max-budget-usd", 0, "Stop the run once it has cost this many USD (0 = unlimited)")
+	maxThinking := flag.Int("max-thinking-tokens", 0, "Thinking token limit per LLM call (0 = model default)")
+	rpm := flag.Int("rpm", 0, "Cap LLM requests per minute across the run and its subagents (0 = unlimited)")
+	tpm := flag.Int("tpm", 0, "Cap estimated LLM tokens per minute across the run and its subagents (0 = unlimited)")
+	modelBudgets := modelBudgetFlag{}
+	flag.Var(modelBudgets, "model-budget", "Per-model USD budget as model=USD (repeatable)")
 	skillsDir := flag.String("skills-dir", "", "Directory containing skill subdirs with SKILL.md files (enables skill-augmented eval)")
 	mcpConfig := flag.String("mcp-config", "", "Path to JSON file with MCP server configurations")
 	multiTurn := flag.Bool("multi-turn", false, "Enable multi-turn REPL mode (read follow-up prompts from stdin)")
+	allowTools := flag.String("allow-tools", "", "Comma-separated built-in tools to register (default: Bash,Read,Write,Edit,Glob,Grep)")
+	denyTools := flag.String("deny-tools", "", "Comma-separated tools to withhold, including MCP and skill tools (mcp__server covers a whole server)")
+	extraTools := flag.String("extra-tools", "", "Comma-separated optional tools to add: WebFetch,WebSearch,TodoWrite,Agent")
+	recordPath := flag.String("record", "", "Write every LLM request and streamed response to this JSON Lines file")
+	replayPath := flag.String("replay", "", "Serve LLM responses from a -record file, in order, instead of calling the API")
+	persistentShell := flag.Bool("persistent-shell", false, "Run foreground Bash commands in one long-lived shell so cd and exported variables carry over")
+	output := flag.String("output", "text", "Output format: text (final assistant text) or json (one JSON record per run, or per turn with -multi-turn)")
 	flag.Parse()
 
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "error: -output must be text or json, got %q\n", *output)
+		os.Exit(1)
+	}
+	jsonOutput := *output == "json"
+	if *recordPath != "" && *replayPath != "" {
+		fmt.Fprintln(os.Stderr, "error: -record and -replay are mutually exclusive")
+		os.Exit(1)
+	}
+	if *maxBudget < 0 || *maxThinking < 0 || *rpm < 0 || *tpm < 0 {
+		fmt.Fprintln(os.Stderr, "error: -max-budget-usd, -max-thinking-tokens, -rpm and -tpm must not be negative")
+		os.Exit(1)
+	}
+	toolSel, err := parseToolFlags(*allowTools, *denyTools, *extraTools)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Resolve prompt: flag > stdin
 	// In multi-turn mode, only read one line from stdin (keep it open for follow-ups).
 	var stdinScanner *bufio.Scanner
@@ -89,20 +152,42 @@ func main() {
 
 	// Create LLM client
 	client := llm.NewClient(llm.ClientConfig{
-		BaseURL: baseURL,
-		APIKey:  apiKey,
-		Model:   model,
+		BaseURL:     baseURL,
+		APIKey:      apiKey,
+		Model:       model,
+		RateLimiter: llm.NewRateLimiter(*rpm, *tpm),
 	})
+	switch {
+	case *replayPath != "":
+		recs, err := llm.LoadRecordings(*replayPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		client = llm.NewReplayClient(recs, llm.ReplayBySequence)
+	case *recordPath != "":
+		recorder, err := llm.NewRecordingClient(client, *recordPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+		defer recorder.Close()
+		client = recorder
+	}
 
 	// Build tool registry with core tools
-	registry := buildToolRegistry(cwd)
+	registry, background := buildToolRegistry(cwd, toolSel, *persistentShell)
 
-	// Set up Ctrl+C support early (needed for MCP connect timeouts).
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+	// Set up Ctrl+C/SIGTERM support early (needed for MCP connect timeouts).
+	// The first signal shuts the session down gracefully, a second forces exit.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var running atomic.Pointer[agent.Query]
+	waitShutdown := handleSignals(cancel, &running)
 
 	// Connect MCP servers if -mcp-config is provided.
 	var mcpServers map[string]types.McpServerConfig
+	var mcpStatus agent.MCPStatusProvider
 	if *mcpConfig != "" {
 		var err error
 		mcpServers, err = loadMCPConfig(*mcpConfig)
@@ -112,6 +197,17 @@ func main() {
 		}
 		mcpClient := mcp.NewClient(registry)
 		defer mcpClient.Close()
+		mcpStatus = mcpClient
+		// Servers that enable sampling in their config may request completions
+		mcpClient.SetSampler(client, &agent.AllowAllChecker{})
+		mcpClient.SetAuthStatusHandler(func(msg *types.AuthStatusMessage) {
+			for _, line := range msg.Output {
+				fmt.Fprintf(os.Stderr, "mcp auth: %s\n", line)
+			}
+			if msg.Error != "" {
+				fmt.Fprintf(os.Stderr, "mcp auth error: %s\n", msg.Error)
+			}
+		})
 		for name, cfg := range mcpServers {
 			if err := mcpClient.Connect(ctx, name, cfg); err != nil {
 				fmt.Fprintf(os.Stderr, "warning: failed to connect MCP server %q: %v\n", name, err)
@@ -121,13 +217,17 @@ func main() {
 		}
 		registry.Register(&tools.ListMcpResourcesTool{Client: mcpClient})
 		registry.Register(&tools.ReadMcpResourceTool{Client: mcpClient})
+		registry.Register(&tools.ListMcpPromptsTool{Client: mcpClient})
+		registry.Register(&tools.GetMcpPromptTool{Client: mcpClient})
 	}
 
 	// Load skills if -skills-dir is provided (for skill-augmented benchmarks).
 	// The loader scans {skillsDir}/.claude/skills/{name}/SKILL.md following
 	// the standard skill directory convention.
 	var skillRegistry *prompt.SkillRegistry
-	if *skillsDir != "" {
+	if *skillsDir != "" && registry.IsDisabled("Skill") {
+		fmt.Fprintln(os.Stderr, "warning: -skills-dir ignored because Skill is in -deny-tools")
+	} else if *skillsDir != "" {
 		loader := prompt.NewSkillLoader(*skillsDir, "")
 		skills, err := loader.LoadAll()
 		if err != nil {
@@ -145,8 +245,9 @@ func main() {
 		}
 		adapter := &tools.SkillProviderAdapter{Inner: skillRegistry}
 		registry.Register(&tools.SkillTool{
-			Skills:         adapter,
-			ArgSubstituter: prompt.SubstituteArgs,
+			Skills:              adapter,
+			ArgSubstituter:      prompt.SubstituteArgs,
+			TypedArgSubstituter: prompt.SubstituteTypedArgs,
 		})
 	}
 
@@ -155,6 +256,13 @@ func main() {
 	config.LLMClient = client
 	config.Model = model
 	config.MaxTurns = *maxTurns
+	config.MaxBudgetUSD = *maxBudget
+	if len(modelBudgets) > 0 {
+		config.ModelBudgets = modelBudgets
+	}
+	if *maxThinking > 0 {
+		config.MaxThinkingTkns = maxThinking
+	}
 	config.CWD = cwd
 	config.OS = runtime.GOOS
 	config.CurrentDate = time.Now().Format("2006-01-02")
@@ -167,6 +275,7 @@ func main() {
 	}
 	if mcpServers != nil {
 		config.MCPServers = mcpServers
+		config.MCPStatus = mcpStatus
 	}
 	if *multiTurn {
 		config.MultiTurn = true
@@ -182,19 +291,56 @@ func main() {
 		config.Prompter = &prompt.Assembler{}
 	}
 
+	config.Background = background
+
+	if toolSel.has("Agent") {
+		subagents := subagent.NewManager(subagent.ManagerOpts{
+			ParentConfig:      &config,
+			LLMClient:         client,
+			PromptAssembler:   config.Prompter,
+			PermissionChecker: config.Permissions,
+			CostTracker:       config.CostTracker,
+			ParentRegistry:    registry,
+		}, nil)
+		// Load .claude/agents/ definitions; malformed ones are skipped with a warning
+		warnings, err := subagents.Reload(cwd)
+		for _, w := range warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to load agent definitions: %v\n", err)
+		}
+		registry.Register(&tools.AgentTool{Spawner: subagents})
+		config.CanSpawnSubagents = true
+		config.Background = append(config.Background, subagents)
+	}
+
 	query := agent.RunLoop(ctx, promptText, config)
+	running.Store(query)
 
 	if *multiTurn {
-		runMultiTurn(query, stdinScanner)
+		runMultiTurn(query, stdinScanner, jsonOutput)
 	} else {
-		runSingleShot(query)
+		runSingleShot(query, jsonOutput)
+	}
+	waitShutdown()
+
+	if msg := budgetExitMessage(query, &config); msg != "" {
+		fmt.Fprintf(os.Stderr, "\nerror: %s\n", msg)
+		exitCode = exitBudget
 	}
 }
 
-// runSingleShot consumes all messages and prints the final assistant text (existing behavior).
-func runSingleShot(query *agent.Query) {
+// runSingleShot consumes all messages and prints the final assistant text,
+// or with jsonOutput a single evalReport for the run.
+func runSingleShot(query *agent.Query, jsonOutput bool) {
 	var lastText string
+	var reporter jsonReporter
+	var final *evalReport
 	for msg := range query.Messages() {
+		if rep := reporter.observe(msg); rep != nil {
+			final = rep
+		}
 		switch m := msg.(type) {
 		case types.AssistantMessage:
 			lastText = extractText(m)
@@ -204,6 +350,14 @@ func runSingleShot(query *agent.Query) {
 	}
 	query.Wait()
 
+	if jsonOutput {
+		if final == nil {
+			final = &evalReport{IsError: true, Errors: []string{"no result"}, ToolCalls: []evalToolCall{}}
+		}
+		final.ExitReason = string(query.GetExitReason())
+		writeReport(os.Stdout, final)
+		return
+	}
 	if lastText != "" {
 		fmt.Print(lastText)
 	}
@@ -211,16 +365,36 @@ func runSingleShot(query *agent.Query) {
 
 // runMultiTurn runs a REPL loop: after each turn completes, it reads the next
 // line from stdinScanner and sends it as a follow-up message. EOF or empty line
-// terminates the session.
-func runMultiTurn(query *agent.Query, stdinScanner *bufio.Scanner) {
+// terminates the session. With jsonOutput each turn is printed as one line of
+// JSON (NDJSON) instead of its text, plus a final line if the session ends
+// with an error.
+func runMultiTurn(query *agent.Query, stdinScanner *bufio.Scanner, jsonOutput bool) {
 	turnDone := make(chan struct{}, 1)
 	loopDone := make(chan struct{})
 	var lastText string
+	var reporter jsonReporter
+	var final *evalReport
+
+	// Read stdin on its own goroutine so a shutdown isn't stuck behind Scan.
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		for stdinScanner.Scan() {
+			lines <- stdinScanner.Text()
+		}
+	}()
 
 	// Message consumer goroutine
 	go func() {
 		defer close(loopDone)
 		for msg := range query.Messages() {
+			if rep := reporter.observe(msg); rep != nil && jsonOutput {
+				if rep.Turn > 0 {
+					writeReport(os.Stdout, rep)
+				} else {
+					final = rep
+				}
+			}
 			switch m := msg.(type) {
 			case types.AssistantMessage:
 				lastText = extractText(m)
@@ -228,10 +402,10 @@ func runMultiTurn(query *agent.Query, stdinScanner *bufio.Scanner) {
 				lastText = extractText(*m)
 			case types.ResultMessage:
 				if m.Subtype == types.ResultSubtypeSuccessTurn {
-					if lastText != "" {
+					if lastText != "" && !jsonOutput {
 						fmt.Println(lastText)
-						lastText = ""
 					}
+					lastText = ""
 					printTurnMeta(m)
 					select {
 					case turnDone <- struct{}{}:
@@ -240,10 +414,10 @@ func runMultiTurn(query *agent.Query, stdinScanner *bufio.Scanner) {
 				}
 			case *types.ResultMessage:
 				if m.Subtype == types.ResultSubtypeSuccessTurn {
-					if lastText != "" {
+					if lastText != "" && !jsonOutput {
 						fmt.Println(lastText)
-						lastText = ""
 					}
+					lastText = ""
 					printTurnMeta(*m)
 					select {
 					case turnDone <- struct{}{}:
@@ -262,10 +436,16 @@ func runMultiTurn(query *agent.Query, stdinScanner *bufio.Scanner) {
 		case <-loopDone:
 			goto exit
 		}
-		if !stdinScanner.Scan() {
+		var line string
+		var ok bool
+		select {
+		case line, ok = <-lines:
+		case <-loopDone:
+			goto exit // shut down while waiting for input
+		}
+		if !ok {
 			break // EOF
 		}
-		line := stdinScanner.Text()
 		if line == "" {
 			break // empty line = done
 		}
@@ -278,6 +458,15 @@ func runMultiTurn(query *agent.Query, stdinScanner *bufio.Scanner) {
 exit:
 	query.Close()
 	query.Wait()
+	<-loopDone // the consumer has seen every message
+
+	if jsonOutput {
+		if final != nil && final.IsError {
+			final.ExitReason = string(query.GetExitReason())
+			writeReport(os.Stdout, final)
+		}
+		return
+	}
 
 	// Print any remaining text from the final turn
 	if lastText != "" {
@@ -302,6 +491,8 @@ func extractText(m types.AssistantMessage) string {
 
 // loadMCPConfig reads a JSON file containing MCP server configurations.
 // The file must contain a non-empty JSON object mapping server names to configs.
+// ${VAR} references are left in place; the MCP client expands them when it
+// connects each server (see mcp.ExpandConfig).
 func loadMCPConfig(path string) (map[string]types.McpServerConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -317,19 +508,89 @@ func loadMCPConfig(path string) (map[string]types.McpServerConfig, error) {
 	return servers, nil
 }
 
-// buildToolRegistry creates a registry with the 6 core eval tools.
-func buildToolRegistry(cwd string) *tools.Registry {
+// shutdownTimeout bounds how long a graceful shutdown waits for the loop and
+// background work to stop.
+const shutdownTimeout = 10 * time.Second
+
+// handleSignals shuts the running query down on the first SIGINT or SIGTERM
+// (cancelling ctx if no query has started yet) and exits on the second. The
+// returned function stops watching for signals and waits for a shutdown
+// already in progress to finish; main calls it before exiting.
+func handleSignals(cancel context.CancelFunc, running *atomic.Pointer[agent.Query]) (wait func()) {
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-sigCh:
+		case <-quit:
+			signal.Stop(sigCh)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "shutting down; interrupt again to force exit")
+		go func() {
+			<-sigCh
+			os.Exit(130)
+		}()
+
+		defer cancel()
+		q := running.Load()
+		if q == nil {
+			return
+		}
+		ctx, stop := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer stop()
+		if err := q.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "shutdown: %v\n", err)
+		}
+	}()
+	return func() {
+		close(quit)
+		<-done
+	}
+}
+
+// buildToolRegistry creates a registry with the selected eval tools (the 6
+// core tools by default), and returns the TaskManager behind background Bash
+// and, with persistentShell, the Bash tool, so shutdown can stop them. Denied names are disabled on the registry, so
+// MCP or skill tools registered later under them stay hidden. The Agent tool
+// needs the final config and is registered by main.
+func buildToolRegistry(cwd string, sel toolSelection, persistentShell bool) (*tools.Registry, []agent.BackgroundWork) {
 	tm := tools.NewTaskManager()
+	background := []agent.BackgroundWork{tm}
 	registry := tools.NewRegistry(
 		tools.WithAllowed("Read", "Glob", "Grep"),
+		tools.WithDisabled(sel.deny...),
 	)
-	registry.Register(&tools.BashTool{CWD: cwd, TaskManager: tm})
-	registry.Register(&tools.FileReadTool{})
-	registry.Register(&tools.FileWriteTool{})
-	registry.Register(&tools.FileEditTool{})
-	registry.Register(&tools.GlobTool{CWD: cwd})
-	registry.Register(&tools.GrepTool{CWD: cwd})
-	return registry
+	for _, name := range sel.enabled {
+		switch name {
+		case "Bash":
+			bash := &tools.BashTool{CWD: cwd, TaskManager: tm, Persistent: persistentShell}
+			registry.Register(bash)
+			if persistentShell {
+				background = append(background, bash)
+			}
+		case "Read":
+			registry.Register(&tools.FileReadTool{})
+		case "Write":
+			registry.Register(&tools.FileWriteTool{})
+		case "Edit":
+			registry.Register(&tools.FileEditTool{})
+		case "Glob":
+			registry.Register(&tools.GlobTool{CWD: cwd})
+		case "Grep":
+			registry.Register(&tools.GrepTool{CWD: cwd})
+		case "WebFetch":
+			registry.Register(&tools.WebFetchTool{})
+		case "WebSearch":
+			registry.Register(&tools.WebSearchTool{Provider: tools.SearchProviderFromEnv()})
+		case "TodoWrite":
+			registry.Register(&tools.TodoWriteTool{})
+		}
+	}
+	return registry, background
 }
 
 // envOr returns the value of an environment variable, or the fallback if unset.
@@ -103,3 +103,26 @@ func TestLoadMCPConfig_MissingFile(t *testing.T) {
 		t.Fatal("expected error for missing file")
 	}
 }
+
+func TestLoadMCPConfig_KeepsEnvReferences(t *testing.T) {
+	t.Setenv("GOAT_TEST_MCP_TOKEN", "secret")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mcp.json")
+	os.WriteFile(path, []byte(`{
+		"github": {
+			"type": "http",
+			"url": "https://${GOAT_TEST_MCP_HOST:-api.example.com}/mcp",
+			"headers": {"Authorization": "Bearer ${GOAT_TEST_MCP_TOKEN}"}
+		}
+	}`), 0644)
+
+	servers, err := loadMCPConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Expanded once, when the client connects the server
+	gh := servers["github"]
+	if gh.URL != "https://${GOAT_TEST_MCP_HOST:-api.example.com}/mcp" || gh.Headers["Authorization"] != "Bearer ${GOAT_TEST_MCP_TOKEN}" {
+		t.Errorf("config expanded early: %+v", gh)
+	}
+}
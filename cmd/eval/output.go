@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// evalReport is the -output json record for a run or, in multi-turn mode,
+// for one turn. NumTurns, TotalCostUSD and Usage are cumulative for the
+// session; ToolCalls covers only the run or turn the record describes.
+type evalReport struct {
+	Turn         int             `json:"turn,omitempty"` // 1-based, multi-turn mode only
+	Result       string          `json:"result"`
+	ExitReason   string          `json:"exit_reason"`
+	IsError      bool            `json:"is_error"`
+	Errors       []string        `json:"errors,omitempty"`
+	NumTurns     int             `json:"num_turns"`
+	TotalCostUSD float64         `json:"total_cost_usd"`
+	Usage        types.BetaUsage `json:"usage"`
+	ToolCalls    []evalToolCall  `json:"tool_calls"`
+	DurationMs   int64           `json:"duration_ms"`
+}
+
+// evalToolCall is one tool invocation the model made.
+type evalToolCall struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Input map[string]any `json:"input"`
+}
+
+// jsonReporter collects assistant text and tool calls from the message
+// stream and turns each result message into an evalReport.
+type jsonReporter struct {
+	turns     int
+	lastText  string
+	toolCalls []evalToolCall
+}
+
+// observe records msg. For a result message it returns the report covering
+// everything observed since the previous result, and nil otherwise.
+func (r *jsonReporter) observe(msg types.SDKMessage) *evalReport {
+	switch m := msg.(type) {
+	case types.AssistantMessage:
+		r.observeAssistant(m)
+	case *types.AssistantMessage:
+		r.observeAssistant(*m)
+	case types.ResultMessage:
+		return r.report(m)
+	case *types.ResultMessage:
+		return r.report(*m)
+	}
+	return nil
+}
+
+func (r *jsonReporter) observeAssistant(m types.AssistantMessage) {
+	if text := extractText(m); text != "" {
+		r.lastText = text
+	}
+	for _, block := range m.Message.Content {
+		if block.Type == "tool_use" {
+			r.toolCalls = append(r.toolCalls, evalToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
+	}
+}
+
+func (r *jsonReporter) report(m types.ResultMessage) *evalReport {
+	rep := &evalReport{
+		Result:       m.Result,
+		ExitReason:   string(m.Subtype),
+		IsError:      m.IsError,
+		Errors:       m.Errors,
+		NumTurns:     m.NumTurns,
+		TotalCostUSD: m.TotalCostUSD,
+		Usage:        m.Usage,
+		ToolCalls:    r.toolCalls,
+		DurationMs:   m.DurationMs,
+	}
+	if rep.Result == "" && !m.IsError {
+		rep.Result = r.lastText
+	}
+	if rep.ToolCalls == nil {
+		rep.ToolCalls = []evalToolCall{}
+	}
+	if m.Subtype == types.ResultSubtypeSuccessTurn {
+		r.turns++
+		rep.Turn = r.turns
+		rep.ExitReason = "end_turn"
+	}
+	r.lastText = ""
+	r.toolCalls = nil
+	return rep
+}
+
+// writeReport writes rep as a single line of JSON.
+func writeReport(w io.Writer, rep *evalReport) error {
+	return json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func assistant(blocks ...types.ContentBlock) *types.AssistantMessage {
+	return &types.AssistantMessage{Message: types.BetaMessage{Content: blocks}}
+}
+
+func TestJSONReporter_SingleShot(t *testing.T) {
+	var r jsonReporter
+	msgs := []types.SDKMessage{
+		assistant(types.ContentBlock{Type: "text", Text: "Let me look."},
+			types.ContentBlock{Type: "tool_use", ID: "call-1", Name: "Bash", Input: map[string]any{"command": "ls"}}),
+		assistant(types.ContentBlock{Type: "tool_use", ID: "call-2", Name: "Read", Input: map[string]any{"file_path": "go.mod"}}),
+		assistant(types.ContentBlock{Type: "text", Text: "Done."}),
+	}
+	for _, m := range msgs {
+		if rep := r.observe(m); rep != nil {
+			t.Fatalf("unexpected report for %T", m)
+		}
+	}
+	rep := r.observe(&types.ResultMessage{
+		Subtype:      types.ResultSubtypeSuccess,
+		NumTurns:     3,
+		TotalCostUSD: 0.01,
+		Usage:        types.BetaUsage{InputTokens: 100, OutputTokens: 20},
+		Result:       "Done.",
+	})
+	if rep == nil {
+		t.Fatal("no report for the result message")
+	}
+	if rep.Result != "Done." || rep.NumTurns != 3 || rep.Usage.InputTokens != 100 || rep.Turn != 0 {
+		t.Errorf("report = %+v", rep)
+	}
+	if len(rep.ToolCalls) != 2 || rep.ToolCalls[0].Name != "Bash" || rep.ToolCalls[1].Input["file_path"] != "go.mod" {
+		t.Errorf("tool calls = %+v", rep.ToolCalls)
+	}
+
+	var buf bytes.Buffer
+	if err := writeReport(&buf, rep); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	for _, key := range []string{"result", "exit_reason", "num_turns", "total_cost_usd", "usage", "tool_calls"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("JSON missing %q: %s", key, buf.String())
+		}
+	}
+}
+
+func TestJSONReporter_Turns(t *testing.T) {
+	var r jsonReporter
+	r.observe(assistant(types.ContentBlock{Type: "tool_use", ID: "call-1", Name: "Bash"}))
+	r.observe(assistant(types.ContentBlock{Type: "text", Text: "first"}))
+	first := r.observe(&types.ResultMessage{Subtype: types.ResultSubtypeSuccessTurn, NumTurns: 2})
+
+	r.observe(assistant(types.ContentBlock{Type: "text", Text: "second"}))
+	second := r.observe(types.ResultMessage{Subtype: types.ResultSubtypeSuccessTurn, NumTurns: 3})
+
+	if first.Turn != 1 || first.Result != "first" || first.ExitReason != "end_turn" || len(first.ToolCalls) != 1 {
+		t.Errorf("first turn = %+v", first)
+	}
+	if second.Turn != 2 || second.Result != "second" || second.ToolCalls == nil || len(second.ToolCalls) != 0 {
+		t.Errorf("second turn = %+v, want only its own tool calls", second)
+	}
+}
+
+func TestJSONReporter_Error(t *testing.T) {
+	var r jsonReporter
+	r.observe(assistant(types.ContentBlock{Type: "text", Text: "partial"}))
+	rep := r.observe(&types.ResultMessage{
+		Subtype: types.ResultSubtypeErrorMaxTurns,
+		IsError: true,
+		Errors:  []string{"max turns reached"},
+	})
+	if !rep.IsError || rep.Result != "" || rep.ExitReason != string(types.ResultSubtypeErrorMaxTurns) {
+		t.Errorf("report = %+v", rep)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// coreTools are registered unless -allow-tools narrows them.
+var coreTools = []string{"Bash", "Read", "Write", "Edit", "Glob", "Grep"}
+
+// optionalTools are registered only when named in -extra-tools or -allow-tools.
+var optionalTools = []string{"WebFetch", "WebSearch", "TodoWrite", "Agent"}
+
+// dynamicTools are registered by -mcp-config and -skills-dir rather than by
+// name, so they can be denied but not allowed.
+var dynamicTools = []string{"ListMcpResources", "ReadMcpResource", "ListMcpPrompts", "GetMcpPrompt", "Skill"}
+
+// toolSelection is the parsed -allow-tools, -deny-tools and -extra-tools flags.
+type toolSelection struct {
+	enabled []string // core and optional tools to register, in catalog order
+	deny    []string // names and mcp__ globs withheld from the registry
+}
+
+// parseToolFlags validates the comma-separated tool flags. The registered
+// tools are -allow-tools (default: the core tools) plus -extra-tools, minus
+// -deny-tools. Deny entries also withhold MCP and skill tools registered
+// later; "mcp__server" or a glob like "mcp__server__write_*" covers MCP tools.
+func parseToolFlags(allow, deny, extra string) (toolSelection, error) {
+	builtin := slices.Concat(coreTools, optionalTools)
+	var sel toolSelection
+
+	allowed, err := splitToolList("-allow-tools", allow, func(name string) error {
+		if slices.Contains(dynamicTools, name) {
+			return fmt.Errorf("%s is registered by -mcp-config or -skills-dir, not -allow-tools", name)
+		}
+		return knownTool(name, builtin)
+	})
+	if err != nil {
+		return sel, err
+	}
+	extras, err := splitToolList("-extra-tools", extra, func(name string) error {
+		return knownTool(name, optionalTools)
+	})
+	if err != nil {
+		return sel, err
+	}
+	sel.deny, err = splitToolList("-deny-tools", deny, func(name string) error {
+		if strings.HasPrefix(name, "mcp__") {
+			if _, err := path.Match(name, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q", name)
+			}
+			return nil
+		}
+		return knownTool(name, slices.Concat(builtin, dynamicTools))
+	})
+	if err != nil {
+		return sel, err
+	}
+	for i, name := range sel.deny {
+		if strings.HasPrefix(name, "mcp__") && strings.Count(name, "__") == 1 {
+			sel.deny[i] = name + "__*" // a bare server name covers all its tools
+		}
+	}
+
+	if len(allowed) == 0 {
+		allowed = coreTools
+	}
+	for _, name := range builtin {
+		if (slices.Contains(allowed, name) || slices.Contains(extras, name)) && !slices.Contains(sel.deny, name) {
+			sel.enabled = append(sel.enabled, name)
+		}
+	}
+	return sel, nil
+}
+
+// has reports whether name is one of the enabled built-in tools.
+func (s toolSelection) has(name string) bool {
+	return slices.Contains(s.enabled, name)
+}
+
+// splitToolList splits a comma-separated flag value and checks each name.
+func splitToolList(flagName, value string, check func(string) error) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if err := check(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", flagName, err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func knownTool(name string, known []string) error {
+	if slices.Contains(known, name) {
+		return nil
+	}
+	return fmt.Errorf("unknown tool %q (known: %s)", name, strings.Join(known, ", "))
+}
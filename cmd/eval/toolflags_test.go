@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+func TestParseToolFlags(t *testing.T) {
+	tests := []struct {
+		allow, deny, extra string
+		want               []string
+	}{
+		{"", "", "", coreTools},
+		{"", "Bash,Write", "", []string{"Read", "Edit", "Glob", "Grep"}},
+		{"", "", "WebFetch, TodoWrite", []string{"Bash", "Read", "Write", "Edit", "Glob", "Grep", "WebFetch", "TodoWrite"}},
+		{"Read,Grep", "", "", []string{"Read", "Grep"}},
+		{"Read,WebSearch", "", "Agent", []string{"Read", "WebSearch", "Agent"}},
+		// Deny wins over allow and extra.
+		{"Read,Bash", "Bash", "WebFetch", []string{"Read", "WebFetch"}},
+		{"", "WebFetch", "WebFetch", coreTools},
+	}
+	for _, tt := range tests {
+		sel, err := parseToolFlags(tt.allow, tt.deny, tt.extra)
+		if err != nil {
+			t.Fatalf("parseToolFlags(%q, %q, %q): %v", tt.allow, tt.deny, tt.extra, err)
+		}
+		if !reflect.DeepEqual(sel.enabled, tt.want) {
+			t.Errorf("parseToolFlags(%q, %q, %q) enabled = %v, want %v", tt.allow, tt.deny, tt.extra, sel.enabled, tt.want)
+		}
+	}
+}
+
+func TestParseToolFlags_Errors(t *testing.T) {
+	tests := []struct {
+		allow, deny, extra string
+		want               string
+	}{
+		{"Bsh", "", "", `-allow-tools: unknown tool "Bsh"`},
+		{"Skill", "", "", "registered by -mcp-config or -skills-dir"},
+		{"", "Nope", "", `-deny-tools: unknown tool "Nope"`},
+		{"", "mcp__fs__[", "", "invalid pattern"},
+		{"", "", "Bash", `-extra-tools: unknown tool "Bash"`},
+	}
+	for _, tt := range tests {
+		_, err := parseToolFlags(tt.allow, tt.deny, tt.extra)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseToolFlags(%q, %q, %q) err = %v, want %q", tt.allow, tt.deny, tt.extra, err, tt.want)
+		}
+	}
+}
+
+func TestBuildToolRegistry_DenyCoversLateTools(t *testing.T) {
+	sel, err := parseToolFlags("", "Bash,Skill,mcp__github,ListMcpResources", "WebFetch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry, _ := buildToolRegistry(t.TempDir(), sel, false)
+
+	// Registered afterwards, as -mcp-config and -skills-dir do.
+	registry.Register(&tools.SkillTool{})
+	registry.Register(&tools.ListMcpResourcesTool{})
+	registry.RegisterMCPTool("github", "create_issue", "", nil, nil, nil)
+	registry.RegisterMCPTool("fs", "read", "", nil, nil, nil)
+
+	want := []string{"Edit", "Glob", "Grep", "Read", "WebFetch", "Write", "mcp__fs__read"}
+	if got := registry.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
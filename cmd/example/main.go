@@ -29,6 +29,9 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/jg-phare/goat/pkg/agent"
 	goatctx "github.com/jg-phare/goat/pkg/context"
@@ -48,6 +51,7 @@ func main() {
 	noTools := flag.Bool("no-tools", false, "Run without tools (pure chat)")
 	streaming := flag.Bool("stream", false, "Show streaming chunks")
 	envFile := flag.String("env", ".env", "Path to .env file (empty to skip)")
+	pricingFile := flag.String("pricing", "", "JSON file of model prices per million tokens")
 	flag.Parse()
 
 	// Load .env file
@@ -74,6 +78,15 @@ func main() {
 		}
 	}
 
+	if *pricingFile != "" {
+		if err := llm.LoadPricing(*pricingFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	llm.SetUnknownModelHandler(func(model string) {
+		fmt.Fprintf(os.Stderr, "Warning: no pricing for model %q; its cost is counted as $0\n", model)
+	})
+
 	fmt.Printf("Provider: %s\n", rc.Provider)
 	fmt.Printf("Base URL: %s\n", rc.BaseURL)
 	fmt.Printf("Model:    %s\n", rc.Model)
@@ -97,17 +110,23 @@ func main() {
 	// returns "You are a helpful assistant." which is fine for most models.
 	// For tool-enabled runs, tell the model it has tools available.
 	if !*noTools {
-		config.ToolRegistry = buildToolRegistry(cwd)
+		registry, tm := buildToolRegistry(cwd)
+		config.ToolRegistry = registry
+		config.Background = []agent.BackgroundWork{tm}
 		config.Prompter = &agent.StaticPromptAssembler{
 			Prompt: "You are a helpful coding assistant. You have access to tools for running commands, reading/writing files, and searching. Use tools when the user asks you to interact with the filesystem or run commands. Answer directly when no tools are needed.",
 		}
 	}
 
-	// Run with Ctrl+C support
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
-	defer stop()
+	// Run with Ctrl+C support: the first interrupt drains gracefully, a
+	// second forces exit
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var running atomic.Pointer[agent.Query]
+	waitShutdown := handleSignals(cancel, &running)
 
 	query := agent.RunLoop(ctx, *prompt, config)
+	running.Store(query)
 
 	// Consume messages
 	for msg := range query.Messages() {
@@ -132,6 +151,7 @@ func main() {
 	}
 
 	query.Wait()
+	waitShutdown()
 }
 
 func printAssistant(m types.AssistantMessage) {
@@ -153,7 +173,11 @@ func printResult(m types.ResultMessage, q *agent.Query) {
 		m.Subtype, q.TurnCount(), q.TotalCostUSD())
 	usage := q.TotalUsage()
 	fmt.Printf("Tokens: %d input, %d output\n",
-		usage.InputTokens, usage.OutputTokens)
+		usage.TotalInputTokens(), usage.OutputTokens)
+	if usage.CacheReadInputTokens > 0 || usage.CacheCreationInputTokens > 0 {
+		fmt.Printf("Cache: %d read, %d written (%.0f%% of input from cache)\n",
+			usage.CacheReadInputTokens, usage.CacheCreationInputTokens, 100*usage.CacheHitRatio())
+	}
 	if m.IsError && len(m.Errors) > 0 {
 		fmt.Printf("Errors: %s\n", strings.Join(m.Errors, "; "))
 	}
@@ -166,9 +190,49 @@ func truncate(s string, n int) string {
 	return s[:n] + "..."
 }
 
+// handleSignals shuts the running query down on the first SIGINT or SIGTERM
+// (cancelling ctx if no query has started yet) and exits on the second. The
+// returned function stops watching for signals and waits for a shutdown
+// already in progress to finish; main calls it before exiting.
+func handleSignals(cancel context.CancelFunc, running *atomic.Pointer[agent.Query]) (wait func()) {
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-sigCh:
+		case <-quit:
+			signal.Stop(sigCh)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\nshutting down; interrupt again to force exit")
+		go func() {
+			<-sigCh
+			os.Exit(130)
+		}()
+
+		defer cancel()
+		q := running.Load()
+		if q == nil {
+			return
+		}
+		ctx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+		defer stop()
+		if err := q.Shutdown(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "shutdown: %v\n", err)
+		}
+	}()
+	return func() {
+		close(quit)
+		<-done
+	}
+}
+
 // buildToolRegistry creates a slim registry with just the core tools.
 // The full DefaultRegistry has 21 tools which overwhelms smaller models.
-func buildToolRegistry(cwd string) *tools.Registry {
+func buildToolRegistry(cwd string) (*tools.Registry, *tools.TaskManager) {
 	tm := tools.NewTaskManager()
 	registry := tools.NewRegistry(
 		tools.WithAllowed("Read", "Glob", "Grep"),
@@ -179,7 +243,7 @@ func buildToolRegistry(cwd string) *tools.Registry {
 	registry.Register(&tools.FileEditTool{})
 	registry.Register(&tools.GlobTool{CWD: cwd})
 	registry.Register(&tools.GrepTool{CWD: cwd})
-	return registry
+	return registry, tm
 }
 
 // loadEnvFile reads a .env file and sets environment variables (won't overwrite existing).
@@ -4,6 +4,7 @@ import (
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Option configures an AgentConfig.
@@ -39,9 +40,21 @@ func WithHooks(runner HookRunner) Option {
 	return func(c *AgentConfig) { c.Hooks = runner }
 }
 
-// WithCompactor sets the context compactor.
+// WithCompactor sets the context compactor, replacing any HistoryWindow.
 func WithCompactor(compactor ContextCompactor) Option {
-	return func(c *AgentConfig) { c.Compactor = compactor }
+	return func(c *AgentConfig) {
+		c.Compactor = compactor
+		c.HistoryWindow = nil
+	}
+}
+
+// WithHistoryWindow trims history to maxTokens (0 = what fits in the context
+// window) before each request, replacing any compactor.
+func WithHistoryWindow(maxTokens int) Option {
+	return func(c *AgentConfig) {
+		c.HistoryWindow = &HistoryWindow{MaxTokens: maxTokens}
+		c.Compactor = &NoOpCompactor{}
+	}
 }
 
 // WithSystemPrompt sets a custom system prompt string.
@@ -56,6 +69,22 @@ func WithIncludePartial(include bool) Option {
 	return func(c *AgentConfig) { c.IncludePartial = include }
 }
 
+// WithAssembledPartials enables streaming of assembled message snapshots
+// (stream_event with a message_snapshot event) in place of raw chunks.
+func WithAssembledPartials(assembled bool) Option {
+	return func(c *AgentConfig) { c.AssembledPartials = assembled }
+}
+
+// WithMetrics sets the metrics sink for loop observability.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *AgentConfig) { c.Metrics = metrics }
+}
+
+// WithTracerProvider enables OpenTelemetry tracing with the given provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *AgentConfig) { c.TracerProvider = tp }
+}
+
 // WithPermissionMode sets the permission mode.
 func WithPermissionMode(mode types.PermissionMode) Option {
 	return func(c *AgentConfig) { c.PermissionMode = mode }
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"time"
 
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AgentConfig holds the full configuration for an agentic loop.
@@ -21,27 +23,92 @@ type AgentConfig struct {
 	MaxBudgetUSD float64            // 0 = unlimited
 	ModelBudgets map[string]float64 // per-model USD budget limits (model ID → max USD)
 
+	// Budget warnings: fractions of MaxBudgetUSD (e.g. 0.5, 0.8) that each emit
+	// a one-time budget_warning status message before the hard cutoff
+	BudgetWarnThresholds []float64
+	// ModelBudgetWarnThreshold is the fraction of each ModelBudgets limit at
+	// which that model gets a one-time budget_warning (0 = no warnings)
+	ModelBudgetWarnThreshold float64
+
 	// Session
 	CWD            string
 	SessionID      string
 	PermissionMode types.PermissionMode
 
+	// Restore selects a previous session in SessionStore to pick up from:
+	// Resume (with ForkSession or ResumeSessionAt) or Continue. Other
+	// QueryOptions fields are ignored. The prompt is appended to the
+	// restored history.
+	Restore types.QueryOptions
+
+	// SessionAutoSaveInterval, when positive, flushes session metadata (turn
+	// count, cost, usage) to SessionStore at most this often during the run,
+	// checked after each turn, so a crash doesn't lose them. 0 = only when
+	// the loop exits.
+	SessionAutoSaveInterval time.Duration
+
+	// TodoContinuity keeps the TodoWrite tool's list across turns and
+	// sessions: it is saved to the session metadata when it changes and
+	// restored into the tool on resume, and while it has items each change
+	// is restated to the model in the system prompt of the next call.
+	TodoContinuity bool
+
 	// Multi-turn mode
 	MultiTurn bool // if true, loop waits for more input after end_turn instead of exiting
 
+	// RecordTranscript keeps a copy of every emitted message for
+	// Query.ExportTranscript. Off by default: the copy grows with the run.
+	RecordTranscript bool
+
+	// ReplaySession, when non-empty, makes the loop re-emit these messages in
+	// order (e.g. from LoadTranscript) instead of calling the LLM, then exit.
+	// Useful for driving UIs from a recorded run. MultiTurn is ignored.
+	ReplaySession []types.SDKMessage
+
+	// Structured output: when set, the final answer must be JSON matching
+	// OutputFormat.Schema; invalid answers are sent back for correction
+	OutputFormat               *types.OutputFormat
+	MaxStructuredOutputRetries int // 0 = default 2
+
 	// Streaming
-	IncludePartial bool // emit stream_event messages for each SSE chunk
+	IncludePartial    bool // emit stream_event messages for each SSE chunk
+	AssembledPartials bool // emit running message snapshots as stream_events instead of raw chunks
+
+	// OnUsageDelta, if set, is called with each increase in token usage as
+	// it becomes known and the run's total cost including it, for a live
+	// spend meter. Providers that report usage while streaming trigger it
+	// mid-turn; others once per turn. It runs on the loop goroutine and must
+	// not block.
+	OnUsageDelta func(delta types.BetaUsage, totalCostUSD float64)
+
+	// Tool-call log: a ToolCallRecord per executed call, with its permission
+	// decision, hook outcomes, duration and a summary of its output
+	ToolCallLog      bool // accumulate records for Query.ToolCalls
+	ToolCallMessages bool // emit each record as a ToolCallMessage when the call finishes
+
+	// ToolUseSummary, when set, emits a ToolUseSummaryMessage after each burst of tool calls
+	ToolUseSummary *ToolUseSummaryConfig
+
+	// ToolLoopDetection, when set, catches the model repeating the same
+	// failing tool call and tells it to change approach, or ends the run
+	ToolLoopDetection *ToolLoopConfig
 
 	// Debug
 	Debug     bool
 	DebugFile string // path for debug output
 
 	// Model control
-	FallbackModel            string  // for automatic model fallback
-	CompactorModel           string  // model to use for context compaction (default: haiku)
-	MaxThinkingTkns          *int    // thinking token limit (wire to LLM request)
-	BudgetDowngradeThreshold float64 // fraction of MaxBudgetUSD (0.0-1.0) to trigger downgrade
-	BudgetDowngradeModel     string  // model to switch to when threshold is exceeded
+	FallbackModels           []string // tried in order on retriable errors, each at most once per turn
+	StickyFallback           bool     // keep the fallback that served a turn instead of returning to the primary next turn
+	CompactorModel           string   // model to use for context compaction (default: haiku)
+	MaxThinkingTkns          *int     // thinking token limit (wire to LLM request)
+	ReasoningEffort          string   // "low" | "medium" | "high"; used when no thinking budget is set
+	BudgetDowngradeThreshold float64  // fraction of MaxBudgetUSD (0.0-1.0) to trigger downgrade
+	BudgetDowngradeModel     string   // model to switch to when threshold is exceeded
+	StopSequences            []string // end generation at custom markers, e.g. "</final>" (sent as the request's stop)
+	ParallelToolCalls        *bool    // parallel_tool_calls sent with tools (nil = provider default); false asks for one call per response
+	StrictTools              bool     // strict function calling; optional arguments the model sends as null are dropped before execution
+	RepairToolCalls          bool     // repair malformed tool-call JSON (always on for llm.IsGroqLlama models)
 
 	// Additional directories for prompt assembly
 	AdditionalDirs []string
@@ -76,6 +143,7 @@ type AgentConfig struct {
 	// Content
 	ManagedPolicyContent string   // OS-level managed CLAUDE.md policy (highest priority)
 	ClaudeMDContent      string   // pre-loaded CLAUDE.md content
+	ProjectMemoryFiles   []string // file names loaded from CWD up to the repo root (default: CLAUDE.md, AGENTS.md)
 	OutputStyle          string   // output style config (empty = default)
 	SlashCommands        []string // registered slash command names
 
@@ -84,14 +152,24 @@ type AgentConfig struct {
 	UserRules       []RuleEntry // rules loaded from ~/.claude/rules/
 	ActiveFilePaths []string    // files currently being worked on (for conditional injection)
 
+	// ConditionalRules inject guidance the first time the session accesses
+	// a matching file (e.g. "*.proto" → "regenerate stubs after editing").
+	ConditionalRules []ConditionalRule
+
 	// Git state (snapshot at session start)
 	GitBranch        string
 	GitMainBranch    string
 	GitStatus        string
 	GitRecentCommits string
 
+	// GitContextProvider, if set, fills the Git fields above from the
+	// repository at CWD once at the start of each run, unless the host
+	// already set GitBranch.
+	GitContextProvider GitContextProvider
+
 	// MCP
 	MCPServers map[string]types.McpServerConfig
+	MCPStatus  MCPStatusProvider // connection states for the init message (nil = inferred from registered tools)
 
 	// Permission configuration
 	AllowedTools                    []string
@@ -105,10 +183,43 @@ type AgentConfig struct {
 	// Subagent behavior
 	BackgroundMode    bool // auto-deny unpermitted tools, disable AskUser, no MCP
 	CanSpawnSubagents bool // false = Agent tool filtered from registry
+	MaxSubagentDepth  int  // levels of subagents allowed below this agent (main agent: 0 = 1, subagents cannot spawn)
 
 	// Parallel tool execution
 	MaxParallelTools int // max concurrency for side-effect-free tools (0 = default 5)
 
+	// DedupToolCalls runs tool calls repeated within one response (same name
+	// and input) once and reuses the result for every copy. Off by default
+	// since some workflows repeat a call on purpose.
+	DedupToolCalls bool
+
+	// Tool execution timeouts: the tool's context is cancelled when exceeded
+	ToolTimeouts       map[string]time.Duration // per-tool overrides keyed by tool name
+	DefaultToolTimeout time.Duration            // applies to tools without an override (0 = no timeout)
+
+	// TurnTimeout caps one turn (LLM call plus tool execution). A turn over
+	// the limit is cut off, its partial text kept, and the loop continues
+	// with a note to the model instead of ending the session (0 = no limit).
+	TurnTimeout time.Duration
+
+	// ToolHeartbeatInterval re-emits a running tool's ToolProgressMessage
+	// with the elapsed time (0 = 5s, negative = only start and end).
+	ToolHeartbeatInterval time.Duration
+
+	// RequireReadBeforeEdit makes Edit and Write fail on existing files that
+	// were not read (or written) earlier in the session, so edits are based on
+	// the file's actual contents. Writes that create new files are exempt.
+	RequireReadBeforeEdit bool
+
+	// Tool result pruning between turns (lighter than full compaction)
+	ToolResultPruneStrategy ToolResultPruneStrategy // "" = PruneByCount
+	ToolResultPruneKeep     int                     // recent messages left intact by PruneByCount (0 = default 10)
+	ToolResultPruneTarget   float64                 // fraction of the context limit targeted by PruneByTokens (0 = default 0.5)
+
+	// HistoryWindow trims the history to a token budget before each request
+	// instead of summarizing it with Compactor. nil = compaction.
+	HistoryWindow *HistoryWindow
+
 	// Compact tools: use shortened tool descriptions for models with limited
 	// instruction-following capacity (e.g., Llama via Groq).
 	CompactTools bool
@@ -123,8 +234,20 @@ type AgentConfig struct {
 	// Context window resolution (injected to avoid import cycle with pkg/context)
 	ContextLimitFunc func(model string, betas []string) int
 
+	// Output token limit per LLM call: MaxOutputTokensFunc(model) if it
+	// returns > 0, else MaxOutputTokens, else 16384. Also reserved in the
+	// token budget.
+	MaxOutputTokens     int
+	MaxOutputTokensFunc func(model string) int
+
+	// Token counting for budget calculations (nil = tokenizer chosen from Model, falling back to len/4)
+	TokenCounter TokenCounter
+
 	// Dynamic model selection: automatically choose model based on estimated task complexity.
 	DynamicModelConfig *DynamicModelConfig
+	// ModelRouter picks the model before each LLM call. nil = a ThresholdRouter
+	// over DynamicModelConfig, if set.
+	ModelRouter ModelRouter
 
 	// Dependencies (injected)
 	LLMClient    llm.Client
@@ -135,7 +258,19 @@ type AgentConfig struct {
 	Compactor    ContextCompactor
 	CostTracker  *llm.CostTracker
 	SessionStore SessionStore // nil = no persistence (default)
+	Redactor     Redactor      // nil = messages are persisted and emitted as is
 	Skills       SkillProvider // nil = no skills
+	Metrics      Metrics       // nil = no metrics collection
+
+	// TracerProvider receives OpenTelemetry spans for the loop, LLM calls,
+	// tool executions and subagent spawns. nil = no tracing.
+	TracerProvider trace.TracerProvider
+
+	// Background is work that can outlive a tool call (e.g. the TaskManager
+	// behind background Bash, the subagent Manager, a persistent BashTool).
+	// Query.Shutdown stops it; entries that are also io.Closers are closed
+	// when the loop exits.
+	Background []BackgroundWork
 }
 
 // RuleEntry is a rule loaded from .claude/rules/ for injection into the system prompt.
@@ -144,6 +279,14 @@ type RuleEntry struct {
 	PathPatterns []string // glob patterns from frontmatter (empty = unconditional)
 }
 
+// ConditionalRule is guidance injected as additional context for the next
+// turn once a file matching Pattern has been accessed. Each rule fires at most
+// once per session.
+type ConditionalRule struct {
+	Pattern string // doublestar glob against the CWD-relative path; without a "/" it matches the base name
+	Text    string
+}
+
 // DynamicModelConfig configures automatic model selection based on estimated prompt complexity.
 type DynamicModelConfig struct {
 	// SimpleModel is used when prompt tokens < SimpleThresholdTokens.
@@ -155,6 +298,12 @@ type DynamicModelConfig struct {
 	// ComplexModel is used when prompt tokens > ComplexThresholdTokens.
 	ComplexModel           string
 	ComplexThresholdTokens int // default: 10000
+
+	// Mid-run routing. Complexity often shows only once the agent is working,
+	// so the model can change after the first turn. 0 disables each rule.
+	UpgradeAfterTurns           int // switch to ComplexModel once this many turns have run
+	UpgradeAfterToolErrors      int // switch to ComplexModel after this many consecutive failed tool calls
+	DowngradeAfterReadOnlyTurns int // switch to SimpleModel after this many consecutive read-only tool turns
 }
 
 // ValidateModel checks if the configured model has pricing data.
@@ -178,16 +327,17 @@ func DefaultConfig() AgentConfig {
 		shell = "/bin/sh"
 	}
 	return AgentConfig{
-		Model:          "claude-sonnet-4-5-20250929",
-		MaxTurns:       100,
-		PermissionMode: types.PermissionModeDefault,
-		OS:             runtime.GOOS,
-		Shell:          shell,
-		PromptVersion:  "2.1.37",
-		Prompter:       &StaticPromptAssembler{Prompt: "You are a helpful assistant."},
-		Permissions:    &AllowAllChecker{},
-		Hooks:          &NoOpHookRunner{},
-		Compactor:      &NoOpCompactor{},
-		CostTracker:    llm.NewCostTracker(),
+		Model:              "claude-sonnet-4-5-20250929",
+		MaxTurns:           100,
+		PermissionMode:     types.PermissionModeDefault,
+		OS:                 runtime.GOOS,
+		Shell:              shell,
+		PromptVersion:      "2.1.37",
+		ProjectMemoryFiles: []string{"CLAUDE.md", "AGENTS.md"},
+		Prompter:           &StaticPromptAssembler{Prompt: "You are a helpful assistant."},
+		Permissions:        &AllowAllChecker{},
+		Hooks:              &NoOpHookRunner{},
+		Compactor:          &NoOpCompactor{},
+		CostTracker:        llm.NewCostTracker(),
 	}
 }
@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// answerKey identifies the tool call an answer_permission or answer_question
+// control request is for.
+type answerKey struct {
+	subtype   string
+	toolUseID string
+}
+
+// expectAnswer registers a tool call as waiting for a control answer of
+// subtype. Tool calls run in parallel all read the shared control channel,
+// so whichever reads an answer hands it to the call it names. Call release
+// once the answer is in.
+func (q *Query) expectAnswer(subtype, toolUseID string) (answers chan types.ControlRequest, release func()) {
+	key := answerKey{subtype, toolUseID}
+	answers = make(chan types.ControlRequest, 1)
+	q.mu.Lock()
+	if q.pendingAnswers == nil {
+		q.pendingAnswers = make(map[answerKey]chan types.ControlRequest)
+	}
+	q.pendingAnswers[key] = answers
+	q.mu.Unlock()
+	return answers, func() {
+		q.mu.Lock()
+		delete(q.pendingAnswers, key)
+		q.mu.Unlock()
+	}
+}
+
+// answerFor returns the channel of the pending tool call req answers, if any.
+func (q *Query) answerFor(req types.ControlRequest) chan types.ControlRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.pendingAnswers[answerKey{req.Request.Subtype, req.Request.ToolUseID}]
+}
+
+// awaitAnswer waits for the control request of subtype answering the tool
+// call toolUseID, registered with expectAnswer. An answer without a
+// ToolUseID goes to whichever call reads it. Answers for other pending calls
+// are routed to them; other control requests are dispatched as usual. The
+// answer is acknowledged before it is returned.
+func awaitAnswer(ctx context.Context, config *AgentConfig, state *LoopState, q *Query, subtype, toolUseID string, answers chan types.ControlRequest) (types.ControlRequestInner, error) {
+	for {
+		var req types.ControlRequest
+		select {
+		case req = <-answers:
+		case req = <-q.controlCh:
+			if req.Request.Subtype == subtype && req.Request.ToolUseID == "" {
+				break
+			}
+			if target := q.answerFor(req); target != nil && target != answers {
+				select {
+				case target <- req:
+				default: // the call already has an answer
+					q.controlResp <- dispatchControl(config, state, q, req)
+				}
+				continue
+			}
+			if req.Request.Subtype != subtype || req.Request.ToolUseID != toolUseID {
+				q.controlResp <- dispatchControl(config, state, q, req)
+				continue
+			}
+		case <-q.closeCh:
+			return types.ControlRequestInner{}, ErrQueryClosed
+		case <-ctx.Done():
+			return types.ControlRequestInner{}, ctx.Err()
+		}
+		q.controlResp <- types.ControlResponse{
+			Type:     "control_response",
+			Response: types.ControlSuccessResponse{RequestID: req.RequestID},
+		}
+		return req.Request, nil
+	}
+}
@@ -13,7 +13,7 @@ func DefaultRegistry(cwd string, mcpClient tools.MCPClient) *tools.Registry {
 
 	registry := tools.NewRegistry(
 		// Read-only tools are auto-allowed
-		tools.WithAllowed("Read", "Glob", "Grep", "ListMcpResources", "ReadMcpResource"),
+		tools.WithAllowed("Read", "Glob", "Grep", "ListMcpResources", "ReadMcpResource", "ListMcpPrompts", "GetMcpPrompt"),
 	)
 
 	// Core 6 (existing)
@@ -21,22 +21,25 @@ func DefaultRegistry(cwd string, mcpClient tools.MCPClient) *tools.Registry {
 	registry.Register(&tools.FileReadTool{})
 	registry.Register(&tools.FileWriteTool{})
 	registry.Register(&tools.FileEditTool{})
+	registry.Register(&tools.ApplyPatchTool{CWD: cwd})
 	registry.Register(&tools.GlobTool{CWD: cwd})
 	registry.Register(&tools.GrepTool{CWD: cwd})
 
 	// Background task tools
 	registry.Register(&tools.TaskOutputTool{TaskManager: tm})
 	registry.Register(&tools.TaskStopTool{TaskManager: tm})
+	registry.Register(&tools.ListTasksTool{TaskManager: tm})
+	registry.Register(&tools.KillTaskTool{TaskManager: tm})
 
 	// State management tools
 	registry.Register(&tools.TodoWriteTool{})
-	registry.Register(&tools.ConfigTool{Store: tools.NewInMemoryConfigStore()})
+	registry.Register(&tools.ConfigTool{Store: tools.NewInMemoryConfigStore()}) // Session settings take precedence in the agent loop
 	registry.Register(&tools.ExitPlanModeTool{})
 	registry.Register(&tools.AskUserQuestionTool{}) // Handler set by host app
 
 	// Network tools
 	registry.Register(&tools.WebFetchTool{})
-	registry.Register(&tools.WebSearchTool{}) // Provider set by host app
+	registry.Register(&tools.WebSearchTool{Provider: tools.SearchProviderFromEnv()}) // nil unless BRAVE_API_KEY/TAVILY_API_KEY set
 
 	// Subagent
 	registry.Register(&tools.AgentTool{}) // Spawner set by host app
@@ -44,9 +47,11 @@ func DefaultRegistry(cwd string, mcpClient tools.MCPClient) *tools.Registry {
 	// Skill
 	registry.Register(&tools.SkillTool{}) // Skills provider set by host app
 
-	// MCP resource tools (mcpClient may be nil → falls back to StubMCPClient)
+	// MCP resource and prompt tools (mcpClient may be nil → falls back to StubMCPClient)
 	registry.Register(&tools.ListMcpResourcesTool{Client: mcpClient})
 	registry.Register(&tools.ReadMcpResourceTool{Client: mcpClient})
+	registry.Register(&tools.ListMcpPromptsTool{Client: mcpClient})
+	registry.Register(&tools.GetMcpPromptTool{Client: mcpClient})
 	// Dynamic mcp__* tools registered at runtime by mcp.Client.Connect()
 
 	// NotebookEdit
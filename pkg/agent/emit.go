@@ -1,10 +1,17 @@
 package agent
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
@@ -22,6 +29,11 @@ func emitInit(ch chan<- types.SDKMessage, config *AgentConfig, state *LoopState)
 		slashCommands = config.Skills.SlashCommands()
 	}
 
+	permissionMode := config.PermissionMode
+	if permissionMode == "" {
+		permissionMode = types.PermissionModeDefault
+	}
+
 	msg := &types.SystemInitMessage{
 		BaseMessage:       types.BaseMessage{UUID: uuid.New(), SessionID: state.SessionID},
 		Type:              types.MessageTypeSystem,
@@ -29,14 +41,76 @@ func emitInit(ch chan<- types.SDKMessage, config *AgentConfig, state *LoopState)
 		Model:             config.Model,
 		ClaudeCodeVersion: "goat-0.1.0",
 		CWD:               config.CWD,
-		PermissionMode:    config.PermissionMode,
+		PermissionMode:    permissionMode,
 		Tools:             toolNames,
+		ToolDetails:       toolDetails(config, toolNames),
+		McpServers:        mcpServerInfo(config, toolNames),
 		Skills:            skillNames,
 		SlashCommands:     slashCommands,
+		RestoredMessages:  len(state.Messages),
+	}
+	if config.ToolRegistry != nil {
+		msg.DisabledTools = config.ToolRegistry.DisabledNames()
 	}
 	ch <- msg
 }
 
+// toolDetails describes each of the named registry tools for the init message.
+func toolDetails(config *AgentConfig, names []string) []types.ToolInfo {
+	if len(names) == 0 {
+		return nil
+	}
+	details := make([]types.ToolInfo, 0, len(names))
+	for _, name := range names {
+		tool, ok := config.ToolRegistry.Get(name)
+		if !ok {
+			continue
+		}
+		info := types.ToolInfo{Name: name, SideEffect: tool.SideEffect().String()}
+		if mcpTool, ok := tool.(*tools.MCPTool); ok {
+			info.McpServer = mcpTool.ServerName
+		}
+		details = append(details, info)
+	}
+	return details
+}
+
+// mcpServerInfo lists the configured MCP servers, and any others tools are
+// registered from, with how many of those tools are enabled. Statuses come
+// from config.MCPStatus; without it a server counts as connected once it
+// has tools and pending before.
+func mcpServerInfo(config *AgentConfig, toolNames []string) []types.McpServerInfo {
+	counts := make(map[string]int)
+	for _, name := range toolNames {
+		if tool, ok := config.ToolRegistry.Get(name); ok {
+			if mcpTool, ok := tool.(*tools.MCPTool); ok {
+				counts[mcpTool.ServerName]++
+			}
+		}
+	}
+
+	servers := make(map[string]types.McpServerInfo)
+	for name := range config.MCPServers {
+		servers[name] = types.McpServerInfo{Name: name, Status: "pending"}
+	}
+	for name := range counts {
+		servers[name] = types.McpServerInfo{Name: name, Status: "connected"}
+	}
+	if config.MCPStatus != nil {
+		for _, s := range config.MCPStatus.McpServerStatus() {
+			servers[s.Name] = s
+		}
+	}
+
+	var infos []types.McpServerInfo
+	for _, name := range slices.Sorted(maps.Keys(servers)) {
+		info := servers[name]
+		info.Tools = counts[name]
+		infos = append(infos, info)
+	}
+	return infos
+}
+
 // emitAssistant sends an AssistantMessage after LLM response accumulation.
 func emitAssistant(ch chan<- types.SDKMessage, resp *llm.CompletionResponse, state *LoopState) {
 	msg := llm.EmitAssistantMessage(resp, nil, state.SessionID, nil)
@@ -49,6 +123,12 @@ func emitStreamEvent(ch chan<- types.SDKMessage, chunk *llm.StreamChunk, state *
 	ch <- msg
 }
 
+// emitPartialSnapshot sends a PartialAssistantMessage carrying the message assembled so far.
+func emitPartialSnapshot(ch chan<- types.SDKMessage, assembler *llm.PartialAssembler, index int, state *LoopState) {
+	msg := llm.EmitPartialSnapshot(assembler, index, nil, state.SessionID)
+	ch <- msg
+}
+
 // emitToolProgress sends a ToolProgressMessage for tool execution tracking.
 func emitToolProgress(ch chan<- types.SDKMessage, toolName, toolUseID string, elapsed float64, state *LoopState) {
 	msg := &types.ToolProgressMessage{
@@ -61,12 +141,36 @@ func emitToolProgress(ch chan<- types.SDKMessage, toolName, toolUseID string, el
 	ch <- msg
 }
 
+// emitToolTimeout sends a ToolProgressMessage marking the tool as timed out.
+func emitToolTimeout(ch chan<- types.SDKMessage, toolName, toolUseID string, elapsed float64, state *LoopState) {
+	ch <- &types.ToolProgressMessage{
+		BaseMessage:        types.BaseMessage{UUID: uuid.New(), SessionID: state.SessionID},
+		Type:               types.MessageTypeToolProgress,
+		ToolUseID:          toolUseID,
+		ToolName:           toolName,
+		ElapsedTimeSeconds: elapsed,
+		TimedOut:           true,
+	}
+}
+
+// emitToolDuplicate sends a ToolProgressMessage noting that a repeated tool
+// call was collapsed into an earlier one.
+func emitToolDuplicate(ch chan<- types.SDKMessage, toolName, toolUseID, originalID string, state *LoopState) {
+	ch <- &types.ToolProgressMessage{
+		BaseMessage: types.BaseMessage{UUID: uuid.New(), SessionID: state.SessionID},
+		Type:        types.MessageTypeToolProgress,
+		ToolUseID:   toolUseID,
+		ToolName:    toolName,
+		DuplicateOf: originalID,
+	}
+}
+
 // buildModelUsage creates a per-model usage map from the CostTracker.
-func buildModelUsage(ct *llm.CostTracker) map[string]types.ModelUsage {
-	if ct == nil {
+func buildModelUsage(config *AgentConfig) map[string]types.ModelUsage {
+	if config.CostTracker == nil {
 		return nil
 	}
-	breakdown := ct.ModelBreakdown()
+	breakdown := config.CostTracker.ModelBreakdown()
 	if len(breakdown) == 0 {
 		return nil
 	}
@@ -78,6 +182,7 @@ func buildModelUsage(ct *llm.CostTracker) map[string]types.ModelUsage {
 			CacheReadInputTokens:     accum.CacheReadInputTokens,
 			CacheCreationInputTokens: accum.CacheCreationInputTokens,
 			CostUSD:                  accum.CostUSD,
+			BudgetUSD:                config.ModelBudgets[model],
 		}
 	}
 	return modelUsage
@@ -88,11 +193,12 @@ func emitTurnResult(ch chan<- types.SDKMessage, config *AgentConfig, state *Loop
 	duration := time.Since(startTime).Milliseconds()
 	apiMs := apiDuration.Milliseconds()
 	result := extractLastTextContent(state)
-	modelUsage := buildModelUsage(config.CostTracker)
+	modelUsage := buildModelUsage(config)
 	msg := types.NewResultSuccess(result, state.TurnCount, state.TotalCostUSD,
 		state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
 	// Mark as a turn result (not final) by setting subtype
 	msg.Subtype = types.ResultSubtypeSuccessTurn
+	msg.StructuredOutput = state.StructuredOutput
 	ch <- msg
 }
 
@@ -100,7 +206,7 @@ func emitTurnResult(ch chan<- types.SDKMessage, config *AgentConfig, state *Loop
 func emitResult(ch chan<- types.SDKMessage, config *AgentConfig, state *LoopState, startTime time.Time, apiDuration time.Duration) {
 	duration := time.Since(startTime).Milliseconds()
 	apiMs := apiDuration.Milliseconds()
-	modelUsage := buildModelUsage(config.CostTracker)
+	modelUsage := buildModelUsage(config)
 
 	// Determine result subtype from exit reason
 	switch state.ExitReason {
@@ -109,18 +215,42 @@ func emitResult(ch chan<- types.SDKMessage, config *AgentConfig, state *LoopStat
 		result := extractLastTextContent(state)
 		msg := types.NewResultSuccess(result, state.TurnCount, state.TotalCostUSD,
 			state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
+		msg.StructuredOutput = state.StructuredOutput
+		ch <- msg
+
+	case ExitStopSequence:
+		result := extractLastTextContent(state)
+		msg := types.NewResultSuccess(result, state.TurnCount, state.TotalCostUSD,
+			state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
+		stopReason, stopSequence := string(ExitStopSequence), state.StopSequence
+		msg.StopReason = &stopReason
+		msg.StopSequence = &stopSequence
 		ch <- msg
 
 	case ExitMaxTurns:
 		msg := types.NewResultError(types.ResultSubtypeErrorMaxTurns,
 			[]string{"max turns reached"}, state.TurnCount, state.TotalCostUSD,
 			state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
+		msg.ErrorDetails = resultErrors(state)
 		ch <- msg
 
 	case ExitMaxBudget:
+		errMsgs := []string{"max budget exceeded"}
+		if model := state.ModelBudgetExceeded; model != "" {
+			errMsgs = append(errMsgs, fmt.Sprintf("model %s exceeded its budget: $%.4f of $%.4f",
+				model, modelUsage[model].CostUSD, config.ModelBudgets[model]))
+		}
 		msg := types.NewResultError(types.ResultSubtypeErrorMaxBudget,
-			[]string{"max budget exceeded"}, state.TurnCount, state.TotalCostUSD,
+			errMsgs, state.TurnCount, state.TotalCostUSD,
+			state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
+		msg.ErrorDetails = resultErrors(state)
+		ch <- msg
+
+	case ExitMaxStructuredRetries:
+		msg := types.NewResultError(types.ResultSubtypeErrorMaxStructuredRetries,
+			[]string{"structured output did not match the schema: " + state.LastError.Error()}, state.TurnCount, state.TotalCostUSD,
 			state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
+		msg.ErrorDetails = resultErrors(state)
 		ch <- msg
 
 	default:
@@ -131,10 +261,81 @@ func emitResult(ch chan<- types.SDKMessage, config *AgentConfig, state *LoopStat
 		msg := types.NewResultError(types.ResultSubtypeErrorDuringExecution,
 			errMsgs, state.TurnCount, state.TotalCostUSD,
 			state.TotalUsage, modelUsage, duration, apiMs, state.SessionID)
+		msg.ErrorDetails = resultErrors(state)
 		ch <- msg
 	}
 }
 
+// errToolInterrupt is the LastError of a run stopped by a permission check
+// that interrupted a tool call.
+var errToolInterrupt = errors.New("a permission check interrupted tool execution")
+
+// resultErrors classifies why the run ended for ResultMessage.ErrorDetails.
+func resultErrors(state *LoopState) []types.ResultError {
+	detail := func(code types.ResultErrorCode, message string) []types.ResultError {
+		return []types.ResultError{{Code: code, Message: message}}
+	}
+	switch state.ExitReason {
+	case ExitMaxTurns:
+		return detail(types.ResultErrorMaxTurns, "max turns reached")
+	case ExitMaxBudget:
+		return detail(types.ResultErrorBudget, "max budget exceeded")
+	case ExitMaxStructuredRetries:
+		return detail(types.ResultErrorStructuredOutput, state.LastError.Error())
+	case ExitMaxTokens:
+		return detail(types.ResultErrorMaxTokens, "the response hit max_tokens and the context could not be compacted")
+	case ExitSessionRestore:
+		return detail(types.ResultErrorSessionRestore, state.LastError.Error())
+	case ExitToolLoop:
+		return detail(types.ResultErrorToolError, state.LastError.Error())
+	case ExitInterrupted, ExitAborted:
+		if errors.Is(state.LastError, errToolInterrupt) {
+			return detail(types.ResultErrorToolError, errToolInterrupt.Error())
+		}
+		return detail(types.ResultErrorAborted, string(state.ExitReason))
+	}
+	if state.LastError == nil {
+		return detail(types.ResultErrorUnknown, string(state.ExitReason))
+	}
+	return []types.ResultError{classifyRunError(state.LastError)}
+}
+
+// classifyRunError classifies the LLM call failure that ended a run.
+func classifyRunError(err error) types.ResultError {
+	re := types.ResultError{Code: types.ResultErrorUnknown, Message: err.Error()}
+	var llmErr *llm.LLMError
+	var retries *llm.ErrMaxRetriesExceeded
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		re.Code = types.ResultErrorAborted
+	case errors.As(err, &llmErr):
+		re.Code, re.Retriable = types.ResultErrorCodeFor(types.AssistantError(llmErr.SDKError))
+		if re.Code == types.ResultErrorInvalidRequest && isContextOverflow(llmErr.Message) {
+			re.Code = types.ResultErrorContextOverflow
+		}
+	case errors.As(err, &retries):
+		re.Code, re.Retriable = types.ResultErrorServerError, true
+		if retries.LastStatus == 429 || retries.LastStatus == 529 {
+			re.Code = types.ResultErrorRateLimit
+		}
+	default:
+		re.Retriable = isRetriableModelError(err)
+	}
+	return re
+}
+
+// isContextOverflow reports whether a provider's invalid-request message
+// says the prompt exceeded the model's context window.
+func isContextOverflow(message string) bool {
+	message = strings.ToLower(message)
+	for _, s := range []string{"prompt is too long", "context_length_exceeded", "maximum context length", "context window"} {
+		if strings.Contains(message, s) {
+			return true
+		}
+	}
+	return false
+}
+
 // extractLastTextContent gets the text content from the last assistant message in state.
 func extractLastTextContent(state *LoopState) string {
 	// Walk backward to find the last assistant message with text content
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+type stubMCPStatus []types.McpServerInfo
+
+func (s stubMCPStatus) McpServerStatus() []types.McpServerInfo { return s }
+
+func initMessage(t *testing.T, config AgentConfig) *types.SystemInitMessage {
+	t.Helper()
+	q := RunLoop(context.Background(), "hi", config)
+	msgs := collectMessages(q)
+	q.Wait()
+	for _, m := range msgs {
+		if im, ok := m.(*types.SystemInitMessage); ok {
+			return im
+		}
+	}
+	t.Fatal("no SystemInitMessage")
+	return nil
+}
+
+func TestEmitInit_Capabilities(t *testing.T) {
+	registry := tools.NewRegistry(tools.WithDisabled("Bash", "mcp__docs__delete"))
+	registry.Register(&tools.FileReadTool{})
+	registry.Register(&tools.BashTool{})
+	registry.Register(&tools.MCPTool{ServerName: "docs", ToolName: "search"})
+	registry.Register(&tools.MCPTool{ServerName: "docs", ToolName: "delete"})
+	registry.Register(&tools.MCPTool{ServerName: "github", ToolName: "issues"})
+
+	config := defaultConfig(&mockLLMClient{responses: []*mockStream{endTurnResponse("ok")}}, registry)
+	config.PermissionMode = ""
+	config.MCPServers = map[string]types.McpServerConfig{
+		"docs":   {Type: "stdio", Command: "docs-server"},
+		"github": {Type: "stdio", Command: "gh-server"},
+		"slack":  {Type: "stdio", Command: "slack-server"},
+	}
+
+	init := initMessage(t, config)
+	if init.PermissionMode != types.PermissionModeDefault {
+		t.Errorf("PermissionMode = %q, want %q", init.PermissionMode, types.PermissionModeDefault)
+	}
+	wantTools := []types.ToolInfo{
+		{Name: "Read", SideEffect: "none"},
+		{Name: "mcp__docs__search", SideEffect: "network", McpServer: "docs"},
+		{Name: "mcp__github__issues", SideEffect: "network", McpServer: "github"},
+	}
+	if !reflect.DeepEqual(init.ToolDetails, wantTools) {
+		t.Errorf("ToolDetails = %+v, want %+v", init.ToolDetails, wantTools)
+	}
+	if want := []string{"Bash", "mcp__docs__delete"}; !reflect.DeepEqual(init.DisabledTools, want) {
+		t.Errorf("DisabledTools = %v, want %v", init.DisabledTools, want)
+	}
+	wantServers := []types.McpServerInfo{
+		{Name: "docs", Status: "connected", Tools: 1},
+		{Name: "github", Status: "connected", Tools: 1},
+		{Name: "slack", Status: "pending"},
+	}
+	if !reflect.DeepEqual(init.McpServers, wantServers) {
+		t.Errorf("McpServers = %+v, want %+v", init.McpServers, wantServers)
+	}
+
+	// A status provider supplies the real connection states.
+	config.LLMClient = &mockLLMClient{responses: []*mockStream{endTurnResponse("ok")}}
+	config.MCPStatus = stubMCPStatus{
+		{Name: "docs", Status: "connected", Tools: 2},
+		{Name: "slack", Status: "failed", Error: "connection refused"},
+	}
+	init = initMessage(t, config)
+	wantServers = []types.McpServerInfo{
+		{Name: "docs", Status: "connected", Tools: 1},
+		{Name: "github", Status: "connected", Tools: 1},
+		{Name: "slack", Status: "failed", Error: "connection refused"},
+	}
+	if !reflect.DeepEqual(init.McpServers, wantServers) {
+		t.Errorf("McpServers with provider = %+v, want %+v", init.McpServers, wantServers)
+	}
+}
@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// defaultToolHeartbeatInterval is how often a running tool's progress is
+// re-emitted when ToolHeartbeatInterval is unset.
+const defaultToolHeartbeatInterval = 5 * time.Second
+
+// toolHeartbeat emits ToolProgressMessages for a running tool: one per
+// interval with the elapsed time, plus any messages the tool reports. A tool
+// that finishes within the interval emits none. After stop, nothing more is
+// sent, even if the tool keeps reporting from another goroutine.
+type toolHeartbeat struct {
+	ch        chan<- types.SDKMessage
+	toolName  string
+	toolUseID string
+	sessionID string
+	start     time.Time
+
+	mu       sync.Mutex // serializes sends with stop
+	stopped  bool
+	done     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// startToolHeartbeat begins heartbeats for a tool started at start. A
+// non-positive interval disables the periodic messages but still relays
+// reported progress.
+func startToolHeartbeat(ch chan<- types.SDKMessage, toolName, toolUseID string, start time.Time, interval time.Duration, state *LoopState) *toolHeartbeat {
+	h := &toolHeartbeat{
+		ch:        ch,
+		toolName:  toolName,
+		toolUseID: toolUseID,
+		sessionID: state.SessionID,
+		start:     start,
+		done:      make(chan struct{}),
+	}
+	if interval > 0 {
+		h.wg.Add(1)
+		go h.run(interval)
+	}
+	return h
+}
+
+func (h *toolHeartbeat) run(interval time.Duration) {
+	defer h.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.done:
+			return
+		case <-ticker.C:
+			h.emit("")
+		}
+	}
+}
+
+// report relays an intermediate message from a ProgressReporter tool.
+func (h *toolHeartbeat) report(message string) {
+	h.emit(message)
+}
+
+func (h *toolHeartbeat) emit(message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return
+	}
+	msg := &types.ToolProgressMessage{
+		BaseMessage:        types.BaseMessage{UUID: uuid.New(), SessionID: h.sessionID},
+		Type:               types.MessageTypeToolProgress,
+		ToolUseID:          h.toolUseID,
+		ToolName:           h.toolName,
+		ElapsedTimeSeconds: time.Since(h.start).Seconds(),
+		Message:            message,
+	}
+	select {
+	case h.ch <- msg:
+	case <-h.done:
+	}
+}
+
+// stop ends the heartbeats and waits for any in-flight send. Safe to call
+// more than once.
+func (h *toolHeartbeat) stop() {
+	h.stopOnce.Do(func() {
+		close(h.done)
+		h.mu.Lock()
+		h.stopped = true
+		h.mu.Unlock()
+		h.wg.Wait()
+	})
+}
+
+// toolHeartbeatInterval resolves the configured heartbeat interval.
+func toolHeartbeatInterval(config *AgentConfig) time.Duration {
+	if config.ToolHeartbeatInterval == 0 {
+		return defaultToolHeartbeatInterval
+	}
+	return config.ToolHeartbeatInterval
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// reportingTool reports a status message, then runs for delay.
+type reportingTool struct {
+	mockRecordingTool
+	delay  time.Duration
+	report func(string)
+}
+
+func (r *reportingTool) ExecuteWithProgress(ctx context.Context, input map[string]any, report func(string)) (tools.ToolOutput, error) {
+	r.report = report
+	report("downloaded 40%")
+	select {
+	case <-time.After(r.delay):
+	case <-ctx.Done():
+		return tools.ToolOutput{}, ctx.Err()
+	}
+	return r.Execute(ctx, input)
+}
+
+func progressMessages(ch chan types.SDKMessage) []*types.ToolProgressMessage {
+	close(ch)
+	var out []*types.ToolProgressMessage
+	for m := range ch {
+		if p, ok := m.(*types.ToolProgressMessage); ok {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func TestRunTool_Heartbeats(t *testing.T) {
+	tool := &reportingTool{mockRecordingTool: mockRecordingTool{name: "Fetch", output: tools.ToolOutput{Content: "ok"}}, delay: 120 * time.Millisecond}
+	config := &AgentConfig{ToolHeartbeatInterval: 25 * time.Millisecond}
+	ch := make(chan types.SDKMessage, 100)
+	if _, err := runTool(context.Background(), tool, "tc1", nil, config, ch, &LoopState{}); err != nil {
+		t.Fatal(err)
+	}
+	tool.report("after return") // dropped: the call is over
+
+	msgs := progressMessages(ch)
+	if len(msgs) < 4 {
+		t.Fatalf("got %d progress messages, want start, report, heartbeats and end", len(msgs))
+	}
+	if msgs[0].ElapsedTimeSeconds != 0 || msgs[1].Message != "downloaded 40%" {
+		t.Errorf("first messages = %+v, %+v", msgs[0], msgs[1])
+	}
+	heartbeats := 0
+	for _, m := range msgs[2 : len(msgs)-1] {
+		if m.Message != "" {
+			t.Errorf("unexpected message %q", m.Message)
+		}
+		heartbeats++
+	}
+	if heartbeats < 2 {
+		t.Errorf("got %d heartbeats over 120ms at 25ms, want several", heartbeats)
+	}
+	for i := 1; i < len(msgs); i++ {
+		if msgs[i].ElapsedTimeSeconds < msgs[i-1].ElapsedTimeSeconds {
+			t.Errorf("elapsed went backwards at %d: %v < %v", i, msgs[i].ElapsedTimeSeconds, msgs[i-1].ElapsedTimeSeconds)
+		}
+	}
+}
+
+func TestRunTool_NoHeartbeatsForFastTools(t *testing.T) {
+	tool := &mockRecordingTool{name: "Read", output: tools.ToolOutput{Content: "ok"}}
+	ch := make(chan types.SDKMessage, 100)
+	if _, err := runTool(context.Background(), tool, "tc1", nil, &AgentConfig{}, ch, &LoopState{}); err != nil {
+		t.Fatal(err)
+	}
+	if msgs := progressMessages(ch); len(msgs) != 2 {
+		t.Errorf("got %d progress messages for a fast tool, want start and end only", len(msgs))
+	}
+}
+
+func TestRunTool_HeartbeatsStopOnCancel(t *testing.T) {
+	tool := &reportingTool{mockRecordingTool: mockRecordingTool{name: "Fetch"}, delay: time.Minute}
+	config := &AgentConfig{ToolHeartbeatInterval: 10 * time.Millisecond, DefaultToolTimeout: time.Minute}
+	ch := make(chan types.SDKMessage, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := runTool(ctx, tool, "tc1", nil, config, ch, &LoopState{}); err == nil {
+		t.Fatal("expected cancellation error")
+	}
+	sent := len(ch)
+	time.Sleep(50 * time.Millisecond)
+	if len(ch) != sent {
+		t.Errorf("heartbeats continued after cancel: %d → %d messages", sent, len(ch))
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
@@ -12,7 +13,7 @@ import (
 type TokenBudget struct {
 	ContextLimit     int // model's total context window
 	SystemPromptTkns int // estimated system prompt tokens
-	MaxOutputTkns    int // reserved for output (default 16384)
+	MaxOutputTkns    int // reserved for output (the call's max_tokens, default 16384)
 	MessageTkns      int // current message history tokens
 }
 
@@ -40,14 +41,15 @@ func (b TokenBudget) Available() int {
 }
 
 // CompactRequest is the input to a Compact call.
+// PreCompact hooks and the CompactBoundaryMessage are handled by the loop.
 type CompactRequest struct {
-	Messages   []llm.ChatMessage
-	Model      string
-	Budget     TokenBudget
-	Trigger    string // "auto" | "manual"
-	SessionID  string
-	SessionDir string // session directory for session-memory lookup
-	EmitCh     chan<- types.SDKMessage
+	Messages           []llm.ChatMessage
+	Model              string
+	Budget             TokenBudget
+	Trigger            string // "auto" | "manual"
+	SessionID          string
+	SessionDir         string // session directory for session-memory lookup
+	CustomInstructions string // summary guidance from PreCompact hooks
 }
 
 // SystemPromptAssembler builds the system prompt for an LLM call.
@@ -55,6 +57,45 @@ type SystemPromptAssembler interface {
 	Assemble(config *AgentConfig) string
 }
 
+// GitContextProvider reports the state of the git repository containing a
+// directory, for the system prompt's git status section. prompt.GitContext is
+// the standard implementation.
+type GitContextProvider interface {
+	// GitContext returns the repository state at cwd; ok is false when cwd
+	// is not inside a git repository.
+	GitContext(ctx context.Context, cwd string) (info GitContext, ok bool, err error)
+}
+
+// GitContext is a snapshot of a repository's state, sized for a prompt.
+type GitContext struct {
+	Branch        string
+	MainBranch    string
+	Status        string // short status, one changed path per line ("" = clean)
+	RecentCommits string // one commit per line, newest first
+}
+
+// WebResourceStore is implemented by SessionStores that can keep WebFetch
+// and WebSearch results with a session, so that repeated requests, also
+// after the session is resumed, are served the stored copy. The loop hands
+// it to those tools when AgentConfig.SessionStore implements it.
+type WebResourceStore interface {
+	LoadWebResource(sessionID, kind, key string) (tools.WebResource, bool, error)
+	SaveWebResource(sessionID string, res tools.WebResource) error
+}
+
+// MCPStatusProvider reports the state of MCP server connections for the
+// init message. mcp.Client is the standard implementation.
+type MCPStatusProvider interface {
+	McpServerStatus() []types.McpServerInfo
+}
+
+// Redactor scrubs secrets from text, for messages that are persisted or
+// emitted. tools.SecretRedactor is the standard implementation.
+type Redactor interface {
+	// Redact returns s with secrets replaced, and whether any were found.
+	Redact(s string) (string, bool)
+}
+
 // PermissionChecker gates tool execution.
 type PermissionChecker interface {
 	Check(ctx context.Context, toolName string, input map[string]any) (PermissionResult, error)
@@ -87,12 +128,45 @@ type HookResult struct {
 	HookSpecificOutput any // typed per-event output
 }
 
-// ContextCompactor handles context overflow.
+// ContextCompactor handles context overflow. It is not consulted while
+// AgentConfig.HistoryWindow is set.
 type ContextCompactor interface {
 	ShouldCompact(budget TokenBudget) bool
 	Compact(ctx context.Context, req CompactRequest) ([]llm.ChatMessage, error)
 }
 
+// BackgroundWork is work that can outlive the tool call that started it,
+// such as background Bash tasks or subagents. StopAll cancels all of it and
+// waits, bounded by ctx, for it to exit.
+type BackgroundWork interface {
+	StopAll(ctx context.Context) error
+}
+
+// ModelRouter chooses the model for the next LLM call. It is called before
+// every turn; returning "" or the current model keeps the model unchanged.
+type ModelRouter interface {
+	SelectModel(state *LoopState) string
+}
+
+// Metrics receives observability callbacks from the loop. Implementations
+// must be safe for concurrent use, since tools may run in parallel.
+type Metrics interface {
+	// RecordLLMCall is called after each LLM response (one turn).
+	RecordLLMCall(model string, usage types.BetaUsage, costUSD float64)
+	// RecordToolCall is called after each tool execution. costUSD is the
+	// tool's share of the cost of the LLM call that requested it.
+	RecordToolCall(tool, model string, duration time.Duration, isError bool, costUSD float64)
+	// RecordCompaction is called after the history has been compacted.
+	RecordCompaction(model, trigger string)
+	// RecordExit is called once when the loop terminates.
+	RecordExit(model string, reason ExitReason, turns int)
+}
+
+// TokenCounter counts tokens in text for context budget calculations.
+type TokenCounter interface {
+	CountTokens(text string) int
+}
+
 // SkillProvider gives access to the loaded skill registry.
 type SkillProvider interface {
 	GetSkill(name string) (types.SkillEntry, bool)
@@ -110,7 +184,13 @@ type SessionStore interface {
 	LoadLatest(cwd string) (*SessionState, error)
 	Delete(sessionID string) error
 	List() ([]SessionMetadata, error)
+	// ListSessions returns the sessions matching filter, in its sort order.
+	ListSessions(filter SessionFilter) ([]SessionMetadata, error)
 	Fork(sourceID, newID string) (*SessionState, error)
+	// ForkAt branches a new session from sourceID at messageUUID, keeping
+	// the messages up to it and replacing it with replacement if non-nil.
+	// The cut is moved as needed to keep tool calls paired with results.
+	ForkAt(sourceID, messageUUID string, replacement *llm.ChatMessage) (*SessionState, error)
 
 	// Message persistence (async-safe)
 	AppendMessage(sessionID string, entry MessageEntry) error
@@ -124,6 +204,9 @@ type SessionStore interface {
 	// Checkpoint management
 	CreateCheckpoint(sessionID, userMsgUUID string, filePaths []string) error
 	RewindFiles(sessionID, userMsgUUID string, dryRun bool) (*RewindFilesResult, error)
+	// Rewind restores a checkpoint's files and truncates the message log to
+	// just before its user message, returning the remaining messages.
+	Rewind(sessionID, checkpointID string) ([]MessageEntry, error)
 
 	// Lifecycle
 	Close() error // flush async writer, close files
@@ -141,10 +224,19 @@ type SessionMetadata struct {
 	MessageCount     int       `json:"message_count"`
 	TurnCount        int       `json:"turn_count"`
 	TotalCostUSD     float64   `json:"total_cost_usd"`
+	InputTokens      int       `json:"input_tokens,omitempty"`
+	OutputTokens     int       `json:"output_tokens,omitempty"`
 	LeafTitle        string    `json:"leaf_title,omitempty"`
 	ProjectHash      string    `json:"project_hash,omitempty"`
 	ExitReason       string    `json:"exit_reason,omitempty"`
 	AgentName        string    `json:"agent_name,omitempty"`
+
+	// Incomplete is set while a run is using the session and cleared when
+	// it exits cleanly; a session left Incomplete was cut off by a crash.
+	Incomplete bool `json:"incomplete,omitempty"`
+
+	// Todos is the TodoWrite list, saved with AgentConfig.TodoContinuity.
+	Todos []tools.TodoItem `json:"todos,omitempty"`
 }
 
 // SessionState is the loaded form of a session: metadata + messages.
@@ -158,6 +250,13 @@ type MessageEntry struct {
 	UUID      string          `json:"uuid"`
 	Timestamp time.Time       `json:"timestamp"`
 	Message   llm.ChatMessage `json:"message"`
+	// Thinking holds the assistant's reasoning for this turn. It is kept for
+	// transcripts only and never sent back to the model.
+	Thinking string `json:"thinking,omitempty"`
+	// IsReplay marks entries re-appended from a parent session on resume.
+	IsReplay bool `json:"isReplay,omitempty"`
+	// Redacted lists the fields AgentConfig.Redactor scrubbed secrets from.
+	Redacted []string `json:"redacted,omitempty"`
 }
 
 // RewindFilesResult describes the outcome of a file rewind operation.
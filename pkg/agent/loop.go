@@ -2,6 +2,10 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
 	"strings"
 	"time"
 
@@ -10,25 +14,40 @@ import (
 	"github.com/jg-phare/goat/pkg/types"
 )
 
+// rateLimitNoticeThreshold is the shortest rate limiter delay of an LLM call
+// that is reported with a rate_limited status message.
+const rateLimitNoticeThreshold = time.Second
+
 // RunLoop starts an agentic loop and returns a Query for observing/controlling it.
 // The loop runs in a background goroutine and emits SDKMessages on the Query's channel.
 func RunLoop(ctx context.Context, prompt string, config AgentConfig) *Query {
 	loopCtx, cancel := context.WithCancel(ctx)
 	ch := make(chan types.SDKMessage, 64)
+	out := make(chan types.SDKMessage, 64)
 
 	state := &LoopState{
 		SessionID: config.SessionID,
+		toolCalls: &toolCallLog{},
 	}
 	if state.SessionID == "" {
 		state.SessionID = uuid.New().String()
 	}
+	if config.Redactor != nil && config.SessionStore != nil {
+		config.SessionStore = &redactingStore{SessionStore: config.SessionStore, redactor: config.Redactor}
+	}
 
 	q := &Query{
-		messages:    ch,
+		messages:    out,
 		done:        make(chan struct{}),
 		state:       state,
 		costTracker: config.CostTracker,
 		cancel:      cancel,
+
+		background:   config.Background,
+		sessionStore: config.SessionStore,
+		redactor:     config.Redactor,
+
+		keepTranscript: config.RecordTranscript,
 	}
 
 	// Set up multi-turn channels if enabled
@@ -39,6 +58,7 @@ func RunLoop(ctx context.Context, prompt string, config AgentConfig) *Query {
 		q.closeCh = make(chan struct{})
 	}
 
+	go q.recordTranscript(ch, out)
 	go runLoop(loopCtx, prompt, &config, state, ch, q)
 
 	return q
@@ -47,36 +67,63 @@ func RunLoop(ctx context.Context, prompt string, config AgentConfig) *Query {
 func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, q *Query) {
 	defer close(ch)
 	defer close(q.done)
+	defer q.closeBackground()
 
 	startTime := time.Now()
 	var apiDuration time.Duration
+	cancelTurn := context.CancelFunc(func() {}) // releases the current turn's deadline
+	defer func() { cancelTurn() }()
+
+	if len(config.ReplaySession) > 0 {
+		replaySession(ctx, config, state, ch)
+		return
+	}
 
 	// 0. Session restore/create (if SessionStore is configured)
-	if config.SessionStore != nil {
-		initializeSession(config, state)
+	if err := initializeSession(config, state); err != nil {
+		state.ExitReason = ExitSessionRestore
+		state.LastError = err
+		emitInit(ch, config, state)
+		emitResult(ch, config, state, startTime, 0)
+		return
 	}
+	restored := len(state.Messages) > 0
+
+	// 0.5 Root span for the run; tools and subagents nest under it via ctx
+	ctx, loopSpan := StartSpan(ctx, config.TracerProvider, "agent.loop",
+		AttrSessionID.String(state.SessionID), AttrModel.String(currentModel(config, state)))
+	ctx = withUserInput(ctx, config, state, q)
+	ctx = withConfigStore(ctx, config, state, q)
+	ctx = withPermissionAsker(ctx, config, state, q)
+	ctx = withWebCache(ctx, config, state)
 
 	// 1. Fire SessionStart hook and collect additional context
+	source := "startup"
+	if restored {
+		source = "resume"
+	}
 	sessionStartResults, _ := config.Hooks.Fire(ctx, types.HookEventSessionStart, map[string]any{
-		"source": "startup",
+		"source": source,
 	})
 	collectAdditionalContext(state, sessionStartResults)
 
 	// 2. Emit system init message
 	emitInit(ch, config, state)
 
-	// 3. Build initial messages (unless restored from session)
-	if len(state.Messages) == 0 {
-		state.Messages = []llm.ChatMessage{
-			{Role: "user", Content: prompt},
-		}
-	}
-
-	// Persist initial user message
-	if config.SessionStore != nil && len(state.Messages) > 0 {
-		last := state.Messages[len(state.Messages)-1]
-		if last.Role == "user" {
-			persistMessage(config.SessionStore, state.SessionID, last)
+	// 3. Build initial messages: the prompt starts the conversation, or is
+	// appended to a restored one. A restored session with no prompt waits
+	// for input.
+	promptAccepted := !restored || prompt != ""
+	if promptAccepted {
+		state.appendMessages(llm.ChatMessage{Role: "user", Content: prompt})
+
+		// 3.1 Let UserPromptSubmit hooks inspect the prompt, then persist it
+		n := len(state.Messages)
+		if !firePromptSubmit(ctx, config, state, ch, prompt) {
+			state.Messages = state.Messages[:n-1]
+			promptAccepted = false
+		} else if config.SessionStore != nil {
+			state.startCheckpoint(persistMessage(config.SessionStore, state.SessionID, state.Messages[n-1]))
 		}
 	}
 
@@ -86,19 +133,26 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 		memTracker = NewSessionMemoryTracker(config.SessionDir, config.LLMClient, config.Prompter)
 	}
 
+	// 3.6 Snapshot git state for the prompt (best-effort; none is injected on failure)
+	if config.GitContextProvider != nil && config.GitBranch == "" && config.CWD != "" {
+		if git, ok, err := config.GitContextProvider.GitContext(ctx, config.CWD); ok && err == nil {
+			config.GitBranch = git.Branch
+			config.GitMainBranch = git.MainBranch
+			config.GitStatus = git.Status
+			config.GitRecentCommits = git.RecentCommits
+		}
+	}
+
 	// 4. Assemble system prompt
 	systemPrompt := config.Prompter.Assemble(config)
+	if config.OutputFormat != nil {
+		systemPrompt += "\n\n" + structuredOutputInstruction(config.OutputFormat)
+	}
 
-	// 4.5 Dynamic model selection (first turn only, based on prompt complexity)
-	if config.DynamicModelConfig != nil && state.Model == "" {
-		dmc := config.DynamicModelConfig
-		promptTokens := len(prompt) / 4 // rough estimate
-		if dmc.SimpleThresholdTokens > 0 && promptTokens < dmc.SimpleThresholdTokens && dmc.SimpleModel != "" {
-			state.Model = dmc.SimpleModel
-		} else if dmc.ComplexThresholdTokens > 0 && promptTokens > dmc.ComplexThresholdTokens && dmc.ComplexModel != "" {
-			state.Model = dmc.ComplexModel
-		}
-		// else: use config.Model (default)
+	// 4.6 A blocked initial prompt skips straight to waiting for the next one
+	if !promptAccepted && (!config.MultiTurn || !waitForInput(ctx, config, state, ch, q)) {
+		state.ExitReason = ExitEndTurn
+		goto done
 	}
 
 	// 5. Main loop
@@ -109,11 +163,20 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 		}
 
 		// Check termination conditions
+		emitBudgetWarnings(config, state, ch)
 		if reason := checkTermination(ctx, config, state); reason != "" {
 			state.ExitReason = reason
 			break
 		}
 
+		// 5.2 A fallback only lasts for the turn it served
+		if state.UsingFallback && !config.StickyFallback {
+			resetFallback(config, state, ch)
+		}
+
+		// 5.3 Per-turn model routing
+		routeModel(config, state, ch)
+
 		// 5.4 Budget-aware model downgrade
 		if config.BudgetDowngradeThreshold > 0 && config.MaxBudgetUSD > 0 &&
 			config.BudgetDowngradeModel != "" && !state.BudgetDowngraded {
@@ -129,22 +192,13 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 			go memTracker.Extract(ctx, state.Messages)
 		}
 
-		// 5.5 Proactive compaction check
+		// 5.5 Proactive compaction check (or windowing, which replaces it)
 		budget := calculateTokenBudget(config, state, systemPrompt)
-		if config.Compactor.ShouldCompact(budget) {
-			compacted, err := config.Compactor.Compact(ctx, CompactRequest{
-				Messages:   state.Messages,
-				Model:      config.Model,
-				Budget:     budget,
-				Trigger:    "auto",
-				SessionID:  state.SessionID,
-				SessionDir: config.SessionDir,
-				EmitCh:     ch,
-			})
-			if err == nil {
-				state.Messages = compacted
-			}
-			// On error, continue with uncompacted messages
+		if config.HistoryWindow != nil {
+			windowHistory(config, state, ch, systemPrompt, budget)
+		} else if config.Compactor.ShouldCompact(budget) {
+			// On error or veto, continue with uncompacted messages
+			compactHistory(ctx, config, state, ch, systemPrompt, budget, "auto")
 		}
 
 		// 6. Build completion request (inject pending additional context)
@@ -155,6 +209,7 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 			} else {
 				llmTools = config.ToolRegistry.LLMTools()
 			}
+			llmTools = scopeLLMTools(llmTools, state.ActiveSkill)
 		}
 
 		effectivePrompt := systemPrompt
@@ -177,21 +232,43 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 			maxThinkingTokens = *config.MaxThinkingTkns
 		}
 
-		req := llm.BuildCompletionRequest(
-			llm.ClientConfig{
-				Model:             model,
-				MaxTokens:         16384,
-				MaxThinkingTokens: maxThinkingTokens,
-			},
-			effectivePrompt,
-			state.Messages,
-			llmTools,
-			llm.LoopState{SessionID: state.SessionID},
-		)
+		buildRequest := func(model string) *llm.CompletionRequest {
+			return llm.BuildCompletionRequest(
+				llm.ClientConfig{
+					Model:             model,
+					MaxTokens:         maxOutputTokens(config, model),
+					MaxThinkingTokens: maxThinkingTokens,
+					ReasoningEffort:   config.ReasoningEffort,
+					StopSequences:     config.StopSequences,
+					ParallelToolCalls: config.ParallelToolCalls,
+					StrictTools:       config.StrictTools,
+				},
+				effectivePrompt,
+				state.Messages,
+				llmTools,
+				llm.LoopState{SessionID: state.SessionID},
+			)
+		}
+		req := buildRequest(model)
 
-		// 7. Call LLM
+		// 6.5 Per-turn deadline covering the LLM call and tool execution
+		cancelTurn()
+		turnCtx, cancel := turnContext(ctx, config)
+		cancelTurn = cancel
+
+		// 7. Call LLM (skipped if cancelled while preparing the request)
 		apiStart := time.Now()
-		stream, err := config.LLMClient.Complete(ctx, req)
+		llmCtx, llmSpan := StartSpan(turnCtx, config.TracerProvider, "llm.complete", AttrModel.String(req.Model))
+		llmCtx = llm.WithRateLimitNotify(llmCtx, func(wait time.Duration) {
+			if wait >= rateLimitNoticeThreshold {
+				ch <- types.NewRateLimitStatus(wait.Milliseconds(), state.SessionID)
+			}
+		})
+		var stream *llm.Stream
+		err := ctx.Err()
+		if err == nil {
+			stream, err = config.LLMClient.Complete(llmCtx, req)
+		}
 		if err != nil {
 			// Check if context was cancelled (interrupt/abort)
 			if ctx.Err() != nil {
@@ -202,36 +279,73 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 					state.ExitReason = ExitAborted
 				}
 				q.mu.Unlock()
+				endSpan(llmSpan, err)
 				break
 			}
-			// Try fallback model on retriable errors (once only)
-			if config.FallbackModel != "" && isRetriableModelError(err) && !state.UsingFallback {
-				state.UsingFallback = true
-				state.Model = config.FallbackModel
-				req = llm.BuildCompletionRequest(
-					llm.ClientConfig{Model: config.FallbackModel, MaxTokens: 16384, MaxThinkingTokens: maxThinkingTokens},
-					effectivePrompt, state.Messages, llmTools,
-					llm.LoopState{SessionID: state.SessionID},
-				)
-				stream, err = config.LLMClient.Complete(ctx, req)
+			if turnTimedOut(ctx, turnCtx) {
+				endSpan(llmSpan, err)
+				handleTurnTimeout(config, state, ch, q, nil, "llm")
+				continue
+			}
+			// Walk down the fallback chain on retriable errors, each model once
+			for _, fallback := range remainingFallbacks(config.FallbackModels, model) {
+				if !isRetriableModelError(err) || ctx.Err() != nil {
+					break
+				}
+				switchToFallback(state, ch, model, fallback)
+				model = fallback
+				req = buildRequest(model)
+				llmSpan.SetAttributes(AttrModel.String(req.Model))
+				if stream, err = config.LLMClient.Complete(llmCtx, req); err == nil {
+					break
+				}
 			}
 			if err != nil {
+				endSpan(llmSpan, err)
 				state.LastError = err
 				state.ExitReason = ExitReason("error")
 				break
 			}
 		}
 
-		// 8. Accumulate response with streaming callbacks
+		// 8. Accumulate response with streaming callbacks. The assembler also
+		// keeps the partial text if the turn times out mid-stream.
 		var onChunk func(*llm.StreamChunk)
-		if config.IncludePartial {
+		var assembler *llm.PartialAssembler
+		if config.AssembledPartials || config.TurnTimeout > 0 {
+			assembler = llm.NewPartialAssembler()
+		}
+		if config.AssembledPartials {
 			onChunk = func(chunk *llm.StreamChunk) {
-				emitStreamEvent(ch, chunk, state)
+				if index, changed := assembler.Add(chunk); changed {
+					emitPartialSnapshot(ch, assembler, index, state)
+				}
+			}
+		} else if config.IncludePartial || assembler != nil {
+			onChunk = func(chunk *llm.StreamChunk) {
+				if assembler != nil {
+					assembler.Add(chunk)
+				}
+				if config.IncludePartial {
+					emitStreamEvent(ch, chunk, state)
+				}
 			}
 		}
 
-		resp, err := stream.AccumulateWithCallback(onChunk)
+		// The requested model serves the turn until its chunks say otherwise
+		state.turnModel = model
+		meter := newUsageMeter(config, state)
+		resp, err := stream.AccumulateWithCallback(meter.observe(onChunk))
 		apiDuration += time.Since(apiStart)
+		if err == nil {
+			llmSpan.SetAttributes(
+				AttrModel.String(resp.Model),
+				AttrInputTokens.Int(resp.Usage.InputTokens),
+				AttrOutputTokens.Int(resp.Usage.OutputTokens),
+				AttrStopReason.String(resp.StopReason),
+			)
+		}
+		endSpan(llmSpan, err)
 
 		if err != nil {
 			if ctx.Err() != nil {
@@ -244,24 +358,50 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 				q.mu.Unlock()
 				break
 			}
+			if turnTimedOut(ctx, turnCtx) {
+				handleTurnTimeout(config, state, ch, q, assembler.Partial(), "llm")
+				continue
+			}
 			state.LastError = err
 			state.ExitReason = ExitReason("error")
 			break
 		}
 
+		// Attribute the turn to the model that served it; providers that
+		// omit the model in the response are charged as the one requested.
+		state.turnModel = resp.Model
+		if state.turnModel == "" {
+			state.turnModel = model
+		}
+
+		// 8.5 Small models often send tool arguments that are not quite JSON
+		if repairToolCallsEnabled(config, state.turnModel) {
+			repairToolBlocks(resp)
+		}
+
 		// 9. Update state
 		assistantMsg := responseToAssistantMessage(resp)
-		state.Messages = append(state.Messages, assistantMsg)
+		state.appendMessages(assistantMsg)
 
 		q.mu.Lock()
 		state.TurnCount++
 		state.addUsage(resp.Usage)
 		if config.CostTracker != nil {
-			state.TotalCostUSD = config.CostTracker.Add(resp.Model, resp.Usage)
+			state.TotalCostUSD = config.CostTracker.Add(state.turnModel, resp.Usage)
 		} else {
-			state.TotalCostUSD += llm.CalculateCost(resp.Model, resp.Usage)
+			state.TotalCostUSD += llm.CalculateCost(state.turnModel, resp.Usage)
 		}
 		q.mu.Unlock()
+		meter.finish(resp.Usage)
+
+		// 9.2 Record turn metrics
+		if config.Metrics != nil {
+			turnCost := llm.CalculateCost(state.turnModel, resp.Usage)
+			config.Metrics.RecordLLMCall(state.turnModel, resp.Usage, turnCost)
+			if n := len(extractToolUseBlocks(resp)); n > 0 {
+				state.toolCostShare = turnCost / float64(n)
+			}
+		}
 
 		// 9.5 Track tokens for session memory extraction
 		if memTracker != nil {
@@ -272,13 +412,22 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 		emitAssistant(ch, resp, state)
 
 		// 10.5 Persist assistant message
-		persistMessage(config.SessionStore, state.SessionID, assistantMsg)
+		persistEntry(config.SessionStore, state.SessionID, MessageEntry{
+			Message:  assistantMsg,
+			Thinking: responseThinking(resp),
+		})
+		autoSaveSession(config, state)
 
 		// 11. Check stop reason
 		switch resp.StopReason {
 		case "end_turn":
 			state.ActiveSkill = nil // clear skill scope on turn end
 
+			// A correction that arrived during the final call redirects the turn
+			if injectSteering(ctx, config, state, ch, q) {
+				continue
+			}
+
 			// Fire Stop hook and check if any hook wants to continue
 			stopResults, _ := config.Hooks.Fire(ctx, types.HookEventStop, nil)
 			if shouldContinue(stopResults) {
@@ -286,6 +435,25 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 				continue
 			}
 
+			// Structured output: send an invalid answer back for correction
+			if config.OutputFormat != nil {
+				output, err := parseStructuredOutput(extractLastTextContent(state), config.OutputFormat.Schema)
+				if err != nil {
+					if state.structuredRetries >= maxStructuredOutputRetries(config) {
+						state.LastError = err
+						state.ExitReason = ExitMaxStructuredRetries
+						goto done
+					}
+					state.structuredRetries++
+					retry := llm.ChatMessage{Role: "user", Content: structuredRetryPrompt(err)}
+					state.appendMessages(retry)
+					persistMessage(config.SessionStore, state.SessionID, retry)
+					continue
+				}
+				state.StructuredOutput = output
+				state.structuredRetries = 0
+			}
+
 			if config.MultiTurn {
 				// Multi-turn: emit per-turn result, then wait for more input
 				emitTurnResult(ch, config, state, startTime, apiDuration)
@@ -308,19 +476,8 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 
 			// Check if compaction can help
 			budget := calculateTokenBudget(config, state, systemPrompt)
-			if config.Compactor.ShouldCompact(budget) {
-				compacted, err := config.Compactor.Compact(ctx, CompactRequest{
-					Messages:  state.Messages,
-					Model:     config.Model,
-					Budget:    budget,
-					Trigger:   "auto",
-					SessionID: state.SessionID,
-					EmitCh:    ch,
-				})
-				if err == nil {
-					state.Messages = compacted
-					continue
-				}
+			if config.HistoryWindow == nil && config.Compactor.ShouldCompact(budget) && compactHistory(ctx, config, state, ch, systemPrompt, budget, "auto") {
+				continue
 			}
 			state.ExitReason = ExitMaxTokens
 			goto done
@@ -336,7 +493,11 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 			}
 
 			// Execute tools
-			toolResults, interrupted := executeTools(ctx, toolBlocks, config, state, ch)
+			toolResults, interrupted := executeTools(turnCtx, toolBlocks, config, state, ch)
+			timedOut := turnTimedOut(ctx, turnCtx)
+			if timedOut {
+				interrupted = false // only this turn was cut off
+			}
 
 			// Track tool calls for session memory
 			if memTracker != nil {
@@ -345,15 +506,20 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 				}
 			}
 
+			// Track tool outcome streaks for model routing
+			trackToolOutcomes(config, state, toolBlocks, toolResults)
+
 			// Sync active file paths for conditional rules injection
 			syncActiveFilePaths(config, state)
+			injectConditionalRules(config, state)
+			syncTodos(config, state)
 
 			// Activate skill permission scope if a Skill tool was invoked
 			setActiveSkillScope(toolBlocks, config, state)
 
 			// Append tool results as messages
 			toolMsgs := llm.ConvertToToolMessages(toolResults)
-			state.Messages = append(state.Messages, toolMsgs...)
+			state.appendMessages(toolMsgs...)
 
 			// Persist tool result messages
 			for _, tm := range toolMsgs {
@@ -361,13 +527,33 @@ func runLoop(ctx context.Context, prompt string, config *AgentConfig, state *Loo
 			}
 
 			// Lightweight pruning of old tool results to manage context pressure
-			state.Messages = pruneOldToolResults(state.Messages, 10)
+			pruneToolResults(config, state, systemPrompt)
+
+			// Optional one-line summary of the tool burst for UIs
+			if config.ToolUseSummary != nil && !interrupted {
+				emitToolUseSummary(ctx, config, state, ch, q, resp, toolBlocks, toolResults)
+			}
 
 			if interrupted {
 				state.ExitReason = ExitInterrupted
+				if ctx.Err() == nil {
+					state.LastError = errToolInterrupt
+				}
+				goto done
+			}
+			if timedOut {
+				handleTurnTimeout(config, state, ch, q, nil, "tools")
+			}
+
+			// Break out of repeated identical failing tool calls
+			if checkToolLoop(config, state, toolBlocks, toolResults) {
+				state.ExitReason = ExitToolLoop
 				goto done
 			}
 
+			// Inject corrections queued with Steer while the tools ran
+			injectSteering(ctx, config, state, ch, q)
+
 			continue
 
 		case "stop_sequence":
@@ -386,12 +572,30 @@ done:
 	// 11.5 Flush session metadata
 	finalizeSession(config, state)
 
+	if config.Metrics != nil {
+		config.Metrics.RecordExit(currentModel(config, state), state.ExitReason, state.TurnCount)
+	}
+
+	loopSpan.SetAttributes(
+		AttrExitReason.String(string(state.ExitReason)),
+		AttrTurns.Int(state.TurnCount),
+		AttrInputTokens.Int(state.TotalUsage.InputTokens),
+		AttrOutputTokens.Int(state.TotalUsage.OutputTokens),
+		AttrCostUSD.Float64(state.TotalCostUSD),
+	)
+	endSpan(loopSpan, state.LastError)
+
 	// 12. Emit result message
 	emitResult(ch, config, state, startTime, apiDuration)
 
-	// 13. Fire SessionEnd hook
-	config.Hooks.Fire(ctx, types.HookEventSessionEnd, map[string]any{
-		"reason": string(state.ExitReason),
+	// 13. Fire SessionEnd hook (ctx may already be cancelled by an interrupt
+	// or shutdown; the hook still runs)
+	reason := string(state.ExitReason)
+	if q.isShuttingDown() {
+		reason = "shutdown"
+	}
+	config.Hooks.Fire(context.WithoutCancel(ctx), types.HookEventSessionEnd, map[string]any{
+		"reason": reason,
 	})
 }
 
@@ -405,10 +609,13 @@ func waitForInput(ctx context.Context, config *AgentConfig, state *LoopState, ch
 				return false // input channel closed
 			}
 			state.ActiveSkill = nil // clear skill scope on new user input
+			if !firePromptSubmit(ctx, config, state, ch, string(msg)) {
+				continue // blocked: drop it and keep waiting
+			}
 			// Append user message to conversation
 			userMsg := llm.ChatMessage{Role: "user", Content: string(msg)}
-			state.Messages = append(state.Messages, userMsg)
-			persistMessage(config.SessionStore, state.SessionID, userMsg)
+			state.appendMessages(userMsg)
+			state.startCheckpoint(persistMessage(config.SessionStore, state.SessionID, userMsg))
 			return true
 
 		case req := <-q.controlCh:
@@ -432,6 +639,26 @@ func waitForInput(ctx context.Context, config *AgentConfig, state *LoopState, ch
 	}
 }
 
+// steerNotice marks a Steer message so the model reads it as an interruption
+// of its current work rather than a new task.
+const steerNotice = "[The user interrupted while you were working. Treat this as a correction to the current task and adjust before continuing.]\n\n"
+
+// injectSteering appends queued Steer messages to the conversation as user
+// messages, after UserPromptSubmit hooks. Returns true if any was injected.
+func injectSteering(ctx context.Context, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, q *Query) bool {
+	injected := false
+	for _, msg := range q.takeSteering() {
+		if !firePromptSubmit(ctx, config, state, ch, string(msg)) {
+			continue
+		}
+		userMsg := llm.ChatMessage{Role: "user", Content: steerNotice + string(msg)}
+		state.appendMessages(userMsg)
+		persistMessage(config.SessionStore, state.SessionID, userMsg)
+		injected = true
+	}
+	return injected
+}
+
 // processControlRequests drains any pending control requests (non-blocking).
 func processControlRequests(config *AgentConfig, state *LoopState, q *Query) {
 	for {
@@ -471,6 +698,8 @@ func dispatchControl(config *AgentConfig, state *LoopState, q *Query, req types.
 		}
 		q.mu.Lock()
 		state.Model = model
+		state.modelPinned = true
+		state.UsingFallback = false // the host's choice outlasts the fallback
 		q.mu.Unlock()
 		return types.ControlResponse{
 			Type:     "control_response",
@@ -489,6 +718,9 @@ func dispatchControl(config *AgentConfig, state *LoopState, q *Query, req types.
 			}
 		}
 		config.PermissionMode = mode
+		if c, ok := config.Permissions.(interface{ SetMode(types.PermissionMode) }); ok {
+			c.SetMode(mode)
+		}
 		return types.ControlResponse{
 			Type:     "control_response",
 			Response: types.ControlSuccessResponse{RequestID: req.RequestID, Result: string(mode)},
@@ -512,6 +744,42 @@ func dispatchControl(config *AgentConfig, state *LoopState, q *Query, req types.
 			Response: types.ControlSuccessResponse{RequestID: req.RequestID, Result: *req.Request.MaxThinkingTokens},
 		}
 
+	case types.ControlSubtypeSteer:
+		if req.Request.Prompt == "" {
+			return types.ControlResponse{
+				Type: "control_response",
+				Response: types.ControlErrorResponse{
+					RequestID: req.RequestID,
+					Error:     "prompt is required",
+				},
+			}
+		}
+		q.mu.Lock()
+		q.steering = append(q.steering, []byte(req.Request.Prompt))
+		q.mu.Unlock()
+		return types.ControlResponse{
+			Type:     "control_response",
+			Response: types.ControlSuccessResponse{RequestID: req.RequestID},
+		}
+
+	case types.ControlSubtypeAnswerQuestion:
+		return types.ControlResponse{
+			Type: "control_response",
+			Response: types.ControlErrorResponse{
+				RequestID: req.RequestID,
+				Error:     "no question is waiting for an answer",
+			},
+		}
+
+	case types.ControlSubtypeAnswerPermission:
+		return types.ControlResponse{
+			Type: "control_response",
+			Response: types.ControlErrorResponse{
+				RequestID: req.RequestID,
+				Error:     "no permission request is waiting for an answer",
+			},
+		}
+
 	default:
 		return types.ControlResponse{
 			Type: "control_response",
@@ -543,6 +811,102 @@ func collectAdditionalContext(state *LoopState, results []HookResult) {
 	}
 }
 
+// firePromptSubmit runs UserPromptSubmit hooks for a user prompt before it is
+// added to the conversation. Context from the hooks (systemMessage or
+// hookSpecificOutput.additionalContext) is queued for the next LLM call.
+// Returns false if a hook blocked the prompt, after emitting a PromptBlockedMessage.
+func firePromptSubmit(ctx context.Context, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, prompt string) bool {
+	results, _ := config.Hooks.Fire(ctx, types.HookEventUserPromptSubmit, map[string]any{
+		"prompt": prompt,
+	})
+	for _, r := range results {
+		if r.Decision == "deny" || (r.Continue != nil && !*r.Continue) {
+			reason := r.Reason
+			if reason == "" {
+				reason = r.StopReason
+			}
+			ch <- &types.PromptBlockedMessage{
+				BaseMessage: types.BaseMessage{UUID: uuid.New(), SessionID: state.SessionID},
+				Type:        types.MessageTypeSystem,
+				Subtype:     types.SystemSubtypePromptBlocked,
+				Prompt:      prompt,
+				Reason:      reason,
+			}
+			return false
+		}
+	}
+	collectAdditionalContext(state, results)
+	for _, r := range results {
+		if extra := hookAdditionalContext(r.HookSpecificOutput); extra != "" {
+			state.PendingAdditionalContext = append(state.PendingAdditionalContext, extra)
+		}
+	}
+	return true
+}
+
+// hookAdditionalContext reads additionalContext from hook-specific output,
+// which is a map for shell hooks and a typed struct for Go callbacks.
+func hookAdditionalContext(output any) string {
+	switch o := output.(type) {
+	case nil:
+		return ""
+	case map[string]any:
+		s, _ := o["additionalContext"].(string)
+		return s
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return ""
+	}
+	var specific struct {
+		AdditionalContext string `json:"additionalContext"`
+	}
+	json.Unmarshal(data, &specific)
+	return specific.AdditionalContext
+}
+
+// emitBudgetWarnings emits a budget warning the first time TotalCostUSD
+// reaches each of config.BudgetWarnThresholds.
+func emitBudgetWarnings(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) {
+	emitModelBudgetWarnings(config, state, ch)
+	if config.MaxBudgetUSD <= 0 {
+		return
+	}
+	for _, threshold := range config.BudgetWarnThresholds {
+		if threshold <= 0 || state.budgetWarned[threshold] ||
+			state.TotalCostUSD < config.MaxBudgetUSD*threshold {
+			continue
+		}
+		if state.budgetWarned == nil {
+			state.budgetWarned = make(map[float64]bool)
+		}
+		state.budgetWarned[threshold] = true
+		ch <- types.NewBudgetWarning(threshold, state.TotalCostUSD, config.MaxBudgetUSD, state.SessionID)
+	}
+}
+
+// emitModelBudgetWarnings sends a budget_warning for each model the first
+// time its spend reaches ModelBudgetWarnThreshold of its ModelBudgets limit.
+func emitModelBudgetWarnings(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) {
+	threshold := config.ModelBudgetWarnThreshold
+	if threshold <= 0 || len(config.ModelBudgets) == 0 || config.CostTracker == nil {
+		return
+	}
+	breakdown := config.CostTracker.ModelBreakdown()
+	for _, model := range slices.Sorted(maps.Keys(config.ModelBudgets)) {
+		limit := config.ModelBudgets[model]
+		accum, ok := breakdown[model]
+		if !ok || state.modelBudgetWarned[model] || accum.CostUSD < limit*threshold {
+			continue
+		}
+		if state.modelBudgetWarned == nil {
+			state.modelBudgetWarned = make(map[string]bool)
+		}
+		state.modelBudgetWarned[model] = true
+		ch <- types.NewModelBudgetWarning(model, threshold, accum.CostUSD, limit, state.SessionID)
+	}
+}
+
 // checkTermination evaluates whether the loop should stop.
 func checkTermination(ctx context.Context, config *AgentConfig, state *LoopState) ExitReason {
 	// Check context
@@ -568,8 +932,9 @@ func checkTermination(ctx context.Context, config *AgentConfig, state *LoopState
 	// Check per-model budgets
 	if len(config.ModelBudgets) > 0 && config.CostTracker != nil {
 		breakdown := config.CostTracker.ModelBreakdown()
-		for model, limit := range config.ModelBudgets {
-			if accum, ok := breakdown[model]; ok && accum.CostUSD >= limit {
+		for _, model := range slices.Sorted(maps.Keys(config.ModelBudgets)) {
+			if accum, ok := breakdown[model]; ok && accum.CostUSD >= config.ModelBudgets[model] {
+				state.ModelBudgetExceeded = model
 				return ExitMaxBudget
 			}
 		}
@@ -578,33 +943,146 @@ func checkTermination(ctx context.Context, config *AgentConfig, state *LoopState
 	return "" // no termination
 }
 
+// compactHistory fires PreCompact hooks and, unless one returns continue=false,
+// compacts state.Messages and emits a CompactBoundaryMessage with the token
+// counts before and after. Hooks may pass hookSpecificOutput.custom_instructions
+// to steer the summary. Returns false if compaction was vetoed or failed.
+func compactHistory(ctx context.Context, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, systemPrompt string, budget TokenBudget, trigger string) bool {
+	results, _ := config.Hooks.Fire(ctx, types.HookEventPreCompact, map[string]any{
+		"trigger":              trigger,
+		"context_limit":        budget.ContextLimit,
+		"message_tokens":       budget.MessageTkns,
+		"system_prompt_tokens": budget.SystemPromptTkns,
+		"utilization":          budget.UtilizationPct(),
+	})
+	var instructions string
+	for _, r := range results {
+		if r.Continue != nil && !*r.Continue {
+			return false
+		}
+		if m, ok := r.HookSpecificOutput.(map[string]any); ok && instructions == "" {
+			instructions, _ = m["custom_instructions"].(string)
+		}
+	}
+
+	compacted, err := config.Compactor.Compact(ctx, CompactRequest{
+		Messages:           state.Messages,
+		Model:              config.Model,
+		Budget:             budget,
+		Trigger:            trigger,
+		SessionID:          state.SessionID,
+		SessionDir:         config.SessionDir,
+		CustomInstructions: instructions,
+	})
+	if err != nil {
+		return false
+	}
+	state.setMessages(compacted)
+
+	// A compactor with nothing to do hands back the same history; no marker then
+	postTokens := calculateTokenBudget(config, state, systemPrompt).MessageTkns
+	if postTokens < budget.MessageTkns {
+		boundary := types.NewCompactBoundary(trigger, budget.MessageTkns, state.SessionID)
+		boundary.CompactMetadata.PostTokens = postTokens
+		ch <- boundary
+		if config.Metrics != nil {
+			config.Metrics.RecordCompaction(currentModel(config, state), trigger)
+		}
+	}
+	return true
+}
+
+// currentModel returns the model in use: the dynamic override, else config.Model.
+func currentModel(config *AgentConfig, state *LoopState) string {
+	if state.Model != "" {
+		return state.Model
+	}
+	return config.Model
+}
+
 // calculateTokenBudget estimates the current token budget for context management.
 func calculateTokenBudget(config *AgentConfig, state *LoopState, systemPrompt string) TokenBudget {
-	// Estimate message tokens using the simple len/4 heuristic
+	model := config.Model
+	if state.Model != "" {
+		model = state.Model
+	}
+
+	// The tokenizer follows the model, which may change after a fallback or routing
+	counter, encoding := config.TokenCounter, configuredEncoding
+	if counter == nil {
+		counter, encoding = modelTokenCounter(model)
+	}
+	if state.tokenCache == nil {
+		state.tokenCache = newMessageTokenCache(counter, encoding)
+	} else {
+		state.tokenCache.use(counter, encoding)
+	}
+
 	msgTokens := 0
 	for _, msg := range state.Messages {
-		msgTokens += estimateMessageTokens(msg)
+		msgTokens += state.tokenCache.count(msg)
 	}
-	sysTokens := len(systemPrompt) / 4
+	sysTokens := state.tokenCache.counter.CountTokens(systemPrompt)
 
 	// Use actual context limit for current model
 	contextLimit := 200_000
 	if config.ContextLimitFunc != nil {
-		model := config.Model
-		if state.Model != "" {
-			model = state.Model
-		}
 		contextLimit = config.ContextLimitFunc(model, config.Betas)
 	}
 
 	return TokenBudget{
 		ContextLimit:     contextLimit,
 		SystemPromptTkns: sysTokens,
-		MaxOutputTkns:    16384,
+		MaxOutputTkns:    maxOutputTokens(config, model),
 		MessageTkns:      msgTokens,
 	}
 }
 
+// maxOutputTokens resolves the max_tokens for a call to model.
+func maxOutputTokens(config *AgentConfig, model string) int {
+	if config.MaxOutputTokensFunc != nil {
+		if n := config.MaxOutputTokensFunc(model); n > 0 {
+			return n
+		}
+	}
+	if config.MaxOutputTokens > 0 {
+		return config.MaxOutputTokens
+	}
+	return 16384
+}
+
+// remainingFallbacks returns the fallback models still to try after model
+// fails: the entries after it when it is itself a fallback (a sticky one),
+// otherwise the whole chain.
+func remainingFallbacks(chain []string, model string) []string {
+	if i := slices.Index(chain, model); i >= 0 {
+		return chain[i+1:]
+	}
+	return chain
+}
+
+// switchToFallback moves the turn from the failing model to fallback,
+// remembering the model to return to on the next turn.
+func switchToFallback(state *LoopState, ch chan<- types.SDKMessage, from, fallback string) {
+	if !state.UsingFallback {
+		state.UsingFallback = true
+		state.fallbackFrom = state.Model
+	}
+	state.Model = fallback
+	ch <- types.NewModelChangeStatus(from, fallback, state.SessionID)
+}
+
+// resetFallback returns to the model in use before the last fallback.
+func resetFallback(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) {
+	from := currentModel(config, state)
+	state.Model = state.fallbackFrom
+	state.UsingFallback = false
+	state.fallbackFrom = ""
+	if to := currentModel(config, state); to != from {
+		ch <- types.NewModelChangeStatus(from, to, state.SessionID)
+	}
+}
+
 // isRetriableModelError checks if the error is a retriable model error
 // (rate limit, service unavailable, model not found).
 func isRetriableModelError(err error) bool {
@@ -615,33 +1093,22 @@ func isRetriableModelError(err error) bool {
 		strings.Contains(errStr, "rate_limit")
 }
 
-// estimateMessageTokens estimates the token count for a single message.
-func estimateMessageTokens(msg llm.ChatMessage) int {
-	overhead := 4 // role + separators
-	switch c := msg.Content.(type) {
-	case string:
-		return len(c)/4 + overhead
-	case nil:
-		return overhead
-	default:
-		// For complex content ([]ContentPart etc), use a rough estimate
-		_ = c
-		return overhead
-	}
-}
-
 // persistMessage writes a ChatMessage to the session store as a MessageEntry.
 // Errors are logged but not fatal — persistence is best-effort.
-func persistMessage(store SessionStore, sessionID string, msg llm.ChatMessage) {
+// Returns the entry's UUID, or "" if there is no store.
+func persistMessage(store SessionStore, sessionID string, msg llm.ChatMessage) string {
+	return persistEntry(store, sessionID, MessageEntry{Message: msg})
+}
+
+// persistEntry assigns a UUID and timestamp to entry and appends it to the session store.
+func persistEntry(store SessionStore, sessionID string, entry MessageEntry) string {
 	if store == nil {
-		return
-	}
-	entry := MessageEntry{
-		UUID:      uuid.New().String(),
-		Timestamp: time.Now(),
-		Message:   msg,
+		return ""
 	}
+	entry.UUID = uuid.New().String()
+	entry.Timestamp = time.Now()
 	_ = store.AppendMessage(sessionID, entry)
+	return entry.UUID
 }
 
 // persistSDKMessage writes an SDKMessage to the transcript log.
@@ -652,22 +1119,44 @@ func persistSDKMessage(store SessionStore, sessionID string, msg types.SDKMessag
 	_ = store.AppendSDKMessage(sessionID, msg)
 }
 
-// initializeSession creates or restores a session based on QueryOptions embedded in config.
-func initializeSession(config *AgentConfig, state *LoopState) {
+// initializeSession restores the session selected by config.Restore, or
+// creates a new one. Resumed and continued sessions keep their ID and
+// metadata, a fork is created by the store, and resuming at a specific
+// message starts a new session seeded with the history up to it.
+func initializeSession(config *AgentConfig, state *LoopState) error {
 	if config.SessionStore == nil {
-		return
+		return nil
+	}
+
+	opts := config.Restore
+	if opts.Continue || opts.Resume != "" {
+		if err := RestoreSession(config, state, opts); err != nil {
+			return fmt.Errorf("restoring session: %w", err)
+		}
+		if len(state.Messages) > 0 && (opts.ResumeSessionAt == "" || opts.ForkSession) {
+			_ = config.SessionStore.UpdateMetadata(state.SessionID, func(m *SessionMetadata) {
+				m.Incomplete = true
+			})
+			state.sessionSavedAt = time.Now()
+			return nil
+		}
 	}
 
-	// Check for resume/continue/fork (via stored options on config)
-	// For now, create a new session if one doesn't exist
 	meta := SessionMetadata{
-		ID:        state.SessionID,
-		CWD:       config.CWD,
-		Model:     config.Model,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:         state.SessionID,
+		CWD:        config.CWD,
+		Model:      config.Model,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+		Incomplete: true,
 	}
+	state.sessionSavedAt = meta.UpdatedAt
 	_ = config.SessionStore.Create(meta)
+	// History carried over from the resumed session is stored as replayed
+	for _, msg := range state.Messages {
+		persistEntry(config.SessionStore, state.SessionID, MessageEntry{Message: msg, IsReplay: true})
+	}
+	return nil
 }
 
 // finalizeSession updates session metadata with final stats and checkpoints accessed files.
@@ -676,13 +1165,9 @@ func finalizeSession(config *AgentConfig, state *LoopState) {
 		return
 	}
 	_ = config.SessionStore.UpdateMetadata(state.SessionID, func(m *SessionMetadata) {
-		m.MessageCount = len(state.Messages)
-		m.TurnCount = state.TurnCount
-		m.TotalCostUSD = state.TotalCostUSD
+		setSessionStats(m, config, state)
 		m.ExitReason = string(state.ExitReason)
-		if config.AgentName != "" {
-			m.AgentName = config.AgentName
-		}
+		m.Incomplete = false
 	})
 
 	// Checkpoint accessed files for session rewind support
@@ -696,6 +1181,54 @@ func finalizeSession(config *AgentConfig, state *LoopState) {
 	}
 }
 
+// autoSaveSession flushes the run's stats to the session metadata once
+// SessionAutoSaveInterval has passed since the last flush. The session stays
+// Incomplete until finalizeSession.
+func autoSaveSession(config *AgentConfig, state *LoopState) {
+	if config.SessionStore == nil || config.SessionAutoSaveInterval <= 0 {
+		return
+	}
+	if time.Since(state.sessionSavedAt) < config.SessionAutoSaveInterval {
+		return
+	}
+	state.sessionSavedAt = time.Now()
+	_ = config.SessionStore.UpdateMetadata(state.SessionID, func(m *SessionMetadata) {
+		setSessionStats(m, config, state)
+	})
+}
+
+func setSessionStats(m *SessionMetadata, config *AgentConfig, state *LoopState) {
+	m.MessageCount = len(state.Messages)
+	m.TurnCount = state.TurnCount
+	m.TotalCostUSD = state.TotalCostUSD
+	m.InputTokens = state.TotalUsage.InputTokens
+	m.OutputTokens = state.TotalUsage.OutputTokens
+	if config.AgentName != "" {
+		m.AgentName = config.AgentName
+	}
+}
+
+// RewindSession undoes every turn from the given checkpoint onward: files
+// modified since are restored and the conversation is truncated to just before
+// the checkpoint's user message. Checkpoint IDs are user message UUIDs.
+func RewindSession(config *AgentConfig, state *LoopState, checkpointID string) error {
+	if config.SessionStore == nil {
+		return nil
+	}
+	entries, err := config.SessionStore.Rewind(state.SessionID, checkpointID)
+	if err != nil {
+		return err
+	}
+	msgs := make([]llm.ChatMessage, len(entries))
+	for i, entry := range entries {
+		msgs[i] = entry.Message
+		msgs[i].ID = entry.UUID
+	}
+	state.setMessages(msgs)
+	state.startCheckpoint("")
+	return nil
+}
+
 // RestoreSession loads a previous session's messages into the loop state.
 // Called by the host app before RunLoop to set up resume/continue/fork.
 func RestoreSession(config *AgentConfig, state *LoopState, opts types.QueryOptions) error {
@@ -737,12 +1270,53 @@ func RestoreSession(config *AgentConfig, state *LoopState, opts types.QueryOptio
 		msgs := make([]llm.ChatMessage, len(sessionState.Messages))
 		for i, entry := range sessionState.Messages {
 			msgs[i] = entry.Message
+			msgs[i].ID = entry.UUID
 		}
-		state.Messages = msgs
+		state.setMessages(msgs)
 		if sessionState.Metadata.ID != "" {
 			state.SessionID = sessionState.Metadata.ID
 		}
+		if sessionState.Metadata.Incomplete {
+			recoverSession(config, state)
+		}
+		restoreTodos(config, state, sessionState.Metadata.Todos)
 	}
 
 	return nil
 }
+
+// interruptedToolResult answers tool calls whose results were lost when a
+// session crashed.
+const interruptedToolResult = "Error: the session ended before this tool call completed. Its effects, if any, are unknown."
+
+// recoverSession repairs the history of a session that crashed mid-turn:
+// tool calls in the last assistant message that have no result get an error
+// result, so the restored conversation is valid to send again.
+func recoverSession(config *AgentConfig, state *LoopState) {
+	last := -1
+	for i := len(state.Messages) - 1; i >= 0; i-- {
+		if state.Messages[i].Role == "assistant" {
+			last = i
+			break
+		}
+	}
+	if last < 0 {
+		return
+	}
+	answered := make(map[string]bool)
+	for _, msg := range state.Messages[last+1:] {
+		if msg.Role == "tool" {
+			answered[msg.ToolCallID] = true
+		}
+	}
+	var missing []llm.ToolResult
+	for _, call := range state.Messages[last].ToolCalls {
+		if !answered[call.ID] {
+			missing = append(missing, llm.ToolResult{ToolUseID: call.ID, Content: interruptedToolResult})
+		}
+	}
+	for _, msg := range llm.ConvertToToolMessages(missing) {
+		state.appendMessages(msg)
+		persistMessage(config.SessionStore, state.SessionID, msg)
+	}
+}
@@ -3,8 +3,11 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -527,6 +530,128 @@ func TestLoop_Interrupt(t *testing.T) {
 	}
 }
 
+func TestQuery_Shutdown(t *testing.T) {
+	client := &blockingLLMClient{blockCh: make(chan struct{})}
+	config := defaultConfig(client, tools.NewRegistry())
+	bg := &mockBackgroundWork{}
+	config.Background = []BackgroundWork{bg}
+	store := &flushingSessionStore{}
+	config.SessionStore = store
+	hooks := &sessionEndRecorder{}
+	config.Hooks = hooks
+
+	q := RunLoop(context.Background(), "Hello", config)
+	go func() {
+		for range q.Messages() {
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := q.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	q.Wait() // already returned: Shutdown waits for the loop
+	if !bg.stopped {
+		t.Error("background work was not stopped")
+	}
+	store.mu.Lock()
+	flushed := store.flushed
+	store.mu.Unlock()
+	if !flushed {
+		t.Error("session store was not flushed")
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	if hooks.reason != "shutdown" {
+		t.Errorf("SessionEnd reason = %q, want shutdown", hooks.reason)
+	}
+}
+
+func TestQuery_ShutdownTimeout(t *testing.T) {
+	client := &blockingLLMClient{blockCh: make(chan struct{})}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Background = []BackgroundWork{&mockBackgroundWork{err: context.DeadlineExceeded}}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	go func() {
+		for range q.Messages() {
+		}
+	}()
+
+	err := q.Shutdown(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the background work's error", err)
+	}
+	q.Wait()
+}
+
+func TestQuery_ClosesBackgroundOnExit(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{endTurnResponse("done")},
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+	shell := &closingBackgroundWork{}
+	config.Background = []BackgroundWork{&mockBackgroundWork{}, shell}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+	if !shell.closed {
+		t.Error("background io.Closer was not closed when the loop exited")
+	}
+}
+
+type mockBackgroundWork struct {
+	stopped bool
+	err     error
+}
+
+func (m *mockBackgroundWork) StopAll(context.Context) error {
+	m.stopped = true
+	return m.err
+}
+
+// closingBackgroundWork is background work holding a session resource.
+type closingBackgroundWork struct {
+	mockBackgroundWork
+	closed bool
+}
+
+func (c *closingBackgroundWork) Close() error {
+	c.closed = true
+	return nil
+}
+
+type flushingSessionStore struct {
+	mockSessionStore
+	flushed bool
+}
+
+func (s *flushingSessionStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushed = true
+	return nil
+}
+
+// sessionEndRecorder records the reason SessionEnd fired with.
+type sessionEndRecorder struct {
+	mu     sync.Mutex
+	reason string
+}
+
+func (r *sessionEndRecorder) Fire(_ context.Context, event types.HookEvent, input any) ([]HookResult, error) {
+	if event == types.HookEventSessionEnd {
+		r.mu.Lock()
+		r.reason, _ = input.(map[string]any)["reason"].(string)
+		r.mu.Unlock()
+	}
+	return nil, nil
+}
+
 // blockingLLMClient blocks until blockCh is closed.
 type blockingLLMClient struct {
 	blockCh chan struct{}
@@ -656,6 +781,119 @@ func TestLoop_UnknownTool(t *testing.T) {
 	}
 }
 
+func TestLoop_DisabledToolNotExecuted(t *testing.T) {
+	bash := &mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "ran"}}
+	registry := tools.NewRegistry(tools.WithDisabled("Bash"))
+	registry.Register(bash)
+
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Bash", map[string]any{"command": "ls"}),
+			endTurnResponse("OK."),
+		},
+	}
+	q := RunLoop(context.Background(), "List files", defaultConfig(client, registry))
+	collectMessages(q)
+	q.Wait()
+
+	if bash.CallCount() != 0 {
+		t.Error("disabled tool was executed")
+	}
+	if got := toolResultContent(q.State(), "call_1"); !strings.Contains(got, "unknown tool") {
+		t.Errorf("tool result = %q, want an unknown tool error", got)
+	}
+}
+
+func TestLoop_SkillScopeRestrictsTools(t *testing.T) {
+	skill := &mockRecordingTool{name: "Skill", output: tools.ToolOutput{Content: "Review the diff."}}
+	read := &mockRecordingTool{name: "Read", output: tools.ToolOutput{Content: "contents"}}
+	write := &mockRecordingTool{name: "Write", output: tools.ToolOutput{Content: "written"}}
+	registry := tools.NewRegistry()
+	registry.Register(skill)
+	registry.Register(read)
+	registry.Register(write)
+
+	client := &capturingLLMClient{inner: &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Skill", map[string]any{"skill": "review"}),
+			toolUseResponse("call_2", "Write", map[string]any{"file_path": "/tmp/x"}),
+			endTurnResponse("Reviewed."),
+		},
+	}}
+	config := defaultConfig(client, registry)
+	config.Skills = &mockSkillProvider{skills: map[string]types.SkillEntry{
+		"review": {SkillDefinition: types.SkillDefinition{Name: "review", AllowedTools: []string{"Read", "Grep"}}},
+	}}
+
+	q := RunLoop(context.Background(), "Review my change", config)
+	collectMessages(q)
+	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	if n := len(reqs[0].Tools); n != 3 {
+		t.Errorf("first request offered %d tools, want all 3", n)
+	}
+	for _, req := range reqs[1:] {
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "Read" {
+			t.Errorf("scoped request tools = %+v, want only Read", req.Tools)
+		}
+	}
+	if write.CallCount() != 0 {
+		t.Error("Write ran outside the skill's allowed-tools")
+	}
+	if got := toolResultContent(q.State(), "call_2"); !strings.Contains(got, `skill "review" is active`) {
+		t.Errorf("tool result = %q, want a skill scope denial", got)
+	}
+	if q.State().ActiveSkill != nil {
+		t.Error("skill scope should be cleared on end_turn")
+	}
+}
+
+func TestLoop_ConditionalRulesInjectOnce(t *testing.T) {
+	read := &mockRecordingTool{name: "Read", output: tools.ToolOutput{Content: "syntax = \"proto3\";"}}
+	registry := tools.NewRegistry()
+	registry.Register(read)
+
+	client := &capturingLLMClient{inner: &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Read", map[string]any{"file_path": "/repo/api/user.proto"}),
+			toolUseResponse("call_2", "Read", map[string]any{"file_path": "/repo/api/order.proto"}),
+			endTurnResponse("Done."),
+		},
+	}}
+	config := defaultConfig(client, registry)
+	config.CWD = "/repo"
+	config.ConditionalRules = []ConditionalRule{
+		{Pattern: "*.proto", Text: "Regenerate stubs after editing."},
+		{Pattern: "docs/**", Text: "Keep docs in sync."},
+	}
+
+	q := RunLoop(context.Background(), "Look at the API", config)
+	collectMessages(q)
+	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	var injected []int
+	for i, req := range reqs {
+		system, _ := req.Messages[0].Content.(string)
+		if strings.Contains(system, "Regenerate stubs after editing.") {
+			injected = append(injected, i)
+		}
+		if strings.Contains(system, "Keep docs in sync.") {
+			t.Errorf("request %d: unmatched rule injected", i)
+		}
+	}
+	if len(injected) != 1 || injected[0] != 1 {
+		t.Errorf("rule injected into requests %v, want only request 1", injected)
+	}
+}
+
 func TestLoop_MessageOrdering(t *testing.T) {
 	mockTool := &mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "ok"}}
 	registry := tools.NewRegistry()
@@ -1031,6 +1269,137 @@ func TestLoop_StopHookNoOverride(t *testing.T) {
 	}
 }
 
+// hookFunc adapts a function to HookRunner for tests that inspect hook input.
+type hookFunc func(event types.HookEvent, input any) []HookResult
+
+func (f hookFunc) Fire(_ context.Context, event types.HookEvent, input any) ([]HookResult, error) {
+	return f(event, input), nil
+}
+
+func TestLoop_UserPromptSubmitDeny(t *testing.T) {
+	hooks := &mockHookRunner{
+		results: map[types.HookEvent][]HookResult{
+			types.HookEventUserPromptSubmit: {{Decision: "deny", Reason: "contains a secret"}},
+		},
+	}
+	client := &capturingLLMClient{inner: &mockLLMClient{}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Hooks = hooks
+	store := &mockSessionStore{}
+	config.SessionStore = store
+
+	q := RunLoop(context.Background(), "token=sk-123", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if n := len(client.getRequests()); n != 0 {
+		t.Errorf("LLM called %d times, want 0 for a blocked prompt", n)
+	}
+	var blocked *types.PromptBlockedMessage
+	for _, m := range msgs {
+		if b, ok := m.(*types.PromptBlockedMessage); ok {
+			blocked = b
+		}
+	}
+	if blocked == nil || blocked.Reason != "contains a secret" || blocked.Prompt != "token=sk-123" {
+		t.Errorf("expected a PromptBlockedMessage, got %+v", blocked)
+	}
+	for _, c := range store.getAppendCalls() {
+		if c.Message.Role == "user" {
+			t.Error("blocked prompt should not be persisted")
+		}
+	}
+	if q.GetExitReason() != ExitEndTurn {
+		t.Errorf("exit reason = %s, want end_turn", q.GetExitReason())
+	}
+}
+
+func TestLoop_UserPromptSubmitAdditionalContext(t *testing.T) {
+	var gotPrompt any
+	hooks := hookFunc(func(event types.HookEvent, input any) []HookResult {
+		if event != types.HookEventUserPromptSubmit {
+			return nil
+		}
+		gotPrompt = input.(map[string]any)["prompt"]
+		return []HookResult{{HookSpecificOutput: map[string]any{
+			"hookEventName":     "UserPromptSubmit",
+			"additionalContext": "Current branch: main",
+		}}}
+	})
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{endTurnResponse("ok")}}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Hooks = hooks
+
+	q := RunLoop(context.Background(), "What branch am I on?", config)
+	collectMessages(q)
+	q.Wait()
+
+	if gotPrompt != "What branch am I on?" {
+		t.Errorf("hook prompt = %v", gotPrompt)
+	}
+	reqs := client.getRequests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	system, _ := reqs[0].Messages[0].Content.(string)
+	if !strings.Contains(system, "Current branch: main") {
+		t.Errorf("system prompt missing hook context: %q", system)
+	}
+}
+
+func TestLoop_UserPromptSubmitBlocksFollowUp(t *testing.T) {
+	hooks := hookFunc(func(event types.HookEvent, input any) []HookResult {
+		if m, ok := input.(map[string]any); ok && event == types.HookEventUserPromptSubmit {
+			if strings.Contains(m["prompt"].(string), "secret") {
+				return []HookResult{{Decision: "deny"}}
+			}
+		}
+		return nil
+	})
+	client := &mockLLMClient{
+		responses: []*mockStream{endTurnResponse("first"), endTurnResponse("second")},
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Hooks = hooks
+	config.MultiTurn = true
+
+	q := RunLoop(context.Background(), "Hello", config)
+	var msgs []types.SDKMessage
+	var msgsMu sync.Mutex
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for m := range q.Messages() {
+			msgsMu.Lock()
+			msgs = append(msgs, m)
+			msgsMu.Unlock()
+		}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	q.SendUserMessage([]byte("here is my secret"))
+	time.Sleep(50 * time.Millisecond)
+	q.SendUserMessage([]byte("never mind"))
+	time.Sleep(100 * time.Millisecond)
+	q.Close()
+	<-done
+
+	if q.TurnCount() != 2 {
+		t.Errorf("turn count = %d, want 2 (blocked follow-up skipped)", q.TurnCount())
+	}
+	msgsMu.Lock()
+	defer msgsMu.Unlock()
+	blocked := 0
+	for _, m := range msgs {
+		if _, ok := m.(*types.PromptBlockedMessage); ok {
+			blocked++
+		}
+	}
+	if blocked != 1 {
+		t.Errorf("blocked messages = %d, want 1", blocked)
+	}
+}
+
 func TestLoop_PreToolUseHookDeny(t *testing.T) {
 	hooks := &mockHookRunner{
 		results: map[types.HookEvent][]HookResult{
@@ -1166,9 +1535,10 @@ func TestLoop_HookEventSequence(t *testing.T) {
 
 	events := hooks.firedEvents()
 
-	// Expected sequence: SessionStart, PreToolUse, PostToolUse, Stop, SessionEnd
+	// Expected sequence: SessionStart, UserPromptSubmit, PreToolUse, PostToolUse, Stop, SessionEnd
 	expectedOrder := []types.HookEvent{
 		types.HookEventSessionStart,
+		types.HookEventUserPromptSubmit,
 		types.HookEventPreToolUse,
 		types.HookEventPostToolUse,
 		types.HookEventStop,
@@ -1194,6 +1564,7 @@ type mockCompactor struct {
 	shouldCompact bool
 	compactCalls  int
 	compactErr    error
+	lastReq       CompactRequest
 }
 
 func (m *mockCompactor) ShouldCompact(_ TokenBudget) bool {
@@ -1206,6 +1577,7 @@ func (m *mockCompactor) Compact(_ context.Context, req CompactRequest) ([]llm.Ch
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.compactCalls++
+	m.lastReq = req
 	if m.compactErr != nil {
 		return nil, m.compactErr
 	}
@@ -1252,6 +1624,77 @@ func TestLoop_ProactiveCompaction(t *testing.T) {
 	}
 }
 
+func TestLoop_PreCompactHookVeto(t *testing.T) {
+	compactor := &mockCompactor{shouldCompact: true}
+	hooks := &mockHookRunner{
+		results: map[types.HookEvent][]HookResult{
+			types.HookEventPreCompact: {{Continue: boolPtr(false)}},
+		},
+	}
+	client := &mockLLMClient{responses: []*mockStream{endTurnResponse("ok")}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Compactor = compactor
+	config.Hooks = hooks
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if n := compactor.CompactCallCount(); n != 0 {
+		t.Errorf("Compact called %d times, want 0 after a PreCompact veto", n)
+	}
+	for _, m := range msgs {
+		if _, ok := m.(*types.CompactBoundaryMessage); ok {
+			t.Error("no compact boundary should be emitted when compaction is vetoed")
+		}
+	}
+}
+
+func TestLoop_CompactBoundaryAndInstructions(t *testing.T) {
+	compactor := &mockCompactor{shouldCompact: true}
+	hooks := &mockHookRunner{
+		results: map[types.HookEvent][]HookResult{
+			types.HookEventPreCompact: {{HookSpecificOutput: map[string]any{"custom_instructions": "keep file paths"}}},
+		},
+	}
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Bash", map[string]any{"command": "ls"}),
+			endTurnResponse("Done"),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: strings.Repeat("output ", 500)}})
+	config := defaultConfig(client, registry)
+	config.Compactor = compactor
+	config.Hooks = hooks
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	compactor.mu.Lock()
+	instructions := compactor.lastReq.CustomInstructions
+	compactor.mu.Unlock()
+	if instructions != "keep file paths" {
+		t.Errorf("CustomInstructions = %q, want hook value", instructions)
+	}
+
+	var boundaries []*types.CompactBoundaryMessage
+	for _, m := range msgs {
+		if b, ok := m.(*types.CompactBoundaryMessage); ok {
+			boundaries = append(boundaries, b)
+		}
+	}
+	if len(boundaries) == 0 {
+		t.Fatal("expected a CompactBoundaryMessage once history was summarized")
+	}
+	md := boundaries[0].CompactMetadata
+	if md.Trigger != "auto" || md.PreTokens <= md.PostTokens {
+		t.Errorf("boundary metadata = %+v, want pre > post tokens", md)
+	}
+}
+
 func TestLoop_ReactiveCompaction_MaxTokens(t *testing.T) {
 	compactor := &mockCompactor{shouldCompact: true}
 
@@ -1405,13 +1848,34 @@ type mockSessionStore struct {
 	appendCalls      []MessageEntry
 	appendSDKCalls   []types.SDKMessage
 	updateCalls      int
+	meta             SessionMetadata   // Create's metadata with updates applied
+	updates          []SessionMetadata // metadata after each UpdateMetadata
 	closeCalled      bool
+	checkpointCalls  map[string][]string // checkpoint ID -> snapshotted paths
 
 	// Configurable restore-related return values
 	loadFunc       func(string) (*SessionState, error)
 	loadLatestFunc func(string) (*SessionState, error)
 	forkFunc       func(string, string) (*SessionState, error)
 	loadUpToFunc   func(string, string) ([]MessageEntry, error)
+	rewindFunc     func(string, string) ([]MessageEntry, error)
+}
+
+func (m *mockSessionStore) CreateCheckpoint(_, id string, paths []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checkpointCalls == nil {
+		m.checkpointCalls = make(map[string][]string)
+	}
+	m.checkpointCalls[id] = append(m.checkpointCalls[id], paths...)
+	return nil
+}
+
+func (m *mockSessionStore) Rewind(sessionID, checkpointID string) ([]MessageEntry, error) {
+	if m.rewindFunc != nil {
+		return m.rewindFunc(sessionID, checkpointID)
+	}
+	return m.NoOpSessionStore.Rewind(sessionID, checkpointID)
 }
 
 func (m *mockSessionStore) Load(sessionID string) (*SessionState, error) {
@@ -1446,6 +1910,7 @@ func (m *mockSessionStore) Create(meta SessionMetadata) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.createCalls = append(m.createCalls, meta)
+	m.meta = meta
 	return nil
 }
 
@@ -1467,8 +1932,8 @@ func (m *mockSessionStore) UpdateMetadata(_ string, fn func(*SessionMetadata)) e
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.updateCalls++
-	meta := SessionMetadata{}
-	fn(&meta)
+	fn(&m.meta)
+	m.updates = append(m.updates, m.meta)
 	return nil
 }
 
@@ -1493,6 +1958,66 @@ func (m *mockSessionStore) getUpdateCalls() int {
 	return m.updateCalls
 }
 
+func TestLoop_CheckpointBeforeWrite(t *testing.T) {
+	store := &mockSessionStore{}
+
+	mockTool := &mockRecordingTool{name: "Write", output: tools.ToolOutput{Content: "ok"}}
+	registry := tools.NewRegistry()
+	registry.Register(mockTool)
+
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Write", map[string]any{"file_path": "/tmp/a.txt", "content": "1"}),
+			toolUseResponse("call_2", "Write", map[string]any{"file_path": "/tmp/a.txt", "content": "2"}),
+			endTurnResponse("Done."),
+		},
+	}
+	config := defaultConfig(client, registry)
+	config.SessionStore = store
+
+	q := RunLoop(context.Background(), "Write twice", config)
+	collectMessages(q)
+	q.Wait()
+
+	userUUID := store.getAppendCalls()[0].UUID
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	paths := store.checkpointCalls[userUUID]
+	if len(paths) != 1 || paths[0] != "/tmp/a.txt" {
+		t.Errorf("checkpoint %s paths = %v, want a single snapshot of /tmp/a.txt", userUUID, paths)
+	}
+}
+
+func TestRewindSession(t *testing.T) {
+	store := &mockSessionStore{
+		rewindFunc: func(sessionID, checkpointID string) ([]MessageEntry, error) {
+			if checkpointID != "u2" {
+				t.Errorf("checkpointID = %q, want u2", checkpointID)
+			}
+			return []MessageEntry{
+				{UUID: "u1", Message: llm.ChatMessage{Role: "user", Content: "first"}},
+				{UUID: "a1", Message: llm.ChatMessage{Role: "assistant", Content: "reply"}},
+			}, nil
+		},
+	}
+	config := &AgentConfig{SessionStore: store}
+	state := &LoopState{
+		SessionID:    "sess-1",
+		Messages:     make([]llm.ChatMessage, 5),
+		CheckpointID: "u3",
+	}
+
+	if err := RewindSession(config, state, "u2"); err != nil {
+		t.Fatalf("RewindSession: %v", err)
+	}
+	if len(state.Messages) != 2 || state.Messages[1].Content != "reply" {
+		t.Errorf("messages after rewind = %v", state.Messages)
+	}
+	if state.CheckpointID != "" {
+		t.Errorf("CheckpointID = %q, want cleared", state.CheckpointID)
+	}
+}
+
 func TestLoop_SessionStore_MessagesPersisted(t *testing.T) {
 	store := &mockSessionStore{}
 
@@ -1569,6 +2094,45 @@ func TestLoop_SessionStore_MetadataUpdatedAtEnd(t *testing.T) {
 	}
 }
 
+func TestLoop_SessionAutoSave(t *testing.T) {
+	run := func(interval time.Duration) *mockSessionStore {
+		store := &mockSessionStore{}
+		registry := tools.NewRegistry()
+		registry.Register(&mockRecordingTool{name: "Echo", output: tools.ToolOutput{Content: "hi"}})
+		client := &mockLLMClient{responses: []*mockStream{
+			toolUseResponse("tc1", "Echo", map[string]any{}),
+			endTurnResponse("done"),
+		}}
+		config := defaultConfig(client, registry)
+		config.SessionStore = store
+		config.SessionAutoSaveInterval = interval
+		q := RunLoop(context.Background(), "Hello", config)
+		collectMessages(q)
+		q.Wait()
+		return store
+	}
+
+	store := run(time.Nanosecond)
+	if !store.createCalls[0].Incomplete {
+		t.Error("session not created Incomplete")
+	}
+	if len(store.updates) != 3 {
+		t.Fatalf("got %d metadata updates, want 2 auto-saves and the final one", len(store.updates))
+	}
+	for i, u := range store.updates[:2] {
+		if !u.Incomplete || u.TurnCount != i+1 || u.ExitReason != "" {
+			t.Errorf("auto-save %d = %+v", i, u)
+		}
+	}
+	if final := store.updates[2]; final.Incomplete || final.TurnCount != 2 || final.ExitReason != string(ExitEndTurn) {
+		t.Errorf("final update = %+v", final)
+	}
+
+	if store := run(time.Hour); len(store.updates) != 1 || store.updates[0].Incomplete {
+		t.Errorf("updates within the interval = %+v, want only the final one", store.updates)
+	}
+}
+
 func TestLoop_SessionStore_NilIsNoOp(t *testing.T) {
 	// Verify that SessionStore=nil doesn't cause any panics or behavior changes
 	client := &mockLLMClient{
@@ -1730,6 +2294,48 @@ func TestRestoreSession_ContinueMode(t *testing.T) {
 	}
 }
 
+func TestRestoreSession_RecoversIncomplete(t *testing.T) {
+	assistant := llm.ChatMessage{Role: "assistant", ToolCalls: []llm.ToolCall{
+		{ID: "tc1", Type: "function", Function: llm.FunctionCall{Name: "Read"}},
+		{ID: "tc2", Type: "function", Function: llm.FunctionCall{Name: "Bash"}},
+	}}
+	newStore := func(incomplete bool) *mockSessionStore {
+		return &mockSessionStore{loadLatestFunc: func(string) (*SessionState, error) {
+			return &SessionState{
+				Metadata: SessionMetadata{ID: "crashed", Incomplete: incomplete},
+				Messages: []MessageEntry{
+					{Message: llm.ChatMessage{Role: "user", Content: "Start"}},
+					{Message: assistant},
+					{Message: llm.ChatMessage{Role: "tool", ToolCallID: "tc1", Content: "contents"}},
+				},
+			}, nil
+		}}
+	}
+
+	store := newStore(true)
+	state := &LoopState{}
+	if err := RestoreSession(&AgentConfig{SessionStore: store}, state, types.QueryOptions{Continue: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Messages) != 4 {
+		t.Fatalf("got %d messages, want the missing tool result appended", len(state.Messages))
+	}
+	if m := state.Messages[3]; m.Role != "tool" || m.ToolCallID != "tc2" || m.Content != interruptedToolResult {
+		t.Errorf("recovered message = %+v", m)
+	}
+	if appended := store.getAppendCalls(); len(appended) != 1 || appended[0].Message.ToolCallID != "tc2" {
+		t.Errorf("persisted %+v, want the recovered tool result", appended)
+	}
+
+	state = &LoopState{}
+	if err := RestoreSession(&AgentConfig{SessionStore: newStore(false)}, state, types.QueryOptions{Continue: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Messages) != 3 {
+		t.Errorf("clean session history changed: %d messages", len(state.Messages))
+	}
+}
+
 func TestRestoreSession_ForkMode(t *testing.T) {
 	store := &mockSessionStore{
 		forkFunc: func(sourceID, newID string) (*SessionState, error) {
@@ -1822,6 +2428,128 @@ func TestRestoreSession_NilStore(t *testing.T) {
 	}
 }
 
+func TestLoop_SessionResumeContinueConversation(t *testing.T) {
+	store := &mockSessionStore{
+		loadFunc: func(id string) (*SessionState, error) {
+			return &SessionState{
+				Metadata: SessionMetadata{ID: id},
+				Messages: []MessageEntry{
+					{UUID: "msg-1", Message: llm.ChatMessage{Role: "user", Content: "My name is Ada."}},
+					{UUID: "msg-2", Message: llm.ChatMessage{Role: "assistant", Content: "Nice to meet you, Ada!"}},
+				},
+			}, nil
+		},
+	}
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{endTurnResponse("Your name is Ada.")}}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.SessionStore = store
+	config.Restore = types.QueryOptions{Resume: "session-123"}
+
+	q := RunLoop(context.Background(), "What is my name?", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	var init *types.SystemInitMessage
+	for _, m := range msgs {
+		if im, ok := m.(*types.SystemInitMessage); ok {
+			init = im
+		}
+	}
+	if init == nil {
+		t.Fatal("no SystemInitMessage")
+	}
+	if init.SessionID != "session-123" || init.RestoredMessages != 2 {
+		t.Errorf("init session = %q with %d restored messages, want session-123 with 2", init.SessionID, init.RestoredMessages)
+	}
+	if q.SessionID() != "session-123" {
+		t.Errorf("SessionID() = %q, want session-123", q.SessionID())
+	}
+
+	reqs := client.getRequests()
+	if len(reqs) != 1 {
+		t.Fatalf("requests = %d, want 1", len(reqs))
+	}
+	var history []string
+	for _, m := range reqs[0].Messages[1:] { // skip the system prompt
+		text, _ := m.Content.(string)
+		history = append(history, m.Role+": "+text)
+	}
+	want := []string{"user: My name is Ada.", "assistant: Nice to meet you, Ada!", "user: What is my name?"}
+	if strings.Join(history, "\n") != strings.Join(want, "\n") {
+		t.Errorf("request history = %q, want %q", history, want)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.createCalls) != 0 {
+		t.Errorf("Create called %d times for a resumed session", len(store.createCalls))
+	}
+	if len(store.appendCalls) == 0 || store.appendCalls[0].Message.Content != "What is my name?" {
+		t.Errorf("first persisted message = %+v, want the new prompt", store.appendCalls)
+	}
+}
+
+func TestLoop_SessionResumeAtSeedsNewSession(t *testing.T) {
+	store := &mockSessionStore{
+		loadUpToFunc: func(_, _ string) ([]MessageEntry, error) {
+			return []MessageEntry{
+				{UUID: "msg-1", Message: llm.ChatMessage{Role: "user", Content: "Q1"}},
+				{UUID: "msg-2", Message: llm.ChatMessage{Role: "assistant", Content: "A1"}},
+			}, nil
+		},
+	}
+	client := &mockLLMClient{responses: []*mockStream{endTurnResponse("A2")}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.SessionID = "branch-id"
+	config.SessionStore = store
+	config.Restore = types.QueryOptions{Resume: "session-123", ResumeSessionAt: "msg-2"}
+
+	q := RunLoop(context.Background(), "Q2", config)
+	collectMessages(q)
+	q.Wait()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.createCalls) != 1 || store.createCalls[0].ID != "branch-id" {
+		t.Fatalf("createCalls = %+v, want one for branch-id", store.createCalls)
+	}
+	var persisted []any
+	for _, e := range store.appendCalls {
+		persisted = append(persisted, e.Message.Content)
+	}
+	if len(persisted) < 3 || persisted[0] != "Q1" || persisted[1] != "A1" || persisted[2] != "Q2" {
+		t.Fatalf("persisted = %v, want the restored history then the prompt", persisted)
+	}
+	if !store.appendCalls[0].IsReplay || !store.appendCalls[1].IsReplay || store.appendCalls[2].IsReplay {
+		t.Error("only the restored history should be marked as replayed")
+	}
+}
+
+func TestLoop_SessionRestoreError(t *testing.T) {
+	store := &mockSessionStore{
+		loadFunc: func(string) (*SessionState, error) { return nil, errors.New("session not found") },
+	}
+	client := &capturingLLMClient{inner: &mockLLMClient{}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.SessionStore = store
+	config.Restore = types.QueryOptions{Resume: "missing"}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if n := len(client.getRequests()); n != 0 {
+		t.Errorf("LLM called %d times after a failed restore", n)
+	}
+	if q.GetExitReason() != ExitSessionRestore {
+		t.Errorf("exit reason = %s, want %s", q.GetExitReason(), ExitSessionRestore)
+	}
+	res, ok := msgs[len(msgs)-1].(*types.ResultMessage)
+	if !ok || !res.IsError || !strings.Contains(strings.Join(res.Errors, " "), "session not found") {
+		t.Errorf("last message = %+v, want an error result", msgs[len(msgs)-1])
+	}
+}
+
 // --- Test Parity: Budget Exhaustion (ported from Python Agent SDK) ---
 
 func TestLoop_BudgetExhausted(t *testing.T) {
@@ -1877,8 +2605,49 @@ func TestLoop_BudgetExhausted(t *testing.T) {
 	}
 }
 
-// --- Test Parity: Thinking Delta StreamEvents (ported from Python Agent SDK) ---
-
+// --- Test Parity: Thinking Delta StreamEvents (ported from Python Agent SDK) ---
+
+func TestLoop_AssembledPartials(t *testing.T) {
+	hello, world := "Hello", ", world"
+	stop := "stop"
+	client := &mockLLMClient{
+		responses: []*mockStream{{
+			chunks: []llm.StreamChunk{
+				{ID: "msg-1", Model: "claude-sonnet-4-5-20250929", Choices: []llm.Choice{{Delta: llm.Delta{Content: &hello}}}},
+				{ID: "msg-1", Model: "claude-sonnet-4-5-20250929", Choices: []llm.Choice{{Delta: llm.Delta{Content: &world}}}},
+				{ID: "msg-1", Model: "claude-sonnet-4-5-20250929", Choices: []llm.Choice{{FinishReason: &stop}},
+					Usage: &llm.Usage{PromptTokens: 10, CompletionTokens: 4, TotalTokens: 14}},
+			},
+		}},
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.AssembledPartials = true
+
+	q := RunLoop(context.Background(), "Hi", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	var texts []string
+	for _, m := range msgs {
+		pm, ok := m.(types.PartialAssistantMessage)
+		if !ok {
+			continue
+		}
+		snap, ok := pm.Event.(*types.MessageSnapshotEvent)
+		if !ok {
+			t.Fatalf("Event = %T, want *types.MessageSnapshotEvent", pm.Event)
+		}
+		if snap.Index != 0 || len(snap.Content) != 1 {
+			t.Errorf("snapshot = %+v, want a single text block at index 0", snap)
+			continue
+		}
+		texts = append(texts, snap.Content[0].Text)
+	}
+	if want := []string{"Hello", "Hello, world"}; strings.Join(texts, "|") != strings.Join(want, "|") {
+		t.Errorf("snapshots = %q, want %q", texts, want)
+	}
+}
+
 func TestLoop_StreamEvents_ThinkingDelta(t *testing.T) {
 	// Mock LLM returns chunks with ReasoningContent deltas (thinking deltas).
 	// With IncludePartial=true, these should be emitted as stream_event messages
@@ -2008,18 +2777,62 @@ func TestLoop_StopSequence(t *testing.T) {
 	}
 }
 
+func TestLoop_StopSequenceReported(t *testing.T) {
+	stop := "stop"
+	ms := &mockStream{
+		chunks: []llm.StreamChunk{
+			textChunk("msg-1", "claude-sonnet-4-5-20250929", "The answer is 42"),
+			{
+				ID:      "msg-1",
+				Model:   "claude-sonnet-4-5-20250929",
+				Choices: []llm.Choice{{FinishReason: &stop, StopReason: "</final>"}},
+			},
+		},
+	}
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{ms}}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.StopSequences = []string{"</final>"}
+
+	q := RunLoop(context.Background(), "Answer, then write </final>", config)
+	var result *types.ResultMessage
+	for m := range q.Messages() {
+		if r, ok := m.(*types.ResultMessage); ok {
+			result = r
+		}
+	}
+	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	if len(reqs[0].Stop) != 1 || reqs[0].Stop[0] != "</final>" {
+		t.Errorf("request stop = %v, want [</final>]", reqs[0].Stop)
+	}
+	if q.GetExitReason() != ExitStopSequence {
+		t.Errorf("exit reason = %q, want stop_sequence", q.GetExitReason())
+	}
+	if result == nil || result.IsError || result.Result != "The answer is 42" {
+		t.Fatalf("result = %+v, want success with the final text", result)
+	}
+	if result.StopReason == nil || *result.StopReason != "stop_sequence" ||
+		result.StopSequence == nil || *result.StopSequence != "</final>" {
+		t.Errorf("result stop_reason = %v, stop_sequence = %v", result.StopReason, result.StopSequence)
+	}
+}
+
 func TestLoop_AgentConfigNewFields(t *testing.T) {
 	// Verify the new config fields can be set without breaking anything
 	config := DefaultConfig()
-	config.FallbackModel = "claude-haiku-4-5-20251001"
+	config.FallbackModels = []string{"claude-haiku-4-5-20251001"}
 	tokens := 1024
 	config.MaxThinkingTkns = &tokens
 	config.AdditionalDirs = []string{"/tmp/extra"}
 	config.Betas = []string{"prompt-caching-2025-04-01"}
 	config.DebugFile = "/tmp/debug.log"
 
-	if config.FallbackModel != "claude-haiku-4-5-20251001" {
-		t.Errorf("FallbackModel = %q", config.FallbackModel)
+	if len(config.FallbackModels) != 1 || config.FallbackModels[0] != "claude-haiku-4-5-20251001" {
+		t.Errorf("FallbackModels = %q", config.FallbackModels)
 	}
 	if config.MaxThinkingTkns == nil || *config.MaxThinkingTkns != 1024 {
 		t.Error("MaxThinkingTkns not set correctly")
@@ -2234,8 +3047,42 @@ func TestLoop_MaxThinkingTokens_FromControlCommand(t *testing.T) {
 		t.Errorf("state.MaxThinkingTokens = %d, want 2048", mtt)
 	}
 
+	// The next request carries the new budget
+	q.SendUserMessage([]byte("Think harder"))
+	time.Sleep(200 * time.Millisecond)
 	q.Close()
 	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	if _, ok := reqs[0].ExtraBody["thinking"]; ok {
+		t.Error("first request should not enable thinking")
+	}
+	thinking, _ := reqs[1].ExtraBody["thinking"].(map[string]any)
+	if thinking == nil || thinking["budget_tokens"] != 2048 {
+		t.Errorf("second request thinking = %v, want budget_tokens 2048", thinking)
+	}
+}
+
+func TestLoop_ReasoningEffort(t *testing.T) {
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{endTurnResponse("Done")}}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Model = "o3"
+	config.ReasoningEffort = "low"
+
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 1 || reqs[0].ReasoningEffort != "low" {
+		t.Fatalf("requests = %d, want one with reasoning_effort low", len(reqs))
+	}
+	if reqs[0].ExtraBody != nil {
+		t.Errorf("o-series request should not carry extra_body, got %v", reqs[0].ExtraBody)
+	}
 }
 
 // --- Stub Tests for Unimplemented Features ---
@@ -2745,7 +3592,7 @@ func TestLoop_FallbackModelOnError(t *testing.T) {
 	}
 	registry := tools.NewRegistry()
 	config := defaultConfig(client, registry)
-	config.FallbackModel = "gpt-5-nano"
+	config.FallbackModels = []string{"gpt-5-nano"}
 
 	q := RunLoop(context.Background(), "Hello", config)
 	msgs := collectMessages(q)
@@ -2765,7 +3612,7 @@ func TestLoop_FallbackModelOnlyOnce(t *testing.T) {
 	failClient := &alwaysFailClient{err: fmt.Errorf("429 rate_limit")}
 	registry := tools.NewRegistry()
 	config := defaultConfig(failClient, registry)
-	config.FallbackModel = "gpt-5-nano"
+	config.FallbackModels = []string{"gpt-5-nano"}
 
 	q := RunLoop(context.Background(), "Hello", config)
 	collectMessages(q)
@@ -2777,6 +3624,102 @@ func TestLoop_FallbackModelOnlyOnce(t *testing.T) {
 	}
 }
 
+// modelFailClient fails the first failures[model] calls for each model with
+// a retriable error and serves responses in order otherwise.
+type modelFailClient struct {
+	mu        sync.Mutex
+	failures  map[string]int
+	responses []*mockStream
+	models    []string // requested model per call
+}
+
+func (c *modelFailClient) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Stream, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.models = append(c.models, req.Model)
+	if c.failures[req.Model] > 0 {
+		c.failures[req.Model]--
+		return nil, fmt.Errorf("503 overloaded: %s", req.Model)
+	}
+	if len(c.responses) == 0 {
+		return makeMockStream(endTurnResponse("No more responses")), nil
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp.toStream(ctx), nil
+}
+func (c *modelFailClient) Model() string     { return "" }
+func (c *modelFailClient) SetModel(s string) {}
+
+func TestLoop_FallbackChain(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		sticky bool
+		want   []string
+	}{
+		{"reset next turn", false, []string{"primary", "backup-1", "backup-2", "primary"}},
+		{"sticky", true, []string{"primary", "backup-1", "backup-2", "backup-2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &modelFailClient{
+				failures: map[string]int{"primary": 1, "backup-1": 1},
+				responses: []*mockStream{
+					toolUseResponse("tc1", "Echo", map[string]any{}),
+					endTurnResponse("done"),
+				},
+			}
+			registry := tools.NewRegistry()
+			registry.Register(&mockRecordingTool{name: "Echo", output: tools.ToolOutput{Content: "ok"}})
+			config := defaultConfig(client, registry)
+			config.Model = "primary"
+			config.FallbackModels = []string{"backup-1", "backup-2"}
+			config.StickyFallback = tc.sticky
+
+			q := RunLoop(context.Background(), "Hello", config)
+			msgs := collectMessages(q)
+			q.Wait()
+
+			if q.GetExitReason() != ExitEndTurn {
+				t.Fatalf("exit reason = %s, want end_turn", q.GetExitReason())
+			}
+			if !slices.Equal(client.models, tc.want) {
+				t.Errorf("requested models = %v, want %v", client.models, tc.want)
+			}
+			var changes []string
+			for _, m := range msgs {
+				if s, ok := m.(*types.StatusMessage); ok && s.ModelChange != nil {
+					changes = append(changes, s.ModelChange.From+">"+s.ModelChange.To)
+				}
+			}
+			wantChanges := []string{"primary>backup-1", "backup-1>backup-2"}
+			if !tc.sticky {
+				wantChanges = append(wantChanges, "backup-2>primary")
+			}
+			if !slices.Equal(changes, wantChanges) {
+				t.Errorf("model changes = %v, want %v", changes, wantChanges)
+			}
+		})
+	}
+}
+
+func TestLoop_FallbackChainExhausted(t *testing.T) {
+	client := &modelFailClient{failures: map[string]int{"primary": 1, "backup-1": 1, "backup-2": 1}}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.Model = "primary"
+	config.FallbackModels = []string{"backup-1", "backup-2"}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+
+	if q.GetExitReason() != ExitReason("error") {
+		t.Errorf("exit reason = %s, want error once the chain is exhausted", q.GetExitReason())
+	}
+	if want := []string{"primary", "backup-1", "backup-2"}; !slices.Equal(client.models, want) {
+		t.Errorf("requested models = %v, want each tried once: %v", client.models, want)
+	}
+}
+
 type alwaysFailClient struct {
 	err error
 }
@@ -2787,6 +3730,54 @@ func (c *alwaysFailClient) Complete(ctx context.Context, req *llm.CompletionRequ
 func (c *alwaysFailClient) Model() string    { return "" }
 func (c *alwaysFailClient) SetModel(s string) {}
 
+func TestLoop_ResultErrorDetails(t *testing.T) {
+	client := &alwaysFailClient{err: &llm.LLMError{StatusCode: 429, SDKError: "rate_limit", Message: "slow down", Retryable: true}}
+	q := RunLoop(context.Background(), "Hello", defaultConfig(client, tools.NewRegistry()))
+	var result *types.ResultMessage
+	for _, msg := range collectMessages(q) {
+		if r, ok := msg.(*types.ResultMessage); ok {
+			result = r
+		}
+	}
+	q.Wait()
+
+	if result == nil || len(result.Errors) == 0 {
+		t.Fatalf("result = %+v, want the error strings kept", result)
+	}
+	want := types.ResultError{Code: types.ResultErrorRateLimit, Message: client.err.Error(), Retriable: true}
+	if len(result.ErrorDetails) != 1 || result.ErrorDetails[0] != want {
+		t.Errorf("ErrorDetails = %+v, want %+v", result.ErrorDetails, want)
+	}
+}
+
+func TestResultErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		state     LoopState
+		code      types.ResultErrorCode
+		retriable bool
+	}{
+		{"max turns", LoopState{ExitReason: ExitMaxTurns}, types.ResultErrorMaxTurns, false},
+		{"budget", LoopState{ExitReason: ExitMaxBudget}, types.ResultErrorBudget, false},
+		{"max tokens", LoopState{ExitReason: ExitMaxTokens}, types.ResultErrorMaxTokens, false},
+		{"aborted", LoopState{ExitReason: ExitAborted}, types.ResultErrorAborted, false},
+		{"permission interrupt", LoopState{ExitReason: ExitInterrupted, LastError: errToolInterrupt}, types.ResultErrorToolError, false},
+		{"auth", LoopState{ExitReason: "error", LastError: &llm.LLMError{StatusCode: 401, SDKError: "authentication_failed"}}, types.ResultErrorAuth, false},
+		{"overflow", LoopState{ExitReason: "error", LastError: &llm.LLMError{StatusCode: 400, SDKError: "invalid_request", Message: "prompt is too long: 210000 tokens"}}, types.ResultErrorContextOverflow, false},
+		{"retries exhausted", LoopState{ExitReason: "error", LastError: fmt.Errorf("complete: %w", &llm.ErrMaxRetriesExceeded{Attempts: 3, LastStatus: 503})}, types.ResultErrorServerError, true},
+		{"cancelled", LoopState{ExitReason: "error", LastError: context.Canceled}, types.ResultErrorAborted, false},
+		{"untyped", LoopState{ExitReason: "error", LastError: errors.New("connection reset")}, types.ResultErrorUnknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resultErrors(&tt.state)
+			if len(got) != 1 || got[0].Code != tt.code || got[0].Retriable != tt.retriable || got[0].Message == "" {
+				t.Errorf("resultErrors = %+v, want code %s retriable %v", got, tt.code, tt.retriable)
+			}
+		})
+	}
+}
+
 func TestLoop_ContextLimitFunc(t *testing.T) {
 	client := &mockLLMClient{
 		responses: []*mockStream{endTurnResponse("Hello!")},
@@ -2834,6 +3825,132 @@ func TestLoop_BudgetDowngrade(t *testing.T) {
 	}
 }
 
+func TestLoop_BudgetWarnings(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "TestTool", map[string]any{"input": "x"}),
+			toolUseResponse("call_2", "TestTool", map[string]any{"input": "y"}),
+			endTurnResponse("Done"),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "TestTool", output: tools.ToolOutput{Content: "ok"}})
+
+	config := defaultConfig(client, registry)
+	config.MaxBudgetUSD = 1.0
+	config.BudgetWarnThresholds = []float64{0.5, 1e-9}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	var warnings []*types.BudgetStatus
+	for _, m := range msgs {
+		if sm, ok := m.(*types.StatusMessage); ok && sm.Status != nil && *sm.Status == types.StatusBudgetWarning {
+			warnings = append(warnings, sm.Budget)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("got %d budget warnings, want 1 (each threshold fires once, 0.5 never reached)", len(warnings))
+	}
+	w := warnings[0]
+	if w.Threshold != 1e-9 || w.LimitUSD != 1.0 || w.SpentUSD <= 0 {
+		t.Errorf("warning = %+v", w)
+	}
+	if w.RemainingUSD != w.LimitUSD-w.SpentUSD {
+		t.Errorf("remaining = %v, want %v", w.RemainingUSD, w.LimitUSD-w.SpentUSD)
+	}
+}
+
+func TestLoop_ModelBudgets(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "TestTool", map[string]any{"input": "x"}),
+			endTurnResponse("Done"),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "TestTool", output: tools.ToolOutput{Content: "ok"}})
+
+	model := "claude-sonnet-4-5-20250929"
+	config := defaultConfig(client, registry)
+	config.ModelBudgets = map[string]float64{model: 1e-9, "claude-opus-4-5-20250514": 100}
+	config.ModelBudgetWarnThreshold = 0.5
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if q.GetExitReason() != ExitMaxBudget {
+		t.Fatalf("exit reason = %s, want %s", q.GetExitReason(), ExitMaxBudget)
+	}
+	var warnings []*types.BudgetStatus
+	var result *types.ResultMessage
+	for _, m := range msgs {
+		switch m := m.(type) {
+		case *types.StatusMessage:
+			if m.Budget != nil {
+				warnings = append(warnings, m.Budget)
+			}
+		case *types.ResultMessage:
+			result = m
+		}
+	}
+	if len(warnings) != 1 || warnings[0].Model != model || warnings[0].LimitUSD != 1e-9 {
+		t.Errorf("warnings = %+v, want one for %s", warnings, model)
+	}
+	if result == nil || len(result.Errors) != 2 || !strings.Contains(result.Errors[1], model) {
+		t.Fatalf("result errors = %v, want the exceeded model named", result.Errors)
+	}
+	usage, ok := result.ModelUsage[model]
+	if !ok || usage.CostUSD <= 0 || usage.BudgetUSD != 1e-9 {
+		t.Errorf("model usage = %+v, want cost and budget for %s", result.ModelUsage, model)
+	}
+}
+
+func TestLoop_Metrics(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "TestTool", map[string]any{"input": "x"}),
+			endTurnResponse("Done"),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "TestTool", output: tools.ToolOutput{Content: "ok"}})
+
+	metrics := NewPrometheusMetrics("")
+	config := defaultConfig(client, registry)
+	config.Metrics = metrics
+
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+
+	var out strings.Builder
+	metrics.WriteTo(&out)
+	text := out.String()
+	model := "claude-sonnet-4-5-20250929"
+	for _, want := range []string{
+		`goat_turns_total{model="` + model + `"} 2`,
+		`goat_input_tokens_total{model="` + model + `"} 300`,
+		`goat_output_tokens_total{model="` + model + `"} 130`,
+		`goat_tool_calls_total{tool="TestTool",model="` + model + `",status="ok"} 1`,
+		`goat_tool_duration_seconds_count{tool="TestTool"} 1`,
+		`goat_exits_total{model="` + model + `",reason="end_turn"} 1`,
+		`goat_run_turns_sum{model="` + model + `"} 2`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("metrics missing %q\n%s", want, text)
+		}
+	}
+
+	// The tool is charged the full cost of the single-call response that requested it
+	wantCost := llm.CalculateCost(model, types.BetaUsage{InputTokens: 200, OutputTokens: 80})
+	if want := fmt.Sprintf(`goat_tool_cost_usd_total{tool="TestTool",model="%s"} %s`, model, formatFloat(wantCost)); !strings.Contains(text, want) {
+		t.Errorf("metrics missing %q\n%s", want, text)
+	}
+}
+
 func TestLoop_ModelBreakdownQuery(t *testing.T) {
 	client := &mockLLMClient{
 		responses: []*mockStream{endTurnResponse("Hello!")},
@@ -2955,3 +4072,313 @@ func TestLoop_DynamicModelSelection_Default(t *testing.T) {
 		t.Errorf("state.Model = %q, want empty (default)", q.State().Model)
 	}
 }
+
+// rateLimitedClient gates an inner client on a limiter, as the HTTP client does.
+type rateLimitedClient struct {
+	*mockLLMClient
+	limiter *llm.RateLimiter
+}
+
+func (c *rateLimitedClient) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Stream, error) {
+	if _, err := c.limiter.Wait(ctx, 15); err != nil {
+		return nil, err
+	}
+	return c.mockLLMClient.Complete(ctx, req)
+}
+
+func TestLoop_RateLimitedStatus(t *testing.T) {
+	limiter := llm.NewRateLimiter(0, 600)
+	limiter.Reserve(600) // drained: the next 15 tokens take 1.5s to refill
+
+	client := &rateLimitedClient{
+		mockLLMClient: &mockLLMClient{responses: []*mockStream{endTurnResponse("Done")}},
+		limiter:       limiter,
+	}
+	q := RunLoop(context.Background(), "Hello", defaultConfig(client, tools.NewRegistry()))
+	msgs := collectMessages(q)
+	q.Wait()
+
+	var statuses []*types.StatusMessage
+	for _, m := range msgs {
+		if sm, ok := m.(*types.StatusMessage); ok && sm.Status != nil && *sm.Status == types.StatusRateLimited {
+			statuses = append(statuses, sm)
+		}
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("got %d rate_limited statuses, want 1", len(statuses))
+	}
+	if rl := statuses[0].RateLimit; rl == nil || rl.WaitMs < 1000 {
+		t.Errorf("rate limit = %+v, want a wait of about 1500ms", rl)
+	}
+	if q.GetExitReason() != ExitEndTurn {
+		t.Errorf("exit reason = %v, want end_turn", q.GetExitReason())
+	}
+}
+
+func TestLoop_MaxOutputTokens(t *testing.T) {
+	run := func(configure func(*AgentConfig)) int {
+		client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{endTurnResponse("Done")}}}
+		config := defaultConfig(client, tools.NewRegistry())
+		configure(&config)
+		q := RunLoop(context.Background(), "Hello", config)
+		collectMessages(q)
+		q.Wait()
+		return client.getRequests()[0].MaxTokens
+	}
+
+	if got := run(func(*AgentConfig) {}); got != 16384 {
+		t.Errorf("default max_tokens = %d, want 16384", got)
+	}
+	if got := run(func(c *AgentConfig) { c.MaxOutputTokens = 8192 }); got != 8192 {
+		t.Errorf("MaxOutputTokens max_tokens = %d, want 8192", got)
+	}
+	perModel := func(c *AgentConfig) {
+		c.MaxOutputTokens = 8192
+		c.MaxOutputTokensFunc = func(model string) int {
+			if model == "claude-sonnet-4-5-20250929" {
+				return 64000
+			}
+			return 0
+		}
+	}
+	if got := run(perModel); got != 64000 {
+		t.Errorf("MaxOutputTokensFunc max_tokens = %d, want 64000", got)
+	}
+	if got := run(func(c *AgentConfig) { perModel(c); c.Model = "other" }); got != 8192 {
+		t.Errorf("max_tokens = %d, want MaxOutputTokens when the func returns 0", got)
+	}
+
+	config := &AgentConfig{Model: "test", MaxOutputTokens: 4096}
+	if b := calculateTokenBudget(config, &LoopState{}, ""); b.MaxOutputTkns != 4096 {
+		t.Errorf("budget MaxOutputTkns = %d, want 4096", b.MaxOutputTkns)
+	}
+}
+
+// gatedTool signals when it starts and waits for release before returning.
+type gatedTool struct {
+	mockRecordingTool
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedTool) Execute(ctx context.Context, input map[string]any) (tools.ToolOutput, error) {
+	close(g.started)
+	<-g.release
+	return g.mockRecordingTool.Execute(ctx, input)
+}
+
+func TestLoop_SteerInjectsAfterToolBatch(t *testing.T) {
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call_1", "Slow", map[string]any{}),
+		endTurnResponse("Switched to b"),
+	}}}
+	tool := &gatedTool{
+		mockRecordingTool: mockRecordingTool{name: "Slow", output: tools.ToolOutput{Content: "ok"}},
+		started:           make(chan struct{}),
+		release:           make(chan struct{}),
+	}
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+
+	q := RunLoop(context.Background(), "Edit a", defaultConfig(client, registry))
+	go func() {
+		<-tool.started
+		if err := q.Steer([]byte("use b instead")); err != nil {
+			t.Errorf("Steer: %v", err)
+		}
+		close(tool.release)
+	}()
+	collectMessages(q)
+	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d LLM calls, want 2", len(reqs))
+	}
+	msgs := reqs[1].Messages
+	last, prev := msgs[len(msgs)-1], msgs[len(msgs)-2]
+	if prev.Role != "tool" {
+		t.Errorf("message before the correction has role %q, want the tool result", prev.Role)
+	}
+	content, _ := last.Content.(string)
+	if last.Role != "user" || !strings.HasPrefix(content, steerNotice) || !strings.HasSuffix(content, "use b instead") {
+		t.Errorf("last message = %+v, want the marked correction", last)
+	}
+	if err := q.Steer([]byte("too late")); !errors.Is(err, ErrQueryClosed) {
+		t.Errorf("Steer after exit = %v, want ErrQueryClosed", err)
+	}
+}
+
+// steeringClient steers the query from inside its first Complete call.
+type steeringClient struct {
+	*capturingLLMClient
+	q   *Query
+	msg string
+}
+
+func (c *steeringClient) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Stream, error) {
+	if len(c.getRequests()) == 0 {
+		c.q.Steer([]byte(c.msg))
+	}
+	return c.capturingLLMClient.Complete(ctx, req)
+}
+
+func TestLoop_SteerDuringFinalCallContinuesTurn(t *testing.T) {
+	client := &steeringClient{
+		capturingLLMClient: &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{
+			endTurnResponse("Done with a"),
+			endTurnResponse("Done with b"),
+		}}},
+		msg: "also b",
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+
+	// Hold the loop at SessionStart until client.q is set.
+	ready := make(chan struct{})
+	config.Hooks = &readyHookRunner{ready: ready}
+	client.q = RunLoop(context.Background(), "Edit a", config)
+	close(ready)
+	collectMessages(client.q)
+	client.q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d LLM calls, want 2 (the steer redirects the ended turn)", len(reqs))
+	}
+	last := reqs[1].Messages[len(reqs[1].Messages)-1]
+	if content, _ := last.Content.(string); !strings.HasSuffix(content, "also b") {
+		t.Errorf("last message = %+v, want the correction", last)
+	}
+}
+
+// readyHookRunner blocks every hook until ready is closed.
+type readyHookRunner struct {
+	NoOpHookRunner
+	ready chan struct{}
+}
+
+func (r *readyHookRunner) Fire(ctx context.Context, event types.HookEvent, input any) ([]HookResult, error) {
+	<-r.ready
+	return r.NoOpHookRunner.Fire(ctx, event, input)
+}
+
+func TestDispatchControl_Steer(t *testing.T) {
+	q := &Query{}
+	resp := dispatchControl(&AgentConfig{}, &LoopState{}, q, types.ControlRequest{
+		RequestID: "s1",
+		Request:   types.ControlRequestInner{Subtype: types.ControlSubtypeSteer},
+	})
+	if _, ok := resp.Response.(types.ControlErrorResponse); !ok {
+		t.Errorf("empty prompt response = %+v, want an error", resp.Response)
+	}
+	resp = dispatchControl(&AgentConfig{}, &LoopState{}, q, types.ControlRequest{
+		RequestID: "s2",
+		Request:   types.ControlRequestInner{Subtype: types.ControlSubtypeSteer, Prompt: "stop editing tests"},
+	})
+	if _, ok := resp.Response.(types.ControlSuccessResponse); !ok {
+		t.Errorf("response = %+v, want success", resp.Response)
+	}
+	if got := q.takeSteering(); len(got) != 1 || string(got[0]) != "stop editing tests" {
+		t.Errorf("queued = %q", got)
+	}
+}
+
+func TestLoop_ReplayReproducesRecordedRun(t *testing.T) {
+	run := func(client llm.Client) (*types.ResultMessage, int) {
+		tool := &mockRecordingTool{name: "TestTool", output: tools.ToolOutput{Content: "ok"}}
+		registry := tools.NewRegistry()
+		registry.Register(tool)
+		q := RunLoop(context.Background(), "Hello", defaultConfig(client, registry))
+		var result *types.ResultMessage
+		for _, m := range collectMessages(q) {
+			if r, ok := m.(*types.ResultMessage); ok {
+				result = r
+			}
+		}
+		q.Wait()
+		if result == nil {
+			t.Fatal("no result message")
+		}
+		return result, tool.CallCount()
+	}
+
+	path := filepath.Join(t.TempDir(), "run.jsonl")
+	recorder, err := llm.NewRecordingClient(&mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call_1", "TestTool", map[string]any{"input": "x"}),
+		endTurnResponse("All done"),
+	}}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, recordedCalls := run(recorder)
+	recorder.Close()
+
+	recs, err := llm.LoadRecordings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := llm.NewReplayClient(recs, llm.ReplayBySequence)
+	replayed, replayedCalls := run(replay)
+
+	if replayed.Result != recorded.Result || replayed.NumTurns != recorded.NumTurns ||
+		replayed.Usage != recorded.Usage || replayed.Subtype != recorded.Subtype {
+		t.Errorf("replayed result = %+v, recorded %+v", replayed, recorded)
+	}
+	if replayedCalls != recordedCalls || replayedCalls != 1 {
+		t.Errorf("tool calls: replayed %d, recorded %d, want 1", replayedCalls, recordedCalls)
+	}
+	if replay.Remaining() != 0 {
+		t.Errorf("%d recordings left unused", replay.Remaining())
+	}
+}
+
+// stubGitContext returns a fixed GitContext and counts its calls.
+type stubGitContext struct {
+	info  GitContext
+	calls int
+}
+
+func (s *stubGitContext) GitContext(_ context.Context, _ string) (GitContext, bool, error) {
+	s.calls++
+	return s.info, true, nil
+}
+
+// gitPrompter records the git fields the assembler is given.
+type gitPrompter struct{ prompt string }
+
+func (p *gitPrompter) Assemble(config *AgentConfig) string {
+	p.prompt = "branch=" + config.GitBranch + " main=" + config.GitMainBranch + " status=" + config.GitStatus + " commits=" + config.GitRecentCommits
+	return p.prompt
+}
+
+func TestLoop_GitContextProvider(t *testing.T) {
+	run := func(provider *stubGitContext, hostBranch string) string {
+		client := &mockLLMClient{responses: []*mockStream{endTurnResponse("done")}}
+		config := defaultConfig(client, tools.NewRegistry())
+		config.CWD = "/repo"
+		prompter := &gitPrompter{}
+		config.Prompter = prompter
+		config.GitContextProvider = provider
+		config.GitBranch = hostBranch
+		q := RunLoop(context.Background(), "Hello", config)
+		collectMessages(q)
+		q.Wait()
+		return prompter.prompt
+	}
+
+	provider := &stubGitContext{info: GitContext{Branch: "feature", MainBranch: "main", Status: " M a.go", RecentCommits: "abc123 fix"}}
+	if got, want := run(provider, ""), "branch=feature main=main status= M a.go commits=abc123 fix"; got != want {
+		t.Errorf("system prompt = %q, want %q", got, want)
+	}
+	if provider.calls != 1 {
+		t.Errorf("provider called %d times per run, want 1", provider.calls)
+	}
+
+	provider = &stubGitContext{info: GitContext{Branch: "feature"}}
+	if got := run(provider, "host-branch"); !strings.HasPrefix(got, "branch=host-branch ") {
+		t.Errorf("host-set GitBranch was replaced: %q", got)
+	}
+	if provider.calls != 0 {
+		t.Errorf("provider called %d times with GitBranch already set", provider.calls)
+	}
+}
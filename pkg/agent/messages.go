@@ -2,6 +2,7 @@ package agent
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/types"
@@ -43,6 +44,17 @@ func responseToAssistantMessage(resp *llm.CompletionResponse) llm.ChatMessage {
 	return cm
 }
 
+// responseThinking joins the thinking blocks of a CompletionResponse.
+func responseThinking(resp *llm.CompletionResponse) string {
+	var parts []string
+	for _, block := range resp.Content {
+		if block.Type == "thinking" && block.Thinking != "" {
+			parts = append(parts, block.Thinking)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
 // toolResultsToMessages converts tool execution results to OpenAI "tool" role messages.
 func toolResultsToMessages(results []llm.ToolResult) []llm.ChatMessage {
 	return llm.ConvertToToolMessages(results)
@@ -59,6 +71,40 @@ func extractToolUseBlocks(resp *llm.CompletionResponse) []types.ContentBlock {
 	return blocks
 }
 
+// rawArgumentsKey holds a tool call's arguments when the stream accumulator
+// could not parse them as JSON.
+const rawArgumentsKey = "_raw"
+
+// repairToolCallsEnabled reports whether malformed tool-call JSON from model
+// is repaired: with RepairToolCalls, and always for Groq/Llama models.
+func repairToolCallsEnabled(config *AgentConfig, model string) bool {
+	return config.RepairToolCalls || llm.IsGroqLlama(model)
+}
+
+// repairToolBlocks re-parses tool_use blocks whose arguments were not valid
+// JSON. Blocks that cannot be repaired keep their raw arguments and fail in
+// checkToolArguments instead of running.
+func repairToolBlocks(resp *llm.CompletionResponse) {
+	for i, b := range resp.Content {
+		raw, ok := rawArguments(b.Input)
+		if b.Type != "tool_use" || !ok {
+			continue
+		}
+		if input, err := llm.RepairToolArguments(raw); err == nil {
+			resp.Content[i].Input = input
+		}
+	}
+}
+
+// rawArguments returns the unparsed arguments of a tool call whose JSON was invalid.
+func rawArguments(input map[string]any) (string, bool) {
+	if len(input) != 1 {
+		return "", false
+	}
+	raw, ok := input[rawArgumentsKey].(string)
+	return raw, ok
+}
+
 // discardTruncatedToolBlocks removes tool_use blocks with incomplete JSON arguments
 // from a max_tokens response. This prevents executing tools with partially-formed input.
 func discardTruncatedToolBlocks(resp *llm.CompletionResponse) {
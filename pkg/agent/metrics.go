@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// DefaultToolDurationBuckets are the histogram bounds (seconds) for tool latency.
+var DefaultToolDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+// DefaultTurnBuckets are the histogram bounds for turns per run.
+var DefaultTurnBuckets = []float64{1, 2, 5, 10, 20, 50, 100}
+
+// PrometheusMetrics is a Metrics implementation that keeps counters and
+// histograms in memory and serves them in the Prometheus text exposition
+// format. It is an http.Handler, so it can be mounted at /metrics directly.
+type PrometheusMetrics struct {
+	mu sync.Mutex
+
+	turns          *counterVec
+	inputTokens    *counterVec
+	outputTokens   *counterVec
+	cacheReadTkns  *counterVec
+	cacheWriteTkns *counterVec
+	cost           *counterVec
+	toolCalls      *counterVec
+	toolCost       *counterVec
+	toolDuration   *histogramVec
+	compactions    *counterVec
+	exits          *counterVec
+	runTurns       *histogramVec
+}
+
+// NewPrometheusMetrics creates a PrometheusMetrics whose metric names are
+// prefixed with namespace (default "goat").
+func NewPrometheusMetrics(namespace string) *PrometheusMetrics {
+	if namespace == "" {
+		namespace = "goat"
+	}
+	name := func(n string) string { return namespace + "_" + n }
+	return &PrometheusMetrics{
+		turns:          newCounterVec(name("turns_total"), "LLM calls (turns) made by the agent loop.", "model"),
+		inputTokens:    newCounterVec(name("input_tokens_total"), "Input tokens sent to the LLM.", "model"),
+		outputTokens:   newCounterVec(name("output_tokens_total"), "Output tokens generated by the LLM.", "model"),
+		cacheReadTkns:  newCounterVec(name("cache_read_input_tokens_total"), "Input tokens read from the prompt cache.", "model"),
+		cacheWriteTkns: newCounterVec(name("cache_creation_input_tokens_total"), "Input tokens written to the prompt cache.", "model"),
+		cost:           newCounterVec(name("cost_usd_total"), "Estimated LLM cost in USD.", "model"),
+		toolCalls:      newCounterVec(name("tool_calls_total"), "Tool executions by outcome.", "tool", "model", "status"),
+		toolCost:       newCounterVec(name("tool_cost_usd_total"), "LLM cost in USD attributed to the tool calls each response requested.", "tool", "model"),
+		toolDuration:   newHistogramVec(name("tool_duration_seconds"), "Tool execution latency.", DefaultToolDurationBuckets, "tool"),
+		compactions:    newCounterVec(name("compactions_total"), "Context compactions.", "model", "trigger"),
+		exits:          newCounterVec(name("exits_total"), "Agent loop terminations by exit reason.", "model", "reason"),
+		runTurns:       newHistogramVec(name("run_turns"), "Turns taken per agent loop run.", DefaultTurnBuckets, "model"),
+	}
+}
+
+// RecordLLMCall implements Metrics.
+func (m *PrometheusMetrics) RecordLLMCall(model string, usage types.BetaUsage, costUSD float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.turns.add(1, model)
+	m.inputTokens.add(float64(usage.InputTokens), model)
+	m.outputTokens.add(float64(usage.OutputTokens), model)
+	m.cacheReadTkns.add(float64(usage.CacheReadInputTokens), model)
+	m.cacheWriteTkns.add(float64(usage.CacheCreationInputTokens), model)
+	m.cost.add(costUSD, model)
+}
+
+// RecordToolCall implements Metrics.
+func (m *PrometheusMetrics) RecordToolCall(tool, model string, duration time.Duration, isError bool, costUSD float64) {
+	status := "ok"
+	if isError {
+		status = "error"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolCalls.add(1, tool, model, status)
+	m.toolCost.add(costUSD, tool, model)
+	m.toolDuration.observe(duration.Seconds(), tool)
+}
+
+// RecordCompaction implements Metrics.
+func (m *PrometheusMetrics) RecordCompaction(model, trigger string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compactions.add(1, model, trigger)
+}
+
+// RecordExit implements Metrics.
+func (m *PrometheusMetrics) RecordExit(model string, reason ExitReason, turns int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exits.add(1, model, string(reason))
+	m.runTurns.observe(float64(turns), model)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	m.mu.Lock()
+	for _, c := range []*counterVec{m.turns, m.inputTokens, m.outputTokens, m.cacheReadTkns, m.cacheWriteTkns, m.cost, m.toolCalls, m.toolCost} {
+		c.write(&b)
+	}
+	m.toolDuration.write(&b)
+	m.compactions.write(&b)
+	m.exits.write(&b)
+	m.runTurns.write(&b)
+	m.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics for a Prometheus scrape.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// counterVec is a counter family keyed by label values.
+type counterVec struct {
+	name, help string
+	labels     []string
+	values     map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+}
+
+func (c *counterVec) add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labels: labelValues}
+		c.values[key] = cv
+	}
+	cv.value += v
+}
+
+func (c *counterVec) write(b *strings.Builder) {
+	if len(c.values) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		fmt.Fprintf(b, "%s%s %s\n", c.name, formatLabels(c.labels, cv.labels, "", ""), formatFloat(cv.value))
+	}
+}
+
+// histogramVec is a histogram family keyed by label values.
+type histogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	values     map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // per bucket, non-cumulative
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			hv.counts[i]++
+			break
+		}
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *histogramVec) write(b *strings.Builder) {
+	if len(h.values) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, hv.labels, "le", formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, hv.labels, "le", "+Inf"), hv.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.name, formatLabels(h.labels, hv.labels, "", ""), formatFloat(hv.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, formatLabels(h.labels, hv.labels, "", ""), hv.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels renders {name="value",...}, with an optional extra label (le).
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	var parts []string
+	for i, name := range names {
+		parts = append(parts, name+`="`+escapeLabelValue(values[i])+`"`)
+	}
+	if extraName != "" {
+		parts = append(parts, extraName+`="`+extraValue+`"`)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
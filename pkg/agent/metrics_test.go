@@ -0,0 +1,67 @@
+package agent
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestPrometheusMetrics_Exposition(t *testing.T) {
+	m := NewPrometheusMetrics("svc")
+	m.RecordLLMCall("m1", types.BetaUsage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 3}, 0.25)
+	m.RecordLLMCall("m1", types.BetaUsage{InputTokens: 20, OutputTokens: 5}, 0.5)
+	m.RecordToolCall("Bash", "m1", 30*time.Millisecond, false, 0.1)
+	m.RecordToolCall("Bash", "m1", 3*time.Second, true, 0.1)
+	m.RecordCompaction("m1", "auto")
+	m.RecordExit("m1", ExitMaxTurns, 2)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	text := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE svc_turns_total counter",
+		`svc_turns_total{model="m1"} 2`,
+		`svc_input_tokens_total{model="m1"} 30`,
+		`svc_cache_read_input_tokens_total{model="m1"} 3`,
+		`svc_cost_usd_total{model="m1"} 0.75`,
+		`svc_tool_calls_total{tool="Bash",model="m1",status="ok"} 1`,
+		`svc_tool_calls_total{tool="Bash",model="m1",status="error"} 1`,
+		`svc_tool_cost_usd_total{tool="Bash",model="m1"} 0.2`,
+		"# TYPE svc_tool_duration_seconds histogram",
+		`svc_tool_duration_seconds_bucket{tool="Bash",le="0.01"} 0`,
+		`svc_tool_duration_seconds_bucket{tool="Bash",le="0.05"} 1`,
+		`svc_tool_duration_seconds_bucket{tool="Bash",le="2.5"} 1`,
+		`svc_tool_duration_seconds_bucket{tool="Bash",le="5"} 2`,
+		`svc_tool_duration_seconds_bucket{tool="Bash",le="+Inf"} 2`,
+		`svc_tool_duration_seconds_sum{tool="Bash"} 3.03`,
+		`svc_tool_duration_seconds_count{tool="Bash"} 2`,
+		`svc_compactions_total{model="m1",trigger="auto"} 1`,
+		`svc_exits_total{model="m1",reason="max_turns"} 1`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%s", want, text)
+		}
+	}
+}
+
+func TestPrometheusMetrics_EscapesLabelsAndSkipsEmpty(t *testing.T) {
+	m := NewPrometheusMetrics("")
+	m.RecordToolCall(`mcp__a"b\c`, "m", time.Millisecond, false, 0)
+
+	var out strings.Builder
+	m.WriteTo(&out)
+	text := out.String()
+	if !strings.Contains(text, `tool="mcp__a\"b\\c"`) {
+		t.Errorf("label value not escaped:\n%s", text)
+	}
+	if strings.Contains(text, "goat_turns_total") {
+		t.Error("families with no samples should be omitted")
+	}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// PermissionRule is one entry of a RuleBasedPermissionChecker.
+type PermissionRule struct {
+	Tool  string                          // tool name glob (path.Match syntax): "Bash", "mcp__github__*", "*"
+	Args  map[string]*regexp.Regexp       // input field → pattern its string value must match; nil = any input
+	Match func(input map[string]any) bool // optional extra condition on the input
+
+	Behavior string // "allow"|"deny"|"ask"
+	Message  string // reason reported when the rule denies or asks
+
+	// Rewrite, if set, sanitizes the input of a call the rule allows (or the
+	// user approves after an ask). The result becomes UpdatedInput.
+	Rewrite func(input map[string]any) map[string]any
+}
+
+// Matches reports whether the rule applies to a call of toolName with input.
+// Every Args pattern must match, so a rule on a field the call lacks never applies.
+func (r *PermissionRule) Matches(toolName string, input map[string]any) bool {
+	if ok, _ := path.Match(r.Tool, toolName); !ok && r.Tool != toolName {
+		return false
+	}
+	for field, re := range r.Args {
+		value, ok := input[field].(string)
+		if !ok || !re.MatchString(value) {
+			return false
+		}
+	}
+	return r.Match == nil || r.Match(input)
+}
+
+// RuleBasedPermissionChecker decides tool calls with ordered rules: the first
+// matching rule's behavior applies, else Default ("ask" when empty). An ask
+// goes to the user through the session's control channel in multi-turn mode
+// (see Query.AnswerPermission) and is denied in headless runs.
+type RuleBasedPermissionChecker struct {
+	Rules   []PermissionRule
+	Default string
+}
+
+func (c *RuleBasedPermissionChecker) Check(ctx context.Context, toolName string, input map[string]any) (PermissionResult, error) {
+	var rule *PermissionRule
+	for i := range c.Rules {
+		if c.Rules[i].Matches(toolName, input) {
+			rule = &c.Rules[i]
+			break
+		}
+	}
+	behavior := c.Default
+	if rule != nil {
+		behavior = rule.Behavior
+	}
+	if behavior == "" {
+		behavior = "ask"
+	}
+
+	switch behavior {
+	case "allow":
+		return PermissionResult{Behavior: "allow", UpdatedInput: rewriteInput(rule, input)}, nil
+
+	case "deny":
+		msg := "denied by permission rule"
+		if rule != nil && rule.Message != "" {
+			msg = rule.Message
+		}
+		return PermissionResult{Behavior: "deny", Message: msg}, nil
+
+	case "ask":
+		asker := permissionAskerFrom(ctx)
+		if asker == nil {
+			return PermissionResult{
+				Behavior: "deny",
+				Message:  fmt.Sprintf("permission required for %s (no interactive user to ask)", toolName),
+			}, nil
+		}
+		var reason string
+		if rule != nil {
+			reason = rule.Message
+		}
+		proposed := input
+		if updated := rewriteInput(rule, input); updated != nil {
+			proposed = updated
+		}
+		result, err := asker.AskPermission(ctx, toolName, proposed, reason)
+		if err != nil {
+			return PermissionResult{}, err
+		}
+		if result.Behavior == "allow" && result.UpdatedInput == nil && rule != nil && rule.Rewrite != nil {
+			result.UpdatedInput = proposed
+		}
+		return result, nil
+	}
+	return PermissionResult{}, fmt.Errorf("permission rule for %s has unknown behavior %q", toolName, behavior)
+}
+
+func rewriteInput(rule *PermissionRule, input map[string]any) map[string]any {
+	if rule == nil || rule.Rewrite == nil {
+		return nil
+	}
+	return rule.Rewrite(input)
+}
+
+// permissionAsker puts an "ask" permission decision to the user.
+type permissionAsker interface {
+	AskPermission(ctx context.Context, toolName string, input map[string]any, reason string) (PermissionResult, error)
+}
+
+type permissionAskerKey struct{}
+
+func permissionAskerFrom(ctx context.Context) permissionAsker {
+	a, _ := ctx.Value(permissionAskerKey{}).(permissionAsker)
+	return a
+}
+
+// withPermissionAsker attaches the loop's permission prompt to ctx. Like
+// AskUserQuestion, only multi-turn sessions have a host to answer; one-shot
+// runs (including subagents) clear any asker inherited from a parent.
+func withPermissionAsker(ctx context.Context, config *AgentConfig, state *LoopState, q *Query) context.Context {
+	if !config.MultiTurn {
+		return context.WithValue(ctx, permissionAskerKey{}, permissionAsker(nil))
+	}
+	return context.WithValue(ctx, permissionAskerKey{}, &controlPermissionAsker{config: config, state: state, q: q})
+}
+
+// controlPermissionAsker emits a PermissionRequestMessage and waits for the
+// host's answer_permission control request for the tool call. Other control
+// requests are dispatched as usual while it waits.
+type controlPermissionAsker struct {
+	config *AgentConfig
+	state  *LoopState
+	q      *Query
+}
+
+func (a *controlPermissionAsker) AskPermission(ctx context.Context, toolName string, input map[string]any, reason string) (PermissionResult, error) {
+	info, ok := ToolCallFromContext(ctx)
+	if !ok || info.EmitCh == nil {
+		return PermissionResult{}, errors.New("no message stream to ask the user on")
+	}
+
+	answers, release := a.q.expectAnswer(types.ControlSubtypeAnswerPermission, info.ToolUseID)
+	defer release()
+	info.EmitCh <- &types.PermissionRequestMessage{
+		BaseMessage: types.BaseMessage{UUID: uuid.New(), SessionID: a.state.SessionID},
+		Type:        types.MessageTypeSystem,
+		Subtype:     types.SystemSubtypePermission,
+		ToolUseID:   info.ToolUseID,
+		ToolName:    toolName,
+		Input:       input,
+		Reason:      reason,
+	}
+
+	r, err := awaitAnswer(ctx, a.config, a.state, a.q, types.ControlSubtypeAnswerPermission, info.ToolUseID, answers)
+	if err != nil {
+		return PermissionResult{}, err
+	}
+	if r.Behavior != "allow" {
+		msg := r.Message
+		if msg == "" {
+			msg = "permission denied by user"
+		}
+		return PermissionResult{Behavior: "deny", Message: msg}, nil
+	}
+	return PermissionResult{Behavior: "allow", UpdatedInput: r.Input}, nil
+}
@@ -0,0 +1,241 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func testRuleChecker() *RuleBasedPermissionChecker {
+	return &RuleBasedPermissionChecker{Rules: []PermissionRule{
+		{Tool: "Bash", Args: map[string]*regexp.Regexp{"command": regexp.MustCompile(`rm\s+-rf`)}, Behavior: "deny", Message: "no recursive deletes"},
+		{Tool: "Bash", Args: map[string]*regexp.Regexp{"command": regexp.MustCompile(`^git push\b`)}, Behavior: "allow",
+			Rewrite: func(input map[string]any) map[string]any {
+				cmd, _ := input["command"].(string)
+				return map[string]any{"command": strings.ReplaceAll(cmd, " --force", "")}
+			}},
+		{Tool: "Read", Behavior: "allow"},
+		{Tool: "mcp__github__*", Behavior: "deny"},
+	}}
+}
+
+func TestRuleBasedPermissionChecker(t *testing.T) {
+	c := testRuleChecker()
+	tests := []struct {
+		tool     string
+		input    map[string]any
+		behavior string
+		message  string
+	}{
+		{"Bash", map[string]any{"command": "rm -rf /tmp/x"}, "deny", "no recursive deletes"},
+		{"Read", map[string]any{"file_path": "/etc/hosts"}, "allow", ""},
+		{"mcp__github__create_issue", nil, "deny", "denied by permission rule"},
+		// No rule matches: default ask, denied without an interactive user.
+		{"Bash", map[string]any{"command": "ls"}, "deny", "no interactive user"},
+		{"Write", nil, "deny", "no interactive user"},
+	}
+	for _, tt := range tests {
+		res, err := c.Check(context.Background(), tt.tool, tt.input)
+		if err != nil {
+			t.Fatalf("Check(%s): %v", tt.tool, err)
+		}
+		if res.Behavior != tt.behavior || !strings.Contains(res.Message, tt.message) {
+			t.Errorf("Check(%s, %v) = %q %q, want %q %q", tt.tool, tt.input, res.Behavior, res.Message, tt.behavior, tt.message)
+		}
+	}
+
+	res, _ := c.Check(context.Background(), "Bash", map[string]any{"command": "git push --force origin main"})
+	if res.Behavior != "allow" || res.UpdatedInput["command"] != "git push origin main" {
+		t.Errorf("git push = %q %v, want allow with the sanitized command", res.Behavior, res.UpdatedInput)
+	}
+
+	c.Default = "allow"
+	if res, _ := c.Check(context.Background(), "Write", nil); res.Behavior != "allow" || res.UpdatedInput != nil {
+		t.Errorf("Default allow = %+v", res)
+	}
+}
+
+func TestLoop_PermissionRuleAskInteractive(t *testing.T) {
+	bash := &mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "done"}}
+	registry := tools.NewRegistry()
+	registry.Register(bash)
+	client := &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call-1", "Bash", map[string]any{"command": "make deploy"}),
+		endTurnResponse("Deployed."),
+	}}
+	config := defaultConfig(client, registry)
+	config.MultiTurn = true
+	config.Permissions = &RuleBasedPermissionChecker{Rules: []PermissionRule{
+		{Tool: "Bash", Args: map[string]*regexp.Regexp{"command": regexp.MustCompile(`deploy`)}, Behavior: "ask", Message: "deploys need approval"},
+	}}
+
+	q := RunLoop(context.Background(), "Deploy", config)
+
+	var req *types.PermissionRequestMessage
+	for m := range q.Messages() {
+		if pr, ok := m.(*types.PermissionRequestMessage); ok {
+			req = pr
+			break
+		}
+	}
+	if req == nil {
+		t.Fatal("no PermissionRequestMessage emitted")
+	}
+	if req.ToolUseID != "call-1" || req.ToolName != "Bash" || req.Reason != "deploys need approval" {
+		t.Fatalf("request = %+v", req)
+	}
+
+	resp, err := q.AnswerPermission(req.ToolUseID, types.PermissionResult{
+		Behavior:     "allow",
+		UpdatedInput: map[string]any{"command": "make deploy DRY_RUN=1"},
+	})
+	if err != nil {
+		t.Fatalf("AnswerPermission: %v", err)
+	}
+	if _, ok := resp.Response.(types.ControlSuccessResponse); !ok {
+		t.Fatalf("response = %+v", resp.Response)
+	}
+
+	go func() {
+		for range q.Messages() {
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	q.Close()
+	q.Wait()
+
+	bash.mu.Lock()
+	defer bash.mu.Unlock()
+	if len(bash.calls) != 1 || bash.calls[0]["command"] != "make deploy DRY_RUN=1" {
+		t.Errorf("Bash calls = %v, want the input the user approved", bash.calls)
+	}
+}
+
+func TestLoop_PermissionRuleAskDeniedByUser(t *testing.T) {
+	bash := &mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "done"}}
+	registry := tools.NewRegistry()
+	registry.Register(bash)
+	client := &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call-1", "Bash", map[string]any{"command": "make deploy"}),
+		endTurnResponse("OK, not deploying."),
+	}}
+	config := defaultConfig(client, registry)
+	config.MultiTurn = true
+	config.Permissions = &RuleBasedPermissionChecker{}
+
+	q := RunLoop(context.Background(), "Deploy", config)
+	for m := range q.Messages() {
+		if pr, ok := m.(*types.PermissionRequestMessage); ok {
+			if _, err := q.AnswerPermission(pr.ToolUseID, types.PermissionResult{Behavior: "deny", Message: "not today"}); err != nil {
+				t.Fatalf("AnswerPermission: %v", err)
+			}
+			break
+		}
+	}
+	go func() {
+		for range q.Messages() {
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	q.Close()
+	q.Wait()
+
+	if bash.CallCount() != 0 {
+		t.Error("Bash ran after the user denied it")
+	}
+	if got := toolResultContent(q.State(), "call-1"); !strings.Contains(got, "not today") {
+		t.Errorf("tool result = %q, want the user's reason", got)
+	}
+}
+
+// twoReadsResponse requests two Read calls in one turn, which run in parallel.
+func twoReadsResponse() *mockStream {
+	toolCalls := "tool_calls"
+	return &mockStream{chunks: []llm.StreamChunk{
+		{ID: "msg-1", Model: "claude-sonnet-4-5-20250929", Choices: []llm.Choice{{Delta: llm.Delta{ToolCalls: []llm.ToolCall{
+			{Index: 0, ID: "call-1", Type: "function", Function: llm.FunctionCall{Name: "Read", Arguments: `{"file_path":"/a"}`}},
+			{Index: 1, ID: "call-2", Type: "function", Function: llm.FunctionCall{Name: "Read", Arguments: `{"file_path":"/b"}`}},
+		}}}}},
+		{ID: "msg-1", Model: "claude-sonnet-4-5-20250929", Choices: []llm.Choice{{FinishReason: &toolCalls}}},
+	}}
+}
+
+func TestLoop_PermissionAsksAnsweredOutOfOrder(t *testing.T) {
+	read := &mockRecordingTool{name: "Read", output: tools.ToolOutput{Content: "contents"}}
+	registry := tools.NewRegistry()
+	registry.Register(read)
+	client := &mockLLMClient{responses: []*mockStream{twoReadsResponse(), endTurnResponse("Read /b.")}}
+	config := defaultConfig(client, registry)
+	config.MultiTurn = true
+	config.Permissions = &RuleBasedPermissionChecker{}
+
+	q := RunLoop(context.Background(), "Read both", config)
+	var asked []string
+	for m := range q.Messages() {
+		if pr, ok := m.(*types.PermissionRequestMessage); ok {
+			if asked = append(asked, pr.ToolUseID); len(asked) == 2 {
+				break
+			}
+		}
+	}
+	if len(asked) != 2 {
+		t.Fatalf("permission requests = %v, want both calls", asked)
+	}
+	// Answer the later request first: the earlier one's waiter reads it.
+	answers := map[string]types.PermissionResult{
+		"call-1": {Behavior: "deny", Message: "not /a"},
+		"call-2": {Behavior: "allow"},
+	}
+
+	answered := make(chan error, 1)
+	go func() {
+		for _, id := range []string{asked[1], asked[0]} {
+			resp, err := q.AnswerPermission(id, answers[id])
+			if err == nil {
+				if _, ok := resp.Response.(types.ControlSuccessResponse); !ok {
+					err = fmt.Errorf("answer for %s: %+v", id, resp.Response)
+				}
+			}
+			if err != nil {
+				answered <- err
+				return
+			}
+		}
+		answered <- nil
+	}()
+	go func() {
+		for range q.Messages() {
+		}
+	}()
+	select {
+	case err := <-answered:
+		if err != nil {
+			t.Fatalf("AnswerPermission: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("answers were not taken")
+	}
+	time.Sleep(100 * time.Millisecond)
+	q.Close()
+	q.Wait()
+
+	read.mu.Lock()
+	calls := read.calls
+	read.mu.Unlock()
+	if len(calls) != 1 || calls[0]["file_path"] != "/b" {
+		t.Errorf("Read calls = %v, want only the allowed /b", calls)
+	}
+	if got := toolResultContent(q.State(), "call-1"); !strings.Contains(got, "not /a") {
+		t.Errorf("call-1 result = %q, want the user's denial", got)
+	}
+	if got := toolResultContent(q.State(), "call-2"); got != "contents" {
+		t.Errorf("call-2 result = %q, want the file contents", got)
+	}
+}
@@ -2,6 +2,87 @@ package agent
 
 import "github.com/jg-phare/goat/pkg/llm"
 
+// ToolResultPruneStrategy selects how old tool results are pruned between turns.
+type ToolResultPruneStrategy string
+
+const (
+	// PruneByCount truncates verbose tool results outside the most recent
+	// ToolResultPruneKeep messages (the default).
+	PruneByCount ToolResultPruneStrategy = "count"
+	// PruneByTokens elides the oldest tool results only while the estimated
+	// context exceeds ToolResultPruneTarget of the model's context limit.
+	PruneByTokens ToolResultPruneStrategy = "tokens"
+)
+
+const (
+	defaultToolResultPruneKeep   = 10
+	defaultToolResultPruneTarget = 0.5
+
+	// elidedToolResult replaces tool output pruned by the token strategy. The
+	// message itself is kept so every tool_use still has a matching tool_result.
+	elidedToolResult = "[earlier tool output elided]"
+)
+
+// pruneToolResults applies the configured pruning strategy to state.Messages.
+func pruneToolResults(config *AgentConfig, state *LoopState, systemPrompt string) {
+	if config.ToolResultPruneStrategy != PruneByTokens {
+		keep := config.ToolResultPruneKeep
+		if keep <= 0 {
+			keep = defaultToolResultPruneKeep
+		}
+		state.setMessages(pruneOldToolResults(state.Messages, keep))
+		return
+	}
+
+	target := config.ToolResultPruneTarget
+	if target <= 0 || target > 1 {
+		target = defaultToolResultPruneTarget
+	}
+	budget := calculateTokenBudget(config, state, systemPrompt)
+	limit := int(float64(budget.ContextLimit)*target) - budget.SystemPromptTkns
+	state.setMessages(pruneToolResultsToBudget(state.Messages, state.tokenCache, budget.MessageTkns, limit))
+}
+
+// pruneToolResultsToBudget replaces the content of the oldest tool results
+// with a short stub until the history's estimated tokens fit within limit.
+// Results after the last assistant message (the batch the model has not yet
+// seen) are never elided.
+func pruneToolResultsToBudget(messages []llm.ChatMessage, cache *messageTokenCache, total, limit int) []llm.ChatMessage {
+	if total <= limit {
+		return messages
+	}
+
+	protectFrom := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "assistant" {
+			protectFrom = i
+			break
+		}
+	}
+
+	result := make([]llm.ChatMessage, len(messages))
+	copy(result, messages)
+
+	for i := 0; i < protectFrom && total > limit; i++ {
+		if result[i].Role != "tool" {
+			continue
+		}
+		if content, ok := result[i].Content.(string); ok && content == elidedToolResult {
+			continue
+		}
+		stub := llm.ChatMessage{
+			Role:       "tool",
+			ToolCallID: result[i].ToolCallID,
+			Name:       result[i].Name,
+			Content:    elidedToolResult,
+		}
+		total -= cache.count(result[i]) - cache.count(stub)
+		result[i] = stub
+	}
+
+	return result
+}
+
 // pruneOldToolResults replaces verbose tool result content (>1000 chars)
 // with truncated versions, except for the most recent preserveRecent messages.
 // This is a lightweight alternative to full compaction, called after each tool
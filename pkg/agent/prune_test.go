@@ -84,3 +84,73 @@ func TestPruneOldToolResults_NegativePreserve(t *testing.T) {
 		t.Error("negative preserveRecent should default to 0")
 	}
 }
+
+func TestPruneToolResultsToBudget(t *testing.T) {
+	long := strings.Repeat("word ", 400)
+	msgs := []llm.ChatMessage{
+		{Role: "user", Content: "hello"},
+		{Role: "assistant", Content: "step 1"},
+		{Role: "tool", ToolCallID: "call_1", Content: long},
+		{Role: "assistant", Content: "step 2"},
+		{Role: "tool", ToolCallID: "call_2", Content: long},
+		{Role: "assistant", Content: "step 3"},
+		{Role: "tool", ToolCallID: "call_3", Content: long},
+	}
+	cache := newMessageTokenCache(HeuristicTokenCounter{}, "heuristic")
+	total := 0
+	for _, m := range msgs {
+		total += cache.count(m)
+	}
+	perResult := cache.count(msgs[2])
+
+	tests := []struct {
+		name       string
+		limit      int
+		wantElided []string
+	}{
+		{"fits", total, nil},
+		{"one over", total - 1, []string{"call_1"}},
+		{"needs two", total - perResult - 1, []string{"call_1", "call_2"}},
+		{"never elides unseen batch", 0, []string{"call_1", "call_2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pruneToolResultsToBudget(msgs, cache, total, tt.limit)
+			if len(result) != len(msgs) {
+				t.Fatalf("message count changed: %d -> %d", len(msgs), len(result))
+			}
+			var elided []string
+			for _, m := range result {
+				if m.Role == "tool" && m.Content == elidedToolResult {
+					elided = append(elided, m.ToolCallID)
+				}
+			}
+			if strings.Join(elided, ",") != strings.Join(tt.wantElided, ",") {
+				t.Errorf("elided = %v, want %v", elided, tt.wantElided)
+			}
+		})
+	}
+	if msgs[2].Content != long {
+		t.Error("input slice must not be modified")
+	}
+}
+
+func TestPruneToolResults_CountKeep(t *testing.T) {
+	long := strings.Repeat("x", 2000)
+	state := &LoopState{Messages: []llm.ChatMessage{
+		{Role: "user", Content: "hello"},
+		{Role: "tool", ToolCallID: "call_1", Content: long},
+		{Role: "assistant", Content: "a"},
+		{Role: "assistant", Content: "b"},
+	}}
+
+	pruneToolResults(&AgentConfig{}, state, "")
+	if state.Messages[1].Content != long {
+		t.Error("default keep window should preserve the tool result")
+	}
+
+	pruneToolResults(&AgentConfig{ToolResultPruneKeep: 2}, state, "")
+	if content, _ := state.Messages[1].Content.(string); !strings.Contains(content, "[output truncated]") {
+		t.Error("ToolResultPruneKeep=2 should truncate the older tool result")
+	}
+}
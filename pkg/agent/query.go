@@ -3,6 +3,8 @@ package agent
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"sync"
 
 	"github.com/jg-phare/goat/pkg/llm"
@@ -28,11 +30,25 @@ type Query struct {
 	controlResp chan types.ControlResponse // response channel for control commands
 	closeCh     chan struct{}              // explicit close signal
 
-	mu          sync.Mutex
-	state       *LoopState
-	costTracker *llm.CostTracker
-	cancel      context.CancelFunc
-	closed      bool
+	mu           sync.Mutex
+	state        *LoopState
+	costTracker  *llm.CostTracker
+	cancel       context.CancelFunc
+	closed       bool
+	shuttingDown bool
+
+	background   []BackgroundWork // stopped by Shutdown
+	sessionStore SessionStore     // flushed by Shutdown
+
+	steering [][]byte // Steer messages awaiting injection, guarded by mu
+
+	pendingAnswers map[answerKey]chan types.ControlRequest // tool calls awaiting answer_permission/answer_question, guarded by mu
+
+	redactor Redactor // applied to each message before it is delivered
+
+	keepTranscript bool // AgentConfig.RecordTranscript
+	transcriptMu   sync.Mutex
+	transcript     []types.SDKMessage // every message delivered on messages, in order
 }
 
 // Messages returns the channel of SDKMessages emitted by the loop.
@@ -77,6 +93,36 @@ func (q *Query) SendUserMessage(data []byte) error {
 	}
 }
 
+// Steer queues a user message to be injected mid-turn: after the current tool
+// batch completes and before the next LLM call, even though the model has not
+// ended its turn. The message is marked as an interruption so the model treats
+// it as a correction. Unlike SendUserMessage it never blocks and also works in
+// one-shot mode; a message queued during the final LLM call is injected
+// instead of ending the turn.
+func (q *Query) Steer(data []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueryClosed
+	}
+	select {
+	case <-q.done:
+		return ErrQueryClosed
+	default:
+	}
+	q.steering = append(q.steering, data)
+	return nil
+}
+
+// takeSteering removes and returns the queued Steer messages.
+func (q *Query) takeSteering() [][]byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	msgs := q.steering
+	q.steering = nil
+	return msgs
+}
+
 // SendControl dispatches a synchronous control request and waits for the response.
 func (q *Query) SendControl(req types.ControlRequest) (types.ControlResponse, error) {
 	q.mu.Lock()
@@ -108,7 +154,8 @@ func (q *Query) SendControl(req types.ControlRequest) (types.ControlResponse, er
 	}
 }
 
-// Close gracefully shuts down the loop. Safe to call multiple times.
+// Close gracefully shuts down the loop, which closes the Background work
+// that is an io.Closer as it exits. Safe to call multiple times.
 func (q *Query) Close() error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
@@ -122,6 +169,57 @@ func (q *Query) Close() error {
 	return nil
 }
 
+// Shutdown ends the session gracefully, e.g. on SIGTERM. It interrupts the
+// loop, stops the configured Background work (background Bash tasks,
+// subagents), waits for the loop to emit its result and fire SessionEnd with
+// reason "shutdown", then flushes the SessionStore. The caller must keep
+// draining Messages until it closes. If ctx expires first, Shutdown returns
+// without waiting further.
+func (q *Query) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	q.shuttingDown = true
+	q.state.IsInterrupted = true
+	q.mu.Unlock()
+	q.cancel()
+	q.Close()
+
+	var errs []error
+	for _, w := range q.background {
+		if err := w.StopAll(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	select {
+	case <-q.done:
+	case <-ctx.Done():
+		return errors.Join(append(errs, fmt.Errorf("waiting for the loop to exit: %w", ctx.Err()))...)
+	}
+
+	if f, ok := q.sessionStore.(interface{ Flush() error }); ok {
+		if err := f.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("flushing session: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeBackground closes the Background work that holds session resources,
+// such as a persistent Bash shell. It runs as the loop exits.
+func (q *Query) closeBackground() {
+	for _, w := range q.background {
+		if c, ok := w.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+func (q *Query) isShuttingDown() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.shuttingDown
+}
+
 // SessionID returns the session identifier.
 func (q *Query) SessionID() string {
 	q.mu.Lock()
@@ -187,6 +285,36 @@ func (q *Query) SetModel(model string) (types.ControlResponse, error) {
 	})
 }
 
+// AnswerQuestion answers the AskUserQuestion call announced by a
+// UserQuestionMessage (multi-turn only). answers maps each question's text
+// to the chosen option label(s) or free text.
+func (q *Query) AnswerQuestion(toolUseID string, answers map[string]string) (types.ControlResponse, error) {
+	return q.SendControl(types.ControlRequest{
+		RequestID: "answer-" + toolUseID,
+		Request: types.ControlRequestInner{
+			Subtype:   types.ControlSubtypeAnswerQuestion,
+			ToolUseID: toolUseID,
+			Answers:   answers,
+		},
+	})
+}
+
+// AnswerPermission decides the tool call announced by a
+// PermissionRequestMessage (multi-turn only). A non-nil result.UpdatedInput
+// replaces the call's input; result.Message is reported on deny.
+func (q *Query) AnswerPermission(toolUseID string, result types.PermissionResult) (types.ControlResponse, error) {
+	return q.SendControl(types.ControlRequest{
+		RequestID: "permission-" + toolUseID,
+		Request: types.ControlRequestInner{
+			Subtype:   types.ControlSubtypeAnswerPermission,
+			ToolUseID: toolUseID,
+			Behavior:  result.Behavior,
+			Input:     result.UpdatedInput,
+			Message:   result.Message,
+		},
+	})
+}
+
 // SetMaxThinkingTokens updates the thinking token limit at runtime (multi-turn only).
 func (q *Query) SetMaxThinkingTokens(tokens int) (types.ControlResponse, error) {
 	return q.SendControl(types.ControlRequest{
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// controlInputHandler answers AskUserQuestion calls in a multi-turn session:
+// it emits a UserQuestionMessage and waits for the host's answer_question
+// control request. Other control requests (e.g. interrupt) are dispatched as
+// usual while it waits.
+type controlInputHandler struct {
+	config *AgentConfig
+	state  *LoopState
+	q      *Query
+}
+
+func (h *controlInputHandler) AskQuestions(ctx context.Context, questions []tools.QuestionSpec) (map[string]string, error) {
+	info, ok := ToolCallFromContext(ctx)
+	if !ok || info.EmitCh == nil {
+		return nil, errors.New("no message stream to ask the user on")
+	}
+
+	msg := &types.UserQuestionMessage{
+		BaseMessage: types.BaseMessage{UUID: uuid.New(), SessionID: h.state.SessionID},
+		Type:        types.MessageTypeSystem,
+		Subtype:     types.SystemSubtypeUserQuestion,
+		ToolUseID:   info.ToolUseID,
+		Questions:   make([]types.UserQuestion, 0, len(questions)),
+	}
+	for _, q := range questions {
+		uq := types.UserQuestion{Question: q.Question, Header: q.Header, MultiSelect: q.MultiSelect}
+		for _, opt := range q.Options {
+			uq.Options = append(uq.Options, types.UserQuestionOption{Label: opt.Label, Description: opt.Description})
+		}
+		msg.Questions = append(msg.Questions, uq)
+	}
+	answers, release := h.q.expectAnswer(types.ControlSubtypeAnswerQuestion, info.ToolUseID)
+	defer release()
+	info.EmitCh <- msg
+
+	r, err := awaitAnswer(ctx, h.config, h.state, h.q, types.ControlSubtypeAnswerQuestion, info.ToolUseID, answers)
+	if err != nil {
+		return nil, err
+	}
+	return r.Answers, nil
+}
+
+// withUserInput attaches the loop's AskUserQuestion handler to ctx. Only
+// multi-turn sessions have a host to answer; one-shot runs (including
+// subagents started from an interactive session) get none, so the tool falls
+// back to its configured defaults.
+func withUserInput(ctx context.Context, config *AgentConfig, state *LoopState, q *Query) context.Context {
+	if !config.MultiTurn {
+		return tools.WithUserInputHandler(ctx, nil)
+	}
+	return tools.WithUserInputHandler(ctx, &controlInputHandler{config: config, state: state, q: q})
+}
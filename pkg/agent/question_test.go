@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+var authQuestion = map[string]any{
+	"questions": []any{
+		map[string]any{
+			"question":    "Which auth method?",
+			"header":      "Auth",
+			"multiSelect": false,
+			"options": []any{
+				map[string]any{"label": "JWT", "description": "JSON Web Tokens"},
+				map[string]any{"label": "OAuth", "description": "OAuth 2.0"},
+			},
+		},
+	},
+}
+
+// toolResultContent returns the content of the tool message answering callID.
+func toolResultContent(state *LoopState, callID string) string {
+	for _, m := range state.Messages {
+		if m.Role == "tool" && m.ToolCallID == callID {
+			content, _ := m.Content.(string)
+			return content
+		}
+	}
+	return ""
+}
+
+func TestLoop_AskUserQuestionInteractive(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call-ask", "AskUserQuestion", authQuestion),
+			endTurnResponse("Using JWT."),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&tools.AskUserQuestionTool{})
+	config := defaultConfig(client, registry)
+	config.MultiTurn = true
+
+	q := RunLoop(context.Background(), "Add auth", config)
+
+	var question *types.UserQuestionMessage
+	for m := range q.Messages() {
+		if uq, ok := m.(*types.UserQuestionMessage); ok {
+			question = uq
+			break
+		}
+	}
+	if question == nil {
+		t.Fatal("no UserQuestionMessage emitted")
+	}
+	if question.ToolUseID != "call-ask" || len(question.Questions) != 1 || len(question.Questions[0].Options) != 2 {
+		t.Fatalf("question = %+v", question)
+	}
+
+	resp, err := q.AnswerQuestion(question.ToolUseID, map[string]string{"Which auth method?": "JWT"})
+	if err != nil {
+		t.Fatalf("AnswerQuestion: %v", err)
+	}
+	if _, ok := resp.Response.(types.ControlSuccessResponse); !ok {
+		t.Fatalf("response = %+v", resp.Response)
+	}
+
+	go func() {
+		for range q.Messages() {
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	q.Close()
+	q.Wait()
+
+	if got := toolResultContent(q.State(), "call-ask"); !strings.Contains(got, "Auth: JWT") {
+		t.Errorf("tool result = %q, want the chosen option recorded", got)
+	}
+}
+
+func TestLoop_AskUserQuestionHeadless(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call-ask", "AskUserQuestion", authQuestion),
+			endTurnResponse("Proceeding."),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&tools.AskUserQuestionTool{Defaults: map[string]string{"Auth": "OAuth"}})
+	config := defaultConfig(client, registry)
+
+	q := RunLoop(context.Background(), "Add auth", config)
+	for m := range q.Messages() {
+		if _, ok := m.(*types.UserQuestionMessage); ok {
+			t.Error("one-shot run should not ask the host")
+		}
+	}
+	q.Wait()
+
+	if got := toolResultContent(q.State(), "call-ask"); !strings.Contains(got, "default answers") || !strings.Contains(got, "Auth: OAuth") {
+		t.Errorf("tool result = %q, want the default answer", got)
+	}
+}
+
+func TestDispatchControl_AnswerWithoutQuestion(t *testing.T) {
+	q := &Query{cancel: func() {}}
+	resp := dispatchControl(&AgentConfig{}, &LoopState{}, q, types.ControlRequest{
+		RequestID: "r1",
+		Request:   types.ControlRequestInner{Subtype: types.ControlSubtypeAnswerQuestion},
+	})
+	if _, ok := resp.Response.(types.ControlErrorResponse); !ok {
+		t.Errorf("response = %+v, want an error", resp.Response)
+	}
+}
@@ -0,0 +1,212 @@
+package agent
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// redactSDKMessage returns msg with secrets removed from its string fields
+// and the redacted field paths listed in its Redacted field. msg itself is
+// left alone when anything changes: it may share slices and maps with the
+// loop state, so a redacted copy is returned instead.
+func redactSDKMessage(r Redactor, msg types.SDKMessage) types.SDKMessage {
+	var fields []string
+	v, changed := redactValue(r, reflect.ValueOf(msg), "", &fields)
+	if !changed {
+		return msg
+	}
+	if f := reflect.Indirect(v).FieldByName("Redacted"); f.CanSet() {
+		f.Set(reflect.ValueOf(fields))
+	}
+	return v.Interface().(types.SDKMessage)
+}
+
+// redactEntry is redactSDKMessage for a session store entry.
+func redactEntry(r Redactor, entry MessageEntry) MessageEntry {
+	var fields []string
+	v, changed := redactValue(r, reflect.ValueOf(entry), "", &fields)
+	if !changed {
+		return entry
+	}
+	entry = v.Interface().(MessageEntry)
+	entry.Redacted = fields
+	return entry
+}
+
+// redactValue redacts every string reachable from v, copying each container
+// on the way to a change rather than modifying it. Fields are named by their
+// JSON keys, joined into paths like "message.content[0].text".
+func redactValue(r Redactor, v reflect.Value, path string, fields *[]string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		s, ok := r.Redact(v.String())
+		if !ok {
+			return v, false
+		}
+		*fields = append(*fields, path)
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(s)
+		return out, true
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, ok := redactValue(r, v.Elem(), path, fields)
+		if !ok {
+			return v, false
+		}
+		if v.Kind() == reflect.Interface {
+			out := reflect.New(v.Type()).Elem()
+			out.Set(elem)
+			return out, true
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, true
+
+	case reflect.Struct:
+		var out reflect.Value
+		t := v.Type()
+		for i := range t.NumField() {
+			sf := t.Field(i)
+			if !sf.IsExported() || sf.Name == "Redacted" {
+				continue
+			}
+			name := path
+			if !sf.Anonymous {
+				name = joinPath(path, jsonName(sf))
+			}
+			field, ok := redactValue(r, v.Field(i), name, fields)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(t).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(field)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 { // []byte, json.RawMessage
+			s, ok := r.Redact(string(v.Bytes()))
+			if !ok {
+				return v, false
+			}
+			*fields = append(*fields, path)
+			return reflect.ValueOf([]byte(s)).Convert(v.Type()), true
+		}
+		var out reflect.Value
+		for i := range v.Len() {
+			elem, ok := redactValue(r, v.Index(i), path+"["+strconv.Itoa(i)+"]", fields)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(out, v)
+			}
+			out.Index(i).Set(elem)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+
+	case reflect.Map:
+		var out reflect.Value
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			elem, ok := redactValue(r, iter.Value(), joinPath(path, mapKeyName(key)), fields)
+			if !ok {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeMapWithSize(v.Type(), v.Len())
+				copyIter := v.MapRange()
+				for copyIter.Next() {
+					out.SetMapIndex(copyIter.Key(), copyIter.Value())
+				}
+			}
+			out.SetMapIndex(key, elem)
+		}
+		if !out.IsValid() {
+			return v, false
+		}
+		return out, true
+	}
+	return v, false
+}
+
+func jsonName(sf reflect.StructField) string {
+	if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	return sf.Name
+}
+
+func mapKeyName(key reflect.Value) string {
+	if key.Kind() == reflect.String {
+		return key.String()
+	}
+	return "?"
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// redactingStore redacts messages on their way into a SessionStore.
+type redactingStore struct {
+	SessionStore
+	redactor Redactor
+}
+
+func (s *redactingStore) AppendMessage(sessionID string, entry MessageEntry) error {
+	return s.SessionStore.AppendMessage(sessionID, redactEntry(s.redactor, entry))
+}
+
+func (s *redactingStore) AppendSDKMessage(sessionID string, msg types.SDKMessage) error {
+	return s.SessionStore.AppendSDKMessage(sessionID, redactSDKMessage(s.redactor, msg))
+}
+
+// LoadWebResource forwards to the wrapped store when it keeps web resources.
+func (s *redactingStore) LoadWebResource(sessionID, kind, key string) (tools.WebResource, bool, error) {
+	if ws, ok := s.SessionStore.(WebResourceStore); ok {
+		return ws.LoadWebResource(sessionID, kind, key)
+	}
+	return tools.WebResource{}, false, nil
+}
+
+// SaveWebResource redacts the content on its way into the wrapped store.
+func (s *redactingStore) SaveWebResource(sessionID string, res tools.WebResource) error {
+	ws, ok := s.SessionStore.(WebResourceStore)
+	if !ok {
+		return nil
+	}
+	if redacted, changed := s.redactor.Redact(res.Content); changed {
+		res.Content = redacted
+	}
+	return ws.SaveWebResource(sessionID, res)
+}
+
+// Flush forwards to the wrapped store, for Query.Shutdown.
+func (s *redactingStore) Flush() error {
+	if f, ok := s.SessionStore.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// ThresholdRouter is the default ModelRouter. On the first turn it picks a
+// model from the prompt's estimated size; afterwards it upgrades or
+// downgrades on the mid-run rules of its DynamicModelConfig.
+type ThresholdRouter struct {
+	Config DynamicModelConfig
+}
+
+// SelectModel implements ModelRouter.
+func (r *ThresholdRouter) SelectModel(state *LoopState) string {
+	c := r.Config
+	if state.TurnCount == 0 {
+		promptTokens := len(lastUserText(state.Messages)) / 4 // rough estimate
+		if c.SimpleThresholdTokens > 0 && promptTokens < c.SimpleThresholdTokens && c.SimpleModel != "" {
+			return c.SimpleModel
+		}
+		if c.ComplexThresholdTokens > 0 && promptTokens > c.ComplexThresholdTokens && c.ComplexModel != "" {
+			return c.ComplexModel
+		}
+		return "" // use config.Model
+	}
+
+	// Struggling beats exploring: upgrades win over the downgrade.
+	if c.ComplexModel != "" {
+		if c.UpgradeAfterToolErrors > 0 && state.ConsecutiveToolErrors >= c.UpgradeAfterToolErrors {
+			return c.ComplexModel
+		}
+		if c.UpgradeAfterTurns > 0 && state.TurnCount >= c.UpgradeAfterTurns {
+			return c.ComplexModel
+		}
+	}
+	if c.SimpleModel != "" && c.DowngradeAfterReadOnlyTurns > 0 && state.ReadOnlyTurns >= c.DowngradeAfterReadOnlyTurns {
+		return c.SimpleModel
+	}
+	return ""
+}
+
+// lastUserText returns the text of the most recent plain-text user message.
+func lastUserText(messages []llm.ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			text, _ := messages[i].Content.(string)
+			return text
+		}
+	}
+	return ""
+}
+
+// routeModel asks the configured router for the next turn's model and
+// announces a switch with a StatusMessage. Routing stops once the host sets
+// the model, or the budget downgrade or fallback model has taken over.
+func routeModel(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) {
+	router := config.ModelRouter
+	if router == nil {
+		if config.DynamicModelConfig == nil {
+			return
+		}
+		router = &ThresholdRouter{Config: *config.DynamicModelConfig}
+	}
+	if state.modelPinned || state.BudgetDowngraded || state.UsingFallback {
+		return
+	}
+
+	previous := currentModel(config, state)
+	model := router.SelectModel(state)
+	if model == "" || model == previous {
+		return
+	}
+	state.Model = model
+	ch <- types.NewModelChangeStatus(previous, model, state.SessionID)
+}
+
+// trackToolOutcomes updates the tool streaks in state after a tool turn.
+// Failed calls are those whose result carries the "Error:" prefix.
+func trackToolOutcomes(config *AgentConfig, state *LoopState, toolBlocks []types.ContentBlock, results []llm.ToolResult) {
+	for _, r := range results {
+		if strings.HasPrefix(r.Content, "Error:") {
+			state.ConsecutiveToolErrors++
+		} else {
+			state.ConsecutiveToolErrors = 0
+		}
+	}
+
+	readOnly := true
+	for _, block := range toolBlocks {
+		if !isParallelSafe(block, config.ToolRegistry) {
+			readOnly = false
+			break
+		}
+	}
+	if readOnly {
+		state.ReadOnlyTurns++
+	} else {
+		state.ReadOnlyTurns = 0
+	}
+}
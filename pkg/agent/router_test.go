@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestThresholdRouter_MidRun(t *testing.T) {
+	r := &ThresholdRouter{Config: DynamicModelConfig{
+		SimpleModel:                 "simple",
+		ComplexModel:                "complex",
+		UpgradeAfterTurns:           10,
+		UpgradeAfterToolErrors:      3,
+		DowngradeAfterReadOnlyTurns: 4,
+	}}
+
+	tests := []struct {
+		name  string
+		state LoopState
+		want  string
+	}{
+		{"early turn", LoopState{TurnCount: 2}, ""},
+		{"turn limit", LoopState{TurnCount: 10}, "complex"},
+		{"tool errors", LoopState{TurnCount: 3, ConsecutiveToolErrors: 3}, "complex"},
+		{"read-only exploration", LoopState{TurnCount: 5, ReadOnlyTurns: 4}, "simple"},
+		{"errors beat exploration", LoopState{TurnCount: 5, ReadOnlyTurns: 4, ConsecutiveToolErrors: 3}, "complex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.SelectModel(&tt.state); got != tt.want {
+				t.Errorf("SelectModel = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrackToolOutcomes(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Read"})
+	config := &AgentConfig{ToolRegistry: registry}
+	state := &LoopState{}
+
+	read := []types.ContentBlock{{Type: "tool_use", ID: "1", Name: "Read"}}
+	trackToolOutcomes(config, state, read, []llm.ToolResult{{Content: "Error: no such file"}, {Content: "Error: denied"}})
+	if state.ConsecutiveToolErrors != 2 || state.ReadOnlyTurns != 1 {
+		t.Fatalf("after failures: errors = %d, read-only = %d", state.ConsecutiveToolErrors, state.ReadOnlyTurns)
+	}
+
+	write := []types.ContentBlock{{Type: "tool_use", ID: "2", Name: "Write"}}
+	trackToolOutcomes(config, state, write, []llm.ToolResult{{Content: "ok"}})
+	if state.ConsecutiveToolErrors != 0 || state.ReadOnlyTurns != 0 {
+		t.Errorf("after success: errors = %d, read-only = %d", state.ConsecutiveToolErrors, state.ReadOnlyTurns)
+	}
+}
+
+func TestLoop_ModelRouterUpgradesOnToolErrors(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "exit 1", IsError: true}})
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call-1", "Bash", map[string]any{"command": "make"}),
+			toolUseResponse("call-2", "Bash", map[string]any{"command": "make"}),
+			endTurnResponse("Fixed."),
+		},
+	}
+	config := defaultConfig(client, registry)
+	config.DynamicModelConfig = &DynamicModelConfig{
+		ComplexModel:           "complex",
+		UpgradeAfterToolErrors: 2,
+	}
+
+	q := RunLoop(context.Background(), "build it", config)
+	var changes []*types.ModelChange
+	for m := range q.Messages() {
+		if s, ok := m.(*types.StatusMessage); ok && s.ModelChange != nil {
+			changes = append(changes, s.ModelChange)
+		}
+	}
+	q.Wait()
+
+	if q.State().Model != "complex" {
+		t.Errorf("state.Model = %q, want complex", q.State().Model)
+	}
+	if len(changes) != 1 || changes[0].From != config.Model || changes[0].To != "complex" {
+		t.Errorf("model changes = %+v, want one %s -> complex", changes, config.Model)
+	}
+}
+
+type fixedRouter string
+
+func (r fixedRouter) SelectModel(*LoopState) string { return string(r) }
+
+func TestRouteModel_PinnedByHost(t *testing.T) {
+	config := &AgentConfig{Model: "base", ModelRouter: fixedRouter("routed")}
+	state := &LoopState{Model: "chosen", modelPinned: true}
+	ch := make(chan types.SDKMessage, 1)
+
+	routeModel(config, state, ch)
+	if state.Model != "chosen" || len(ch) != 0 {
+		t.Errorf("pinned model changed to %q", state.Model)
+	}
+
+	state.modelPinned = false
+	routeModel(config, state, ch)
+	if state.Model != "routed" || len(ch) != 1 {
+		t.Errorf("model = %q, %d messages; want routed with a status message", state.Model, len(ch))
+	}
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// SessionSort selects the order of SessionStore.ListSessions results.
+type SessionSort string
+
+const (
+	SessionSortUpdated SessionSort = "updated" // last activity (default)
+	SessionSortCreated SessionSort = "created"
+	SessionSortCost    SessionSort = "cost"
+	SessionSortTurns   SessionSort = "turns"
+)
+
+// SessionFilter selects sessions for SessionStore.ListSessions. Zero-valued
+// fields don't filter.
+type SessionFilter struct {
+	CWD       string
+	AgentName string
+
+	// Since and Until bound UpdatedAt: Since is inclusive, Until exclusive.
+	Since time.Time
+	Until time.Time
+
+	MinCostUSD float64
+	MaxCostUSD float64 // 0 = no upper bound
+
+	SortBy    SessionSort // default SessionSortUpdated
+	Ascending bool        // default is descending (newest, costliest first)
+	Limit     int         // 0 = no limit
+}
+
+// Match reports whether meta passes the filter's conditions.
+func (f SessionFilter) Match(meta SessionMetadata) bool {
+	switch {
+	case f.CWD != "" && meta.CWD != f.CWD:
+		return false
+	case f.AgentName != "" && meta.AgentName != f.AgentName:
+		return false
+	case !f.Since.IsZero() && meta.UpdatedAt.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !meta.UpdatedAt.Before(f.Until):
+		return false
+	case meta.TotalCostUSD < f.MinCostUSD:
+		return false
+	case f.MaxCostUSD > 0 && meta.TotalCostUSD > f.MaxCostUSD:
+		return false
+	}
+	return true
+}
+
+// FilterSessions applies f to sessions: it keeps the matches, sorts them and
+// truncates to the limit. Stores that can't filter natively use it to
+// implement ListSessions. sessions is not modified.
+func FilterSessions(sessions []SessionMetadata, f SessionFilter) []SessionMetadata {
+	var out []SessionMetadata
+	for _, meta := range sessions {
+		if f.Match(meta) {
+			out = append(out, meta)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b SessionMetadata) int {
+		var c int
+		switch f.SortBy {
+		case SessionSortCreated:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case SessionSortCost:
+			c = cmp.Compare(a.TotalCostUSD, b.TotalCostUSD)
+		case SessionSortTurns:
+			c = cmp.Compare(a.TurnCount, b.TurnCount)
+		}
+		if c == 0 {
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		if !f.Ascending {
+			c = -c
+		}
+		return c
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out
+}
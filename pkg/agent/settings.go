@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// Session settings exposed to the Config tool.
+const (
+	SettingModel             = "model"
+	SettingPermissionMode    = "permission_mode"
+	SettingMaxThinkingTokens = "max_thinking_tokens"
+)
+
+// permissionModeRank orders permission modes from most to least restrictive.
+// The Config tool may tighten the mode but never loosen it: only the host
+// can grant the model more autonomy.
+var permissionModeRank = map[types.PermissionMode]int{
+	types.PermissionModePlan:              0,
+	types.PermissionModeDontAsk:           0,
+	types.PermissionModeDefault:           1,
+	types.PermissionModeDelegate:          1,
+	types.PermissionModeAcceptEdits:       2,
+	types.PermissionModeBypassPermissions: 3,
+}
+
+// sessionConfigStore backs the Config tool with the running session's
+// settings. Writes are translated into the same control requests a host
+// would send and dispatched directly, since the tool runs on the loop.
+type sessionConfigStore struct {
+	config *AgentConfig
+	state  *LoopState
+	q      *Query
+}
+
+func (s *sessionConfigStore) Get(key string) (any, bool) {
+	s.q.mu.Lock()
+	defer s.q.mu.Unlock()
+	switch key {
+	case SettingModel:
+		return currentModel(s.config, s.state), true
+	case SettingPermissionMode:
+		return string(s.config.PermissionMode), true
+	case SettingMaxThinkingTokens:
+		if s.state.MaxThinkingTokens > 0 {
+			return s.state.MaxThinkingTokens, true
+		}
+		if s.config.MaxThinkingTkns != nil {
+			return *s.config.MaxThinkingTkns, true
+		}
+		return 0, true
+	}
+	return nil, false
+}
+
+func (s *sessionConfigStore) Set(key string, value any) error {
+	req := types.ControlRequest{RequestID: "config-tool-" + key}
+	switch key {
+	case SettingModel:
+		model, ok := value.(string)
+		if !ok || strings.TrimSpace(model) == "" {
+			return fmt.Errorf("%s must be a non-empty string", key)
+		}
+		req.Request = types.ControlRequestInner{Subtype: types.ControlSubtypeSetModel, Model: strings.TrimSpace(model)}
+
+	case SettingPermissionMode:
+		str, _ := value.(string)
+		mode := types.PermissionMode(str)
+		rank, ok := permissionModeRank[mode]
+		if !ok {
+			return fmt.Errorf("unknown permission mode %v", value)
+		}
+		if current, known := permissionModeRank[s.config.PermissionMode]; known && rank > current {
+			return fmt.Errorf("cannot loosen permission mode from %s to %s; ask the user to change it", s.config.PermissionMode, mode)
+		}
+		req.Request = types.ControlRequestInner{Subtype: types.ControlSubtypeSetPermissionMode, Mode: mode}
+
+	case SettingMaxThinkingTokens:
+		tokens, err := settingInt(value)
+		if err != nil || tokens < 0 {
+			return fmt.Errorf("%s must be a non-negative integer", key)
+		}
+		req.Request = types.ControlRequestInner{Subtype: types.ControlSubtypeSetMaxThinkingTokens, MaxThinkingTokens: &tokens}
+
+	default:
+		return fmt.Errorf("%w: %q cannot be changed; settable keys are %s, %s and %s",
+			tools.ErrUnknownSetting, key, SettingModel, SettingPermissionMode, SettingMaxThinkingTokens)
+	}
+
+	if errResp, ok := dispatchControl(s.config, s.state, s.q, req).Response.(types.ControlErrorResponse); ok {
+		return fmt.Errorf("%s", errResp.Error)
+	}
+	return nil
+}
+
+// settingInt converts a tool input value (a JSON number or numeric string) to an int.
+func settingInt(value any) (int, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, fmt.Errorf("not an integer: %v", v)
+		}
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(strings.TrimSpace(v))
+	}
+	return 0, fmt.Errorf("not a number: %v", value)
+}
+
+// withConfigStore attaches the session's settings store to ctx for the Config tool.
+func withConfigStore(ctx context.Context, config *AgentConfig, state *LoopState, q *Query) context.Context {
+	return tools.WithConfigStore(ctx, &sessionConfigStore{config: config, state: state, q: q})
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestLoop_ConfigToolSwitchesModel(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&tools.ConfigTool{})
+	client := &capturingLLMClient{inner: &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Config", map[string]any{"setting": "model", "value": "claude-haiku-4-5-20251001"}),
+			endTurnResponse("Switched."),
+		},
+	}}
+	config := defaultConfig(client, registry)
+
+	q := RunLoop(context.Background(), "Use a cheaper model", config)
+	collectMessages(q)
+	q.Wait()
+
+	reqs := client.getRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(reqs))
+	}
+	if !strings.HasSuffix(reqs[1].Model, "claude-haiku-4-5-20251001") {
+		t.Errorf("second request model = %q, want the model set by Config", reqs[1].Model)
+	}
+}
+
+// modeRecordingChecker allows everything and records SetMode calls.
+type modeRecordingChecker struct{ mode types.PermissionMode }
+
+func (c *modeRecordingChecker) Check(context.Context, string, map[string]any) (PermissionResult, error) {
+	return PermissionResult{Behavior: "allow"}, nil
+}
+
+func (c *modeRecordingChecker) SetMode(mode types.PermissionMode) { c.mode = mode }
+
+func TestSessionConfigStore_AppliesModeToChecker(t *testing.T) {
+	checker := &modeRecordingChecker{mode: types.PermissionModeDefault}
+	config := AgentConfig{PermissionMode: types.PermissionModeDefault, Permissions: checker}
+	state := &LoopState{}
+	store := &sessionConfigStore{config: &config, state: state, q: &Query{state: state}}
+
+	if err := store.Set(SettingPermissionMode, "plan"); err != nil {
+		t.Fatalf("Set(permission_mode, plan): %v", err)
+	}
+	if checker.mode != types.PermissionModePlan {
+		t.Errorf("checker mode = %q, want plan", checker.mode)
+	}
+}
+
+func TestSessionConfigStore(t *testing.T) {
+	config := AgentConfig{Model: "claude-sonnet-4-5-20250929", PermissionMode: types.PermissionModeDefault}
+	state := &LoopState{}
+	store := &sessionConfigStore{config: &config, state: state, q: &Query{state: state}}
+
+	if err := store.Set(SettingMaxThinkingTokens, float64(4096)); err != nil {
+		t.Fatalf("Set(max_thinking_tokens): %v", err)
+	}
+	if v, _ := store.Get(SettingMaxThinkingTokens); v != 4096 {
+		t.Errorf("max_thinking_tokens = %v, want 4096", v)
+	}
+
+	if err := store.Set(SettingPermissionMode, "plan"); err != nil {
+		t.Fatalf("Set(permission_mode, plan): %v", err)
+	}
+	if config.PermissionMode != types.PermissionModePlan {
+		t.Errorf("PermissionMode = %q, want plan", config.PermissionMode)
+	}
+
+	for _, tt := range []struct {
+		key   string
+		value any
+		want  string
+	}{
+		{SettingPermissionMode, "bypassPermissions", "cannot loosen"},
+		{SettingPermissionMode, "yolo", "unknown permission mode"},
+		{SettingMaxThinkingTokens, 1.5, "non-negative integer"},
+		{SettingModel, "", "non-empty string"},
+		{"max_turns", float64(500), "cannot be changed"},
+	} {
+		err := store.Set(tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Set(%s, %v) error = %v, want %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+	if _, ok := store.Get("max_turns"); ok {
+		t.Error("Get(max_turns) should report an unknown setting")
+	}
+}
@@ -1,7 +1,13 @@
 package agent
 
 import (
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
 	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
@@ -16,6 +22,10 @@ const (
 	ExitInterrupted   ExitReason = "interrupted"
 	ExitMaxTokens     ExitReason = "max_tokens"
 	ExitAborted       ExitReason = "aborted"
+
+	ExitMaxStructuredRetries ExitReason = "error_max_structured_output_retries"
+	ExitSessionRestore       ExitReason = "error_session_restore"
+	ExitToolLoop             ExitReason = "error_tool_loop"
 )
 
 // LoopState tracks the mutable state of a running agentic loop.
@@ -32,12 +42,22 @@ type LoopState struct {
 	Model             string
 	MaxThinkingTokens int
 	StopSequence      string // the stop sequence value if stop_sequence reason
-	UsingFallback     bool   // true if currently using FallbackModel after a retriable error
+	UsingFallback     bool   // true if a FallbackModels entry took over after a retriable error
+	fallbackFrom      string // state.Model before the fallback, restored on the next turn
 	BudgetDowngraded  bool   // true if model was downgraded due to budget threshold
+	modelPinned       bool   // true once the host sets the model; routing stops
+
+	// Tool outcome streaks, for ModelRouter decisions.
+	ConsecutiveToolErrors int // failed tool calls since the last success
+	ReadOnlyTurns         int // consecutive turns that only called side-effect-free tools
 
 	// LastError captures the last error that caused the loop to exit.
 	LastError error
 
+	// StructuredOutput is the parsed final answer when OutputFormat is set.
+	StructuredOutput  any
+	structuredRetries int // corrections requested for the current answer
+
 	// PendingAdditionalContext collects context from hooks to inject
 	// into the system prompt on the next LLM call.
 	PendingAdditionalContext []string
@@ -46,10 +66,46 @@ type LoopState struct {
 	// Key: absolute file path, Value: set of operations (read, write, edit, glob, grep, exec)
 	AccessedFiles map[string]map[string]bool
 
+	// CheckpointID is the UUID of the user message that started the current
+	// turn. Files are snapshotted under it before their first modification so
+	// the turn can be undone with SessionStore.Rewind.
+	CheckpointID string
+	checkpointed map[string]bool // paths already snapshotted under CheckpointID
+
+	sessionSavedAt time.Time // last metadata flush, for SessionAutoSaveInterval
+
 	// ActiveSkill holds the scope of the currently executing skill.
-	// When set, tool permission checks are augmented by the skill's allowed-tools.
-	// Cleared on end_turn or next user message.
+	// When set, only the skill's allowed-tools are offered to the model and
+	// permitted to run. Cleared on end_turn or next user message.
 	ActiveSkill *SkillScope
+
+	// firedRules records which ConditionalRules (by index) have been injected.
+	firedRules map[int]bool
+
+	// recentToolCalls is the ToolLoopDetection window, oldest first.
+	recentToolCalls []toolCallSignature
+
+	// todos is the TodoWrite list as of the last TodoContinuity sync.
+	todos []tools.TodoItem
+
+	// budgetWarned records which BudgetWarnThresholds have already been reported,
+	// and modelBudgetWarned which models have had their ModelBudgets warning.
+	budgetWarned      map[float64]bool
+	modelBudgetWarned map[string]bool
+
+	// ModelBudgetExceeded is the model whose ModelBudgets limit ended the run.
+	ModelBudgetExceeded string
+
+	// Cost and metrics attribution for the current turn: the model that
+	// served it (after any fallback) and each tool call's share of its cost.
+	turnModel     string
+	toolCostShare float64
+
+	// toolCalls backs Query.ToolCalls when ToolCallLog is set.
+	toolCalls *toolCallLog
+
+	// tokenCache memoizes per-message token counts across turns.
+	tokenCache *messageTokenCache
 }
 
 // SkillScope holds the runtime context for an active skill execution.
@@ -69,6 +125,49 @@ func (s *LoopState) RecordFileAccess(path string, op string) {
 	s.AccessedFiles[path][op] = true
 }
 
+// fileKnown reports whether path was read, written or edited this session.
+func (s *LoopState) fileKnown(path string) bool {
+	path = filepath.Clean(path)
+	for p, ops := range s.AccessedFiles {
+		if filepath.Clean(p) == path && (ops["read"] || ops["write"] || ops["edit"]) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMessages adds msgs to the history, giving each an ID.
+func (s *LoopState) appendMessages(msgs ...llm.ChatMessage) {
+	assignMessageIDs(msgs)
+	s.Messages = append(s.Messages, msgs...)
+}
+
+// setMessages replaces the history (after compaction, windowing, pruning or
+// a rewind), giving new messages an ID and forgetting the token counts of
+// the ones dropped.
+func (s *LoopState) setMessages(msgs []llm.ChatMessage) {
+	assignMessageIDs(msgs)
+	s.Messages = msgs
+	if s.tokenCache != nil {
+		s.tokenCache.retain(msgs)
+	}
+}
+
+// assignMessageIDs gives each message without an ID a new one.
+func assignMessageIDs(msgs []llm.ChatMessage) {
+	for i := range msgs {
+		if msgs[i].ID == "" {
+			msgs[i].ID = uuid.New().String()
+		}
+	}
+}
+
+// startCheckpoint begins a new per-turn checkpoint keyed by a user message UUID.
+func (s *LoopState) startCheckpoint(id string) {
+	s.CheckpointID = id
+	s.checkpointed = nil
+}
+
 // addUsage accumulates token usage from an LLM response.
 func (s *LoopState) addUsage(usage types.BetaUsage) {
 	s.TotalUsage.InputTokens += usage.InputTokens
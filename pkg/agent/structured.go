@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// defaultStructuredOutputRetries is how many times the model is asked to fix
+// a final answer that does not match the output schema.
+const defaultStructuredOutputRetries = 2
+
+func maxStructuredOutputRetries(config *AgentConfig) int {
+	if config.MaxStructuredOutputRetries > 0 {
+		return config.MaxStructuredOutputRetries
+	}
+	return defaultStructuredOutputRetries
+}
+
+// structuredOutputInstruction is appended to the system prompt so the model
+// knows the shape its final answer must take.
+func structuredOutputInstruction(format *types.OutputFormat) string {
+	schema, _ := json.MarshalIndent(format.Schema, "", "  ")
+	return "# Output format\n\nWhen you have finished the task, your final response must be a single JSON value " +
+		"that conforms to the JSON Schema below. Do not wrap it in prose or code fences.\n\n" + string(schema)
+}
+
+// structuredRetryPrompt asks the model to correct an invalid final answer.
+func structuredRetryPrompt(err error) string {
+	return fmt.Sprintf("Your final response is not valid structured output: %s. "+
+		"Reply with only the corrected JSON value that conforms to the required schema.", err)
+}
+
+// parseStructuredOutput decodes the model's final text as JSON, tolerating a
+// surrounding code fence, and checks it against schema.
+func parseStructuredOutput(text string, schema map[string]any) (any, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err := tools.ValidateSchema(value, schema); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
@@ -0,0 +1,117 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+var callersSchema = map[string]any{
+	"type":     "object",
+	"required": []any{"callers"},
+	"properties": map[string]any{
+		"callers": map[string]any{
+			"type":  "array",
+			"items": map[string]any{"type": "string"},
+		},
+		"kind": map[string]any{"type": "string", "enum": []any{"direct", "indirect"}},
+	},
+}
+
+func TestParseStructuredOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr string
+	}{
+		{"valid", `{"callers": ["a.go:1", "b.go:2"]}`, ""},
+		{"code fence", "```json\n{\"callers\": []}\n```", ""},
+		{"not json", "The callers are a and b.", "invalid JSON"},
+		{"missing required", `{"kind": "direct"}`, `$: missing required property "callers"`},
+		{"wrong item type", `{"callers": [1]}`, "$.callers[0]: expected string, got number"},
+		{"enum", `{"callers": [], "kind": "sideways"}`, "$.kind: sideways is not one of"},
+		{"wrong top-level type", `["a.go:1"]`, "$: expected object, got array"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseStructuredOutput(tt.text, callersSchema)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoop_StructuredOutputRetry(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			endTurnResponse("The callers are a.go and b.go."),
+			endTurnResponse(`{"callers": ["a.go:1", "b.go:2"]}`),
+		},
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.OutputFormat = &types.OutputFormat{Type: "json_schema", Schema: callersSchema}
+
+	q := RunLoop(context.Background(), "Find all callers of X", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	result := lastResult(t, msgs)
+	if result.IsError {
+		t.Fatalf("unexpected error result: %v", result.Errors)
+	}
+	got, ok := result.StructuredOutput.(map[string]any)
+	if !ok || len(got["callers"].([]any)) != 2 {
+		t.Fatalf("structured output = %#v", result.StructuredOutput)
+	}
+
+	state := q.State()
+	if n := len(state.Messages); n != 4 || !strings.Contains(state.Messages[2].Content.(string), "not valid structured output: invalid JSON") {
+		t.Errorf("expected a correction request after the prose answer, got %+v", state.Messages)
+	}
+}
+
+func TestLoop_StructuredOutputGivesUp(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			endTurnResponse("prose"),
+			endTurnResponse(`{"kind": "direct"}`),
+		},
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.OutputFormat = &types.OutputFormat{Type: "json_schema", Schema: callersSchema}
+	config.MaxStructuredOutputRetries = 1
+
+	q := RunLoop(context.Background(), "Find all callers of X", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if reason := q.GetExitReason(); reason != ExitMaxStructuredRetries {
+		t.Fatalf("exit reason = %q", reason)
+	}
+	result := lastResult(t, msgs)
+	if result.Subtype != types.ResultSubtypeErrorMaxStructuredRetries || len(result.Errors) == 0 ||
+		!strings.Contains(result.Errors[0], `missing required property "callers"`) {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func lastResult(t *testing.T, msgs []types.SDKMessage) *types.ResultMessage {
+	t.Helper()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if r, ok := msgs[i].(*types.ResultMessage); ok {
+			return r
+		}
+	}
+	t.Fatal("no result message")
+	return nil
+}
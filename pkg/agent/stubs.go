@@ -67,12 +67,18 @@ func (n *NoOpCompactor) Compact(_ context.Context, req CompactRequest) ([]llm.Ch
 // NoOpSessionStore does nothing and returns empty values.
 type NoOpSessionStore struct{}
 
-func (n *NoOpSessionStore) Create(_ SessionMetadata) error                          { return nil }
-func (n *NoOpSessionStore) Load(_ string) (*SessionState, error)                    { return &SessionState{}, nil }
-func (n *NoOpSessionStore) LoadLatest(_ string) (*SessionState, error)              { return nil, nil }
-func (n *NoOpSessionStore) Delete(_ string) error                                   { return nil }
-func (n *NoOpSessionStore) List() ([]SessionMetadata, error)                        { return nil, nil }
-func (n *NoOpSessionStore) Fork(_, _ string) (*SessionState, error)                 { return &SessionState{}, nil }
+func (n *NoOpSessionStore) Create(_ SessionMetadata) error             { return nil }
+func (n *NoOpSessionStore) Load(_ string) (*SessionState, error)       { return &SessionState{}, nil }
+func (n *NoOpSessionStore) LoadLatest(_ string) (*SessionState, error) { return nil, nil }
+func (n *NoOpSessionStore) Delete(_ string) error                      { return nil }
+func (n *NoOpSessionStore) List() ([]SessionMetadata, error)           { return nil, nil }
+func (n *NoOpSessionStore) ListSessions(_ SessionFilter) ([]SessionMetadata, error) {
+	return nil, nil
+}
+func (n *NoOpSessionStore) Fork(_, _ string) (*SessionState, error) { return &SessionState{}, nil }
+func (n *NoOpSessionStore) ForkAt(_, _ string, _ *llm.ChatMessage) (*SessionState, error) {
+	return &SessionState{}, nil
+}
 func (n *NoOpSessionStore) AppendMessage(_ string, _ MessageEntry) error            { return nil }
 func (n *NoOpSessionStore) AppendSDKMessage(_ string, _ types.SDKMessage) error     { return nil }
 func (n *NoOpSessionStore) LoadMessages(_ string) ([]MessageEntry, error)           { return nil, nil }
@@ -82,4 +88,5 @@ func (n *NoOpSessionStore) CreateCheckpoint(_, _ string, _ []string) error
 func (n *NoOpSessionStore) RewindFiles(_, _ string, _ bool) (*RewindFilesResult, error) {
 	return &RewindFilesResult{}, nil
 }
-func (n *NoOpSessionStore) Close() error { return nil }
+func (n *NoOpSessionStore) Rewind(_, _ string) ([]MessageEntry, error) { return nil, nil }
+func (n *NoOpSessionStore) Close() error                               { return nil }
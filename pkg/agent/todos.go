@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"encoding/json"
+	"slices"
+
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// todoTool returns the registry's TodoWrite tool when it is the built-in one.
+func todoTool(config *AgentConfig) *tools.TodoWriteTool {
+	if config.ToolRegistry == nil {
+		return nil
+	}
+	tool, _ := config.ToolRegistry.Get("TodoWrite")
+	todo, _ := tool.(*tools.TodoWriteTool)
+	return todo
+}
+
+// restoreTodos puts a resumed session's saved todo list back into the
+// TodoWrite tool and reminds the model of it on the first call.
+func restoreTodos(config *AgentConfig, state *LoopState, todos []tools.TodoItem) {
+	tool := todoTool(config)
+	if !config.TodoContinuity || tool == nil || len(todos) == 0 {
+		return
+	}
+	tool.SetTodos(todos)
+	state.todos = slices.Clone(todos)
+	state.PendingAdditionalContext = append(state.PendingAdditionalContext, todoReminder(todos))
+}
+
+// syncTodos runs after each batch of tool calls: when the todo list has
+// changed it is saved to the session and, unless now empty, restated to the
+// model on the next call.
+func syncTodos(config *AgentConfig, state *LoopState) {
+	tool := todoTool(config)
+	if !config.TodoContinuity || tool == nil {
+		return
+	}
+	todos := tool.List()
+	if slices.Equal(todos, state.todos) {
+		return
+	}
+	state.todos = todos
+	if config.SessionStore != nil {
+		_ = config.SessionStore.UpdateMetadata(state.SessionID, func(m *SessionMetadata) {
+			m.Todos = todos
+		})
+	}
+	if len(todos) > 0 {
+		state.PendingAdditionalContext = append(state.PendingAdditionalContext, todoReminder(todos))
+	}
+}
+
+// todoReminder restates the todo list, worded like the todo_list_changed
+// system reminder.
+func todoReminder(todos []tools.TodoItem) string {
+	data, _ := json.Marshal(todos)
+	return "Your todo list has changed. DO NOT mention this explicitly to the user. Here are the latest contents of your todo list:\n\n" +
+		string(data) + ". Continue on with the tasks at hand if applicable."
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestLoop_TodoContinuity(t *testing.T) {
+	todos := map[string]any{"todos": []any{
+		map[string]any{"content": "Write tests", "status": "in_progress", "activeForm": "Writing tests"},
+	}}
+	todoTool := &tools.TodoWriteTool{}
+	registry := tools.NewRegistry()
+	registry.Register(todoTool)
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call_1", "TodoWrite", todos),
+		toolUseResponse("call_2", "TodoWrite", todos), // unchanged
+		endTurnResponse("Done."),
+	}}}
+	store := &mockSessionStore{}
+	config := defaultConfig(client, registry)
+	config.SessionStore = store
+	config.TodoContinuity = true
+
+	q := RunLoop(context.Background(), "Plan it", config)
+	collectMessages(q)
+	q.Wait()
+
+	want := []tools.TodoItem{{Content: "Write tests", Status: "in_progress", ActiveForm: "Writing tests"}}
+	store.mu.Lock()
+	saved := store.meta.Todos
+	store.mu.Unlock()
+	if !slices.Equal(saved, want) {
+		t.Errorf("saved todos = %+v, want %+v", saved, want)
+	}
+
+	reqs := client.getRequests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	var reminded []int
+	for i, req := range reqs {
+		system, _ := req.Messages[0].Content.(string)
+		if strings.Contains(system, "Your todo list has changed") {
+			reminded = append(reminded, i)
+			if !strings.Contains(system, `"content":"Write tests"`) {
+				t.Errorf("request %d: reminder missing the list: %q", i, system)
+			}
+		}
+	}
+	if len(reminded) != 1 || reminded[0] != 1 {
+		t.Errorf("todo reminder in requests %v, want only request 1", reminded)
+	}
+}
+
+func TestRestoreSession_Todos(t *testing.T) {
+	saved := []tools.TodoItem{{Content: "Ship it", Status: "pending"}}
+	newStore := func() *mockSessionStore {
+		return &mockSessionStore{loadFunc: func(string) (*SessionState, error) {
+			return &SessionState{
+				Metadata: SessionMetadata{ID: "s1", Todos: saved},
+				Messages: []MessageEntry{{UUID: "m1", Message: llm.ChatMessage{Role: "user", Content: "Hello"}}},
+			}, nil
+		}}
+	}
+
+	for _, continuity := range []bool{true, false} {
+		todoTool := &tools.TodoWriteTool{}
+		registry := tools.NewRegistry()
+		registry.Register(todoTool)
+		config := &AgentConfig{SessionStore: newStore(), ToolRegistry: registry, TodoContinuity: continuity}
+		state := &LoopState{}
+		if err := RestoreSession(config, state, types.QueryOptions{Resume: "s1"}); err != nil {
+			t.Fatal(err)
+		}
+
+		restored := todoTool.List()
+		if continuity {
+			if !slices.Equal(restored, saved) {
+				t.Errorf("restored todos = %+v, want %+v", restored, saved)
+			}
+			if len(state.PendingAdditionalContext) != 1 || !strings.Contains(state.PendingAdditionalContext[0], "Ship it") {
+				t.Errorf("pending context = %q, want a todo reminder", state.PendingAdditionalContext)
+			}
+		} else if len(restored) != 0 || len(state.PendingAdditionalContext) != 0 {
+			t.Errorf("todos restored without TodoContinuity: %+v", restored)
+		}
+	}
+}
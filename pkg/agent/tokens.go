@@ -0,0 +1,185 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+// messageOverheadTokens approximates the per-message framing cost (role + separators).
+const messageOverheadTokens = 4
+
+func init() {
+	// Use the embedded BPE ranks so token counting never touches the network.
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// HeuristicTokenCounter estimates tokens using the ~4 characters per token rule.
+// It is the fallback when no tokenizer is available for the model.
+type HeuristicTokenCounter struct{}
+
+// CountTokens returns len(text)/4.
+func (HeuristicTokenCounter) CountTokens(text string) int {
+	return len(text) / 4
+}
+
+// TiktokenCounter counts tokens with an OpenAI BPE encoding (cl100k_base, o200k_base).
+type TiktokenCounter struct {
+	enc *tiktoken.Tiktoken
+	mu  sync.Mutex // the underlying encoder caches internally and is not goroutine-safe
+}
+
+var (
+	tiktokenMu       sync.Mutex
+	tiktokenCounters = make(map[string]*TiktokenCounter)
+)
+
+// NewTiktokenCounter returns a counter for the named encoding (e.g. "cl100k_base").
+// Encodings are expensive to build, so counters are shared process-wide.
+func NewTiktokenCounter(encoding string) (*TiktokenCounter, error) {
+	tiktokenMu.Lock()
+	defer tiktokenMu.Unlock()
+	if c, ok := tiktokenCounters[encoding]; ok {
+		return c, nil
+	}
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	c := &TiktokenCounter{enc: enc}
+	tiktokenCounters[encoding] = c
+	return c, nil
+}
+
+// CountTokens returns the exact BPE token count for text.
+func (c *TiktokenCounter) CountTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.enc.Encode(text, nil, nil))
+}
+
+// NewTokenCounterForModel returns the best available counter for a model.
+// GPT-4o and o-series models use o200k_base, other OpenAI-style models use
+// cl100k_base, and anything whose encoding cannot be loaded falls back to
+// HeuristicTokenCounter. cl100k_base is a closer approximation for Claude
+// than len/4, especially on code.
+func NewTokenCounterForModel(model string) TokenCounter {
+	counter, _ := modelTokenCounter(model)
+	return counter
+}
+
+// modelTokenCounter returns NewTokenCounterForModel's counter and the name
+// of its encoding ("heuristic" for the fallback).
+func modelTokenCounter(model string) (TokenCounter, string) {
+	encoding := "cl100k_base"
+	m := strings.ToLower(model)
+	if i := strings.LastIndex(m, "/"); i >= 0 {
+		m = m[i+1:]
+	}
+	if strings.HasPrefix(m, "gpt-4o") || strings.HasPrefix(m, "gpt-4.1") || strings.HasPrefix(m, "gpt-5") ||
+		strings.HasPrefix(m, "o1") || strings.HasPrefix(m, "o3") || strings.HasPrefix(m, "o4") {
+		encoding = "o200k_base"
+	}
+	counter, err := NewTiktokenCounter(encoding)
+	if err != nil {
+		return HeuristicTokenCounter{}, "heuristic"
+	}
+	return counter, encoding
+}
+
+// configuredEncoding names AgentConfig.TokenCounter in cache keys.
+const configuredEncoding = "configured"
+
+// messageTokenCache memoizes per-message token counts so the full history is
+// not re-tokenized on every turn. Entries are keyed by message ID and the
+// encoding that counted them, so switching to a model with another tokenizer
+// recounts. Messages without an ID are counted every time.
+type messageTokenCache struct {
+	mu       sync.Mutex
+	counter  TokenCounter
+	encoding string
+	counts   map[tokenCacheKey]int
+}
+
+type tokenCacheKey struct {
+	encoding, id string
+}
+
+func newMessageTokenCache(counter TokenCounter, encoding string) *messageTokenCache {
+	return &messageTokenCache{counter: counter, encoding: encoding, counts: make(map[tokenCacheKey]int)}
+}
+
+// use makes counter, named encoding, count from now on. Counts made with
+// other encodings are kept for a switch back.
+func (c *messageTokenCache) use(counter TokenCounter, encoding string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counter, c.encoding = counter, encoding
+}
+
+// count returns the cached token count for msg, tokenizing it on first sight.
+func (c *messageTokenCache) count(msg llm.ChatMessage) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if msg.ID == "" {
+		return countMessageTokens(c.counter, msg)
+	}
+	key := tokenCacheKey{c.encoding, msg.ID}
+	if n, ok := c.counts[key]; ok {
+		return n
+	}
+	n := countMessageTokens(c.counter, msg)
+	c.counts[key] = n
+	return n
+}
+
+// retain drops the counts of messages not in messages, e.g. after compaction.
+func (c *messageTokenCache) retain(messages []llm.ChatMessage) {
+	live := make(map[string]bool, len(messages))
+	for _, msg := range messages {
+		live[msg.ID] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.counts {
+		if !live[key.id] {
+			delete(c.counts, key)
+		}
+	}
+}
+
+// countMessageTokens counts the tokens in a message: text content (including
+// tool-result content and multi-part text), a fixed estimate per image, tool
+// call names and arguments, plus a fixed per-message overhead.
+func countMessageTokens(counter TokenCounter, msg llm.ChatMessage) int {
+	total := messageOverheadTokens
+	switch c := msg.Content.(type) {
+	case string:
+		total += counter.CountTokens(c)
+	case []llm.ContentPart:
+		for _, part := range c {
+			total += counter.CountTokens(part.Text)
+		}
+	case []any:
+		for _, part := range c {
+			if m, ok := part.(map[string]any); ok {
+				if text, ok := m["text"].(string); ok {
+					total += counter.CountTokens(text)
+				}
+			}
+		}
+	}
+	total += llm.CountImageParts(msg.Content) * llm.ImageTokenEstimate
+	for _, tc := range msg.ToolCalls {
+		total += counter.CountTokens(tc.Function.Name)
+		total += counter.CountTokens(tc.Function.Arguments)
+	}
+	return total
+}
@@ -0,0 +1,178 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+// countingCounter counts every CountTokens call so cache hits can be observed.
+type countingCounter struct {
+	calls int
+}
+
+func (c *countingCounter) CountTokens(text string) int {
+	c.calls++
+	return len(text)
+}
+
+func TestTiktokenCounter_CountTokens(t *testing.T) {
+	for _, enc := range []string{"cl100k_base", "o200k_base"} {
+		t.Run(enc, func(t *testing.T) {
+			c, err := NewTiktokenCounter(enc)
+			if err != nil {
+				t.Fatalf("NewTiktokenCounter: %v", err)
+			}
+			if got := c.CountTokens(""); got != 0 {
+				t.Errorf("empty text = %d, want 0", got)
+			}
+			if got := c.CountTokens("hello world"); got != 2 {
+				t.Errorf("hello world = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestTiktokenCounter_CodeExceedsHeuristic(t *testing.T) {
+	c, err := NewTiktokenCounter("cl100k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := strings.Repeat("if (x[i] != y[j]) { z += a*b; }\n", 50)
+	exact := c.CountTokens(code)
+	heuristic := HeuristicTokenCounter{}.CountTokens(code)
+	if exact <= heuristic {
+		t.Errorf("expected tokenizer (%d) to exceed len/4 (%d) on code", exact, heuristic)
+	}
+}
+
+func TestNewTokenCounterForModel(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o-mini", "o200k_base"},
+		{"openai/o3-mini", "o200k_base"},
+		{"gpt-4-turbo", "cl100k_base"},
+		{"claude-sonnet-4-5-20250929", "cl100k_base"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			c, ok := NewTokenCounterForModel(tt.model).(*TiktokenCounter)
+			if !ok {
+				t.Fatalf("expected *TiktokenCounter")
+			}
+			if got := c.enc.Encode("x", nil, nil); len(got) != 1 {
+				t.Fatalf("unexpected encoding result %v", got)
+			}
+			want, _ := NewTiktokenCounter(tt.want)
+			sample := "func main() { fmt.Println(\"héllo\") }"
+			if c.CountTokens(sample) != want.CountTokens(sample) {
+				t.Errorf("model %s did not resolve to %s", tt.model, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountMessageTokens(t *testing.T) {
+	h := HeuristicTokenCounter{}
+	tests := []struct {
+		name string
+		msg  llm.ChatMessage
+		want int
+	}{
+		{"nil content", llm.ChatMessage{Role: "assistant"}, 4},
+		{"string", llm.ChatMessage{Role: "user", Content: strings.Repeat("a", 40)}, 14},
+		{"tool result", llm.ChatMessage{Role: "tool", ToolCallID: "t1", Content: strings.Repeat("b", 400)}, 104},
+		{"content parts", llm.ChatMessage{Role: "user", Content: []llm.ContentPart{
+			{Type: "text", Text: strings.Repeat("c", 20)},
+			{Type: "text", Text: strings.Repeat("d", 20)},
+		}}, 14},
+		{"image", llm.ChatMessage{Role: "user", Content: []llm.ContentPart{
+			llm.TextPart(strings.Repeat("c", 40)),
+			llm.ImageURLPart("https://example.com/shot.png"),
+		}}, 14 + llm.ImageTokenEstimate},
+		{"tool calls", llm.ChatMessage{Role: "assistant", ToolCalls: []llm.ToolCall{
+			{ID: "t1", Type: "function", Function: llm.FunctionCall{Name: "Bash", Arguments: strings.Repeat("e", 40)}},
+		}}, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := countMessageTokens(h, tt.msg); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateTokenBudget_UsesConfiguredCounterAndCache(t *testing.T) {
+	counter := &countingCounter{}
+	config := &AgentConfig{Model: "test", TokenCounter: counter}
+	state := &LoopState{}
+	state.appendMessages(
+		llm.ChatMessage{Role: "user", Content: "hello"},
+		llm.ChatMessage{Role: "tool", ToolCallID: "t1", Content: "tool output"},
+	)
+
+	budget := calculateTokenBudget(config, state, "system")
+	if budget.SystemPromptTkns != len("system") {
+		t.Errorf("SystemPromptTkns = %d, want %d", budget.SystemPromptTkns, len("system"))
+	}
+	wantMsg := len("hello") + len("tool output") + 2*messageOverheadTokens
+	if budget.MessageTkns != wantMsg {
+		t.Errorf("MessageTkns = %d, want %d", budget.MessageTkns, wantMsg)
+	}
+
+	// Second call should only tokenize the system prompt and the new message.
+	counter.calls = 0
+	state.appendMessages(llm.ChatMessage{Role: "assistant", Content: "done"})
+	budget = calculateTokenBudget(config, state, "system")
+	if counter.calls != 2 {
+		t.Errorf("expected 2 CountTokens calls with cache, got %d", counter.calls)
+	}
+	if budget.MessageTkns != wantMsg+len("done")+messageOverheadTokens {
+		t.Errorf("MessageTkns = %d after append", budget.MessageTkns)
+	}
+}
+
+func TestCalculateTokenBudget_FollowsModelSwitch(t *testing.T) {
+	config := &AgentConfig{Model: "claude-sonnet-4-5"}
+	state := &LoopState{}
+	state.appendMessages(llm.ChatMessage{Role: "user", Content: "func main() { fmt.Println(\"héllo, wörld\") }"})
+	calculateTokenBudget(config, state, "")
+
+	state.Model = "gpt-4o" // e.g. a fallback or router switch
+	budget := calculateTokenBudget(config, state, "")
+	o200k, err := NewTiktokenCounter("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := countMessageTokens(o200k, state.Messages[0]); budget.MessageTkns != want {
+		t.Errorf("MessageTkns = %d, want the o200k_base count %d", budget.MessageTkns, want)
+	}
+	if len(state.tokenCache.counts) != 2 {
+		t.Errorf("cache has %d entries, want one per encoding", len(state.tokenCache.counts))
+	}
+}
+
+func TestSetMessages_DropsRemovedCounts(t *testing.T) {
+	config := &AgentConfig{Model: "test", TokenCounter: &countingCounter{}}
+	state := &LoopState{}
+	state.appendMessages(
+		llm.ChatMessage{Role: "user", Content: "first"},
+		llm.ChatMessage{Role: "assistant", Content: "second"},
+		llm.ChatMessage{Role: "user", Content: "third"},
+	)
+	calculateTokenBudget(config, state, "")
+
+	// As after compaction: a summary replaces the first two messages
+	state.setMessages([]llm.ChatMessage{{Role: "user", Content: "summary"}, state.Messages[2]})
+	calculateTokenBudget(config, state, "")
+	if len(state.tokenCache.counts) != 2 {
+		t.Errorf("cache has %d entries, want only the 2 live messages", len(state.tokenCache.counts))
+	}
+	if state.Messages[0].ID == "" {
+		t.Error("setMessages should give the summary an ID")
+	}
+}
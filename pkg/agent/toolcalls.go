@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// toolCallOutputLimit caps the result content kept in a ToolCallRecord.
+const toolCallOutputLimit = 500
+
+// toolCallLog accumulates ToolCallRecords for Query.ToolCalls. It has its
+// own lock since parallel tool batches finish on other goroutines.
+type toolCallLog struct {
+	mu      sync.Mutex
+	records []types.ToolCallRecord
+}
+
+func (l *toolCallLog) add(rec types.ToolCallRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, rec)
+}
+
+func (l *toolCallLog) snapshot() []types.ToolCallRecord {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]types.ToolCallRecord(nil), l.records...)
+}
+
+// ToolCalls returns a record of every tool call executed so far, in call
+// order. It is empty unless AgentConfig.ToolCallLog is set.
+func (q *Query) ToolCalls() []types.ToolCallRecord {
+	return q.state.toolCalls.snapshot()
+}
+
+// newToolCallRecord starts the record for block; the executor fills in
+// permission and hook outcomes as the call progresses.
+func newToolCallRecord(block types.ContentBlock) *types.ToolCallRecord {
+	return &types.ToolCallRecord{ToolUseID: block.ID, ToolName: block.Name, Input: block.Input}
+}
+
+// logToolCall completes rec from the call's result and adds it to the log
+// and the message stream, as configured.
+func logToolCall(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, rec *types.ToolCallRecord, result llm.ToolResult) {
+	if !config.ToolCallLog && !config.ToolCallMessages {
+		return
+	}
+	rec.Output = truncateToolResult(result.Content, toolCallOutputLimit)
+	rec.IsError = strings.HasPrefix(result.Content, "Error:")
+	rec.FromCache = result.Metadata != nil && result.Metadata.FromCache
+	if config.ToolCallLog {
+		if state.toolCalls == nil {
+			state.toolCalls = &toolCallLog{} // states built outside RunLoop
+		}
+		state.toolCalls.add(*rec)
+	}
+	if config.ToolCallMessages {
+		ch <- types.NewToolCallMessage(*rec, state.SessionID)
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// denyToolChecker denies one tool by name and allows the rest.
+type denyToolChecker struct{ name string }
+
+func (c *denyToolChecker) Check(_ context.Context, toolName string, _ map[string]any) (PermissionResult, error) {
+	if toolName == c.name {
+		return PermissionResult{Behavior: "deny", Message: "blocked by policy"}, nil
+	}
+	return PermissionResult{Behavior: "allow"}, nil
+}
+
+func TestExecuteTools_ToolCallLog(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Reader", output: tools.ToolOutput{Content: "contents"}})
+	registry.Register(&mockRecordingTool{name: "Blocked", output: tools.ToolOutput{Content: "never"}})
+	config := &AgentConfig{
+		ToolRegistry: registry,
+		Permissions:  &denyToolChecker{name: "Blocked"},
+		Hooks: hookFunc(func(event types.HookEvent, _ any) []HookResult {
+			if event == types.HookEventPreToolUse {
+				return []HookResult{{Decision: "allow"}}
+			}
+			return nil
+		}),
+		DedupToolCalls:   true,
+		ToolCallLog:      true,
+		ToolCallMessages: true,
+	}
+	blocks := []types.ContentBlock{
+		{Name: "Reader", ID: "tc1", Input: map[string]any{"path": "a"}},
+		{Name: "Blocked", ID: "tc2", Input: map[string]any{}},
+		{Name: "Reader", ID: "tc3", Input: map[string]any{"path": "a"}},
+	}
+	state := &LoopState{SessionID: "s1"}
+	ch := make(chan types.SDKMessage, 100)
+
+	executeTools(context.Background(), blocks, config, state, ch)
+	close(ch)
+
+	records := state.toolCalls.snapshot()
+	if len(records) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(records), records)
+	}
+	if r := records[0]; r.ToolUseID != "tc1" || r.Permission != "allow" || r.PreToolUse != "allow" ||
+		r.Output != "contents" || r.IsError || r.Input["path"] != "a" {
+		t.Errorf("allowed call = %+v", r)
+	}
+	if r := records[1]; r.ToolUseID != "tc2" || r.Permission != "deny" || r.PermissionMessage != "blocked by policy" ||
+		!r.IsError || r.PreToolUse != "" || r.DurationMs != 0 {
+		t.Errorf("denied call = %+v", r)
+	}
+	if r := records[2]; r.ToolUseID != "tc3" || r.DuplicateOf != "tc1" || r.Output != "contents" {
+		t.Errorf("duplicate call = %+v", r)
+	}
+
+	var streamed []string
+	for msg := range ch {
+		if m, ok := msg.(*types.ToolCallMessage); ok {
+			streamed = append(streamed, m.ToolUseID)
+		}
+	}
+	if len(streamed) != 3 || streamed[0] != "tc1" || streamed[1] != "tc2" || streamed[2] != "tc3" {
+		t.Errorf("streamed records = %v, want call order", streamed)
+	}
+}
+
+func TestQuery_ToolCalls(t *testing.T) {
+	newConfig := func(log bool) AgentConfig {
+		client := &mockLLMClient{responses: []*mockStream{
+			toolUseResponse("tc1", "Echo", map[string]any{"text": "hi"}),
+			endTurnResponse("done"),
+		}}
+		registry := tools.NewRegistry()
+		registry.Register(&mockRecordingTool{name: "Echo", output: tools.ToolOutput{Content: "hi"}})
+		config := defaultConfig(client, registry)
+		config.ToolCallLog = log
+		return config
+	}
+
+	q := RunLoop(context.Background(), "Hello", newConfig(true))
+	for msg := range q.Messages() {
+		if _, ok := msg.(*types.ToolCallMessage); ok {
+			t.Error("ToolCallMessage emitted without ToolCallMessages")
+		}
+	}
+	q.Wait()
+	calls := q.ToolCalls()
+	if len(calls) != 1 || calls[0].ToolName != "Echo" || calls[0].Output != "hi" {
+		t.Errorf("ToolCalls() = %+v", calls)
+	}
+
+	q = RunLoop(context.Background(), "Hello", newConfig(false))
+	collectMessages(q)
+	q.Wait()
+	if calls := q.ToolCalls(); len(calls) != 0 {
+		t.Errorf("ToolCalls() without ToolCallLog = %+v", calls)
+	}
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// ToolLoopConfig enables detection of a model stuck repeating the same
+// failing tool call. A call counts as a repeat when its tool name, input and
+// error all match an earlier one among the recent calls.
+type ToolLoopConfig struct {
+	// Threshold is how many identical failing calls make a loop (default 3).
+	Threshold int
+	// Window is how many of the most recent tool calls are compared (default 10).
+	Window int
+	// Stop ends the run with ExitToolLoop when a loop is found. By default
+	// the model is instead told to change approach on its next turn.
+	Stop bool
+}
+
+const (
+	toolLoopDefaultThreshold = 3
+	toolLoopDefaultWindow    = 10
+	toolLoopErrorPrefix      = 500 // bytes of the repeated error quoted back to the model
+)
+
+func (c *ToolLoopConfig) threshold() int {
+	if c.Threshold > 0 {
+		return c.Threshold
+	}
+	return toolLoopDefaultThreshold
+}
+
+func (c *ToolLoopConfig) window() int {
+	if c.Window > 0 {
+		return c.Window
+	}
+	return toolLoopDefaultWindow
+}
+
+// toolCallSignature identifies a tool call and its outcome for loop detection.
+type toolCallSignature struct {
+	name  string
+	input string // canonical JSON
+	err   string // "" for a successful call
+}
+
+// errToolLoop is the LastError of a run ended by ToolLoopConfig.Stop.
+var errToolLoop = errors.New("the model repeated the same failing tool call")
+
+// checkToolLoop runs loop detection after a turn's tool calls when
+// ToolLoopDetection is set. It queues a reminder to change approach, or
+// with Stop set reports that the run should end.
+func checkToolLoop(config *AgentConfig, state *LoopState, toolBlocks []types.ContentBlock, results []llm.ToolResult) (stop bool) {
+	if config.ToolLoopDetection == nil {
+		return false
+	}
+	loop, found := detectToolLoop(config, state, toolBlocks, results)
+	if !found {
+		return false
+	}
+	if config.ToolLoopDetection.Stop {
+		state.LastError = fmt.Errorf("%w: %s", errToolLoop, loop.name)
+		return true
+	}
+	state.PendingAdditionalContext = append(state.PendingAdditionalContext,
+		toolLoopReminder(loop, config.ToolLoopDetection.threshold()))
+	return false
+}
+
+// detectToolLoop records a turn's tool calls in the rolling window and
+// reports the call that has now failed Threshold times in it, if any. The
+// window is cleared when a loop is found, so the model gets a fresh
+// Threshold of attempts after a nudge.
+func detectToolLoop(config *AgentConfig, state *LoopState, toolBlocks []types.ContentBlock, results []llm.ToolResult) (toolCallSignature, bool) {
+	threshold, window := config.ToolLoopDetection.threshold(), config.ToolLoopDetection.window()
+
+	content := make(map[string]string, len(results))
+	for _, r := range results {
+		content[r.ToolUseID] = r.Content
+	}
+	var loop toolCallSignature
+	found := false
+	for _, block := range toolBlocks {
+		input, _ := json.Marshal(block.Input) // map keys are sorted
+		key := toolCallSignature{name: block.Name, input: string(input)}
+		if c := content[block.ID]; strings.HasPrefix(c, "Error:") {
+			key.err = c
+		}
+		state.recentToolCalls = append(state.recentToolCalls, key)
+		if n := len(state.recentToolCalls); n > window {
+			state.recentToolCalls = state.recentToolCalls[n-window:]
+		}
+		if key.err == "" || found {
+			continue
+		}
+		repeats := 0
+		for _, prev := range state.recentToolCalls {
+			if prev == key {
+				repeats++
+			}
+		}
+		if repeats >= threshold {
+			loop, found = key, true
+		}
+	}
+	if found {
+		state.recentToolCalls = nil
+	}
+	return loop, found
+}
+
+// toolLoopReminder tells the model to stop retrying the call in loop.
+func toolLoopReminder(loop toolCallSignature, threshold int) string {
+	return fmt.Sprintf("You have called %s with the same input %d times and it failed the same way each time:\n\n%s\n\n"+
+		"Repeating the call will not change the result. Do not call it again with this input. Work out why it fails and take a different approach, or explain to the user what is blocking you.",
+		loop.name, threshold, truncateToolResult(loop.err, toolLoopErrorPrefix))
+}
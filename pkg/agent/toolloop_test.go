@@ -0,0 +1,134 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func failingToolConfig(responses ...*mockStream) (*capturingLLMClient, AgentConfig) {
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{
+		name:   "Read",
+		output: tools.ToolOutput{Content: "file does not exist: /tmp/missing.go", IsError: true},
+	})
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: responses}}
+	return client, defaultConfig(client, registry)
+}
+
+func TestLoop_ToolLoopNudge(t *testing.T) {
+	args := map[string]any{"file_path": "/tmp/missing.go"}
+	client, config := failingToolConfig(
+		toolUseResponse("call-1", "Read", args),
+		toolUseResponse("call-2", "Read", args),
+		toolUseResponse("call-3", "Read", args),
+		endTurnResponse("The file doesn't exist."),
+	)
+	config.ToolLoopDetection = &ToolLoopConfig{}
+
+	q := RunLoop(context.Background(), "read it", config)
+	collectMessages(q)
+	q.Wait()
+
+	if q.GetExitReason() != ExitEndTurn {
+		t.Fatalf("exit reason = %q, want end_turn", q.GetExitReason())
+	}
+	reqs := client.getRequests()
+	if len(reqs) != 4 {
+		t.Fatalf("requests = %d, want 4", len(reqs))
+	}
+	for i, req := range reqs {
+		prompt := req.Messages[0].Content.(string)
+		nudged := strings.Contains(prompt, "You have called Read with the same input 3 times")
+		if nudged != (i == 3) {
+			t.Errorf("request %d nudged = %v, want %v", i, nudged, i == 3)
+		}
+		if i == 3 && !strings.Contains(prompt, "file does not exist: /tmp/missing.go") {
+			t.Errorf("reminder does not quote the error:\n%s", prompt)
+		}
+	}
+}
+
+func TestLoop_ToolLoopStop(t *testing.T) {
+	args := map[string]any{"file_path": "/tmp/missing.go"}
+	client, config := failingToolConfig(
+		toolUseResponse("call-1", "Read", args),
+		toolUseResponse("call-2", "Read", args),
+		toolUseResponse("call-3", "Read", args),
+		endTurnResponse("unreachable"),
+	)
+	config.ToolLoopDetection = &ToolLoopConfig{Threshold: 2, Stop: true}
+
+	q := RunLoop(context.Background(), "read it", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if q.GetExitReason() != ExitToolLoop {
+		t.Fatalf("exit reason = %q, want %q", q.GetExitReason(), ExitToolLoop)
+	}
+	if n := len(client.getRequests()); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+	res, ok := msgs[len(msgs)-1].(*types.ResultMessage)
+	if !ok {
+		t.Fatalf("last message = %T, want *types.ResultMessage", msgs[len(msgs)-1])
+	}
+	if res.Subtype != types.ResultSubtypeErrorDuringExecution {
+		t.Errorf("result subtype = %q, want %q", res.Subtype, types.ResultSubtypeErrorDuringExecution)
+	}
+	if len(res.ErrorDetails) != 1 || res.ErrorDetails[0].Code != types.ResultErrorToolError ||
+		!strings.Contains(res.ErrorDetails[0].Message, "Read") {
+		t.Errorf("error details = %+v, want a tool_error naming Read", res.ErrorDetails)
+	}
+}
+
+func TestDetectToolLoop(t *testing.T) {
+	config := &AgentConfig{ToolLoopDetection: &ToolLoopConfig{Threshold: 3, Window: 4}}
+	state := &LoopState{}
+	call := func(id string, input map[string]any, result string) bool {
+		_, found := detectToolLoop(config, state,
+			[]types.ContentBlock{{Type: "tool_use", ID: id, Name: "Bash", Input: input}},
+			[]llm.ToolResult{{ToolUseID: id, Content: result}})
+		return found
+	}
+	failing := map[string]any{"command": "make test"}
+
+	// Successes and different errors don't count as repeats.
+	steps := []struct {
+		input  map[string]any
+		result string
+		want   bool
+	}{
+		{failing, "Error: exit status 2", false},
+		{failing, "ok", false},
+		{failing, "Error: exit status 1", false},
+		{failing, "Error: exit status 2", false},
+		{map[string]any{"command": "make build"}, "Error: exit status 2", false},
+		{failing, "Error: exit status 2", false}, // the first has left the window
+		{failing, "Error: exit status 2", true},
+		// The window restarts after a loop is reported.
+		{failing, "Error: exit status 2", false},
+		{failing, "Error: exit status 2", false},
+		{failing, "Error: exit status 2", true},
+	}
+	for i, s := range steps {
+		if got := call("call", s.input, s.result); got != s.want {
+			t.Fatalf("step %d: loop = %v, want %v", i, got, s.want)
+		}
+	}
+
+	// Calls that fall out of the window are forgotten.
+	state = &LoopState{}
+	call("call", failing, "Error: exit status 2")
+	call("call", failing, "Error: exit status 2")
+	for range 3 {
+		call("call", map[string]any{"command": "ls"}, "ok")
+	}
+	if call("call", failing, "Error: exit status 2") {
+		t.Error("loop reported for repeats outside the window")
+	}
+}
@@ -2,49 +2,153 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
 // executeTools runs each tool_use block and returns tool result messages.
-// If all tools are side-effect-free and there are multiple blocks, they
-// execute concurrently up to MaxParallelTools. Otherwise, serial execution.
+// Consecutive side-effect-free tools execute concurrently up to
+// MaxParallelTools; tools with side effects act as barriers and run serially
+// in their original order. Results are always returned in tool-call order.
+// With DedupToolCalls, repeated calls run once and share the result.
 // If interrupted is true, the caller should stop the loop.
 func executeTools(ctx context.Context, toolBlocks []types.ContentBlock, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) (results []llm.ToolResult, interrupted bool) {
+	ctx, span := StartSpan(ctx, config.TracerProvider, "agent.execute_tools", AttrToolCount.Int(len(toolBlocks)))
+	defer span.End()
+
+	if config.StrictTools {
+		toolBlocks = stripStrictNulls(toolBlocks, config.ToolRegistry)
+	}
+
+	if !config.DedupToolCalls {
+		return executeToolBatches(ctx, toolBlocks, config, state, ch)
+	}
+	unique, firstOf := dedupToolBlocks(toolBlocks)
+	if len(unique) == len(toolBlocks) {
+		return executeToolBatches(ctx, toolBlocks, config, state, ch)
+	}
+	for i, block := range toolBlocks {
+		if orig := unique[firstOf[i]]; orig.ID != block.ID {
+			emitToolDuplicate(ch, block.Name, block.ID, orig.ID, state)
+		}
+	}
+	uniqueResults, interrupted := executeToolBatches(ctx, unique, config, state, ch)
+	results = make([]llm.ToolResult, len(toolBlocks))
+	for i, block := range toolBlocks {
+		results[i] = uniqueResults[firstOf[i]]
+		results[i].ToolUseID = block.ID
+		if orig := unique[firstOf[i]]; orig.ID != block.ID {
+			rec := newToolCallRecord(block)
+			rec.DuplicateOf = orig.ID
+			logToolCall(config, state, ch, rec, results[i])
+		}
+	}
+	return results, interrupted
+}
+
+// stripStrictNulls drops the nulls strict function calling makes the model
+// send for optional arguments, so tools see them as absent. The blocks are
+// copied since their inputs are shared with the emitted assistant message.
+func stripStrictNulls(toolBlocks []types.ContentBlock, registry *tools.Registry) []types.ContentBlock {
+	if registry == nil {
+		return toolBlocks
+	}
+	stripped := slices.Clone(toolBlocks)
+	for i, block := range stripped {
+		if tool, ok := registry.Get(block.Name); ok {
+			stripped[i].Input = llm.StripStrictNulls(block.Input, tool.InputSchema())
+		}
+	}
+	return stripped
+}
+
+// dedupToolBlocks drops tool calls whose name and input repeat an earlier
+// call. firstOf maps each index of toolBlocks to its call's index in unique.
+func dedupToolBlocks(toolBlocks []types.ContentBlock) (unique []types.ContentBlock, firstOf []int) {
+	seen := make(map[string]int, len(toolBlocks))
+	firstOf = make([]int, len(toolBlocks))
+	for i, block := range toolBlocks {
+		input, err := json.Marshal(block.Input) // map keys marshal sorted
+		if err != nil {
+			firstOf[i] = len(unique)
+			unique = append(unique, block)
+			continue
+		}
+		key := block.Name + "\x00" + string(input)
+		if idx, ok := seen[key]; ok {
+			firstOf[i] = idx
+			continue
+		}
+		seen[key] = len(unique)
+		firstOf[i] = len(unique)
+		unique = append(unique, block)
+	}
+	return unique, firstOf
+}
+
+// executeToolBatches splits toolBlocks into parallel-safe runs and barriers
+// and executes them in order.
+func executeToolBatches(ctx context.Context, toolBlocks []types.ContentBlock, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) (results []llm.ToolResult, interrupted bool) {
 	maxConcurrency := 5
 	if config.MaxParallelTools > 0 {
 		maxConcurrency = config.MaxParallelTools
 	}
 
-	if len(toolBlocks) > 1 && canRunParallel(toolBlocks, config.ToolRegistry) {
-		return executeToolsParallel(ctx, toolBlocks, config, state, ch, maxConcurrency)
+	results = make([]llm.ToolResult, 0, len(toolBlocks))
+	for start := 0; start < len(toolBlocks); {
+		end := start + 1
+		if isParallelSafe(toolBlocks[start], config.ToolRegistry) {
+			for end < len(toolBlocks) && isParallelSafe(toolBlocks[end], config.ToolRegistry) {
+				end++
+			}
+		}
+		batch := toolBlocks[start:end]
+
+		var batchResults []llm.ToolResult
+		var batchInterrupted bool
+		if len(batch) > 1 {
+			batchResults, batchInterrupted = executeToolsParallel(ctx, batch, config, state, ch, maxConcurrency)
+		} else {
+			batchResults, batchInterrupted = executeToolsSerial(ctx, batch, config, state, ch)
+		}
+		results = append(results, batchResults...)
+
+		if batchInterrupted {
+			for _, remaining := range toolBlocks[len(results):] {
+				results = append(results, llm.ToolResult{
+					ToolUseID: remaining.ID,
+					Content:   "Error: execution interrupted",
+				})
+			}
+			return results, true
+		}
+		start = end
 	}
-	return executeToolsSerial(ctx, toolBlocks, config, state, ch)
+	return results, false
 }
 
-// canRunParallel returns true if all tool blocks reference side-effect-free tools.
-func canRunParallel(toolBlocks []types.ContentBlock, registry *tools.Registry) bool {
+// isParallelSafe returns true if the block references a known side-effect-free tool.
+func isParallelSafe(block types.ContentBlock, registry *tools.Registry) bool {
 	if registry == nil {
 		return false
 	}
-	for _, block := range toolBlocks {
-		tool, ok := registry.Get(block.Name)
-		if !ok {
-			return false // unknown tool, play safe
-		}
-		if tool.SideEffect() != tools.SideEffectNone {
-			return false
-		}
+	tool, ok := registry.Get(block.Name)
+	if !ok {
+		return false // unknown tool, play safe
 	}
-	return true
+	return tool.SideEffect() == tools.SideEffectNone
 }
 
 // executeToolsSerial runs tools one at a time (the original behavior).
@@ -63,8 +167,10 @@ func executeToolsSerial(ctx context.Context, toolBlocks []types.ContentBlock, co
 		default:
 		}
 
-		result, permInterrupt := executeSingleTool(ctx, block, config, state, ch)
+		rec := newToolCallRecord(block)
+		result, permInterrupt := executeSingleTool(ctx, block, config, state, ch, rec)
 		results = append(results, result)
+		logToolCall(config, state, ch, rec, result)
 
 		if permInterrupt {
 			// Permission interrupt: stop processing remaining tools
@@ -90,6 +196,7 @@ func executeToolsParallel(ctx context.Context, toolBlocks []types.ContentBlock,
 	var wg sync.WaitGroup
 	var contextMu sync.Mutex
 	var allAdditionalContext []string
+	recs := make([]*types.ToolCallRecord, len(toolBlocks))
 
 	for i, block := range toolBlocks {
 		if interrupted.Load() {
@@ -106,7 +213,8 @@ func executeToolsParallel(ctx context.Context, toolBlocks []types.ContentBlock,
 			defer wg.Done()
 			defer func() { <-sem }() // release semaphore
 
-			result, permInterrupt := executeSingleToolParallel(ctx, blk, config, &contextMu, &allAdditionalContext, ch, state)
+			recs[idx] = newToolCallRecord(blk)
+			result, permInterrupt := executeSingleToolParallel(ctx, blk, config, &contextMu, &allAdditionalContext, ch, state, recs[idx])
 			results[idx] = result
 			if permInterrupt {
 				interrupted.Store(true)
@@ -116,6 +224,13 @@ func executeToolsParallel(ctx context.Context, toolBlocks []types.ContentBlock,
 
 	wg.Wait()
 
+	// Log finished calls in call order, not completion order
+	for i, rec := range recs {
+		if rec != nil {
+			logToolCall(config, state, ch, rec, results[i])
+		}
+	}
+
 	// Merge collected context into state
 	state.PendingAdditionalContext = append(state.PendingAdditionalContext, allAdditionalContext...)
 
@@ -137,28 +252,32 @@ func executeToolsParallel(ctx context.Context, toolBlocks []types.ContentBlock,
 // executeSingleToolParallel is a parallel-safe variant of executeSingleTool.
 // It uses a mutex for shared context collection and records file access
 // under a lock to protect the shared LoopState.
-func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, config *AgentConfig, contextMu *sync.Mutex, allAdditionalContext *[]string, ch chan<- types.SDKMessage, state *LoopState) (llm.ToolResult, bool) {
+func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, config *AgentConfig, contextMu *sync.Mutex, allAdditionalContext *[]string, ch chan<- types.SDKMessage, state *LoopState, rec *types.ToolCallRecord) (llm.ToolResult, bool) {
 	toolName := block.Name
 	toolUseID := block.ID
 	input := block.Input
 
 	tool, ok := config.ToolRegistry.Get(toolName)
-	if !ok {
+	if !ok || config.ToolRegistry.IsDisabled(toolName) { // disabled tools are hidden from the model and never run
 		return llm.ToolResult{
 			ToolUseID: toolUseID,
 			Content:   fmt.Sprintf("Error: unknown tool %q", toolName),
 		}, false
 	}
+	if msg := checkToolArguments(config, state, toolName, input); msg != "" {
+		return llm.ToolResult{ToolUseID: toolUseID, Content: msg}, false
+	}
 
 	// Check permissions (apply skill scope if active)
 	checker := effectivePermissionChecker(config.Permissions, state)
-	permResult, err := checker.Check(ctx, toolName, input)
+	permResult, err := checker.Check(withToolCall(ctx, toolUseID, ch), toolName, input)
 	if err != nil {
 		return llm.ToolResult{
 			ToolUseID: toolUseID,
 			Content:   fmt.Sprintf("Error: permission check failed: %s", err),
 		}, false
 	}
+	rec.Permission, rec.PermissionMessage = permResult.Behavior, permResult.Message
 	if permResult.Behavior != "allow" {
 		msg := permResult.Message
 		if msg == "" {
@@ -171,6 +290,7 @@ func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, co
 	}
 	if permResult.UpdatedInput != nil {
 		input = permResult.UpdatedInput
+		rec.InputUpdated = true
 	}
 
 	// Fire PreToolUse hook
@@ -180,6 +300,7 @@ func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, co
 		"tool_input":  input,
 	})
 	if decision, reason := processPreToolUseResults(preResults); decision != "" {
+		rec.PreToolUse, rec.PreToolUseReason = decision, reason
 		if decision == "deny" {
 			msg := reason
 			if msg == "" {
@@ -202,18 +323,23 @@ func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, co
 
 	if updatedInput := getUpdatedInputFromHookResults(preResults); updatedInput != nil {
 		input = updatedInput
+		rec.InputUpdated = true
 	}
 
-	// Emit tool progress (start)
-	emitToolProgress(ch, toolName, toolUseID, 0, state)
-
-	// Execute the tool
-	startTime := time.Now()
-	output, err := tool.Execute(ctx, input)
-	elapsed := time.Since(startTime).Seconds()
+	contextMu.Lock()
+	readErr := checkReadBeforeEdit(config, state, toolName, input)
+	if readErr == "" {
+		checkpointBeforeWrite(config, state, toolName, input)
+	}
+	contextMu.Unlock()
+	if readErr != "" {
+		return llm.ToolResult{ToolUseID: toolUseID, Content: readErr}, false
+	}
 
-	// Emit tool progress (complete)
-	emitToolProgress(ch, toolName, toolUseID, elapsed, state)
+	rec.Input = input
+	start := time.Now()
+	output, err := runTool(ctx, tool, toolUseID, input, config, ch, state)
+	rec.DurationMs = time.Since(start).Milliseconds()
 
 	if err != nil {
 		failResults, _ := config.Hooks.Fire(ctx, types.HookEventPostToolUseFailure, map[string]any{
@@ -237,7 +363,7 @@ func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, co
 
 	// Record file access under lock (shared state)
 	contextMu.Lock()
-	recordToolFileAccess(state, toolName, input)
+	recordToolFileAccess(config, state, toolName, input)
 	contextMu.Unlock()
 
 	// Fire PostToolUse hook
@@ -255,38 +381,50 @@ func executeSingleToolParallel(ctx context.Context, block types.ContentBlock, co
 	contextMu.Unlock()
 
 	content := output.Content
+	if updated, ok := getUpdatedToolOutputFromHookResults(postResults); ok {
+		content = updated
+		rec.OutputUpdated = true
+	}
 	if output.IsError {
 		content = "Error: " + content
 	}
+	images := output.Images
 	if shouldSuppressOutput(postResults) {
 		content = "[output suppressed by hook]"
+		images = nil
+		rec.OutputSuppressed = true
 	}
 
 	return llm.ToolResult{
 		ToolUseID: toolUseID,
 		Content:   content,
+		Images:    images,
+		Metadata:  resultMetadata(output.Metadata),
 	}, false
 }
 
 // executeSingleTool runs one tool and returns its result.
 // permInterrupt is true if the permission check set Interrupt=true.
-func executeSingleTool(ctx context.Context, block types.ContentBlock, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) (llm.ToolResult, bool) {
+func executeSingleTool(ctx context.Context, block types.ContentBlock, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, rec *types.ToolCallRecord) (llm.ToolResult, bool) {
 	toolName := block.Name
 	toolUseID := block.ID
 	input := block.Input
 
 	// Look up tool in registry
 	tool, ok := config.ToolRegistry.Get(toolName)
-	if !ok {
+	if !ok || config.ToolRegistry.IsDisabled(toolName) {
 		return llm.ToolResult{
 			ToolUseID: toolUseID,
 			Content:   fmt.Sprintf("Error: unknown tool %q", toolName),
 		}, false
 	}
+	if msg := checkToolArguments(config, state, toolName, input); msg != "" {
+		return llm.ToolResult{ToolUseID: toolUseID, Content: msg}, false
+	}
 
 	// Check permissions (apply skill scope if active)
 	checker := effectivePermissionChecker(config.Permissions, state)
-	permResult, err := checker.Check(ctx, toolName, input)
+	permResult, err := checker.Check(withToolCall(ctx, toolUseID, ch), toolName, input)
 	if err != nil {
 		return llm.ToolResult{
 			ToolUseID: toolUseID,
@@ -294,6 +432,7 @@ func executeSingleTool(ctx context.Context, block types.ContentBlock, config *Ag
 		}, false
 	}
 
+	rec.Permission, rec.PermissionMessage = permResult.Behavior, permResult.Message
 	if permResult.Behavior != "allow" {
 		msg := permResult.Message
 		if msg == "" {
@@ -308,6 +447,7 @@ func executeSingleTool(ctx context.Context, block types.ContentBlock, config *Ag
 	// Use updated input if permission check modified it
 	if permResult.UpdatedInput != nil {
 		input = permResult.UpdatedInput
+		rec.InputUpdated = true
 	}
 
 	// Fire PreToolUse hook and process results
@@ -317,6 +457,7 @@ func executeSingleTool(ctx context.Context, block types.ContentBlock, config *Ag
 		"tool_input":  input,
 	})
 	if decision, reason := processPreToolUseResults(preResults); decision != "" {
+		rec.PreToolUse, rec.PreToolUseReason = decision, reason
 		if decision == "deny" {
 			msg := reason
 			if msg == "" {
@@ -333,18 +474,20 @@ func executeSingleTool(ctx context.Context, block types.ContentBlock, config *Ag
 	// Check for updated input from hooks
 	if updatedInput := getUpdatedInputFromHookResults(preResults); updatedInput != nil {
 		input = updatedInput
+		rec.InputUpdated = true
 	}
 
-	// Emit tool progress (start)
-	emitToolProgress(ch, toolName, toolUseID, 0, state)
+	if msg := checkReadBeforeEdit(config, state, toolName, input); msg != "" {
+		return llm.ToolResult{ToolUseID: toolUseID, Content: msg}, false
+	}
 
-	// Execute the tool
-	startTime := time.Now()
-	output, err := tool.Execute(ctx, input)
-	elapsed := time.Since(startTime).Seconds()
+	// Snapshot files about to change so the turn can be rewound
+	checkpointBeforeWrite(config, state, toolName, input)
 
-	// Emit tool progress (complete)
-	emitToolProgress(ch, toolName, toolUseID, elapsed, state)
+	rec.Input = input
+	start := time.Now()
+	output, err := runTool(ctx, tool, toolUseID, input, config, ch, state)
+	rec.DurationMs = time.Since(start).Milliseconds()
 
 	if err != nil {
 		// Fire PostToolUseFailure hook and collect context
@@ -362,7 +505,7 @@ func executeSingleTool(ctx context.Context, block types.ContentBlock, config *Ag
 	}
 
 	// Record file access for tracking
-	recordToolFileAccess(state, toolName, input)
+	recordToolFileAccess(config, state, toolName, input)
 
 	// Fire PostToolUse hook and collect context
 	postResults, _ := config.Hooks.Fire(ctx, types.HookEventPostToolUse, map[string]any{
@@ -373,21 +516,148 @@ func executeSingleTool(ctx context.Context, block types.ContentBlock, config *Ag
 	collectAdditionalContext(state, postResults)
 
 	content := output.Content
+	// Check for rewritten output from hooks
+	if updated, ok := getUpdatedToolOutputFromHookResults(postResults); ok {
+		content = updated
+		rec.OutputUpdated = true
+	}
 	if output.IsError {
 		content = "Error: " + content
 	}
 
 	// Check suppress output from hooks
+	images := output.Images
 	if shouldSuppressOutput(postResults) {
 		content = "[output suppressed by hook]"
+		images = nil
+		rec.OutputSuppressed = true
 	}
 
 	return llm.ToolResult{
 		ToolUseID: toolUseID,
 		Content:   content,
+		Images:    images,
+		Metadata:  resultMetadata(output.Metadata),
 	}, false
 }
 
+// runTool executes a tool under its configured timeout, emitting start and
+// completion progress with heartbeats in between. A timed-out tool has its
+// context cancelled and yields an error output so the model can react
+// instead of the loop hanging.
+func runTool(ctx context.Context, tool tools.Tool, toolUseID string, input map[string]any, config *AgentConfig, ch chan<- types.SDKMessage, state *LoopState) (output tools.ToolOutput, err error) {
+	toolName := tool.Name()
+	emitToolProgress(ch, toolName, toolUseID, 0, state)
+
+	ctx = withToolCall(ctx, toolUseID, ch)
+	ctx, span := StartSpan(ctx, config.TracerProvider, "agent.tool", AttrToolName.String(toolName), AttrModel.String(state.turnModel))
+	defer func() {
+		span.SetAttributes(AttrToolIsError.Bool(err != nil || output.IsError))
+		endSpan(span, err)
+	}()
+
+	if config.Metrics != nil {
+		start := time.Now()
+		defer func() {
+			config.Metrics.RecordToolCall(toolName, state.turnModel, time.Since(start), err != nil || output.IsError, state.toolCostShare)
+		}()
+	}
+
+	startTime := time.Now()
+	heartbeat := startToolHeartbeat(ch, toolName, toolUseID, startTime, toolHeartbeatInterval(config), state)
+	defer heartbeat.stop()
+	execute := tool.Execute
+	if reporter, ok := tool.(tools.ProgressReporter); ok {
+		execute = func(ctx context.Context, input map[string]any) (tools.ToolOutput, error) {
+			return reporter.ExecuteWithProgress(ctx, input, heartbeat.report)
+		}
+	}
+	if config.ToolRegistry != nil {
+		execute = config.ToolRegistry.Wrap(tool, execute)
+	}
+
+	timeout := toolTimeout(config, toolName)
+	if timeout <= 0 {
+		output, err := execute(ctx, input)
+		heartbeat.stop()
+		emitToolProgress(ch, toolName, toolUseID, time.Since(startTime).Seconds(), state)
+		return output, err
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type execResult struct {
+		output tools.ToolOutput
+		err    error
+	}
+	done := make(chan execResult, 1)
+	go func() {
+		output, err := execute(execCtx, input)
+		done <- execResult{output, err}
+	}()
+
+	select {
+	case r := <-done:
+		heartbeat.stop()
+		emitToolProgress(ch, toolName, toolUseID, time.Since(startTime).Seconds(), state)
+		return r.output, r.err
+	case <-execCtx.Done():
+		heartbeat.stop()
+		if ctx.Err() != nil {
+			// Parent cancellation (interrupt/abort), not a timeout
+			emitToolProgress(ch, toolName, toolUseID, time.Since(startTime).Seconds(), state)
+			return tools.ToolOutput{}, ctx.Err()
+		}
+		emitToolTimeout(ch, toolName, toolUseID, time.Since(startTime).Seconds(), state)
+		return tools.ToolOutput{
+			Content: fmt.Sprintf("tool %s timed out after %s", toolName, timeout),
+			IsError: true,
+		}, nil
+	}
+}
+
+// ToolCallInfo describes the tool call a tool is executing for.
+type ToolCallInfo struct {
+	ToolUseID string
+	EmitCh    chan<- types.SDKMessage // the calling loop's message stream, open until the tool returns
+}
+
+type toolCallKey struct{}
+
+// ToolCallFromContext returns the tool call the loop attached to a tool's
+// execution context, letting a tool such as Agent emit messages on the
+// caller's stream tagged with the tool use ID.
+func ToolCallFromContext(ctx context.Context) (ToolCallInfo, bool) {
+	info, ok := ctx.Value(toolCallKey{}).(ToolCallInfo)
+	return info, ok
+}
+
+// withToolCall attaches the tool call to ctx for the permission check and the tool.
+func withToolCall(ctx context.Context, toolUseID string, ch chan<- types.SDKMessage) context.Context {
+	return context.WithValue(ctx, toolCallKey{}, ToolCallInfo{ToolUseID: toolUseID, EmitCh: ch})
+}
+
+// resultMetadata maps tool output bookkeeping onto the tool result.
+func resultMetadata(meta *tools.OutputMetadata) *llm.ToolResultMetadata {
+	if meta == nil {
+		return nil
+	}
+	return &llm.ToolResultMetadata{
+		WasTruncated: meta.Truncated,
+		OriginalLen:  meta.OriginalBytes,
+		FromCache:    meta.FromCache,
+	}
+}
+
+// toolTimeout returns the timeout for the named tool (0 = none).
+func toolTimeout(config *AgentConfig, toolName string) time.Duration {
+	if d, ok := config.ToolTimeouts[toolName]; ok {
+		return d
+	}
+	return config.DefaultToolTimeout
+}
+
 // processPreToolUseResults checks hook results for permission decisions.
 // Returns ("deny", reason) if denied, ("allow", "") if allowed, ("", "") if no decision.
 func processPreToolUseResults(results []HookResult) (string, string) {
@@ -423,6 +693,45 @@ func getUpdatedInputFromHookResults(results []HookResult) map[string]any {
 	return nil
 }
 
+// getUpdatedToolOutputFromHookResults checks for updatedToolOutput from
+// PostToolUse hook-specific output, which replaces the tool's content before
+// the model sees it. Non-string values are sent as JSON.
+func getUpdatedToolOutputFromHookResults(results []HookResult) (string, bool) {
+	for _, r := range results {
+		var updated any
+		switch o := r.HookSpecificOutput.(type) {
+		case nil:
+			continue
+		case map[string]any:
+			updated = o["updatedToolOutput"]
+		default:
+			data, err := json.Marshal(o)
+			if err != nil {
+				continue
+			}
+			var specific struct {
+				UpdatedToolOutput any `json:"updatedToolOutput"`
+			}
+			json.Unmarshal(data, &specific)
+			updated = specific.UpdatedToolOutput
+		}
+
+		switch v := updated.(type) {
+		case nil:
+			continue
+		case string:
+			return v, true
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
 // shouldSuppressOutput checks if any hook result requests output suppression.
 func shouldSuppressOutput(results []HookResult) bool {
 	for _, r := range results {
@@ -438,6 +747,7 @@ func shouldSuppressOutput(results []HookResult) bool {
 func effectivePermissionChecker(base PermissionChecker, state *LoopState) PermissionChecker {
 	if state.ActiveSkill != nil && len(state.ActiveSkill.AllowedTools) > 0 {
 		return &skillPermissionWrapper{
+			skillName:    state.ActiveSkill.SkillName,
 			allowedTools: state.ActiveSkill.AllowedTools,
 			inner:        base,
 		}
@@ -446,8 +756,11 @@ func effectivePermissionChecker(base PermissionChecker, state *LoopState) Permis
 }
 
 // skillPermissionWrapper wraps a PermissionChecker to auto-allow tools
-// that match a skill's allowed-tools patterns.
+// that match a skill's allowed-tools patterns. Tools whose name matches no
+// pattern are denied outright; a name match whose constraint fails (e.g. a
+// Bash command outside "Bash(gh:*)") falls back to the inner checker.
 type skillPermissionWrapper struct {
+	skillName    string
 	allowedTools []string
 	inner        PermissionChecker
 }
@@ -458,9 +771,42 @@ func (w *skillPermissionWrapper) Check(ctx context.Context, toolName string, inp
 			return PermissionResult{Behavior: "allow"}, nil
 		}
 	}
+	if !skillScopeIncludes(w.allowedTools, toolName) {
+		return PermissionResult{
+			Behavior: "deny",
+			Message: fmt.Sprintf("%s is not available while skill %q is active (allowed tools: %s)",
+				toolName, w.skillName, strings.Join(w.allowedTools, ", ")),
+		}, nil
+	}
 	return w.inner.Check(ctx, toolName, input)
 }
 
+// skillScopeIncludes reports whether any allowed-tools pattern names toolName,
+// ignoring argument constraints.
+func skillScopeIncludes(allowedTools []string, toolName string) bool {
+	for _, pattern := range allowedTools {
+		if name, _, _ := strings.Cut(pattern, "("); matchSkillName(name, toolName) {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeLLMTools narrows the tools offered to the model to those an active
+// skill's allowed-tools names. Without a scope the list is returned as is.
+func scopeLLMTools(llmTools []llm.Tool, scope *SkillScope) []llm.Tool {
+	if scope == nil || len(scope.AllowedTools) == 0 {
+		return llmTools
+	}
+	scoped := make([]llm.Tool, 0, len(llmTools))
+	for _, t := range llmTools {
+		if skillScopeIncludes(scope.AllowedTools, t.ToolName()) {
+			scoped = append(scoped, t)
+		}
+	}
+	return scoped
+}
+
 // matchSkillToolPattern matches a single allowed-tools pattern against a tool name and input.
 // Supports exact names ("Bash"), globs ("mcp__*"), and constrained patterns ("Bash(gh:*)").
 func matchSkillToolPattern(pattern, toolName string, input map[string]any) bool {
@@ -539,7 +885,7 @@ func setActiveSkillScope(toolBlocks []types.ContentBlock, config *AgentConfig, s
 
 // recordToolFileAccess extracts file paths from tool input and records them in state.
 // It also updates config.ActiveFilePaths for conditional rules injection.
-func recordToolFileAccess(state *LoopState, toolName string, input map[string]any) {
+func recordToolFileAccess(config *AgentConfig, state *LoopState, toolName string, input map[string]any) {
 	opMap := map[string]string{
 		"Read":         "read",
 		"Write":        "write",
@@ -548,6 +894,7 @@ func recordToolFileAccess(state *LoopState, toolName string, input map[string]an
 		"Grep":         "grep",
 		"Bash":         "exec",
 		"NotebookEdit": "edit",
+		"ApplyPatch":   "edit",
 	}
 	op, tracked := opMap[toolName]
 	if !tracked {
@@ -562,6 +909,89 @@ func recordToolFileAccess(state *LoopState, toolName string, input map[string]an
 	if path, ok := input["path"].(string); ok && path != "" {
 		state.RecordFileAccess(path, op)
 	}
+	if patch, ok := input["patch"].(string); ok && patch != "" {
+		for _, path := range tools.PatchFilePaths(patch, config.CWD) {
+			state.RecordFileAccess(path, op)
+		}
+	}
+}
+
+// checkToolArguments rejects a call whose arguments were not valid JSON and
+// could not be repaired, showing the model what it sent so it can retry.
+func checkToolArguments(config *AgentConfig, state *LoopState, toolName string, input map[string]any) string {
+	raw, ok := rawArguments(input)
+	if !ok || !repairToolCallsEnabled(config, state.turnModel) {
+		return ""
+	}
+	return fmt.Sprintf("Error: the arguments for %s are not valid JSON and could not be repaired:\n%s\n"+
+		"Call %s again with its arguments as a single valid JSON object.", toolName, truncateToolResult(raw, 2000), toolName)
+}
+
+// checkReadBeforeEdit enforces RequireReadBeforeEdit, returning the error
+// result content when an Edit or Write targets an existing file the session
+// has not read, written or edited yet.
+func checkReadBeforeEdit(config *AgentConfig, state *LoopState, toolName string, input map[string]any) string {
+	if !config.RequireReadBeforeEdit || (toolName != "Edit" && toolName != "Write") {
+		return ""
+	}
+	path, _ := input["file_path"].(string)
+	if path == "" {
+		return ""
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "" // new file (Write) or a missing one the tool will report (Edit)
+	}
+	if state.fileKnown(path) {
+		return ""
+	}
+	return fmt.Sprintf("Error: %s has not been read in this session. "+
+		"Use the Read tool to read the file first, then retry the %s based on its current contents.", path, toolName)
+}
+
+// checkpointBeforeWrite snapshots the files a mutating tool is about to change
+// into the current turn's checkpoint, once per file per turn.
+func checkpointBeforeWrite(config *AgentConfig, state *LoopState, toolName string, input map[string]any) {
+	if config.SessionStore == nil || state.CheckpointID == "" {
+		return
+	}
+	var paths []string
+	switch toolName {
+	case "Write", "Edit":
+		if path, ok := input["file_path"].(string); ok && path != "" {
+			paths = append(paths, path)
+		}
+	case "NotebookEdit":
+		if path, ok := input["notebook_path"].(string); ok && path != "" {
+			paths = append(paths, path)
+		}
+	case "ApplyPatch":
+		if patch, ok := input["patch"].(string); ok && patch != "" {
+			paths = tools.PatchFilePaths(patch, config.CWD)
+		}
+	}
+
+	var pending []string
+	for _, path := range paths {
+		if !filepath.IsAbs(path) && config.CWD != "" {
+			path = filepath.Join(config.CWD, path)
+		}
+		if !state.checkpointed[path] {
+			pending = append(pending, path)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	// Best-effort: a failed snapshot must not block the tool
+	if err := config.SessionStore.CreateCheckpoint(state.SessionID, state.CheckpointID, pending); err != nil {
+		return
+	}
+	if state.checkpointed == nil {
+		state.checkpointed = make(map[string]bool)
+	}
+	for _, path := range pending {
+		state.checkpointed[path] = true
+	}
 }
 
 // syncActiveFilePaths updates config.ActiveFilePaths from state.AccessedFiles.
@@ -575,3 +1005,44 @@ func syncActiveFilePaths(config *AgentConfig, state *LoopState) {
 	}
 	config.ActiveFilePaths = paths
 }
+
+// injectConditionalRules queues the text of each ConditionalRule whose pattern
+// matches an accessed file as additional context for the next LLM call.
+// A rule that has fired is not injected again for the rest of the session.
+func injectConditionalRules(config *AgentConfig, state *LoopState) {
+	if len(config.ConditionalRules) == 0 || len(state.AccessedFiles) == 0 {
+		return
+	}
+	for i, rule := range config.ConditionalRules {
+		if state.firedRules[i] || rule.Text == "" {
+			continue
+		}
+		for path := range state.AccessedFiles {
+			if !matchConditionalRule(rule.Pattern, path, config.CWD) {
+				continue
+			}
+			if state.firedRules == nil {
+				state.firedRules = make(map[int]bool)
+			}
+			state.firedRules[i] = true
+			state.PendingAdditionalContext = append(state.PendingAdditionalContext, rule.Text)
+			break
+		}
+	}
+}
+
+// matchConditionalRule matches pattern against path relative to cwd, or
+// against the base name when the pattern has no directory part.
+func matchConditionalRule(pattern, path, cwd string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := doublestar.Match(pattern, filepath.Base(path))
+		return ok
+	}
+	if cwd != "" {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	ok, _ := doublestar.Match(pattern, filepath.ToSlash(path))
+	return ok
+}
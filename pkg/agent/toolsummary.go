@@ -0,0 +1,181 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// ToolUseSummaryConfig enables a one-line ToolUseSummaryMessage after each
+// burst of tool calls, for UIs that show it in place of raw tool output.
+type ToolUseSummaryConfig struct {
+	// Model writes the summary; it should be cheap. Empty = heuristic only.
+	Model string
+	// MinToolCalls is the smallest burst that gets a summary (default 2).
+	MinToolCalls int
+	// MaxCostFraction caps the summary's estimated cost as a fraction of the
+	// turn that requested the tools (default 0.05). Over the cap, or when
+	// either model's pricing is unknown, the heuristic summary is used.
+	MaxCostFraction float64
+}
+
+const (
+	toolSummaryMaxTokens    = 60
+	toolSummaryTimeout      = 10 * time.Second
+	toolSummaryResultPrefix = 200 // bytes of each tool input and result shown to the model
+)
+
+const toolSummaryPrompt = `Summarize what these tool calls did and found in one short line (at most 12 words), like "ran tests, 3 failed" or "read config files, found the port setting". Reply with the line only.`
+
+// emitToolUseSummary sends a ToolUseSummaryMessage for the tool calls of one
+// turn. The model's summary is used when it fits the cost cap and succeeds;
+// otherwise a heuristic one ("Bash ×2 (1 failed), Read") is emitted.
+func emitToolUseSummary(ctx context.Context, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, q *Query,
+	resp *llm.CompletionResponse, toolBlocks []types.ContentBlock, results []llm.ToolResult) {
+	cfg := config.ToolUseSummary
+	minCalls := cfg.MinToolCalls
+	if minCalls <= 0 {
+		minCalls = 2
+	}
+	if len(toolBlocks) < minCalls {
+		return
+	}
+
+	summary := ""
+	if cfg.Model != "" && config.LLMClient != nil {
+		prompt := toolSummaryRequestPrompt(toolBlocks, results)
+		if toolSummaryAffordable(cfg, resp, prompt) {
+			var usage types.BetaUsage
+			summary, usage = modelToolSummary(ctx, config.LLMClient, cfg.Model, prompt)
+			if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+				q.mu.Lock()
+				state.addUsage(usage)
+				if config.CostTracker != nil {
+					state.TotalCostUSD = config.CostTracker.Add(cfg.Model, usage)
+				} else {
+					state.TotalCostUSD += llm.CalculateCost(cfg.Model, usage)
+				}
+				q.mu.Unlock()
+			}
+		}
+	}
+	if summary == "" {
+		summary = heuristicToolSummary(toolBlocks, results)
+	}
+
+	ids := make([]string, len(toolBlocks))
+	for i, block := range toolBlocks {
+		ids[i] = block.ID
+	}
+	ch <- &types.ToolUseSummaryMessage{
+		BaseMessage:         types.BaseMessage{UUID: uuid.New(), SessionID: state.SessionID},
+		Type:                types.MessageTypeToolUseSummary,
+		Summary:             summary,
+		PrecedingToolUseIDs: ids,
+	}
+}
+
+// toolSummaryAffordable reports whether a summary call with prompt stays
+// within MaxCostFraction of the turn's cost.
+func toolSummaryAffordable(cfg *ToolUseSummaryConfig, resp *llm.CompletionResponse, prompt string) bool {
+	if _, ok := llm.GetPricing(cfg.Model); !ok {
+		return false
+	}
+	if _, ok := llm.GetPricing(resp.Model); !ok {
+		return false
+	}
+	fraction := cfg.MaxCostFraction
+	if fraction <= 0 {
+		fraction = 0.05
+	}
+	estimate := llm.CalculateCost(cfg.Model, types.BetaUsage{
+		InputTokens:  len(prompt)/4 + 16,
+		OutputTokens: toolSummaryMaxTokens,
+	})
+	return estimate <= fraction*llm.CalculateCost(resp.Model, resp.Usage)
+}
+
+// modelToolSummary asks model for the one-line summary, returning "" on any
+// failure along with whatever usage was reported.
+func modelToolSummary(ctx context.Context, client llm.Client, model, prompt string) (string, types.BetaUsage) {
+	ctx, cancel := context.WithTimeout(ctx, toolSummaryTimeout)
+	defer cancel()
+
+	stream, err := client.Complete(ctx, &llm.CompletionRequest{
+		Model:     model,
+		Stream:    true,
+		MaxTokens: toolSummaryMaxTokens,
+		Messages:  []llm.ChatMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", types.BetaUsage{}
+	}
+	resp, err := stream.Accumulate()
+	if err != nil {
+		return "", types.BetaUsage{}
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(text.String()), "\n")
+	return strings.Trim(strings.TrimSpace(line), `"`), resp.Usage
+}
+
+// toolSummaryRequestPrompt lists each call with the start of its result.
+func toolSummaryRequestPrompt(toolBlocks []types.ContentBlock, results []llm.ToolResult) string {
+	var b strings.Builder
+	b.WriteString(toolSummaryPrompt)
+	b.WriteString("\n")
+	for i, block := range toolBlocks {
+		input, _ := json.Marshal(block.Input)
+		fmt.Fprintf(&b, "\n- %s %s", block.Name, truncateToolResult(string(input), toolSummaryResultPrefix))
+		if i < len(results) {
+			fmt.Fprintf(&b, "\n  → %s", truncateToolResult(results[i].Content, toolSummaryResultPrefix))
+		}
+	}
+	return b.String()
+}
+
+// heuristicToolSummary counts calls per tool in order of first use, noting
+// failures, e.g. "Bash ×2 (1 failed), Read".
+func heuristicToolSummary(toolBlocks []types.ContentBlock, results []llm.ToolResult) string {
+	type toolCount struct {
+		name          string
+		calls, failed int
+	}
+	var counts []*toolCount
+	byName := map[string]*toolCount{}
+	for i, block := range toolBlocks {
+		c, ok := byName[block.Name]
+		if !ok {
+			c = &toolCount{name: block.Name}
+			byName[block.Name] = c
+			counts = append(counts, c)
+		}
+		c.calls++
+		if i < len(results) && strings.HasPrefix(results[i].Content, "Error:") {
+			c.failed++
+		}
+	}
+
+	parts := make([]string, len(counts))
+	for i, c := range counts {
+		parts[i] = c.name
+		if c.calls > 1 {
+			parts[i] += fmt.Sprintf(" ×%d", c.calls)
+		}
+		if c.failed > 0 {
+			parts[i] += fmt.Sprintf(" (%d failed)", c.failed)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
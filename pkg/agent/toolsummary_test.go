@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// twoToolResponse requests Bash and Grep in one turn.
+func twoToolResponse() *mockStream {
+	toolCalls := "tool_calls"
+	return &mockStream{
+		chunks: []llm.StreamChunk{
+			textChunk("msg-1", "claude-sonnet-4-5-20250929", "Let me check."),
+			{
+				ID:    "msg-1",
+				Model: "claude-sonnet-4-5-20250929",
+				Choices: []llm.Choice{{
+					Delta: llm.Delta{
+						ToolCalls: []llm.ToolCall{
+							{Index: 0, ID: "call_1", Type: "function", Function: llm.FunctionCall{Name: "Bash", Arguments: `{"command":"ls"}`}},
+							{Index: 1, ID: "call_2", Type: "function", Function: llm.FunctionCall{Name: "Grep", Arguments: `{"pattern":"foo"}`}},
+						},
+					},
+				}},
+			},
+			{
+				ID:      "msg-1",
+				Model:   "claude-sonnet-4-5-20250929",
+				Choices: []llm.Choice{{FinishReason: &toolCalls}},
+				Usage:   &llm.Usage{PromptTokens: 300, CompletionTokens: 100, TotalTokens: 400},
+			},
+		},
+	}
+}
+
+func toolSummaryRegistry() *tools.Registry {
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "main.go"}})
+	registry.Register(&mockRecordingTool{name: "Grep", output: tools.ToolOutput{Content: "main.go:3: foo"}})
+	return registry
+}
+
+func toolUseSummaries(msgs []types.SDKMessage) []*types.ToolUseSummaryMessage {
+	var summaries []*types.ToolUseSummaryMessage
+	for _, msg := range msgs {
+		if s, ok := msg.(*types.ToolUseSummaryMessage); ok {
+			summaries = append(summaries, s)
+		}
+	}
+	return summaries
+}
+
+func TestLoop_ToolUseSummary_Model(t *testing.T) {
+	client := &capturingLLMClient{inner: &mockLLMClient{
+		responses: []*mockStream{
+			twoToolResponse(),
+			endTurnResponse("listed files, found foo in main.go\nextra line"),
+			endTurnResponse("Done."),
+		},
+	}}
+	config := defaultConfig(client, toolSummaryRegistry())
+	config.ToolUseSummary = &ToolUseSummaryConfig{Model: "claude-haiku-4-5-20251001", MaxCostFraction: 0.5}
+
+	q := RunLoop(context.Background(), "Find foo", config)
+	summaries := toolUseSummaries(collectMessages(q))
+	q.Wait()
+
+	if len(summaries) != 1 {
+		t.Fatalf("got %d tool use summaries, want 1", len(summaries))
+	}
+	if summaries[0].Summary != "listed files, found foo in main.go" {
+		t.Errorf("Summary = %q", summaries[0].Summary)
+	}
+	if !slices.Equal(summaries[0].PrecedingToolUseIDs, []string{"call_1", "call_2"}) {
+		t.Errorf("PrecedingToolUseIDs = %v", summaries[0].PrecedingToolUseIDs)
+	}
+
+	reqs := client.getRequests()
+	if len(reqs) != 3 {
+		t.Fatalf("got %d requests, want 3", len(reqs))
+	}
+	if reqs[1].Model != "claude-haiku-4-5-20251001" || reqs[1].MaxTokens != toolSummaryMaxTokens || len(reqs[1].Tools) != 0 {
+		t.Errorf("summary request = model %q, max_tokens %d, %d tools", reqs[1].Model, reqs[1].MaxTokens, len(reqs[1].Tools))
+	}
+	// The summary call is billed to the session.
+	if got := q.TotalUsage().InputTokens; got != 300+100+100 {
+		t.Errorf("TotalUsage.InputTokens = %d, want 500", got)
+	}
+}
+
+func TestLoop_ToolUseSummary_OverCostCap(t *testing.T) {
+	client := &capturingLLMClient{inner: &mockLLMClient{
+		responses: []*mockStream{twoToolResponse(), endTurnResponse("Done.")},
+	}}
+	config := defaultConfig(client, toolSummaryRegistry())
+	// The default 5% cap is below a haiku call for a 400-token sonnet turn.
+	config.ToolUseSummary = &ToolUseSummaryConfig{Model: "claude-haiku-4-5-20251001"}
+
+	q := RunLoop(context.Background(), "Find foo", config)
+	summaries := toolUseSummaries(collectMessages(q))
+	q.Wait()
+
+	if len(summaries) != 1 || summaries[0].Summary != "Bash, Grep" {
+		t.Fatalf("summaries = %+v, want the heuristic summary", summaries)
+	}
+	if n := len(client.getRequests()); n != 2 {
+		t.Errorf("got %d requests, want no summary call", n)
+	}
+}
+
+func TestLoop_ToolUseSummary_BelowMinToolCalls(t *testing.T) {
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: "ok"}})
+	client := &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call_1", "Bash", map[string]any{"command": "ls"}),
+		endTurnResponse("Done."),
+	}}
+	config := defaultConfig(client, registry)
+	config.ToolUseSummary = &ToolUseSummaryConfig{}
+
+	q := RunLoop(context.Background(), "List files", config)
+	summaries := toolUseSummaries(collectMessages(q))
+	q.Wait()
+
+	if len(summaries) != 0 {
+		t.Errorf("got %d summaries for a single tool call, want 0", len(summaries))
+	}
+}
+
+func TestHeuristicToolSummary(t *testing.T) {
+	blocks := []types.ContentBlock{
+		{Type: "tool_use", ID: "1", Name: "Bash"},
+		{Type: "tool_use", ID: "2", Name: "Read"},
+		{Type: "tool_use", ID: "3", Name: "Bash"},
+	}
+	results := []llm.ToolResult{
+		{ToolUseID: "1", Content: "ok"},
+		{ToolUseID: "2", Content: "package main"},
+		{ToolUseID: "3", Content: "Error: exit status 1"},
+	}
+	if got, want := heuristicToolSummary(blocks, results), "Bash ×2 (1 failed), Read"; got != want {
+		t.Errorf("heuristicToolSummary = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName identifies spans created by goat.
+const tracerName = "github.com/jg-phare/goat"
+
+// Span attribute keys.
+const (
+	AttrSessionID    = attribute.Key("goat.session_id")
+	AttrModel        = attribute.Key("goat.model")
+	AttrAgentType    = attribute.Key("goat.agent_type")
+	AttrToolName     = attribute.Key("goat.tool.name")
+	AttrToolCount    = attribute.Key("goat.tool.count")
+	AttrToolIsError  = attribute.Key("goat.tool.is_error")
+	AttrInputTokens  = attribute.Key("goat.usage.input_tokens")
+	AttrOutputTokens = attribute.Key("goat.usage.output_tokens")
+	AttrStopReason   = attribute.Key("goat.stop_reason")
+	AttrExitReason   = attribute.Key("goat.exit_reason")
+	AttrTurns        = attribute.Key("goat.turns")
+	AttrCostUSD      = attribute.Key("goat.cost_usd")
+)
+
+// StartSpan starts a span named name as a child of any span in ctx. With a
+// nil provider it returns ctx unchanged and a no-op span, so tracing costs
+// nothing unless configured.
+func StartSpan(ctx context.Context, provider trace.TracerProvider, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if provider == nil {
+		return ctx, noop.Span{}
+	}
+	return provider.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span, if any, and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestTracer() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func spanAttr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestStartSpan_NilProviderIsNoop(t *testing.T) {
+	ctx := context.Background()
+	got, span := StartSpan(ctx, nil, "agent.loop")
+	if got != ctx {
+		t.Error("context should be returned unchanged without a provider")
+	}
+	if span.IsRecording() || span.SpanContext().IsValid() {
+		t.Error("span should be a no-op without a provider")
+	}
+	span.End()
+}
+
+func TestLoop_Tracing(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "TestTool", map[string]any{"input": "x"}),
+			endTurnResponse("Done"),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "TestTool", output: tools.ToolOutput{Content: "ok"}})
+
+	tp, exporter := newTestTracer()
+	parentCtx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+
+	config := defaultConfig(client, registry)
+	config.TracerProvider = tp
+
+	q := RunLoop(parentCtx, "Hello", config)
+	collectMessages(q)
+	q.Wait()
+	parent.End()
+
+	byName := map[string][]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	if len(byName["agent.loop"]) != 1 || len(byName["llm.complete"]) != 2 ||
+		len(byName["agent.execute_tools"]) != 1 || len(byName["agent.tool"]) != 1 {
+		t.Fatalf("unexpected spans: %v", byName)
+	}
+
+	loop := byName["agent.loop"][0]
+	if loop.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("loop span should nest under the caller's span")
+	}
+	if got := spanAttr(loop, AttrExitReason).AsString(); got != "end_turn" {
+		t.Errorf("exit reason = %q", got)
+	}
+	if got := spanAttr(loop, AttrTurns).AsInt64(); got != 2 {
+		t.Errorf("turns = %d, want 2", got)
+	}
+	if got := spanAttr(loop, AttrInputTokens).AsInt64(); got != 300 {
+		t.Errorf("input tokens = %d, want 300", got)
+	}
+
+	first := byName["llm.complete"][0]
+	if first.Parent.SpanID() != loop.SpanContext.SpanID() {
+		t.Error("llm span should nest under the loop span")
+	}
+	if got := spanAttr(first, AttrOutputTokens).AsInt64(); got != 80 {
+		t.Errorf("output tokens = %d, want 80", got)
+	}
+	if got := spanAttr(first, AttrStopReason).AsString(); got != "tool_use" {
+		t.Errorf("stop reason = %q", got)
+	}
+
+	exec := byName["agent.execute_tools"][0]
+	tool := byName["agent.tool"][0]
+	if exec.Parent.SpanID() != loop.SpanContext.SpanID() || tool.Parent.SpanID() != exec.SpanContext.SpanID() {
+		t.Error("tool span should nest under execute_tools, under the loop")
+	}
+	if got := spanAttr(tool, AttrToolName).AsString(); got != "TestTool" {
+		t.Errorf("tool name = %q", got)
+	}
+	if spanAttr(tool, AttrModel).AsString() == "" || spanAttr(tool, AttrToolIsError).AsBool() {
+		t.Errorf("tool attributes = %v", tool.Attributes)
+	}
+}
+
+// spanCtxTool records the span active in the context it is executed with.
+type spanCtxTool struct {
+	mockRecordingTool
+	span trace.SpanContext
+}
+
+func (s *spanCtxTool) Execute(ctx context.Context, input map[string]any) (tools.ToolOutput, error) {
+	s.span = trace.SpanContextFromContext(ctx)
+	return s.mockRecordingTool.Execute(ctx, input)
+}
+
+func TestLoop_TracingPropagatesToolContext(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "TestTool", map[string]any{}),
+			endTurnResponse("Done"),
+		},
+	}
+	tool := &spanCtxTool{mockRecordingTool: mockRecordingTool{name: "TestTool", output: tools.ToolOutput{Content: "ok"}}}
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+
+	tp, exporter := newTestTracer()
+	config := defaultConfig(client, registry)
+	config.TracerProvider = tp
+
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+
+	for _, s := range exporter.GetSpans() {
+		if s.Name == "agent.tool" {
+			if tool.span.SpanID() != s.SpanContext.SpanID() {
+				t.Error("tool should execute with the tool span in its context")
+			}
+			return
+		}
+	}
+	t.Fatal("no agent.tool span recorded")
+}
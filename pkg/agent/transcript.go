@@ -0,0 +1,118 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// errTranscriptDisabled is returned by ExportTranscript when the query was
+// not started with AgentConfig.RecordTranscript.
+var errTranscriptDisabled = errors.New("transcript not recorded: set AgentConfig.RecordTranscript")
+
+// recordTranscript forwards loop messages to the Messages channel and, with
+// AgentConfig.RecordTranscript, keeps a copy of each for ExportTranscript. A
+// message is recorded before it is delivered, so a caller that has drained
+// Messages can export all of them. Secrets are redacted first, so neither
+// sees them.
+func (q *Query) recordTranscript(in <-chan types.SDKMessage, out chan<- types.SDKMessage) {
+	defer close(out)
+	for msg := range in {
+		if q.redactor != nil {
+			msg = redactSDKMessage(q.redactor, msg)
+		}
+		if q.keepTranscript {
+			q.transcriptMu.Lock()
+			q.transcript = append(q.transcript, msg)
+			q.transcriptMu.Unlock()
+		}
+		out <- msg
+	}
+}
+
+// ExportTranscript writes the messages emitted so far to w as JSONL, one
+// SDKMessage per line in emission order. The output can be read back with
+// LoadTranscript. The query must have been started with
+// AgentConfig.RecordTranscript.
+func (q *Query) ExportTranscript(w io.Writer) error {
+	if !q.keepTranscript {
+		return errTranscriptDisabled
+	}
+	q.transcriptMu.Lock()
+	msgs := append([]types.SDKMessage(nil), q.transcript...)
+	q.transcriptMu.Unlock()
+
+	enc := json.NewEncoder(w)
+	for i, msg := range msgs {
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("encode message %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// LoadTranscript reads a JSONL transcript written by ExportTranscript.
+// Blank lines are skipped; any malformed or unknown message is an error.
+func LoadTranscript(r io.Reader) ([]types.SDKMessage, error) {
+	dec := json.NewDecoder(r)
+	var msgs []types.SDKMessage
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return msgs, nil
+			}
+			return nil, fmt.Errorf("read message %d: %w", len(msgs)+1, err)
+		}
+		msg, err := types.UnmarshalSDKMessage(raw)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", len(msgs)+1, err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+// replaySession re-emits config.ReplaySession without calling the LLM. The
+// replayed result message, if any, sets the loop's turn count, usage, cost
+// and exit reason so Query accessors report the recorded run.
+func replaySession(ctx context.Context, config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage) {
+	for _, msg := range config.ReplaySession {
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+			state.ExitReason = ExitAborted
+			return
+		}
+		switch m := msg.(type) {
+		case *types.ResultMessage:
+			applyReplayedResult(state, m)
+		case types.ResultMessage:
+			applyReplayedResult(state, &m)
+		}
+	}
+	if state.ExitReason == "" {
+		state.ExitReason = ExitEndTurn
+	}
+}
+
+func applyReplayedResult(state *LoopState, m *types.ResultMessage) {
+	state.TurnCount = m.NumTurns
+	state.TotalUsage = m.Usage
+	state.TotalCostUSD = m.TotalCostUSD
+	switch m.Subtype {
+	case types.ResultSubtypeSuccess, types.ResultSubtypeSuccessTurn:
+		state.ExitReason = ExitEndTurn
+	case types.ResultSubtypeErrorMaxTurns:
+		state.ExitReason = ExitMaxTurns
+	case types.ResultSubtypeErrorMaxBudget:
+		state.ExitReason = ExitMaxBudget
+	case types.ResultSubtypeErrorMaxStructuredRetries:
+		state.ExitReason = ExitMaxStructuredRetries
+	default:
+		state.ExitReason = ExitAborted
+	}
+}
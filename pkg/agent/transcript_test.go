@@ -0,0 +1,109 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestTranscript_ExportLoadReplay(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call-1", "Echo", map[string]any{"text": "hi"}),
+			endTurnResponse("Done."),
+		},
+	}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Echo", output: tools.ToolOutput{Content: "hi"}})
+	config := defaultConfig(client, registry)
+	config.RecordTranscript = true
+	q := RunLoop(context.Background(), "say hi", config)
+
+	var emitted []types.SDKMessage
+	for m := range q.Messages() {
+		emitted = append(emitted, m)
+	}
+	q.Wait()
+
+	var buf bytes.Buffer
+	if err := q.ExportTranscript(&buf); err != nil {
+		t.Fatalf("ExportTranscript: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(emitted) {
+		t.Fatalf("transcript has %d lines, want %d", lines, len(emitted))
+	}
+
+	loaded, err := LoadTranscript(&buf)
+	if err != nil {
+		t.Fatalf("LoadTranscript: %v", err)
+	}
+	if len(loaded) != len(emitted) {
+		t.Fatalf("loaded %d messages, want %d", len(loaded), len(emitted))
+	}
+	for i := range emitted {
+		if loaded[i].GetType() != emitted[i].GetType() {
+			t.Errorf("message %d type = %s, want %s", i, loaded[i].GetType(), emitted[i].GetType())
+		}
+	}
+
+	replayClient := &mockLLMClient{}
+	config = defaultConfig(replayClient, registry)
+	config.ReplaySession = loaded
+	rq := RunLoop(context.Background(), "", config)
+
+	var replayed []types.SDKMessage
+	for m := range rq.Messages() {
+		replayed = append(replayed, m)
+	}
+	rq.Wait()
+
+	if replayClient.callIndex != 0 {
+		t.Errorf("replay called the LLM %d times", replayClient.callIndex)
+	}
+	if len(replayed) != len(loaded) {
+		t.Fatalf("replayed %d messages, want %d", len(replayed), len(loaded))
+	}
+	for i := range loaded {
+		want, _ := json.Marshal(loaded[i])
+		got, _ := json.Marshal(replayed[i])
+		if !bytes.Equal(got, want) {
+			t.Errorf("replayed message %d = %s, want %s", i, got, want)
+		}
+	}
+	if rq.TurnCount() != q.TurnCount() || rq.GetExitReason() != ExitEndTurn {
+		t.Errorf("replay turns = %d, exit = %s; want %d, %s", rq.TurnCount(), rq.GetExitReason(), q.TurnCount(), ExitEndTurn)
+	}
+}
+
+func TestTranscript_OffByDefault(t *testing.T) {
+	client := &mockLLMClient{responses: []*mockStream{endTurnResponse("Done.")}}
+	q := RunLoop(context.Background(), "hi", defaultConfig(client, tools.NewRegistry()))
+	collectMessages(q)
+	q.Wait()
+
+	if len(q.transcript) != 0 {
+		t.Errorf("recorded %d messages without RecordTranscript", len(q.transcript))
+	}
+	if err := q.ExportTranscript(&bytes.Buffer{}); !errors.Is(err, errTranscriptDisabled) {
+		t.Errorf("ExportTranscript err = %v, want errTranscriptDisabled", err)
+	}
+}
+
+func TestLoadTranscript_Errors(t *testing.T) {
+	if _, err := LoadTranscript(strings.NewReader(`{"type":"bogus"}`)); err == nil {
+		t.Error("unknown message type: want error")
+	}
+	if _, err := LoadTranscript(strings.NewReader(`{"type":`)); err == nil {
+		t.Error("truncated JSON: want error")
+	}
+	msgs, err := LoadTranscript(strings.NewReader("\n\n"))
+	if err != nil || len(msgs) != 0 {
+		t.Errorf("blank input = %v, %v; want no messages", msgs, err)
+	}
+}
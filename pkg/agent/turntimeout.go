@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// errTurnTimeout is the cancellation cause of a turn that exceeded TurnTimeout.
+var errTurnTimeout = errors.New("turn timed out")
+
+// turnContext derives the context for one turn's LLM call and tools,
+// bounded by TurnTimeout when set.
+func turnContext(ctx context.Context, config *AgentConfig) (context.Context, context.CancelFunc) {
+	if config.TurnTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, config.TurnTimeout, errTurnTimeout)
+}
+
+// turnTimedOut reports whether turnCtx ended because of TurnTimeout rather
+// than cancellation of the session context.
+func turnTimedOut(ctx, turnCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(context.Cause(turnCtx), errTurnTimeout)
+}
+
+// handleTurnTimeout records a turn cut off during phase ("llm" or "tools"):
+// partial, the text streamed before an LLM timeout, is kept as the assistant
+// message, and a user note tells the model what happened so the next turn
+// can pick up from there.
+func handleTurnTimeout(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, q *Query, partial *llm.CompletionResponse, phase string) {
+	if phase == "llm" {
+		if partial != nil {
+			assistantMsg := responseToAssistantMessage(partial)
+			state.appendMessages(assistantMsg)
+			emitAssistant(ch, partial, state)
+			persistMessage(config.SessionStore, state.SessionID, assistantMsg)
+		}
+		q.mu.Lock()
+		state.TurnCount++ // counts toward MaxTurns so repeated timeouts end the session
+		q.mu.Unlock()
+	}
+
+	ch <- types.NewTurnTimeoutStatus(config.TurnTimeout.Milliseconds(), phase, state.SessionID)
+
+	var note string
+	if phase == "llm" {
+		note = fmt.Sprintf("[The previous response was cut off after the turn time limit of %s. Continue from where it stopped, in smaller steps.]", config.TurnTimeout)
+	} else {
+		note = fmt.Sprintf("[The turn time limit of %s was reached while tools were running; unfinished tool calls were cancelled. Take a smaller step or a faster approach.]", config.TurnTimeout)
+	}
+	noteMsg := llm.ChatMessage{Role: "user", Content: note}
+	state.appendMessages(noteMsg)
+	persistMessage(config.SessionStore, state.SessionID, noteMsg)
+}
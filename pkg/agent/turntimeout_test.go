@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// stallingClient streams some text on its first call and then hangs until
+// the request is cancelled; later calls are served by inner.
+type stallingClient struct {
+	*capturingLLMClient
+	text string
+}
+
+func (c *stallingClient) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Stream, error) {
+	if len(c.getRequests()) > 0 {
+		return c.capturingLLMClient.Complete(ctx, req)
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.mu.Unlock()
+
+	events := make(chan llm.StreamEvent, 2)
+	chunk := textChunk("msg-1", "claude-sonnet-4-5-20250929", c.text)
+	events <- llm.StreamEvent{Chunk: &chunk}
+	go func() {
+		defer close(events)
+		<-ctx.Done()
+		events <- llm.StreamEvent{Err: ctx.Err()}
+	}()
+	pr, pw := io.Pipe()
+	pw.Close()
+	return llm.NewStream(events, pr, func() {}), nil
+}
+
+func turnTimeouts(msgs []types.SDKMessage) []*types.TurnTimeout {
+	var out []*types.TurnTimeout
+	for _, m := range msgs {
+		if sm, ok := m.(*types.StatusMessage); ok && sm.TurnTimeout != nil {
+			out = append(out, sm.TurnTimeout)
+		}
+	}
+	return out
+}
+
+func TestLoop_TurnTimeoutDuringLLMKeepsPartialText(t *testing.T) {
+	client := &stallingClient{
+		capturingLLMClient: &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{endTurnResponse("Finished")}}},
+		text:               "Step one is",
+	}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.TurnTimeout = 50 * time.Millisecond
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if q.GetExitReason() != ExitEndTurn {
+		t.Fatalf("exit reason = %v, want end_turn after recovering", q.GetExitReason())
+	}
+	if got := turnTimeouts(msgs); len(got) != 1 || got[0].Phase != "llm" || got[0].TimeoutMs != 50 {
+		t.Errorf("turn timeouts = %+v", got)
+	}
+	reqs := client.getRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d LLM calls, want 2", len(reqs))
+	}
+	history := reqs[1].Messages
+	partial, note := history[len(history)-2], history[len(history)-1]
+	if partial.Role != "assistant" || partial.Content != "Step one is" {
+		t.Errorf("partial = %+v, want the streamed text kept", partial)
+	}
+	if content, _ := note.Content.(string); note.Role != "user" || !strings.Contains(content, "cut off") {
+		t.Errorf("note = %+v", note)
+	}
+	if n := q.TurnCount(); n != 2 {
+		t.Errorf("TurnCount = %d, want the timed-out turn counted", n)
+	}
+}
+
+func TestLoop_TurnTimeoutDuringTools(t *testing.T) {
+	client := &capturingLLMClient{inner: &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("call_1", "Block", map[string]any{}),
+		endTurnResponse("Gave up on that"),
+	}}}
+	tool := &blockingCtxTool{name: "Block", cancelled: make(chan struct{})}
+	registry := tools.NewRegistry()
+	registry.Register(tool)
+	config := defaultConfig(client, registry)
+	config.TurnTimeout = 50 * time.Millisecond
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	select {
+	case <-tool.cancelled:
+	default:
+		t.Error("tool was not cancelled")
+	}
+	if q.GetExitReason() != ExitEndTurn {
+		t.Fatalf("exit reason = %v, want end_turn rather than interrupted", q.GetExitReason())
+	}
+	if got := turnTimeouts(msgs); len(got) != 1 || got[0].Phase != "tools" {
+		t.Errorf("turn timeouts = %+v", got)
+	}
+	reqs := client.getRequests()
+	if len(reqs) != 2 {
+		t.Fatalf("got %d LLM calls, want 2", len(reqs))
+	}
+	history := reqs[1].Messages
+	if result := history[len(history)-2]; result.Role != "tool" || result.ToolCallID != "call_1" {
+		t.Errorf("message before the note = %+v, want the tool result", result)
+	}
+	if note, _ := history[len(history)-1].Content.(string); !strings.Contains(note, "tools were running") {
+		t.Errorf("note = %q", note)
+	}
+}
+
+func TestLoop_CancelWithTurnTimeoutIsAborted(t *testing.T) {
+	client := &stallingClient{capturingLLMClient: &capturingLLMClient{inner: &mockLLMClient{}}, text: "x"}
+	config := defaultConfig(client, tools.NewRegistry())
+	config.TurnTimeout = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	q := RunLoop(ctx, "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if q.GetExitReason() != ExitAborted {
+		t.Errorf("exit reason = %v, want aborted", q.GetExitReason())
+	}
+	if got := turnTimeouts(msgs); len(got) != 0 {
+		t.Errorf("cancellation reported as a turn timeout: %+v", got)
+	}
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// usageMeter reports one turn's usage to AgentConfig.OnUsageDelta as it
+// becomes known: from each streamed chunk that carries usage, then whatever
+// the final response adds.
+type usageMeter struct {
+	config   *AgentConfig
+	state    *LoopState
+	reported types.BetaUsage // the turn's usage passed to OnUsageDelta so far
+}
+
+func newUsageMeter(config *AgentConfig, state *LoopState) *usageMeter {
+	if config.OnUsageDelta == nil {
+		return nil
+	}
+	return &usageMeter{config: config, state: state}
+}
+
+// observe wraps a stream callback to report usage from the chunks it sees,
+// priced for the model serving the turn.
+func (m *usageMeter) observe(next func(*llm.StreamChunk)) func(*llm.StreamChunk) {
+	if m == nil {
+		return next
+	}
+	return func(chunk *llm.StreamChunk) {
+		if next != nil {
+			next(chunk)
+		}
+		if chunk.Model != "" {
+			m.state.turnModel = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage := llm.TranslateUsage(chunk.Usage)
+			m.report(usage, m.state.TotalCostUSD+llm.CalculateCost(m.state.turnModel, usage))
+		}
+	}
+}
+
+// finish reports the rest of the turn's usage once the turn's cost has been
+// added to the run total.
+func (m *usageMeter) finish(usage types.BetaUsage) {
+	if m != nil {
+		m.report(usage, m.state.TotalCostUSD)
+	}
+}
+
+// report passes the growth of the turn's cumulative usage since the last call.
+func (m *usageMeter) report(usage types.BetaUsage, totalCostUSD float64) {
+	delta := types.BetaUsage{
+		InputTokens:              max(usage.InputTokens-m.reported.InputTokens, 0),
+		OutputTokens:             max(usage.OutputTokens-m.reported.OutputTokens, 0),
+		CacheReadInputTokens:     max(usage.CacheReadInputTokens-m.reported.CacheReadInputTokens, 0),
+		CacheCreationInputTokens: max(usage.CacheCreationInputTokens-m.reported.CacheCreationInputTokens, 0),
+	}
+	if delta == (types.BetaUsage{}) {
+		return
+	}
+	m.reported.InputTokens += delta.InputTokens
+	m.reported.OutputTokens += delta.OutputTokens
+	m.reported.CacheReadInputTokens += delta.CacheReadInputTokens
+	m.reported.CacheCreationInputTokens += delta.CacheCreationInputTokens
+	m.config.OnUsageDelta(delta, totalCostUSD)
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestLoop_OnUsageDelta(t *testing.T) {
+	const model = "claude-sonnet-4-5-20250929"
+	// The first turn reports cumulative usage mid-stream before its final
+	// chunk; the deltas must not count the early tokens twice.
+	first := &mockStream{chunks: []llm.StreamChunk{
+		textChunk("r1", model, "Working"),
+		{ID: "r1", Model: model, Usage: &llm.Usage{PromptTokens: 100, CompletionTokens: 10}},
+		toolCallChunk("r1", model, "tc1", "Echo", `{"text":"hi"}`),
+		finishChunk("r1", model, "tool_calls", 100, 30),
+	}}
+	client := &mockLLMClient{responses: []*mockStream{first, endTurnResponse("done")}}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Echo", output: tools.ToolOutput{Content: "hi"}})
+	config := defaultConfig(client, registry)
+
+	var deltas []types.BetaUsage
+	var lastCost float64
+	config.OnUsageDelta = func(delta types.BetaUsage, totalCostUSD float64) {
+		deltas = append(deltas, delta)
+		if totalCostUSD < lastCost {
+			t.Errorf("total cost went down: %v after %v", totalCostUSD, lastCost)
+		}
+		lastCost = totalCostUSD
+	}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+
+	if len(deltas) < 3 {
+		t.Fatalf("got %d deltas, want a mid-stream report plus one per turn: %+v", len(deltas), deltas)
+	}
+	if d := deltas[0]; d.InputTokens != 100 || d.OutputTokens != 10 {
+		t.Errorf("first delta = %+v, want the mid-stream usage", d)
+	}
+	var sum types.BetaUsage
+	for _, d := range deltas {
+		sum.InputTokens += d.InputTokens
+		sum.OutputTokens += d.OutputTokens
+	}
+	total := q.TotalUsage()
+	if sum.InputTokens != total.InputTokens || sum.OutputTokens != total.OutputTokens {
+		t.Errorf("summed deltas = %+v, want total usage %+v", sum, total)
+	}
+	if lastCost != q.TotalCostUSD() {
+		t.Errorf("last total cost = %v, want %v", lastCost, q.TotalCostUSD())
+	}
+}
+
+func TestLoop_OnUsageDelta_PricesServedModel(t *testing.T) {
+	// Requested opus, but a router or proxy served the turn with haiku
+	const served = "claude-haiku-4-5-20251001"
+	usage := &llm.Usage{PromptTokens: 1000, CompletionTokens: 100}
+	stream := &mockStream{chunks: []llm.StreamChunk{
+		textChunk("r1", served, "Done"),
+		{ID: "r1", Model: served, Usage: usage},
+		finishChunk("r1", served, "stop", 1000, 100),
+	}}
+	config := defaultConfig(&mockLLMClient{responses: []*mockStream{stream}}, tools.NewRegistry())
+	config.Model = "claude-opus-4-5-20250514"
+
+	var costs []float64
+	config.OnUsageDelta = func(_ types.BetaUsage, totalCostUSD float64) {
+		costs = append(costs, totalCostUSD)
+	}
+	q := RunLoop(context.Background(), "Hello", config)
+	collectMessages(q)
+	q.Wait()
+
+	want := llm.CalculateCost(served, llm.TranslateUsage(usage))
+	if len(costs) == 0 || costs[0] != want {
+		t.Errorf("mid-stream total cost = %v, want %v (priced as %s)", costs, want, served)
+	}
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// withWebCache attaches the session store to ctx as the WebFetch and
+// WebSearch cache, when it can keep web resources.
+func withWebCache(ctx context.Context, config *AgentConfig, state *LoopState) context.Context {
+	store, ok := config.SessionStore.(WebResourceStore)
+	if !ok {
+		return ctx
+	}
+	return tools.WithWebCache(ctx, &sessionWebCache{store: store, sessionID: state.SessionID})
+}
+
+// sessionWebCache is a tools.WebCache over one session's web resources.
+type sessionWebCache struct {
+	store     WebResourceStore
+	sessionID string
+}
+
+func (c *sessionWebCache) GetWebResource(kind, key string) (tools.WebResource, bool, error) {
+	return c.store.LoadWebResource(c.sessionID, kind, key)
+}
+
+func (c *sessionWebCache) PutWebResource(res tools.WebResource) error {
+	return c.store.SaveWebResource(c.sessionID, res)
+}
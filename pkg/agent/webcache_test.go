@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// webResourceMockStore adds web resource storage to mockSessionStore.
+type webResourceMockStore struct {
+	mockSessionStore
+	webMu     sync.Mutex
+	resources map[string]tools.WebResource // session ID, kind and key -> resource
+}
+
+func (s *webResourceMockStore) LoadWebResource(sessionID, kind, key string) (tools.WebResource, bool, error) {
+	s.webMu.Lock()
+	defer s.webMu.Unlock()
+	res, ok := s.resources[sessionID+"|"+kind+"|"+key]
+	return res, ok, nil
+}
+
+func (s *webResourceMockStore) SaveWebResource(sessionID string, res tools.WebResource) error {
+	s.webMu.Lock()
+	defer s.webMu.Unlock()
+	if s.resources == nil {
+		s.resources = make(map[string]tools.WebResource)
+	}
+	s.resources[sessionID+"|"+res.Kind+"|"+res.Key] = res
+	return nil
+}
+
+type countingSearchProvider struct {
+	mu      sync.Mutex
+	calls   int
+	snippet string
+}
+
+func (p *countingSearchProvider) Search(_ context.Context, _ string, _ tools.SearchOptions) ([]tools.SearchResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	return []tools.SearchResult{{Title: "Go", URL: "https://go.dev", Snippet: p.snippet}}, nil
+}
+
+func TestLoop_WebCacheInSessionStore(t *testing.T) {
+	store := &webResourceMockStore{}
+	provider := &countingSearchProvider{snippet: "The Go programming language"}
+	registry := tools.NewRegistry()
+	registry.Register(&tools.WebSearchTool{Provider: provider})
+	client := &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("tc1", "WebSearch", map[string]any{"query": "golang"}),
+		toolUseResponse("tc2", "WebSearch", map[string]any{"query": "golang"}),
+		endTurnResponse("Found it."),
+	}}
+	config := defaultConfig(client, registry)
+	config.SessionStore = store
+	config.ToolCallLog = true
+
+	q := RunLoop(context.Background(), "search", config)
+	collectMessages(q)
+	q.Wait()
+
+	if provider.calls != 1 {
+		t.Errorf("provider calls = %d, want 1", provider.calls)
+	}
+	calls := q.ToolCalls()
+	if len(calls) != 2 || calls[0].FromCache || !calls[1].FromCache {
+		t.Errorf("tool calls = %+v, want the second served from cache", calls)
+	}
+	if _, ok, _ := store.LoadWebResource(q.SessionID(), tools.WebResourceSearch, "golang"); !ok {
+		t.Error("search results not stored with the session")
+	}
+}
+
+func TestLoop_WebCacheRedacted(t *testing.T) {
+	store := &webResourceMockStore{}
+	provider := &countingSearchProvider{snippet: "leaked AWS_ACCESS_KEY_ID=" + testAWSKey}
+	registry := tools.NewRegistry()
+	registry.Register(&tools.WebSearchTool{Provider: provider})
+	client := &mockLLMClient{responses: []*mockStream{
+		toolUseResponse("tc1", "WebSearch", map[string]any{"query": "leak"}),
+		endTurnResponse("Done."),
+	}}
+	config := defaultConfig(client, registry)
+	config.SessionStore = store
+	config.Redactor = tools.NewSecretRedactor(nil)
+
+	q := RunLoop(context.Background(), "search", config)
+	collectMessages(q)
+	q.Wait()
+
+	res, ok, _ := store.LoadWebResource(q.SessionID(), tools.WebResourceSearch, "leak")
+	if !ok {
+		t.Fatal("search results not stored through the redacting store")
+	}
+	if strings.Contains(res.Content, testAWSKey) {
+		t.Errorf("secret stored in web resource: %q", res.Content)
+	}
+}
@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// windowTrigger is the CompactBoundaryMessage trigger for history windowing.
+const windowTrigger = "window"
+
+// HistoryWindow keeps the first user message and the most recent messages
+// that fit in MaxTokens, dropping the middle of the conversation. Unlike a
+// Compactor it makes no LLM calls. The system prompt is sent separately and
+// always kept.
+type HistoryWindow struct {
+	// MaxTokens is the history budget (0 = the context limit less the system
+	// prompt and reserved output tokens).
+	MaxTokens int
+}
+
+// windowHistory trims state.Messages to the configured window and emits a
+// CompactBoundaryMessage when anything was dropped.
+func windowHistory(config *AgentConfig, state *LoopState, ch chan<- types.SDKMessage, systemPrompt string, budget TokenBudget) {
+	limit := config.HistoryWindow.MaxTokens
+	if limit <= 0 {
+		limit = budget.ContextLimit - budget.SystemPromptTkns - budget.MaxOutputTkns
+	}
+	windowed := windowMessages(state.Messages, state.tokenCache, budget.MessageTkns, limit)
+	if len(windowed) == len(state.Messages) {
+		return
+	}
+	state.setMessages(windowed)
+
+	boundary := types.NewCompactBoundary(windowTrigger, budget.MessageTkns, state.SessionID)
+	boundary.CompactMetadata.PostTokens = calculateTokenBudget(config, state, systemPrompt).MessageTkns
+	ch <- boundary
+	if config.Metrics != nil {
+		config.Metrics.RecordCompaction(currentModel(config, state), windowTrigger)
+	}
+}
+
+// windowMessages returns the messages up to and including the first user
+// message, followed by the longest suffix that fits in limit. The suffix never
+// starts with a tool result, so every kept tool_result has its tool_use. When
+// not even the latest exchange fits, that exchange is kept anyway.
+func windowMessages(messages []llm.ChatMessage, cache *messageTokenCache, total, limit int) []llm.ChatMessage {
+	if total <= limit {
+		return messages
+	}
+
+	head := 0
+	for i, msg := range messages {
+		if msg.Role == "user" {
+			head = i + 1
+			break
+		}
+	}
+	used := 0
+	for _, msg := range messages[:head] {
+		used += cache.count(msg)
+	}
+
+	// Grow the suffix backwards, remembering the earliest start that fits
+	// and is a valid cut point.
+	cut := -1
+	for i := len(messages) - 1; i >= head; i-- {
+		used += cache.count(messages[i])
+		if used > limit && cut >= 0 {
+			break
+		}
+		if messages[i].Role != "tool" {
+			cut = i
+		}
+	}
+	if cut <= head {
+		return messages
+	}
+
+	result := make([]llm.ChatMessage, 0, head+len(messages)-cut)
+	result = append(result, messages[:head]...)
+	return append(result, messages[cut:]...)
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestWindowMessages(t *testing.T) {
+	long := strings.Repeat("x", 2000)
+	msgs := []llm.ChatMessage{
+		{Role: "user", Content: "task"},
+		{Role: "assistant", Content: "step 1", ToolCalls: []llm.ToolCall{{ID: "call_1"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: long},
+		{Role: "assistant", Content: "step 2", ToolCalls: []llm.ToolCall{{ID: "call_2"}, {ID: "call_3"}}},
+		{Role: "tool", ToolCallID: "call_2", Content: long},
+		{Role: "tool", ToolCallID: "call_3", Content: long},
+		{Role: "assistant", Content: "done"},
+	}
+	cache := newMessageTokenCache(HeuristicTokenCounter{}, "heuristic")
+	total := 0
+	for _, m := range msgs {
+		total += cache.count(m)
+	}
+	lastTwo := cache.count(msgs[0]) + cache.count(msgs[5]) + cache.count(msgs[6])
+
+	tests := []struct {
+		name  string
+		limit int
+		want  []string // Content of the kept messages
+	}{
+		{"fits", total, []string{"task", "step 1", long, "step 2", long, long, "done"}},
+		{"drops first exchange", total - 1, []string{"task", "step 2", long, long, "done"}},
+		// The tail could fit from call_3's result, but that would orphan it
+		{"keeps tool pairs", lastTwo, []string{"task", "done"}},
+		{"keeps latest exchange", 0, []string{"task", "done"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := windowMessages(msgs, cache, total, tt.limit)
+			var got []string
+			for _, m := range result {
+				got = append(got, m.Content.(string))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("kept %d messages %q, want %d", len(got), got, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestWindowMessages_LatestExchangeWithToolResults(t *testing.T) {
+	long := strings.Repeat("x", 2000)
+	msgs := []llm.ChatMessage{
+		{Role: "user", Content: "task"},
+		{Role: "assistant", Content: "step 1", ToolCalls: []llm.ToolCall{{ID: "call_1"}}},
+		{Role: "tool", ToolCallID: "call_1", Content: long},
+		{Role: "assistant", Content: "step 2", ToolCalls: []llm.ToolCall{{ID: "call_2"}}},
+		{Role: "tool", ToolCallID: "call_2", Content: long},
+	}
+	cache := newMessageTokenCache(HeuristicTokenCounter{}, "heuristic")
+
+	result := windowMessages(msgs, cache, 10_000, 0)
+	if len(result) != 3 || result[1].Content != "step 2" || result[2].ToolCallID != "call_2" {
+		t.Errorf("result = %+v, want the first user message and the latest tool exchange", result)
+	}
+}
+
+func TestLoop_HistoryWindow(t *testing.T) {
+	inner := &mockLLMClient{
+		responses: []*mockStream{
+			toolUseResponse("call_1", "Bash", map[string]any{"command": "ls"}),
+			toolUseResponse("call_2", "Bash", map[string]any{"command": "pwd"}),
+			toolUseResponse("call_3", "Bash", map[string]any{"command": "id"}),
+			endTurnResponse("Done"),
+		},
+	}
+	client := &capturingLLMClient{inner: inner}
+	registry := tools.NewRegistry()
+	registry.Register(&mockRecordingTool{name: "Bash", output: tools.ToolOutput{Content: strings.Repeat("output ", 500)}})
+	compactor := &mockCompactor{shouldCompact: true}
+	config := defaultConfig(client, registry)
+	config.Compactor = compactor
+	config.HistoryWindow = &HistoryWindow{MaxTokens: 1000}
+
+	q := RunLoop(context.Background(), "Hello", config)
+	msgs := collectMessages(q)
+	q.Wait()
+
+	if n := compactor.CompactCallCount(); n != 0 {
+		t.Errorf("Compact called %d times, want 0 while HistoryWindow is set", n)
+	}
+
+	var boundaries []*types.CompactBoundaryMessage
+	for _, m := range msgs {
+		if b, ok := m.(*types.CompactBoundaryMessage); ok {
+			boundaries = append(boundaries, b)
+		}
+	}
+	if len(boundaries) == 0 {
+		t.Fatal("expected a CompactBoundaryMessage once history was windowed")
+	}
+	if md := boundaries[0].CompactMetadata; md.Trigger != "window" || md.PreTokens <= md.PostTokens {
+		t.Errorf("boundary metadata = %+v, want trigger window and pre > post tokens", md)
+	}
+
+	reqs := client.getRequests()
+	last := reqs[len(reqs)-1].Messages
+	if last[1].Role != "user" || last[1].Content != "Hello" {
+		t.Errorf("first history message = %+v, want the original prompt", last[1])
+	}
+	if last[2].Role == "tool" {
+		t.Error("windowed history starts with an orphaned tool result")
+	}
+	if len(last) >= len(reqs[len(reqs)-2].Messages)+2 {
+		t.Errorf("final request has %d messages, expected the window to drop older exchanges", len(last))
+	}
+}
+
+func TestWithHistoryWindow_ReplacesCompactor(t *testing.T) {
+	config := DefaultConfig()
+	WithCompactor(&mockCompactor{})(&config)
+	WithHistoryWindow(5000)(&config)
+	if config.HistoryWindow == nil || config.HistoryWindow.MaxTokens != 5000 {
+		t.Fatalf("HistoryWindow = %+v", config.HistoryWindow)
+	}
+	if _, ok := config.Compactor.(*NoOpCompactor); !ok {
+		t.Errorf("Compactor = %T, want NoOpCompactor", config.Compactor)
+	}
+
+	WithCompactor(&mockCompactor{})(&config)
+	if config.HistoryWindow != nil {
+		t.Error("WithCompactor should clear HistoryWindow")
+	}
+}
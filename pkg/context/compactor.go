@@ -2,12 +2,13 @@ package context
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/jg-phare/goat/pkg/agent"
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/types"
@@ -25,6 +26,15 @@ type CompactorConfig struct {
 	ThresholdPct  float64        // default: 0.80
 	CriticalPct   float64        // default: 0.95
 	PreserveRatio float64        // default: 0.40
+
+	// PreserveTurns, when > 0, keeps the last PreserveTurns assistant turns
+	// verbatim instead of PreserveRatio of the context window.
+	PreserveTurns int
+
+	// RequireSummary makes Compact return an error, leaving the history
+	// uncompacted, when no summary can be produced, instead of dropping the
+	// oldest messages.
+	RequireSummary bool
 }
 
 // Compactor implements context window management via conversation summarization.
@@ -36,6 +46,9 @@ type Compactor struct {
 	thresholdPct  float64
 	criticalPct   float64
 	preserveRatio float64
+	preserveTurns int
+
+	requireSummary bool
 }
 
 // NewCompactor creates a Compactor with sensible defaults for any unset config fields.
@@ -48,6 +61,9 @@ func NewCompactor(cfg CompactorConfig) *Compactor {
 		thresholdPct:  cfg.ThresholdPct,
 		criticalPct:   cfg.CriticalPct,
 		preserveRatio: cfg.PreserveRatio,
+		preserveTurns: cfg.PreserveTurns,
+
+		requireSummary: cfg.RequireSummary,
 	}
 	if c.estimator == nil {
 		c.estimator = &SimpleEstimator{}
@@ -82,36 +98,27 @@ func (c *Compactor) MustCompact(budget agent.TokenBudget) bool {
 }
 
 // Compact summarizes older messages, keeping the most recent messages verbatim.
-// On summary generation failure, it falls back to simple truncation.
+// On summary generation failure, it falls back to simple truncation, or
+// returns an error when RequireSummary is set.
 func (c *Compactor) Compact(ctx context.Context, req agent.CompactRequest) ([]llm.ChatMessage, error) {
 	if len(req.Messages) <= 1 {
 		return req.Messages, nil
 	}
 
-	// 1. Fire PreCompact hook and capture custom instructions
+	// 1. Custom instructions come from PreCompact hooks fired by the loop
 	var customInstructions *string
-	if c.hooks != nil {
-		results, _ := c.hooks.Fire(ctx, types.HookEventPreCompact, map[string]any{
-			"trigger": req.Trigger,
-		})
-		for _, hr := range results {
-			if hr.HookSpecificOutput != nil {
-				if output, ok := hr.HookSpecificOutput.(map[string]any); ok {
-					if ci, ok := output["custom_instructions"].(string); ok && ci != "" {
-						customInstructions = &ci
-						break
-					}
-				}
-			}
-		}
+	if req.CustomInstructions != "" {
+		customInstructions = &req.CustomInstructions
 	}
 
-	// 2. Record pre-compaction token count
-	preTokens := c.estimator.EstimateMessages(req.Messages)
-
-	// 3. Calculate split point
-	preserveBudget := int(float64(req.Budget.ContextLimit) * c.preserveRatio)
-	splitIdx := calculateSplitPoint(req.Messages, preserveBudget, c.estimator)
+	// 2. Calculate split point
+	var splitIdx int
+	if c.preserveTurns > 0 {
+		splitIdx = turnSplitPoint(req.Messages, c.preserveTurns)
+	} else {
+		preserveBudget := int(float64(req.Budget.ContextLimit) * c.preserveRatio)
+		splitIdx = calculateSplitPoint(req.Messages, preserveBudget, c.estimator)
+	}
 
 	if splitIdx <= 0 || splitIdx >= len(req.Messages) {
 		// Nothing to compact
@@ -121,7 +128,7 @@ func (c *Compactor) Compact(ctx context.Context, req agent.CompactRequest) ([]ll
 	compactZone := req.Messages[:splitIdx]
 	preserveZone := req.Messages[splitIdx:]
 
-	// 4. Try session memory summary first (if available and recent)
+	// 3. Try session memory summary first (if available and recent)
 	var compacted []llm.ChatMessage
 	if sessionSummary := loadRecentSessionSummary(req.SessionDir); sessionSummary != "" {
 		summaryMsg := llm.ChatMessage{
@@ -130,9 +137,15 @@ func (c *Compactor) Compact(ctx context.Context, req agent.CompactRequest) ([]ll
 		}
 		compacted = append([]llm.ChatMessage{summaryMsg}, preserveZone...)
 	} else if c.client != nil {
-		// 4b. Fall back to LLM-generated summary
+		// 3b. Fall back to LLM-generated summary
 		summary, err := generateSummary(ctx, compactZone, c.client, c.summaryModel, customInstructions)
+		if err == nil && c.requireSummary && strings.TrimSpace(summary) == "" {
+			err = errors.New("summarizer returned an empty summary")
+		}
 		if err != nil {
+			if c.requireSummary {
+				return nil, fmt.Errorf("compactor: %w", err)
+			}
 			// Fallback: simple truncation (drop oldest messages)
 			compacted = preserveZone
 		} else {
@@ -142,26 +155,14 @@ func (c *Compactor) Compact(ctx context.Context, req agent.CompactRequest) ([]ll
 			}
 			compacted = append([]llm.ChatMessage{summaryMsg}, preserveZone...)
 		}
+	} else if c.requireSummary {
+		return nil, errors.New("compactor: no LLM client configured")
 	} else {
 		// No LLM client — simple truncation fallback
 		compacted = preserveZone
 	}
 
-	// 5. Emit CompactBoundaryMessage
-	if req.EmitCh != nil {
-		msg := &types.CompactBoundaryMessage{
-			BaseMessage: types.BaseMessage{UUID: uuid.New(), SessionID: req.SessionID},
-			Type:        types.MessageTypeSystem,
-			Subtype:     types.SystemSubtypeCompactBoundary,
-			CompactMetadata: types.CompactMetadata{
-				Trigger:   req.Trigger,
-				PreTokens: preTokens,
-			},
-		}
-		req.EmitCh <- msg
-	}
-
-	// 6. Fire SessionStart hook with source="compact"
+	// 4. Fire SessionStart hook with source="compact"
 	if c.hooks != nil {
 		c.hooks.Fire(ctx, types.HookEventSessionStart, map[string]any{
 			"source": "compact",
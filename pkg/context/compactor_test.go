@@ -96,7 +96,6 @@ func TestCompactor_Compact_WithSummary(t *testing.T) {
 		}
 	}
 
-	ch := make(chan types.SDKMessage, 10)
 	budget := agent.TokenBudget{
 		ContextLimit:     1000,
 		SystemPromptTkns: 50,
@@ -110,7 +109,6 @@ func TestCompactor_Compact_WithSummary(t *testing.T) {
 		Budget:    budget,
 		Trigger:   "auto",
 		SessionID: "test-session",
-		EmitCh:    ch,
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -131,33 +129,10 @@ func TestCompactor_Compact_WithSummary(t *testing.T) {
 		t.Errorf("expected 1 LLM call, got %d", client.calls)
 	}
 
-	// Hooks should have been fired
+	// PreCompact is fired by the loop; the compactor only signals the reset
 	events := hooks.firedEvents()
-	if len(events) != 2 {
-		t.Fatalf("expected 2 hook events, got %d: %v", len(events), events)
-	}
-	if events[0] != types.HookEventPreCompact {
-		t.Errorf("first hook = %s, want PreCompact", events[0])
-	}
-	if events[1] != types.HookEventSessionStart {
-		t.Errorf("second hook = %s, want SessionStart", events[1])
-	}
-
-	// CompactBoundaryMessage should have been emitted
-	select {
-	case msg := <-ch:
-		cbm, ok := msg.(*types.CompactBoundaryMessage)
-		if !ok {
-			t.Fatalf("expected CompactBoundaryMessage, got %T", msg)
-		}
-		if cbm.CompactMetadata.Trigger != "auto" {
-			t.Errorf("trigger = %s, want auto", cbm.CompactMetadata.Trigger)
-		}
-		if cbm.CompactMetadata.PreTokens <= 0 {
-			t.Errorf("pre_tokens = %d, should be > 0", cbm.CompactMetadata.PreTokens)
-		}
-	default:
-		t.Error("no CompactBoundaryMessage emitted")
+	if len(events) != 1 || events[0] != types.HookEventSessionStart {
+		t.Errorf("hook events = %v, want [SessionStart]", events)
 	}
 }
 
@@ -183,14 +158,12 @@ func TestCompactor_Compact_FallbackOnError(t *testing.T) {
 		}
 	}
 
-	ch := make(chan types.SDKMessage, 10)
 	compacted, err := c.Compact(context.Background(), agent.CompactRequest{
 		Messages:  messages,
 		Model:     "claude-sonnet-4-5-20250929",
 		Budget:    agent.TokenBudget{ContextLimit: 1000, MaxOutputTkns: 100, MessageTkns: 900},
 		Trigger:   "auto",
 		SessionID: "test-session",
-		EmitCh:    ch,
 	})
 	if err != nil {
 		t.Fatalf("should not return error on summary failure, got: %v", err)
@@ -244,13 +217,11 @@ func TestCompactor_Compact_NoLLMClient(t *testing.T) {
 		}
 	}
 
-	ch := make(chan types.SDKMessage, 10)
 	compacted, err := c.Compact(context.Background(), agent.CompactRequest{
 		Messages:  messages,
 		Budget:    agent.TokenBudget{ContextLimit: 1000, MaxOutputTkns: 100, MessageTkns: 900},
 		Trigger:   "manual",
 		SessionID: "s1",
-		EmitCh:    ch,
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -302,15 +273,11 @@ func TestCompactor_MustCompact(t *testing.T) {
 	}
 }
 
-func TestCompactor_Compact_CustomInstructionsFromHook(t *testing.T) {
-	hookRunner := &hookRunnerWithCustomInstructions{
-		instructions: "Focus on code changes and ignore chitchat",
-	}
+func TestCompactor_Compact_CustomInstructions(t *testing.T) {
 	client := &mockSummaryClient{summary: "Summary with custom instructions."}
 
 	c := NewCompactor(CompactorConfig{
 		LLMClient:     client,
-		HookRunner:    hookRunner,
 		PreserveRatio: 0.40,
 	})
 
@@ -326,13 +293,12 @@ func TestCompactor_Compact_CustomInstructionsFromHook(t *testing.T) {
 		}
 	}
 
-	ch := make(chan types.SDKMessage, 10)
 	_, err := c.Compact(context.Background(), agent.CompactRequest{
-		Messages:  messages,
-		Budget:    agent.TokenBudget{ContextLimit: 1000, MaxOutputTkns: 100, MessageTkns: 900},
-		Trigger:   "auto",
-		SessionID: "test",
-		EmitCh:    ch,
+		Messages:           messages,
+		Budget:             agent.TokenBudget{ContextLimit: 1000, MaxOutputTkns: 100, MessageTkns: 900},
+		Trigger:            "auto",
+		SessionID:          "test",
+		CustomInstructions: "Focus on code changes and ignore chitchat",
 	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -347,24 +313,6 @@ func TestCompactor_Compact_CustomInstructionsFromHook(t *testing.T) {
 	}
 }
 
-// hookRunnerWithCustomInstructions returns custom_instructions from PreCompact hook.
-type hookRunnerWithCustomInstructions struct {
-	instructions string
-}
-
-func (h *hookRunnerWithCustomInstructions) Fire(_ context.Context, event types.HookEvent, _ any) ([]agent.HookResult, error) {
-	if event == types.HookEventPreCompact {
-		return []agent.HookResult{
-			{
-				HookSpecificOutput: map[string]any{
-					"custom_instructions": h.instructions,
-				},
-			},
-		}, nil
-	}
-	return nil, nil
-}
-
 func TestNewCompactor_Defaults(t *testing.T) {
 	c := NewCompactor(CompactorConfig{})
 
@@ -544,3 +492,81 @@ func TestLoadRecentSessionSummary_NoFile(t *testing.T) {
 		t.Errorf("expected empty, got %q", result)
 	}
 }
+
+// agenticHistory returns a prompt followed by n tool-calling turns, each an
+// assistant message with one tool call and its tool result.
+func agenticHistory(n int) []llm.ChatMessage {
+	messages := []llm.ChatMessage{{Role: "user", Content: "Fix the build"}}
+	for i := range n {
+		id := fmt.Sprintf("call_%d", i)
+		messages = append(messages,
+			llm.ChatMessage{Role: "assistant", Content: fmt.Sprintf("step %d", i), ToolCalls: []llm.ToolCall{
+				{ID: id, Type: "function", Function: llm.FunctionCall{Name: "Bash", Arguments: `{"command":"make"}`}},
+			}},
+			llm.ChatMessage{Role: "tool", ToolCallID: id, Content: strings.Repeat("output ", 50)},
+		)
+	}
+	return messages
+}
+
+func TestCompactor_Compact_PreserveTurns(t *testing.T) {
+	client := &mockSummaryClient{summary: "Ran make twice; both failed on a missing header."}
+	hooks := &mockHookRunner{}
+	c := NewCompactor(CompactorConfig{LLMClient: client, HookRunner: hooks, SummaryModel: "gpt-5-nano", PreserveTurns: 2})
+
+	messages := agenticHistory(4)
+	compacted, err := c.Compact(context.Background(), agent.CompactRequest{Messages: messages})
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+
+	// summary + the last 2 turns (assistant + tool each)
+	if len(compacted) != 5 {
+		t.Fatalf("len(compacted) = %d, want 5", len(compacted))
+	}
+	if compacted[0].Role != "user" || !strings.Contains(ContentString(compacted[0]), "missing header") {
+		t.Errorf("first message = %+v, want the summary as a user message", compacted[0])
+	}
+	if compacted[1].Role != "assistant" || compacted[1].ToolCalls[0].ID != "call_2" {
+		t.Errorf("kept history should start at turn 2, got %+v", compacted[1])
+	}
+	for i, msg := range compacted[1:] {
+		if msg.Role == "tool" && compacted[i].ToolCalls[0].ID != msg.ToolCallID {
+			t.Errorf("tool result %s separated from its call", msg.ToolCallID)
+		}
+	}
+	if !strings.Contains(client.lastPrompt, "Fix the build") || strings.Contains(client.lastPrompt, "step 2") {
+		t.Error("summarizer should see only the compacted turns")
+	}
+	if events := hooks.firedEvents(); len(events) != 1 {
+		t.Errorf("hook events = %v, want SessionStart", events)
+	}
+}
+
+func TestCompactor_Compact_TooFewTurns(t *testing.T) {
+	client := &mockSummaryClient{summary: "unused"}
+	c := NewCompactor(CompactorConfig{LLMClient: client, PreserveTurns: 3})
+
+	messages := agenticHistory(3)
+	compacted, err := c.Compact(context.Background(), agent.CompactRequest{Messages: messages})
+	if err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	if len(compacted) != len(messages) || client.calls != 0 {
+		t.Errorf("len = %d, calls = %d; want history unchanged without a summary call", len(compacted), client.calls)
+	}
+}
+
+func TestCompactor_Compact_RequireSummary(t *testing.T) {
+	for name, client := range map[string]*mockSummaryClient{
+		"error":         {err: fmt.Errorf("model overloaded")},
+		"empty summary": {summary: "  "},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewCompactor(CompactorConfig{LLMClient: client, PreserveTurns: 1, RequireSummary: true})
+			if _, err := c.Compact(context.Background(), agent.CompactRequest{Messages: agenticHistory(3)}); err == nil {
+				t.Error("want an error so the loop keeps the history uncompacted")
+			}
+		})
+	}
+}
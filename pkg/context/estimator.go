@@ -25,6 +25,7 @@ func (e *SimpleEstimator) EstimateMessages(messages []llm.ChatMessage) int {
 	total := 0
 	for _, msg := range messages {
 		total += e.Estimate(ContentString(msg))
+		total += llm.CountImageParts(msg.Content) * llm.ImageTokenEstimate
 		total += 4 // overhead per message (role, separators)
 	}
 	return total
@@ -37,6 +38,13 @@ func ContentString(msg llm.ChatMessage) string {
 		return c
 	case nil:
 		return ""
+	case []llm.ContentPart:
+		// Text parts only; images are estimated separately
+		var sb []byte
+		for _, part := range c {
+			sb = append(sb, part.Text...)
+		}
+		return string(sb)
 	case []any:
 		// Handle []ContentPart-like structures serialized as []any
 		var sb []byte
@@ -49,6 +49,12 @@ func TestSimpleEstimator_EstimateMessages(t *testing.T) {
 		{"nil content", []llm.ChatMessage{
 			{Role: "assistant", Content: nil},
 		}, 4}, // 0 + 4 overhead
+		{"image", []llm.ChatMessage{
+			{Role: "user", Content: []llm.ContentPart{
+				llm.TextPart(strings.Repeat("a", 100)),
+				llm.ImageDataPart("image/png", make([]byte, 4000)),
+			}},
+		}, 29 + llm.ImageTokenEstimate}, // base64 data is not counted as text
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -86,6 +92,9 @@ func TestContentString(t *testing.T) {
 				map[string]any{"type": "image_url"},
 			},
 		}, ""},
+		{"content parts", llm.ChatMessage{
+			Content: []llm.ContentPart{llm.TextPart("look: "), llm.ImageURLPart("https://example.com/a.png")},
+		}, "look: "},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
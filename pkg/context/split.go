@@ -1,6 +1,10 @@
 package context
 
-import "github.com/jg-phare/goat/pkg/llm"
+import (
+	"slices"
+
+	"github.com/jg-phare/goat/pkg/llm"
+)
 
 // calculateSplitPoint determines where to split the conversation into
 // compact zone (to summarize) and preserve zone (to keep verbatim).
@@ -74,3 +78,25 @@ func adjustSplitForToolPairs(messages []llm.ChatMessage, splitIdx int) int {
 
 	return splitIdx
 }
+
+// turnSplitPoint returns the index of the assistant message that starts the
+// last preserveTurns turns, or 0 if no earlier turn exists to compact. The
+// preserve zone then starts at an assistant message, so every tool_use stays
+// with its tool results and the summary (a user message) is followed by an
+// assistant turn.
+func turnSplitPoint(messages []llm.ChatMessage, preserveTurns int) int {
+	turns := 0
+	for i := len(messages) - 1; i > 0; i-- {
+		if messages[i].Role != "assistant" {
+			continue
+		}
+		if turns++; turns == preserveTurns {
+			earlier := slices.ContainsFunc(messages[:i], func(m llm.ChatMessage) bool { return m.Role == "assistant" })
+			if !earlier {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
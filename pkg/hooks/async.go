@@ -2,25 +2,90 @@ package hooks
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
 // defaultAsyncTimeout is the default timeout for async hooks (30 seconds).
 const defaultAsyncTimeout = 30
 
-// executeAsync runs a hook callback asynchronously with a timeout.
-// If the hook returns an AsyncHookJSONOutput, it waits for the async result
-// by re-running the callback with the async timeout.
-func executeAsync(ctx context.Context, hook HookCallback, input any, asyncTimeout int) (HookJSONOutput, error) {
+// defaultMaxAsyncHooks is the default number of async hooks run at once.
+const defaultMaxAsyncHooks = 4
+
+// startAsync runs the remaining work of a hook that returned an
+// AsyncHookJSONOutput in a background goroutine and returns immediately.
+// The hook is re-executed with its own context, detached from ctx's
+// cancellation and bounded by the async timeout; at most MaxAsyncHooks hooks run
+// at a time and the rest wait for a free worker. The outcome is reported via
+// a HookResponseMessage but never affects the turn that fired it.
+func (r *Runner) startAsync(ctx context.Context, ref hookRef, hook HookCallback, input any, asyncTimeout int) {
 	if asyncTimeout <= 0 {
 		asyncTimeout = defaultAsyncTimeout
 	}
+	asyncCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(asyncTimeout)*time.Second)
+
+	r.asyncStarted()
+	go func() {
+		defer r.asyncFinished()
+		defer cancel()
+
+		start := time.Now()
+		select {
+		case r.asyncSem <- struct{}{}:
+			defer func() { <-r.asyncSem }()
+		case <-asyncCtx.Done():
+			r.finishHook(ref, start, "error")
+			return
+		}
+
+		// The hook implementation is responsible for blocking until completion
+		// within the async timeout period.
+		if _, err := hook(input, "", asyncCtx); err != nil {
+			r.finishHook(ref, start, "error")
+			return
+		}
+		r.finishHook(ref, start, "success")
+	}()
+}
+
+// DrainAsync waits up to timeout for background async hooks to finish, so
+// their work is flushed before the process exits, then emits a
+// HookMetricsMessage. It returns an error if hooks are still running when the
+// timeout expires. Callers that close the runner's EmitChannel should drain
+// first.
+func (r *Runner) DrainAsync(timeout time.Duration) error {
+	defer r.EmitMetrics()
+
+	r.asyncMu.Lock()
+	if r.asyncPending == 0 {
+		r.asyncMu.Unlock()
+		return nil
+	}
+	idle := r.asyncIdle
+	r.asyncMu.Unlock()
 
-	asyncCtx, cancel := context.WithTimeout(ctx, time.Duration(asyncTimeout)*time.Second)
-	defer cancel()
+	select {
+	case <-idle:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("async hooks still running after %s", timeout)
+	}
+}
 
-	// Re-execute the hook with the async timeout context.
-	// The hook implementation is responsible for blocking until completion
-	// within the async timeout period.
-	return hook(input, "", asyncCtx)
+func (r *Runner) asyncStarted() {
+	r.asyncMu.Lock()
+	defer r.asyncMu.Unlock()
+	if r.asyncPending == 0 {
+		r.asyncIdle = make(chan struct{})
+	}
+	r.asyncPending++
+}
+
+func (r *Runner) asyncFinished() {
+	r.asyncMu.Lock()
+	defer r.asyncMu.Unlock()
+	r.asyncPending--
+	if r.asyncPending == 0 {
+		close(r.asyncIdle)
+	}
 }
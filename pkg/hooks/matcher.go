@@ -1,6 +1,11 @@
 package hooks
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
 
 // matchToolName checks if the tool name in the input matches the pattern.
 // An empty pattern matches everything.
@@ -55,3 +60,64 @@ func extractToolName(input any) string {
 	}
 	return ""
 }
+
+// matchFilePath checks if the file_path argument in the tool input matches
+// the doublestar glob pattern ("**/*.go", "src/**"). Input without a
+// file_path matches. Paths under cwd are also tried relative to it, and a
+// pattern without a separator is tried against the base name, so "*.go"
+// matches any Go file.
+func matchFilePath(pattern, cwd string, input any) bool {
+	path := extractFilePath(input)
+	if path == "" {
+		return true // no file path to filter on = match
+	}
+
+	candidates := []string{filepath.ToSlash(path)}
+	if cwd != "" && filepath.IsAbs(path) {
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			candidates = append(candidates, filepath.ToSlash(rel))
+		}
+	}
+	if !strings.Contains(pattern, "/") {
+		candidates = append(candidates, filepath.Base(path))
+	}
+
+	for _, candidate := range candidates {
+		if matched, err := doublestar.Match(pattern, candidate); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// extractFilePath extracts the file_path argument from the hook's tool input.
+func extractFilePath(input any) string {
+	var toolInput any
+	switch v := input.(type) {
+	case *PreToolUseHookInput:
+		toolInput = v.ToolInput
+	case PreToolUseHookInput:
+		toolInput = v.ToolInput
+	case *PostToolUseHookInput:
+		toolInput = v.ToolInput
+	case PostToolUseHookInput:
+		toolInput = v.ToolInput
+	case *PostToolUseFailureHookInput:
+		toolInput = v.ToolInput
+	case PostToolUseFailureHookInput:
+		toolInput = v.ToolInput
+	case *PermissionRequestHookInput:
+		toolInput = v.ToolInput
+	case PermissionRequestHookInput:
+		toolInput = v.ToolInput
+	case map[string]any:
+		toolInput = v["tool_input"]
+	}
+
+	if args, ok := toolInput.(map[string]any); ok {
+		if path, ok := args["file_path"].(string); ok {
+			return path
+		}
+	}
+	return ""
+}
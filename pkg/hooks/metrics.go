@@ -0,0 +1,136 @@
+package hooks
+
+import (
+	"cmp"
+	"reflect"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// hookRef identifies one hook within a matcher for messages and metrics.
+type hookRef struct {
+	id          string
+	name        string // command, webhook URL, or callback label
+	event       types.HookEvent
+	matcher     string
+	pathMatcher string
+	callback    string // Go function name, for callback hooks
+}
+
+func newHookRef(event types.HookEvent, matcher CallbackMatcher, id, name string) hookRef {
+	return hookRef{
+		id:          id,
+		name:        name,
+		event:       event,
+		matcher:     matcher.Matcher,
+		pathMatcher: matcher.PathMatcher,
+	}
+}
+
+// metricsKey is the identity hook runs are aggregated under.
+type metricsKey struct {
+	event       types.HookEvent
+	matcher     string
+	pathMatcher string
+	name        string
+	callback    string
+}
+
+type hookStats struct {
+	calls  int
+	errors int
+	total  time.Duration
+	max    time.Duration
+}
+
+// callbackName returns the Go function name of a callback, e.g.
+// "main.formatHook" or "main.main.func1" for a closure.
+func callbackName(hook HookCallback) string {
+	if fn := runtime.FuncForPC(reflect.ValueOf(hook).Pointer()); fn != nil {
+		return fn.Name()
+	}
+	return ""
+}
+
+// finishHook records a completed hook run and emits its HookResponseMessage.
+func (r *Runner) finishHook(ref hookRef, start time.Time, outcome string) {
+	duration := time.Since(start)
+	r.recordHook(ref, duration, outcome == "error")
+	r.emitHookResponse(ref.id, ref.name, ref.event, "", "", outcome, duration)
+}
+
+func (r *Runner) recordHook(ref hookRef, duration time.Duration, failed bool) {
+	key := metricsKey{
+		event:       ref.event,
+		matcher:     ref.matcher,
+		pathMatcher: ref.pathMatcher,
+		name:        ref.name,
+		callback:    ref.callback,
+	}
+
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+	if r.metrics == nil {
+		r.metrics = make(map[metricsKey]*hookStats)
+	}
+	stats, ok := r.metrics[key]
+	if !ok {
+		stats = &hookStats{}
+		r.metrics[key] = stats
+	}
+	stats.calls++
+	if failed {
+		stats.errors++
+	}
+	stats.total += duration
+	stats.max = max(stats.max, duration)
+}
+
+// Metrics returns per-hook timing and outcome totals since the runner was
+// created, slowest (by total time) first.
+func (r *Runner) Metrics() []types.HookMetric {
+	r.metricsMu.Lock()
+	defer r.metricsMu.Unlock()
+
+	metrics := make([]types.HookMetric, 0, len(r.metrics))
+	for key, stats := range r.metrics {
+		metrics = append(metrics, types.HookMetric{
+			HookEvent:   string(key.event),
+			Matcher:     key.matcher,
+			PathMatcher: key.pathMatcher,
+			HookName:    key.name,
+			Callback:    key.callback,
+			Calls:       stats.calls,
+			Errors:      stats.errors,
+			ErrorRate:   float64(stats.errors) / float64(stats.calls),
+			TotalMs:     stats.total.Milliseconds(),
+			MaxMs:       stats.max.Milliseconds(),
+		})
+	}
+	slices.SortFunc(metrics, func(a, b types.HookMetric) int {
+		return cmp.Or(
+			cmp.Compare(b.TotalMs, a.TotalMs),
+			cmp.Compare(a.HookEvent, b.HookEvent),
+			cmp.Compare(a.HookName, b.HookName),
+		)
+	})
+	return metrics
+}
+
+// EmitMetrics sends a HookMetricsMessage with the current Metrics on the
+// runner's EmitChannel, if one is configured.
+func (r *Runner) EmitMetrics() {
+	if r.emitCh == nil {
+		return
+	}
+	r.emitCh <- &types.HookMetricsMessage{
+		BaseMessage: types.BaseMessage{UUID: uuid.New(), SessionID: r.sessionID},
+		Type:        types.MessageTypeSystem,
+		Subtype:     types.SystemSubtypeHookMetrics,
+		Hooks:       r.Metrics(),
+	}
+}
@@ -17,6 +17,9 @@ type RunnerConfig struct {
 	EmitChannel chan<- types.SDKMessage // optional: emit hook lifecycle messages
 	SessionID   string
 	CWD         string
+
+	// MaxAsyncHooks bounds how many async hooks run at once (0 = default 4).
+	MaxAsyncHooks int
 }
 
 // Runner manages hook registration and execution.
@@ -29,6 +32,14 @@ type Runner struct {
 
 	mu          sync.RWMutex
 	scopedHooks map[string]map[types.HookEvent][]CallbackMatcher // scopeID → event → matchers
+
+	asyncSem     chan struct{} // worker slots for async hooks
+	asyncMu      sync.Mutex
+	asyncPending int           // async hooks started but not finished
+	asyncIdle    chan struct{} // closed when asyncPending drops to zero
+
+	metricsMu sync.Mutex
+	metrics   map[metricsKey]*hookStats
 }
 
 // NewRunner creates a Runner from configuration.
@@ -37,11 +48,16 @@ func NewRunner(config RunnerConfig) *Runner {
 	if hooks == nil {
 		hooks = make(map[types.HookEvent][]CallbackMatcher)
 	}
+	maxAsync := config.MaxAsyncHooks
+	if maxAsync <= 0 {
+		maxAsync = defaultMaxAsyncHooks
+	}
 	return &Runner{
 		hooks:     hooks,
 		emitCh:    config.EmitChannel,
 		sessionID: config.SessionID,
 		cwd:       config.CWD,
+		asyncSem:  make(chan struct{}, maxAsync),
 	}
 }
 
@@ -79,6 +95,9 @@ func (r *Runner) Fire(ctx context.Context, event types.HookEvent, input any) ([]
 		if matcher.Matcher != "" && !matchToolName(matcher.Matcher, input) {
 			continue
 		}
+		if matcher.PathMatcher != "" && !matchFilePath(matcher.PathMatcher, r.cwd, input) {
+			continue
+		}
 
 		// Apply timeout for this matcher
 		hookCtx := ctx
@@ -89,13 +108,19 @@ func (r *Runner) Fire(ctx context.Context, event types.HookEvent, input any) ([]
 		}
 
 		// Run Go function callbacks
-		stop := r.executeCallbacks(hookCtx, event, matcher.Hooks, input, &results)
+		stop := r.executeCallbacks(hookCtx, event, matcher, input, &results)
 		if stop {
 			return results, nil
 		}
 
 		// Run shell command hooks
-		stop = r.executeShellCommands(hookCtx, event, matcher.Commands, input, &results)
+		stop = r.executeShellCommands(hookCtx, event, matcher, input, &results)
+		if stop {
+			return results, nil
+		}
+
+		// Run webhook hooks
+		stop = r.executeWebhooks(hookCtx, event, matcher, input, &results)
 		if stop {
 			return results, nil
 		}
@@ -104,32 +129,29 @@ func (r *Runner) Fire(ctx context.Context, event types.HookEvent, input any) ([]
 	return results, nil
 }
 
-// executeCallbacks runs Go function callbacks sequentially, appending results.
+// executeCallbacks runs the matcher's Go function callbacks sequentially, appending results.
 // Returns true if processing should stop (continue=false).
-func (r *Runner) executeCallbacks(ctx context.Context, event types.HookEvent, callbacks []HookCallback, input any, results *[]agent.HookResult) bool {
-	for i, hook := range callbacks {
-		hookID := fmt.Sprintf("%s-go-%d", event, i)
-		hookName := fmt.Sprintf("%s callback %d", event, i)
+func (r *Runner) executeCallbacks(ctx context.Context, event types.HookEvent, matcher CallbackMatcher, input any, results *[]agent.HookResult) bool {
+	for i, hook := range matcher.Hooks {
+		ref := newHookRef(event, matcher, fmt.Sprintf("%s-go-%d", event, i), fmt.Sprintf("%s callback %d", event, i))
+		ref.callback = callbackName(hook)
 
-		r.emitHookStarted(hookID, hookName, event)
+		r.emitHookStarted(ref.id, ref.name, event)
+		start := time.Now()
 
 		output, err := hook(input, "", ctx)
 		if err != nil {
-			r.emitHookResponse(hookID, hookName, event, "", "", "error")
+			r.finishHook(ref, start, "error")
 			continue
 		}
 
-		// Handle async hooks: re-execute with async timeout
+		// Handle async hooks: finish in the background without a result
 		if output.Async != nil && output.Async.Async {
-			asyncOutput, asyncErr := executeAsync(ctx, hook, input, output.Async.AsyncTimeout)
-			if asyncErr != nil {
-				r.emitHookResponse(hookID, hookName, event, "", "", "error")
-				continue
-			}
-			output = asyncOutput
+			r.startAsync(ctx, ref, hook, input, output.Async.AsyncTimeout)
+			continue
 		}
 
-		r.emitHookResponse(hookID, hookName, event, "", "", "success")
+		r.finishHook(ref, start, "success")
 
 		result := convertOutput(output)
 		*results = append(*results, result)
@@ -141,38 +163,68 @@ func (r *Runner) executeCallbacks(ctx context.Context, event types.HookEvent, ca
 	return false
 }
 
-// executeShellCommands runs shell command hooks sequentially, appending results.
+// executeShellCommands runs the matcher's shell command hooks sequentially, appending results.
 // Returns true if processing should stop (continue=false).
-func (r *Runner) executeShellCommands(ctx context.Context, event types.HookEvent, commands []string, input any, results *[]agent.HookResult) bool {
-	for i, command := range commands {
-		hookID := fmt.Sprintf("%s-shell-%d", event, i)
-		hookName := command
+func (r *Runner) executeShellCommands(ctx context.Context, event types.HookEvent, matcher CallbackMatcher, input any, results *[]agent.HookResult) bool {
+	for i, command := range matcher.Commands {
+		ref := newHookRef(event, matcher, fmt.Sprintf("%s-shell-%d", event, i), command)
 
-		r.emitHookStarted(hookID, hookName, event)
+		r.emitHookStarted(ref.id, ref.name, event)
+		start := time.Now()
 
 		shellCB := ShellHookCallbackWithProgress(command, func(stdout, stderr string) {
-			r.emitHookProgress(hookID, hookName, event, stdout, stderr)
+			r.emitHookProgress(ref.id, ref.name, event, stdout, stderr)
 		})
 		output, err := shellCB(input, "", ctx)
 		if err != nil {
-			r.emitHookResponse(hookID, hookName, event, "", "", "error")
+			r.finishHook(ref, start, "error")
 			continue
 		}
 
-		// Handle async hooks: re-execute with async timeout
+		// Handle async hooks: finish in the background without a result
 		if output.Async != nil && output.Async.Async {
 			asyncCB := ShellHookCallbackWithProgress(command, func(stdout, stderr string) {
-				r.emitHookProgress(hookID, hookName, event, stdout, stderr)
+				r.emitHookProgress(ref.id, ref.name, event, stdout, stderr)
 			})
-			asyncOutput, asyncErr := executeAsync(ctx, asyncCB, input, output.Async.AsyncTimeout)
-			if asyncErr != nil {
-				r.emitHookResponse(hookID, hookName, event, "", "", "error")
-				continue
-			}
-			output = asyncOutput
+			r.startAsync(ctx, ref, asyncCB, input, output.Async.AsyncTimeout)
+			continue
+		}
+
+		r.finishHook(ref, start, "success")
+
+		result := convertOutput(output)
+		*results = append(*results, result)
+
+		if result.Continue != nil && !*result.Continue {
+			return true
+		}
+	}
+	return false
+}
+
+// executeWebhooks delivers the event to each of the matcher's webhooks sequentially, appending results.
+// Returns true if processing should stop (continue=false).
+func (r *Runner) executeWebhooks(ctx context.Context, event types.HookEvent, matcher CallbackMatcher, input any, results *[]agent.HookResult) bool {
+	for i, spec := range matcher.Webhooks {
+		ref := newHookRef(event, matcher, fmt.Sprintf("%s-webhook-%d", event, i), spec.URL)
+
+		r.emitHookStarted(ref.id, ref.name, event)
+		start := time.Now()
+
+		webhookCB := WebhookHookCallback(spec)
+		output, err := webhookCB(input, "", ctx)
+		if err != nil {
+			r.finishHook(ref, start, "error")
+			continue
+		}
+
+		// Handle async hooks: finish in the background without a result
+		if output.Async != nil && output.Async.Async {
+			r.startAsync(ctx, ref, webhookCB, input, output.Async.AsyncTimeout)
+			continue
 		}
 
-		r.emitHookResponse(hookID, hookName, event, "", "", "success")
+		r.finishHook(ref, start, "success")
 
 		result := convertOutput(output)
 		*results = append(*results, result)
@@ -217,7 +269,7 @@ func (r *Runner) emitHookProgress(hookID, hookName string, event types.HookEvent
 	}
 }
 
-func (r *Runner) emitHookResponse(hookID, hookName string, event types.HookEvent, stdout, stderr, outcome string) {
+func (r *Runner) emitHookResponse(hookID, hookName string, event types.HookEvent, stdout, stderr, outcome string, duration time.Duration) {
 	if r.emitCh == nil {
 		return
 	}
@@ -231,6 +283,7 @@ func (r *Runner) emitHookResponse(hookID, hookName string, event types.HookEvent
 		Stdout:      stdout,
 		Stderr:      stderr,
 		Outcome:     outcome,
+		DurationMs:  duration.Milliseconds(),
 	}
 }
 
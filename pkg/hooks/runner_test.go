@@ -4,9 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -670,6 +674,61 @@ func TestMatchToolName_TypedInput(t *testing.T) {
 	}
 }
 
+func TestMatchFilePath(t *testing.T) {
+	goWrite := map[string]any{"tool_name": "Write", "tool_input": map[string]any{"file_path": "/repo/pkg/a.go"}}
+	mdWrite := map[string]any{"tool_name": "Write", "tool_input": map[string]any{"file_path": "/repo/README.md"}}
+
+	if !matchFilePath("**/*.go", "", goWrite) {
+		t.Error("**/*.go should match a Go file")
+	}
+	if matchFilePath("**/*.go", "", mdWrite) {
+		t.Error("**/*.go should not match a Markdown file")
+	}
+	if !matchFilePath("*.go", "", goWrite) {
+		t.Error("a pattern without a separator should match the base name")
+	}
+	if !matchFilePath("pkg/**", "/repo", goWrite) || matchFilePath("pkg/**", "/repo", mdWrite) {
+		t.Error("patterns should match paths relative to cwd")
+	}
+	if !matchFilePath("**/*.go", "", &PostToolUseHookInput{ToolInput: map[string]any{"file_path": "main.go"}}) {
+		t.Error("should match typed struct")
+	}
+	if !matchFilePath("**/*.go", "", map[string]any{"tool_name": "Bash", "tool_input": map[string]any{"command": "ls"}}) {
+		t.Error("no file_path in input should match (no filter possible)")
+	}
+}
+
+func TestRunner_PathMatcher(t *testing.T) {
+	callCount := 0
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPostToolUse: {
+				{
+					Matcher:     "Write",
+					PathMatcher: "**/*.go",
+					Hooks: []HookCallback{func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+						callCount++
+						return HookJSONOutput{Sync: &SyncHookJSONOutput{}}, nil
+					}},
+				},
+			},
+		},
+	})
+
+	fire := func(tool, path string) {
+		r.Fire(context.Background(), types.HookEventPostToolUse, &PostToolUseHookInput{
+			ToolName:  tool,
+			ToolInput: map[string]any{"file_path": path},
+		})
+	}
+	fire("Write", "/repo/main.go")
+	fire("Write", "/repo/notes.txt") // path doesn't match
+	fire("Edit", "/repo/main.go")    // tool doesn't match
+	if callCount != 1 {
+		t.Errorf("expected 1 call (both matchers must match), got %d", callCount)
+	}
+}
+
 // --- Test Parity: Hook Event-Specific Tests (ported from Python Agent SDK) ---
 
 func TestRunner_NotificationEvent(t *testing.T) {
@@ -1097,19 +1156,22 @@ func TestRunner_MultipleScopedHooks(t *testing.T) {
 // --- Phase 4 Tests: Async, Progress, Interrupt ---
 
 func TestRunner_AsyncCallbackExecution(t *testing.T) {
-	// A Go callback that returns async first, then completes with a sync result
-	callCount := 0
+	// A Go callback that returns async first, then finishes in the background
+	var callCount atomic.Int32
+	release := make(chan struct{})
+	done := make(chan string, 1)
 	r := NewRunner(RunnerConfig{
 		Hooks: map[types.HookEvent][]CallbackMatcher{
 			types.HookEventPreToolUse: {
 				{Hooks: []HookCallback{func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
-					callCount++
-					if callCount == 1 {
+					if callCount.Add(1) == 1 {
 						// First call: return async signal
 						return HookJSONOutput{Async: &AsyncHookJSONOutput{Async: true, AsyncTimeout: 5}}, nil
 					}
-					// Second call (executeAsync): return sync result
-					return HookJSONOutput{Sync: &SyncHookJSONOutput{Decision: "approve", Reason: "async done"}}, nil
+					// Second call (background): block until released
+					<-release
+					done <- "logged"
+					return HookJSONOutput{Sync: &SyncHookJSONOutput{Decision: "approve"}}, nil
 				}}},
 			},
 		},
@@ -1119,17 +1181,20 @@ func TestRunner_AsyncCallbackExecution(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if callCount != 2 {
-		t.Errorf("expected 2 calls (initial + async re-execute), got %d", callCount)
+	// Fire returns without waiting, and async work contributes no result
+	if len(results) != 0 {
+		t.Fatalf("expected 0 results, got %d", len(results))
 	}
-	if len(results) != 1 {
-		t.Fatalf("expected 1 result, got %d", len(results))
+
+	close(release)
+	if err := r.DrainAsync(5 * time.Second); err != nil {
+		t.Fatalf("DrainAsync: %v", err)
 	}
-	if results[0].Decision != "allow" {
-		t.Errorf("decision = %q, want 'allow' (mapped from 'approve')", results[0].Decision)
+	if callCount.Load() != 2 {
+		t.Errorf("expected 2 calls (initial + async re-execute), got %d", callCount.Load())
 	}
-	if results[0].Reason != "async done" {
-		t.Errorf("reason = %q, want 'async done'", results[0].Reason)
+	if got := <-done; got != "logged" {
+		t.Errorf("async work = %q", got)
 	}
 }
 
@@ -1141,15 +1206,17 @@ func TestRunner_AsyncShellExecution(t *testing.T) {
 	dir := t.TempDir()
 	script := filepath.Join(dir, "async_hook.sh")
 	stateFile := filepath.Join(dir, "state")
+	doneFile := filepath.Join(dir, "done")
 	os.WriteFile(script, []byte(fmt.Sprintf(`#!/bin/sh
 cat > /dev/null
 if [ ! -f %s ]; then
   touch %s
   echo '{"async": true, "asyncTimeout": 5}'
 else
+  touch %s
   echo '{"decision":"approve","reason":"async completed"}'
 fi
-`, stateFile, stateFile)), 0o755)
+`, stateFile, stateFile, doneFile)), 0o755)
 
 	r := NewRunner(RunnerConfig{
 		Hooks: map[types.HookEvent][]CallbackMatcher{
@@ -1163,14 +1230,14 @@ fi
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(results) != 1 {
-		t.Fatalf("expected 1 result, got %d", len(results))
+	if len(results) != 0 {
+		t.Fatalf("expected 0 results, got %d", len(results))
 	}
-	if results[0].Decision != "allow" {
-		t.Errorf("decision = %q, want 'allow' (mapped from 'approve')", results[0].Decision)
+	if err := r.DrainAsync(5 * time.Second); err != nil {
+		t.Fatalf("DrainAsync: %v", err)
 	}
-	if results[0].Reason != "async completed" {
-		t.Errorf("reason = %q, want 'async completed'", results[0].Reason)
+	if _, err := os.Stat(doneFile); err != nil {
+		t.Errorf("async shell hook did not run to completion: %v", err)
 	}
 }
 
@@ -1222,6 +1289,7 @@ echo '{"decision":"approve"}'
 
 func TestRunner_AsyncCallbackError(t *testing.T) {
 	// Async callback that fails on re-execute
+	ch := make(chan types.SDKMessage, 100)
 	callCount := 0
 	r := NewRunner(RunnerConfig{
 		Hooks: map[types.HookEvent][]CallbackMatcher{
@@ -1235,6 +1303,7 @@ func TestRunner_AsyncCallbackError(t *testing.T) {
 				}}},
 			},
 		},
+		EmitChannel: ch,
 	})
 
 	results, err := r.Fire(context.Background(), types.HookEventPreToolUse, nil)
@@ -1245,6 +1314,94 @@ func TestRunner_AsyncCallbackError(t *testing.T) {
 	if len(results) != 0 {
 		t.Errorf("expected 0 results (async failed), got %d", len(results))
 	}
+	if err := r.DrainAsync(5 * time.Second); err != nil {
+		t.Fatalf("DrainAsync: %v", err)
+	}
+
+	close(ch)
+	var outcome string
+	for msg := range ch {
+		if resp, ok := msg.(*types.HookResponseMessage); ok {
+			outcome = resp.Outcome
+		}
+	}
+	if outcome != "error" {
+		t.Errorf("async outcome = %q, want error", outcome)
+	}
+}
+
+func TestRunner_AsyncDetachedFromFireContext(t *testing.T) {
+	var callCount atomic.Int32
+	finished := make(chan error, 1)
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPostToolUse: {
+				{Hooks: []HookCallback{func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+					if callCount.Add(1) == 1 {
+						return HookJSONOutput{Async: &AsyncHookJSONOutput{Async: true, AsyncTimeout: 5}}, nil
+					}
+					time.Sleep(20 * time.Millisecond)
+					finished <- ctx.Err()
+					return HookJSONOutput{}, nil
+				}}},
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Fire(ctx, types.HookEventPostToolUse, nil)
+	cancel() // the turn ends; the async hook keeps going
+
+	if err := r.DrainAsync(5 * time.Second); err != nil {
+		t.Fatalf("DrainAsync: %v", err)
+	}
+	if err := <-finished; err != nil {
+		t.Errorf("async hook context = %v, want it detached from Fire's cancellation", err)
+	}
+}
+
+func TestRunner_AsyncWorkerPoolBounded(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	hook := func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return HookJSONOutput{}, nil
+	}
+	r := NewRunner(RunnerConfig{MaxAsyncHooks: 2})
+
+	for i := 0; i < 6; i++ {
+		r.startAsync(context.Background(), hookRef{id: "id", name: "name", event: types.HookEventPostToolUse}, hook, nil, 5)
+	}
+	if err := r.DrainAsync(5 * time.Second); err != nil {
+		t.Fatalf("DrainAsync: %v", err)
+	}
+	if got := maxRunning.Load(); got != 2 {
+		t.Errorf("max concurrent async hooks = %d, want 2", got)
+	}
+}
+
+func TestRunner_DrainAsyncTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := NewRunner(RunnerConfig{})
+	r.startAsync(context.Background(), hookRef{id: "id", name: "name", event: types.HookEventPostToolUse}, func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+		<-release
+		return HookJSONOutput{}, nil
+	}, nil, 5)
+
+	if err := r.DrainAsync(20 * time.Millisecond); err == nil {
+		t.Error("expected an error while an async hook is still running")
+	}
+	if err := NewRunner(RunnerConfig{}).DrainAsync(0); err != nil {
+		t.Errorf("DrainAsync with nothing pending: %v", err)
+	}
 }
 
 func TestRunner_EmitChannelError(t *testing.T) {
@@ -1282,3 +1439,186 @@ func TestRunner_EmitChannelError(t *testing.T) {
 		t.Errorf("response.Outcome = %s, want error", response.Outcome)
 	}
 }
+
+func TestRunner_WebhookDecision(t *testing.T) {
+	var gotBody map[string]any
+	var gotHeader, gotMethod string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotMethod = req.Method
+		gotHeader = req.Header.Get("Authorization")
+		json.NewDecoder(req.Body).Decode(&gotBody)
+		w.Write([]byte(`{"decision":"block","reason":"not on this host"}`))
+	}))
+	defer srv.Close()
+
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPreToolUse: {
+				{Webhooks: []WebhookSpec{{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer t"}}}},
+			},
+		},
+	})
+
+	results, err := r.Fire(context.Background(), types.HookEventPreToolUse, map[string]any{"tool_name": "Bash"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Decision != "deny" || results[0].Reason != "not on this host" {
+		t.Fatalf("results = %+v, want a deny decision from the webhook", results)
+	}
+	if gotMethod != http.MethodPost || gotHeader != "Bearer t" {
+		t.Errorf("method = %q, Authorization = %q", gotMethod, gotHeader)
+	}
+	if gotBody["tool_name"] != "Bash" {
+		t.Errorf("payload = %v, want the hook input", gotBody)
+	}
+}
+
+func TestRunner_WebhookNonJSONBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPostToolUse: {{Webhooks: []WebhookSpec{{URL: srv.URL}}}},
+		},
+	})
+
+	results, _ := r.Fire(context.Background(), types.HookEventPostToolUse, map[string]any{})
+	if len(results) != 1 || results[0].Decision != "" {
+		t.Errorf("results = %+v, want one no-op result", results)
+	}
+}
+
+func TestRunner_WebhookError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		calls.Add(1)
+		http.Error(w, "bad event", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPreToolUse: {
+				{Webhooks: []WebhookSpec{{URL: srv.URL, Retries: 2}}},
+			},
+		},
+	})
+
+	results, err := r.Fire(context.Background(), types.HookEventPreToolUse, map[string]any{})
+	if err != nil {
+		t.Fatalf("unexpected error (should be isolated): %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected 0 results from errored webhook, got %d", len(results))
+	}
+	if calls.Load() != 1 {
+		t.Errorf("calls = %d, client errors should not be retried", calls.Load())
+	}
+}
+
+func TestRunner_WebhookRetry(t *testing.T) {
+	defer func(d time.Duration) { webhookRetryDelay = d }(webhookRetryDelay)
+	webhookRetryDelay = time.Millisecond
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"decision":"approve"}`))
+	}))
+	defer srv.Close()
+
+	hook := WebhookHookCallback(WebhookSpec{URL: srv.URL, Retries: 2})
+	output, err := hook(map[string]any{}, "", context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output.Sync == nil || output.Sync.Decision != "approve" {
+		t.Errorf("output = %+v, want the final approve", output)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+
+	calls.Store(-10)
+	if _, err := WebhookHookCallback(WebhookSpec{URL: srv.URL, Retries: 1})(map[string]any{}, "", context.Background()); err == nil {
+		t.Error("expected an error once retries are used up")
+	}
+}
+
+func slowApproveHook(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+	time.Sleep(15 * time.Millisecond)
+	return HookJSONOutput{Sync: &SyncHookJSONOutput{Decision: "approve"}}, nil
+}
+
+func TestRunner_HookResponseDuration(t *testing.T) {
+	ch := make(chan types.SDKMessage, 10)
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPreToolUse: {{Hooks: []HookCallback{slowApproveHook}}},
+		},
+		EmitChannel: ch,
+	})
+
+	r.Fire(context.Background(), types.HookEventPreToolUse, map[string]any{"tool_name": "Bash"})
+	close(ch)
+	for msg := range ch {
+		if resp, ok := msg.(*types.HookResponseMessage); ok && resp.DurationMs < 15 {
+			t.Errorf("DurationMs = %d, want >= 15", resp.DurationMs)
+		}
+	}
+}
+
+func TestRunner_Metrics(t *testing.T) {
+	ch := make(chan types.SDKMessage, 20)
+	r := NewRunner(RunnerConfig{
+		Hooks: map[types.HookEvent][]CallbackMatcher{
+			types.HookEventPreToolUse: {
+				{Matcher: "Bash", Hooks: []HookCallback{slowApproveHook}},
+				{Matcher: "Bash", Hooks: []HookCallback{func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+					return HookJSONOutput{}, fmt.Errorf("hook failed")
+				}}},
+			},
+		},
+		EmitChannel: ch,
+	})
+
+	for range 2 {
+		r.Fire(context.Background(), types.HookEventPreToolUse, map[string]any{"tool_name": "Bash"})
+	}
+
+	metrics := r.Metrics()
+	if len(metrics) != 2 {
+		t.Fatalf("got %d metrics, want one per hook: %+v", len(metrics), metrics)
+	}
+	slow := metrics[0]
+	if !strings.HasSuffix(slow.Callback, "slowApproveHook") {
+		t.Errorf("slowest hook = %+v, want slowApproveHook first", slow)
+	}
+	if slow.Calls != 2 || slow.Errors != 0 || slow.TotalMs < 30 || slow.Matcher != "Bash" || slow.HookEvent != "PreToolUse" {
+		t.Errorf("slow hook metric = %+v", slow)
+	}
+	if failing := metrics[1]; failing.Calls != 2 || failing.Errors != 2 || failing.ErrorRate != 1 {
+		t.Errorf("failing hook metric = %+v", failing)
+	}
+
+	if err := r.DrainAsync(time.Second); err != nil {
+		t.Fatalf("DrainAsync: %v", err)
+	}
+	close(ch)
+	var summary *types.HookMetricsMessage
+	for msg := range ch {
+		if m, ok := msg.(*types.HookMetricsMessage); ok {
+			summary = m
+		}
+	}
+	if summary == nil || len(summary.Hooks) != 2 {
+		t.Fatalf("expected a HookMetricsMessage from DrainAsync, got %+v", summary)
+	}
+}
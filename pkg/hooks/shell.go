@@ -94,25 +94,30 @@ func ShellHookCallbackWithProgress(command string, onProgress ProgressFunc) Hook
 			return HookJSONOutput{Sync: &SyncHookJSONOutput{}}, nil
 		}
 
-		// Try parsing as async first (check for "async" field)
-		var raw map[string]json.RawMessage
-		if err := json.Unmarshal(outBytes, &raw); err != nil {
-			return HookJSONOutput{}, err
-		}
+		return parseHookOutput(outBytes)
+	}
+}
 
-		if _, hasAsync := raw["async"]; hasAsync {
-			var asyncOut AsyncHookJSONOutput
-			if err := json.Unmarshal(outBytes, &asyncOut); err == nil && asyncOut.Async {
-				return HookJSONOutput{Async: &asyncOut}, nil
-			}
-		}
+// parseHookOutput decodes a hook's JSON reply as an async signal or a sync result.
+func parseHookOutput(outBytes []byte) (HookJSONOutput, error) {
+	// Try parsing as async first (check for "async" field)
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(outBytes, &raw); err != nil {
+		return HookJSONOutput{}, err
+	}
 
-		// Parse as sync
-		var syncOut SyncHookJSONOutput
-		if err := json.Unmarshal(outBytes, &syncOut); err != nil {
-			return HookJSONOutput{}, err
+	if _, hasAsync := raw["async"]; hasAsync {
+		var asyncOut AsyncHookJSONOutput
+		if err := json.Unmarshal(outBytes, &asyncOut); err == nil && asyncOut.Async {
+			return HookJSONOutput{Async: &asyncOut}, nil
 		}
+	}
 
-		return HookJSONOutput{Sync: &syncOut}, nil
+	// Parse as sync
+	var syncOut SyncHookJSONOutput
+	if err := json.Unmarshal(outBytes, &syncOut); err != nil {
+		return HookJSONOutput{}, err
 	}
+
+	return HookJSONOutput{Sync: &syncOut}, nil
 }
@@ -180,6 +180,7 @@ type PostToolUseSpecificOutput struct {
 	HookEventName        string `json:"hookEventName"`
 	AdditionalContext    string `json:"additionalContext,omitempty"`
 	UpdatedMCPToolOutput any    `json:"updatedMCPToolOutput,omitempty"`
+	UpdatedToolOutput    any    `json:"updatedToolOutput,omitempty"` // replaces any tool's output; non-strings are sent as JSON
 }
 
 // PostToolUseFailureSpecificOutput is the hook-specific output for PostToolUseFailure.
@@ -214,16 +215,19 @@ type NotificationSpecificOutput struct {
 
 // UserPromptSubmitSpecificOutput is the hook-specific output for UserPromptSubmit.
 type UserPromptSubmitSpecificOutput struct {
-	HookEventName string `json:"hookEventName"`
+	HookEventName     string `json:"hookEventName"`
+	AdditionalContext string `json:"additionalContext,omitempty"`
 }
 
 // HookCallback is the Go function type for hook implementations.
 type HookCallback func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error)
 
-// CallbackMatcher groups callbacks with an optional tool name matcher and timeout.
+// CallbackMatcher groups callbacks with optional tool name and file path matchers and a timeout.
 type CallbackMatcher struct {
-	Matcher  string         // tool name pattern (glob or exact), empty = match all
-	Hooks    []HookCallback // Go function callbacks
-	Commands []string       // shell command hooks
-	Timeout  int            // seconds, 0 = no timeout
+	Matcher     string         // tool name pattern (glob or exact), empty = match all
+	PathMatcher string         // file_path glob ("**/*.go"), empty = match all; both matchers must match
+	Hooks       []HookCallback // Go function callbacks
+	Commands    []string       // shell command hooks
+	Webhooks    []WebhookSpec  // HTTP endpoints that receive the event as JSON
+	Timeout     int            // seconds, 0 = no timeout
 }
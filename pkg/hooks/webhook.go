@@ -0,0 +1,111 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultWebhookTimeout is the per-request timeout for webhooks (10 seconds).
+const defaultWebhookTimeout = 10
+
+// webhookRetryDelay is the pause before the first retry; it doubles per attempt.
+var webhookRetryDelay = 500 * time.Millisecond
+
+// WebhookSpec describes an HTTP endpoint that receives hook events.
+type WebhookSpec struct {
+	URL     string
+	Method  string            // default POST
+	Headers map[string]string // extra request headers
+	Timeout int               // seconds per request, 0 = default 10
+	Retries int               // extra attempts on network errors and 5xx responses
+}
+
+// WebhookHookCallback creates a HookCallback that delivers the hook input to
+// spec.URL as a JSON request body, the same payload shell hooks receive on
+// stdin. A JSON response body is parsed as HookJSONOutput; an empty or
+// non-JSON body is a no-op result. Non-2xx responses are errors.
+func WebhookHookCallback(spec WebhookSpec) HookCallback {
+	return func(input any, toolUseID string, ctx context.Context) (HookJSONOutput, error) {
+		body, err := json.Marshal(input)
+		if err != nil {
+			return HookJSONOutput{}, err
+		}
+
+		delay := webhookRetryDelay
+		for attempt := 0; ; attempt++ {
+			respBody, retryable, err := postWebhook(ctx, spec, body)
+			if err == nil {
+				return webhookOutput(respBody), nil
+			}
+			if !retryable || attempt >= spec.Retries {
+				return HookJSONOutput{}, err
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return HookJSONOutput{}, ctx.Err()
+			}
+			delay *= 2
+		}
+	}
+}
+
+// postWebhook sends one request. The returned bool reports whether a failure
+// is worth retrying.
+func postWebhook(ctx context.Context, spec WebhookSpec, body []byte) ([]byte, bool, error) {
+	timeout := spec.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	method := spec.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(reqCtx, method, spec.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range spec.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, resp.StatusCode >= 500, fmt.Errorf("webhook %s returned %s: %s",
+			spec.URL, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, false, nil
+}
+
+// webhookOutput parses a response body, treating anything but a JSON object
+// as a no-op sync result.
+func webhookOutput(body []byte) HookJSONOutput {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return HookJSONOutput{Sync: &SyncHookJSONOutput{}}
+	}
+	output, err := parseHookOutput(body)
+	if err != nil {
+		return HookJSONOutput{Sync: &SyncHookJSONOutput{}}
+	}
+	return output
+}
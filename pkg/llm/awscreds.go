@@ -0,0 +1,546 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentials are the keys BedrockClient signs requests with.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string    // for temporary credentials
+	Expires         time.Time // zero for credentials that don't expire
+}
+
+// AWSCredentialsProvider supplies AWS credentials. Retrieve is called for
+// every request, so providers of temporary credentials should cache them.
+type AWSCredentialsProvider interface {
+	Retrieve(ctx context.Context) (AWSCredentials, error)
+}
+
+// StaticAWSCredentials returns a provider of fixed credentials.
+func StaticAWSCredentials(accessKeyID, secretAccessKey, sessionToken string) AWSCredentialsProvider {
+	return staticAWSCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken}
+}
+
+type staticAWSCredentials AWSCredentials
+
+func (s staticAWSCredentials) Retrieve(context.Context) (AWSCredentials, error) {
+	return AWSCredentials(s), nil
+}
+
+// errNoAWSCredentials is returned when no source in the default chain has
+// credentials.
+var errNoAWSCredentials = errors.New("llm: no AWS credentials found: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, " +
+	"configure a profile in ~/.aws, or run with a web identity, task or instance role")
+
+const (
+	awsCredentialsRefreshWindow = 5 * time.Minute // refresh temporary credentials this long before they expire
+	awsMetadataTimeout          = 2 * time.Second // container and instance metadata are local; fail fast elsewhere
+	awsContainerHost            = "http://169.254.170.2"
+	awsIMDSEndpoint             = "http://169.254.169.254"
+)
+
+// DefaultAWSCredentials returns the standard AWS credential chain, tried in
+// order until one source has credentials:
+//
+//   - AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//   - the AWS_PROFILE (default "default") profile in the shared credentials
+//     and config files (~/.aws/credentials, ~/.aws/config): its static keys,
+//     a role_arn assumed with the credentials of its source_profile, or its
+//     credential_process. SSO profiles and credential_source are not
+//     supported; Retrieve reports the profile keys it cannot use.
+//   - a web identity token (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN,
+//     as on EKS) exchanged with STS
+//   - the ECS/EKS container credentials endpoint
+//     (AWS_CONTAINER_CREDENTIALS_RELATIVE_URI or _FULL_URI)
+//   - the EC2 instance metadata service (IMDSv2), unless
+//     AWS_EC2_METADATA_DISABLED is "true"
+//
+// Temporary credentials are cached until shortly before they expire. Once
+// the instance metadata service is found unreachable or without a role, it
+// is not asked again.
+func DefaultAWSCredentials() AWSCredentialsProvider {
+	return &awsCredentialChain{httpClient: &http.Client{Timeout: awsMetadataTimeout}}
+}
+
+type awsCredentialChain struct {
+	httpClient *http.Client
+	mu         sync.Mutex
+	cached     AWSCredentials
+	noIMDS     bool // the instance metadata service has no credentials for us
+}
+
+func (c *awsCredentialChain) Retrieve(ctx context.Context) (AWSCredentials, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached.AccessKeyID != "" && (c.cached.Expires.IsZero() || time.Until(c.cached.Expires) > awsCredentialsRefreshWindow) {
+		return c.cached, nil
+	}
+
+	sources := []func(context.Context) (AWSCredentials, bool, error){
+		envAWSCredentials,
+		c.sharedFileCredentials,
+		c.webIdentityCredentials,
+		c.containerCredentials,
+		c.instanceCredentials,
+	}
+	for _, source := range sources {
+		creds, ok, err := source(ctx)
+		if err != nil {
+			return AWSCredentials{}, err
+		}
+		if ok {
+			c.cached = creds
+			return creds, nil
+		}
+	}
+	return AWSCredentials{}, errNoAWSCredentials
+}
+
+func envAWSCredentials(context.Context) (AWSCredentials, bool, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     firstEnv("AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY"),
+		SecretAccessKey: firstEnv("AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	return creds, creds.AccessKeyID != "" && creds.SecretAccessKey != "", nil
+}
+
+// awsUnsupportedProfileKeys are shared config keys naming credential sources
+// the chain does not implement.
+var awsUnsupportedProfileKeys = []string{"sso_session", "sso_start_url", "sso_account_id", "sso_role_name", "credential_source", "web_identity_token_file"}
+
+// sharedFileCredentials resolves the AWS_PROFILE profile from the shared
+// credentials and config files.
+func (c *awsCredentialChain) sharedFileCredentials(ctx context.Context) (AWSCredentials, bool, error) {
+	return c.profileCredentials(ctx, awsProfile(), nil)
+}
+
+// profileCredentials resolves profile: a role_arn is assumed with the
+// credentials of its source_profile, otherwise its static keys or its
+// credential_process are used. visited holds the profiles whose roles are
+// being resolved, to catch source_profile cycles.
+func (c *awsCredentialChain) profileCredentials(ctx context.Context, profile string, visited []string) (AWSCredentials, bool, error) {
+	sections := []map[string]string{
+		readAWSProfile(awsSharedFile("AWS_SHARED_CREDENTIALS_FILE", "credentials"), profile, false),
+		readAWSProfile(awsSharedFile("AWS_CONFIG_FILE", "config"), profile, true),
+	}
+	get := func(key string) string {
+		for _, section := range sections {
+			if v := section[key]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if roleARN := get("role_arn"); roleARN != "" && get("credential_source") == "" && get("web_identity_token_file") == "" {
+		source := get("source_profile")
+		if source == "" {
+			return AWSCredentials{}, false, fmt.Errorf("llm: AWS profile %q: role_arn requires source_profile", profile)
+		}
+		if slices.Contains(visited, profile) {
+			return AWSCredentials{}, false, fmt.Errorf("llm: AWS profile %q: source_profile cycle", profile)
+		}
+		var sourceCreds AWSCredentials
+		var ok bool
+		var err error
+		if source == profile {
+			sourceCreds, ok = staticProfileCredentials(sections)
+		} else {
+			sourceCreds, ok, err = c.profileCredentials(ctx, source, append(visited, profile))
+		}
+		if err != nil {
+			return AWSCredentials{}, false, err
+		}
+		if !ok {
+			return AWSCredentials{}, false, fmt.Errorf("llm: AWS profile %q: source profile %q has no credentials", profile, source)
+		}
+		creds, err := c.assumeRole(ctx, sourceCreds, roleARN, get("role_session_name"), get("external_id"), get("region"))
+		return creds, err == nil, err
+	}
+
+	if creds, ok := staticProfileCredentials(sections); ok {
+		return creds, true, nil
+	}
+	if command := get("credential_process"); command != "" {
+		creds, err := processAWSCredentials(ctx, profile, command)
+		return creds, err == nil, err
+	}
+
+	var unsupported []string
+	for _, key := range awsUnsupportedProfileKeys {
+		if get(key) != "" {
+			unsupported = append(unsupported, key)
+		}
+	}
+	if len(unsupported) > 0 {
+		return AWSCredentials{}, false, fmt.Errorf("llm: AWS profile %q uses unsupported credential settings (%s); "+
+			"export credentials with `aws configure export-credentials` or set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY",
+			profile, strings.Join(unsupported, ", "))
+	}
+	return AWSCredentials{}, false, nil
+}
+
+// staticProfileCredentials returns the first section's static keys.
+func staticProfileCredentials(sections []map[string]string) (AWSCredentials, bool) {
+	for _, section := range sections {
+		creds := AWSCredentials{
+			AccessKeyID:     section["aws_access_key_id"],
+			SecretAccessKey: section["aws_secret_access_key"],
+			SessionToken:    section["aws_session_token"],
+		}
+		if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+			return creds, true
+		}
+	}
+	return AWSCredentials{}, false
+}
+
+// processAWSCredentials runs a profile's credential_process command and
+// decodes the credentials it prints.
+func processAWSCredentials(ctx context.Context, profile, command string) (AWSCredentials, error) {
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("llm: AWS profile %q: credential_process: %w: %s", profile, err, strings.TrimSpace(stderr.String()))
+	}
+	var cr struct {
+		Version         int       `json:"Version"`
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		SessionToken    string    `json:"SessionToken"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal(out, &cr); err != nil {
+		return AWSCredentials{}, fmt.Errorf("llm: AWS profile %q: decode credential_process output: %w", profile, err)
+	}
+	if cr.Version != 1 || cr.AccessKeyID == "" || cr.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("llm: AWS profile %q: credential_process output is not version 1 credentials", profile)
+	}
+	return AWSCredentials{AccessKeyID: cr.AccessKeyID, SecretAccessKey: cr.SecretAccessKey, SessionToken: cr.SessionToken, Expires: cr.Expiration}, nil
+}
+
+// assumeRole exchanges source for temporary credentials for roleARN with a
+// SigV4-signed STS AssumeRole call.
+func (c *awsCredentialChain) assumeRole(ctx context.Context, source AWSCredentials, roleARN, sessionName, externalID, region string) (AWSCredentials, error) {
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("goat-%d", time.Now().Unix())
+	}
+	query := url.Values{
+		"Action":          {"AssumeRole"},
+		"Version":         {"2011-06-15"},
+		"RoleArn":         {roleARN},
+		"RoleSessionName": {sessionName},
+	}
+	if externalID != "" {
+		query.Set("ExternalId", externalID)
+	}
+	endpoint, signingRegion := awsSTSEndpoint(region)
+	payload := []byte(query.Encode())
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(string(payload)))
+	if err != nil {
+		return AWSCredentials{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	signSigV4(req, payload, source, signingRegion, "sts", time.Now())
+	body, err := awsMetadataDo(c.httpClient, req)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("llm: assume role %s: %w", roleARN, err)
+	}
+
+	var resp struct {
+		Credentials stsCredentials `xml:"AssumeRoleResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return AWSCredentials{}, fmt.Errorf("llm: decode STS response: %w", err)
+	}
+	return resp.Credentials.credentials(), nil
+}
+
+// stsCredentials are the temporary credentials in an STS response.
+type stsCredentials struct {
+	AccessKeyID     string    `xml:"AccessKeyId"`
+	SecretAccessKey string    `xml:"SecretAccessKey"`
+	SessionToken    string    `xml:"SessionToken"`
+	Expiration      time.Time `xml:"Expiration"`
+}
+
+func (cr stsCredentials) credentials() AWSCredentials {
+	return AWSCredentials{AccessKeyID: cr.AccessKeyID, SecretAccessKey: cr.SecretAccessKey, SessionToken: cr.SessionToken, Expires: cr.Expiration}
+}
+
+// awsSTSEndpoint returns the STS endpoint (AWS_ENDPOINT_URL_STS, else the
+// regional one for region or the environment's region, else the global one)
+// and the region to sign requests to it for.
+func awsSTSEndpoint(region string) (endpoint, signingRegion string) {
+	if region == "" {
+		region = awsRegionFromEnv()
+	}
+	if region == "" {
+		region = "us-east-1"
+		endpoint = "https://sts.amazonaws.com"
+	} else {
+		endpoint = "https://sts." + region + ".amazonaws.com"
+	}
+	if override := os.Getenv("AWS_ENDPOINT_URL_STS"); override != "" {
+		endpoint = override
+	}
+	return endpoint, region
+}
+
+// webIdentityCredentials exchanges the token in AWS_WEB_IDENTITY_TOKEN_FILE
+// for temporary credentials for AWS_ROLE_ARN. The STS call is unsigned.
+func (c *awsCredentialChain) webIdentityCredentials(ctx context.Context) (AWSCredentials, bool, error) {
+	tokenFile, roleARN := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"), os.Getenv("AWS_ROLE_ARN")
+	if tokenFile == "" || roleARN == "" {
+		return AWSCredentials{}, false, nil
+	}
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return AWSCredentials{}, false, fmt.Errorf("llm: read web identity token: %w", err)
+	}
+	sessionName := os.Getenv("AWS_ROLE_SESSION_NAME")
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("goat-%d", time.Now().Unix())
+	}
+
+	endpoint, _ := awsSTSEndpoint("")
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {sessionName},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(query.Encode()))
+	if err != nil {
+		return AWSCredentials{}, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := awsMetadataDo(c.httpClient, req)
+	if err != nil {
+		return AWSCredentials{}, false, fmt.Errorf("llm: assume role with web identity: %w", err)
+	}
+
+	var resp struct {
+		Credentials stsCredentials `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+	}
+	if err := xml.Unmarshal(body, &resp); err != nil {
+		return AWSCredentials{}, false, fmt.Errorf("llm: decode STS response: %w", err)
+	}
+	return resp.Credentials.credentials(), true, nil
+}
+
+// containerCredentials fetches task role credentials from the ECS (or EKS
+// Pod Identity) credentials endpoint.
+func (c *awsCredentialChain) containerCredentials(ctx context.Context) (AWSCredentials, bool, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		endpoint = awsContainerHost + rel
+	}
+	if endpoint == "" {
+		return AWSCredentials{}, false, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return AWSCredentials{}, false, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return AWSCredentials{}, false, fmt.Errorf("llm: read container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	body, err := awsMetadataDo(c.httpClient, req)
+	if err != nil {
+		return AWSCredentials{}, false, fmt.Errorf("llm: container credentials: %w", err)
+	}
+	creds, err := decodeMetadataCredentials(body)
+	return creds, err == nil, err
+}
+
+// instanceCredentials fetches the instance role's credentials from the EC2
+// instance metadata service using an IMDSv2 session token. An unreachable
+// service means the process isn't on EC2 and is not an error; it is
+// remembered so later calls don't wait on it again.
+func (c *awsCredentialChain) instanceCredentials(ctx context.Context) (AWSCredentials, bool, error) {
+	if c.noIMDS || strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return AWSCredentials{}, false, nil
+	}
+	endpoint := strings.TrimSuffix(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), "/")
+	if endpoint == "" {
+		endpoint = awsIMDSEndpoint
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return AWSCredentials{}, false, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := awsMetadataDo(c.httpClient, req)
+	if err != nil {
+		c.noIMDS = ctx.Err() == nil
+		return AWSCredentials{}, false, nil
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return awsMetadataDo(c.httpClient, req)
+	}
+	const credsPath = "/latest/meta-data/iam/security-credentials/"
+	roles, err := get(credsPath)
+	if err != nil {
+		c.noIMDS = ctx.Err() == nil // no instance role
+		return AWSCredentials{}, false, nil
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	body, err := get(credsPath + role)
+	if err != nil {
+		return AWSCredentials{}, false, fmt.Errorf("llm: instance role credentials: %w", err)
+	}
+	creds, err := decodeMetadataCredentials(body)
+	return creds, err == nil, err
+}
+
+// decodeMetadataCredentials decodes the credentials JSON served by the
+// container and instance metadata endpoints.
+func decodeMetadataCredentials(body []byte) (AWSCredentials, error) {
+	var cr struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.Unmarshal(body, &cr); err != nil {
+		return AWSCredentials{}, fmt.Errorf("llm: decode credentials: %w", err)
+	}
+	if cr.AccessKeyID == "" || cr.SecretAccessKey == "" {
+		return AWSCredentials{}, errors.New("llm: credentials response has no keys")
+	}
+	return AWSCredentials{AccessKeyID: cr.AccessKeyID, SecretAccessKey: cr.SecretAccessKey, SessionToken: cr.Token, Expires: cr.Expiration}, nil
+}
+
+// awsMetadataDo sends req and returns the body of a 200 response.
+func awsMetadataDo(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d: %s", req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// awsRegionFromEnv returns the region from AWS_REGION, AWS_DEFAULT_REGION or
+// the AWS_PROFILE section of the shared config file.
+func awsRegionFromEnv() string {
+	if region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"); region != "" {
+		return region
+	}
+	return readAWSProfile(awsSharedFile("AWS_CONFIG_FILE", "config"), awsProfile(), true)["region"]
+}
+
+func awsProfile() string {
+	if p := firstEnv("AWS_PROFILE", "AWS_DEFAULT_PROFILE"); p != "" {
+		return p
+	}
+	return "default"
+}
+
+// awsSharedFile returns the path in env, else ~/.aws/name.
+func awsSharedFile(env, name string) string {
+	if path := os.Getenv(env); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readAWSProfile returns the keys of profile's section in an AWS shared
+// credentials or config file; config files name non-default sections
+// "[profile name]". A missing file or profile yields nil.
+func readAWSProfile(path, profile string, config bool) map[string]string {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	want := profile
+	if config && profile != "default" {
+		want = "profile " + profile
+	}
+	var values map[string]string
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			name := strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			inSection = name == want
+			if inSection && values == nil {
+				values = make(map[string]string)
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		if key, value, ok := strings.Cut(line, "="); ok {
+			values[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+		}
+	}
+	return values
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,303 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// isolateAWSEnv clears the AWS environment and points the shared files at
+// an empty home directory.
+func isolateAWSEnv(t *testing.T) string {
+	t.Helper()
+	for _, name := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY", "AWS_SECRET_ACCESS_KEY", "AWS_SECRET_KEY", "AWS_SESSION_TOKEN",
+		"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_SHARED_CREDENTIALS_FILE", "AWS_CONFIG_FILE",
+		"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_WEB_IDENTITY_TOKEN_FILE", "AWS_ROLE_ARN", "AWS_ENDPOINT_URL_STS",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN", "AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", "AWS_EC2_METADATA_SERVICE_ENDPOINT",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return home
+}
+
+func retrieve(t *testing.T, p AWSCredentialsProvider) AWSCredentials {
+	t.Helper()
+	creds, err := p.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	return creds
+}
+
+func TestDefaultAWSCredentials_Env(t *testing.T) {
+	isolateAWSEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "token")
+
+	if creds := retrieve(t, DefaultAWSCredentials()); creds.AccessKeyID != "AKIDENV" || creds.SessionToken != "token" {
+		t.Errorf("creds = %+v, want the environment's", creds)
+	}
+}
+
+func TestDefaultAWSCredentials_SharedFiles(t *testing.T) {
+	home := isolateAWSEnv(t)
+	dir := filepath.Join(home, ".aws")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "credentials"), []byte(`
+[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+[work]
+# work account
+aws_access_key_id=AKIDWORK
+aws_secret_access_key=work-secret
+`), 0600)
+	os.WriteFile(filepath.Join(dir, "config"), []byte(`
+[default]
+region = us-east-2
+
+[profile ci]
+region = eu-central-1
+aws_access_key_id = AKIDCI
+aws_secret_access_key = ci-secret
+`), 0600)
+
+	if creds := retrieve(t, DefaultAWSCredentials()); creds.AccessKeyID != "AKIDDEFAULT" {
+		t.Errorf("default profile creds = %+v", creds)
+	}
+	if region := awsRegionFromEnv(); region != "us-east-2" {
+		t.Errorf("default profile region = %q, want us-east-2", region)
+	}
+
+	t.Setenv("AWS_PROFILE", "work")
+	if creds := retrieve(t, DefaultAWSCredentials()); creds.AccessKeyID != "AKIDWORK" || creds.SecretAccessKey != "work-secret" {
+		t.Errorf("work profile creds = %+v", creds)
+	}
+
+	// Keys may also live in the config file, under "[profile name]".
+	t.Setenv("AWS_PROFILE", "ci")
+	if creds := retrieve(t, DefaultAWSCredentials()); creds.AccessKeyID != "AKIDCI" {
+		t.Errorf("ci profile creds = %+v", creds)
+	}
+	if region := awsRegionFromEnv(); region != "eu-central-1" {
+		t.Errorf("ci profile region = %q, want eu-central-1", region)
+	}
+}
+
+func TestDefaultAWSCredentials_AssumeRoleProfile(t *testing.T) {
+	home := isolateAWSEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRole" || r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/deploy" ||
+			r.Form.Get("ExternalId") != "ext-1" {
+			t.Errorf("STS form = %v", r.Form)
+		}
+		if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "Credential=AKIDSOURCE/") || !strings.Contains(auth, "/eu-west-1/sts/") {
+			t.Errorf("Authorization = %q, want signed with the source profile for eu-west-1", auth)
+		}
+		fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ASIAROLE</AccessKeyId>
+      <SecretAccessKey>role-secret</SecretAccessKey>
+      <SessionToken>role-token</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	dir := filepath.Join(home, ".aws")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "credentials"), []byte(`
+[source]
+aws_access_key_id = AKIDSOURCE
+aws_secret_access_key = source-secret
+`), 0600)
+	os.WriteFile(filepath.Join(dir, "config"), []byte(`
+[profile deploy]
+role_arn = arn:aws:iam::123456789012:role/deploy
+source_profile = source
+external_id = ext-1
+region = eu-west-1
+`), 0600)
+	t.Setenv("AWS_PROFILE", "deploy")
+	t.Setenv("AWS_ENDPOINT_URL_STS", srv.URL)
+
+	creds := retrieve(t, DefaultAWSCredentials())
+	if creds.AccessKeyID != "ASIAROLE" || creds.SessionToken != "role-token" || creds.Expires.IsZero() {
+		t.Errorf("creds = %+v, want the assumed role's", creds)
+	}
+}
+
+func TestDefaultAWSCredentials_CredentialProcess(t *testing.T) {
+	home := isolateAWSEnv(t)
+	script := filepath.Join(home, "creds.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\necho '{\"Version\":1,\"AccessKeyId\":\"AKIDPROC\",\"SecretAccessKey\":\"proc-secret\",\"SessionToken\":\"proc-token\"}'\n"), 0700)
+	dir := filepath.Join(home, ".aws")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "config"), []byte("[default]\ncredential_process = "+script+"\n"), 0600)
+
+	if creds := retrieve(t, DefaultAWSCredentials()); creds.AccessKeyID != "AKIDPROC" || creds.SessionToken != "proc-token" {
+		t.Errorf("creds = %+v, want the credential_process output", creds)
+	}
+}
+
+func TestDefaultAWSCredentials_UnsupportedProfile(t *testing.T) {
+	home := isolateAWSEnv(t)
+	dir := filepath.Join(home, ".aws")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "config"), []byte(`
+[profile sso]
+sso_session = corp
+sso_account_id = 123456789012
+sso_role_name = Developer
+`), 0600)
+	t.Setenv("AWS_PROFILE", "sso")
+
+	_, err := DefaultAWSCredentials().Retrieve(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `"sso"`) || !strings.Contains(err.Error(), "sso_session, sso_account_id, sso_role_name") {
+		t.Errorf("err = %v, want the unsupported profile keys named", err)
+	}
+}
+
+func TestDefaultAWSCredentials_WebIdentity(t *testing.T) {
+	home := isolateAWSEnv(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") != "AssumeRoleWithWebIdentity" || r.Form.Get("WebIdentityToken") != "jwt-token" ||
+			r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/agent" {
+			t.Errorf("STS form = %v", r.Form)
+		}
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAWEB</AccessKeyId>
+      <SecretAccessKey>web-secret</SecretAccessKey>
+      <SessionToken>web-token</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`, time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer srv.Close()
+
+	tokenFile := filepath.Join(home, "token")
+	os.WriteFile(tokenFile, []byte("jwt-token\n"), 0600)
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/agent")
+	t.Setenv("AWS_ENDPOINT_URL_STS", srv.URL)
+
+	creds := retrieve(t, DefaultAWSCredentials())
+	if creds.AccessKeyID != "ASIAWEB" || creds.SessionToken != "web-token" || creds.Expires.IsZero() {
+		t.Errorf("creds = %+v, want the STS credentials", creds)
+	}
+}
+
+func TestDefaultAWSCredentials_Container(t *testing.T) {
+	isolateAWSEnv(t)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Header.Get("Authorization") != "task-auth" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		fmt.Fprintf(w, `{"AccessKeyId":"ASIATASK","SecretAccessKey":"task-secret","Token":"task-token","Expiration":%q}`,
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", srv.URL+"/v2/credentials")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "task-auth")
+
+	provider := DefaultAWSCredentials()
+	if creds := retrieve(t, provider); creds.AccessKeyID != "ASIATASK" || creds.SessionToken != "task-token" {
+		t.Errorf("creds = %+v, want the task role's", creds)
+	}
+	retrieve(t, provider)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("endpoint calls = %d, want 1 (cached until near expiry)", n)
+	}
+}
+
+func TestDefaultAWSCredentials_InstanceMetadata(t *testing.T) {
+	isolateAWSEnv(t)
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+			w.Write([]byte("imds-token"))
+		case r.Header.Get("X-aws-ec2-metadata-token") != "imds-token":
+			w.WriteHeader(401)
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+			w.Write([]byte("agent-role"))
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/agent-role":
+			fetches.Add(1)
+			// Expiring within the refresh window: fetched again each time.
+			fmt.Fprintf(w, `{"Code":"Success","AccessKeyId":"ASIAEC2","SecretAccessKey":"ec2-secret","Token":"ec2-token","Expiration":%q}`,
+				time.Now().Add(time.Minute).UTC().Format(time.RFC3339))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", srv.URL)
+
+	provider := DefaultAWSCredentials()
+	if creds := retrieve(t, provider); creds.AccessKeyID != "ASIAEC2" {
+		t.Errorf("creds = %+v, want the instance role's", creds)
+	}
+	retrieve(t, provider)
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("credential fetches = %d, want 2", n)
+	}
+}
+
+func TestDefaultAWSCredentials_InstanceMetadataProbedOnce(t *testing.T) {
+	isolateAWSEnv(t)
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Method == "PUT" {
+			w.Write([]byte("imds-token"))
+			return
+		}
+		w.WriteHeader(404) // no instance role
+	}))
+	defer srv.Close()
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", srv.URL)
+
+	provider := DefaultAWSCredentials()
+	for range 3 {
+		if _, err := provider.Retrieve(context.Background()); !errors.Is(err, errNoAWSCredentials) {
+			t.Fatalf("err = %v, want errNoAWSCredentials", err)
+		}
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("metadata requests = %d, want 2 (probed on the first call only)", n)
+	}
+}
+
+func TestDefaultAWSCredentials_None(t *testing.T) {
+	isolateAWSEnv(t)
+	if _, err := DefaultAWSCredentials().Retrieve(context.Background()); !errors.Is(err, errNoAWSCredentials) {
+		t.Errorf("err = %v, want errNoAWSCredentials", err)
+	}
+}
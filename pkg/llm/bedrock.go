@@ -0,0 +1,716 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+	bedrockService          = "bedrock"
+	bedrockMaxThoughts      = 256 // tool-use turns whose thinking blocks are kept for the next request
+)
+
+// bedrockAnthropicModel matches Bedrock Claude model and inference profile
+// IDs, capturing the Anthropic model name: "anthropic.claude-3-5-haiku-20241022-v1:0",
+// "us.anthropic.claude-sonnet-4-5-20250929-v1:0", ...
+var bedrockAnthropicModel = regexp.MustCompile(`^(?:[a-z-]+\.)?anthropic\.(claude-[a-z0-9.-]+?)(?:-v\d+(?::\d+)?)?$`)
+
+// BedrockClient calls Anthropic models on AWS Bedrock through the
+// InvokeModelWithResponseStream API. It implements Client: requests are
+// translated to the Anthropic Messages format and the Bedrock event stream
+// back into StreamChunks, including tool calls and extended thinking.
+//
+// Requests are signed with SigV4 using ClientConfig.AWSCredentials, or the
+// default AWS credential chain when it is nil; a ClientConfig.APIKey (or
+// AWS_BEARER_TOKEN_BEDROCK) is sent as a Bedrock API key instead. The region
+// is ClientConfig.AWSRegion, else AWS_REGION, AWS_DEFAULT_REGION or the
+// shared config profile's; BaseURL overrides the regional endpoint (e.g. a
+// VPC endpoint). Models are Bedrock model or inference profile IDs,
+// optionally with a "bedrock/" prefix ("bedrock/us.anthropic.claude-sonnet-4-5-20250929-v1:0").
+//
+// Throttling and service errors become LLMErrors with the status codes the
+// retry logic and the loop's fallback handling treat as retriable.
+type BedrockClient struct {
+	config      ClientConfig
+	httpClient  *http.Client
+	credentials AWSCredentialsProvider
+	configErr   error
+	mu          sync.RWMutex
+
+	// thoughts holds the signed thinking blocks of recent tool-use turns,
+	// keyed by their first tool call ID. ChatMessages don't carry them, but
+	// Anthropic requires them back in the request that continues the turn.
+	thoughtsMu   sync.Mutex
+	thoughts     map[string][]bedrockBlock
+	thoughtOrder []string
+}
+
+// NewBedrockClient creates a BedrockClient.
+func NewBedrockClient(cfg ClientConfig) *BedrockClient {
+	var configErr error
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient, configErr = newDefaultHTTPClient(cfg)
+	}
+	if cfg.MaxTokens == 0 {
+		cfg.MaxTokens = 16384
+	}
+	if cfg.Retry.MaxRetries == 0 && cfg.Retry.InitialBackoff == 0 {
+		cfg.Retry = DefaultRetryConfig()
+	}
+	if cfg.APIKey == "" {
+		cfg.APIKey = os.Getenv("AWS_BEARER_TOKEN_BEDROCK")
+	}
+	if cfg.AWSRegion == "" {
+		cfg.AWSRegion = awsRegionFromEnv()
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.BaseURL == "" && cfg.AWSRegion != "" {
+		cfg.BaseURL = "https://bedrock-runtime." + cfg.AWSRegion + ".amazonaws.com"
+	}
+	if configErr == nil && cfg.AWSRegion == "" {
+		configErr = errors.New("llm: no AWS region for Bedrock: set ClientConfig.AWSRegion or AWS_REGION")
+	}
+	credentials := cfg.AWSCredentials
+	if credentials == nil {
+		credentials = DefaultAWSCredentials()
+	}
+	return &BedrockClient{
+		config:      cfg,
+		httpClient:  cfg.HTTPClient,
+		credentials: credentials,
+		configErr:   configErr,
+		thoughts:    make(map[string][]bedrockBlock),
+	}
+}
+
+// IsBedrockModel reports whether model is named with the "bedrock/" prefix.
+func IsBedrockModel(model string) bool {
+	return strings.HasPrefix(model, "bedrock/")
+}
+
+// bedrockModelName returns the Anthropic model name of a Bedrock Claude
+// model or inference profile ID, or "" if model isn't one.
+func bedrockModelName(model string) string {
+	if m := bedrockAnthropicModel.FindStringSubmatch(model); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// bedrockRequest is the Anthropic Messages body InvokeModel takes.
+type bedrockRequest struct {
+	AnthropicVersion string           `json:"anthropic_version"`
+	AnthropicBeta    []string         `json:"anthropic_beta,omitempty"`
+	MaxTokens        int              `json:"max_tokens"`
+	System           string           `json:"system,omitempty"`
+	Messages         []bedrockMessage `json:"messages"`
+	Tools            []bedrockTool    `json:"tools,omitempty"`
+	ToolChoice       map[string]any   `json:"tool_choice,omitempty"`
+	Temperature      *float64         `json:"temperature,omitempty"`
+	TopP             *float64         `json:"top_p,omitempty"`
+	StopSequences    []string         `json:"stop_sequences,omitempty"`
+	Thinking         any              `json:"thinking,omitempty"`
+}
+
+type bedrockMessage struct {
+	Role    string         `json:"role"`
+	Content []bedrockBlock `json:"content"`
+}
+
+// bedrockBlock is an Anthropic content block of any type.
+type bedrockBlock struct {
+	Type string `json:"type"` // "text"|"image"|"tool_use"|"tool_result"|"thinking"|"redacted_thinking"
+	Text string `json:"text,omitempty"`
+
+	Source *bedrockImageSource `json:"source,omitempty"` // image
+
+	ID    string `json:"id,omitempty"` // tool_use
+	Name  string `json:"name,omitempty"`
+	Input any    `json:"input,omitempty"`
+
+	ToolUseID string         `json:"tool_use_id,omitempty"` // tool_result
+	Content   []bedrockBlock `json:"content,omitempty"`
+
+	Thinking  string `json:"thinking,omitempty"` // thinking
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // redacted_thinking
+}
+
+type bedrockImageSource struct {
+	Type      string `json:"type"` // "base64"
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type bedrockTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+// Complete sends req to InvokeModelWithResponseStream and returns the
+// streamed response.
+func (c *BedrockClient) Complete(ctx context.Context, req *CompletionRequest) (*Stream, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+
+	model := req.Model
+	if model == "" {
+		model = c.Model()
+	}
+	body, err := json.Marshal(c.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("llm: marshal request: %w", err)
+	}
+
+	if _, err := c.config.RateLimiter.Wait(ctx, estimateRequestTokens(body, req.MaxTokens)); err != nil {
+		return nil, err
+	}
+
+	var creds AWSCredentials
+	if c.config.APIKey == "" {
+		if creds, err = c.credentials.Retrieve(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	url := c.config.BaseURL + "/model/" + awsURIEncode(strings.TrimPrefix(model, "bedrock/")) + "/invoke-with-response-stream"
+	resp, err := doWithRetry(ctx, c.config.Retry, func(ctx context.Context) (*http.Response, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "application/vnd.amazon.eventstream")
+		for k, v := range c.config.Headers {
+			httpReq.Header.Set(k, v)
+		}
+		if c.config.APIKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		} else {
+			signSigV4(httpReq, body, creds, c.config.AWSRegion, bedrockService, time.Now())
+		}
+		return c.httpClient.Do(httpReq)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		llmErr := classifyBedrockError(resp)
+		resp.Body.Close()
+		return nil, llmErr
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	events := parseBedrockStream(streamCtx, resp.Body, model, c.rememberThoughts)
+	return NewStream(events, resp.Body, cancel), nil
+}
+
+// buildRequest maps an OpenAI-format request onto the Anthropic Messages
+// format. Thinking and betas come from the ExtraBody BuildCompletionRequest
+// fills for Claude.
+func (c *BedrockClient) buildRequest(req *CompletionRequest) bedrockRequest {
+	out := bedrockRequest{
+		AnthropicVersion: bedrockAnthropicVersion,
+		AnthropicBeta:    c.config.Betas,
+		MaxTokens:        req.MaxTokens,
+		Temperature:      req.Temperature,
+		TopP:             req.TopP,
+		StopSequences:    req.Stop,
+		Thinking:         req.ExtraBody["thinking"],
+	}
+	if out.MaxTokens == 0 {
+		out.MaxTokens = c.config.MaxTokens
+	}
+	if betas, ok := req.ExtraBody["betas"].([]string); ok {
+		out.AnthropicBeta = betas
+	}
+	out.System, out.Messages = c.toBedrockMessages(req.Messages)
+
+	for _, t := range req.Tools {
+		schema := t.Function.Parameters
+		if schema == nil {
+			schema = map[string]any{"type": "object"}
+		}
+		out.Tools = append(out.Tools, bedrockTool{Name: t.Function.Name, Description: t.Function.Description, InputSchema: schema})
+	}
+	if len(out.Tools) > 0 {
+		out.ToolChoice = bedrockToolChoice(req.ToolChoice, req.ParallelToolCalls)
+	}
+
+	if out.Thinking != nil {
+		// A tool-use turn must continue with its signed thinking blocks;
+		// without them, the request is sent without thinking.
+		if last := lastAssistantMessage(out.Messages); last != nil && hasToolUse(last) && !startsWithThinking(last) {
+			out.Thinking = nil
+		} else {
+			// Thinking requires the default sampling parameters.
+			out.Temperature, out.TopP = nil, nil
+		}
+	}
+	return out
+}
+
+// toBedrockMessages converts OpenAI-format messages, returning the joined
+// system messages separately. Tool results become tool_result blocks in
+// user messages, and consecutive messages of the same role are merged, as
+// Anthropic requires roles to alternate.
+func (c *BedrockClient) toBedrockMessages(msgs []ChatMessage) (string, []bedrockMessage) {
+	var system []string
+	var out []bedrockMessage
+	add := func(role string, blocks []bedrockBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			return
+		}
+		out = append(out, bedrockMessage{Role: role, Content: blocks})
+	}
+
+	for _, m := range msgs {
+		switch m.Role {
+		case "system":
+			if text := bedrockText(m.Content); text != "" {
+				system = append(system, text)
+			}
+		case "tool":
+			add("user", []bedrockBlock{{Type: "tool_result", ToolUseID: m.ToolCallID, Content: bedrockContent(m.Content)}})
+		case "assistant":
+			var blocks []bedrockBlock
+			if len(m.ToolCalls) > 0 {
+				blocks = append(blocks, c.thoughtsFor(m.ToolCalls[0].ID)...)
+			}
+			blocks = append(blocks, bedrockContent(m.Content)...)
+			for _, tc := range m.ToolCalls {
+				input := map[string]any{}
+				if tc.Function.Arguments != "" {
+					if args, err := RepairToolArguments(tc.Function.Arguments); err == nil {
+						input = args
+					}
+				}
+				blocks = append(blocks, bedrockBlock{Type: "tool_use", ID: tc.ID, Name: tc.Function.Name, Input: input})
+			}
+			add("assistant", blocks)
+		default:
+			add("user", bedrockContent(m.Content))
+		}
+	}
+	return strings.Join(system, "\n\n"), out
+}
+
+// bedrockContent converts message content to text and image blocks. Only
+// inline (data URI) images can be sent; image URLs are passed as text.
+func bedrockContent(content any) []bedrockBlock {
+	var blocks []bedrockBlock
+	switch c := content.(type) {
+	case string:
+		if c != "" {
+			blocks = append(blocks, bedrockBlock{Type: "text", Text: c})
+		}
+	case []ContentPart:
+		for _, part := range c {
+			switch {
+			case part.Type == "text" && part.Text != "":
+				blocks = append(blocks, bedrockBlock{Type: "text", Text: part.Text})
+			case part.Type == "image_url" && part.ImageURL != nil:
+				blocks = append(blocks, bedrockImage(part.ImageURL.URL))
+			}
+		}
+	case []any:
+		// Content restored from a session transcript.
+		var parts []ContentPart
+		if data, err := json.Marshal(c); err == nil && json.Unmarshal(data, &parts) == nil {
+			return bedrockContent(parts)
+		}
+	}
+	return blocks
+}
+
+func bedrockImage(url string) bedrockBlock {
+	meta, data, ok := strings.Cut(url, ";base64,")
+	if !ok || !strings.HasPrefix(meta, "data:") {
+		return bedrockBlock{Type: "text", Text: "[image: " + url + "]"}
+	}
+	return bedrockBlock{Type: "image", Source: &bedrockImageSource{Type: "base64", MediaType: strings.TrimPrefix(meta, "data:"), Data: data}}
+}
+
+// bedrockText joins the text of message content.
+func bedrockText(content any) string {
+	var texts []string
+	for _, b := range bedrockContent(content) {
+		if b.Type == "text" {
+			texts = append(texts, b.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// bedrockToolChoice maps an OpenAI tool_choice ("auto", "none", "required"
+// or a named function) and parallel_tool_calls to Anthropic's tool_choice.
+func bedrockToolChoice(choice any, parallel *bool) map[string]any {
+	tc := map[string]any{"type": "auto"}
+	switch c := choice.(type) {
+	case string:
+		switch c {
+		case "none":
+			return map[string]any{"type": "none"}
+		case "required":
+			tc["type"] = "any"
+		}
+	case map[string]any:
+		if fn, ok := c["function"].(map[string]any); ok {
+			tc = map[string]any{"type": "tool", "name": fn["name"]}
+		}
+	}
+	if parallel != nil && !*parallel {
+		tc["disable_parallel_tool_use"] = true
+	}
+	return tc
+}
+
+func lastAssistantMessage(msgs []bedrockMessage) *bedrockMessage {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "assistant" {
+			return &msgs[i]
+		}
+	}
+	return nil
+}
+
+func hasToolUse(m *bedrockMessage) bool {
+	for _, b := range m.Content {
+		if b.Type == "tool_use" {
+			return true
+		}
+	}
+	return false
+}
+
+func startsWithThinking(m *bedrockMessage) bool {
+	return len(m.Content) > 0 && (m.Content[0].Type == "thinking" || m.Content[0].Type == "redacted_thinking")
+}
+
+// rememberThoughts keeps the thinking blocks of a tool-use turn, dropping
+// the oldest turn's beyond bedrockMaxThoughts.
+func (c *BedrockClient) rememberThoughts(toolUseID string, blocks []bedrockBlock) {
+	c.thoughtsMu.Lock()
+	defer c.thoughtsMu.Unlock()
+	if _, ok := c.thoughts[toolUseID]; !ok {
+		c.thoughtOrder = append(c.thoughtOrder, toolUseID)
+	}
+	c.thoughts[toolUseID] = blocks
+	if len(c.thoughtOrder) > bedrockMaxThoughts {
+		delete(c.thoughts, c.thoughtOrder[0])
+		c.thoughtOrder = c.thoughtOrder[1:]
+	}
+}
+
+func (c *BedrockClient) thoughtsFor(toolUseID string) []bedrockBlock {
+	c.thoughtsMu.Lock()
+	defer c.thoughtsMu.Unlock()
+	return c.thoughts[toolUseID]
+}
+
+// classifyBedrockError classifies a non-200 response, naming the Bedrock
+// exception (x-amzn-ErrorType) in the message. Throttling (429) and service
+// errors keep their retriable status codes; model timeouts are retriable too.
+func classifyBedrockError(resp *http.Response) *LLMError {
+	llmErr := classifyError(resp)
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(llmErr.Message), &body) == nil && body.Message != "" {
+		llmErr.Message = body.Message
+	}
+	errType, _, _ := strings.Cut(resp.Header.Get("X-Amzn-Errortype"), ":")
+	if errType != "" {
+		llmErr.Message = errType + ": " + llmErr.Message
+	}
+	if errType == "ModelTimeoutException" {
+		llmErr.SDKError, llmErr.Retryable = "server_error", true
+	}
+	return llmErr
+}
+
+// bedrockStreamError converts an exception sent mid-stream to an LLMError
+// with the status Bedrock gives the same exception on a request.
+func bedrockStreamError(exceptionType string, payload []byte) *LLMError {
+	var body struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(payload, &body)
+	if body.Message == "" {
+		body.Message = string(payload)
+	}
+	status := 500
+	switch exceptionType {
+	case "throttlingException":
+		status = 429
+	case "serviceUnavailableException":
+		status = 503
+	case "validationException":
+		status = 400
+	case "modelTimeoutException":
+		status = 408
+	}
+	sdkError, retryable := classifyStatus(status)
+	if status == 408 {
+		sdkError, retryable = "server_error", true
+	}
+	return &LLMError{StatusCode: status, SDKError: sdkError, Message: exceptionType + ": " + body.Message, Retryable: retryable}
+}
+
+// bedrockEvent is an Anthropic streaming event, as carried in the bytes of
+// a Bedrock chunk event.
+type bedrockEvent struct {
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Message struct {
+		ID    string       `json:"id"`
+		Model string       `json:"model"`
+		Usage bedrockUsage `json:"usage"`
+	} `json:"message"`
+	ContentBlock bedrockBlock `json:"content_block"`
+	Delta        struct {
+		Type         string `json:"type"`
+		Text         string `json:"text"`
+		PartialJSON  string `json:"partial_json"`
+		Thinking     string `json:"thinking"`
+		Signature    string `json:"signature"`
+		StopReason   string `json:"stop_reason"`
+		StopSequence string `json:"stop_sequence"`
+	} `json:"delta"`
+	Usage bedrockUsage `json:"usage"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type bedrockUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// parseBedrockStream reads the event stream from body and yields
+// StreamEvents in the OpenAI chunk format. The signed thinking blocks of a
+// turn that ends in tool calls are passed to remember with the first tool
+// call's ID.
+func parseBedrockStream(ctx context.Context, body io.ReadCloser, model string, remember func(string, []bedrockBlock)) <-chan StreamEvent {
+	ch := make(chan StreamEvent)
+
+	go func() {
+		defer close(ch)
+		defer body.Close()
+
+		id := fmt.Sprintf("bedrock-%d", time.Now().UnixNano())
+		created := time.Now().Unix()
+		var usage Usage
+		var stopReason, stopSequence, firstToolID string
+		toolIndex := -1
+		toolIndexes := make(map[int]int) // content block index -> tool call index
+		var thoughts []bedrockBlock
+		thinking := make(map[int]*bedrockBlock) // open thinking blocks by content block index
+
+		send := func(delta Delta, finish *string) {
+			choice := Choice{Delta: delta, FinishReason: finish}
+			chunk := &StreamChunk{ID: id, Object: "chat.completion.chunk", Created: created, Model: model, Choices: []Choice{choice}}
+			if finish != nil {
+				u := usage
+				u.TotalTokens = u.PromptTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens + u.CompletionTokens
+				chunk.Usage = &u
+				if stopSequence != "" {
+					chunk.Choices[0].StopReason = stopSequence
+				}
+			}
+			ch <- StreamEvent{Chunk: chunk}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				ch <- StreamEvent{Err: ctx.Err()}
+				return
+			default:
+			}
+
+			msg, err := readEventStreamMessage(body)
+			if err != nil {
+				switch {
+				case ctx.Err() != nil:
+					ch <- StreamEvent{Err: ctx.Err()}
+				case errors.Is(err, io.EOF):
+					// EOF before message_stop: the stream ended unexpectedly
+				default:
+					ch <- StreamEvent{Err: err}
+				}
+				return
+			}
+
+			switch msg.Headers[":message-type"] {
+			case "exception":
+				ch <- StreamEvent{Err: bedrockStreamError(msg.Headers[":exception-type"], msg.Payload)}
+				return
+			case "error":
+				ch <- StreamEvent{Err: &LLMError{StatusCode: 500, SDKError: "server_error", Retryable: true,
+					Message: msg.Headers[":error-code"] + ": " + msg.Headers[":error-message"]}}
+				return
+			}
+			if msg.Headers[":event-type"] != "chunk" {
+				continue
+			}
+			var payload struct {
+				Bytes []byte `json:"bytes"` // base64 in the JSON
+			}
+			var ev bedrockEvent
+			if json.Unmarshal(msg.Payload, &payload) != nil || json.Unmarshal(payload.Bytes, &ev) != nil {
+				continue // malformed event: skip, not fatal
+			}
+
+			switch ev.Type {
+			case "message_start":
+				if ev.Message.ID != "" {
+					id = ev.Message.ID
+				}
+				if ev.Message.Model != "" {
+					model = ev.Message.Model
+				}
+				u := ev.Message.Usage
+				usage.PromptTokens = u.InputTokens
+				usage.CacheReadInputTokens = u.CacheReadInputTokens
+				usage.CacheCreationInputTokens = u.CacheCreationInputTokens
+				usage.CompletionTokens = u.OutputTokens
+				send(Delta{Role: "assistant"}, nil)
+
+			case "content_block_start":
+				block := ev.ContentBlock
+				switch block.Type {
+				case "text":
+					if block.Text != "" {
+						send(Delta{Content: &block.Text}, nil)
+					}
+				case "tool_use":
+					toolIndex++
+					toolIndexes[ev.Index] = toolIndex
+					if firstToolID == "" {
+						firstToolID = block.ID
+					}
+					send(Delta{ToolCalls: []ToolCall{{Index: toolIndex, ID: block.ID, Type: "function",
+						Function: FunctionCall{Name: block.Name}}}}, nil)
+				case "thinking":
+					thinking[ev.Index] = &bedrockBlock{Type: "thinking", Thinking: block.Thinking, Signature: block.Signature}
+				case "redacted_thinking":
+					thoughts = append(thoughts, bedrockBlock{Type: "redacted_thinking", Data: block.Data})
+				}
+
+			case "content_block_delta":
+				d := ev.Delta
+				switch d.Type {
+				case "text_delta":
+					send(Delta{Content: &d.Text}, nil)
+				case "input_json_delta":
+					if i, ok := toolIndexes[ev.Index]; ok {
+						send(Delta{ToolCalls: []ToolCall{{Index: i, Function: FunctionCall{Arguments: d.PartialJSON}}}}, nil)
+					}
+				case "thinking_delta":
+					if b := thinking[ev.Index]; b != nil {
+						b.Thinking += d.Thinking
+					}
+					send(Delta{ReasoningContent: &d.Thinking}, nil)
+				case "signature_delta":
+					if b := thinking[ev.Index]; b != nil {
+						b.Signature += d.Signature
+					}
+				}
+
+			case "content_block_stop":
+				if b := thinking[ev.Index]; b != nil {
+					thoughts = append(thoughts, *b)
+					delete(thinking, ev.Index)
+				}
+
+			case "message_delta":
+				stopReason, stopSequence = ev.Delta.StopReason, ev.Delta.StopSequence
+				if ev.Usage.OutputTokens > 0 {
+					usage.CompletionTokens = ev.Usage.OutputTokens
+				}
+
+			case "message_stop":
+				if firstToolID != "" && len(thoughts) > 0 && remember != nil {
+					remember(firstToolID, thoughts)
+				}
+				reason := bedrockFinishReason(stopReason)
+				send(Delta{}, &reason)
+				ch <- StreamEvent{Done: true}
+				return
+
+			case "error":
+				ch <- StreamEvent{Err: anthropicStreamError(ev.Error.Type, ev.Error.Message)}
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// bedrockFinishReason maps an Anthropic stop_reason to an OpenAI
+// finish_reason.
+func bedrockFinishReason(stopReason string) string {
+	switch stopReason {
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	}
+	return "stop"
+}
+
+// anthropicStreamError converts an Anthropic error event to an LLMError,
+// using the status the Anthropic API gives the same error type.
+func anthropicStreamError(errType, message string) *LLMError {
+	status := 500
+	switch errType {
+	case "overloaded_error":
+		status = 529
+	case "rate_limit_error":
+		status = 429
+	case "invalid_request_error":
+		status = 400
+	}
+	sdkError, retryable := classifyStatus(status)
+	return &LLMError{StatusCode: status, SDKError: sdkError, Message: errType + ": " + message, Retryable: retryable}
+}
+
+// Model returns the configured default model string.
+func (c *BedrockClient) Model() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Model
+}
+
+// SetModel changes the default model for subsequent requests.
+func (c *BedrockClient) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Model = model
+}
+
+var _ Client = (*BedrockClient)(nil)
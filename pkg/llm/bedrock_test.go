@@ -0,0 +1,372 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testBedrockModel = "bedrock/us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+
+// bedrockEvents frames Anthropic streaming events as Bedrock chunk events.
+func bedrockEvents(events ...string) []byte {
+	var buf bytes.Buffer
+	for _, ev := range events {
+		payload, _ := json.Marshal(map[string][]byte{"bytes": []byte(ev)})
+		buf.Write(encodeEventStreamMessage(map[string]string{
+			":message-type": "event", ":event-type": "chunk", ":content-type": "application/json",
+		}, payload))
+	}
+	return buf.Bytes()
+}
+
+// bedrockServer serves each request the next response, recording decoded
+// request bodies.
+func bedrockServer(t *testing.T, responses ...[]byte) (*httptest.Server, *[]bedrockRequest) {
+	t.Helper()
+	var reqs []bedrockRequest
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req bedrockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		reqs = append(reqs, req)
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		w.Write(responses[int(n.Add(1))-1])
+	}))
+	return srv, &reqs
+}
+
+func newTestBedrockClient(baseURL string) *BedrockClient {
+	return NewBedrockClient(ClientConfig{
+		BaseURL:        baseURL,
+		Model:          testBedrockModel,
+		AWSRegion:      "us-west-2",
+		AWSCredentials: StaticAWSCredentials(testAWSCredentials.AccessKeyID, testAWSCredentials.SecretAccessKey, ""),
+	})
+}
+
+func TestBedrockClient_Text(t *testing.T) {
+	body := bedrockEvents(
+		`{"type":"message_start","message":{"id":"msg_bdrk_01","model":"claude-sonnet-4-5-20250929","usage":{"input_tokens":20,"cache_read_input_tokens":100,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"ping"}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" there"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":9}}`,
+		`{"type":"message_stop","amazon-bedrock-invocationMetrics":{"inputTokenCount":20,"outputTokenCount":9}}`,
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if want := "/model/us.anthropic.claude-sonnet-4-5-20250929-v1%3A0/invoke-with-response-stream"; r.URL.EscapedPath() != want {
+			t.Errorf("path = %q, want %q", r.URL.EscapedPath(), want)
+		}
+		scope := "Credential=AKIDEXAMPLE/" + time.Now().UTC().Format(sigV4DateFormat) + "/us-west-2/bedrock/aws4_request"
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 "+scope) {
+			t.Errorf("Authorization = %q, want a SigV4 signature for %s", auth, scope)
+		}
+		var req map[string]any
+		json.NewDecoder(r.Body).Decode(&req)
+		if req["anthropic_version"] != bedrockAnthropicVersion || req["system"] != "Be brief." || req["max_tokens"] != float64(1024) {
+			t.Errorf("request = %v", req)
+		}
+		if _, ok := req["model"]; ok {
+			t.Error("model sent in the body; Bedrock takes it from the path")
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	stream, err := newTestBedrockClient(srv.URL).Complete(context.Background(), &CompletionRequest{
+		Messages: []ChatMessage{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "Hi"},
+		},
+		MaxTokens: 1024,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	resp, err := stream.Accumulate()
+	if err != nil {
+		t.Fatalf("Accumulate: %v", err)
+	}
+
+	if resp.ID != "msg_bdrk_01" || resp.Model != "claude-sonnet-4-5-20250929" {
+		t.Errorf("ID, Model = %q, %q", resp.ID, resp.Model)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Text != "Hello there" {
+		t.Errorf("content = %+v, want \"Hello there\"", resp.Content)
+	}
+	if resp.StopReason != "end_turn" {
+		t.Errorf("StopReason = %q, want end_turn", resp.StopReason)
+	}
+	if u := resp.Usage; u.InputTokens != 20 || u.CacheReadInputTokens != 100 || u.OutputTokens != 9 {
+		t.Errorf("usage = %+v, want 20 in, 100 cache read, 9 out", u)
+	}
+}
+
+func TestBedrockClient_ToolUseWithThinking(t *testing.T) {
+	first := bedrockEvents(
+		`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":50}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need to read the file."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig-abc"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_01","name":"Read","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"file_path\":"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"/tmp/a.go\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":30}}`,
+		`{"type":"message_stop"}`,
+	)
+	second := bedrockEvents(
+		`{"type":"message_start","message":{"id":"msg_2","usage":{"input_tokens":90}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":"Done."}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+		`{"type":"message_stop"}`,
+	)
+	srv, reqs := bedrockServer(t, first, second)
+	defer srv.Close()
+	client := newTestBedrockClient(srv.URL)
+
+	tools := []ToolDefinition{{Type: "function", Function: FunctionDef{Name: "Read", Description: "Read a file",
+		Parameters: map[string]any{"type": "object"}}}}
+	thinking := map[string]any{"type": "enabled", "budget_tokens": 2048}
+	temp := 0.3
+	messages := []ChatMessage{{Role: "user", Content: "Read a.go"}}
+	stream, err := client.Complete(context.Background(), &CompletionRequest{
+		Messages: messages, Tools: tools, ToolChoice: "auto", Temperature: &temp,
+		ExtraBody: map[string]any{"thinking": thinking},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	resp, err := stream.Accumulate()
+	if err != nil {
+		t.Fatalf("Accumulate: %v", err)
+	}
+
+	if resp.StopReason != "tool_use" {
+		t.Errorf("StopReason = %q, want tool_use", resp.StopReason)
+	}
+	if len(resp.Content) != 2 || resp.Content[0].Thinking != "Need to read the file." {
+		t.Fatalf("content = %+v, want thinking then tool_use", resp.Content)
+	}
+	if tu := resp.Content[1]; tu.ID != "toolu_01" || tu.Name != "Read" || tu.Input["file_path"] != "/tmp/a.go" {
+		t.Errorf("tool_use = %+v", tu)
+	}
+	req := (*reqs)[0]
+	if req.Temperature != nil {
+		t.Errorf("temperature = %v sent with thinking", *req.Temperature)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Name != "Read" || req.ToolChoice["type"] != "auto" {
+		t.Errorf("tools = %+v, tool_choice = %v", req.Tools, req.ToolChoice)
+	}
+
+	// The next request carries the signed thinking block back.
+	messages = append(messages,
+		ChatMessage{Role: "assistant", ToolCalls: resp.ToolCalls},
+		ChatMessage{Role: "tool", ToolCallID: "toolu_01", Content: "package a"},
+	)
+	stream, err = client.Complete(context.Background(), &CompletionRequest{
+		Messages: messages, Tools: tools, ExtraBody: map[string]any{"thinking": thinking},
+	})
+	if err != nil {
+		t.Fatalf("second Complete: %v", err)
+	}
+	if _, err := stream.Accumulate(); err != nil {
+		t.Fatalf("second Accumulate: %v", err)
+	}
+
+	req = (*reqs)[1]
+	if req.Thinking == nil {
+		t.Error("thinking dropped from the tool-use continuation")
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("messages = %+v, want 3", req.Messages)
+	}
+	assistant := req.Messages[1].Content
+	if len(assistant) != 2 || assistant[0].Type != "thinking" || assistant[0].Signature != "sig-abc" ||
+		assistant[1].Type != "tool_use" || assistant[1].ID != "toolu_01" {
+		t.Errorf("assistant content = %+v, want the signed thinking block then tool_use", assistant)
+	}
+	result := req.Messages[2]
+	if result.Role != "user" || result.Content[0].Type != "tool_result" || result.Content[0].ToolUseID != "toolu_01" ||
+		result.Content[0].Content[0].Text != "package a" {
+		t.Errorf("tool result message = %+v", result)
+	}
+}
+
+func TestBedrockClient_BuildRequest(t *testing.T) {
+	client := newTestBedrockClient("http://bedrock.invalid")
+	noParallel := false
+	req := client.buildRequest(&CompletionRequest{
+		Messages: []ChatMessage{
+			{Role: "user", Content: "Look at these"},
+			{Role: "assistant", Content: "Reading both.", ToolCalls: []ToolCall{
+				{ID: "t1", Function: FunctionCall{Name: "Read", Arguments: `{"file_path":"a.png"}`}},
+				{ID: "t2", Function: FunctionCall{Name: "Read", Arguments: `{"file_path":"b.png"}`}},
+			}},
+			{Role: "tool", ToolCallID: "t1", Content: "a"},
+			{Role: "tool", ToolCallID: "t2", Content: "b"},
+			{Role: "user", Content: []any{
+				map[string]any{"type": "image_url", "image_url": map[string]any{"url": "data:image/png;base64,iVBORw0K"}},
+			}},
+		},
+		Tools:             []ToolDefinition{{Type: "function", Function: FunctionDef{Name: "Read"}}},
+		ToolChoice:        map[string]any{"type": "function", "function": map[string]any{"name": "Read"}},
+		ParallelToolCalls: &noParallel,
+		Stop:              []string{"</done>"},
+		ExtraBody:         map[string]any{"thinking": map[string]any{"type": "enabled", "budget_tokens": 2048}, "betas": []string{"context-1m-2025-08-07"}},
+	})
+
+	if req.MaxTokens != 16384 {
+		t.Errorf("max_tokens = %d, want the 16384 default", req.MaxTokens)
+	}
+	if req.Thinking != nil {
+		t.Error("thinking sent for a tool-use turn without its thinking blocks")
+	}
+	if len(req.AnthropicBeta) != 1 || len(req.StopSequences) != 1 {
+		t.Errorf("anthropic_beta = %v, stop_sequences = %v", req.AnthropicBeta, req.StopSequences)
+	}
+	if req.ToolChoice["type"] != "tool" || req.ToolChoice["name"] != "Read" || req.ToolChoice["disable_parallel_tool_use"] != true {
+		t.Errorf("tool_choice = %v", req.ToolChoice)
+	}
+	if len(req.Messages) != 3 {
+		t.Fatalf("messages = %d, want user, assistant, user", len(req.Messages))
+	}
+	results := req.Messages[2].Content
+	if len(results) != 3 || results[0].Type != "tool_result" || results[1].ToolUseID != "t2" || results[2].Type != "image" ||
+		results[2].Source.MediaType != "image/png" || results[2].Source.Data != "iVBORw0K" {
+		t.Errorf("tool results and image = %+v, want them merged into one user message", results)
+	}
+	if tu := req.Messages[1].Content[1]; tu.Type != "tool_use" || tu.Input.(map[string]any)["file_path"] != "a.png" {
+		t.Errorf("tool_use = %+v", tu)
+	}
+}
+
+func TestBedrockClient_Throttling(t *testing.T) {
+	t.Run("throttled request is retried", func(t *testing.T) {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.Header().Set("X-Amzn-Errortype", "ThrottlingException:http://internal.amazon.com/coral/com.amazon.bedrock/")
+				w.WriteHeader(429)
+				w.Write([]byte(`{"message":"Too many requests, please wait before trying again."}`))
+				return
+			}
+			w.Write(bedrockEvents(
+				`{"type":"message_start","message":{"id":"msg_1","usage":{"input_tokens":5}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":"ok"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+				`{"type":"message_stop"}`,
+			))
+		}))
+		defer srv.Close()
+
+		client := newTestBedrockClient(srv.URL)
+		client.config.Retry = RetryConfig{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond,
+			BackoffFactor: 1, RetryableStatuses: []int{429}}
+		stream, err := client.Complete(context.Background(), &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "Hi"}}})
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if resp, err := stream.Accumulate(); err != nil || resp.Content[0].Text != "ok" {
+			t.Fatalf("Accumulate = %+v, %v", resp, err)
+		}
+		if n := calls.Load(); n != 2 {
+			t.Errorf("calls = %d, want 2", n)
+		}
+	})
+
+	t.Run("throttling exception mid-stream", func(t *testing.T) {
+		body := append(bedrockEvents(`{"type":"message_start","message":{"id":"msg_1"}}`),
+			encodeEventStreamMessage(map[string]string{":message-type": "exception", ":exception-type": "throttlingException"},
+				[]byte(`{"message":"Too many tokens, please wait before trying again."}`))...)
+		srv, _ := bedrockServer(t, body)
+		defer srv.Close()
+
+		stream, err := newTestBedrockClient(srv.URL).Complete(context.Background(), &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "Hi"}}})
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		_, err = stream.Accumulate()
+		var llmErr *LLMError
+		if !errors.As(err, &llmErr) || llmErr.StatusCode != 429 || llmErr.SDKError != "rate_limit" || !llmErr.Retryable {
+			t.Errorf("err = %v, want a retriable 429 rate_limit LLMError", err)
+		}
+	})
+
+	t.Run("validation error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Amzn-Errortype", "ValidationException")
+			w.WriteHeader(400)
+			w.Write([]byte(`{"message":"max_tokens: Field required"}`))
+		}))
+		defer srv.Close()
+
+		_, err := newTestBedrockClient(srv.URL).Complete(context.Background(), &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "Hi"}}})
+		var llmErr *LLMError
+		if !errors.As(err, &llmErr) || llmErr.Retryable || llmErr.Message != "ValidationException: max_tokens: Field required" {
+			t.Errorf("err = %v, want a non-retriable ValidationException", err)
+		}
+	})
+}
+
+func TestBedrockClient_APIKeyAndRegion(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		io.Copy(io.Discard, r.Body)
+		w.Write(bedrockEvents(`{"type":"message_stop"}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_BEARER_TOKEN_BEDROCK", "bedrock-api-key")
+	stream, err := NewBedrockClient(ClientConfig{BaseURL: srv.URL, Model: testBedrockModel, AWSRegion: "eu-west-1"}).
+		Complete(context.Background(), &CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "Hi"}}})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	stream.Accumulate()
+	if auth != "Bearer bedrock-api-key" {
+		t.Errorf("Authorization = %q, want the Bedrock API key", auth)
+	}
+
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	if c := NewBedrockClient(ClientConfig{}); c.config.BaseURL != "https://bedrock-runtime.ap-southeast-2.amazonaws.com" {
+		t.Errorf("BaseURL = %q, want the AWS_REGION endpoint", c.config.BaseURL)
+	}
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	if _, err := NewBedrockClient(ClientConfig{}).Complete(context.Background(), &CompletionRequest{}); err == nil {
+		t.Error("Complete without a region succeeded")
+	}
+}
+
+func TestBedrockModelPricing(t *testing.T) {
+	want, _ := GetPricing("claude-sonnet-4-5-20250929")
+	for _, model := range []string{
+		testBedrockModel,
+		"anthropic.claude-sonnet-4-5-20250929-v1:0",
+		"global.anthropic.claude-sonnet-4-5-20250929-v1:0",
+	} {
+		if got, ok := GetPricing(model); !ok || got != want {
+			t.Errorf("GetPricing(%q) = %+v, %v, want the claude-sonnet-4-5 pricing", model, got, ok)
+		}
+	}
+	if MaxStopSequences(testBedrockModel) != 0 {
+		t.Errorf("MaxStopSequences(%q) = %d, want no limit", testBedrockModel, MaxStopSequences(testBedrockModel))
+	}
+}
@@ -6,6 +6,7 @@ import "sync"
 type ModelCapabilities struct {
 	SupportsToolUse  bool
 	SupportsThinking bool
+	SupportsVision   bool // accepts image content parts
 	MaxInputTokens   int
 	MaxOutputTokens  int
 }
@@ -13,9 +14,9 @@ type ModelCapabilities struct {
 var (
 	capabilityMu sync.RWMutex
 	modelCaps    = map[string]ModelCapabilities{
-		"claude-opus-4-5-20250514":   {SupportsToolUse: true, SupportsThinking: true, MaxInputTokens: 200_000, MaxOutputTokens: 16384},
-		"claude-sonnet-4-5-20250929": {SupportsToolUse: true, SupportsThinking: true, MaxInputTokens: 200_000, MaxOutputTokens: 16384},
-		"claude-haiku-4-5-20251001":  {SupportsToolUse: true, SupportsThinking: true, MaxInputTokens: 200_000, MaxOutputTokens: 16384},
+		"claude-opus-4-5-20250514":   {SupportsToolUse: true, SupportsThinking: true, SupportsVision: true, MaxInputTokens: 200_000, MaxOutputTokens: 16384},
+		"claude-sonnet-4-5-20250929": {SupportsToolUse: true, SupportsThinking: true, SupportsVision: true, MaxInputTokens: 200_000, MaxOutputTokens: 16384},
+		"claude-haiku-4-5-20251001":  {SupportsToolUse: true, SupportsThinking: true, SupportsVision: true, MaxInputTokens: 200_000, MaxOutputTokens: 16384},
 	}
 )
 
@@ -33,3 +34,14 @@ func SetCapabilities(model string, caps ModelCapabilities) {
 	defer capabilityMu.Unlock()
 	modelCaps[model] = caps
 }
+
+// updateCapabilities applies update to a model's capabilities (the zero
+// value if it has none yet) and stores the result.
+func updateCapabilities(model string, update func(*ModelCapabilities)) ModelCapabilities {
+	capabilityMu.Lock()
+	defer capabilityMu.Unlock()
+	caps := modelCaps[model]
+	update(&caps)
+	modelCaps[model] = caps
+	return caps
+}
@@ -5,8 +5,22 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"sync"
+	"time"
+)
+
+// Timeouts for the default HTTP client. There is deliberately no overall
+// request timeout: streamed generations can run for many minutes, so only
+// connecting and waiting for response headers are bounded.
+const (
+	defaultDialTimeout           = 30 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultResponseHeaderTimeout = 2 * time.Minute
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultMaxIdleConnsPerHost   = 10
 )
 
 // Client is the LLM inference client. All methods are safe for concurrent use.
@@ -25,13 +39,15 @@ type Client interface {
 type httpClient struct {
 	config     ClientConfig
 	httpClient *http.Client
+	configErr  error // invalid transport settings, reported by Complete
 	mu         sync.RWMutex
 }
 
 // NewClient creates a new LLM client with the given configuration.
 func NewClient(cfg ClientConfig) Client {
+	var configErr error
 	if cfg.HTTPClient == nil {
-		cfg.HTTPClient = http.DefaultClient
+		cfg.HTTPClient, configErr = newDefaultHTTPClient(cfg)
 	}
 	if cfg.MaxTokens == 0 {
 		cfg.MaxTokens = 16384
@@ -43,11 +59,53 @@ func NewClient(cfg ClientConfig) Client {
 	return &httpClient{
 		config:     cfg,
 		httpClient: cfg.HTTPClient,
+		configErr:  configErr,
 	}
 }
 
+// newDefaultHTTPClient builds a pooled client honoring cfg.ProxyURL and
+// cfg.TLSConfig. Without a ProxyURL the standard proxy environment variables
+// apply.
+func newDefaultHTTPClient(cfg ClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   defaultDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = defaultTLSHandshakeTimeout
+	transport.ResponseHeaderTimeout = defaultResponseHeaderTimeout
+	transport.IdleConnTimeout = defaultIdleConnTimeout
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+	if cfg.TLSConfig != nil {
+		transport.TLSClientConfig = cfg.TLSConfig.Clone()
+	}
+
+	var err error
+	if cfg.ProxyURL != "" {
+		var proxy *url.URL
+		proxy, err = url.Parse(cfg.ProxyURL)
+		if err == nil && (proxy.Scheme == "" || proxy.Host == "") {
+			err = fmt.Errorf("missing scheme or host")
+		}
+		if err != nil {
+			err = fmt.Errorf("llm: invalid ProxyURL %q: %w", cfg.ProxyURL, err)
+		} else {
+			transport.Proxy = http.ProxyURL(proxy)
+		}
+	}
+	return &http.Client{Transport: transport}, err
+}
+
 // Complete sends a streaming completion request and returns a Stream.
 func (c *httpClient) Complete(ctx context.Context, req *CompletionRequest) (*Stream, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+
+	if err := ValidateStopSequences(req.Model, req.Stop); err != nil {
+		return nil, fmt.Errorf("llm: %w", err)
+	}
+
 	// Ensure streaming is enabled
 	req.Stream = true
 	if req.StreamOptions == nil {
@@ -59,6 +117,10 @@ func (c *httpClient) Complete(ctx context.Context, req *CompletionRequest) (*Str
 		return nil, fmt.Errorf("llm: marshal request: %w", err)
 	}
 
+	if _, err := c.config.RateLimiter.Wait(ctx, estimateRequestTokens(body, req.MaxTokens)); err != nil {
+		return nil, err
+	}
+
 	url := c.config.BaseURL + "/chat/completions"
 
 	resp, err := doWithRetry(ctx, c.config.Retry, func(ctx context.Context) (*http.Response, error) {
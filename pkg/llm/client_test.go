@@ -2,9 +2,12 @@ package llm
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -249,3 +252,85 @@ data: [DONE]
 		}
 	})
 }
+
+const minimalSSE = `data: {"id":"chatcmpl-net","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"ok"},"finish_reason":"stop"}]}
+
+data: [DONE]
+`
+
+func serveMinimalSSE(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprint(w, minimalSSE)
+}
+
+func completeOnce(t *testing.T, client Client) error {
+	t.Helper()
+	stream, err := client.Complete(context.Background(), &CompletionRequest{
+		Model:    "m",
+		Messages: []ChatMessage{{Role: "user", Content: "Hi"}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = stream.Accumulate()
+	return err
+}
+
+func TestNewClient_HTTPTransport(t *testing.T) {
+	t.Run("default client bounds connect and headers but not the body", func(t *testing.T) {
+		c := NewClient(ClientConfig{}).(*httpClient)
+		if c.httpClient.Timeout != 0 {
+			t.Errorf("Timeout = %v, want none so long streams are not cut off", c.httpClient.Timeout)
+		}
+		transport := c.httpClient.Transport.(*http.Transport)
+		if transport.ResponseHeaderTimeout != defaultResponseHeaderTimeout || transport.TLSHandshakeTimeout != defaultTLSHandshakeTimeout {
+			t.Errorf("transport timeouts = %v/%v", transport.ResponseHeaderTimeout, transport.TLSHandshakeTimeout)
+		}
+		if transport.Proxy == nil {
+			t.Error("default client should honor proxy environment variables")
+		}
+	})
+
+	t.Run("custom CA via TLSConfig", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serveMinimalSSE(w)
+		}))
+		defer srv.Close()
+
+		noRetry := RetryConfig{MaxRetries: 0, InitialBackoff: time.Millisecond}
+		if err := completeOnce(t, NewClient(ClientConfig{BaseURL: srv.URL, Retry: noRetry})); err == nil {
+			t.Fatal("expected a certificate error without the custom CA")
+		}
+
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+		client := NewClient(ClientConfig{BaseURL: srv.URL, Retry: noRetry, TLSConfig: &tls.Config{RootCAs: pool}})
+		if err := completeOnce(t, client); err != nil {
+			t.Fatalf("request with custom CA failed: %v", err)
+		}
+	})
+
+	t.Run("ProxyURL routes requests through the proxy", func(t *testing.T) {
+		var proxied atomic.Value
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied.Store(r.URL.String()) // absolute URI of the upstream request
+			serveMinimalSSE(w)
+		}))
+		defer proxy.Close()
+
+		client := NewClient(ClientConfig{BaseURL: "http://llm.internal:4000/v1", ProxyURL: proxy.URL})
+		if err := completeOnce(t, client); err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if got, _ := proxied.Load().(string); got != "http://llm.internal:4000/v1/chat/completions" {
+			t.Errorf("proxy saw %q", got)
+		}
+	})
+
+	t.Run("invalid ProxyURL is reported by Complete", func(t *testing.T) {
+		err := completeOnce(t, NewClient(ClientConfig{BaseURL: "http://localhost:1", ProxyURL: "proxy:3128"}))
+		if err == nil || !strings.Contains(err.Error(), "invalid ProxyURL") {
+			t.Errorf("error = %v", err)
+		}
+	})
+}
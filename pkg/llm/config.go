@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"crypto/tls"
 	"net/http"
 	"time"
 )
@@ -12,11 +13,21 @@ type ClientConfig struct {
 	Model              string            // Default model, e.g. "anthropic/claude-opus-4-5-20250514"
 	MaxTokens          int               // Default max_tokens for responses (16384)
 	MaxThinkingTokens  int               // Budget tokens for extended thinking (0 = disabled)
+	ReasoningEffort    string            // "low" | "medium" | "high"; reasoning_effort for o-series, a thinking budget for Claude
 	Betas              []string          // Beta feature flags, e.g. ["context-1m-2025-08-07"]
+	StopSequences      []string          // Custom strings that end generation, e.g. ["</final>"]
+	ParallelToolCalls  *bool             // parallel_tool_calls when tools are sent (nil = provider default)
+	StrictTools        bool              // strict function calling; tool schemas are normalized with StrictSchema
 	Headers            map[string]string // Additional HTTP headers
-	HTTPClient         *http.Client      // Custom HTTP client (for timeouts, TLS, proxies)
+	Extras             map[string]any    // Provider-specific options, e.g. OllamaClient's num_ctx and keep_alive
+	HTTPClient         *http.Client      // Custom HTTP client (nil = pooled client with connect/header timeouts)
+	ProxyURL           string            // Proxy for the default client, e.g. "http://proxy:3128" (default: HTTPS_PROXY etc.)
+	TLSConfig          *tls.Config       // TLS settings for the default client, e.g. a custom CA pool
 	Retry              RetryConfig
 	CostTracker        *CostTracker // Optional cost accumulation across requests
+	RateLimiter        *RateLimiter // Optional RPM/TPM gate; share it to cap combined traffic
+	AWSRegion          string                 // BedrockClient region (default: AWS_REGION, AWS_DEFAULT_REGION, the shared config profile)
+	AWSCredentials     AWSCredentialsProvider // BedrockClient credentials (nil = DefaultAWSCredentials chain)
 }
 
 // RetryConfig controls retry behavior for transient failures.
@@ -25,11 +25,63 @@ var DefaultPricing = map[string]ModelPricing{
 var pricingMu sync.RWMutex
 
 // GetPricing returns the pricing for a model and whether it was found.
+// Provider prefixes are ignored, and a model without an exact entry falls
+// back to a wildcard key such as "claude-sonnet-4-5-*" (longest prefix wins)
+// or to another dated snapshot of the same model.
 func GetPricing(model string) (ModelPricing, bool) {
 	pricingMu.RLock()
 	defer pricingMu.RUnlock()
-	p, ok := DefaultPricing[model]
-	return p, ok
+	normalized := normalizeModelID(model)
+	if p, ok := DefaultPricing[normalized]; ok {
+		return p, true
+	}
+	// Try original (for dynamic pricing with prefixed keys)
+	if p, ok := DefaultPricing[model]; ok {
+		return p, true
+	}
+	return fuzzyPricing(normalized)
+}
+
+// fuzzyPricing matches wildcard keys, then keys that differ from model only
+// in a -YYYYMMDD date suffix (the latest such snapshot wins). Callers must
+// hold pricingMu.
+func fuzzyPricing(model string) (ModelPricing, bool) {
+	var best string
+	for key := range DefaultPricing {
+		prefix, ok := strings.CutSuffix(key, "*")
+		if ok && strings.HasPrefix(model, prefix) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best != "" {
+		return DefaultPricing[best], true
+	}
+
+	base := stripDateSuffix(model)
+	for key := range DefaultPricing {
+		if !strings.HasSuffix(key, "*") && stripDateSuffix(key) == base && key > best {
+			best = key
+		}
+	}
+	if best != "" {
+		return DefaultPricing[best], true
+	}
+	return ModelPricing{}, false
+}
+
+// stripDateSuffix removes a trailing snapshot date, e.g.
+// "claude-haiku-4-5-20251001" → "claude-haiku-4-5".
+func stripDateSuffix(model string) string {
+	i := strings.LastIndexByte(model, '-')
+	if i < 0 || len(model)-i-1 != 8 {
+		return model
+	}
+	for _, c := range model[i+1:] {
+		if c < '0' || c > '9' {
+			return model
+		}
+	}
+	return model[:i]
 }
 
 // SetPricing sets the pricing for a model. Safe for concurrent use.
@@ -39,24 +91,70 @@ func SetPricing(model string, p ModelPricing) {
 	DefaultPricing[model] = p
 }
 
-// normalizeModelID strips provider prefixes (e.g. "anthropic/", "openai/") from model IDs.
+// RegisterModelPrice sets a model's prices in USD per million tokens. Cache
+// writes are billed at the input price; use SetPricing to set them
+// separately. model may end in "*" to cover dated variants, e.g.
+// "claude-sonnet-4-5-*".
+func RegisterModelPrice(model string, in, out, cachedIn float64) {
+	SetPricing(model, ModelPricing{
+		InputPerMTok:       in,
+		OutputPerMTok:      out,
+		CacheReadPerMTok:   cachedIn,
+		CacheCreatePerMTok: in,
+	})
+}
+
+var (
+	unknownMu      sync.Mutex
+	unknownHandler func(model string)
+	unknownWarned  = map[string]bool{}
+)
+
+// SetUnknownModelHandler registers fn to be called the first time cost is
+// calculated for a model without pricing (which is then counted as $0), so
+// callers can warn that budgets will not see its spend. nil disables it.
+func SetUnknownModelHandler(fn func(model string)) {
+	unknownMu.Lock()
+	defer unknownMu.Unlock()
+	unknownHandler = fn
+	unknownWarned = map[string]bool{}
+}
+
+func reportUnknownModel(model string) {
+	unknownMu.Lock()
+	fn := unknownHandler
+	first := fn != nil && !unknownWarned[model]
+	if first {
+		unknownWarned[model] = true
+	}
+	unknownMu.Unlock()
+	if first {
+		fn(model)
+	}
+}
+
+// normalizeModelID strips provider prefixes (e.g. "anthropic/", "openai/") from model IDs,
+// and maps Bedrock Claude IDs to their Anthropic model names.
 func normalizeModelID(model string) string {
 	if idx := strings.Index(model, "/"); idx >= 0 {
-		return model[idx+1:]
+		model = model[idx+1:]
+	}
+	if name := bedrockModelName(model); name != "" {
+		return name
 	}
 	return model
 }
 
-// CalculateCost computes the USD cost for a single API response.
+// CalculateCost computes the USD cost for a single API response. Models
+// without pricing cost $0 and are reported to the SetUnknownModelHandler
+// callback, once per model. Local "ollama/" models are free and not reported.
 func CalculateCost(model string, usage types.BetaUsage) float64 {
-	normalizedModel := normalizeModelID(model)
-	pricing, ok := GetPricing(normalizedModel)
+	pricing, ok := GetPricing(model)
 	if !ok {
-		// Try original (for dynamic pricing with prefixed keys)
-		pricing, ok = GetPricing(model)
-		if !ok {
-			return 0
+		if !IsOllamaModel(model) {
+			reportUnknownModel(model)
 		}
+		return 0
 	}
 	cost := float64(usage.InputTokens) * pricing.InputPerMTok / 1_000_000
 	cost += float64(usage.OutputTokens) * pricing.OutputPerMTok / 1_000_000
@@ -71,6 +169,7 @@ type CostTracker struct {
 	mu         sync.Mutex
 	totalCost  float64
 	modelUsage map[string]*ModelUsageAccum
+	parent     *CostTracker // also receives every Add (nil = root tracker)
 }
 
 // ModelUsageAccum holds per-model token accumulation.
@@ -89,8 +188,20 @@ func NewCostTracker() *CostTracker {
 	}
 }
 
+// NewChildCostTracker creates a CostTracker whose totals cover only the usage
+// added to it, while every Add is also recorded in parent. A subagent uses
+// one to enforce its own budget and still count toward the parent's spend.
+func NewChildCostTracker(parent *CostTracker) *CostTracker {
+	ct := NewCostTracker()
+	ct.parent = parent
+	return ct
+}
+
 // Add records usage from a single API response and returns cumulative cost.
 func (ct *CostTracker) Add(model string, usage types.BetaUsage) float64 {
+	if ct.parent != nil {
+		ct.parent.Add(model, usage)
+	}
 	cost := CalculateCost(model, usage)
 	normalizedModel := normalizeModelID(model)
 	ct.mu.Lock()
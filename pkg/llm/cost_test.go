@@ -109,6 +109,21 @@ func TestCostTracker(t *testing.T) {
 		}
 	})
 
+	t.Run("child totals are local and roll up to the parent", func(t *testing.T) {
+		parent := NewCostTracker()
+		parent.Add("claude-opus-4-5-20250514", types.BetaUsage{InputTokens: 1000})
+		child := NewChildCostTracker(parent)
+		if total := child.Add("claude-opus-4-5-20250514", types.BetaUsage{InputTokens: 1000}); math.Abs(total-0.015) > 1e-10 {
+			t.Errorf("child Add = %f, want 0.015", total)
+		}
+		if total := parent.TotalCost(); math.Abs(total-0.030) > 1e-10 {
+			t.Errorf("parent TotalCost = %f, want 0.030", total)
+		}
+		if in := parent.ModelBreakdown()["claude-opus-4-5-20250514"].InputTokens; in != 2000 {
+			t.Errorf("parent InputTokens = %d, want 2000", in)
+		}
+	})
+
 	t.Run("model breakdown", func(t *testing.T) {
 		ct := NewCostTracker()
 		ct.Add("claude-opus-4-5-20250514", types.BetaUsage{InputTokens: 1000, OutputTokens: 500})
@@ -162,3 +177,63 @@ func TestCostTracker(t *testing.T) {
 		}
 	})
 }
+
+func TestGetPricingFuzzy(t *testing.T) {
+	origPricing := snapshotPricing()
+	defer restorePricing(origPricing)
+
+	RegisterModelPrice("gpt-6-*", 1, 2, 0.1)
+	RegisterModelPrice("gpt-6-mini-*", 0.5, 1, 0.05)
+
+	tests := []struct {
+		model     string
+		wantInput float64
+		wantOK    bool
+	}{
+		{"gpt-6-2026-09-01", 1, true},
+		{"openai/gpt-6-mini-2026-09-01", 0.5, true}, // longest wildcard wins
+		{"claude-sonnet-4-5-20261201", 3.0, true},   // other dated snapshot
+		{"claude-sonnet-4-5", 3.0, true},            // undated alias
+		{"gpt-7", 0, false},
+	}
+	for _, tt := range tests {
+		p, ok := GetPricing(tt.model)
+		if ok != tt.wantOK || p.InputPerMTok != tt.wantInput {
+			t.Errorf("GetPricing(%q) = %v/%v, want input %v/%v", tt.model, p.InputPerMTok, ok, tt.wantInput, tt.wantOK)
+		}
+	}
+
+	p, _ := GetPricing("gpt-6-mini-x")
+	if p.CacheReadPerMTok != 0.05 || p.CacheCreatePerMTok != 0.5 {
+		t.Errorf("RegisterModelPrice cache prices = %+v", p)
+	}
+}
+
+func TestStripDateSuffix(t *testing.T) {
+	tests := map[string]string{
+		"claude-haiku-4-5-20251001": "claude-haiku-4-5",
+		"claude-haiku-4-5":          "claude-haiku-4-5",
+		"model-2025100":             "model-2025100",
+		"model-2025100a":            "model-2025100a",
+	}
+	for in, want := range tests {
+		if got := stripDateSuffix(in); got != want {
+			t.Errorf("stripDateSuffix(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestUnknownModelHandler(t *testing.T) {
+	var warned []string
+	SetUnknownModelHandler(func(model string) { warned = append(warned, model) })
+	defer SetUnknownModelHandler(nil)
+
+	ct := NewCostTracker()
+	ct.Add("mystery-model", types.BetaUsage{InputTokens: 10})
+	ct.Add("mystery-model", types.BetaUsage{InputTokens: 10})
+	ct.Add("claude-haiku-4-5-20251001", types.BetaUsage{InputTokens: 10})
+
+	if len(warned) != 1 || warned[0] != "mystery-model" {
+		t.Errorf("warned = %v, want [mystery-model] once", warned)
+	}
+}
@@ -23,6 +23,9 @@ func EmitAssistantMessage(resp *CompletionResponse, parentToolUseID *string, ses
 		Message:         resp.ToBetaMessage(),
 		ParentToolUseID: parentToolUseID,
 		Error:           err,
+		StopReason:      resp.StopReason,
+		StopSequence:    resp.StopSequence,
+		FinishReason:    resp.FinishReason,
 	}
 }
 
@@ -72,6 +72,31 @@ func TestEmitAssistantMessage(t *testing.T) {
 		if msg.Message.ID != "chatcmpl-1" {
 			t.Errorf("Message.ID = %q", msg.Message.ID)
 		}
+		if msg.StopReason != "end_turn" || msg.FinishReason != "stop" || msg.StopSequence != "" {
+			t.Errorf("stop details = %q, %q, %q", msg.StopReason, msg.FinishReason, msg.StopSequence)
+		}
+		if msg.Message.StopSequence != nil {
+			t.Errorf("Message.StopSequence = %q, want nil", *msg.Message.StopSequence)
+		}
+	})
+
+	t.Run("stop sequence", func(t *testing.T) {
+		resp := &CompletionResponse{
+			ID:           "chatcmpl-3",
+			Model:        "gpt-4o",
+			FinishReason: "stop",
+			StopReason:   "stop_sequence",
+			StopSequence: "</final>",
+		}
+
+		msg := EmitAssistantMessage(resp, nil, "session-1", nil)
+
+		if msg.StopReason != "stop_sequence" || msg.StopSequence != "</final>" {
+			t.Errorf("stop details = %q, %q", msg.StopReason, msg.StopSequence)
+		}
+		if msg.Message.StopSequence == nil || *msg.Message.StopSequence != "</final>" {
+			t.Errorf("Message.StopSequence = %v", msg.Message.StopSequence)
+		}
 	})
 
 	t.Run("error case", func(t *testing.T) {
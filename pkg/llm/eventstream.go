@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// AWS event stream framing (application/vnd.amazon.eventstream), used by
+// Bedrock's streaming APIs. Each message is
+//
+//	total length (4) | headers length (4) | prelude CRC (4) | headers | payload | message CRC (4)
+//
+// with big-endian lengths and CRC32 (IEEE) checksums.
+const (
+	eventStreamPreludeLen = 12
+	eventStreamMinLen     = eventStreamPreludeLen + 4
+	eventStreamMaxLen     = 16 * 1024 * 1024
+)
+
+// eventStreamMessage is one decoded event stream message. Only string
+// headers (":message-type", ":event-type", ":exception-type", ...) are kept.
+type eventStreamMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// readEventStreamMessage reads the next message from r, returning io.EOF at
+// a clean end of stream.
+func readEventStreamMessage(r io.Reader) (eventStreamMessage, error) {
+	var msg eventStreamMessage
+	prelude := make([]byte, eventStreamPreludeLen)
+	if _, err := io.ReadFull(r, prelude); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return msg, fmt.Errorf("llm: event stream: truncated prelude")
+		}
+		return msg, err
+	}
+	totalLen := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return msg, fmt.Errorf("llm: event stream: prelude checksum mismatch")
+	}
+	if totalLen < eventStreamMinLen || totalLen > eventStreamMaxLen || headersLen > totalLen-eventStreamMinLen {
+		return msg, fmt.Errorf("llm: event stream: invalid message length %d (headers %d)", totalLen, headersLen)
+	}
+
+	buf := make([]byte, totalLen)
+	copy(buf, prelude)
+	if _, err := io.ReadFull(r, buf[eventStreamPreludeLen:]); err != nil {
+		return msg, fmt.Errorf("llm: event stream: truncated message: %w", err)
+	}
+	end := totalLen - 4
+	if crc32.ChecksumIEEE(buf[:end]) != binary.BigEndian.Uint32(buf[end:]) {
+		return msg, fmt.Errorf("llm: event stream: message checksum mismatch")
+	}
+
+	headersEnd := eventStreamPreludeLen + headersLen
+	headers, err := parseEventStreamHeaders(buf[eventStreamPreludeLen:headersEnd])
+	if err != nil {
+		return msg, err
+	}
+	msg.Headers = headers
+	msg.Payload = buf[headersEnd:end]
+	return msg, nil
+}
+
+// parseEventStreamHeaders decodes a headers block, skipping values that
+// aren't strings.
+func parseEventStreamHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	errTruncated := fmt.Errorf("llm: event stream: truncated headers")
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, errTruncated
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		var size int
+		switch valueType {
+		case 0, 1: // bool true, bool false
+			size = 0
+		case 2: // byte
+			size = 1
+		case 3: // int16
+			size = 2
+		case 4: // int32
+			size = 4
+		case 5, 8: // int64, timestamp
+			size = 8
+		case 9: // uuid
+			size = 16
+		case 6, 7: // bytes, string
+			if len(b) < 2 {
+				return nil, errTruncated
+			}
+			size = 2 + int(binary.BigEndian.Uint16(b))
+		default:
+			return nil, fmt.Errorf("llm: event stream: unknown header type %d", valueType)
+		}
+		if len(b) < size {
+			return nil, errTruncated
+		}
+		if valueType == 7 {
+			headers[name] = string(b[2:size])
+		}
+		b = b[size:]
+	}
+	return headers, nil
+}
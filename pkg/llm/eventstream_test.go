@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encodeEventStreamMessage frames payload with string headers.
+func encodeEventStreamMessage(headers map[string]string, payload []byte) []byte {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var hdr bytes.Buffer
+	for _, name := range names {
+		hdr.WriteByte(byte(len(name)))
+		hdr.WriteString(name)
+		hdr.WriteByte(7)
+		binary.Write(&hdr, binary.BigEndian, uint16(len(headers[name])))
+		hdr.WriteString(headers[name])
+	}
+
+	total := eventStreamPreludeLen + hdr.Len() + len(payload) + 4
+	msg := make([]byte, 0, total)
+	msg = binary.BigEndian.AppendUint32(msg, uint32(total))
+	msg = binary.BigEndian.AppendUint32(msg, uint32(hdr.Len()))
+	msg = binary.BigEndian.AppendUint32(msg, crc32.ChecksumIEEE(msg))
+	msg = append(msg, hdr.Bytes()...)
+	msg = append(msg, payload...)
+	return binary.BigEndian.AppendUint32(msg, crc32.ChecksumIEEE(msg))
+}
+
+func TestReadEventStreamMessage(t *testing.T) {
+	first := encodeEventStreamMessage(map[string]string{":message-type": "event", ":event-type": "chunk"}, []byte(`{"bytes":"e30="}`))
+	second := encodeEventStreamMessage(map[string]string{":message-type": "exception", ":exception-type": "throttlingException"}, nil)
+	r := bytes.NewReader(append(first, second...))
+
+	msg, err := readEventStreamMessage(r)
+	if err != nil {
+		t.Fatalf("first message: %v", err)
+	}
+	if msg.Headers[":event-type"] != "chunk" || string(msg.Payload) != `{"bytes":"e30="}` {
+		t.Errorf("first message = %+v", msg)
+	}
+	msg, err = readEventStreamMessage(r)
+	if err != nil {
+		t.Fatalf("second message: %v", err)
+	}
+	if msg.Headers[":exception-type"] != "throttlingException" || len(msg.Payload) != 0 {
+		t.Errorf("second message = %+v", msg)
+	}
+	if _, err := readEventStreamMessage(r); !errors.Is(err, io.EOF) {
+		t.Errorf("after the last message err = %v, want io.EOF", err)
+	}
+}
+
+func TestReadEventStreamMessage_Corrupt(t *testing.T) {
+	valid := encodeEventStreamMessage(map[string]string{":message-type": "event"}, []byte("payload"))
+
+	corrupt := func(i int) []byte {
+		b := bytes.Clone(valid)
+		b[i] ^= 0xff
+		return b
+	}
+	for name, data := range map[string][]byte{
+		"prelude checksum": corrupt(1),
+		"message checksum": corrupt(len(valid) - 6),
+		"truncated":        valid[:len(valid)-3],
+	} {
+		if _, err := readEventStreamMessage(bytes.NewReader(data)); err == nil || !strings.HasPrefix(err.Error(), "llm: event stream:") {
+			t.Errorf("%s: err = %v, want an event stream error", name, err)
+		}
+	}
+}
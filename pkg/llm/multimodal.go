@@ -0,0 +1,210 @@
+package llm
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// ImageTokenEstimate is the approximate token cost of one image, used for
+// context accounting before the API reports real usage. It matches a
+// ~1.15 megapixel image, the largest size sent without downscaling.
+const ImageTokenEstimate = 1600
+
+// imageOmittedText replaces image parts for models without vision support.
+const imageOmittedText = "[image omitted: the current model does not support image input]"
+
+// TextPart returns a text content part.
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: "text", Text: text}
+}
+
+// ImageURLPart returns an image content part referencing an HTTPS URL or a
+// data URI.
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url}}
+}
+
+// ImageDataPart returns an image content part carrying base64-encoded data
+// of the given media type (e.g. "image/png").
+func ImageDataPart(mediaType string, data []byte) ContentPart {
+	return ImageURLPart("data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data))
+}
+
+// CountImageParts returns the number of image parts in message content,
+// which may be []ContentPart or its decoded []any form.
+func CountImageParts(content any) int {
+	n := 0
+	switch c := content.(type) {
+	case []ContentPart:
+		for _, part := range c {
+			if part.Type == "image_url" {
+				n++
+			}
+		}
+	case []any:
+		for _, part := range c {
+			if m, ok := part.(map[string]any); ok && m["type"] == "image_url" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// SupportsVision reports whether model accepts image content. Registered
+// capabilities decide; otherwise Groq/Llama and local models are assumed
+// text-only and everything else vision-capable.
+func SupportsVision(model string) bool {
+	if caps, ok := GetCapabilities(model); ok {
+		return caps.SupportsVision
+	}
+	return !IsGroqLlama(model) && !IsLocalModel(model)
+}
+
+// stripImages replaces image parts with a text placeholder so text-only
+// models get a readable message instead of an API error. Messages without
+// images are returned as-is; the input slice is never modified.
+func stripImages(messages []ChatMessage) []ChatMessage {
+	var out []ChatMessage
+	for i, msg := range messages {
+		if CountImageParts(msg.Content) == 0 {
+			if out != nil {
+				out = append(out, msg)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]ChatMessage, 0, len(messages)), messages[:i]...)
+		}
+		msg.Content = textOnlyContent(msg.Content)
+		out = append(out, msg)
+	}
+	if out == nil {
+		return messages
+	}
+	return out
+}
+
+// textOnlyContent flattens multi-part content to a string, substituting a
+// placeholder for each image.
+func textOnlyContent(content any) string {
+	var texts []string
+	switch c := content.(type) {
+	case []ContentPart:
+		for _, part := range c {
+			if part.Type == "image_url" {
+				texts = append(texts, imageOmittedText)
+			} else if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	case []any:
+		for _, part := range c {
+			m, ok := part.(map[string]any)
+			if !ok {
+				continue
+			}
+			if m["type"] == "image_url" {
+				texts = append(texts, imageOmittedText)
+			} else if text, ok := m["text"].(string); ok && text != "" {
+				texts = append(texts, text)
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// supportsToolResultImages reports whether the provider accepts image parts
+// inside "tool" messages. Anthropic models do (the proxy maps them to
+// tool_result image blocks); OpenAI-style APIs only allow text there.
+func supportsToolResultImages(model string) bool {
+	return strings.HasPrefix(toRequestModel(model), "anthropic/")
+}
+
+// hoistToolImages moves image parts out of tool messages, which keep their
+// text, into a user message placed right after each run of tool messages.
+// Messages without tool images are returned as-is; the input slice is never
+// modified.
+func hoistToolImages(messages []ChatMessage) []ChatMessage {
+	hasToolImages := false
+	for _, msg := range messages {
+		if msg.Role == "tool" && CountImageParts(msg.Content) > 0 {
+			hasToolImages = true
+			break
+		}
+	}
+	if !hasToolImages {
+		return messages
+	}
+
+	out := make([]ChatMessage, 0, len(messages)+1)
+	var pending []ContentPart
+	flush := func() {
+		if len(pending) > 0 {
+			parts := append([]ContentPart{TextPart("Images from the tool results above:")}, pending...)
+			out = append(out, ChatMessage{Role: "user", Content: parts})
+			pending = nil
+		}
+	}
+	for _, msg := range messages {
+		if msg.Role != "tool" {
+			flush()
+			out = append(out, msg)
+			continue
+		}
+		if images := imageParts(msg.Content); len(images) > 0 {
+			pending = append(pending, images...)
+			msg.Content = textParts(msg.Content)
+		}
+		out = append(out, msg)
+	}
+	flush()
+	return out
+}
+
+// imageParts returns the image parts of multi-part content.
+func imageParts(content any) []ContentPart {
+	var images []ContentPart
+	switch c := content.(type) {
+	case []ContentPart:
+		for _, part := range c {
+			if part.Type == "image_url" {
+				images = append(images, part)
+			}
+		}
+	case []any:
+		for _, part := range c {
+			m, ok := part.(map[string]any)
+			if !ok || m["type"] != "image_url" {
+				continue
+			}
+			if img, ok := m["image_url"].(map[string]any); ok {
+				url, _ := img["url"].(string)
+				images = append(images, ImageURLPart(url))
+			}
+		}
+	}
+	return images
+}
+
+// textParts joins the text parts of multi-part content.
+func textParts(content any) string {
+	var texts []string
+	switch c := content.(type) {
+	case []ContentPart:
+		for _, part := range c {
+			if part.Type == "text" && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	case []any:
+		for _, part := range c {
+			if m, ok := part.(map[string]any); ok && m["type"] == "text" {
+				if text, ok := m["text"].(string); ok && text != "" {
+					texts = append(texts, text)
+				}
+			}
+		}
+	}
+	return strings.Join(texts, "\n")
+}
@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestImageDataPart(t *testing.T) {
+	part := ImageDataPart("image/png", []byte("png"))
+	if part.Type != "image_url" || part.ImageURL.URL != "data:image/png;base64,cG5n" {
+		t.Errorf("part = %+v", part)
+	}
+
+	data, _ := json.Marshal(part)
+	if string(data) != `{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}` {
+		t.Errorf("wire format = %s", data)
+	}
+}
+
+func TestCountImageParts(t *testing.T) {
+	parts := []ContentPart{TextPart("a"), ImageURLPart("https://x/1.png"), ImageURLPart("https://x/2.png")}
+	if n := CountImageParts(parts); n != 2 {
+		t.Errorf("[]ContentPart: got %d, want 2", n)
+	}
+
+	// Content decoded from a stored session
+	var decoded []any
+	data, _ := json.Marshal(parts)
+	json.Unmarshal(data, &decoded)
+	if n := CountImageParts(decoded); n != 2 {
+		t.Errorf("[]any: got %d, want 2", n)
+	}
+	if n := CountImageParts("text"); n != 0 {
+		t.Errorf("string: got %d, want 0", n)
+	}
+}
+
+func TestSupportsVision(t *testing.T) {
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"claude-sonnet-4-5-20250929", true},
+		{"gpt-4o", true},
+		{"llama-3.3-70b-versatile", false},
+		{"qwen3-4b-local", false},
+	}
+	for _, tt := range tests {
+		if got := SupportsVision(tt.model); got != tt.want {
+			t.Errorf("SupportsVision(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+
+	SetCapabilities("vision-test-model", ModelCapabilities{SupportsToolUse: true})
+	if SupportsVision("vision-test-model") {
+		t.Error("registered capabilities without SupportsVision should disable images")
+	}
+}
+
+func TestBuildCompletionRequest_Images(t *testing.T) {
+	messages := []ChatMessage{
+		{Role: "user", Content: "first"},
+		{Role: "user", Content: []ContentPart{TextPart("what is this?"), ImageURLPart("https://x/shot.png")}},
+	}
+
+	t.Run("vision model passes images through", func(t *testing.T) {
+		req := BuildCompletionRequest(ClientConfig{Model: "claude-sonnet-4-5-20250929"}, "sys", messages, nil, LoopState{})
+		parts, ok := req.Messages[2].Content.([]ContentPart)
+		if !ok || len(parts) != 2 || parts[1].ImageURL.URL != "https://x/shot.png" {
+			t.Errorf("content = %#v, want the image part unchanged", req.Messages[2].Content)
+		}
+	})
+
+	t.Run("text-only model gets a placeholder", func(t *testing.T) {
+		req := BuildCompletionRequest(ClientConfig{Model: "llama-3.3-70b-versatile"}, "sys", messages, nil, LoopState{})
+		content, ok := req.Messages[2].Content.(string)
+		if !ok || !strings.HasPrefix(content, "what is this?\n") || !strings.Contains(content, "image omitted") {
+			t.Errorf("content = %#v, want text with an image placeholder", req.Messages[2].Content)
+		}
+		if req.Messages[1].Content != "first" {
+			t.Errorf("messages without images should be unchanged: %#v", req.Messages[1])
+		}
+		if _, ok := messages[1].Content.([]ContentPart); !ok {
+			t.Error("caller's messages must not be modified")
+		}
+	})
+}
+
+func TestConvertToToolMessages_Images(t *testing.T) {
+	msgs := ConvertToToolMessages([]ToolResult{
+		{ToolUseID: "call_1", Content: "Image: /tmp/shot.png", Images: []ContentPart{ImageURLPart("data:image/png;base64,AA==")}},
+	})
+	parts, ok := msgs[0].Content.([]ContentPart)
+	if !ok || len(parts) != 2 || parts[0].Text != "Image: /tmp/shot.png" || parts[1].Type != "image_url" {
+		t.Errorf("content = %#v, want text then image", msgs[0].Content)
+	}
+}
+
+func TestBuildCompletionRequest_ToolResultImages(t *testing.T) {
+	messages := []ChatMessage{
+		{Role: "user", Content: "look at the screenshot"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "Read"}}}},
+	}
+	messages = append(messages, ConvertToToolMessages([]ToolResult{
+		{ToolUseID: "call_1", Content: "Image: /tmp/shot.png", Images: []ContentPart{ImageURLPart("data:image/png;base64,AA==")}},
+	})...)
+
+	t.Run("anthropic keeps images in the tool result", func(t *testing.T) {
+		req := BuildCompletionRequest(ClientConfig{Model: "claude-sonnet-4-5-20250929"}, "sys", messages, nil, LoopState{})
+		if len(req.Messages) != 4 || CountImageParts(req.Messages[3].Content) != 1 {
+			t.Errorf("messages = %#v", req.Messages)
+		}
+	})
+
+	t.Run("openai-style providers get a follow-up user message", func(t *testing.T) {
+		req := BuildCompletionRequest(ClientConfig{Model: "gpt-4o"}, "sys", messages, nil, LoopState{})
+		if len(req.Messages) != 5 {
+			t.Fatalf("got %d messages, want the images hoisted into a 5th", len(req.Messages))
+		}
+		if tool := req.Messages[3]; tool.Role != "tool" || tool.Content != "Image: /tmp/shot.png" {
+			t.Errorf("tool message = %#v, want text only", tool)
+		}
+		if user := req.Messages[4]; user.Role != "user" || CountImageParts(user.Content) != 1 {
+			t.Errorf("hoisted message = %#v", user)
+		}
+	})
+
+	t.Run("text-only models get a placeholder", func(t *testing.T) {
+		req := BuildCompletionRequest(ClientConfig{Model: "llama-3.3-70b-versatile"}, "sys", messages, nil, LoopState{})
+		content, _ := req.Messages[3].Content.(string)
+		if !strings.Contains(content, "Image: /tmp/shot.png") || !strings.Contains(content, "image omitted") {
+			t.Errorf("tool content = %#v", req.Messages[3].Content)
+		}
+	})
+}
@@ -0,0 +1,373 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ollamaDefaultBaseURL = "http://localhost:11434"
+	ollamaMaxLine        = 16 * 1024 * 1024 // a single NDJSON line, e.g. one carrying large tool arguments
+)
+
+// OllamaClient talks to Ollama's native /api/chat endpoint rather than its
+// OpenAI-compatible shim, so model options such as num_ctx and keep_alive
+// can be set. It implements Client and streams Ollama's NDJSON responses as
+// the same StreamChunks the OpenAI-format client produces.
+//
+// ClientConfig.Extras holds the Ollama options: "keep_alive" (e.g. "30m" or
+// -1) is sent with the request, and every other key ("num_ctx",
+// "num_predict", "temperature", ...) goes into its options, overriding
+// values derived from the request. Models may be named with an "ollama/"
+// prefix ("ollama/llama3.1"), which is stripped before sending and makes
+// cost tracking count them as free.
+type OllamaClient struct {
+	config     ClientConfig
+	httpClient *http.Client
+	configErr  error
+	mu         sync.RWMutex
+}
+
+// NewOllamaClient creates an OllamaClient. BaseURL is the Ollama server
+// (default http://localhost:11434); APIKey, if set, is sent as a bearer
+// token for servers behind an authenticating proxy.
+func NewOllamaClient(cfg ClientConfig) *OllamaClient {
+	var configErr error
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient, configErr = newDefaultHTTPClient(cfg)
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = ollamaDefaultBaseURL
+	}
+	if cfg.Retry.MaxRetries == 0 && cfg.Retry.InitialBackoff == 0 {
+		cfg.Retry = DefaultRetryConfig()
+	}
+	return &OllamaClient{config: cfg, httpClient: cfg.HTTPClient, configErr: configErr}
+}
+
+// IsOllamaModel reports whether model is named with the "ollama/" prefix.
+func IsOllamaModel(model string) bool {
+	return strings.HasPrefix(model, "ollama/")
+}
+
+// ollamaRequest is the /api/chat request body.
+type ollamaRequest struct {
+	Model     string           `json:"model"`
+	Messages  []ollamaMessage  `json:"messages"`
+	Tools     []ToolDefinition `json:"tools,omitempty"`
+	Stream    bool             `json:"stream"`
+	Options   map[string]any   `json:"options,omitempty"`
+	KeepAlive any              `json:"keep_alive,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Thinking  string           `json:"thinking,omitempty"`
+	Images    []string         `json:"images,omitempty"` // base64, without the data URI prefix
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	ToolName  string           `json:"tool_name,omitempty"` // tool result messages only
+}
+
+type ollamaToolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	} `json:"function"`
+}
+
+// ollamaChunk is one NDJSON line of a streamed /api/chat response.
+type ollamaChunk struct {
+	Model           string        `json:"model"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// Complete sends req to /api/chat and returns the streamed response.
+func (c *OllamaClient) Complete(ctx context.Context, req *CompletionRequest) (*Stream, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+
+	body, err := json.Marshal(c.buildRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("llm: marshal request: %w", err)
+	}
+
+	if _, err := c.config.RateLimiter.Wait(ctx, estimateRequestTokens(body, req.MaxTokens)); err != nil {
+		return nil, err
+	}
+
+	url := c.config.BaseURL + "/api/chat"
+	resp, err := doWithRetry(ctx, c.config.Retry, func(ctx context.Context) (*http.Response, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Accept", "application/x-ndjson")
+		if c.config.APIKey != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+		}
+		for k, v := range c.config.Headers {
+			httpReq.Header.Set(k, v)
+		}
+		return c.httpClient.Do(httpReq)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		llmErr := classifyError(resp)
+		resp.Body.Close()
+		return nil, llmErr
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	events := parseOllamaStream(streamCtx, resp.Body)
+	return NewStream(events, resp.Body, cancel), nil
+}
+
+// buildRequest maps an OpenAI-format request onto /api/chat.
+func (c *OllamaClient) buildRequest(req *CompletionRequest) ollamaRequest {
+	model := req.Model
+	if model == "" {
+		model = c.Model()
+	}
+	out := ollamaRequest{
+		Model:    strings.TrimPrefix(model, "ollama/"),
+		Messages: toOllamaMessages(req.Messages),
+		Tools:    req.Tools,
+		Stream:   true,
+		Options:  make(map[string]any),
+	}
+
+	if req.MaxTokens > 0 {
+		out.Options["num_predict"] = req.MaxTokens
+	}
+	if req.Temperature != nil {
+		out.Options["temperature"] = *req.Temperature
+	}
+	if req.TopP != nil {
+		out.Options["top_p"] = *req.TopP
+	}
+	if len(req.Stop) > 0 {
+		out.Options["stop"] = req.Stop
+	}
+	for k, v := range c.config.Extras {
+		if k == "keep_alive" {
+			out.KeepAlive = v
+			continue
+		}
+		out.Options[k] = v
+	}
+	if len(out.Options) == 0 {
+		out.Options = nil
+	}
+	return out
+}
+
+// toOllamaMessages converts OpenAI-format messages. Content parts are
+// flattened to text plus base64 images; tool results are labelled with the
+// name of the tool that was called.
+func toOllamaMessages(msgs []ChatMessage) []ollamaMessage {
+	toolNames := make(map[string]string) // tool call ID -> tool name
+	out := make([]ollamaMessage, 0, len(msgs))
+	for _, m := range msgs {
+		om := ollamaMessage{Role: m.Role}
+		om.Content, om.Images = ollamaContent(m.Content)
+		for _, tc := range m.ToolCalls {
+			toolNames[tc.ID] = tc.Function.Name
+			call := ollamaToolCall{ID: tc.ID}
+			call.Function.Name = tc.Function.Name
+			call.Function.Arguments = map[string]any{}
+			if tc.Function.Arguments != "" {
+				if args, err := RepairToolArguments(tc.Function.Arguments); err == nil {
+					call.Function.Arguments = args
+				}
+			}
+			om.ToolCalls = append(om.ToolCalls, call)
+		}
+		if m.Role == "tool" {
+			om.ToolName = toolNames[m.ToolCallID]
+		}
+		out = append(out, om)
+	}
+	return out
+}
+
+// ollamaContent splits message content into text and base64 images. Only
+// inline (data URI) images can be sent; image URLs are dropped.
+func ollamaContent(content any) (string, []string) {
+	switch c := content.(type) {
+	case string:
+		return c, nil
+	case []ContentPart:
+		var text []string
+		var images []string
+		for _, part := range c {
+			switch part.Type {
+			case "text":
+				text = append(text, part.Text)
+			case "image_url":
+				if part.ImageURL == nil {
+					continue
+				}
+				if _, data, ok := strings.Cut(part.ImageURL.URL, ";base64,"); ok && strings.HasPrefix(part.ImageURL.URL, "data:") {
+					images = append(images, data)
+				}
+			}
+		}
+		return strings.Join(text, "\n"), images
+	}
+	return "", nil
+}
+
+// parseOllamaStream reads NDJSON lines from body and yields StreamEvents in
+// the OpenAI chunk format. Tool calls arrive whole and are given indexes
+// (and IDs, when Ollama doesn't send any) for the accumulator; the final
+// line's counts become the usage.
+func parseOllamaStream(ctx context.Context, body io.ReadCloser) <-chan StreamEvent {
+	ch := make(chan StreamEvent)
+
+	go func() {
+		defer close(ch)
+		defer body.Close()
+
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), ollamaMaxLine)
+		id := fmt.Sprintf("ollama-%d", time.Now().UnixNano())
+		toolCalls := 0
+
+		for scanner.Scan() {
+			select {
+			case <-ctx.Done():
+				ch <- StreamEvent{Err: ctx.Err()}
+				return
+			default:
+			}
+
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var oc ollamaChunk
+			if err := json.Unmarshal(line, &oc); err != nil {
+				continue // malformed line: skip, not fatal
+			}
+			if oc.Error != "" {
+				ch <- StreamEvent{Err: &LLMError{StatusCode: 200, SDKError: "server_error", Message: oc.Error}}
+				return
+			}
+
+			chunk := &StreamChunk{
+				ID:      id,
+				Object:  "chat.completion.chunk",
+				Created: oc.CreatedAt.Unix(),
+				Model:   oc.Model,
+			}
+			var delta Delta
+			if oc.Message.Content != "" {
+				text := oc.Message.Content
+				delta.Content = &text
+			}
+			if oc.Message.Thinking != "" {
+				thinking := oc.Message.Thinking
+				delta.ReasoningContent = &thinking
+			}
+			for _, tc := range oc.Message.ToolCalls {
+				args, _ := json.Marshal(tc.Function.Arguments)
+				callID := tc.ID
+				if callID == "" {
+					callID = fmt.Sprintf("call_%s_%d", id, toolCalls)
+				}
+				delta.ToolCalls = append(delta.ToolCalls, ToolCall{
+					Index:    toolCalls,
+					ID:       callID,
+					Type:     "function",
+					Function: FunctionCall{Name: tc.Function.Name, Arguments: string(args)},
+				})
+				toolCalls++
+			}
+			choice := Choice{Delta: delta}
+			if oc.Done {
+				reason := ollamaFinishReason(oc.DoneReason, toolCalls > 0)
+				choice.FinishReason = &reason
+				chunk.Usage = &Usage{
+					PromptTokens:     oc.PromptEvalCount,
+					CompletionTokens: oc.EvalCount,
+					TotalTokens:      oc.PromptEvalCount + oc.EvalCount,
+				}
+			}
+			chunk.Choices = []Choice{choice}
+			ch <- StreamEvent{Chunk: chunk}
+
+			if oc.Done {
+				ch <- StreamEvent{Done: true}
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			select {
+			case <-ctx.Done():
+				ch <- StreamEvent{Err: ctx.Err()}
+			default:
+				ch <- StreamEvent{Err: err}
+			}
+			return
+		}
+		select {
+		case <-ctx.Done():
+			ch <- StreamEvent{Err: ctx.Err()}
+		default:
+			// EOF before a done line: the stream ended unexpectedly
+		}
+	}()
+
+	return ch
+}
+
+// ollamaFinishReason maps done_reason to an OpenAI finish_reason. Ollama
+// reports "stop" even when the model called tools.
+func ollamaFinishReason(doneReason string, calledTools bool) string {
+	switch {
+	case calledTools:
+		return "tool_calls"
+	case doneReason == "length":
+		return "length"
+	}
+	return "stop"
+}
+
+// Model returns the configured default model string.
+func (c *OllamaClient) Model() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.config.Model
+}
+
+// SetModel changes the default model for subsequent requests.
+func (c *OllamaClient) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Model = model
+}
+
+var _ Client = (*OllamaClient)(nil)
@@ -0,0 +1,193 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func ollamaServer(t *testing.T, body string, check func(req map[string]any)) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("path = %q, want /api/chat", r.URL.Path)
+		}
+		var req map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if check != nil {
+			check(req)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, body)
+	}))
+}
+
+func TestOllamaClient_Text(t *testing.T) {
+	body := `{"model":"llama3.1","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":"Hello"},"done":false}
+{"model":"llama3.1","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":" there"},"done":false}
+{"model":"llama3.1","created_at":"2025-01-01T00:00:00Z","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":42,"eval_count":7}
+`
+	srv := ollamaServer(t, body, func(req map[string]any) {
+		if req["model"] != "llama3.1" {
+			t.Errorf("model = %v, want llama3.1", req["model"])
+		}
+		if req["stream"] != true {
+			t.Errorf("stream = %v, want true", req["stream"])
+		}
+		if req["keep_alive"] != "30m" {
+			t.Errorf("keep_alive = %v, want 30m", req["keep_alive"])
+		}
+		opts, _ := req["options"].(map[string]any)
+		if opts["num_ctx"] != float64(32768) {
+			t.Errorf("options.num_ctx = %v, want 32768", opts["num_ctx"])
+		}
+		if opts["num_predict"] != float64(2048) {
+			t.Errorf("options.num_predict = %v, want the Extras override 2048", opts["num_predict"])
+		}
+		if opts["temperature"] != 0.2 {
+			t.Errorf("options.temperature = %v, want 0.2", opts["temperature"])
+		}
+		if _, ok := opts["keep_alive"]; ok {
+			t.Error("keep_alive sent as an option")
+		}
+	})
+	defer srv.Close()
+
+	client := NewOllamaClient(ClientConfig{
+		BaseURL: srv.URL + "/",
+		Model:   "ollama/llama3.1",
+		Extras:  map[string]any{"num_ctx": 32768, "num_predict": 2048, "keep_alive": "30m"},
+	})
+	temp := 0.2
+	stream, err := client.Complete(context.Background(), &CompletionRequest{
+		Messages:    []ChatMessage{{Role: "user", Content: "Hi"}},
+		MaxTokens:   1024,
+		Temperature: &temp,
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	resp, err := stream.Accumulate()
+	if err != nil {
+		t.Fatalf("Accumulate: %v", err)
+	}
+
+	if len(resp.Content) != 1 || resp.Content[0].Text != "Hello there" {
+		t.Errorf("content = %+v, want \"Hello there\"", resp.Content)
+	}
+	if resp.StopReason != "end_turn" {
+		t.Errorf("StopReason = %q, want end_turn", resp.StopReason)
+	}
+	if resp.Usage.InputTokens != 42 || resp.Usage.OutputTokens != 7 {
+		t.Errorf("usage = %+v, want 42 in / 7 out", resp.Usage)
+	}
+}
+
+func TestOllamaClient_ToolCalls(t *testing.T) {
+	body := `{"model":"llama3.1","message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"Read","arguments":{"file_path":"/tmp/a.go"}}}]},"done":false}
+{"model":"llama3.1","message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":80,"eval_count":12}
+`
+	srv := ollamaServer(t, body, func(req map[string]any) {
+		msgs, _ := req["messages"].([]any)
+		if len(msgs) != 3 {
+			t.Fatalf("messages = %d, want 3", len(msgs))
+		}
+		call := msgs[1].(map[string]any)["tool_calls"].([]any)[0].(map[string]any)["function"].(map[string]any)
+		if args, _ := call["arguments"].(map[string]any); args["pattern"] != "*.go" {
+			t.Errorf("tool call arguments = %v, want an object", call["arguments"])
+		}
+		if name := msgs[2].(map[string]any)["tool_name"]; name != "Glob" {
+			t.Errorf("tool result tool_name = %v, want Glob", name)
+		}
+		if tools, _ := req["tools"].([]any); len(tools) != 1 {
+			t.Errorf("tools = %v, want 1", req["tools"])
+		}
+	})
+	defer srv.Close()
+
+	client := NewOllamaClient(ClientConfig{BaseURL: srv.URL, Model: "llama3.1"})
+	stream, err := client.Complete(context.Background(), &CompletionRequest{
+		Messages: []ChatMessage{
+			{Role: "user", Content: "Read the Go file"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Type: "function",
+				Function: FunctionCall{Name: "Glob", Arguments: `{"pattern":"*.go"}`}}}},
+			{Role: "tool", ToolCallID: "call_1", Content: "/tmp/a.go"},
+		},
+		Tools: []ToolDefinition{{Type: "function", Function: FunctionDef{Name: "Read"}}},
+	})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	resp, err := stream.Accumulate()
+	if err != nil {
+		t.Fatalf("Accumulate: %v", err)
+	}
+
+	if resp.StopReason != "tool_use" {
+		t.Errorf("StopReason = %q, want tool_use", resp.StopReason)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Type != "tool_use" {
+		t.Fatalf("content = %+v, want one tool_use", resp.Content)
+	}
+	block := resp.Content[0]
+	if block.Name != "Read" || block.ID == "" || block.Input["file_path"] != "/tmp/a.go" {
+		t.Errorf("tool_use = %+v", block)
+	}
+}
+
+func TestOllamaClient_Errors(t *testing.T) {
+	t.Run("error line mid-stream", func(t *testing.T) {
+		body := `{"model":"llama3.1","message":{"role":"assistant","content":"Hel"},"done":false}
+{"error":"model runner has unexpectedly stopped"}
+`
+		srv := ollamaServer(t, body, nil)
+		defer srv.Close()
+
+		stream, err := NewOllamaClient(ClientConfig{BaseURL: srv.URL, Model: "llama3.1"}).Complete(context.Background(),
+			&CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "Hi"}}})
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		_, err = stream.Accumulate()
+		var llmErr *LLMError
+		if !errors.As(err, &llmErr) || llmErr.Message != "model runner has unexpectedly stopped" {
+			t.Errorf("err = %v, want the stream's error", err)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(404)
+			fmt.Fprint(w, `{"error":"model \"llama9\" not found, try pulling it first"}`)
+		}))
+		defer srv.Close()
+
+		_, err := NewOllamaClient(ClientConfig{BaseURL: srv.URL, Model: "llama9"}).Complete(context.Background(),
+			&CompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "Hi"}}})
+		var llmErr *LLMError
+		if !errors.As(err, &llmErr) || llmErr.StatusCode != 404 {
+			t.Errorf("err = %v, want a 404 LLMError", err)
+		}
+	})
+}
+
+func TestCalculateCost_OllamaModel(t *testing.T) {
+	var warned []string
+	SetUnknownModelHandler(func(model string) { warned = append(warned, model) })
+	defer SetUnknownModelHandler(nil)
+
+	if cost := CalculateCost("ollama/llama3.1", types.BetaUsage{InputTokens: 1000, OutputTokens: 1000}); cost != 0 {
+		t.Errorf("cost = %v, want 0", cost)
+	}
+	if len(warned) != 0 {
+		t.Errorf("warned = %v, want no unknown-model report", warned)
+	}
+}
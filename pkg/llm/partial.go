@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// PartialAssembler maintains a running assistant message from stream chunks
+// so consumers can render it live without reassembling deltas themselves.
+// Content blocks are indexed in the order they first appear.
+type PartialAssembler struct {
+	id    string
+	model string
+
+	blocks   []*partialBlock
+	text     int         // index of the text block, -1 until seen
+	thinking int         // index of the thinking block, -1 until seen
+	tools    map[int]int // streaming tool call index → block index
+}
+
+type partialBlock struct {
+	typ      string
+	id, name string
+	buf      strings.Builder
+}
+
+// NewPartialAssembler creates an empty assembler.
+func NewPartialAssembler() *PartialAssembler {
+	return &PartialAssembler{text: -1, thinking: -1, tools: make(map[int]int)}
+}
+
+// Add merges a chunk into the running message. It returns the index of the
+// last content block the chunk extended, and false if the chunk carried no
+// content (e.g. a usage-only or finish chunk).
+func (a *PartialAssembler) Add(chunk *StreamChunk) (int, bool) {
+	if a.id == "" {
+		a.id = chunk.ID
+		a.model = chunk.Model
+	}
+
+	index, changed := -1, false
+	for _, choice := range chunk.Choices {
+		delta := choice.Delta
+		if delta.ReasoningContent != nil && *delta.ReasoningContent != "" {
+			a.thinking = a.blockIndex(a.thinking, "thinking")
+			a.blocks[a.thinking].buf.WriteString(*delta.ReasoningContent)
+			index, changed = a.thinking, true
+		}
+		if delta.Content != nil && *delta.Content != "" {
+			a.text = a.blockIndex(a.text, "text")
+			a.blocks[a.text].buf.WriteString(*delta.Content)
+			index, changed = a.text, true
+		}
+		for _, tc := range delta.ToolCalls {
+			i, ok := a.tools[tc.Index]
+			if !ok {
+				i = a.blockIndex(-1, "tool_use")
+				a.tools[tc.Index] = i
+			}
+			b := a.blocks[i]
+			// ID and Name only arrive on the first delta for this index
+			if tc.ID != "" {
+				b.id = tc.ID
+			}
+			if tc.Function.Name != "" {
+				b.name = tc.Function.Name
+			}
+			b.buf.WriteString(tc.Function.Arguments)
+			index, changed = i, true
+		}
+	}
+	return index, changed
+}
+
+// blockIndex returns i, or appends a new block of typ if i is unset.
+func (a *PartialAssembler) blockIndex(i int, typ string) int {
+	if i >= 0 {
+		return i
+	}
+	a.blocks = append(a.blocks, &partialBlock{typ: typ})
+	return len(a.blocks) - 1
+}
+
+// Snapshot returns the message assembled so far, with index as the changed block.
+func (a *PartialAssembler) Snapshot(index int) *types.MessageSnapshotEvent {
+	content := make([]types.PartialContentBlock, len(a.blocks))
+	for i, b := range a.blocks {
+		block := types.PartialContentBlock{Type: b.typ}
+		switch b.typ {
+		case "text":
+			block.Text = b.buf.String()
+		case "thinking":
+			block.Thinking = b.buf.String()
+		case "tool_use":
+			block.ID = b.id
+			block.Name = b.name
+			block.PartialJSON = b.buf.String()
+		}
+		content[i] = block
+	}
+	return &types.MessageSnapshotEvent{
+		Type:    types.StreamEventMessageSnapshot,
+		Index:   index,
+		ID:      a.id,
+		Model:   a.model,
+		Content: content,
+	}
+}
+
+// Partial returns the text assembled so far as a response, for keeping the
+// output of a stream that was cut off. Thinking (unsigned until the stream
+// ends) and tool calls (whose input may be incomplete) are left out. Returns
+// nil if no text was streamed.
+func (a *PartialAssembler) Partial() *CompletionResponse {
+	if a.text < 0 {
+		return nil
+	}
+	return &CompletionResponse{
+		ID:      a.id,
+		Model:   a.model,
+		Content: []types.ContentBlock{{Type: "text", Text: a.blocks[a.text].buf.String()}},
+	}
+}
+
+// EmitPartialSnapshot wraps an assembler snapshot as an SDKPartialAssistantMessage.
+func EmitPartialSnapshot(a *PartialAssembler, index int, parentToolUseID *string, sessionID string) types.PartialAssistantMessage {
+	return types.PartialAssistantMessage{
+		BaseMessage:     types.BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:            types.MessageTypeStreamEvent,
+		Event:           a.Snapshot(index),
+		ParentToolUseID: parentToolUseID,
+	}
+}
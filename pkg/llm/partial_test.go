@@ -0,0 +1,98 @@
+package llm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestPartialAssembler(t *testing.T) {
+	str := func(s string) *string { return &s }
+	stop := "tool_calls"
+	chunks := []*StreamChunk{
+		{ID: "msg-1", Model: "claude", Choices: []Choice{{Delta: Delta{ReasoningContent: str("Let me ")}}}},
+		{Choices: []Choice{{Delta: Delta{ReasoningContent: str("think.")}}}},
+		{Choices: []Choice{{Delta: Delta{Content: str("Reading ")}}}},
+		{Choices: []Choice{{Delta: Delta{ToolCalls: []ToolCall{{Index: 0, ID: "call_1", Type: "function", Function: FunctionCall{Name: "Read", Arguments: `{"file_`}}}}}}},
+		{Choices: []Choice{{Delta: Delta{Content: str("the file.")}}}},
+		{Choices: []Choice{{Delta: Delta{ToolCalls: []ToolCall{{Index: 0, Function: FunctionCall{Arguments: `path":"a.go"}`}}}}}}},
+		{Choices: []Choice{{FinishReason: &stop}}, Usage: &Usage{PromptTokens: 10, CompletionTokens: 5}},
+	}
+	wantIndex := []int{0, 0, 1, 2, 1, 2, -1}
+
+	a := NewPartialAssembler()
+	for i, chunk := range chunks {
+		index, changed := a.Add(chunk)
+		if index != wantIndex[i] || changed != (wantIndex[i] >= 0) {
+			t.Errorf("chunk %d: Add = (%d, %v), want index %d", i, index, changed, wantIndex[i])
+		}
+		if i == 3 {
+			if got := a.Snapshot(index).Content[2].PartialJSON; got != `{"file_` {
+				t.Errorf("in-progress PartialJSON = %q", got)
+			}
+		}
+	}
+
+	snap := a.Snapshot(2)
+	if snap.Type != types.StreamEventMessageSnapshot || snap.ID != "msg-1" || snap.Model != "claude" || snap.Index != 2 {
+		t.Errorf("snapshot header = %+v", snap)
+	}
+	want := []types.PartialContentBlock{
+		{Type: "thinking", Thinking: "Let me think."},
+		{Type: "text", Text: "Reading the file."},
+		{Type: "tool_use", ID: "call_1", Name: "Read", PartialJSON: `{"file_path":"a.go"}`},
+	}
+	if len(snap.Content) != len(want) {
+		t.Fatalf("content = %+v", snap.Content)
+	}
+	for i := range want {
+		if snap.Content[i] != want[i] {
+			t.Errorf("block %d = %+v, want %+v", i, snap.Content[i], want[i])
+		}
+	}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(snap.Content[2].PartialJSON), &input); err != nil {
+		t.Errorf("completed tool input should be valid JSON: %v", err)
+	}
+}
+
+func TestPartialAssembler_MultipleToolCalls(t *testing.T) {
+	a := NewPartialAssembler()
+	a.Add(&StreamChunk{Choices: []Choice{{Delta: Delta{ToolCalls: []ToolCall{
+		{Index: 0, ID: "call_1", Function: FunctionCall{Name: "Read", Arguments: "{}"}},
+		{Index: 1, ID: "call_2", Function: FunctionCall{Name: "Glob", Arguments: "{"}},
+	}}}}})
+	index, _ := a.Add(&StreamChunk{Choices: []Choice{{Delta: Delta{ToolCalls: []ToolCall{
+		{Index: 1, Function: FunctionCall{Arguments: "}"}},
+	}}}}})
+	if index != 1 {
+		t.Errorf("index = %d, want 1", index)
+	}
+	content := a.Snapshot(index).Content
+	if len(content) != 2 || content[0].ID != "call_1" || content[1].PartialJSON != "{}" {
+		t.Errorf("content = %+v", content)
+	}
+}
+
+func TestPartialAssembler_Partial(t *testing.T) {
+	str := func(s string) *string { return &s }
+	a := NewPartialAssembler()
+	if a.Partial() != nil {
+		t.Error("Partial of an empty stream should be nil")
+	}
+	a.Add(&StreamChunk{ID: "msg-1", Model: "claude", Choices: []Choice{{Delta: Delta{ReasoningContent: str("hmm")}}}})
+	if a.Partial() != nil {
+		t.Error("Partial with only thinking should be nil")
+	}
+	a.Add(&StreamChunk{Choices: []Choice{{Delta: Delta{Content: str("Half a sen")}}}})
+	a.Add(&StreamChunk{Choices: []Choice{{Delta: Delta{ToolCalls: []ToolCall{{Index: 0, ID: "call_1", Function: FunctionCall{Name: "Read", Arguments: `{"fi`}}}}}}})
+
+	resp := a.Partial()
+	if resp == nil || resp.ID != "msg-1" || resp.Model != "claude" {
+		t.Fatalf("Partial = %+v", resp)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Type != "text" || resp.Content[0].Text != "Half a sen" {
+		t.Errorf("Partial content = %+v, want only the text", resp.Content)
+	}
+}
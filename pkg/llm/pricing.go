@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 )
@@ -29,6 +30,7 @@ type modelInfoDetail struct {
 	MaxInputTokens              *int     `json:"max_input_tokens"`
 	MaxOutputTokens             *int     `json:"max_output_tokens"`
 	SupportsToolUse             *bool    `json:"supports_function_calling"`
+	SupportsVision              *bool    `json:"supports_vision"`
 }
 
 // FetchPricingResult holds data extracted from the LiteLLM /model/info endpoint.
@@ -89,22 +91,32 @@ func FetchPricing(ctx context.Context, baseURL, apiKey string) (*FetchPricingRes
 		SetPricing(entry.ModelName, p)
 		merged++
 
-		// Extract context limits and capabilities
-		var caps ModelCapabilities
-		if entry.ModelInfo.MaxInputTokens != nil && *entry.ModelInfo.MaxInputTokens > 0 {
-			result.ContextLimits[entry.ModelName] = *entry.ModelInfo.MaxInputTokens
-			caps.MaxInputTokens = *entry.ModelInfo.MaxInputTokens
+		// Extract context limits and capabilities. Fields the proxy omits
+		// keep what is already known about the model.
+		mi := entry.ModelInfo
+		hasInput := mi.MaxInputTokens != nil && *mi.MaxInputTokens > 0
+		hasOutput := mi.MaxOutputTokens != nil && *mi.MaxOutputTokens > 0
+		if hasInput {
+			result.ContextLimits[entry.ModelName] = *mi.MaxInputTokens
 		}
-		if entry.ModelInfo.MaxOutputTokens != nil && *entry.ModelInfo.MaxOutputTokens > 0 {
-			result.MaxOutputTokens[entry.ModelName] = *entry.ModelInfo.MaxOutputTokens
-			caps.MaxOutputTokens = *entry.ModelInfo.MaxOutputTokens
+		if hasOutput {
+			result.MaxOutputTokens[entry.ModelName] = *mi.MaxOutputTokens
 		}
-		if entry.ModelInfo.SupportsToolUse != nil {
-			caps.SupportsToolUse = *entry.ModelInfo.SupportsToolUse
-		}
-		if caps.MaxInputTokens > 0 || caps.MaxOutputTokens > 0 || caps.SupportsToolUse {
-			result.Capabilities[entry.ModelName] = caps
-			SetCapabilities(entry.ModelName, caps)
+		if hasInput || hasOutput || mi.SupportsToolUse != nil || mi.SupportsVision != nil {
+			result.Capabilities[entry.ModelName] = updateCapabilities(entry.ModelName, func(caps *ModelCapabilities) {
+				if hasInput {
+					caps.MaxInputTokens = *mi.MaxInputTokens
+				}
+				if hasOutput {
+					caps.MaxOutputTokens = *mi.MaxOutputTokens
+				}
+				if mi.SupportsToolUse != nil {
+					caps.SupportsToolUse = *mi.SupportsToolUse
+				}
+				if mi.SupportsVision != nil {
+					caps.SupportsVision = *mi.SupportsVision
+				}
+			})
 		}
 	}
 
@@ -138,3 +150,59 @@ func modelInfoTopricing(d modelInfoDetail) ModelPricing {
 	}
 	return p
 }
+
+// pricingFileEntry is one model's prices in a LoadPricing file, in USD per
+// million tokens.
+type pricingFileEntry struct {
+	Input       *float64 `json:"input"`
+	Output      *float64 `json:"output"`
+	CachedInput float64  `json:"cached_input"`
+	CacheWrite  *float64 `json:"cache_write"` // default: input price
+}
+
+// LoadPricing reads model prices from a JSON file and merges them into
+// DefaultPricing, so new models can be priced without recompiling. The file
+// maps model IDs (optionally ending in "*" to match dated variants) to prices
+// in USD per million tokens:
+//
+//	{
+//	  "claude-sonnet-4-5-*": {"input": 3, "output": 15, "cached_input": 0.3, "cache_write": 3.75},
+//	  "gpt-5-mini": {"input": 0.25, "output": 2}
+//	}
+//
+// input and output are required. Nothing is merged if any entry is invalid.
+func LoadPricing(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("load pricing: %w", err)
+	}
+	var entries map[string]pricingFileEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("load pricing %s: %w", path, err)
+	}
+
+	prices := make(map[string]ModelPricing, len(entries))
+	for model, e := range entries {
+		if e.Input == nil || e.Output == nil {
+			return fmt.Errorf("load pricing %s: model %q needs input and output prices", path, model)
+		}
+		p := ModelPricing{
+			InputPerMTok:       *e.Input,
+			OutputPerMTok:      *e.Output,
+			CacheReadPerMTok:   e.CachedInput,
+			CacheCreatePerMTok: *e.Input,
+		}
+		if e.CacheWrite != nil {
+			p.CacheCreatePerMTok = *e.CacheWrite
+		}
+		if p.InputPerMTok < 0 || p.OutputPerMTok < 0 || p.CacheReadPerMTok < 0 || p.CacheCreatePerMTok < 0 {
+			return fmt.Errorf("load pricing %s: model %q has a negative price", path, model)
+		}
+		prices[model] = p
+	}
+
+	for model, p := range prices {
+		SetPricing(model, p)
+	}
+	return nil
+}
@@ -5,6 +5,9 @@ import (
 	"math"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jg-phare/goat/pkg/types"
@@ -167,6 +170,32 @@ func TestFetchPricing(t *testing.T) {
 	}
 }
 
+func TestFetchPricingKeepsKnownCapabilities(t *testing.T) {
+	const model = "claude-sonnet-4-5-20250929"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": [{"model_name": "` + model + `", "model_info": {
+			"input_cost_per_token": 3e-06, "output_cost_per_token": 1.5e-05, "max_output_tokens": 64000}}]}`))
+	}))
+	defer srv.Close()
+
+	origPricing := snapshotPricing()
+	defer restorePricing(origPricing)
+	origCaps, _ := GetCapabilities(model)
+	defer SetCapabilities(model, origCaps)
+
+	if _, err := FetchPricing(context.Background(), srv.URL, ""); err != nil {
+		t.Fatalf("FetchPricing returned error: %v", err)
+	}
+	caps, _ := GetCapabilities(model)
+	if caps.MaxOutputTokens != 64000 {
+		t.Errorf("MaxOutputTokens = %d, want the fetched 64000", caps.MaxOutputTokens)
+	}
+	if !caps.SupportsVision || !caps.SupportsToolUse || !caps.SupportsThinking || caps.MaxInputTokens != origCaps.MaxInputTokens {
+		t.Errorf("caps = %+v, want fields the proxy omitted kept from %+v", caps, origCaps)
+	}
+}
+
 func TestFetchPricingCalculateCost(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -267,3 +296,58 @@ func restorePricing(snap map[string]ModelPricing) {
 	defer pricingMu.Unlock()
 	DefaultPricing = snap
 }
+
+func TestLoadPricing(t *testing.T) {
+	origPricing := snapshotPricing()
+	defer restorePricing(origPricing)
+
+	path := filepath.Join(t.TempDir(), "pricing.json")
+	os.WriteFile(path, []byte(`{
+		"claude-sonnet-5-*": {"input": 4, "output": 20, "cached_input": 0.4, "cache_write": 5},
+		"new-model": {"input": 1, "output": 2}
+	}`), 0o644)
+
+	if err := LoadPricing(path); err != nil {
+		t.Fatalf("LoadPricing: %v", err)
+	}
+
+	// Dated variant resolves through the wildcard key.
+	cost := CalculateCost("anthropic/claude-sonnet-5-20261001", types.BetaUsage{
+		InputTokens: 1000, OutputTokens: 1000, CacheReadInputTokens: 1000, CacheCreationInputTokens: 1000,
+	})
+	assertClose(t, "sonnet-5 cost", cost, 0.004+0.020+0.0004+0.005)
+
+	// cache_write defaults to the input price.
+	p, ok := GetPricing("new-model")
+	if !ok || p.CacheCreatePerMTok != 1 || p.CacheReadPerMTok != 0 {
+		t.Errorf("new-model pricing = %+v, %v", p, ok)
+	}
+}
+
+func TestLoadPricingErrors(t *testing.T) {
+	origPricing := snapshotPricing()
+	defer restorePricing(origPricing)
+
+	dir := t.TempDir()
+	tests := map[string]string{
+		"invalid JSON":   `{"m": `,
+		"missing output": `{"ok-model": {"input": 1, "output": 1}, "m": {"input": 1}}`,
+		"negative price": `{"m": {"input": -1, "output": 1}}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(name, " ", "-")+".json")
+			os.WriteFile(path, []byte(content), 0o644)
+			if err := LoadPricing(path); err == nil {
+				t.Fatal("expected error")
+			}
+			if IsKnownModel("ok-model") {
+				t.Error("valid entries should not be merged when the file has errors")
+			}
+		})
+	}
+
+	if err := LoadPricing(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter gates completion requests by requests-per-minute and
+// tokens-per-minute using token buckets. Calls block until capacity is
+// available rather than failing; share one limiter (or one Client) across the
+// main loop and its subagents to keep their combined traffic under the
+// account limits. A nil *RateLimiter never blocks.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests bucket
+	tokens   bucket
+	now      func() time.Time
+}
+
+// bucket is a token bucket that may go negative: a reservation that exceeds
+// the current level is granted immediately and the caller waits out the debt,
+// so later callers queue behind it.
+type bucket struct {
+	capacity float64 // also the per-minute refill amount; 0 = unlimited
+	level    float64
+	last     time.Time
+}
+
+// NewRateLimiter creates a limiter allowing rpm requests and tpm tokens per
+// minute. Zero disables the corresponding limit. Both buckets start full, so
+// a burst up to the per-minute allowance is not delayed.
+func NewRateLimiter(rpm, tpm int) *RateLimiter {
+	now := time.Now()
+	return &RateLimiter{
+		requests: bucket{capacity: float64(rpm), level: float64(rpm), last: now},
+		tokens:   bucket{capacity: float64(tpm), level: float64(tpm), last: now},
+		now:      time.Now,
+	}
+}
+
+// Reserve claims one request and tokens from the buckets and returns how long
+// the caller must wait before sending. The reservation is kept even if the
+// caller gives up, which errs on the side of staying under the limit.
+func (l *RateLimiter) Reserve(tokens int) time.Duration {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	return max(l.requests.reserve(1, now), l.tokens.reserve(float64(tokens), now))
+}
+
+// Wait blocks until a request estimated at tokens may be sent, or ctx is done.
+// Before blocking it reports the delay to any notifier attached to ctx with
+// WithRateLimitNotify. Returns the time waited.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) (time.Duration, error) {
+	delay := l.Reserve(tokens)
+	if delay <= 0 {
+		return 0, nil
+	}
+	if notify, ok := ctx.Value(rateLimitNotifyKey{}).(func(time.Duration)); ok {
+		notify(delay)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	case <-timer.C:
+		return delay, nil
+	}
+}
+
+func (b *bucket) reserve(n float64, now time.Time) time.Duration {
+	if b.capacity <= 0 {
+		return 0
+	}
+	perSecond := b.capacity / 60
+	b.level = min(b.capacity, b.level+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	// A single request larger than the whole allowance waits for a full bucket
+	// instead of forever.
+	b.level -= min(n, b.capacity)
+	if b.level >= 0 {
+		return 0
+	}
+	return time.Duration(-b.level / perSecond * float64(time.Second))
+}
+
+type rateLimitNotifyKey struct{}
+
+// WithRateLimitNotify returns a context whose RateLimiter.Wait calls report
+// their delay to notify before blocking.
+func WithRateLimitNotify(ctx context.Context, notify func(time.Duration)) context.Context {
+	return context.WithValue(ctx, rateLimitNotifyKey{}, notify)
+}
+
+// estimateRequestTokens approximates the tokens-per-minute cost of a request
+// from its encoded size (about four bytes per token) plus its max_tokens.
+func estimateRequestTokens(body []byte, maxTokens int) int {
+	return len(body)/4 + maxTokens
+}
@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestRateLimiter returns a limiter whose clock is advanced by hand.
+func newTestRateLimiter(rpm, tpm int) (*RateLimiter, *time.Time) {
+	l := NewRateLimiter(rpm, tpm)
+	clock := l.requests.last
+	l.now = func() time.Time { return clock }
+	return l, &clock
+}
+
+func TestRateLimiter_RequestsPerMinute(t *testing.T) {
+	l, clock := newTestRateLimiter(60, 0)
+	for i := range 60 {
+		if d := l.Reserve(100); d != 0 {
+			t.Fatalf("request %d within the allowance waited %v", i, d)
+		}
+	}
+	if d := l.Reserve(100); d != time.Second {
+		t.Errorf("61st request waited %v, want 1s", d)
+	}
+	if d := l.Reserve(100); d != 2*time.Second {
+		t.Errorf("62nd request waited %v, want 2s (queued behind the 61st)", d)
+	}
+	*clock = clock.Add(3 * time.Second)
+	if d := l.Reserve(100); d != 0 {
+		t.Errorf("after refill waited %v, want 0", d)
+	}
+}
+
+func TestRateLimiter_TokensPerMinute(t *testing.T) {
+	l, clock := newTestRateLimiter(0, 6000)
+	if d := l.Reserve(6000); d != 0 {
+		t.Fatalf("first request waited %v", d)
+	}
+	if d := l.Reserve(1000); d != 10*time.Second {
+		t.Errorf("waited %v, want 10s for 1000 tokens at 6000 TPM", d)
+	}
+	*clock = clock.Add(10 * time.Second)
+	// A request larger than the whole allowance is capped at a full bucket.
+	if d := l.Reserve(1_000_000); d != time.Minute {
+		t.Errorf("oversized request waited %v, want 1m", d)
+	}
+}
+
+func TestRateLimiter_Nil(t *testing.T) {
+	var l *RateLimiter
+	if d, err := l.Wait(context.Background(), 1_000_000); d != 0 || err != nil {
+		t.Errorf("nil limiter Wait = %v, %v", d, err)
+	}
+}
+
+func TestRateLimiter_WaitNotifiesAndHonorsCancel(t *testing.T) {
+	l := NewRateLimiter(1, 0)
+	l.Reserve(0)
+
+	var notified time.Duration
+	ctx, cancel := context.WithCancel(WithRateLimitNotify(context.Background(), func(d time.Duration) {
+		notified = d
+	}))
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := l.Wait(ctx, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wait blocked %v after cancel", elapsed)
+	}
+	if notified < 59*time.Second {
+		t.Errorf("notified delay = %v, want about 1m", notified)
+	}
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Reasoning effort levels for ClientConfig.ReasoningEffort.
+const (
+	ReasoningEffortLow    = "low"
+	ReasoningEffortMedium = "medium"
+	ReasoningEffortHigh   = "high"
+)
+
+// minThinkingBudget is the smallest budget_tokens Anthropic accepts.
+const minThinkingBudget = 1024
+
+// effortThinkingBudgets are the Anthropic thinking budgets used when only a
+// reasoning effort is configured. All fit under the loop's default 16384
+// max_tokens, which budget_tokens must stay below.
+var effortThinkingBudgets = map[string]int{
+	ReasoningEffortLow:    2048,
+	ReasoningEffortMedium: 8192,
+	ReasoningEffortHigh:   12288,
+}
+
+// openAIReasoningModel matches o-series and gpt-5 model names, optionally
+// "openai/"-prefixed: o1, o3-mini, o4-mini-2025-04-16, gpt-5-nano, ...
+var openAIReasoningModel = regexp.MustCompile(`^(openai/)?(o\d+|gpt-5)([-.]|$)`)
+
+// IsOpenAIReasoningModel reports whether model is an OpenAI reasoning model,
+// which takes reasoning_effort instead of a thinking budget.
+func IsOpenAIReasoningModel(model string) bool {
+	return openAIReasoningModel.MatchString(strings.ToLower(model))
+}
+
+// reasoningEffort returns the effort to request from an OpenAI reasoning
+// model: the configured effort, else one matching MaxThinkingTokens, else ""
+// (the provider default).
+func reasoningEffort(config ClientConfig) string {
+	switch {
+	case config.ReasoningEffort != "":
+		return config.ReasoningEffort
+	case config.MaxThinkingTokens <= 0:
+		return ""
+	case config.MaxThinkingTokens <= effortThinkingBudgets[ReasoningEffortLow]:
+		return ReasoningEffortLow
+	case config.MaxThinkingTokens <= effortThinkingBudgets[ReasoningEffortMedium]:
+		return ReasoningEffortMedium
+	default:
+		return ReasoningEffortHigh
+	}
+}
+
+// thinkingBudget returns the Anthropic budget_tokens for a request:
+// MaxThinkingTokens when set, else the budget for ReasoningEffort (0 when
+// neither is set or the effort is unknown). An effort-derived budget is kept
+// below MaxTokens, and dropped if that leaves too little room to think.
+func thinkingBudget(config ClientConfig) int {
+	if config.MaxThinkingTokens > 0 {
+		return config.MaxThinkingTokens
+	}
+	budget := effortThinkingBudgets[strings.ToLower(config.ReasoningEffort)]
+	if budget > 0 && config.MaxTokens > 0 && budget >= config.MaxTokens {
+		budget = config.MaxTokens - 1
+	}
+	if budget < minThinkingBudget {
+		return 0
+	}
+	return budget
+}
@@ -0,0 +1,60 @@
+package llm
+
+import "testing"
+
+func TestIsOpenAIReasoningModel(t *testing.T) {
+	for model, want := range map[string]bool{
+		"o1":                         true,
+		"o3":                         true,
+		"o3-mini":                    true,
+		"o4-mini-2025-04-16":         true,
+		"openai/o3":                  true,
+		"gpt-5-nano":                 true,
+		"gpt-5":                      true,
+		"gpt-4o":                     false,
+		"claude-opus-4-5-20250514":   false,
+		"llama-3.3-70b-versatile":    false,
+		"omni-local":                 false,
+		"anthropic/claude-haiku-4-5": false,
+	} {
+		if got := IsOpenAIReasoningModel(model); got != want {
+			t.Errorf("IsOpenAIReasoningModel(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestBuildCompletionRequest_Reasoning(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     ClientConfig
+		wantEffort string
+		wantBudget int // 0 = no thinking block
+	}{
+		{"o-series effort", ClientConfig{Model: "o3", ReasoningEffort: "high"}, "high", 0},
+		{"o-series effort from budget", ClientConfig{Model: "o4-mini", MaxThinkingTokens: 4000}, "medium", 0},
+		{"o-series default", ClientConfig{Model: "o3"}, "", 0},
+		{"Claude budget", ClientConfig{Model: "claude-sonnet-4-5-20250929", MaxTokens: 16384, MaxThinkingTokens: 3000}, "", 3000},
+		{"Claude budget from effort", ClientConfig{Model: "claude-sonnet-4-5-20250929", MaxTokens: 16384, ReasoningEffort: "medium"}, "", 8192},
+		{"Claude effort clamped below max_tokens", ClientConfig{Model: "claude-sonnet-4-5-20250929", MaxTokens: 4096, ReasoningEffort: "high"}, "", 4095},
+		{"Claude unknown effort", ClientConfig{Model: "claude-sonnet-4-5-20250929", ReasoningEffort: "extreme"}, "", 0},
+		{"non-reasoning model", ClientConfig{Model: "gpt-4o"}, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := BuildCompletionRequest(tt.config, "sys", nil, nil, LoopState{})
+			if req.ReasoningEffort != tt.wantEffort {
+				t.Errorf("ReasoningEffort = %q, want %q", req.ReasoningEffort, tt.wantEffort)
+			}
+			thinking, _ := req.ExtraBody["thinking"].(map[string]any)
+			if tt.wantBudget == 0 {
+				if thinking != nil {
+					t.Errorf("unexpected thinking block %v", thinking)
+				}
+				return
+			}
+			if thinking == nil || thinking["type"] != "enabled" || thinking["budget_tokens"] != tt.wantBudget {
+				t.Errorf("thinking = %v, want budget_tokens %d", thinking, tt.wantBudget)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// RepairToolArguments parses a tool call's arguments, fixing the mistakes
+// small models commonly make: code fences or prose around the object,
+// single-quoted strings, unquoted keys, Python literals, missing or trailing
+// commas, raw newlines in strings, double encoding, and output cut off
+// mid-object. It fails if the result is still not a JSON object.
+func RepairToolArguments(raw string) (map[string]any, error) {
+	var input map[string]any
+	if json.Unmarshal([]byte(raw), &input) == nil && input != nil {
+		return input, nil
+	}
+	var inner string
+	if json.Unmarshal([]byte(raw), &inner) == nil {
+		raw = inner // arguments sent as a JSON string holding the object
+	}
+
+	if err := json.Unmarshal([]byte(repairJSON(trimToObject(raw))), &input); err != nil {
+		return nil, err
+	}
+	if input == nil {
+		return nil, errors.New("arguments are not a JSON object")
+	}
+	return input, nil
+}
+
+// trimToObject drops code fences and any text before the first '{'.
+func trimToObject(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		if nl := strings.IndexByte(s, '\n'); nl >= 0 {
+			s = s[nl+1:]
+		}
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	}
+	if i := strings.IndexByte(s, '{'); i > 0 {
+		s = s[i:]
+	}
+	return s
+}
+
+// What the enclosing container expects next.
+const (
+	wantKey   = iota // object: after '{' or ','
+	wantColon        // object: after a key
+	wantValue        // object after ':', or array after '[' or ','
+	wantComma        // after a complete value
+)
+
+type jsonFrame struct {
+	object bool
+	want   int
+}
+
+// jsonRepairer rewrites lenient JSON into strict JSON in one pass, tracking
+// open containers so truncated input can be closed off.
+type jsonRepairer struct {
+	b     strings.Builder
+	stack []jsonFrame
+}
+
+func repairJSON(s string) string {
+	var r jsonRepairer
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			r.beginValue()
+			str, n := quoteLenientString(s[i:])
+			r.b.WriteString(str)
+			i += n
+			r.endValue()
+		case c == '{' || c == '[':
+			r.beginValue()
+			r.b.WriteByte(c)
+			if c == '{' {
+				r.stack = append(r.stack, jsonFrame{object: true, want: wantKey})
+			} else {
+				r.stack = append(r.stack, jsonFrame{want: wantValue})
+			}
+			i++
+		case c == '}' || c == ']':
+			// Close inner containers the model forgot, then this one.
+			for len(r.stack) > 0 && r.top().object != (c == '}') {
+				r.closeFrame()
+			}
+			if len(r.stack) > 0 {
+				r.closeFrame()
+			}
+			i++
+		case c == ',':
+			i++
+			if j := skipSpace(s, i); j == len(s) || s[j] == '}' || s[j] == ']' {
+				continue // trailing comma
+			}
+			if f := r.top(); f != nil && f.want == wantComma {
+				r.b.WriteByte(',')
+				f.want = wantValue
+				if f.object {
+					f.want = wantKey
+				}
+			}
+		case c == ':':
+			r.b.WriteByte(':')
+			if f := r.top(); f != nil && f.object {
+				f.want = wantValue
+			}
+			i++
+		case c == '-' || (c >= '0' && c <= '9'):
+			j := i + 1
+			for j < len(s) && strings.IndexByte("0123456789.eE+-", s[j]) >= 0 {
+				j++
+			}
+			r.beginValue()
+			r.b.WriteString(s[i:j])
+			r.endValue()
+			i = j
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(s) && (isIdentByte(s[j]) || (s[j] >= '0' && s[j] <= '9')) {
+				j++
+			}
+			word := s[i:j]
+			isKey := r.top() != nil && r.top().object && r.top().want != wantValue
+			r.beginValue()
+			switch {
+			case isKey:
+				r.b.WriteString(quoteJSON(word))
+			case word == "true" || word == "True":
+				r.b.WriteString("true")
+			case word == "false" || word == "False":
+				r.b.WriteString("false")
+			case word == "null" || word == "None" || word == "undefined":
+				r.b.WriteString("null")
+			default:
+				r.b.WriteString(quoteJSON(word))
+			}
+			r.endValue()
+			i = j
+		default:
+			r.b.WriteByte(c)
+			i++
+		}
+	}
+	for len(r.stack) > 0 {
+		r.closeFrame()
+	}
+	return r.b.String()
+}
+
+func (r *jsonRepairer) top() *jsonFrame {
+	if len(r.stack) == 0 {
+		return nil
+	}
+	return &r.stack[len(r.stack)-1]
+}
+
+// beginValue inserts a comma the model left out between two members.
+func (r *jsonRepairer) beginValue() {
+	if f := r.top(); f != nil && f.want == wantComma {
+		r.b.WriteByte(',')
+		f.want = wantValue
+		if f.object {
+			f.want = wantKey
+		}
+	}
+}
+
+// endValue advances the enclosing container past a key or value.
+func (r *jsonRepairer) endValue() {
+	if f := r.top(); f != nil {
+		if f.object && f.want == wantKey {
+			f.want = wantColon
+		} else {
+			f.want = wantComma
+		}
+	}
+}
+
+// closeFrame closes the innermost container, giving a dangling key a null value.
+func (r *jsonRepairer) closeFrame() {
+	f := r.top()
+	if f.object {
+		switch f.want {
+		case wantColon:
+			r.b.WriteString(":null")
+		case wantValue:
+			r.b.WriteString("null")
+		}
+		r.b.WriteByte('}')
+	} else {
+		r.b.WriteByte(']')
+	}
+	r.stack = r.stack[:len(r.stack)-1]
+	r.endValue()
+}
+
+// quoteLenientString converts the single- or double-quoted string at the
+// start of s to a JSON string, closing it if s ends first. It returns the
+// JSON string and the number of bytes of s consumed.
+func quoteLenientString(s string) (string, int) {
+	quote := s[0]
+	var b strings.Builder
+	b.WriteByte('"')
+	i := 1
+	for ; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			if i+1 == len(s) {
+				continue // cut off mid-escape
+			}
+			i++
+			if s[i] == '\'' {
+				b.WriteByte('\'')
+			} else {
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		case c == quote:
+			b.WriteByte('"')
+			return b.String(), i + 1
+		case c == '"':
+			b.WriteString(`\"`)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String(), i
+}
+
+func quoteJSON(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+		i++
+	}
+	return i
+}
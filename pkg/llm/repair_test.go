@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRepairToolArguments(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want map[string]any
+	}{
+		{"valid", `{"command": "ls"}`, map[string]any{"command": "ls"}},
+		{"trailing comma", `{"command": "ls", "timeout": 5,}`, map[string]any{"command": "ls", "timeout": float64(5)}},
+		{"trailing comma in array", `{"paths": ["a", "b",]}`, map[string]any{"paths": []any{"a", "b"}}},
+		{"unquoted keys", `{command: "ls", run_in_background: true}`, map[string]any{"command": "ls", "run_in_background": true}},
+		{"single quotes", `{'pattern': 'it\'s "here"'}`, map[string]any{"pattern": `it's "here"`}},
+		{"python literals", `{"a": True, "b": False, "c": None}`, map[string]any{"a": true, "b": false, "c": nil}},
+		{"missing comma", `{"a": 1 "b": 2}`, map[string]any{"a": float64(1), "b": float64(2)}},
+		{"raw newline in string", "{\"content\": \"line 1\nline 2\"}", map[string]any{"content": "line 1\nline 2"}},
+		{"truncated string", `{"file_path": "/tmp/a.go", "content": "package ma`, map[string]any{"file_path": "/tmp/a.go", "content": "package ma"}},
+		{"truncated after key", `{"a": 1, "b"`, map[string]any{"a": float64(1), "b": nil}},
+		{"truncated nested", `{"edits": [{"old": "x", "new": "y"`, map[string]any{"edits": []any{map[string]any{"old": "x", "new": "y"}}}},
+		{"mismatched closer", `{"a": [1, 2}`, map[string]any{"a": []any{float64(1), float64(2)}}},
+		{"code fence", "```json\n{\"command\": \"ls\"}\n```", map[string]any{"command": "ls"}},
+		{"leading prose", `Here are the arguments: {"command": "ls"}`, map[string]any{"command": "ls"}},
+		{"double encoded", `"{\"command\": \"ls\"}"`, map[string]any{"command": "ls"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RepairToolArguments(tt.raw)
+			if err != nil {
+				t.Fatalf("RepairToolArguments(%q): %v", tt.raw, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RepairToolArguments(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepairToolArguments_Unrepairable(t *testing.T) {
+	for _, raw := range []string{`ls -la`, `["a", "b"]`, `{"a": 1}; rm -rf`} {
+		if got, err := RepairToolArguments(raw); err == nil {
+			t.Errorf("RepairToolArguments(%q) = %v, want an error", raw, got)
+		}
+	}
+}
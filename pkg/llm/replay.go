@@ -0,0 +1,305 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sync"
+)
+
+// ErrReplayExhausted is returned by ReplayClient.Complete when no recorded
+// interaction is left for the request.
+var ErrReplayExhausted = errors.New("llm: no recorded response left to replay")
+
+// Recording is one recorded Complete call, stored as a line of JSON.
+type Recording struct {
+	RequestHash string          `json:"request_hash"`
+	Request     json.RawMessage `json:"request"`
+	Chunks      []StreamChunk   `json:"chunks,omitempty"`
+
+	// Error is set when Complete itself failed; StreamError when the stream
+	// failed after delivering Chunks. LLMError preserves HTTP error details
+	// so retry and fallback decisions replay the same way.
+	Error       string    `json:"error,omitempty"`
+	StreamError string    `json:"stream_error,omitempty"`
+	LLMError    *LLMError `json:"llm_error,omitempty"`
+}
+
+// RequestHash identifies a request for ReplayByHash. The per-session
+// metadata.user_id is ignored so recordings match across sessions; anything
+// else that varies between runs (dates, paths in the system prompt) must be
+// made deterministic for hashes to match.
+func RequestHash(req *CompletionRequest) string {
+	r := *req
+	if _, ok := r.ExtraBody["metadata"]; ok {
+		r.ExtraBody = maps.Clone(r.ExtraBody)
+		delete(r.ExtraBody, "metadata")
+	}
+	r.Stream = false
+	r.StreamOptions = nil
+	data, _ := json.Marshal(&r)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// RecordingClient wraps a Client and appends every interaction to a JSON
+// Lines file for later replay with ReplayClient. Streams pass through
+// unchanged; a call is written once its stream ends or is closed. Calls
+// abandoned because the caller's context was cancelled are not recorded.
+type RecordingClient struct {
+	inner Client
+
+	mu   sync.Mutex // serializes writes
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecordingClient records inner's interactions to path, truncating it.
+func NewRecordingClient(inner Client, path string) (*RecordingClient, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("llm: create recording: %w", err)
+	}
+	return &RecordingClient{inner: inner, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// Complete forwards req to the wrapped client and records the result.
+func (c *RecordingClient) Complete(ctx context.Context, req *CompletionRequest) (*Stream, error) {
+	rec := Recording{RequestHash: RequestHash(req)}
+	rec.Request, _ = json.Marshal(req) // snapshot before the inner client mutates req
+
+	stream, err := c.inner.Complete(ctx, req)
+	if err != nil {
+		if ctx.Err() == nil {
+			rec.setError(err, &rec.Error)
+			c.write(rec)
+		}
+		return nil, err
+	}
+
+	events := make(chan StreamEvent)
+	fwdCtx, cancel := context.WithCancel(ctx)
+	forward := func(event StreamEvent) bool {
+		select {
+		case events <- event:
+			return true
+		case <-fwdCtx.Done():
+			return false
+		}
+	}
+	go func() {
+		defer close(events)
+		for event := range stream.events {
+			if event.Chunk != nil {
+				rec.Chunks = append(rec.Chunks, *event.Chunk)
+			}
+			// The stream ends at [DONE] or its first error; anything after
+			// is teardown noise from the consumer closing it.
+			if event.Done || event.Err != nil {
+				if event.Err != nil {
+					rec.setError(event.Err, &rec.StreamError)
+				}
+				c.write(rec)
+				forward(event)
+				return
+			}
+			if !forward(event) {
+				stream.Close()
+				if ctx.Err() == nil {
+					c.write(rec) // closed early by the consumer
+				}
+				return
+			}
+		}
+		c.write(rec)
+	}()
+	return NewStream(events, nil, func() {
+		cancel()
+		stream.Close()
+	}), nil
+}
+
+// Model returns the wrapped client's model.
+func (c *RecordingClient) Model() string { return c.inner.Model() }
+
+// SetModel sets the wrapped client's model.
+func (c *RecordingClient) SetModel(model string) { c.inner.SetModel(model) }
+
+// Close closes the recording file.
+func (c *RecordingClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.file.Close()
+}
+
+func (c *RecordingClient) write(rec Recording) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enc.Encode(rec) // best effort: recording must not fail the session
+}
+
+func (r *Recording) setError(err error, field *string) {
+	*field = err.Error()
+	var llmErr *LLMError
+	if errors.As(err, &llmErr) {
+		r.LLMError = llmErr
+	}
+}
+
+// ReplayMode selects how ReplayClient matches requests to recordings.
+type ReplayMode int
+
+const (
+	// ReplayBySequence serves recordings in file order, ignoring the request.
+	ReplayBySequence ReplayMode = iota
+	// ReplayByHash serves the next unused recording whose RequestHash
+	// matches, so concurrent callers (e.g. subagents) get their own responses.
+	ReplayByHash
+)
+
+// ReplayClient serves recorded interactions back as Streams that yield the
+// recorded StreamChunk sequence, including usage and finish reasons, so a
+// loop run against it behaves as it did when recorded.
+type ReplayClient struct {
+	mode ReplayMode
+
+	mu     sync.Mutex
+	queue  []Recording            // ReplayBySequence
+	byHash map[string][]Recording // ReplayByHash
+	model  string
+}
+
+// LoadRecordings reads a file written by RecordingClient.
+func LoadRecordings(path string) ([]Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("llm: open recording: %w", err)
+	}
+	defer f.Close()
+
+	var recs []Recording
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var rec Recording
+		if err := dec.Decode(&rec); err == io.EOF {
+			return recs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("llm: read recording %s: entry %d: %w", path, len(recs)+1, err)
+		}
+		recs = append(recs, rec)
+	}
+}
+
+// NewReplayClient replays recs. Model starts as the first recorded request's model.
+func NewReplayClient(recs []Recording, mode ReplayMode) *ReplayClient {
+	c := &ReplayClient{mode: mode, queue: recs, byHash: make(map[string][]Recording)}
+	if mode == ReplayByHash {
+		for _, rec := range recs {
+			c.byHash[rec.RequestHash] = append(c.byHash[rec.RequestHash], rec)
+		}
+		c.queue = nil
+	}
+	if len(recs) > 0 {
+		var req CompletionRequest
+		if json.Unmarshal(recs[0].Request, &req) == nil {
+			c.model = req.Model
+		}
+	}
+	return c
+}
+
+// Complete returns the next matching recording as a Stream, or its recorded error.
+func (c *ReplayClient) Complete(ctx context.Context, req *CompletionRequest) (*Stream, error) {
+	rec, err := c.next(req)
+	if err != nil {
+		return nil, err
+	}
+	if rec.Error != "" {
+		return nil, rec.err(rec.Error)
+	}
+
+	events := make(chan StreamEvent, len(rec.Chunks)+1)
+	streamCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer close(events)
+		for i := range rec.Chunks {
+			select {
+			case events <- StreamEvent{Chunk: &rec.Chunks[i]}:
+			case <-streamCtx.Done():
+				events <- StreamEvent{Err: streamCtx.Err()} // like the SSE parser
+				return
+			}
+		}
+		if rec.StreamError != "" {
+			events <- StreamEvent{Err: rec.err(rec.StreamError)}
+		}
+	}()
+	return NewStream(events, nil, cancel), nil
+}
+
+func (c *ReplayClient) next(req *CompletionRequest) (Recording, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mode == ReplayByHash {
+		hash := RequestHash(req)
+		recs := c.byHash[hash]
+		if len(recs) == 0 {
+			return Recording{}, fmt.Errorf("%w (request hash %s)", ErrReplayExhausted, hash)
+		}
+		c.byHash[hash] = recs[1:]
+		return recs[0], nil
+	}
+	if len(c.queue) == 0 {
+		return Recording{}, ErrReplayExhausted
+	}
+	rec := c.queue[0]
+	c.queue = c.queue[1:]
+	return rec, nil
+}
+
+// Remaining reports how many recordings have not been served.
+func (c *ReplayClient) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.queue)
+	for _, recs := range c.byHash {
+		n += len(recs)
+	}
+	return n
+}
+
+// Model returns the current model.
+func (c *ReplayClient) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
+
+// SetModel changes the model reported by Model; it does not affect matching.
+func (c *ReplayClient) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.model = model
+}
+
+// err rebuilds a recorded error, keeping *LLMError and context errors typed.
+func (r *Recording) err(msg string) error {
+	if r.LLMError != nil {
+		e := *r.LLMError
+		return &e
+	}
+	switch msg {
+	case context.Canceled.Error():
+		return context.Canceled
+	case context.DeadlineExceeded.Error():
+		return context.DeadlineExceeded
+	}
+	return errors.New(msg)
+}
@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sseClient serves one SSE fixture (or error) per call.
+type sseClient struct {
+	fixtures []string
+	errs     map[int]error
+	calls    int
+}
+
+func (c *sseClient) Complete(_ context.Context, req *CompletionRequest) (*Stream, error) {
+	i := c.calls
+	c.calls++
+	req.Stream = true // like the HTTP client, mutate the request
+	if err := c.errs[i]; err != nil {
+		return nil, err
+	}
+	return makeTestStream(c.fixtures[i]), nil
+}
+
+func (c *sseClient) Model() string   { return "test-model" }
+func (c *sseClient) SetModel(string) {}
+
+func replayRequest(prompt string) *CompletionRequest {
+	return BuildCompletionRequest(ClientConfig{Model: "test-model", MaxTokens: 100}, "system", []ChatMessage{{Role: "user", Content: prompt}}, nil, LoopState{SessionID: "session-1"})
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	fixtures := []string{loadSSETestData(t, "tool_calls"), loadSSETestData(t, "text_only")}
+	overloaded := &LLMError{StatusCode: 529, SDKError: "rate_limit", Message: "overloaded", Retryable: true}
+	inner := &sseClient{fixtures: []string{fixtures[0], "", fixtures[1]}, errs: map[int]error{1: overloaded}}
+
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	rec, err := NewRecordingClient(inner, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prompts := []string{"list files", "retry me", "say hi"}
+	var want []*CompletionResponse
+	for _, p := range prompts {
+		stream, err := rec.Complete(context.Background(), replayRequest(p))
+		if err != nil {
+			want = append(want, nil)
+			continue
+		}
+		resp, err := stream.Accumulate()
+		if err != nil {
+			t.Fatalf("Accumulate %q: %v", p, err)
+		}
+		want = append(want, resp)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recs, err := LoadRecordings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("recorded %d calls, want 3", len(recs))
+	}
+
+	check := func(t *testing.T, client *ReplayClient, order []int) {
+		for _, i := range order {
+			stream, err := client.Complete(context.Background(), replayRequest(prompts[i]))
+			if want[i] == nil {
+				var llmErr *LLMError
+				if !errors.As(err, &llmErr) || *llmErr != *overloaded {
+					t.Errorf("call %d error = %v, want the recorded LLMError", i, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("call %d: %v", i, err)
+			}
+			got, err := stream.Accumulate()
+			if err != nil {
+				t.Fatalf("call %d Accumulate: %v", i, err)
+			}
+			if !reflect.DeepEqual(got, want[i]) {
+				t.Errorf("call %d replayed %+v, want %+v", i, got, want[i])
+			}
+		}
+		if _, err := client.Complete(context.Background(), replayRequest("extra")); !errors.Is(err, ErrReplayExhausted) {
+			t.Errorf("extra call error = %v, want ErrReplayExhausted", err)
+		}
+		if n := client.Remaining(); n != 0 {
+			t.Errorf("Remaining = %d after replay", n)
+		}
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		client := NewReplayClient(recs, ReplayBySequence)
+		if client.Model() != "test-model" {
+			t.Errorf("Model = %q", client.Model())
+		}
+		check(t, client, []int{0, 1, 2})
+	})
+	t.Run("hash", func(t *testing.T) {
+		// Out of order, and from a different session.
+		check(t, NewReplayClient(recs, ReplayByHash), []int{2, 0, 1})
+	})
+}
+
+func TestRequestHash_IgnoresSessionMetadata(t *testing.T) {
+	a := BuildCompletionRequest(ClientConfig{Model: "m", MaxThinkingTokens: 2048}, "s", nil, nil, LoopState{SessionID: "one"})
+	b := BuildCompletionRequest(ClientConfig{Model: "m", MaxThinkingTokens: 2048}, "s", nil, nil, LoopState{SessionID: "two"})
+	if a.ExtraBody["metadata"] == nil {
+		t.Fatal("expected session metadata in the request")
+	}
+	if RequestHash(a) != RequestHash(b) {
+		t.Error("hash differs across sessions")
+	}
+	c := BuildCompletionRequest(ClientConfig{Model: "other", MaxThinkingTokens: 2048}, "s", nil, nil, LoopState{SessionID: "one"})
+	if RequestHash(a) == RequestHash(c) {
+		t.Error("hash ignores the model")
+	}
+	if a.ExtraBody["metadata"] == nil {
+		t.Error("RequestHash modified the request")
+	}
+}
+
+// brokenStreamClient returns a stream that fails after one chunk.
+type brokenStreamClient struct{ sseClient }
+
+func (c *brokenStreamClient) Complete(context.Context, *CompletionRequest) (*Stream, error) {
+	text := "partial"
+	events := make(chan StreamEvent, 2)
+	events <- StreamEvent{Chunk: &StreamChunk{ID: "c1", Choices: []Choice{{Delta: Delta{Content: &text}}}}}
+	events <- StreamEvent{Err: errors.New("llm: stream read: connection reset")}
+	close(events)
+	return NewStream(events, nil, func() {}), nil
+}
+
+func TestRecordingClient_StreamError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	rec, err := NewRecordingClient(&brokenStreamClient{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := rec.Complete(context.Background(), replayRequest("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Accumulate(); err == nil {
+		t.Fatal("expected the stream error")
+	}
+	rec.Close()
+
+	recs, err := LoadRecordings(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || len(recs[0].Chunks) != 1 || recs[0].StreamError == "" {
+		t.Fatalf("recordings = %+v", recs)
+	}
+	stream, err = NewReplayClient(recs, ReplayBySequence).Complete(context.Background(), replayRequest("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chunk, err := stream.Next(); err != nil || *chunk.Choices[0].Delta.Content != "partial" {
+		t.Fatalf("first replayed chunk = %+v, %v", chunk, err)
+	}
+	if _, err := stream.Next(); err == nil || err.Error() != "llm: stream read: connection reset" {
+		t.Errorf("replayed stream error = %v", err)
+	}
+}
@@ -25,6 +25,7 @@ func BuildCompletionRequest(config ClientConfig, systemPrompt string, messages [
 		Stream:        true,
 		MaxTokens:     config.MaxTokens,
 		StreamOptions: &StreamOptions{IncludeUsage: true},
+		Stop:          config.StopSequences,
 	}
 
 	// System prompt as first message
@@ -33,20 +34,35 @@ func BuildCompletionRequest(config ClientConfig, systemPrompt string, messages [
 		Content: systemPrompt,
 	})
 
-	// Append conversation messages
+	// Append conversation messages; text-only models get image placeholders,
+	// and providers without image tool results get them in a user message
+	if !SupportsVision(config.Model) {
+		messages = stripImages(messages)
+	} else if !supportsToolResultImages(config.Model) {
+		messages = hoistToolImages(messages)
+	}
 	req.Messages = append(req.Messages, messages...)
 
 	// Tool definitions
 	for _, tool := range tools {
+		params := tool.InputSchema()
+		if config.StrictTools {
+			params = StrictSchema(params)
+		}
 		req.Tools = append(req.Tools, ToolDefinition{
 			Type: "function",
 			Function: FunctionDef{
 				Name:        tool.ToolName(),
 				Description: tool.Description(),
-				Parameters:  tool.InputSchema(),
+				Parameters:  params,
+				Strict:      config.StrictTools,
 			},
 		})
 	}
+	// Providers reject parallel_tool_calls on requests without tools
+	if len(req.Tools) > 0 {
+		req.ParallelToolCalls = config.ParallelToolCalls
+	}
 
 	// Groq/Llama-specific tuning: lower temperature and explicit tool_choice
 	// improve tool-calling reliability. Groq recommends temperature 0.0-0.3 for
@@ -79,10 +95,14 @@ func BuildCompletionRequest(config ClientConfig, systemPrompt string, messages [
 	// standard OpenAI-compatible providers reject unknown top-level fields.
 	extraBody := map[string]any{}
 
-	if config.MaxThinkingTokens > 0 {
+	// Reasoning: OpenAI reasoning models take reasoning_effort; everything
+	// else (Claude via LiteLLM) gets an Anthropic thinking block.
+	if IsOpenAIReasoningModel(config.Model) {
+		req.ReasoningEffort = reasoningEffort(config)
+	} else if budget := thinkingBudget(config); budget > 0 {
 		extraBody["thinking"] = map[string]any{
 			"type":          "enabled",
-			"budget_tokens": config.MaxThinkingTokens,
+			"budget_tokens": budget,
 		}
 	}
 
@@ -106,13 +126,19 @@ func BuildCompletionRequest(config ClientConfig, systemPrompt string, messages [
 }
 
 // ConvertToToolMessages converts internal tool_result content blocks to OpenAI "tool" messages.
+// Results with images get multi-part content: the text followed by the image parts.
 func ConvertToToolMessages(toolResults []ToolResult) []ChatMessage {
 	msgs := make([]ChatMessage, 0, len(toolResults))
 	for _, tr := range toolResults {
+		var content any = tr.Content
+		if len(tr.Images) > 0 {
+			parts := append([]ContentPart{TextPart(tr.Content)}, tr.Images...)
+			content = parts
+		}
 		msgs = append(msgs, ChatMessage{
 			Role:       "tool",
 			ToolCallID: tr.ToolUseID,
-			Content:    tr.Content,
+			Content:    content,
 		})
 	}
 	return msgs
@@ -122,6 +148,7 @@ func ConvertToToolMessages(toolResults []ToolResult) []ChatMessage {
 type ToolResult struct {
 	ToolUseID string
 	Content   string
+	Images    []ContentPart // image parts shown with Content (e.g. from the Read tool)
 	// Metadata contains optional structured data about the tool execution.
 	// Not sent to the LLM, used internally for tracking.
 	Metadata *ToolResultMetadata
@@ -132,6 +159,7 @@ type ToolResultMetadata struct {
 	FilePaths    []string // file paths accessed during this tool call
 	WasTruncated bool     // whether output was truncated
 	OriginalLen  int      // original content length before truncation
+	FromCache    bool     // served from a cached copy (WebFetch, WebSearch)
 }
 
 // convertAssistantToOpenAI converts internal content blocks to an OpenAI assistant message.
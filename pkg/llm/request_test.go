@@ -81,6 +81,36 @@ func TestBuildCompletionRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("parallel_tool_calls and strict tools", func(t *testing.T) {
+		parallel := false
+		config := ClientConfig{Model: "gpt-5-mini", MaxTokens: 8192, ParallelToolCalls: &parallel, StrictTools: true}
+		tools := []Tool{&mockTool{name: "Read", schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"file_path": map[string]any{"type": "string"}, "limit": map[string]any{"type": "integer"}},
+			"required":   []any{"file_path"},
+		}}}
+
+		req := BuildCompletionRequest(config, "sys", nil, tools, LoopState{})
+		body, _ := json.Marshal(req)
+
+		if !strings.Contains(string(body), `"parallel_tool_calls":false`) {
+			t.Errorf("body missing parallel_tool_calls=false: %s", body)
+		}
+		fn := req.Tools[0].Function
+		if !fn.Strict || fn.Parameters["additionalProperties"] != false {
+			t.Errorf("function = %+v, want strict with a normalized schema", fn)
+		}
+		if _, ok := tools[0].InputSchema()["additionalProperties"]; ok {
+			t.Error("tool's own schema was modified")
+		}
+
+		noTools := BuildCompletionRequest(config, "sys", nil, nil, LoopState{})
+		body, _ = json.Marshal(noTools)
+		if strings.Contains(string(body), "parallel_tool_calls") {
+			t.Errorf("parallel_tool_calls sent without tools: %s", body)
+		}
+	})
+
 	t.Run("extra_body with thinking", func(t *testing.T) {
 		config := ClientConfig{
 			Model:             "claude-opus-4-5-20250514",
@@ -170,6 +200,19 @@ func TestBuildCompletionRequest(t *testing.T) {
 		}
 	})
 
+	t.Run("stop sequences", func(t *testing.T) {
+		config := ClientConfig{Model: "gpt-4o", StopSequences: []string{"END"}}
+		req := BuildCompletionRequest(config, "sys", nil, nil, LoopState{})
+
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), `"stop":["END"]`) {
+			t.Errorf("request body %s missing stop", body)
+		}
+	})
+
 	t.Run("no extra_body when not needed", func(t *testing.T) {
 		config := ClientConfig{Model: "claude-opus-4-5-20250514", MaxTokens: 8192}
 		req := BuildCompletionRequest(config, "sys", nil, nil, LoopState{})
@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAWSCredentials = AWSCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSignSigV4(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite.
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	signSigV4(req, nil, testAWSCredentials, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n  %s\nwant\n  %s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestSignSigV4_SessionTokenAndPath(t *testing.T) {
+	creds := testAWSCredentials
+	creds.SessionToken = "session-token"
+	req, _ := http.NewRequest("POST", "https://bedrock-runtime.us-east-1.amazonaws.com/model/anthropic.claude-v2%3A1/invoke", nil)
+	req.Header.Set("Content-Type", "application/json")
+	signSigV4(req, []byte(`{}`), creds, "us-east-1", "bedrock", time.Now())
+
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "session-token" {
+		t.Errorf("X-Amz-Security-Token = %q", got)
+	}
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization = %q, want content type and session token signed", auth)
+	}
+	if got := sigV4CanonicalURI(req.URL); got != "/model/anthropic.claude-v2%253A1/invoke" {
+		t.Errorf("canonical URI = %q, want the path encoded twice", got)
+	}
+}
+
+func TestAWSURIEncode(t *testing.T) {
+	for in, want := range map[string]string{
+		"us.anthropic.claude-sonnet-4-5-20250929-v1:0": "us.anthropic.claude-sonnet-4-5-20250929-v1%3A0",
+		"a b/c~d": "a%20b%2Fc~d",
+		"x=y&z+1": "x%3Dy%26z%2B1",
+		"unicodé": "unicod%C3%A9",
+	} {
+		if got := awsURIEncode(in); got != want {
+			t.Errorf("awsURIEncode(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
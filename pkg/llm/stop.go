@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// openAIMaxStopSequences is the OpenAI API's limit on the stop parameter,
+// which OpenAI-compatible providers such as Groq share.
+const openAIMaxStopSequences = 4
+
+// MaxStopSequences returns how many stop sequences a request to model may
+// carry, or 0 if the provider sets no practical limit (Anthropic via the
+// LiteLLM proxy or Bedrock, and local vLLM models).
+func MaxStopSequences(model string) int {
+	if strings.HasPrefix(toRequestModel(model), "anthropic/") || IsBedrockModel(model) || IsLocalModel(model) {
+		return 0
+	}
+	return openAIMaxStopSequences
+}
+
+// ValidateStopSequences checks stop sequences against the provider limits
+// for model. Empty sequences are rejected since they would end generation
+// immediately on some providers and be ignored on others.
+func ValidateStopSequences(model string, stops []string) error {
+	if limit := MaxStopSequences(model); limit > 0 && len(stops) > limit {
+		return fmt.Errorf("%d stop sequences exceed the limit of %d for model %s", len(stops), limit, model)
+	}
+	for i, s := range stops {
+		if s == "" {
+			return fmt.Errorf("stop sequence %d is empty", i+1)
+		}
+	}
+	return nil
+}
+
+// matchedStopSequence returns the stop sequence a choice reports matching,
+// if any. Numeric values (stop token IDs) are not sequences and are ignored.
+func matchedStopSequence(choice Choice) string {
+	for _, v := range []any{choice.StopReason, choice.MatchedStop} {
+		if s, ok := v.(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
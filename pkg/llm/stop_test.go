@@ -0,0 +1,47 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateStopSequences(t *testing.T) {
+	five := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		name    string
+		model   string
+		stops   []string
+		wantErr string
+	}{
+		{"none", "gpt-4o", nil, ""},
+		{"within OpenAI limit", "gpt-4o", five[:4], ""},
+		{"over OpenAI limit", "gpt-4o", five, "exceed the limit of 4"},
+		{"over limit on Groq", "llama-3.3-70b-versatile", five, "exceed the limit of 4"},
+		{"Anthropic has no limit", "claude-sonnet-4-5-20250929", five, ""},
+		{"local has no limit", "qwen-local", five, ""},
+		{"empty sequence", "claude-sonnet-4-5-20250929", []string{"END", ""}, "stop sequence 2 is empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStopSequences(tt.model, tt.stops)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestComplete_RejectsInvalidStopSequences(t *testing.T) {
+	client := NewClient(ClientConfig{BaseURL: "http://127.0.0.1:0", Model: "gpt-4o"})
+	req := BuildCompletionRequest(ClientConfig{Model: "gpt-4o", StopSequences: []string{"1", "2", "3", "4", "5"}},
+		"sys", nil, nil, LoopState{})
+
+	if _, err := client.Complete(context.Background(), req); err == nil || !strings.Contains(err.Error(), "stop sequences") {
+		t.Errorf("Complete error = %v, want a stop sequence limit error", err)
+	}
+}
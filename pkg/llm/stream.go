@@ -101,6 +101,7 @@ func (s *Stream) AccumulateWithCallback(cb func(*StreamChunk)) (*CompletionRespo
 			// Capture finish reason
 			if choice.FinishReason != nil {
 				response.FinishReason = *choice.FinishReason
+				response.StopSequence = matchedStopSequence(choice)
 			}
 		}
 	}
@@ -136,7 +137,14 @@ func (s *Stream) AccumulateWithCallback(cb func(*StreamChunk)) (*CompletionRespo
 
 	response.ToolCalls = toolAccum.Complete()
 	response.StopReason = translateFinishReason(response.FinishReason)
-	response.Usage = translateUsage(usage)
+	if response.StopSequence != "" {
+		if response.StopReason == "end_turn" {
+			response.StopReason = "stop_sequence"
+		} else {
+			response.StopSequence = ""
+		}
+	}
+	response.Usage = TranslateUsage(usage)
 
 	return &response, nil
 }
@@ -214,4 +214,36 @@ data: [DONE]
 			t.Errorf("Content[0].Text = %q, want ab", resp.Content[0].Text)
 		}
 	})
+
+	t.Run("reported stop sequence", func(t *testing.T) {
+		for _, field := range []string{"stop_reason", "matched_stop"} {
+			sseData := `data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1234,"model":"llama-local","choices":[{"index":0,"delta":{"content":"42"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-7","object":"chat.completion.chunk","created":1234,"model":"llama-local","choices":[{"index":0,"delta":{},"finish_reason":"stop","` + field + `":"</final>"}]}
+
+data: [DONE]
+`
+			resp, err := makeTestStream(sseData).Accumulate()
+			if err != nil {
+				t.Fatalf("Accumulate error: %v", err)
+			}
+			if resp.StopReason != "stop_sequence" || resp.StopSequence != "</final>" {
+				t.Errorf("%s: StopReason = %q, StopSequence = %q; want stop_sequence, </final>", field, resp.StopReason, resp.StopSequence)
+			}
+		}
+	})
+
+	t.Run("stop token id is not a sequence", func(t *testing.T) {
+		sseData := `data: {"id":"chatcmpl-8","object":"chat.completion.chunk","created":1234,"model":"llama-local","choices":[{"index":0,"delta":{"content":"hi"},"finish_reason":"stop","stop_reason":128009}]}
+
+data: [DONE]
+`
+		resp, err := makeTestStream(sseData).Accumulate()
+		if err != nil {
+			t.Fatalf("Accumulate error: %v", err)
+		}
+		if resp.StopReason != "end_turn" || resp.StopSequence != "" {
+			t.Errorf("StopReason = %q, StopSequence = %q; want end_turn with no sequence", resp.StopReason, resp.StopSequence)
+		}
+	})
 }
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"maps"
+	"slices"
+)
+
+// StrictSchema returns a copy of an input schema that satisfies OpenAI's
+// strict function-calling rules: every object lists all of its properties as
+// required and sets additionalProperties to false. Properties that were
+// optional become nullable instead, so the model can still leave them out by
+// sending null; StripStrictNulls undoes that on the returned arguments. The
+// result is plain JSON Schema, which Anthropic models accept as well.
+func StrictSchema(schema map[string]any) map[string]any {
+	if schema == nil {
+		return nil
+	}
+	out := maps.Clone(schema)
+
+	if props, ok := schema["properties"].(map[string]any); ok || schema["type"] == "object" {
+		required := requiredSet(schema)
+		strictProps := make(map[string]any, len(props))
+		names := make([]any, 0, len(props))
+		for _, name := range slices.Sorted(maps.Keys(props)) {
+			prop, _ := props[name].(map[string]any)
+			if prop == nil {
+				prop = map[string]any{}
+			}
+			prop = StrictSchema(prop)
+			if !required[name] {
+				prop = nullable(prop)
+			}
+			strictProps[name] = prop
+			names = append(names, name)
+		}
+		out["properties"] = strictProps
+		out["required"] = names
+		out["additionalProperties"] = false
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		out["items"] = StrictSchema(items)
+	}
+	for _, key := range []string{"anyOf", "$defs", "definitions"} {
+		switch sub := schema[key].(type) {
+		case []any:
+			strict := make([]any, len(sub))
+			for i, s := range sub {
+				m, _ := s.(map[string]any)
+				strict[i] = StrictSchema(m)
+			}
+			out[key] = strict
+		case map[string]any:
+			strict := make(map[string]any, len(sub))
+			for name, s := range sub {
+				m, _ := s.(map[string]any)
+				strict[name] = StrictSchema(m)
+			}
+			out[key] = strict
+		}
+	}
+	return out
+}
+
+// StripStrictNulls removes the null values a model sends for optional
+// properties under StrictSchema, so tools see those properties as absent.
+// schema is the tool's original (non-strict) schema; nulls for required
+// properties are kept. input is not modified.
+func StripStrictNulls(input map[string]any, schema map[string]any) map[string]any {
+	if input == nil {
+		return nil
+	}
+	props, _ := schema["properties"].(map[string]any)
+	required := requiredSet(schema)
+	out := make(map[string]any, len(input))
+	for name, value := range input {
+		if value == nil && !required[name] {
+			continue
+		}
+		propSchema, _ := props[name].(map[string]any)
+		switch v := value.(type) {
+		case map[string]any:
+			value = StripStrictNulls(v, propSchema)
+		case []any:
+			items, _ := propSchema["items"].(map[string]any)
+			stripped := make([]any, len(v))
+			for i, item := range v {
+				if m, ok := item.(map[string]any); ok {
+					item = StripStrictNulls(m, items)
+				}
+				stripped[i] = item
+			}
+			value = stripped
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// nullable widens a property schema to also accept null.
+func nullable(prop map[string]any) map[string]any {
+	switch typ := prop["type"].(type) {
+	case string:
+		if typ == "null" {
+			return prop
+		}
+		prop["type"] = []any{typ, "null"}
+	case []any:
+		if !slices.Contains(typ, any("null")) {
+			prop["type"] = append(slices.Clone(typ), "null")
+		}
+	default:
+		return map[string]any{"anyOf": []any{prop, map[string]any{"type": "null"}}}
+	}
+	if enum, ok := prop["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		prop["enum"] = append(slices.Clone(enum), nil)
+	}
+	return prop
+}
+
+func requiredSet(schema map[string]any) map[string]bool {
+	set := map[string]bool{}
+	switch req := schema["required"].(type) {
+	case []any:
+		for _, name := range req {
+			if s, ok := name.(string); ok {
+				set[s] = true
+			}
+		}
+	case []string:
+		for _, name := range req {
+			set[name] = true
+		}
+	}
+	return set
+}
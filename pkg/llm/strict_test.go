@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStrictSchema(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer"},
+			"mode":  map[string]any{"type": "string", "enum": []any{"fast", "full"}},
+			"filter": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"glob": map[string]any{"type": "string"}},
+			},
+			"tags": map[string]any{"type": "array", "items": map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"name": map[string]any{"type": "string"}},
+				"required":   []any{"name"},
+			}},
+		},
+		"required": []any{"path"},
+	}
+	orig, _ := json.Marshal(schema)
+
+	strict := StrictSchema(schema)
+
+	if got, _ := json.Marshal(schema); string(got) != string(orig) {
+		t.Errorf("input schema was modified: %s", got)
+	}
+	if want := []any{"filter", "limit", "mode", "path", "tags"}; !reflect.DeepEqual(strict["required"], want) {
+		t.Errorf("required = %v, want all properties %v", strict["required"], want)
+	}
+	if strict["additionalProperties"] != false {
+		t.Errorf("additionalProperties = %v, want false", strict["additionalProperties"])
+	}
+	props := strict["properties"].(map[string]any)
+	if typ := props["path"].(map[string]any)["type"]; typ != "string" {
+		t.Errorf("required property type = %v, want unchanged", typ)
+	}
+	if typ := props["limit"].(map[string]any)["type"]; !reflect.DeepEqual(typ, []any{"integer", "null"}) {
+		t.Errorf("optional property type = %v, want nullable", typ)
+	}
+	if enum := props["mode"].(map[string]any)["enum"]; !reflect.DeepEqual(enum, []any{"fast", "full", nil}) {
+		t.Errorf("optional enum = %v, want null allowed", enum)
+	}
+	filter := props["filter"].(map[string]any)
+	if filter["additionalProperties"] != false || !reflect.DeepEqual(filter["required"], []any{"glob"}) {
+		t.Errorf("nested object = %v, want strict", filter)
+	}
+	items := props["tags"].(map[string]any)["items"].(map[string]any)
+	if items["additionalProperties"] != false {
+		t.Errorf("array items = %v, want strict", items)
+	}
+	if name := items["properties"].(map[string]any)["name"].(map[string]any)["type"]; name != "string" {
+		t.Errorf("required item property type = %v", name)
+	}
+}
+
+func TestStrictSchema_UntypedOptional(t *testing.T) {
+	strict := StrictSchema(map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"value": map[string]any{"description": "anything"}},
+	})
+	value := strict["properties"].(map[string]any)["value"].(map[string]any)
+	if _, ok := value["anyOf"]; !ok {
+		t.Errorf("untyped optional property = %v, want anyOf with null", value)
+	}
+}
+
+func TestStripStrictNulls(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":   map[string]any{"type": "string"},
+			"limit":  map[string]any{"type": "integer"},
+			"filter": map[string]any{"type": "object", "properties": map[string]any{"glob": map[string]any{"type": "string"}}},
+		},
+		"required": []any{"path"},
+	}
+	input := map[string]any{"path": nil, "limit": nil, "filter": map[string]any{"glob": nil}}
+
+	got := StripStrictNulls(input, schema)
+
+	want := map[string]any{"path": nil, "filter": map[string]any{}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StripStrictNulls = %v, want %v", got, want)
+	}
+	if _, ok := input["limit"]; !ok {
+		t.Error("input was modified")
+	}
+}
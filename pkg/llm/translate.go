@@ -20,17 +20,31 @@ func translateFinishReason(fr string) string {
 	}
 }
 
-// translateUsage converts OpenAI Usage to Anthropic BetaUsage.
-func translateUsage(u *Usage) types.BetaUsage {
+// TranslateUsage converts OpenAI Usage to Anthropic BetaUsage, where
+// InputTokens counts only uncached input. OpenAI's
+// prompt_tokens_details.cached_tokens maps to CacheReadInputTokens unless
+// the Anthropic cache fields are also present.
+func TranslateUsage(u *Usage) types.BetaUsage {
 	if u == nil {
 		return types.BetaUsage{}
 	}
-	return types.BetaUsage{
+	usage := types.BetaUsage{
 		InputTokens:              u.PromptTokens,
 		OutputTokens:             u.CompletionTokens,
 		CacheReadInputTokens:     u.CacheReadInputTokens,
 		CacheCreationInputTokens: u.CacheCreationInputTokens,
 	}
+	if d := u.PromptTokensDetails; d != nil && d.CachedTokens > 0 {
+		if usage.CacheReadInputTokens == 0 {
+			usage.CacheReadInputTokens = d.CachedTokens
+		}
+		// OpenAI-style prompt_tokens include cached tokens; remove them so
+		// they are not billed twice.
+		if cached := usage.CacheReadInputTokens + usage.CacheCreationInputTokens; cached <= usage.InputTokens {
+			usage.InputTokens -= cached
+		}
+	}
+	return usage
 }
 
 // IsGroqLlama returns true if the model identifier suggests a Groq-hosted or Llama/Mixtral
@@ -76,7 +90,7 @@ func fromResponseModel(model string) string {
 // ToBetaMessage converts the accumulated CompletionResponse to an Anthropic-equivalent BetaMessage.
 func (r *CompletionResponse) ToBetaMessage() types.BetaMessage {
 	stopReason := r.StopReason
-	return types.BetaMessage{
+	msg := types.BetaMessage{
 		ID:         r.ID,
 		Type:       "message",
 		Role:       "assistant",
@@ -85,4 +99,9 @@ func (r *CompletionResponse) ToBetaMessage() types.BetaMessage {
 		StopReason: &stopReason,
 		Usage:      r.Usage,
 	}
+	if r.StopSequence != "" {
+		stopSequence := r.StopSequence
+		msg.StopSequence = &stopSequence
+	}
+	return msg
 }
@@ -1,6 +1,7 @@
 package llm
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/jg-phare/goat/pkg/types"
@@ -31,9 +32,9 @@ func TestTranslateFinishReason(t *testing.T) {
 
 func TestTranslateUsage(t *testing.T) {
 	t.Run("nil usage", func(t *testing.T) {
-		got := translateUsage(nil)
+		got := TranslateUsage(nil)
 		if got != (types.BetaUsage{}) {
-			t.Errorf("translateUsage(nil) = %+v, want zero BetaUsage", got)
+			t.Errorf("TranslateUsage(nil) = %+v, want zero BetaUsage", got)
 		}
 	})
 
@@ -45,7 +46,7 @@ func TestTranslateUsage(t *testing.T) {
 			CacheReadInputTokens:     100,
 			CacheCreationInputTokens: 50,
 		}
-		got := translateUsage(u)
+		got := TranslateUsage(u)
 		expected := types.BetaUsage{
 			InputTokens:              1234,
 			OutputTokens:             567,
@@ -53,15 +54,42 @@ func TestTranslateUsage(t *testing.T) {
 			CacheCreationInputTokens: 50,
 		}
 		if got != expected {
-			t.Errorf("translateUsage() = %+v, want %+v", got, expected)
+			t.Errorf("TranslateUsage() = %+v, want %+v", got, expected)
 		}
 	})
 
 	t.Run("zero usage", func(t *testing.T) {
 		u := &Usage{}
-		got := translateUsage(u)
+		got := TranslateUsage(u)
 		if got != (types.BetaUsage{}) {
-			t.Errorf("translateUsage(&Usage{}) = %+v, want zero BetaUsage", got)
+			t.Errorf("TranslateUsage(&Usage{}) = %+v, want zero BetaUsage", got)
+		}
+	})
+
+	t.Run("OpenAI cached tokens", func(t *testing.T) {
+		var u Usage
+		raw := `{"prompt_tokens":1000,"completion_tokens":20,"total_tokens":1020,"prompt_tokens_details":{"cached_tokens":800}}`
+		if err := json.Unmarshal([]byte(raw), &u); err != nil {
+			t.Fatal(err)
+		}
+		got := TranslateUsage(&u)
+		expected := types.BetaUsage{InputTokens: 200, OutputTokens: 20, CacheReadInputTokens: 800}
+		if got != expected {
+			t.Errorf("TranslateUsage() = %+v, want %+v", got, expected)
+		}
+	})
+
+	t.Run("Anthropic cache fields take precedence over details", func(t *testing.T) {
+		u := &Usage{
+			PromptTokens:             1000,
+			CacheReadInputTokens:     700,
+			CacheCreationInputTokens: 100,
+			PromptTokensDetails:      &PromptTokensDetails{CachedTokens: 700},
+		}
+		got := TranslateUsage(u)
+		expected := types.BetaUsage{InputTokens: 200, CacheReadInputTokens: 700, CacheCreationInputTokens: 100}
+		if got != expected {
+			t.Errorf("TranslateUsage() = %+v, want %+v", got, expected)
 		}
 	})
 }
@@ -2,16 +2,18 @@ package llm
 
 // CompletionRequest maps to OpenAI /v1/chat/completions request body.
 type CompletionRequest struct {
-	Model         string           `json:"model"`
-	Messages      []ChatMessage    `json:"messages"`
-	Tools         []ToolDefinition `json:"tools,omitempty"`
-	ToolChoice    any              `json:"tool_choice,omitempty"` // "auto" | "none" | {"type":"function","function":{"name":"..."}}
-	Stream        bool             `json:"stream"`
-	MaxTokens     int              `json:"max_tokens,omitempty"`
-	Temperature   *float64         `json:"temperature,omitempty"`
-	TopP          *float64         `json:"top_p,omitempty"`
-	Stop          []string         `json:"stop,omitempty"`
-	StreamOptions *StreamOptions   `json:"stream_options,omitempty"`
+	Model             string           `json:"model"`
+	Messages          []ChatMessage    `json:"messages"`
+	Tools             []ToolDefinition `json:"tools,omitempty"`
+	ToolChoice        any              `json:"tool_choice,omitempty"` // "auto" | "none" | {"type":"function","function":{"name":"..."}}
+	ParallelToolCalls *bool            `json:"parallel_tool_calls,omitempty"`
+	Stream            bool             `json:"stream"`
+	MaxTokens         int              `json:"max_tokens,omitempty"`
+	Temperature       *float64         `json:"temperature,omitempty"`
+	TopP              *float64         `json:"top_p,omitempty"`
+	Stop              []string         `json:"stop,omitempty"`
+	ReasoningEffort   string           `json:"reasoning_effort,omitempty"` // OpenAI reasoning models (o-series, gpt-5)
+	StreamOptions     *StreamOptions   `json:"stream_options,omitempty"`
 
 	// LiteLLM passthrough for Anthropic-specific fields
 	ExtraBody map[string]any `json:"extra_body,omitempty"`
@@ -33,6 +35,10 @@ type ChatMessage struct {
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // assistant messages only
 	ToolCallID string     `json:"tool_call_id,omitempty"` // tool result messages only
 	Name       string     `json:"name,omitempty"`         // optional sender name
+
+	// ID identifies the message in the agent's history (e.g. for cached
+	// token counts). It is never sent to the API.
+	ID string `json:"-"`
 }
 
 // ContentPart for multi-part content arrays (text, images, tool results).
@@ -73,6 +79,7 @@ type FunctionDef struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	Parameters  map[string]any `json:"parameters"` // JSON Schema object
+	Strict      bool           `json:"strict,omitempty"`
 }
 
 // StreamChunk represents a single SSE chunk from LiteLLM.
@@ -91,6 +98,12 @@ type Choice struct {
 	Index        int     `json:"index"`
 	Delta        Delta   `json:"delta"`
 	FinishReason *string `json:"finish_reason"` // null | "stop" | "tool_calls" | "length"
+
+	// The matched stop sequence, for providers that report it alongside
+	// finish_reason "stop" (vLLM: stop_reason, SGLang: matched_stop). Either
+	// may also be a token ID when generation ended on a stop token.
+	StopReason  any `json:"stop_reason,omitempty"`
+	MatchedStop any `json:"matched_stop,omitempty"`
 }
 
 // Delta is the incremental content in a streaming chunk.
@@ -108,4 +121,12 @@ type Usage struct {
 	TotalTokens              int `json:"total_tokens"`
 	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"` // OpenAI cache accounting
+}
+
+// PromptTokensDetails breaks down OpenAI prompt_tokens; cached tokens are
+// included in prompt_tokens.
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
 }
@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrServerDegraded is returned by CallTool without contacting the server
+// while its circuit breaker is open.
+var ErrServerDegraded = errors.New("mcp: server degraded")
+
+const (
+	defaultBreakerThreshold  = 3
+	defaultBreakerCooldown   = 30 * time.Second
+	defaultReconnectAttempts = 3
+)
+
+// BreakerConfig tunes the per-server circuit breaker behind CallTool. A call
+// fails when the server's transport errors and reconnecting does not help.
+// After FailureThreshold such calls in a row the server is marked degraded
+// and calls fail fast for Cooldown; the next call after that probes the
+// server with Ping (reconnecting if needed) and restores it on success.
+type BreakerConfig struct {
+	FailureThreshold  int           // consecutive failed calls that open the breaker (0 = 3, negative = never)
+	Cooldown          time.Duration // how long calls fail fast before the next probe (0 = 30s)
+	ReconnectAttempts int           // reconnects tried per failed call while closed (0 = 3)
+}
+
+func (b BreakerConfig) threshold() int {
+	if b.FailureThreshold == 0 {
+		return defaultBreakerThreshold
+	}
+	return b.FailureThreshold
+}
+
+func (b BreakerConfig) cooldown() time.Duration {
+	if b.Cooldown <= 0 {
+		return defaultBreakerCooldown
+	}
+	return b.Cooldown
+}
+
+func (b BreakerConfig) reconnectAttempts() int {
+	if b.ReconnectAttempts <= 0 {
+		return defaultReconnectAttempts
+	}
+	return b.ReconnectAttempts
+}
+
+// breaker is the circuit state of one server. It is keyed by server name on
+// the Client because Reconnect replaces the ServerConnection.
+type breaker struct {
+	failures  int
+	open      bool
+	openUntil time.Time
+	probing   bool
+	lastErr   string
+	tools     []ToolInfo // last known tools, kept registered while open
+}
+
+// SetBreaker configures the circuit breaker for all servers.
+func (c *Client) SetBreaker(config BreakerConfig) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	c.breakerConfig = config
+}
+
+// admit fails fast while name's breaker is open. Once the cooldown has passed
+// one caller probes the server; the others keep failing fast until it is done.
+func (c *Client) admit(ctx context.Context, name string) error {
+	c.breakerMu.Lock()
+	b := c.breakers[name]
+	if b == nil || !b.open {
+		c.breakerMu.Unlock()
+		return nil
+	}
+	if b.probing || c.now().Before(b.openUntil) {
+		err := degradedError(name, b, c.now())
+		c.breakerMu.Unlock()
+		return err
+	}
+	b.probing = true
+	c.breakerMu.Unlock()
+
+	err := c.probe(ctx, name)
+
+	c.breakerMu.Lock()
+	b.probing = false
+	if err == nil {
+		delete(c.breakers, name)
+		c.breakerMu.Unlock()
+		return nil
+	}
+	if ctx.Err() == nil {
+		b.openUntil = c.now().Add(c.breakerConfig.cooldown())
+		b.lastErr = err.Error()
+	}
+	degraded := degradedError(name, b, c.now())
+	tools := b.tools
+	c.breakerMu.Unlock()
+
+	c.keepToolsRegistered(name, tools)
+	return degraded
+}
+
+// probe checks that name answers a ping, reconnecting once if it does not.
+func (c *Client) probe(ctx context.Context, name string) error {
+	if err := c.Ping(ctx, name); err == nil {
+		return nil
+	}
+	if err := c.Reconnect(ctx, name); err != nil {
+		return err
+	}
+	return c.Ping(ctx, name)
+}
+
+// recordFailure counts a call that failed at the transport level even after
+// reconnecting, opening the breaker at the threshold.
+func (c *Client) recordFailure(name string, err error, tools []ToolInfo) {
+	c.breakerMu.Lock()
+	threshold := c.breakerConfig.threshold()
+	if threshold < 0 {
+		c.breakerMu.Unlock()
+		return
+	}
+	b := c.breakers[name]
+	if b == nil {
+		b = &breaker{}
+		c.breakers[name] = b
+	}
+	b.failures++
+	b.lastErr = err.Error()
+	if len(tools) > 0 {
+		b.tools = tools
+	}
+	if b.failures >= threshold {
+		b.open = true
+		b.openUntil = c.now().Add(c.breakerConfig.cooldown())
+	}
+	tools = b.tools
+	c.breakerMu.Unlock()
+
+	c.keepToolsRegistered(name, tools)
+}
+
+// recordSuccess resets name's consecutive failure count.
+func (c *Client) recordSuccess(name string) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	delete(c.breakers, name)
+}
+
+// keepToolsRegistered re-registers a failing server's tools after a failed
+// reconnect dropped them, so later calls still reach CallTool: they count
+// towards the breaker, then get the degraded error and trigger the probe,
+// rather than an unknown-tool error.
+func (c *Client) keepToolsRegistered(name string, tools []ToolInfo) {
+	c.registerTools(name, tools)
+}
+
+// applyBreaker reports an open breaker in s.
+func (c *Client) applyBreaker(s *ServerStatus) {
+	c.breakerMu.Lock()
+	defer c.breakerMu.Unlock()
+	if b := c.breakers[s.Name]; b != nil && b.open {
+		s.Status = StatusDegraded
+		s.Error = b.lastErr
+	}
+}
+
+func degradedError(name string, b *breaker, now time.Time) error {
+	wait := max(b.openUntil.Sub(now), 0).Round(time.Second)
+	return fmt.Errorf("%w: %q is failing fast after repeated transport errors (retry in %s): %s",
+		ErrServerDegraded, name, wait, b.lastErr)
+}
@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// newBreakerTestClient connects a mock server whose transport then dies.
+// Reconnecting fails immediately because the server has no command.
+func newBreakerTestClient(t *testing.T, threshold int) (*Client, *time.Time) {
+	t.Helper()
+	client := NewClient(tools.NewRegistry())
+	client.SetBreaker(BreakerConfig{FailureThreshold: threshold, Cooldown: time.Minute, ReconnectAttempts: 1})
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	mock := newMockTransport().
+		withInitialize(ServerCapabilities{Tools: &ToolsCapability{}}).
+		withTools([]ToolInfo{{Name: "tool1"}})
+	connectWithMock(t, client, "srv1", mock)
+	mock.Close()
+	return client, &now
+}
+
+func serverStatus(t *testing.T, client *Client) ServerStatus {
+	t.Helper()
+	s, err := client.ServerStatus("srv1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *s
+}
+
+func TestBreaker_OpensAfterConsecutiveFailures(t *testing.T) {
+	client, _ := newBreakerTestClient(t, 2)
+
+	for i := range 2 {
+		_, err := client.CallTool(context.Background(), "srv1", "tool1", nil)
+		if err == nil || errors.Is(err, ErrServerDegraded) {
+			t.Fatalf("call %d: err = %v, want a transport failure", i+1, err)
+		}
+		if _, ok := client.registry.Get("mcp__srv1__tool1"); !ok {
+			t.Fatalf("call %d: tool dropped from the registry after a failed reconnect", i+1)
+		}
+	}
+	if s := serverStatus(t, client); s.Status != StatusDegraded || s.Error == "" {
+		t.Errorf("status = %+v, want degraded with the last error", s)
+	}
+
+	start := time.Now()
+	_, err := client.CallTool(context.Background(), "srv1", "tool1", nil)
+	if !errors.Is(err, ErrServerDegraded) {
+		t.Fatalf("err = %v, want ErrServerDegraded", err)
+	}
+	if !strings.Contains(err.Error(), "retry in 1m0s") {
+		t.Errorf("err = %q, want the remaining cooldown", err)
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Error("degraded call should fail fast")
+	}
+}
+
+func TestBreaker_ProbeRestoresServer(t *testing.T) {
+	client, now := newBreakerTestClient(t, 1)
+	client.CallTool(context.Background(), "srv1", "tool1", nil)
+	if s := serverStatus(t, client); s.Status != StatusDegraded {
+		t.Fatalf("status = %q, want degraded", s.Status)
+	}
+
+	// The server comes back; calls keep failing fast until the cooldown ends
+	pong, _ := json.Marshal(map[string]string{})
+	healthy := newMockTransport().
+		withInitialize(ServerCapabilities{Tools: &ToolsCapability{}}).
+		withTools([]ToolInfo{{Name: "tool1"}}).
+		withToolCall(ToolResult{Content: []ContentBlock{{Type: "text", Text: "ok"}}}).
+		withResponse("ping", pong)
+	connectWithMock(t, client, "srv1", healthy)
+	if _, err := client.CallTool(context.Background(), "srv1", "tool1", nil); !errors.Is(err, ErrServerDegraded) {
+		t.Fatalf("err = %v, want ErrServerDegraded during cooldown", err)
+	}
+
+	*now = now.Add(time.Minute)
+	result, err := client.CallTool(context.Background(), "srv1", "tool1", nil)
+	if err != nil {
+		t.Fatalf("call after successful probe: %v", err)
+	}
+	if result.Content[0].Text != "ok" {
+		t.Errorf("result = %+v", result)
+	}
+	if s := serverStatus(t, client); s.Status != StatusConnected {
+		t.Errorf("status = %q, want connected after the probe", s.Status)
+	}
+}
+
+func TestBreaker_FailedProbeReopens(t *testing.T) {
+	client, now := newBreakerTestClient(t, 1)
+	client.CallTool(context.Background(), "srv1", "tool1", nil)
+
+	*now = now.Add(time.Minute)
+	_, err := client.CallTool(context.Background(), "srv1", "tool1", nil)
+	if !errors.Is(err, ErrServerDegraded) {
+		t.Fatalf("err = %v, want ErrServerDegraded after a failed probe", err)
+	}
+	if !strings.Contains(err.Error(), "retry in 1m0s") {
+		t.Errorf("err = %q, want a fresh cooldown", err)
+	}
+	if _, ok := client.registry.Get("mcp__srv1__tool1"); !ok {
+		t.Error("degraded server's tools should stay registered")
+	}
+}
+
+func TestBreaker_ApplicationErrorsDoNotCount(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	client.SetBreaker(BreakerConfig{FailureThreshold: 1})
+	mock := newMockTransport().
+		withInitialize(ServerCapabilities{Tools: &ToolsCapability{}}).
+		withTools([]ToolInfo{{Name: "tool1"}}) // no tools/call response: method not found
+	connectWithMock(t, client, "srv1", mock)
+
+	for range 3 {
+		_, err := client.CallTool(context.Background(), "srv1", "tool1", nil)
+		if err == nil || errors.Is(err, ErrServerDegraded) {
+			t.Fatalf("err = %v, want the server's error", err)
+		}
+	}
+	if s := serverStatus(t, client); s.Status != StatusConnected {
+		t.Errorf("status = %q, want connected", s.Status)
+	}
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	client, _ := newBreakerTestClient(t, -1)
+	for range 3 {
+		_, err := client.CallTool(context.Background(), "srv1", "tool1", nil)
+		if errors.Is(err, ErrServerDegraded) {
+			t.Fatal("breaker opened with a negative threshold")
+		}
+	}
+	if s := serverStatus(t, client); s.Status == StatusDegraded {
+		t.Error("status should not be degraded with the breaker disabled")
+	}
+}
@@ -3,11 +3,15 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
@@ -17,19 +21,62 @@ type Client struct {
 	mu       sync.RWMutex
 	servers  map[string]*ServerConnection
 	registry *tools.Registry
+
+	// Sampling: see SetSampler
+	sampler         llm.Client
+	samplingChecker agent.PermissionChecker
+	samplingUsed    map[string]int // tokens consumed per server
+
+	// OAuth: see SetTokenStore and SetAuthStatusHandler
+	tokenStore   TokenStore
+	onAuthStatus func(*types.AuthStatusMessage)
+
+	// Resource subscriptions per server (server → URI → handler); they
+	// survive reconnects and are dropped by Disconnect
+	subscriptions map[string]map[string]ResourceUpdateHandler
+
+	// Circuit breaking: see SetBreaker
+	breakerMu     sync.Mutex
+	breakerConfig BreakerConfig
+	breakers      map[string]*breaker
+	now           func() time.Time
 }
 
 // NewClient creates a new MCP client that will register discovered tools in the given registry.
 func NewClient(registry *tools.Registry) *Client {
 	return &Client{
-		servers:  make(map[string]*ServerConnection),
-		registry: registry,
+		servers:    make(map[string]*ServerConnection),
+		registry:   registry,
+		tokenStore: newMemoryTokenStore(),
+		breakers:   make(map[string]*breaker),
+		now:        time.Now,
+
+		subscriptions: make(map[string]map[string]ResourceUpdateHandler),
 	}
 }
 
+// SetTokenStore sets where OAuth tokens are cached. By default they are kept
+// in memory for the lifetime of the client.
+func (c *Client) SetTokenStore(store TokenStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tokenStore = store
+}
+
+// SetAuthStatusHandler registers a callback for OAuth progress, including
+// the URL the user must open to authorize a server.
+func (c *Client) SetAuthStatusHandler(handler func(*types.AuthStatusMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onAuthStatus = handler
+}
+
 // Connect establishes a connection to an MCP server and registers its tools.
 func (c *Client) Connect(ctx context.Context, name string, config types.McpServerConfig) error {
 	conn := newServerConnection(name, config)
+	c.mu.RLock()
+	conn.tokenStore, conn.onAuthStatus = c.tokenStore, c.onAuthStatus
+	c.mu.RUnlock()
 
 	if err := conn.connect(ctx); err != nil {
 		c.mu.Lock()
@@ -47,16 +94,25 @@ func (c *Client) Connect(ctx context.Context, name string, config types.McpServe
 	if conn.Transport != nil {
 		serverName := name
 		conn.Transport.SetNotificationHandler(func(method string, params json.RawMessage) {
-			if method == "notifications/tools/list_changed" {
-				c.handleToolListChanged(serverName)
-			}
+			c.handleNotification(serverName, method, params)
 		})
+		if responder, ok := conn.Transport.(RequestResponder); ok {
+			responder.SetRequestHandler(func(ctx context.Context, method string, params json.RawMessage) (any, *JSONRPCError) {
+				return c.handleServerRequest(ctx, serverName, method, params)
+			})
+		}
+		if sse, ok := conn.Transport.(*SSETransport); ok {
+			sse.SetReconnectHandler(func() { c.handleStreamReconnect(serverName) })
+		}
 	}
 	conn.mu.Unlock()
 
 	// Register tools in the registry
 	c.registerTools(name, conn.Tools)
 
+	// Renew subscriptions held from before a reconnect
+	c.resubscribe(ctx, name)
+
 	return nil
 }
 
@@ -69,8 +125,10 @@ func (c *Client) Disconnect(name string) error {
 		return fmt.Errorf("unknown server: %q", name)
 	}
 	delete(c.servers, name)
+	delete(c.subscriptions, name) // the server forgets them with the session
 	c.mu.Unlock()
 
+	c.recordSuccess(name) // forget breaker state
 	c.registry.UnregisterMCPTools(name)
 	return conn.disconnect()
 }
@@ -124,6 +182,9 @@ func (c *Client) Toggle(name string, enabled bool) error {
 }
 
 // SetServers performs a bulk update: adds new servers, removes old ones, keeps unchanged.
+// Configs are compared before ${VAR} expansion (see ExpandConfig), so a changed
+// environment value alone does not reconnect a server; a server with an unset
+// variable is reported in Errors.
 func (c *Client) SetServers(ctx context.Context, servers map[string]types.McpServerConfig) *SetServersResult {
 	result := &SetServersResult{
 		Errors: make(map[string]string),
@@ -195,11 +256,26 @@ func (c *Client) Status() []ServerStatus {
 
 	statuses := make([]ServerStatus, 0, len(c.servers))
 	for _, conn := range c.servers {
-		statuses = append(statuses, conn.status())
+		s := conn.status()
+		c.applyBreaker(&s)
+		statuses = append(statuses, s)
 	}
 	return statuses
 }
 
+// McpServerStatus reports Status in the form the agent's init message uses.
+// It implements agent.MCPStatusProvider.
+func (c *Client) McpServerStatus() []types.McpServerInfo {
+	statuses := c.Status()
+	infos := make([]types.McpServerInfo, len(statuses))
+	for i, s := range statuses {
+		infos[i] = types.McpServerInfo{Name: s.Name, Status: string(s.Status), Tools: len(s.Tools), Error: s.Error}
+	}
+	return infos
+}
+
+var _ agent.MCPStatusProvider = (*Client)(nil)
+
 // ServerStatus returns the status of a specific server.
 func (c *Client) ServerStatus(name string) (*ServerStatus, error) {
 	c.mu.RLock()
@@ -211,6 +287,7 @@ func (c *Client) ServerStatus(name string) (*ServerStatus, error) {
 	}
 
 	s := conn.status()
+	c.applyBreaker(&s)
 	return &s, nil
 }
 
@@ -268,10 +345,98 @@ func (c *Client) ReadResource(ctx context.Context, serverName, uri string) (tool
 	}, nil
 }
 
+// ListPrompts implements tools.MCPClient. An empty serverName lists the
+// prompts of every connected server that supports them; a server that fails
+// to list is reported in the joined error alongside the prompts the others
+// returned.
+func (c *Client) ListPrompts(ctx context.Context, serverName string) ([]tools.MCPPrompt, error) {
+	var conns []*ServerConnection
+	c.mu.RLock()
+	if serverName == "" {
+		for _, conn := range c.servers {
+			conns = append(conns, conn)
+		}
+	} else if conn, ok := c.servers[serverName]; ok {
+		conns = append(conns, conn)
+	}
+	c.mu.RUnlock()
+
+	if serverName != "" && len(conns) == 0 {
+		return nil, fmt.Errorf("unknown server: %q", serverName)
+	}
+	sort.Slice(conns, func(i, j int) bool { return conns[i].Name < conns[j].Name })
+
+	var result []tools.MCPPrompt
+	var errs []error
+	for _, conn := range conns {
+		if serverName == "" && !conn.supportsPrompts() {
+			continue
+		}
+		prompts, err := conn.listPrompts(ctx)
+		if err != nil {
+			if serverName != "" {
+				return nil, err
+			}
+			errs = append(errs, fmt.Errorf("server %q: %w", conn.Name, err))
+			continue
+		}
+		for _, p := range prompts {
+			args := make([]tools.MCPPromptArgument, len(p.Arguments))
+			for i, a := range p.Arguments {
+				args[i] = tools.MCPPromptArgument{Name: a.Name, Description: a.Description, Required: a.Required}
+			}
+			result = append(result, tools.MCPPrompt{
+				ServerName:  conn.Name,
+				Name:        p.Name,
+				Description: p.Description,
+				Arguments:   args,
+			})
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// GetPrompt implements tools.MCPClient.
+func (c *Client) GetPrompt(ctx context.Context, serverName, name string, args map[string]string) (tools.MCPPromptResult, error) {
+	c.mu.RLock()
+	conn, ok := c.servers[serverName]
+	c.mu.RUnlock()
+
+	if !ok {
+		return tools.MCPPromptResult{}, fmt.Errorf("unknown server: %q", serverName)
+	}
+
+	result, err := conn.getPrompt(ctx, name, args)
+	if err != nil {
+		return tools.MCPPromptResult{}, err
+	}
+
+	messages := make([]tools.MCPPromptMessage, len(result.Messages))
+	for i, m := range result.Messages {
+		messages[i] = tools.MCPPromptMessage{
+			Role: m.Role,
+			Content: tools.MCPContentBlock{
+				Type:     m.Content.Type,
+				Text:     m.Content.Text,
+				MimeType: m.Content.MimeType,
+				Data:     m.Content.Data,
+				URI:      m.Content.URI,
+			},
+		}
+	}
+	return tools.MCPPromptResult{Description: result.Description, Messages: messages}, nil
+}
+
 // CallTool implements tools.MCPClient.
 // If the transport reports a connection error, CallTool attempts auto-reconnection
-// with exponential backoff before retrying the call once.
+// with exponential backoff before retrying the call once. Calls that still fail
+// count towards the server's circuit breaker (see BreakerConfig); while it is
+// open CallTool returns ErrServerDegraded without contacting the server.
 func (c *Client) CallTool(ctx context.Context, serverName, toolName string, args map[string]any) (tools.MCPToolCallResult, error) {
+	if err := c.admit(ctx, serverName); err != nil {
+		return tools.MCPToolCallResult{}, err
+	}
+
 	c.mu.RLock()
 	conn, ok := c.servers[serverName]
 	c.mu.RUnlock()
@@ -283,20 +448,40 @@ func (c *Client) CallTool(ctx context.Context, serverName, toolName string, args
 	result, err := conn.callTool(ctx, toolName, args)
 	if err != nil {
 		// Check if this is a transport-level failure worth reconnecting for
-		if isTransportError(err) {
-			if reconnErr := c.reconnectWithBackoff(ctx, serverName, 3); reconnErr == nil {
-				// Retry once after successful reconnect
-				result, err = conn.callTool(ctx, toolName, args)
-				if err != nil {
-					return tools.MCPToolCallResult{}, err
-				}
-			} else {
-				return tools.MCPToolCallResult{}, fmt.Errorf("tool call failed and reconnect failed: %w", err)
+		if !isTransportError(err) {
+			c.recordSuccess(serverName) // the server answered
+			return tools.MCPToolCallResult{}, err
+		}
+		conn.mu.Lock()
+		known := conn.Tools
+		conn.mu.Unlock()
+
+		c.breakerMu.Lock()
+		attempts := c.breakerConfig.reconnectAttempts()
+		c.breakerMu.Unlock()
+		if reconnErr := c.reconnectWithBackoff(ctx, serverName, attempts); reconnErr != nil {
+			if ctx.Err() == nil {
+				c.recordFailure(serverName, err, known)
+			}
+			return tools.MCPToolCallResult{}, fmt.Errorf("tool call failed and reconnect failed: %w", err)
+		}
+
+		// Retry once on the new connection
+		c.mu.RLock()
+		conn, ok = c.servers[serverName]
+		c.mu.RUnlock()
+		if !ok {
+			return tools.MCPToolCallResult{}, fmt.Errorf("unknown server: %q", serverName)
+		}
+		result, err = conn.callTool(ctx, toolName, args)
+		if err != nil {
+			if isTransportError(err) && ctx.Err() == nil {
+				c.recordFailure(serverName, err, known)
 			}
-		} else {
 			return tools.MCPToolCallResult{}, err
 		}
 	}
+	c.recordSuccess(serverName)
 
 	blocks := make([]tools.MCPContentBlock, len(result.Content))
 	for i, cb := range result.Content {
@@ -365,6 +550,9 @@ func (c *Client) reconnectWithBackoff(ctx context.Context, name string, maxAttem
 		if err == nil {
 			return nil
 		}
+		if attempt == maxAttempts-1 {
+			break
+		}
 
 		select {
 		case <-ctx.Done():
@@ -409,6 +597,38 @@ func configEqual(a, b types.McpServerConfig) bool {
 			return false
 		}
 	}
+	return samplingEqual(a.Sampling, b.Sampling) && authEqual(a.Auth, b.Auth)
+}
+
+// samplingEqual compares two sampling configs; nil equals only nil.
+func samplingEqual(a, b *types.McpSamplingConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Enabled != b.Enabled || a.MaxTokens != b.MaxTokens || len(a.Models) != len(b.Models) {
+		return false
+	}
+	for i := range a.Models {
+		if a.Models[i] != b.Models[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func authEqual(a, b *types.McpAuthConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Type != b.Type || a.TokenEnv != b.TokenEnv || a.ClientID != b.ClientID ||
+		a.ClientSecret != b.ClientSecret || a.RedirectURI != b.RedirectURI || len(a.Scopes) != len(b.Scopes) {
+		return false
+	}
+	for i := range a.Scopes {
+		if a.Scopes[i] != b.Scopes[i] {
+			return false
+		}
+	}
 	return true
 }
 
@@ -426,6 +646,23 @@ func isTransportError(err error) bool {
 		strings.Contains(msg, "broken pipe")
 }
 
+// handleNotification dispatches a server-initiated notification.
+func (c *Client) handleNotification(name, method string, params json.RawMessage) {
+	switch method {
+	case NotificationResourcesUpdated:
+		c.handleResourceUpdated(name, params)
+	case NotificationToolsListChanged:
+		c.handleToolListChanged(name)
+	case NotificationPromptsListChanged:
+		c.mu.RLock()
+		conn, ok := c.servers[name]
+		c.mu.RUnlock()
+		if ok {
+			conn.invalidatePrompts()
+		}
+	}
+}
+
 // handleToolListChanged re-fetches and re-registers tools when a server
 // sends a notifications/tools/list_changed notification.
 func (c *Client) handleToolListChanged(name string) {
@@ -459,6 +696,28 @@ func (c *Client) handleToolListChanged(name string) {
 	c.registerTools(name, tools)
 }
 
+// handleStreamReconnect re-initializes a server whose SSE stream was
+// re-established, drops its cached prompts, then refreshes its tools.
+// Registered tools stay in place throughout and are only replaced if the
+// refreshed list is fetched.
+func (c *Client) handleStreamReconnect(name string) {
+	c.mu.RLock()
+	conn, ok := c.servers[name]
+	c.mu.RUnlock()
+	if !ok {
+		return
+	}
+	conn.invalidatePrompts() // a new session may serve a different list
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := conn.reinitialize(ctx); err != nil {
+		return
+	}
+	c.resubscribe(ctx, name)
+	c.handleToolListChanged(name)
+}
+
 // registerTools registers MCP tools in the tool registry.
 func (c *Client) registerTools(serverName string, mcpTools []ToolInfo) {
 	for _, t := range mcpTools {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 
@@ -343,24 +344,26 @@ func TestSetServers_ConfigChange(t *testing.T) {
 		t.Fatal("expected tool_v1 to be registered")
 	}
 
-	// Call SetServers with changed URL — since we can't do a real reconnect,
-	// it will fail, but the config change should be detected
+	// Point srv1 at a real streamable HTTP server serving a new tool set
+	server := newStreamableTestServer(t, []ToolInfo{{Name: "tool_v2", Description: "Version 2"}})
 	result := client.SetServers(context.Background(), map[string]types.McpServerConfig{
-		"srv1": {Type: "http", URL: "http://new.example.com"},
+		"srv1": {Type: "http", URL: server.URL},
 	})
 
-	// The disconnect should succeed (mock transport), but Connect with real transport will fail
-	// What we're verifying: the config change was detected and disconnect was called
-	if _, ok := result.Errors["srv1"]; !ok {
-		// If no error, it somehow connected (unlikely without real server)
-		if len(result.Updated) == 0 {
-			t.Error("expected either Updated or Errors for changed config")
-		}
+	if msg, ok := result.Errors["srv1"]; ok {
+		t.Fatalf("reconnect with changed config failed: %s", msg)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != "srv1" {
+		t.Errorf("Updated = %v, want [srv1]", result.Updated)
 	}
-	// Old tool should be unregistered (disconnect was called)
+	// Old tool unregistered by the disconnect, new tool from the new server
 	if _, ok := registry.Get("mcp__srv1__tool_v1"); ok {
 		t.Error("tool_v1 should have been unregistered after config change")
 	}
+	if _, ok := registry.Get("mcp__srv1__tool_v2"); !ok {
+		t.Error("tool_v2 should be registered from the new server")
+	}
+	client.Close()
 }
 
 func TestConfigEqual(t *testing.T) {
@@ -394,6 +397,28 @@ func TestConfigEqual(t *testing.T) {
 	if configEqual(base, diffHeader) {
 		t.Error("expected different headers to not match")
 	}
+
+	sampling := same
+	sampling.Sampling = &types.McpSamplingConfig{Enabled: true, MaxTokens: 1000}
+	if configEqual(base, sampling) {
+		t.Error("expected enabling sampling to not match")
+	}
+	tighter := sampling
+	tighter.Sampling = &types.McpSamplingConfig{Enabled: true, MaxTokens: 500}
+	if configEqual(sampling, tighter) {
+		t.Error("expected different sampling budgets to not match")
+	}
+
+	oauth := same
+	oauth.Auth = &types.McpAuthConfig{Type: "oauth", Scopes: []string{"read"}}
+	if configEqual(base, oauth) {
+		t.Error("expected adding auth to not match")
+	}
+	wider := oauth
+	wider.Auth = &types.McpAuthConfig{Type: "oauth", Scopes: []string{"read", "write"}}
+	if configEqual(oauth, wider) {
+		t.Error("expected different auth scopes to not match")
+	}
 }
 
 func TestClient_Status(t *testing.T) {
@@ -715,3 +740,100 @@ func TestClient_ToolSchemaPassthrough(t *testing.T) {
 		t.Errorf("expected object schema, got %v", s["type"])
 	}
 }
+
+func TestClient_Prompts(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+
+	mock := newMockTransport().
+		withInitialize(ServerCapabilities{Prompts: &PromptsCapability{ListChanged: true}}).
+		withResponse(MethodPromptsList, json.RawMessage(`{"prompts":[{"name":"review","description":"Code review","arguments":[{"name":"file","required":true}]}]}`)).
+		withResponse(MethodPromptsGet, json.RawMessage(`{"description":"Review","messages":[{"role":"user","content":{"type":"text","text":"Review a.go"}}]}`))
+	connectWithMock(t, client, "srv1", mock)
+	connectWithMock(t, client, "noprompts", newMockTransport().withInitialize(ServerCapabilities{}))
+
+	prompts, err := client.ListPrompts(context.Background(), "")
+	if err != nil {
+		t.Fatalf("ListPrompts: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].ServerName != "srv1" || prompts[0].Name != "review" ||
+		len(prompts[0].Arguments) != 1 || !prompts[0].Arguments[0].Required {
+		t.Errorf("prompts = %+v", prompts)
+	}
+
+	result, err := client.GetPrompt(context.Background(), "srv1", "review", map[string]string{"file": "a.go"})
+	if err != nil {
+		t.Fatalf("GetPrompt: %v", err)
+	}
+	if len(result.Messages) != 1 || result.Messages[0].Role != "user" || result.Messages[0].Content.Text != "Review a.go" {
+		t.Errorf("result = %+v", result)
+	}
+
+	// Capability gating
+	if _, err := client.ListPrompts(context.Background(), "noprompts"); err == nil {
+		t.Error("expected error listing prompts on a server without the prompts capability")
+	}
+	if _, err := client.GetPrompt(context.Background(), "noprompts", "review", nil); err == nil {
+		t.Error("expected error getting a prompt from a server without the prompts capability")
+	}
+	if _, err := client.ListPrompts(context.Background(), "missing"); err == nil {
+		t.Error("expected error for unknown server")
+	}
+}
+
+func TestClient_ListPromptsPartial(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	connectWithMock(t, client, "good", newMockTransport().
+		withInitialize(ServerCapabilities{Prompts: &PromptsCapability{}}).
+		withResponse(MethodPromptsList, json.RawMessage(`{"prompts":[{"name":"review"}]}`)))
+	// Advertises prompts but fails to list them
+	connectWithMock(t, client, "broken", newMockTransport().
+		withInitialize(ServerCapabilities{Prompts: &PromptsCapability{}}))
+
+	prompts, err := client.ListPrompts(context.Background(), "")
+	if len(prompts) != 1 || prompts[0].ServerName != "good" {
+		t.Errorf("prompts = %+v, want the good server's prompt", prompts)
+	}
+	if err == nil || !strings.Contains(err.Error(), `server "broken"`) {
+		t.Errorf("err = %v, want an error naming the broken server", err)
+	}
+}
+
+func TestClient_PromptsListChangedInvalidatesCache(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	mock := newMockTransport().
+		withInitialize(ServerCapabilities{Prompts: &PromptsCapability{ListChanged: true}}).
+		withResponse(MethodPromptsList, json.RawMessage(`{"prompts":[{"name":"v1"}]}`))
+	connectWithMock(t, client, "srv1", mock)
+
+	list := func() string {
+		t.Helper()
+		prompts, err := client.ListPrompts(context.Background(), "srv1")
+		if err != nil || len(prompts) != 1 {
+			t.Fatalf("ListPrompts = %+v, %v", prompts, err)
+		}
+		return prompts[0].Name
+	}
+	if got := list(); got != "v1" {
+		t.Fatalf("prompt = %q, want v1", got)
+	}
+
+	mock.mu.Lock()
+	mock.responses[MethodPromptsList] = json.RawMessage(`{"prompts":[{"name":"v2"}]}`)
+	mock.mu.Unlock()
+	if got := list(); got != "v1" {
+		t.Errorf("prompt = %q, want cached v1 before list_changed", got)
+	}
+
+	client.handleNotification("srv1", NotificationPromptsListChanged, nil)
+	if got := list(); got != "v2" {
+		t.Errorf("prompt = %q, want v2 after list_changed", got)
+	}
+
+	mock.mu.Lock()
+	mock.responses[MethodPromptsList] = json.RawMessage(`{"prompts":[{"name":"v3"}]}`)
+	mock.mu.Unlock()
+	client.handleStreamReconnect("srv1")
+	if got := list(); got != "v3" {
+		t.Errorf("prompt = %q, want v3 after the stream reconnected", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
 
@@ -19,12 +20,17 @@ type ServerConnection struct {
 	Capabilities *ServerCapabilities
 	Tools        []ToolInfo
 	Resources    []Resource
+	Prompts      []Prompt // cached prompts/list result; nil until fetched
 	Enabled      bool
 	Transport    Transport
 	ErrorMsg     string
 
 	mu    sync.Mutex
 	nextID atomic.Int32
+
+	// Auth settings from the Client, used when creating sse/http transports
+	tokenStore   TokenStore
+	onAuthStatus func(*types.AuthStatusMessage)
 }
 
 // newServerConnection creates a new connection in pending state.
@@ -40,7 +46,7 @@ func newServerConnection(name string, config types.McpServerConfig) *ServerConne
 // connect creates the transport and runs the MCP initialization handshake.
 func (sc *ServerConnection) connect(ctx context.Context) error {
 	sc.mu.Lock()
-	transport, err := sc.createTransport()
+	transport, err := sc.createTransport(ctx)
 	if err != nil {
 		sc.Status = StatusFailed
 		sc.ErrorMsg = err.Error()
@@ -64,7 +70,7 @@ func (sc *ServerConnection) runHandshake(ctx context.Context) error {
 	// 1. Initialize handshake
 	initParams := InitializeParams{
 		ProtocolVersion: "2024-11-05",
-		Capabilities:    ClientCapabilities{},
+		Capabilities:    sc.clientCapabilities(),
 		ClientInfo:      ClientInfo{Name: "goat", Version: "0.1.0"},
 	}
 	resp, err := transport.Send(ctx, newRequest(sc.nextRequestID(), MethodInitialize, initParams))
@@ -133,6 +139,42 @@ func (sc *ServerConnection) runHandshake(ctx context.Context) error {
 	return nil
 }
 
+// reinitialize repeats the initialize handshake on the current transport
+// after it has re-established its session (e.g. an SSE stream reconnect).
+// Tools and resources are left as they are.
+func (sc *ServerConnection) reinitialize(ctx context.Context) error {
+	sc.mu.Lock()
+	transport := sc.Transport
+	sc.mu.Unlock()
+	if transport == nil {
+		return fmt.Errorf("not connected")
+	}
+
+	initParams := InitializeParams{
+		ProtocolVersion: "2024-11-05",
+		Capabilities:    sc.clientCapabilities(),
+		ClientInfo:      ClientInfo{Name: "goat", Version: "0.1.0"},
+	}
+	resp, err := transport.Send(ctx, newRequest(sc.nextRequestID(), MethodInitialize, initParams))
+	if err != nil {
+		return fmt.Errorf("initialize: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("initialize error: %s", resp.Error.Message)
+	}
+	return transport.Notify(ctx, MethodInitialized, nil)
+}
+
+// clientCapabilities returns the capabilities advertised to this server.
+// Sampling is only offered when the server's config enables it.
+func (sc *ServerConnection) clientCapabilities() ClientCapabilities {
+	var caps ClientCapabilities
+	if sc.Config.Sampling != nil && sc.Config.Sampling.Enabled {
+		caps.Sampling = &SamplingCapability{}
+	}
+	return caps
+}
+
 // disconnect closes the transport and resets state.
 func (sc *ServerConnection) disconnect() error {
 	sc.mu.Lock()
@@ -143,6 +185,7 @@ func (sc *ServerConnection) disconnect() error {
 		sc.Transport = nil
 		sc.Tools = nil
 		sc.Resources = nil
+		sc.Prompts = nil
 		sc.Info = nil
 		sc.Capabilities = nil
 		sc.Status = StatusPending
@@ -205,6 +248,118 @@ func (sc *ServerConnection) readResource(ctx context.Context, uri string) (*Reso
 	return &result, nil
 }
 
+// setResourceSubscription sends resources/subscribe or resources/unsubscribe
+// for uri. Servers must advertise resources.subscribe.
+func (sc *ServerConnection) setResourceSubscription(ctx context.Context, uri string, subscribe bool) error {
+	sc.mu.Lock()
+	transport, caps := sc.Transport, sc.Capabilities
+	sc.mu.Unlock()
+
+	if transport == nil {
+		return fmt.Errorf("not connected")
+	}
+	if caps == nil || caps.Resources == nil || !caps.Resources.Subscribe {
+		return fmt.Errorf("server %q does not support resource subscriptions", sc.Name)
+	}
+
+	method := MethodResourcesSubscribe
+	if !subscribe {
+		method = MethodResourcesUnsubscribe
+	}
+	resp, err := transport.Send(ctx, newRequest(sc.nextRequestID(), method, ResourceSubscribeParams{URI: uri}))
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	return nil
+}
+
+// listPrompts returns the server's prompts, fetching them on first use.
+// The cache is cleared by invalidatePrompts on prompts/list_changed.
+func (sc *ServerConnection) listPrompts(ctx context.Context) ([]Prompt, error) {
+	sc.mu.Lock()
+	transport, caps, cached := sc.Transport, sc.Capabilities, sc.Prompts
+	sc.mu.Unlock()
+
+	if transport == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	if caps == nil || caps.Prompts == nil {
+		return nil, fmt.Errorf("server %q does not support prompts", sc.Name)
+	}
+	if cached != nil {
+		return cached, nil
+	}
+
+	resp, err := transport.Send(ctx, newRequest(sc.nextRequestID(), MethodPromptsList, nil))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	var result PromptsListResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("parse prompts result: %w", err)
+	}
+	prompts := result.Prompts
+	if prompts == nil {
+		prompts = []Prompt{}
+	}
+	sc.mu.Lock()
+	sc.Prompts = prompts
+	sc.mu.Unlock()
+	return prompts, nil
+}
+
+// getPrompt renders a prompt template via the transport.
+func (sc *ServerConnection) getPrompt(ctx context.Context, name string, args map[string]string) (*PromptGetResult, error) {
+	sc.mu.Lock()
+	transport, caps := sc.Transport, sc.Capabilities
+	sc.mu.Unlock()
+
+	if transport == nil {
+		return nil, fmt.Errorf("not connected")
+	}
+	if caps == nil || caps.Prompts == nil {
+		return nil, fmt.Errorf("server %q does not support prompts", sc.Name)
+	}
+
+	resp, err := transport.Send(ctx, newRequest(sc.nextRequestID(), MethodPromptsGet, PromptGetParams{
+		Name:      name,
+		Arguments: args,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	var result PromptGetResult
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return nil, fmt.Errorf("parse prompt result: %w", err)
+	}
+	return &result, nil
+}
+
+// supportsPrompts reports whether the connected server advertised the prompts capability.
+func (sc *ServerConnection) supportsPrompts() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.Transport != nil && sc.Capabilities != nil && sc.Capabilities.Prompts != nil
+}
+
+// invalidatePrompts drops the cached prompt list.
+func (sc *ServerConnection) invalidatePrompts() {
+	sc.mu.Lock()
+	sc.Prompts = nil
+	sc.mu.Unlock()
+}
+
 func (sc *ServerConnection) listTools(ctx context.Context) ([]ToolInfo, error) {
 	resp, err := sc.Transport.Send(ctx, newRequest(sc.nextRequestID(), MethodToolsList, nil))
 	if err != nil {
@@ -237,21 +392,61 @@ func (sc *ServerConnection) listResources(ctx context.Context) ([]Resource, erro
 	return result.Resources, nil
 }
 
-func (sc *ServerConnection) createTransport() (Transport, error) {
-	switch sc.Config.Type {
+// createTransport builds the transport for the server's config. ${VAR}
+// references are expanded from the environment here, on every (re)connect,
+// so sc.Config keeps the templates and never holds the secrets.
+func (sc *ServerConnection) createTransport(ctx context.Context) (Transport, error) {
+	config, err := ExpandConfig(sc.Config, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	switch config.Type {
 	case TransportStdio, "":
-		if sc.Config.Command == "" {
+		if config.Command == "" {
 			return nil, fmt.Errorf("stdio transport requires a command")
 		}
-		return NewStdioTransport(sc.Config.Command, sc.Config.Args, sc.Config.Env)
-	case TransportHTTP, "sse":
-		if sc.Config.URL == "" {
+		return NewStdioTransport(config.Command, config.Args, config.Env)
+	case TransportHTTP:
+		if config.URL == "" {
 			return nil, fmt.Errorf("http transport requires a URL")
 		}
-		return NewHTTPTransport(sc.Config.URL, sc.Config.Headers), nil
+		tokens, err := sc.tokenSource(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		transport := NewHTTPTransport(config.URL, config.Headers)
+		transport.SetTokenSource(tokens)
+		return transport, nil
+	case TransportSSE:
+		if config.URL == "" {
+			return nil, fmt.Errorf("sse transport requires a URL")
+		}
+		tokens, err := sc.tokenSource(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		return NewSSETransportWithAuth(ctx, config.URL, config.Headers, tokens)
 	default:
-		return nil, fmt.Errorf("unsupported transport type: %q", sc.Config.Type)
+		return nil, fmt.Errorf("unsupported transport type: %q", config.Type)
+	}
+}
+
+// tokenSource builds the server's token source from its auth config and
+// obtains a first token, so that a required authorization runs (or fails)
+// during connect rather than on the first request.
+func (sc *ServerConnection) tokenSource(ctx context.Context, config types.McpServerConfig) (TokenSource, error) {
+	store := sc.tokenStore
+	if store == nil {
+		store = newMemoryTokenStore()
+	}
+	tokens, err := newTokenSource(sc.Name, config, store, sc.onAuthStatus)
+	if err != nil || tokens == nil {
+		return nil, err
+	}
+	if _, err := tokens.Token(ctx); err != nil {
+		return nil, err
 	}
+	return tokens, nil
 }
 
 func (sc *ServerConnection) nextRequestID() int {
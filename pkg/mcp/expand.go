@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// ExpandConfig returns config with ${VAR} references in its command, args,
+// env values, URL and header values replaced by values from lookup (usually
+// os.LookupEnv), so committed config files can reference secrets such as
+// ${GITHUB_TOKEN}. ${VAR:-default} falls back to default when VAR is unset
+// or empty; a plain ${VAR} that is unset is an error naming every missing
+// variable. Text outside ${...} is left alone. The input is not modified.
+func ExpandConfig(config types.McpServerConfig, lookup func(string) (string, bool)) (types.McpServerConfig, error) {
+	e := expander{lookup: lookup}
+	config.Command = e.expand(config.Command)
+	if config.Args != nil {
+		config.Args = slices.Clone(config.Args)
+		for i, arg := range config.Args {
+			config.Args[i] = e.expand(arg)
+		}
+	}
+	config.Env = e.expandMap(config.Env)
+	config.URL = e.expand(config.URL)
+	config.Headers = e.expandMap(config.Headers)
+
+	if len(e.missing) > 0 {
+		names := slices.Compact(slices.Sorted(slices.Values(e.missing)))
+		return config, fmt.Errorf("undefined environment variables: %s", strings.Join(names, ", "))
+	}
+	return config, nil
+}
+
+type expander struct {
+	lookup  func(string) (string, bool)
+	missing []string
+}
+
+func (e *expander) expandMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := maps.Clone(m)
+	for k, v := range out {
+		out[k] = e.expand(v)
+	}
+	return out
+}
+
+func (e *expander) expand(s string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			break // unterminated: keep as is
+		}
+		b.WriteString(s[:start])
+		b.WriteString(e.resolve(s[start+2 : start+end]))
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+func (e *expander) resolve(ref string) string {
+	name, def, hasDefault := strings.Cut(ref, ":-")
+	value, ok := e.lookup(name)
+	if hasDefault && value == "" {
+		return def
+	}
+	if !ok {
+		e.missing = append(e.missing, name)
+	}
+	return value
+}
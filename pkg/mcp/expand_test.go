@@ -0,0 +1,83 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestExpandConfig(t *testing.T) {
+	lookup := envLookup(map[string]string{"TOKEN": "s3cret", "DIR": "/work", "EMPTY": ""})
+	config := types.McpServerConfig{
+		Command: "${DIR}/bin/server",
+		Args:    []string{"--root", "${DIR}", "--level=${LEVEL:-info}", "$HOME", "${unterminated"},
+		Env:     map[string]string{"GITHUB_TOKEN": "${TOKEN}", "OPT": "${EMPTY:-fallback}"},
+		URL:     "https://example.com/${DIR}",
+		Headers: map[string]string{"Authorization": "Bearer ${TOKEN}"},
+	}
+
+	got, err := ExpandConfig(config, lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != "/work/bin/server" {
+		t.Errorf("Command = %q", got.Command)
+	}
+	wantArgs := []string{"--root", "/work", "--level=info", "$HOME", "${unterminated"}
+	if strings.Join(got.Args, " ") != strings.Join(wantArgs, " ") {
+		t.Errorf("Args = %q, want %q", got.Args, wantArgs)
+	}
+	if got.Env["GITHUB_TOKEN"] != "s3cret" || got.Env["OPT"] != "fallback" {
+		t.Errorf("Env = %v", got.Env)
+	}
+	if got.URL != "https://example.com//work" || got.Headers["Authorization"] != "Bearer s3cret" {
+		t.Errorf("URL = %q, Headers = %v", got.URL, got.Headers)
+	}
+	if config.Args[1] != "${DIR}" || config.Env["GITHUB_TOKEN"] != "${TOKEN}" || config.Headers["Authorization"] != "Bearer ${TOKEN}" {
+		t.Error("input config was modified")
+	}
+}
+
+func TestExpandConfig_Missing(t *testing.T) {
+	config := types.McpServerConfig{
+		Command: "server",
+		Args:    []string{"${B}", "${A}", "${B}", "${EMPTY}"},
+		Headers: map[string]string{"X-Key": "${OPTIONAL:-}"},
+	}
+	_, err := ExpandConfig(config, envLookup(map[string]string{"EMPTY": ""}))
+	if err == nil || err.Error() != "undefined environment variables: A, B" {
+		t.Errorf("err = %v, want A and B listed once each", err)
+	}
+}
+
+func TestConnect_MissingEnvFails(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	err := client.Connect(context.Background(), "srv1", types.McpServerConfig{
+		Command: "server",
+		Env:     map[string]string{"TOKEN": "${GOAT_TEST_MCP_UNSET}"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "GOAT_TEST_MCP_UNSET") {
+		t.Fatalf("err = %v, want the missing variable named", err)
+	}
+	s, _ := client.ServerStatus("srv1")
+	if s.Status != StatusFailed {
+		t.Errorf("status = %q, want failed", s.Status)
+	}
+
+	result := client.SetServers(context.Background(), map[string]types.McpServerConfig{
+		"srv2": {Command: "server", Args: []string{"${GOAT_TEST_MCP_UNSET}"}},
+	})
+	if !strings.Contains(result.Errors["srv2"], "GOAT_TEST_MCP_UNSET") {
+		t.Errorf("SetServers errors = %v", result.Errors)
+	}
+}
@@ -1,7 +1,6 @@
 package mcp
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,19 +9,23 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 )
 
 // HTTPTransport communicates with an MCP server via Streamable HTTP.
 // Each JSON-RPC request is sent as an HTTP POST; the response may be
-// immediate JSON or an SSE stream.
+// immediate JSON or an SSE stream. The Mcp-Session-Id assigned by the
+// server is sent on every later request and the session is deleted on Close.
 type HTTPTransport struct {
 	url       string
 	headers   map[string]string
 	client    *http.Client
 	sessionID string // Mcp-Session-Id from server
 	mu        sync.Mutex
+	tokens    TokenSource // bearer tokens for authenticated servers; nil if none
 
 	onNotification NotificationHandler
+	onRequest      RequestHandler
 	notifyMu       sync.RWMutex
 }
 
@@ -43,29 +46,9 @@ func (t *HTTPTransport) Send(ctx context.Context, req JSONRPCRequest) (JSONRPCRe
 		return JSONRPCResponse{}, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	resp, sessionID, err := t.post(ctx, body)
 	if err != nil {
-		return JSONRPCResponse{}, fmt.Errorf("create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Accept", "application/json, text/event-stream")
-
-	// Add custom headers
-	for k, v := range t.headers {
-		httpReq.Header.Set(k, v)
-	}
-
-	// Add session ID if we have one
-	t.mu.Lock()
-	if t.sessionID != "" {
-		httpReq.Header.Set("Mcp-Session-Id", t.sessionID)
-	}
-	t.mu.Unlock()
-
-	resp, err := t.client.Do(httpReq)
-	if err != nil {
-		return JSONRPCResponse{}, fmt.Errorf("http request: %w", err)
+		return JSONRPCResponse{}, err
 	}
 	defer resp.Body.Close()
 
@@ -76,6 +59,9 @@ func (t *HTTPTransport) Send(ctx context.Context, req JSONRPCRequest) (JSONRPCRe
 		t.mu.Unlock()
 	}
 
+	if err := t.checkSession(resp, sessionID); err != nil {
+		return JSONRPCResponse{}, err
+	}
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return JSONRPCResponse{}, fmt.Errorf("http %d: %s", resp.StatusCode, string(bodyBytes))
@@ -94,90 +80,119 @@ func (t *HTTPTransport) Send(ctx context.Context, req JSONRPCRequest) (JSONRPCRe
 	return rpcResp, nil
 }
 
-// parseSSEResponse reads an SSE stream and extracts the JSON-RPC response matching the request ID.
+// parseSSEResponse reads an SSE stream and extracts the JSON-RPC response
+// matching the request ID. Notifications sent on the stream before the
+// response are forwarded to the notification handler, and server-initiated
+// requests are answered with a separate POST.
 func (t *HTTPTransport) parseSSEResponse(ctx context.Context, body io.Reader, reqID *int) (JSONRPCResponse, error) {
-	scanner := bufio.NewScanner(body)
-	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
-
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			return JSONRPCResponse{}, ctx.Err()
-		default:
+	var resp JSONRPCResponse
+	found := false
+	err := scanSSE(body, func(_, data string) bool {
+		if ctx.Err() != nil {
+			return false
 		}
 
-		line := scanner.Text()
-
-		// Skip SSE comments and empty lines
-		if line == "" || strings.HasPrefix(line, ":") {
-			continue
+		var msg struct {
+			ID     *int            `json:"id,omitempty"`
+			Method string          `json:"method,omitempty"`
+			Params json.RawMessage `json:"params,omitempty"`
 		}
-
-		if !strings.HasPrefix(line, "data: ") {
-			continue
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return true // skip unparseable
+		}
+		if msg.Method != "" {
+			t.notifyMu.RLock()
+			onNotification, onRequest := t.onNotification, t.onRequest
+			t.notifyMu.RUnlock()
+			if msg.ID != nil {
+				// The answer outlives this Send if the server responds first
+				go t.respond(context.WithoutCancel(ctx), onRequest, *msg.ID, msg.Method, msg.Params)
+			} else if onNotification != nil {
+				go onNotification(msg.Method, msg.Params)
+			}
+			return true
 		}
 
-		data := strings.TrimPrefix(line, "data: ")
-
-		var resp JSONRPCResponse
 		if err := json.Unmarshal([]byte(data), &resp); err != nil {
-			continue // skip unparseable
+			return true
 		}
+		// Match by ID if we have one; otherwise take the first response
+		found = reqID == nil || resp.ID == *reqID
+		return !found
+	})
 
-		// Match by ID if we have one
-		if reqID != nil && resp.ID == *reqID {
-			return resp, nil
-		}
-		// If no ID matching needed or first valid response
-		if reqID == nil {
-			return resp, nil
-		}
+	if found {
+		return resp, nil
 	}
-
-	if err := scanner.Err(); err != nil {
+	if ctx.Err() != nil {
+		return JSONRPCResponse{}, ctx.Err()
+	}
+	if err != nil {
 		return JSONRPCResponse{}, fmt.Errorf("sse stream: %w", err)
 	}
-
 	return JSONRPCResponse{}, fmt.Errorf("sse stream ended without matching response")
 }
 
 // Notify sends a JSON-RPC notification via HTTP POST (no response body expected).
 func (t *HTTPTransport) Notify(ctx context.Context, method string, params any) error {
-	n := newNotification(method, params)
-	body, err := json.Marshal(n)
+	body, err := json.Marshal(newNotification(method, params))
 	if err != nil {
 		return fmt.Errorf("marshal notification: %w", err)
 	}
+	return t.postAccepted(ctx, body, "notification")
+}
 
-	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+// respond answers a server-initiated request by POSTing the response.
+func (t *HTTPTransport) respond(ctx context.Context, handler RequestHandler, id int, method string, params json.RawMessage) {
+	body, err := json.Marshal(answerRequest(ctx, handler, id, method, params))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return
 	}
+	t.postAccepted(ctx, body, "response")
+}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	for k, v := range t.headers {
-		httpReq.Header.Set(k, v)
-	}
-
-	t.mu.Lock()
-	if t.sessionID != "" {
-		httpReq.Header.Set("Mcp-Session-Id", t.sessionID)
-	}
-	t.mu.Unlock()
-
-	resp, err := t.client.Do(httpReq)
+// postAccepted POSTs a message the server only acknowledges (a notification
+// or a response to a server-initiated request).
+func (t *HTTPTransport) postAccepted(ctx context.Context, body []byte, kind string) error {
+	resp, sessionID, err := t.post(ctx, body)
 	if err != nil {
-		return fmt.Errorf("http request: %w", err)
+		return err
 	}
 	resp.Body.Close()
 
+	if err := t.checkSession(resp, sessionID); err != nil {
+		return err
+	}
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("http %d for notification", resp.StatusCode)
+		return fmt.Errorf("http %d for %s", resp.StatusCode, kind)
 	}
 
 	return nil
 }
 
+// post sends body to the server with the standard and auth headers,
+// returning the response and the session ID that was sent.
+func (t *HTTPTransport) post(ctx context.Context, body []byte) (*http.Response, string, error) {
+	var sessionID string
+	resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		sessionID = t.setHeaders(req)
+		return req, nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("http request: %w", err)
+	}
+	return resp, sessionID, nil
+}
+
+// SetTokenSource sets the source of bearer tokens sent with each request.
+func (t *HTTPTransport) SetTokenSource(tokens TokenSource) {
+	t.tokens = tokens
+}
+
 // SetNotificationHandler registers a handler for server-initiated notifications.
 func (t *HTTPTransport) SetNotificationHandler(handler NotificationHandler) {
 	t.notifyMu.Lock()
@@ -185,7 +200,78 @@ func (t *HTTPTransport) SetNotificationHandler(handler NotificationHandler) {
 	t.onNotification = handler
 }
 
-// Close is a no-op for HTTP transport (stateless per-request).
+// SetRequestHandler registers a handler for server-initiated requests.
+func (t *HTTPTransport) SetRequestHandler(handler RequestHandler) {
+	t.notifyMu.Lock()
+	defer t.notifyMu.Unlock()
+	t.onRequest = handler
+}
+
+// setHeaders applies the standard, custom and session headers to req and
+// returns the session ID that was sent.
+func (t *HTTPTransport) setHeaders(req *http.Request) string {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+
+	// Add custom headers
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	// Add session ID if we have one
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	return sessionID
+}
+
+// checkSession detects an expired session: the server answers 404 to a
+// request carrying a session ID it no longer knows. The ID is dropped and a
+// transport error returned so the client reconnects with a new session.
+func (t *HTTPTransport) checkSession(resp *http.Response, sentID string) error {
+	if resp.StatusCode != http.StatusNotFound || sentID == "" {
+		return nil
+	}
+	t.mu.Lock()
+	if t.sessionID == sentID {
+		t.sessionID = ""
+	}
+	t.mu.Unlock()
+	return fmt.Errorf("connection lost: mcp session %s expired", sentID)
+}
+
+// Close terminates the server session, if one was established, with an HTTP
+// DELETE. Failures are ignored: servers that do not support explicit
+// termination answer 405, and an unreachable server has no session to keep.
 func (t *HTTPTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.sessionID = ""
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Best-effort: the server may already be gone
+	resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range t.headers {
+			req.Header.Set(k, v)
+		}
+		req.Header.Set("Mcp-Session-Id", sessionID)
+		return req, nil
+	})
+	if err == nil {
+		resp.Body.Close()
+	}
 	return nil
 }
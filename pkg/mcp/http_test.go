@@ -6,8 +6,13 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
 )
 
 func TestHTTPTransport_JSONResponse(t *testing.T) {
@@ -302,3 +307,180 @@ func TestHTTPTransport_SSEMultipleEvents(t *testing.T) {
 		t.Error("expected matched=true")
 	}
 }
+
+// streamableTestServer is a mock MCP server for the streamable HTTP
+// transport. It assigns a session on initialize and rejects later requests
+// without it, answers tools/call as an SSE stream (preceded by a
+// notification) and everything else as JSON, and terminates sessions on DELETE.
+type streamableTestServer struct {
+	*httptest.Server
+	tools []ToolInfo
+
+	mu       sync.Mutex
+	sessions map[string]bool
+	nextID   int
+	deleted  []string
+}
+
+func newStreamableTestServer(t *testing.T, tools []ToolInfo) *streamableTestServer {
+	s := &streamableTestServer{tools: tools, sessions: make(map[string]bool)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *streamableTestServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	sid := r.Header.Get("Mcp-Session-Id")
+	s.mu.Lock()
+	known := s.sessions[sid]
+	s.mu.Unlock()
+
+	if r.Method == http.MethodDelete {
+		s.mu.Lock()
+		delete(s.sessions, sid)
+		s.deleted = append(s.deleted, sid)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if accept := r.Header.Get("Accept"); !strings.Contains(accept, "application/json") || !strings.Contains(accept, "text/event-stream") {
+		http.Error(w, "bad accept header: "+accept, http.StatusNotAcceptable)
+		return
+	}
+
+	var req JSONRPCRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Method != MethodInitialize {
+		switch {
+		case sid == "":
+			http.Error(w, "missing session", http.StatusBadRequest)
+			return
+		case !known:
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+	}
+	if req.ID == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var result any
+	switch req.Method {
+	case MethodInitialize:
+		s.mu.Lock()
+		s.nextID++
+		sid = fmt.Sprintf("sess-%d", s.nextID)
+		s.sessions[sid] = true
+		s.mu.Unlock()
+		w.Header().Set("Mcp-Session-Id", sid)
+		result = InitializeResult{
+			ProtocolVersion: "2024-11-05",
+			Capabilities:    ServerCapabilities{Tools: &ToolsCapability{}},
+			ServerInfo:      ServerInfo{Name: "streamable", Version: "1.0"},
+		}
+	case MethodToolsList:
+		result = ToolsListResult{Tools: s.tools}
+	case MethodToolsCall:
+		data, _ := json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: *req.ID,
+			Result: json.RawMessage(`{"content":[{"type":"text","text":"streamed"}]}`)})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/message\",\"params\":{\"level\":\"info\"}}\n\n")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		return
+	default:
+		result = map[string]any{}
+	}
+	data, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(JSONRPCResponse{JSONRPC: "2.0", ID: *req.ID, Result: data})
+}
+
+func (s *streamableTestServer) expireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+func TestHTTPTransport_StreamableConformance(t *testing.T) {
+	server := newStreamableTestServer(t, []ToolInfo{{Name: "search", Description: "Search"}})
+
+	registry := tools.NewRegistry()
+	client := NewClient(registry)
+	if err := client.Connect(context.Background(), "remote", types.McpServerConfig{Type: "http", URL: server.URL}); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, ok := registry.Get("mcp__remote__search"); !ok {
+		t.Fatal("expected mcp__remote__search to be registered")
+	}
+
+	result, err := client.CallTool(context.Background(), "remote", "search", map[string]any{"q": "x"})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != "streamed" {
+		t.Errorf("CallTool result = %+v", result)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	server.mu.Lock()
+	deleted := server.deleted
+	server.mu.Unlock()
+	if len(deleted) != 1 || deleted[0] != "sess-1" {
+		t.Errorf("deleted sessions = %v, want [sess-1]", deleted)
+	}
+}
+
+func TestHTTPTransport_SSENotificationForwarded(t *testing.T) {
+	server := newStreamableTestServer(t, nil)
+	transport := NewHTTPTransport(server.URL, nil)
+	defer transport.Close()
+
+	got := make(chan string, 1)
+	transport.SetNotificationHandler(func(method string, _ json.RawMessage) { got <- method })
+
+	ctx := context.Background()
+	if _, err := transport.Send(ctx, newRequest(1, MethodInitialize, nil)); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.Send(ctx, newRequest(2, MethodToolsCall, nil))
+	if err != nil || resp.ID != 2 {
+		t.Fatalf("Send = %+v, %v", resp, err)
+	}
+	select {
+	case method := <-got:
+		if method != "notifications/message" {
+			t.Errorf("method = %q", method)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification on SSE response was not forwarded")
+	}
+}
+
+func TestHTTPTransport_SessionExpired(t *testing.T) {
+	server := newStreamableTestServer(t, nil)
+	transport := NewHTTPTransport(server.URL, nil)
+
+	ctx := context.Background()
+	if _, err := transport.Send(ctx, newRequest(1, MethodInitialize, nil)); err != nil {
+		t.Fatal(err)
+	}
+	server.expireSessions()
+
+	_, err := transport.Send(ctx, newRequest(2, MethodToolsList, nil))
+	if err == nil || !isTransportError(err) {
+		t.Fatalf("err = %v, want a transport error so the client reconnects", err)
+	}
+	transport.mu.Lock()
+	sid := transport.sessionID
+	transport.mu.Unlock()
+	if sid != "" {
+		t.Errorf("sessionID = %q, want it cleared after expiry", sid)
+	}
+}
@@ -0,0 +1,580 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// Auth types for McpAuthConfig.Type.
+const (
+	AuthTypeOAuth  = "oauth"
+	AuthTypeBearer = "bearer"
+)
+
+const (
+	// oauthRefreshMargin is how long before expiry a token is refreshed.
+	oauthRefreshMargin = 60 * time.Second
+	// oauthCallbackTimeout bounds the wait for the user to authorize in the browser.
+	oauthCallbackTimeout = 5 * time.Minute
+)
+
+// TokenSource supplies bearer tokens to the HTTP and SSE transports.
+type TokenSource interface {
+	// Token returns a valid access token, refreshing or authorizing as needed.
+	Token(ctx context.Context) (string, error)
+	// Invalidate discards the current access token after the server rejected it.
+	Invalidate()
+}
+
+// OAuthToken is a cached OAuth credential for one server.
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry"`              // zero if the server gave no expires_in
+	ClientID     string    `json:"client_id,omitempty"` // client the token was issued to
+	ClientSecret string    `json:"client_secret,omitempty"`
+}
+
+// valid reports whether the access token can be used without refreshing.
+func (t *OAuthToken) valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Until(t.Expiry) > oauthRefreshMargin)
+}
+
+// TokenStore caches OAuth tokens across connections, keyed by server name.
+type TokenStore interface {
+	// Load returns the stored token, or nil if there is none.
+	Load(server string) (*OAuthToken, error)
+	Save(server string, token *OAuthToken) error
+}
+
+// memoryTokenStore keeps tokens for the lifetime of the Client.
+type memoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*OAuthToken
+}
+
+func newMemoryTokenStore() *memoryTokenStore {
+	return &memoryTokenStore{tokens: make(map[string]*OAuthToken)}
+}
+
+func (s *memoryTokenStore) Load(server string) (*OAuthToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok, ok := s.tokens[server]; ok {
+		c := *tok
+		return &c, nil
+	}
+	return nil, nil
+}
+
+func (s *memoryTokenStore) Save(server string, token *OAuthToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := *token
+	s.tokens[server] = &c
+	return nil
+}
+
+// FileTokenStore persists tokens as one JSON file per server in Dir,
+// readable only by the current user.
+type FileTokenStore struct {
+	Dir string
+}
+
+func (s *FileTokenStore) path(server string) string {
+	return filepath.Join(s.Dir, url.PathEscape(server)+".json")
+}
+
+// Load reads the token file for server.
+func (s *FileTokenStore) Load(server string) (*OAuthToken, error) {
+	data, err := os.ReadFile(s.path(server))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var tok OAuthToken
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return nil, fmt.Errorf("parse token file: %w", err)
+	}
+	return &tok, nil
+}
+
+// Save writes the token file for server.
+func (s *FileTokenStore) Save(server string, token *OAuthToken) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(token, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(server), data, 0o600)
+}
+
+// staticTokenSource serves a pre-supplied bearer token.
+type staticTokenSource string
+
+func (s staticTokenSource) Token(context.Context) (string, error) { return string(s), nil }
+func (s staticTokenSource) Invalidate()                           {}
+
+// newTokenSource returns the token source for a server's auth config, or nil
+// if the server needs no authentication.
+func newTokenSource(server string, config types.McpServerConfig, store TokenStore, onStatus func(*types.AuthStatusMessage)) (TokenSource, error) {
+	auth := config.Auth
+	if auth == nil {
+		return nil, nil
+	}
+	if auth.TokenEnv != "" {
+		if token := os.Getenv(auth.TokenEnv); token != "" {
+			return staticTokenSource(token), nil
+		}
+	}
+
+	switch auth.Type {
+	case AuthTypeBearer:
+		if auth.TokenEnv == "" {
+			return nil, fmt.Errorf("bearer auth requires tokenEnv")
+		}
+		return nil, fmt.Errorf("bearer token environment variable %s is not set", auth.TokenEnv)
+	case AuthTypeOAuth:
+		return &oauthProvider{
+			server:    server,
+			serverURL: config.URL,
+			config:    *auth,
+			store:     store,
+			onStatus:  onStatus,
+			client:    &http.Client{Timeout: 30 * time.Second},
+			authMu:    make(chan struct{}, 1),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported auth type: %q", auth.Type)
+	}
+}
+
+// oauthProvider implements the MCP authorization flow for one server:
+// metadata discovery, dynamic client registration, the authorization code
+// grant with PKCE via a loopback callback, and refresh before expiry.
+type oauthProvider struct {
+	server    string
+	serverURL string
+	config    types.McpAuthConfig
+	store     TokenStore
+	onStatus  func(*types.AuthStatusMessage)
+	client    *http.Client
+
+	// authMu is a one-slot semaphore held while refreshing or authorizing,
+	// so concurrent callers share one browser flow; it also guards meta.
+	// mu guards loaded and token only and is never held across a request.
+	authMu chan struct{}
+	meta   *authServerMetadata
+
+	mu     sync.Mutex
+	loaded bool
+	token  *OAuthToken
+}
+
+// protectedResourceMetadata is the RFC 9728 document served by the MCP server.
+type protectedResourceMetadata struct {
+	AuthorizationServers []string `json:"authorization_servers"`
+}
+
+// authServerMetadata is the RFC 8414 authorization server metadata.
+type authServerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	RegistrationEndpoint  string `json:"registration_endpoint,omitempty"`
+}
+
+// tokenResponse is a token endpoint response (RFC 6749 §5).
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token,omitempty"`
+	ExpiresIn        int    `json:"expires_in,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// Token returns a valid access token: the cached one, a refreshed one, or
+// one obtained through interactive authorization.
+func (p *oauthProvider) Token(ctx context.Context) (string, error) {
+	if tok := p.current(); tok.valid() {
+		return tok.AccessToken, nil
+	}
+
+	select {
+	case p.authMu <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-p.authMu }()
+
+	tok := p.current()
+	if tok.valid() {
+		return tok.AccessToken, nil // another caller authorized while we waited
+	}
+	if tok != nil && tok.RefreshToken != "" {
+		if refreshed, err := p.refresh(ctx, tok); err == nil {
+			return refreshed.AccessToken, nil
+		}
+		// Refresh token expired or revoked; fall back to authorizing again
+	}
+	tok, err := p.authorize(ctx, tok)
+	if err != nil {
+		return "", err
+	}
+	return tok.AccessToken, nil
+}
+
+// current returns the cached token, loading it from the store the first time.
+func (p *oauthProvider) current() *OAuthToken {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.loaded {
+		p.loaded = true
+		if tok, err := p.store.Load(p.server); err == nil {
+			p.token = tok
+		}
+	}
+	return p.token
+}
+
+// Invalidate discards the access token, keeping the refresh token.
+func (p *oauthProvider) Invalidate() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != nil {
+		tok := *p.token // callers may still hold the old token
+		tok.AccessToken = ""
+		p.token = &tok
+	}
+}
+
+func (p *oauthProvider) status(authenticating bool, errMsg string, output ...string) {
+	if p.onStatus != nil {
+		p.onStatus(types.NewAuthStatus(authenticating, output, errMsg, ""))
+	}
+}
+
+// discover fetches the authorization server metadata, following the MCP
+// server's protected resource metadata when it has any. Servers without
+// metadata get the default endpoints on their origin.
+func (p *oauthProvider) discover(ctx context.Context) (*authServerMetadata, error) {
+	if p.meta != nil {
+		return p.meta, nil
+	}
+	resource, err := url.Parse(p.serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse server url: %w", err)
+	}
+	issuer := resource.Scheme + "://" + resource.Host
+
+	var prm protectedResourceMetadata
+	if p.getJSON(ctx, issuer+"/.well-known/oauth-protected-resource", &prm) == nil && len(prm.AuthorizationServers) > 0 {
+		issuer = strings.TrimSuffix(prm.AuthorizationServers[0], "/")
+	}
+
+	issuerURL, err := url.Parse(issuer)
+	if err != nil {
+		return nil, fmt.Errorf("parse authorization server url: %w", err)
+	}
+	// RFC 8414 inserts the well-known path between the host and the issuer's path
+	metaURL := issuerURL.Scheme + "://" + issuerURL.Host + "/.well-known/oauth-authorization-server" + issuerURL.Path
+
+	var meta authServerMetadata
+	if err := p.getJSON(ctx, metaURL, &meta); err != nil || meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" {
+		meta = authServerMetadata{
+			Issuer:                issuer,
+			AuthorizationEndpoint: issuer + "/authorize",
+			TokenEndpoint:         issuer + "/token",
+			RegistrationEndpoint:  issuer + "/register",
+		}
+	}
+	p.meta = &meta
+	return p.meta, nil
+}
+
+func (p *oauthProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("http %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// clientCredentials returns the configured client, or registers one
+// dynamically (RFC 7591) when none is configured or cached.
+func (p *oauthProvider) clientCredentials(ctx context.Context, meta *authServerMetadata, prev *OAuthToken) (id, secret string, err error) {
+	if p.config.ClientID != "" {
+		return p.config.ClientID, p.config.ClientSecret, nil
+	}
+	if prev != nil && prev.ClientID != "" {
+		return prev.ClientID, prev.ClientSecret, nil
+	}
+	if meta.RegistrationEndpoint == "" {
+		return "", "", fmt.Errorf("authorization server does not support dynamic client registration; set auth.clientId")
+	}
+
+	body, _ := json.Marshal(map[string]any{
+		"client_name":                "goat",
+		"redirect_uris":              []string{p.config.RedirectURI},
+		"grant_types":                []string{"authorization_code", "refresh_token"},
+		"response_types":             []string{"code"},
+		"token_endpoint_auth_method": "none",
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.RegistrationEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("client registration: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", "", fmt.Errorf("client registration: http %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	var reg struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret,omitempty"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reg); err != nil || reg.ClientID == "" {
+		return "", "", fmt.Errorf("client registration: missing client_id")
+	}
+	return reg.ClientID, reg.ClientSecret, nil
+}
+
+// authorize runs the authorization code flow with PKCE. A loopback server on
+// the configured redirect URI receives the code once the user approves.
+func (p *oauthProvider) authorize(ctx context.Context, prev *OAuthToken) (*OAuthToken, error) {
+	if p.config.RedirectURI == "" {
+		return nil, fmt.Errorf("mcp server %q requires interactive OAuth authorization but no callback server is configured (set auth.redirectUri, or supply a token with auth.tokenEnv)", p.server)
+	}
+	redirect, err := url.Parse(p.config.RedirectURI)
+	if err != nil {
+		return nil, fmt.Errorf("parse redirect uri: %w", err)
+	}
+
+	p.status(true, "", fmt.Sprintf("Authorizing MCP server %q", p.server))
+	tok, err := p.runAuthorization(ctx, redirect, prev)
+	if err != nil {
+		p.status(false, err.Error())
+		return nil, fmt.Errorf("oauth authorization for %q: %w", p.server, err)
+	}
+	p.status(false, "", fmt.Sprintf("Authorized MCP server %q", p.server))
+	return tok, nil
+}
+
+func (p *oauthProvider) runAuthorization(ctx context.Context, redirect *url.URL, prev *OAuthToken) (*OAuthToken, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientID, clientSecret, err := p.clientCredentials(ctx, meta, prev)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := randomString()
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomString()
+
+	ln, err := net.Listen("tcp", redirect.Host)
+	if err != nil {
+		return nil, fmt.Errorf("start callback server: %w", err)
+	}
+	callbackPath := redirect.Path
+	if callbackPath == "" {
+		callbackPath = "/"
+	}
+	results := make(chan url.Values, 1)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != callbackPath {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintln(w, "Authorization complete. You can close this window.")
+		select {
+		case results <- r.URL.Query():
+		default:
+		}
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {clientID},
+		"redirect_uri":          {p.config.RedirectURI},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+		"resource":              {p.serverURL},
+	}
+	if len(p.config.Scopes) > 0 {
+		query.Set("scope", strings.Join(p.config.Scopes, " "))
+	}
+	authURL := meta.AuthorizationEndpoint + "?" + query.Encode()
+	p.status(true, "", fmt.Sprintf("Open this URL to authorize MCP server %q:", p.server), authURL)
+
+	waitCtx, cancel := context.WithTimeout(ctx, oauthCallbackTimeout)
+	defer cancel()
+	var callback url.Values
+	select {
+	case callback = <-results:
+	case <-waitCtx.Done():
+		return nil, fmt.Errorf("waiting for authorization callback: %w", waitCtx.Err())
+	}
+	if e := callback.Get("error"); e != "" {
+		return nil, fmt.Errorf("authorization denied: %s %s", e, callback.Get("error_description"))
+	}
+	if callback.Get("state") != state {
+		return nil, fmt.Errorf("authorization callback state mismatch")
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {callback.Get("code")},
+		"redirect_uri":  {p.config.RedirectURI},
+		"client_id":     {clientID},
+		"code_verifier": {verifier},
+		"resource":      {p.serverURL},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	return p.requestToken(ctx, meta, form, clientID, clientSecret, prev)
+}
+
+// refresh exchanges prev's refresh token for a new access token.
+func (p *oauthProvider) refresh(ctx context.Context, prev *OAuthToken) (*OAuthToken, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientID, clientSecret := prev.ClientID, prev.ClientSecret
+	if p.config.ClientID != "" {
+		clientID, clientSecret = p.config.ClientID, p.config.ClientSecret
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {prev.RefreshToken},
+		"client_id":     {clientID},
+		"resource":      {p.serverURL},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	return p.requestToken(ctx, meta, form, clientID, clientSecret, prev)
+}
+
+// requestToken calls the token endpoint and caches the resulting token.
+func (p *oauthProvider) requestToken(ctx context.Context, meta *authServerMetadata, form url.Values, clientID, clientSecret string, prev *OAuthToken) (*OAuthToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tr tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return nil, fmt.Errorf("token request: http %d: decode response: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || tr.Error != "" {
+		return nil, fmt.Errorf("token request: http %d: %s %s", resp.StatusCode, tr.Error, tr.ErrorDescription)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("token request: response has no access_token")
+	}
+
+	tok := &OAuthToken{
+		AccessToken:  tr.AccessToken,
+		RefreshToken: tr.RefreshToken,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+	if tok.RefreshToken == "" && prev != nil {
+		tok.RefreshToken = prev.RefreshToken // not rotated
+	}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	p.mu.Lock()
+	p.token = tok
+	p.mu.Unlock()
+	if err := p.store.Save(p.server, tok); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+// randomString returns 32 random bytes, base64url-encoded (a PKCE verifier or state).
+func randomString() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// doWithToken sends the request built by newReq with a bearer token from
+// tokens, if any. If the server rejects the token with 401 it is invalidated
+// and the request retried once with a fresh one.
+func doWithToken(ctx context.Context, client *http.Client, tokens TokenSource, newReq func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		if tokens != nil {
+			token, err := tokens.Token(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("authenticate: %w", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusUnauthorized && tokens != nil && attempt == 0 {
+			resp.Body.Close()
+			tokens.Invalidate()
+			continue
+		}
+		return resp, nil
+	}
+}
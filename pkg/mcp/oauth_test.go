@@ -0,0 +1,350 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// oauthTestServer is an MCP server at /mcp that requires bearer tokens,
+// together with the authorization server that issues them: metadata
+// discovery, dynamic registration, an /authorize endpoint that approves
+// immediately by redirecting back with a code, and a PKCE-checking /token.
+type oauthTestServer struct {
+	*httptest.Server
+	mcp *streamableTestServer
+
+	mu         sync.Mutex
+	access     map[string]bool   // valid access tokens
+	refresh    map[string]bool   // valid refresh tokens
+	challenges map[string]string // code → PKCE challenge
+	grants     []string          // grant types seen by /token
+	n          int
+}
+
+func newOAuthTestServer(t *testing.T) *oauthTestServer {
+	s := &oauthTestServer{
+		mcp:        &streamableTestServer{tools: []ToolInfo{{Name: "search"}}, sessions: make(map[string]bool)},
+		access:     make(map[string]bool),
+		refresh:    make(map[string]bool),
+		challenges: make(map[string]string),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/oauth-protected-resource", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"authorization_servers": []string{s.URL}})
+	})
+	mux.HandleFunc("/.well-known/oauth-authorization-server", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(authServerMetadata{
+			Issuer:                s.URL,
+			AuthorizationEndpoint: s.URL + "/authorize",
+			TokenEndpoint:         s.URL + "/token",
+			RegistrationEndpoint:  s.URL + "/register",
+		})
+	})
+	mux.HandleFunc("POST /register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"client_id": "dyn-client"})
+	})
+	mux.HandleFunc("GET /authorize", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("client_id") != "dyn-client" || q.Get("code_challenge_method") != "S256" {
+			http.Error(w, "bad authorization request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.n++
+		code := fmt.Sprintf("code-%d", s.n)
+		s.challenges[code] = q.Get("code_challenge")
+		s.mu.Unlock()
+		http.Redirect(w, r, q.Get("redirect_uri")+"?code="+code+"&state="+url.QueryEscape(q.Get("state")), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", s.serveToken)
+	mux.HandleFunc("/mcp", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		ok := s.access[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		s.mu.Unlock()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer resource_metadata="`+s.URL+`/.well-known/oauth-protected-resource"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		s.mcp.serveHTTP(w, r)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *oauthTestServer) serveToken(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.grants = append(s.grants, r.Form.Get("grant_type"))
+
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		challenge, ok := s.challenges[r.Form.Get("code")]
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_grant", ErrorDescription: "bad code or verifier"})
+			return
+		}
+		delete(s.challenges, r.Form.Get("code"))
+	case "refresh_token":
+		if !s.refresh[r.Form.Get("refresh_token")] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(tokenResponse{Error: "invalid_grant"})
+			return
+		}
+	}
+	s.n++
+	access, refresh := fmt.Sprintf("access-%d", s.n), fmt.Sprintf("refresh-%d", s.n)
+	s.access[access], s.refresh[refresh] = true, true
+	json.NewEncoder(w).Encode(tokenResponse{AccessToken: access, RefreshToken: refresh, ExpiresIn: 3600})
+}
+
+// freeRedirectURI returns a loopback callback URI on an unused port.
+func freeRedirectURI(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return "http://" + ln.Addr().String() + "/callback"
+}
+
+func TestOAuth_AuthorizationCodeFlow(t *testing.T) {
+	server := newOAuthTestServer(t)
+	client := NewClient(tools.NewRegistry())
+	defer client.Close()
+
+	// Play the user: open the authorization URL once it is announced
+	var mu sync.Mutex
+	var statuses []*types.AuthStatusMessage
+	client.SetAuthStatusHandler(func(msg *types.AuthStatusMessage) {
+		mu.Lock()
+		statuses = append(statuses, msg)
+		mu.Unlock()
+		for _, line := range msg.Output {
+			if strings.HasPrefix(line, server.URL+"/authorize") {
+				go func() {
+					if resp, err := http.Get(line); err == nil {
+						resp.Body.Close()
+					}
+				}()
+			}
+		}
+	})
+
+	config := types.McpServerConfig{
+		Type: "http",
+		URL:  server.URL + "/mcp",
+		Auth: &types.McpAuthConfig{Type: "oauth", RedirectURI: freeRedirectURI(t), Scopes: []string{"tools"}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Connect(ctx, "hosted", config); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, ok := client.registry.Get("mcp__hosted__search"); !ok {
+		t.Error("expected tools from the authenticated server to be registered")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(statuses) < 3 || !statuses[0].IsAuthenticating {
+		t.Fatalf("statuses = %+v, want authenticating updates", statuses)
+	}
+	if last := statuses[len(statuses)-1]; last.IsAuthenticating || last.Error != "" || last.Type != types.MessageTypeAuthStatus {
+		t.Errorf("final status = %+v, want completed without error", last)
+	}
+
+	tok, _ := client.tokenStore.Load("hosted")
+	if tok == nil || tok.AccessToken == "" || tok.RefreshToken == "" || tok.ClientID != "dyn-client" {
+		t.Errorf("cached token = %+v", tok)
+	}
+	if tok != nil && time.Until(tok.Expiry) < 59*time.Minute {
+		t.Errorf("expiry = %v, want about an hour from now", tok.Expiry)
+	}
+}
+
+func TestOAuth_AuthorizationDoesNotHoldLock(t *testing.T) {
+	server := newOAuthTestServer(t)
+	config := types.McpServerConfig{
+		Type: "http",
+		URL:  server.URL + "/mcp",
+		Auth: &types.McpAuthConfig{Type: "oauth", RedirectURI: freeRedirectURI(t)},
+	}
+
+	var source TokenSource
+	waited := make(chan error, 1)
+	onStatus := func(msg *types.AuthStatusMessage) {
+		for _, line := range msg.Output {
+			if !strings.HasPrefix(line, server.URL+"/authorize") {
+				continue
+			}
+			// While the user is in the browser, other callers must not block
+			source.Invalidate()
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			_, err := source.Token(ctx)
+			cancel()
+			waited <- err
+			go func() {
+				if resp, err := http.Get(line); err == nil {
+					resp.Body.Close()
+				}
+			}()
+		}
+	}
+	source, err := newTokenSource("hosted", config, newMemoryTokenStore(), onStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tok, err := source.Token(ctx)
+	if err != nil || tok == "" {
+		t.Fatalf("Token = %q, %v", tok, err)
+	}
+	if err := <-waited; err != context.DeadlineExceeded {
+		t.Errorf("concurrent Token during authorization err = %v, want its own deadline", err)
+	}
+	if again, _ := source.Token(ctx); again != tok {
+		t.Errorf("second Token = %q, want the cached %q", again, tok)
+	}
+}
+
+func TestOAuth_RefreshesTokenNearExpiry(t *testing.T) {
+	server := newOAuthTestServer(t)
+	server.refresh["refresh-seed"] = true
+	server.access["about-to-expire"] = true
+
+	client := NewClient(tools.NewRegistry())
+	defer client.Close()
+	client.tokenStore.Save("hosted", &OAuthToken{
+		AccessToken:  "about-to-expire",
+		RefreshToken: "refresh-seed",
+		Expiry:       time.Now().Add(10 * time.Second),
+		ClientID:     "dyn-client",
+	})
+
+	// No redirect URI: only a refresh can succeed
+	config := types.McpServerConfig{Type: "http", URL: server.URL + "/mcp", Auth: &types.McpAuthConfig{Type: "oauth"}}
+	if err := client.Connect(context.Background(), "hosted", config); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if want := []string{"refresh_token"}; fmt.Sprint(server.grants) != fmt.Sprint(want) {
+		t.Errorf("grants = %v, want %v", server.grants, want)
+	}
+	if tok, _ := client.tokenStore.Load("hosted"); tok.AccessToken == "about-to-expire" {
+		t.Error("token should have been refreshed before expiry")
+	}
+}
+
+func TestOAuth_RetriesWithFreshTokenAfterUnauthorized(t *testing.T) {
+	server := newOAuthTestServer(t)
+	server.refresh["refresh-seed"] = true
+
+	client := NewClient(tools.NewRegistry())
+	defer client.Close()
+	// Unexpired but revoked server-side
+	client.tokenStore.Save("hosted", &OAuthToken{
+		AccessToken:  "revoked",
+		RefreshToken: "refresh-seed",
+		Expiry:       time.Now().Add(time.Hour),
+		ClientID:     "dyn-client",
+	})
+
+	config := types.McpServerConfig{Type: "http", URL: server.URL + "/mcp", Auth: &types.McpAuthConfig{Type: "oauth"}}
+	if err := client.Connect(context.Background(), "hosted", config); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if len(server.grants) != 1 || server.grants[0] != "refresh_token" {
+		t.Errorf("grants = %v, want one refresh after the 401", server.grants)
+	}
+}
+
+func TestOAuth_InteractiveWithoutCallbackFails(t *testing.T) {
+	server := newOAuthTestServer(t)
+	client := NewClient(tools.NewRegistry())
+	defer client.Close()
+
+	config := types.McpServerConfig{Type: "http", URL: server.URL + "/mcp", Auth: &types.McpAuthConfig{Type: "oauth"}}
+	err := client.Connect(context.Background(), "hosted", config)
+	if err == nil || !strings.Contains(err.Error(), "no callback server is configured") {
+		t.Fatalf("Connect error = %v, want missing callback error", err)
+	}
+	if status := client.servers["hosted"].status(); status.Status != StatusFailed {
+		t.Errorf("status = %s, want failed", status.Status)
+	}
+}
+
+func TestOAuth_BearerTokenFromEnv(t *testing.T) {
+	server := newOAuthTestServer(t)
+	server.access["env-token"] = true
+	t.Setenv("GOAT_TEST_MCP_TOKEN", "env-token")
+
+	client := NewClient(tools.NewRegistry())
+	defer client.Close()
+
+	// A supplied token skips the OAuth flow entirely
+	config := types.McpServerConfig{
+		Type: "http",
+		URL:  server.URL + "/mcp",
+		Auth: &types.McpAuthConfig{Type: "oauth", TokenEnv: "GOAT_TEST_MCP_TOKEN"},
+	}
+	if err := client.Connect(context.Background(), "hosted", config); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if len(server.grants) != 0 {
+		t.Errorf("grants = %v, want none", server.grants)
+	}
+
+	config.Auth = &types.McpAuthConfig{Type: "bearer", TokenEnv: "GOAT_TEST_MCP_TOKEN_UNSET"}
+	if err := client.Connect(context.Background(), "other", config); err == nil || !strings.Contains(err.Error(), "GOAT_TEST_MCP_TOKEN_UNSET is not set") {
+		t.Errorf("Connect error = %v, want unset env error", err)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	store := &FileTokenStore{Dir: filepath.Join(t.TempDir(), "tokens")}
+
+	if tok, err := store.Load("srv"); tok != nil || err != nil {
+		t.Fatalf("Load of missing token = %v, %v", tok, err)
+	}
+	want := &OAuthToken{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(time.Hour).Round(time.Second), ClientID: "c"}
+	if err := store.Save("team/srv", want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := store.Load("team/srv")
+	if err != nil || got.AccessToken != "a" || got.RefreshToken != "r" || got.ClientID != "c" || !got.Expiry.Equal(want.Expiry) {
+		t.Fatalf("Load = %+v, %v", got, err)
+	}
+
+	entries, _ := os.ReadDir(store.Dir)
+	if len(entries) != 1 {
+		t.Fatalf("files = %v, want one file (server name escaped)", entries)
+	}
+	info, _ := entries[0].Info()
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("token file mode = %o, want 600", perm)
+	}
+}
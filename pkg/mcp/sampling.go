@@ -0,0 +1,223 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+// errCodeSamplingDenied is returned when a sampling request is refused
+// (sampling disabled, permission denied, or budget exhausted).
+const errCodeSamplingDenied = -1
+
+// SetSampler lets servers whose config enables sampling request completions
+// from llmClient. Every request is checked with checker under the tool name
+// mcp__<server>__sampling first; a nil checker allows all requests from
+// enabled servers.
+func (c *Client) SetSampler(llmClient llm.Client, checker agent.PermissionChecker) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sampler = llmClient
+	c.samplingChecker = checker
+}
+
+// SamplingTokensUsed returns the tokens consumed by a server's sampling
+// requests, including those reserved by requests still in flight.
+func (c *Client) SamplingTokensUsed(server string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.samplingUsed[server]
+}
+
+// handleServerRequest answers a request initiated by the named server.
+func (c *Client) handleServerRequest(ctx context.Context, server, method string, params json.RawMessage) (any, *JSONRPCError) {
+	if method != MethodSamplingCreateMessage {
+		return nil, &JSONRPCError{Code: -32601, Message: fmt.Sprintf("method not found: %s", method)}
+	}
+	return c.handleSampling(ctx, server, params)
+}
+
+// handleSampling forwards a sampling/createMessage request to the LLM client,
+// subject to the server's sampling config, token budget and a permission check.
+// The budget covers input and output tokens: a request reserves its estimated
+// input plus its output cap up front, so concurrent requests cannot together
+// overrun it, and the reservation is settled against the actual usage.
+func (c *Client) handleSampling(ctx context.Context, server string, raw json.RawMessage) (any, *JSONRPCError) {
+	c.mu.RLock()
+	conn := c.servers[server]
+	llmClient, checker := c.sampler, c.samplingChecker
+	c.mu.RUnlock()
+
+	if conn == nil || conn.Config.Sampling == nil || !conn.Config.Sampling.Enabled {
+		return nil, samplingDenied("sampling is not enabled for server %q", server)
+	}
+	if llmClient == nil {
+		return nil, samplingDenied("sampling is not available")
+	}
+	config := conn.Config.Sampling
+
+	var params CreateMessageParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &JSONRPCError{Code: -32602, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	messages, err := samplingMessages(params.Messages)
+	if err != nil {
+		return nil, &JSONRPCError{Code: -32602, Message: err.Error()}
+	}
+
+	// Cap the request at what is left of the server's budget
+	maxTokens := params.MaxTokens
+	reserved, used := 0, 0
+	if config.MaxTokens > 0 {
+		inputTokens := estimateSamplingTokens(raw)
+		c.mu.Lock()
+		remaining := config.MaxTokens - c.samplingUsed[server] - inputTokens
+		if remaining <= 0 {
+			c.mu.Unlock()
+			return nil, samplingDenied("sampling token budget exhausted for server %q", server)
+		}
+		if maxTokens <= 0 || maxTokens > remaining {
+			maxTokens = remaining
+		}
+		reserved = inputTokens + maxTokens
+		c.addSamplingUsage(server, reserved)
+		c.mu.Unlock()
+	}
+	defer func() {
+		c.mu.Lock()
+		c.addSamplingUsage(server, used-reserved)
+		c.mu.Unlock()
+	}()
+	model := selectSamplingModel(params.ModelPreferences, config.Models, llmClient.Model())
+
+	if checker != nil {
+		result, err := checker.Check(ctx, "mcp__"+server+"__sampling", map[string]any{
+			"model":        model,
+			"maxTokens":    maxTokens,
+			"systemPrompt": params.SystemPrompt,
+			"messages":     len(params.Messages),
+		})
+		if err != nil {
+			return nil, samplingDenied("permission check failed: %v", err)
+		}
+		if result.Behavior != "allow" {
+			msg := result.Message
+			if msg == "" {
+				msg = "sampling request denied"
+			}
+			return nil, samplingDenied("%s", msg)
+		}
+	}
+
+	req := llm.BuildCompletionRequest(llm.ClientConfig{Model: model, MaxTokens: maxTokens}, params.SystemPrompt, messages, nil, llm.LoopState{})
+	if params.Temperature != nil {
+		req.Temperature = params.Temperature
+	}
+	req.Stop = params.StopSequences
+
+	stream, err := llmClient.Complete(ctx, req)
+	if err != nil {
+		return nil, &JSONRPCError{Code: -32603, Message: fmt.Sprintf("sampling failed: %v", err)}
+	}
+	resp, err := stream.Accumulate()
+	if err != nil {
+		return nil, &JSONRPCError{Code: -32603, Message: fmt.Sprintf("sampling failed: %v", err)}
+	}
+
+	used = resp.Usage.InputTokens + resp.Usage.OutputTokens
+
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	return CreateMessageResult{
+		Role:       "assistant",
+		Content:    ContentBlock{Type: "text", Text: text.String()},
+		Model:      model,
+		StopReason: samplingStopReason(resp.StopReason),
+	}, nil
+}
+
+// addSamplingUsage adds n (possibly negative) to a server's sampling usage.
+// The caller holds c.mu.
+func (c *Client) addSamplingUsage(server string, n int) {
+	if c.samplingUsed == nil {
+		c.samplingUsed = make(map[string]int)
+	}
+	c.samplingUsed[server] += n
+}
+
+// estimateSamplingTokens approximates the input tokens of a sampling request
+// from its encoded params, at about four bytes per token.
+func estimateSamplingTokens(raw json.RawMessage) int {
+	return len(raw) / 4
+}
+
+func samplingDenied(format string, args ...any) *JSONRPCError {
+	return &JSONRPCError{Code: errCodeSamplingDenied, Message: fmt.Sprintf(format, args...)}
+}
+
+// samplingMessages converts sampling messages to chat messages. Text and
+// base64 image content are supported.
+func samplingMessages(msgs []SamplingMessage) ([]llm.ChatMessage, error) {
+	out := make([]llm.ChatMessage, 0, len(msgs))
+	for i, m := range msgs {
+		if m.Role != "user" && m.Role != "assistant" {
+			return nil, fmt.Errorf("message %d: unsupported role %q", i, m.Role)
+		}
+		switch m.Content.Type {
+		case "text":
+			out = append(out, llm.ChatMessage{Role: m.Role, Content: m.Content.Text})
+		case "image":
+			out = append(out, llm.ChatMessage{Role: m.Role, Content: []llm.ContentPart{{
+				Type:     "image_url",
+				ImageURL: &llm.ImageURL{URL: "data:" + m.Content.MimeType + ";base64," + m.Content.Data},
+			}}})
+		default:
+			return nil, fmt.Errorf("message %d: unsupported content type %q", i, m.Content.Type)
+		}
+	}
+	return out, nil
+}
+
+// selectSamplingModel picks the first configured model matching one of the
+// server's hints, in hint order. Without a match the default model is used.
+func selectSamplingModel(prefs *ModelPreferences, models []string, defaultModel string) string {
+	if prefs == nil {
+		return defaultModel
+	}
+	for _, hint := range prefs.Hints {
+		if hint.Name == "" {
+			continue
+		}
+		for _, model := range models {
+			if strings.Contains(strings.ToLower(model), strings.ToLower(hint.Name)) {
+				return model
+			}
+		}
+	}
+	return defaultModel
+}
+
+// samplingStopReason maps an Anthropic stop_reason to its MCP form.
+func samplingStopReason(reason string) string {
+	switch reason {
+	case "end_turn":
+		return "endTurn"
+	case "max_tokens":
+		return "maxTokens"
+	case "stop_sequence":
+		return "stopSequence"
+	default:
+		return reason
+	}
+}
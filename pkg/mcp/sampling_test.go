@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// samplingLLM records the last request and answers with fixed text. With
+// started and release set, each call signals started and waits for release.
+type samplingLLM struct {
+	mu    sync.Mutex
+	model string
+	calls int
+	last  *llm.CompletionRequest
+	reply string
+
+	started, release chan struct{}
+}
+
+func (m *samplingLLM) Complete(_ context.Context, req *llm.CompletionRequest) (*llm.Stream, error) {
+	m.mu.Lock()
+	m.calls++
+	m.last = req
+	m.mu.Unlock()
+	if m.started != nil {
+		m.started <- struct{}{}
+		<-m.release
+	}
+
+	finish := "stop"
+	events := make(chan llm.StreamEvent, 3)
+	events <- llm.StreamEvent{Chunk: &llm.StreamChunk{ID: "chatcmpl-1", Model: req.Model, Choices: []llm.Choice{{Delta: llm.Delta{Content: &m.reply}}}}}
+	events <- llm.StreamEvent{Chunk: &llm.StreamChunk{
+		Choices: []llm.Choice{{FinishReason: &finish}},
+		Usage:   &llm.Usage{PromptTokens: 30, CompletionTokens: 10, TotalTokens: 40},
+	}}
+	events <- llm.StreamEvent{Done: true}
+	close(events)
+	return llm.NewStream(events, nil, func() {}), nil
+}
+
+func (m *samplingLLM) Model() string         { return m.model }
+func (m *samplingLLM) SetModel(model string) { m.model = model }
+
+type samplingChecker struct {
+	behavior string
+	message  string
+	tool     string
+	input    map[string]any
+}
+
+func (c *samplingChecker) Check(_ context.Context, toolName string, input map[string]any) (agent.PermissionResult, error) {
+	c.tool, c.input = toolName, input
+	return agent.PermissionResult{Behavior: c.behavior, Message: c.message}, nil
+}
+
+func newSamplingClient(t *testing.T, config *types.McpSamplingConfig) (*Client, *samplingLLM) {
+	t.Helper()
+	client := NewClient(tools.NewRegistry())
+	connectWithMock(t, client, "srv", newMockTransport().withInitialize(ServerCapabilities{}))
+	client.servers["srv"].Config.Sampling = config
+	model := &samplingLLM{model: "default-model", reply: "hello from the host"}
+	client.SetSampler(model, nil)
+	return client, model
+}
+
+func samplingParams(t *testing.T, params CreateMessageParams) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSampling_DeniedByDefault(t *testing.T) {
+	client, model := newSamplingClient(t, nil)
+
+	params := samplingParams(t, CreateMessageParams{
+		Messages:  []SamplingMessage{{Role: "user", Content: ContentBlock{Type: "text", Text: "hi"}}},
+		MaxTokens: 100,
+	})
+	_, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params)
+	if rpcErr == nil || rpcErr.Code != errCodeSamplingDenied {
+		t.Fatalf("expected sampling to be denied, got %+v", rpcErr)
+	}
+	if model.calls != 0 {
+		t.Error("LLM should not be called when sampling is disabled")
+	}
+	if caps := client.servers["srv"].clientCapabilities(); caps.Sampling != nil {
+		t.Error("sampling capability should not be advertised when disabled")
+	}
+}
+
+func TestSampling_ForwardsToLLM(t *testing.T) {
+	client, model := newSamplingClient(t, &types.McpSamplingConfig{
+		Enabled: true,
+		Models:  []string{"openai/gpt-4o", "anthropic/claude-sonnet-4-5"},
+	})
+	if caps := client.servers["srv"].clientCapabilities(); caps.Sampling == nil {
+		t.Error("sampling capability should be advertised when enabled")
+	}
+
+	temp := 0.2
+	params := samplingParams(t, CreateMessageParams{
+		Messages: []SamplingMessage{
+			{Role: "user", Content: ContentBlock{Type: "text", Text: "summarize"}},
+			{Role: "user", Content: ContentBlock{Type: "image", MimeType: "image/png", Data: "aGk="}},
+		},
+		ModelPreferences: &ModelPreferences{Hints: []ModelHint{{Name: "gemini"}, {Name: "Sonnet"}}},
+		SystemPrompt:     "You are a summarizer.",
+		Temperature:      &temp,
+		MaxTokens:        200,
+		StopSequences:    []string{"END"},
+	})
+	result, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params)
+	if rpcErr != nil {
+		t.Fatalf("sampling failed: %v", rpcErr)
+	}
+
+	req := model.last
+	if req.Model != "anthropic/claude-sonnet-4-5" {
+		t.Errorf("model = %q, want the configured model matching the hints", req.Model)
+	}
+	if req.MaxTokens != 200 || req.Temperature == nil || *req.Temperature != 0.2 {
+		t.Errorf("maxTokens = %d, temperature = %v", req.MaxTokens, req.Temperature)
+	}
+	if len(req.Stop) != 1 || req.Stop[0] != "END" {
+		t.Errorf("stop = %v", req.Stop)
+	}
+	if len(req.Messages) != 3 || req.Messages[0].Role != "system" || req.Messages[0].Content != "You are a summarizer." {
+		t.Fatalf("messages = %+v, want system prompt first", req.Messages)
+	}
+	parts, ok := req.Messages[2].Content.([]llm.ContentPart)
+	if !ok || parts[0].ImageURL == nil || parts[0].ImageURL.URL != "data:image/png;base64,aGk=" {
+		t.Errorf("image message = %+v", req.Messages[2].Content)
+	}
+
+	got := result.(CreateMessageResult)
+	if got.Role != "assistant" || got.Content.Text != "hello from the host" || got.StopReason != "endTurn" {
+		t.Errorf("result = %+v", got)
+	}
+	if got.Model != "anthropic/claude-sonnet-4-5" {
+		t.Errorf("result model = %q", got.Model)
+	}
+	if used := client.SamplingTokensUsed("srv"); used != 40 {
+		t.Errorf("tokens used = %d, want 40", used)
+	}
+}
+
+func TestSampling_TokenBudget(t *testing.T) {
+	client, model := newSamplingClient(t, &types.McpSamplingConfig{Enabled: true, MaxTokens: 100})
+
+	params := samplingParams(t, CreateMessageParams{
+		Messages:  []SamplingMessage{{Role: "user", Content: ContentBlock{Type: "text", Text: "hi"}}},
+		MaxTokens: 1000,
+	})
+	input := estimateSamplingTokens(params)
+	if _, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params); rpcErr != nil {
+		t.Fatalf("first request: %v", rpcErr)
+	}
+	// The budget covers the input too, so the output cap leaves room for it
+	if model.last.MaxTokens != 100-input {
+		t.Errorf("maxTokens = %d, want %d (budget less the estimated input)", model.last.MaxTokens, 100-input)
+	}
+	if used := client.SamplingTokensUsed("srv"); used != 40 {
+		t.Errorf("tokens used = %d, want the actual 40", used)
+	}
+
+	// 40 used: the next request gets what is left after its input
+	if _, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params); rpcErr != nil {
+		t.Fatalf("second request: %v", rpcErr)
+	}
+	if model.last.MaxTokens != 60-input {
+		t.Errorf("maxTokens = %d, want %d", model.last.MaxTokens, 60-input)
+	}
+
+	_, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params)
+	if rpcErr == nil || !strings.Contains(rpcErr.Message, "budget exhausted") {
+		t.Fatalf("expected budget error, got %+v", rpcErr)
+	}
+	if model.calls != 2 {
+		t.Errorf("LLM calls = %d, want 2", model.calls)
+	}
+}
+
+func TestSampling_ConcurrentRequestsReserveBudget(t *testing.T) {
+	params := samplingParams(t, CreateMessageParams{
+		Messages:  []SamplingMessage{{Role: "user", Content: ContentBlock{Type: "text", Text: "hi"}}},
+		MaxTokens: 1000,
+	})
+	// Room for one request's input and output cap, not two
+	client, model := newSamplingClient(t, &types.McpSamplingConfig{Enabled: true, MaxTokens: 2*estimateSamplingTokens(params) + 10})
+	model.started, model.release = make(chan struct{}), make(chan struct{})
+
+	done := make(chan *JSONRPCError)
+	go func() {
+		_, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params)
+		done <- rpcErr
+	}()
+	<-model.started
+
+	// The first request is in flight and holds its reservation
+	_, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params)
+	if rpcErr == nil || !strings.Contains(rpcErr.Message, "budget exhausted") {
+		t.Errorf("concurrent request: got %+v, want budget error", rpcErr)
+	}
+	close(model.release)
+	if rpcErr := <-done; rpcErr != nil {
+		t.Fatalf("first request: %v", rpcErr)
+	}
+	if used := client.SamplingTokensUsed("srv"); used != 40 {
+		t.Errorf("tokens used = %d, want 40 once the reservation is settled", used)
+	}
+}
+
+func TestSampling_PermissionCheck(t *testing.T) {
+	client, model := newSamplingClient(t, &types.McpSamplingConfig{Enabled: true})
+	checker := &samplingChecker{behavior: "deny", message: "not now"}
+	client.SetSampler(model, checker)
+
+	params := samplingParams(t, CreateMessageParams{
+		Messages:     []SamplingMessage{{Role: "user", Content: ContentBlock{Type: "text", Text: "hi"}}},
+		SystemPrompt: "be brief",
+		MaxTokens:    64,
+	})
+	_, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params)
+	if rpcErr == nil || rpcErr.Code != errCodeSamplingDenied || rpcErr.Message != "not now" {
+		t.Fatalf("expected denial, got %+v", rpcErr)
+	}
+	if model.calls != 0 {
+		t.Error("LLM should not be called when permission is denied")
+	}
+	if checker.tool != "mcp__srv__sampling" {
+		t.Errorf("checked tool = %q", checker.tool)
+	}
+	if checker.input["model"] != "default-model" || checker.input["systemPrompt"] != "be brief" || checker.input["maxTokens"] != 64 {
+		t.Errorf("checked input = %v", checker.input)
+	}
+
+	checker.behavior = "allow"
+	if _, rpcErr := client.handleServerRequest(context.Background(), "srv", MethodSamplingCreateMessage, params); rpcErr != nil {
+		t.Fatalf("allowed request failed: %v", rpcErr)
+	}
+}
+
+func TestSampling_UnknownMethod(t *testing.T) {
+	client, _ := newSamplingClient(t, &types.McpSamplingConfig{Enabled: true})
+	_, rpcErr := client.handleServerRequest(context.Background(), "srv", "roots/list", nil)
+	if rpcErr == nil || rpcErr.Code != -32601 {
+		t.Fatalf("expected method not found, got %+v", rpcErr)
+	}
+}
@@ -0,0 +1,431 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TransportSSE is the legacy HTTP+SSE transport: a long-lived GET stream
+// carries server messages, and requests are POSTed to an endpoint announced
+// by the server on that stream.
+const TransportSSE = "sse"
+
+// sseEndpointTimeout bounds how long NewSSETransport waits for the endpoint event.
+const sseEndpointTimeout = 30 * time.Second
+
+// SSETransport communicates with an MCP server via the HTTP+SSE transport.
+// Responses arrive on the event stream and are routed to callers by request
+// ID. If the stream drops, it is re-established with exponential backoff and
+// the reconnect handler is called so the session can be re-initialized.
+type SSETransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	tokens  TokenSource // bearer tokens for authenticated servers; nil if none
+
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	mu       sync.Mutex
+	endpoint string        // POST URL from the latest endpoint event; "" while disconnected
+	ready    chan struct{} // closed when endpoint is set for the current stream
+	closed   bool
+
+	pending map[int]chan JSONRPCResponse
+	pendMu  sync.Mutex
+
+	onNotification NotificationHandler
+	onRequest      RequestHandler
+	onReconnect    func()
+	notifyMu       sync.RWMutex
+
+	ctx    context.Context // cancelled by Close; aborts the stream and backoff
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the stream goroutine exits
+}
+
+// NewSSETransport opens the event stream at url and waits for the server's
+// endpoint event before returning.
+func NewSSETransport(ctx context.Context, url string, headers map[string]string) (*SSETransport, error) {
+	return NewSSETransportWithAuth(ctx, url, headers, nil)
+}
+
+// NewSSETransportWithAuth is NewSSETransport with bearer tokens from tokens
+// sent on the stream and every POST.
+func NewSSETransportWithAuth(ctx context.Context, url string, headers map[string]string, tokens TokenSource) (*SSETransport, error) {
+	streamCtx, cancel := context.WithCancel(context.Background())
+	t := &SSETransport{
+		url:        url,
+		headers:    headers,
+		client:     &http.Client{},
+		tokens:     tokens,
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		ready:      make(chan struct{}),
+		pending:    make(map[int]chan JSONRPCResponse),
+		ctx:        streamCtx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+
+	body, err := t.openStream()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	go t.streamLoop(body)
+
+	ctx, cancelWait := context.WithTimeout(ctx, sseEndpointTimeout)
+	defer cancelWait()
+	if err := t.waitReady(ctx); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("waiting for sse endpoint: %w", err)
+	}
+	return t, nil
+}
+
+// openStream issues the GET request for the event stream.
+func (t *SSETransport) openStream() (io.ReadCloser, error) {
+	resp, err := doWithToken(t.ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, t.url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		for k, v := range t.headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sse connect: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("sse connect: http %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return resp.Body, nil
+}
+
+// streamLoop reads the event stream until Close, reconnecting with backoff
+// whenever the stream drops.
+func (t *SSETransport) streamLoop(body io.ReadCloser) {
+	defer close(t.done)
+
+	for {
+		t.readEvents(body)
+		body.Close()
+		t.disconnected()
+
+		backoff := t.minBackoff
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			var err error
+			if body, err = t.openStream(); err == nil {
+				break
+			}
+			backoff = min(backoff*2, t.maxBackoff)
+		}
+
+		t.notifyMu.RLock()
+		handler := t.onReconnect
+		t.notifyMu.RUnlock()
+		if handler != nil {
+			// Runs once the new endpoint arrives, since it sends requests
+			go func() {
+				if t.waitReady(t.ctx) == nil {
+					handler()
+				}
+			}()
+		}
+	}
+}
+
+// readEvents dispatches SSE events from body until it ends or errors.
+func (t *SSETransport) readEvents(body io.Reader) {
+	scanSSE(body, func(event, data string) bool {
+		t.handleEvent(event, data)
+		return true
+	})
+}
+
+// scanSSE parses a text/event-stream body, calling handle with each event's
+// type and data (multi-line data joined with newlines) until handle returns
+// false or the stream ends.
+func scanSSE(body io.Reader, handle func(event, data string) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var event string
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// Blank line dispatches the buffered event
+			if data.Len() > 0 && !handle(event, data.String()) {
+				return nil
+			}
+			event = ""
+			data.Reset()
+		case strings.HasPrefix(line, ":"):
+			// Comment (keep-alive)
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	// A final event may be unterminated when the server closes the stream
+	if data.Len() > 0 {
+		handle(event, data.String())
+	}
+	return scanner.Err()
+}
+
+// handleEvent processes a single SSE event: the endpoint announcement, or a
+// JSON-RPC response, notification or server-initiated request.
+func (t *SSETransport) handleEvent(event, data string) {
+	if event == "endpoint" {
+		endpoint, err := t.resolveEndpoint(data)
+		if err != nil {
+			return
+		}
+		t.mu.Lock()
+		if t.endpoint == "" {
+			t.endpoint = endpoint
+			close(t.ready)
+		}
+		t.mu.Unlock()
+		return
+	}
+	if event != "" && event != "message" {
+		return
+	}
+
+	var msg struct {
+		ID     *int            `json:"id,omitempty"`
+		Method string          `json:"method,omitempty"`
+		Params json.RawMessage `json:"params,omitempty"`
+	}
+	if err := json.Unmarshal([]byte(data), &msg); err != nil {
+		return
+	}
+
+	if msg.ID == nil && msg.Method != "" {
+		t.notifyMu.RLock()
+		handler := t.onNotification
+		t.notifyMu.RUnlock()
+		if handler != nil {
+			// Handlers may send requests whose responses arrive on this stream
+			go handler(msg.Method, msg.Params)
+		}
+		return
+	}
+	if msg.ID == nil {
+		return
+	}
+	if msg.Method != "" {
+		t.notifyMu.RLock()
+		handler := t.onRequest
+		t.notifyMu.RUnlock()
+		go t.respond(handler, *msg.ID, msg.Method, msg.Params)
+		return
+	}
+
+	var resp JSONRPCResponse
+	if err := json.Unmarshal([]byte(data), &resp); err != nil {
+		return
+	}
+	t.pendMu.Lock()
+	ch, ok := t.pending[resp.ID]
+	if ok {
+		delete(t.pending, resp.ID)
+	}
+	t.pendMu.Unlock()
+	if ok {
+		ch <- resp
+	}
+}
+
+// resolveEndpoint resolves the endpoint event's URI against the stream URL.
+func (t *SSETransport) resolveEndpoint(data string) (string, error) {
+	base, err := url.Parse(t.url)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(data))
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// disconnected clears the endpoint and fails in-flight requests, whose
+// responses can no longer arrive on the dropped stream.
+func (t *SSETransport) disconnected() {
+	t.mu.Lock()
+	if t.endpoint != "" {
+		t.endpoint = ""
+		t.ready = make(chan struct{})
+	}
+	t.mu.Unlock()
+
+	t.pendMu.Lock()
+	for id, ch := range t.pending {
+		delete(t.pending, id)
+		close(ch)
+	}
+	t.pendMu.Unlock()
+}
+
+// waitReady blocks until the current stream has an endpoint.
+func (t *SSETransport) waitReady(ctx context.Context) error {
+	t.mu.Lock()
+	ready := t.ready
+	t.mu.Unlock()
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.ctx.Done():
+		return fmt.Errorf("transport closed")
+	}
+}
+
+// Send POSTs a JSON-RPC request to the endpoint and waits for the response
+// on the event stream.
+func (t *SSETransport) Send(ctx context.Context, req JSONRPCRequest) (JSONRPCResponse, error) {
+	if req.ID == nil {
+		return JSONRPCResponse{}, fmt.Errorf("Send requires a request with an ID; use Notify for notifications")
+	}
+	id := *req.ID
+
+	ch := make(chan JSONRPCResponse, 1)
+	t.pendMu.Lock()
+	t.pending[id] = ch
+	t.pendMu.Unlock()
+
+	if err := t.post(ctx, req); err != nil {
+		t.pendMu.Lock()
+		delete(t.pending, id)
+		t.pendMu.Unlock()
+		return JSONRPCResponse{}, err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return JSONRPCResponse{}, fmt.Errorf("connection lost: sse stream dropped")
+		}
+		return resp, nil
+	case <-ctx.Done():
+		t.pendMu.Lock()
+		delete(t.pending, id)
+		t.pendMu.Unlock()
+		return JSONRPCResponse{}, ctx.Err()
+	case <-t.ctx.Done():
+		return JSONRPCResponse{}, fmt.Errorf("transport closed")
+	}
+}
+
+// Notify POSTs a JSON-RPC notification to the endpoint.
+func (t *SSETransport) Notify(ctx context.Context, method string, params any) error {
+	return t.post(ctx, newNotification(method, params))
+}
+
+// respond answers a server-initiated request by POSTing the response to the endpoint.
+func (t *SSETransport) respond(handler RequestHandler, id int, method string, params json.RawMessage) {
+	resp := answerRequest(t.ctx, handler, id, method, params)
+	t.post(t.ctx, resp)
+}
+
+// post sends a message to the current endpoint. The server acknowledges with
+// 202 Accepted; any response is delivered on the event stream.
+func (t *SSETransport) post(ctx context.Context, msg any) error {
+	t.mu.Lock()
+	endpoint, closed := t.endpoint, t.closed
+	t.mu.Unlock()
+	if closed {
+		return fmt.Errorf("transport closed")
+	}
+	if endpoint == "" {
+		return fmt.Errorf("not connected: waiting for sse endpoint")
+	}
+
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+	resp, err := doWithToken(ctx, t.client, t.tokens, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range t.headers {
+			req.Header.Set(k, v)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("http %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
+
+// SetNotificationHandler registers a handler for server-initiated notifications.
+func (t *SSETransport) SetNotificationHandler(handler NotificationHandler) {
+	t.notifyMu.Lock()
+	defer t.notifyMu.Unlock()
+	t.onNotification = handler
+}
+
+// SetRequestHandler registers a handler for server-initiated requests.
+func (t *SSETransport) SetRequestHandler(handler RequestHandler) {
+	t.notifyMu.Lock()
+	defer t.notifyMu.Unlock()
+	t.onRequest = handler
+}
+
+// SetReconnectHandler registers a callback run after the event stream has
+// been re-established and a new endpoint received.
+func (t *SSETransport) SetReconnectHandler(handler func()) {
+	t.notifyMu.Lock()
+	defer t.notifyMu.Unlock()
+	t.onReconnect = handler
+}
+
+// Close cancels the event stream and any pending reconnect, failing in-flight requests.
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	t.cancel()
+	<-t.done
+	return nil
+}
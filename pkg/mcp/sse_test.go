@@ -0,0 +1,342 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// sseTestServer is a minimal HTTP+SSE MCP server. Each GET opens a stream
+// that first announces the POST endpoint; POSTed requests are answered by
+// handle and the responses written to the open stream.
+type sseTestServer struct {
+	*httptest.Server
+	handle func(req JSONRPCRequest) *JSONRPCResponse
+
+	mu      sync.Mutex
+	events  chan string   // events for the current stream
+	drop    chan struct{} // closing this ends the current stream
+	streams atomic.Int32
+	header  http.Header // headers of the last GET
+
+	answers chan JSONRPCResponse // client responses to server-initiated requests
+}
+
+func newSSETestServer(t *testing.T, handle func(req JSONRPCRequest) *JSONRPCResponse) *sseTestServer {
+	s := &sseTestServer{handle: handle, answers: make(chan JSONRPCResponse, 4)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", func(w http.ResponseWriter, r *http.Request) {
+		n := s.streams.Add(1)
+		events, drop := make(chan string, 16), make(chan struct{})
+		s.mu.Lock()
+		s.events, s.drop, s.header = events, drop, r.Header.Clone()
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, ": connected\n\nevent: endpoint\ndata: /messages?session=%d\n\n", n)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case ev := <-events:
+				fmt.Fprint(w, ev)
+				w.(http.Flusher).Flush()
+			case <-drop:
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	mux.HandleFunc("POST /messages", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req JSONRPCRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		if req.ID == nil {
+			return
+		}
+		if req.Method == "" {
+			var answer JSONRPCResponse
+			json.Unmarshal(body, &answer)
+			s.answers <- answer
+			return
+		}
+		if resp := s.handle(req); resp != nil {
+			s.send(mustJSON(t, resp))
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// send writes a JSON-RPC message to the current stream.
+func (s *sseTestServer) send(data string) {
+	s.mu.Lock()
+	events := s.events
+	s.mu.Unlock()
+	events <- "event: message\ndata: " + data + "\n\n"
+}
+
+// dropStream ends the current stream, as a server restart or network blip would.
+func (s *sseTestServer) dropStream() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.drop)
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+	return string(data)
+}
+
+func echoHandler(req JSONRPCRequest) *JSONRPCResponse {
+	return &JSONRPCResponse{JSONRPC: "2.0", ID: *req.ID, Result: json.RawMessage(fmt.Sprintf(`{"method":%q}`, req.Method))}
+}
+
+func newTestSSETransport(t *testing.T, s *sseTestServer) *SSETransport {
+	t.Helper()
+	transport, err := NewSSETransport(context.Background(), s.URL+"/sse", map[string]string{"Authorization": "Bearer tok"})
+	if err != nil {
+		t.Fatalf("NewSSETransport: %v", err)
+	}
+	transport.minBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { transport.Close() })
+	return transport
+}
+
+func TestSSETransport_SendRoutesByID(t *testing.T) {
+	// Answer the second request before the first to check routing by id
+	first := make(chan JSONRPCRequest, 1)
+	var server *sseTestServer
+	server = newSSETestServer(t, func(req JSONRPCRequest) *JSONRPCResponse {
+		if *req.ID == 1 {
+			first <- req
+			return nil
+		}
+		resp := echoHandler(req)
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			server.send(mustJSON(t, echoHandler(<-first)))
+		}()
+		return resp
+	})
+	transport := newTestSSETransport(t, server)
+
+	if got := server.header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("stream Authorization header = %q", got)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 3)
+	for id := 1; id <= 2; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if id == 2 {
+				time.Sleep(10 * time.Millisecond) // ensure request 1 is posted first
+			}
+			resp, err := transport.Send(context.Background(), newRequest(id, fmt.Sprintf("method/%d", id), nil))
+			if err != nil {
+				t.Errorf("Send(%d): %v", id, err)
+				return
+			}
+			if resp.ID != id {
+				t.Errorf("Send(%d) got response id %d", id, resp.ID)
+			}
+			results[id] = string(resp.Result)
+		}(id)
+	}
+	wg.Wait()
+
+	for id := 1; id <= 2; id++ {
+		if want := fmt.Sprintf(`{"method":"method/%d"}`, id); results[id] != want {
+			t.Errorf("result %d = %s, want %s", id, results[id], want)
+		}
+	}
+}
+
+func TestSSETransport_Notification(t *testing.T) {
+	server := newSSETestServer(t, echoHandler)
+	transport := newTestSSETransport(t, server)
+
+	got := make(chan string, 1)
+	transport.SetNotificationHandler(func(method string, _ json.RawMessage) { got <- method })
+	server.send(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`)
+
+	select {
+	case method := <-got:
+		if method != "notifications/tools/list_changed" {
+			t.Errorf("method = %q", method)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("notification not delivered")
+	}
+}
+
+func TestSSETransport_AnswersServerRequest(t *testing.T) {
+	server := newSSETestServer(t, echoHandler)
+	transport := newTestSSETransport(t, server)
+
+	transport.SetRequestHandler(func(_ context.Context, method string, params json.RawMessage) (any, *JSONRPCError) {
+		return map[string]string{"method": method, "params": string(params)}, nil
+	})
+	server.send(`{"jsonrpc":"2.0","id":41,"method":"sampling/createMessage","params":{"maxTokens":5}}`)
+
+	select {
+	case answer := <-server.answers:
+		if answer.ID != 41 || answer.Error != nil {
+			t.Fatalf("answer = %+v", answer)
+		}
+		if want := `{"method":"sampling/createMessage","params":"{\"maxTokens\":5}"}`; string(answer.Result) != want {
+			t.Errorf("result = %s, want %s", answer.Result, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server request not answered")
+	}
+
+	// Without a handler the request is answered with method not found
+	transport.SetRequestHandler(nil)
+	server.send(`{"jsonrpc":"2.0","id":42,"method":"roots/list"}`)
+	select {
+	case answer := <-server.answers:
+		if answer.ID != 42 || answer.Error == nil || answer.Error.Code != -32601 {
+			t.Errorf("answer = %+v, want method not found", answer)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server request not answered")
+	}
+}
+
+func TestSSETransport_ReconnectsAfterDrop(t *testing.T) {
+	server := newSSETestServer(t, echoHandler)
+	transport := newTestSSETransport(t, server)
+
+	reconnected := make(chan struct{}, 1)
+	transport.SetReconnectHandler(func() { reconnected <- struct{}{} })
+	server.dropStream()
+
+	select {
+	case <-reconnected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("transport did not reconnect")
+	}
+	if n := server.streams.Load(); n != 2 {
+		t.Errorf("streams opened = %d, want 2", n)
+	}
+	resp, err := transport.Send(context.Background(), newRequest(7, "ping", nil))
+	if err != nil || resp.ID != 7 {
+		t.Fatalf("Send after reconnect = %+v, %v", resp, err)
+	}
+}
+
+func TestSSETransport_CloseFailsPendingSend(t *testing.T) {
+	server := newSSETestServer(t, func(JSONRPCRequest) *JSONRPCResponse { return nil })
+	transport := newTestSSETransport(t, server)
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := transport.Send(context.Background(), newRequest(1, "never/answered", nil))
+		errCh <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	transport.Close()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("expected error from Send after Close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Send did not return after Close")
+	}
+	if err := transport.Notify(context.Background(), "x", nil); err == nil {
+		t.Error("Notify after Close should fail")
+	}
+}
+
+func TestSSETransport_EndpointTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := NewSSETransport(ctx, server.URL, nil); err == nil {
+		t.Fatal("expected error when no endpoint event arrives")
+	}
+}
+
+func TestClient_ConnectSSE_KeepsToolsAcrossReconnect(t *testing.T) {
+	var inits atomic.Int32
+	server := newSSETestServer(t, func(req JSONRPCRequest) *JSONRPCResponse {
+		var result any
+		switch req.Method {
+		case MethodInitialize:
+			inits.Add(1)
+			result = InitializeResult{
+				ProtocolVersion: "2024-11-05",
+				Capabilities:    ServerCapabilities{Tools: &ToolsCapability{}},
+				ServerInfo:      ServerInfo{Name: "sse-server", Version: "1.0"},
+			}
+		case MethodToolsList:
+			result = ToolsListResult{Tools: []ToolInfo{{Name: "search", Description: "Search"}}}
+		default:
+			return &JSONRPCResponse{JSONRPC: "2.0", ID: *req.ID, Error: &JSONRPCError{Code: -32601, Message: "not found"}}
+		}
+		data, _ := json.Marshal(result)
+		return &JSONRPCResponse{JSONRPC: "2.0", ID: *req.ID, Result: data}
+	})
+
+	registry := tools.NewRegistry()
+	client := NewClient(registry)
+	defer client.Close()
+	if err := client.Connect(context.Background(), "remote", types.McpServerConfig{Type: "sse", URL: server.URL + "/sse"}); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if _, ok := registry.Get("mcp__remote__search"); !ok {
+		t.Fatal("expected mcp__remote__search to be registered")
+	}
+
+	// Wrap the reconnect handler installed by Connect to know when it finished
+	sse := client.servers["remote"].Transport.(*SSETransport)
+	sse.minBackoff = 10 * time.Millisecond
+	done := make(chan struct{})
+	sse.SetReconnectHandler(func() {
+		client.handleStreamReconnect("remote")
+		close(done)
+	})
+	server.dropStream()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("reconnect handler did not run")
+	}
+	if n := inits.Load(); n != 2 {
+		t.Errorf("initialize calls = %d, want 2 (session re-initialized after reconnect)", n)
+	}
+	if _, ok := registry.Get("mcp__remote__search"); !ok {
+		t.Error("tools should remain registered after reconnect")
+	}
+}
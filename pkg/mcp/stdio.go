@@ -27,9 +27,13 @@ type StdioTransport struct {
 	pendMu  sync.Mutex
 
 	onNotification NotificationHandler
+	onRequest      RequestHandler
 	notifyMu       sync.RWMutex
 
 	done chan struct{} // closed when reader goroutine exits
+
+	ctx    context.Context // cancelled by Close; bounds answers to server requests
+	cancel context.CancelFunc
 }
 
 // NewStdioTransport spawns a child process and returns a transport that communicates
@@ -61,6 +65,7 @@ func NewStdioTransport(command string, args []string, env map[string]string) (*S
 		return nil, fmt.Errorf("start process: %w", err)
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	t := &StdioTransport{
 		cmd:     cmd,
 		stdin:   stdinPipe,
@@ -68,6 +73,8 @@ func NewStdioTransport(command string, args []string, env map[string]string) (*S
 		stderr:  &stderrBuf,
 		pending: make(map[int]chan JSONRPCResponse),
 		done:    make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
 	}
 
 	go t.readLoop()
@@ -76,7 +83,8 @@ func NewStdioTransport(command string, args []string, env map[string]string) (*S
 }
 
 // readLoop reads lines from stdout and dispatches JSON-RPC responses to pending channels.
-// It also detects server-initiated notifications (messages with method but no id).
+// It also detects server-initiated notifications (messages with method but no id)
+// and requests (method and id), which are answered on stdin.
 func (t *StdioTransport) readLoop() {
 	defer close(t.done)
 
@@ -113,6 +121,14 @@ func (t *StdioTransport) readLoop() {
 			}
 			continue
 		}
+		if msg.ID != nil && msg.Method != "" {
+			// Server-initiated request; answering may take a while (e.g. an LLM call)
+			t.notifyMu.RLock()
+			handler := t.onRequest
+			t.notifyMu.RUnlock()
+			go t.respond(handler, *msg.ID, msg.Method, msg.Params)
+			continue
+		}
 
 		// Standard response dispatch
 		var resp JSONRPCResponse
@@ -201,6 +217,17 @@ func (t *StdioTransport) Notify(_ context.Context, method string, params any) er
 	return nil
 }
 
+// respond answers a server-initiated request and writes the response to stdin.
+func (t *StdioTransport) respond(handler RequestHandler, id int, method string, params json.RawMessage) {
+	data, err := json.Marshal(answerRequest(t.ctx, handler, id, method, params))
+	if err != nil {
+		return
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	t.stdin.Write(append(data, '\n'))
+}
+
 // SetNotificationHandler registers a handler for server-initiated notifications.
 func (t *StdioTransport) SetNotificationHandler(handler NotificationHandler) {
 	t.notifyMu.Lock()
@@ -208,8 +235,18 @@ func (t *StdioTransport) SetNotificationHandler(handler NotificationHandler) {
 	t.onNotification = handler
 }
 
+// SetRequestHandler registers a handler for server-initiated requests.
+func (t *StdioTransport) SetRequestHandler(handler RequestHandler) {
+	t.notifyMu.Lock()
+	defer t.notifyMu.Unlock()
+	t.onRequest = handler
+}
+
 // Close terminates the child process: close stdin, SIGTERM, wait with timeout, SIGKILL.
 func (t *StdioTransport) Close() error {
+	// Abandon in-flight answers to server requests
+	t.cancel()
+
 	// Close stdin to signal the child process
 	t.stdin.Close()
 
@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ResourceUpdateHandler is called when a subscribed resource changes. It
+// runs on its own goroutine, so it may call ReadResource to fetch the new
+// contents.
+type ResourceUpdateHandler func(serverName, uri string)
+
+// SubscribeResource asks serverName to send notifications/resources/updated
+// for uri and routes them to handler, replacing any handler already set for
+// uri. The server must advertise the resources.subscribe capability.
+// Subscriptions are renewed after a reconnect and dropped by Disconnect.
+func (c *Client) SubscribeResource(ctx context.Context, serverName, uri string, handler ResourceUpdateHandler) error {
+	c.mu.RLock()
+	conn, ok := c.servers[serverName]
+	c.mu.RUnlock()
+
+	if !ok {
+		return fmt.Errorf("unknown server: %q", serverName)
+	}
+	if err := conn.setResourceSubscription(ctx, uri, true); err != nil {
+		return fmt.Errorf("subscribe to %s: %w", uri, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.servers[serverName]; !ok {
+		return fmt.Errorf("unknown server: %q", serverName) // disconnected meanwhile
+	}
+	if c.subscriptions[serverName] == nil {
+		c.subscriptions[serverName] = make(map[string]ResourceUpdateHandler)
+	}
+	c.subscriptions[serverName][uri] = handler
+	return nil
+}
+
+// UnsubscribeResource removes the handler for uri and tells serverName to
+// stop sending updates for it. The handler is removed even if the server
+// cannot be reached.
+func (c *Client) UnsubscribeResource(ctx context.Context, serverName, uri string) error {
+	c.mu.Lock()
+	conn, ok := c.servers[serverName]
+	_, subscribed := c.subscriptions[serverName][uri]
+	delete(c.subscriptions[serverName], uri)
+	c.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown server: %q", serverName)
+	}
+	if !subscribed {
+		return fmt.Errorf("not subscribed to %s on %q", uri, serverName)
+	}
+	if err := conn.setResourceSubscription(ctx, uri, false); err != nil {
+		return fmt.Errorf("unsubscribe from %s: %w", uri, err)
+	}
+	return nil
+}
+
+// handleResourceUpdated dispatches a notifications/resources/updated
+// notification to the URI's handler, if any.
+func (c *Client) handleResourceUpdated(name string, params json.RawMessage) {
+	var p ResourceUpdatedParams
+	if err := json.Unmarshal(params, &p); err != nil || p.URI == "" {
+		return
+	}
+	c.mu.RLock()
+	handler := c.subscriptions[name][p.URI]
+	c.mu.RUnlock()
+	if handler != nil {
+		go handler(name, p.URI)
+	}
+}
+
+// resubscribe renews name's subscriptions on a new session. Failures are
+// ignored; the handlers stay in place for the next reconnect.
+func (c *Client) resubscribe(ctx context.Context, name string) {
+	c.mu.RLock()
+	conn := c.servers[name]
+	uris := make([]string, 0, len(c.subscriptions[name]))
+	for uri := range c.subscriptions[name] {
+		uris = append(uris, uri)
+	}
+	c.mu.RUnlock()
+
+	if conn == nil {
+		return
+	}
+	for _, uri := range uris {
+		conn.setResourceSubscription(ctx, uri, true)
+	}
+}
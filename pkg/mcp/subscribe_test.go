@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// sendRecorder records the methods sent through a mockTransport.
+type sendRecorder struct {
+	*mockTransport
+	mu      sync.Mutex
+	methods []string
+}
+
+func (r *sendRecorder) Send(ctx context.Context, req JSONRPCRequest) (JSONRPCResponse, error) {
+	r.mu.Lock()
+	r.methods = append(r.methods, req.Method)
+	r.mu.Unlock()
+	return r.mockTransport.Send(ctx, req)
+}
+
+func (r *sendRecorder) count(method string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, m := range r.methods {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+func connectSubscribable(t *testing.T, client *Client) *sendRecorder {
+	t.Helper()
+	empty, _ := json.Marshal(map[string]any{})
+	rec := &sendRecorder{mockTransport: newMockTransport().
+		withInitialize(ServerCapabilities{Resources: &ResourcesCapability{Subscribe: true}}).
+		withResources([]Resource{{URI: "file:///app.log", Name: "app.log"}}).
+		withResponse(MethodResourcesSubscribe, empty).
+		withResponse(MethodResourcesUnsubscribe, empty)}
+
+	conn := newServerConnection("srv1", types.McpServerConfig{})
+	conn.Transport = rec
+	if err := conn.runHandshake(context.Background()); err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	client.mu.Lock()
+	client.servers["srv1"] = conn
+	client.mu.Unlock()
+	return rec
+}
+
+func notifyUpdated(client *Client, uri string) {
+	params, _ := json.Marshal(ResourceUpdatedParams{URI: uri})
+	client.handleNotification("srv1", NotificationResourcesUpdated, params)
+}
+
+func TestClient_SubscribeResource(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	rec := connectSubscribable(t, client)
+
+	updates := make(chan string, 4)
+	err := client.SubscribeResource(context.Background(), "srv1", "file:///app.log", func(server, uri string) {
+		updates <- server + " " + uri
+	})
+	if err != nil {
+		t.Fatalf("SubscribeResource: %v", err)
+	}
+	if n := rec.count(MethodResourcesSubscribe); n != 1 {
+		t.Errorf("resources/subscribe sent %d times, want 1", n)
+	}
+
+	notifyUpdated(client, "file:///other.log") // not subscribed
+	notifyUpdated(client, "file:///app.log")
+	select {
+	case got := <-updates:
+		if got != "srv1 file:///app.log" {
+			t.Errorf("handler got %q", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler not called for the subscribed URI")
+	}
+
+	if err := client.UnsubscribeResource(context.Background(), "srv1", "file:///app.log"); err != nil {
+		t.Fatalf("UnsubscribeResource: %v", err)
+	}
+	if n := rec.count(MethodResourcesUnsubscribe); n != 1 {
+		t.Errorf("resources/unsubscribe sent %d times, want 1", n)
+	}
+	notifyUpdated(client, "file:///app.log")
+	select {
+	case got := <-updates:
+		t.Errorf("handler called after unsubscribe: %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := client.UnsubscribeResource(context.Background(), "srv1", "file:///app.log"); err == nil {
+		t.Error("expected an error unsubscribing twice")
+	}
+}
+
+func TestClient_SubscribeResource_Unsupported(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	mock := newMockTransport().
+		withInitialize(ServerCapabilities{Resources: &ResourcesCapability{}}).
+		withResources([]Resource{{URI: "file:///app.log"}})
+	connectWithMock(t, client, "srv1", mock)
+
+	err := client.SubscribeResource(context.Background(), "srv1", "file:///app.log", func(string, string) {})
+	if err == nil || !strings.Contains(err.Error(), "does not support resource subscriptions") {
+		t.Errorf("err = %v, want a clear capability error", err)
+	}
+	if err := client.SubscribeResource(context.Background(), "missing", "file:///app.log", func(string, string) {}); err == nil {
+		t.Error("expected an error for an unknown server")
+	}
+}
+
+func TestClient_SubscriptionsRenewedAndTornDown(t *testing.T) {
+	client := NewClient(tools.NewRegistry())
+	rec := connectSubscribable(t, client)
+
+	called := make(chan struct{}, 1)
+	client.SubscribeResource(context.Background(), "srv1", "file:///app.log", func(string, string) {
+		called <- struct{}{}
+	})
+
+	// A re-established session gets the subscription again
+	client.handleStreamReconnect("srv1")
+	if n := rec.count(MethodResourcesSubscribe); n != 2 {
+		t.Errorf("resources/subscribe sent %d times, want 2 after the session was renewed", n)
+	}
+
+	if err := client.Disconnect("srv1"); err != nil {
+		t.Fatal(err)
+	}
+	client.mu.RLock()
+	remaining := len(client.subscriptions)
+	client.mu.RUnlock()
+	if remaining != 0 {
+		t.Errorf("%d servers still have subscriptions after Disconnect", remaining)
+	}
+	notifyUpdated(client, "file:///app.log")
+	select {
+	case <-called:
+		t.Error("handler called after Disconnect")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 )
 
 // NotificationHandler is called when a server sends a notification (no ID).
@@ -19,3 +20,35 @@ type Transport interface {
 	// SetNotificationHandler registers a callback for server-initiated notifications.
 	SetNotificationHandler(handler NotificationHandler)
 }
+
+// RequestHandler answers a server-initiated request (e.g. sampling/createMessage).
+// It returns the result to send back, or an error object.
+type RequestHandler func(ctx context.Context, method string, params json.RawMessage) (any, *JSONRPCError)
+
+// RequestResponder is implemented by transports that can answer
+// server-initiated requests.
+type RequestResponder interface {
+	SetRequestHandler(handler RequestHandler)
+}
+
+// answerRequest runs handler for a server-initiated request and builds the
+// response to send back. Without a handler the method is reported as not found.
+func answerRequest(ctx context.Context, handler RequestHandler, id int, method string, params json.RawMessage) JSONRPCResponse {
+	resp := JSONRPCResponse{JSONRPC: "2.0", ID: id}
+	if handler == nil {
+		resp.Error = &JSONRPCError{Code: -32601, Message: fmt.Sprintf("method not found: %s", method)}
+		return resp
+	}
+	result, rpcErr := handler(ctx, method, params)
+	if rpcErr != nil {
+		resp.Error = rpcErr
+		return resp
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		resp.Error = &JSONRPCError{Code: -32603, Message: fmt.Sprintf("marshal result: %v", err)}
+		return resp
+	}
+	resp.Result = data
+	return resp
+}
@@ -17,6 +17,7 @@ const (
 	StatusNeedsAuth ConnectionStatus = "needs-auth"
 	StatusPending   ConnectionStatus = "pending"
 	StatusDisabled  ConnectionStatus = "disabled"
+	StatusDegraded  ConnectionStatus = "degraded" // circuit breaker open; see BreakerConfig
 )
 
 // ServerInfo is returned by the server during the initialize handshake.
@@ -27,9 +28,13 @@ type ServerInfo struct {
 
 // ClientCapabilities declares what the client supports (sent during initialize).
 type ClientCapabilities struct {
-	Experimental map[string]any `json:"experimental,omitempty"`
+	Experimental map[string]any      `json:"experimental,omitempty"`
+	Sampling     *SamplingCapability `json:"sampling,omitempty"`
 }
 
+// SamplingCapability indicates the client answers sampling/createMessage requests.
+type SamplingCapability struct{}
+
 // ServerCapabilities declares what the server supports (returned during initialize).
 type ServerCapabilities struct {
 	Tools     *ToolsCapability     `json:"tools,omitempty"`
@@ -132,6 +137,17 @@ type ResourceReadParams struct {
 	URI string `json:"uri"`
 }
 
+// ResourceSubscribeParams is the request body for resources/subscribe and
+// resources/unsubscribe.
+type ResourceSubscribeParams struct {
+	URI string `json:"uri"`
+}
+
+// ResourceUpdatedParams is the body of a notifications/resources/updated notification.
+type ResourceUpdatedParams struct {
+	URI string `json:"uri"`
+}
+
 // ResourceReadResult is the response from resources/read.
 type ResourceReadResult struct {
 	Contents []ResourceContent `json:"contents"`
@@ -145,6 +161,84 @@ type ResourceContent struct {
 	Blob     string `json:"blob,omitempty"` // base64 for binary
 }
 
+// Prompt describes a prompt template exposed by an MCP server.
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+// PromptArgument describes an argument a prompt template accepts.
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+// PromptsListResult is the response from prompts/list.
+type PromptsListResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// PromptGetParams is the request body for prompts/get.
+type PromptGetParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// PromptGetResult is the response from prompts/get.
+type PromptGetResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// PromptMessage is a single message in a rendered prompt.
+type PromptMessage struct {
+	Role    string       `json:"role"` // "user" | "assistant"
+	Content ContentBlock `json:"content"`
+}
+
+// CreateMessageParams is the request body for sampling/createMessage, sent
+// by a server asking the client for an LLM completion.
+type CreateMessageParams struct {
+	Messages         []SamplingMessage `json:"messages"`
+	ModelPreferences *ModelPreferences `json:"modelPreferences,omitempty"`
+	SystemPrompt     string            `json:"systemPrompt,omitempty"`
+	IncludeContext   string            `json:"includeContext,omitempty"` // "none" | "thisServer" | "allServers"
+	Temperature      *float64          `json:"temperature,omitempty"`
+	MaxTokens        int               `json:"maxTokens"`
+	StopSequences    []string          `json:"stopSequences,omitempty"`
+	Metadata         map[string]any    `json:"metadata,omitempty"`
+}
+
+// SamplingMessage is a single message in a sampling request.
+type SamplingMessage struct {
+	Role    string       `json:"role"` // "user" | "assistant"
+	Content ContentBlock `json:"content"`
+}
+
+// ModelPreferences carries the server's model selection hints. Hints are
+// advisory substrings of model names, in order of preference.
+type ModelPreferences struct {
+	Hints                []ModelHint `json:"hints,omitempty"`
+	CostPriority         *float64    `json:"costPriority,omitempty"`
+	SpeedPriority        *float64    `json:"speedPriority,omitempty"`
+	IntelligencePriority *float64    `json:"intelligencePriority,omitempty"`
+}
+
+// ModelHint names a preferred model or model family.
+type ModelHint struct {
+	Name string `json:"name,omitempty"`
+}
+
+// CreateMessageResult is the client's response to sampling/createMessage.
+type CreateMessageResult struct {
+	Role       string       `json:"role"`
+	Content    ContentBlock `json:"content"`
+	Model      string       `json:"model"`
+	StopReason string       `json:"stopReason,omitempty"` // "endTurn" | "stopSequence" | "maxTokens"
+}
+
 // MCP method constants.
 const (
 	MethodInitialize    = "initialize"
@@ -153,4 +247,16 @@ const (
 	MethodToolsCall     = "tools/call"
 	MethodResourcesList = "resources/list"
 	MethodResourcesRead = "resources/read"
+
+	MethodResourcesSubscribe   = "resources/subscribe"
+	MethodResourcesUnsubscribe = "resources/unsubscribe"
+
+	MethodPromptsList   = "prompts/list"
+	MethodPromptsGet    = "prompts/get"
+
+	MethodSamplingCreateMessage = "sampling/createMessage"
+
+	NotificationToolsListChanged   = "notifications/tools/list_changed"
+	NotificationPromptsListChanged = "notifications/prompts/list_changed"
+	NotificationResourcesUpdated   = "notifications/resources/updated"
 )
@@ -28,6 +28,7 @@ type Checker struct {
 	canUseTool           types.CanUseToolFunc
 	userPrompter         UserPrompter
 	toolAnnotationLookup func(string) *MCPAnnotations
+	cwd                  string
 }
 
 // NewChecker creates a permission Checker from configuration.
@@ -57,6 +58,7 @@ func NewChecker(config CheckerConfig) *Checker {
 		canUseTool:                      config.CanUseTool,
 		userPrompter:                    config.UserPrompter,
 		toolAnnotationLookup:            config.ToolAnnotationLookup,
+		cwd:                             config.CWD,
 	}
 }
 
@@ -137,7 +139,7 @@ func (c *Checker) Check(ctx context.Context, toolName string, input map[string]a
 	if c.toolAnnotationLookup != nil {
 		annotations = c.toolAnnotationLookup(toolName)
 	}
-	behavior := DefaultBehaviorForTool(c.mode, toolName, annotations)
+	behavior := behaviorForRisk(c.mode, toolName, toolCallRisk(toolName, input, annotations))
 
 	// If mode default says "ask", try the user prompter
 	if behavior == BehaviorAsk {
@@ -197,7 +199,7 @@ func (c *Checker) firePermissionHook(ctx context.Context, toolName string, input
 // checkRules evaluates config rules then session rules.
 func (c *Checker) checkRules(toolName string, input map[string]any) (agent.PermissionResult, bool) {
 	for _, rule := range c.configRules {
-		if rule.Matches(toolName, input) {
+		if rule.matchesIn(toolName, input, c.cwd) {
 			return agent.PermissionResult{
 				Behavior: string(rule.Behavior),
 				Message:  ruleMessage(rule),
@@ -206,7 +208,7 @@ func (c *Checker) checkRules(toolName string, input map[string]any) (agent.Permi
 	}
 
 	for _, rule := range c.sessionRules {
-		if rule.Matches(toolName, input) {
+		if rule.matchesIn(toolName, input, c.cwd) {
 			return agent.PermissionResult{
 				Behavior: string(rule.Behavior),
 				Message:  ruleMessage(rule),
@@ -91,6 +91,23 @@ func TestChecker_DefaultMode_ReadToolsAutoAllowed(t *testing.T) {
 	}
 }
 
+func TestChecker_ConfigWritesAreGated(t *testing.T) {
+	c := NewChecker(CheckerConfig{Mode: string(types.PermissionModeDefault)})
+
+	if result, _ := c.Check(context.Background(), "Config", map[string]any{"setting": "model"}); result.Behavior != "allow" {
+		t.Errorf("Config read behavior = %q, want allow", result.Behavior)
+	}
+	write := map[string]any{"setting": "model", "value": "claude-haiku-4-5-20251001"}
+	if result, _ := c.Check(context.Background(), "Config", write); result.Behavior != "deny" {
+		t.Errorf("headless Config write behavior = %q, want deny", result.Behavior)
+	}
+
+	c.SetMode(types.PermissionModeAcceptEdits)
+	if result, _ := c.Check(context.Background(), "Config", write); result.Behavior != "allow" {
+		t.Errorf("acceptEdits Config write behavior = %q, want allow", result.Behavior)
+	}
+}
+
 func TestChecker_DefaultMode_WriteToolsDenyHeadless(t *testing.T) {
 	// Without a prompter, "ask" tools are denied (headless mode)
 	c := NewChecker(CheckerConfig{Mode: string(types.PermissionModeDefault)})
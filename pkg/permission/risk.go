@@ -11,21 +11,26 @@ var toolRiskByName = map[string]ToolRiskLevel{
 	"Glob":     RiskNone,
 	"Grep":     RiskNone,
 	"TodoWrite": RiskNone,
+	"ListTasks": RiskNone,
 
 	// RiskLow — informational, minimal impact
-	"Config":           RiskLow,
+	"Config":           RiskLow, // reads only; see toolCallRisk
 	"ListMcpResources": RiskLow,
 	"ReadMcpResource":  RiskLow,
+	"ListMcpPrompts":   RiskLow,
+	"GetMcpPrompt":     RiskLow,
 	"AskUserQuestion":  RiskLow,
 	"ExitPlanMode":     RiskLow,
 	"TaskOutput":       RiskLow,
 	"TaskStop":         RiskLow,
+	"KillTask":         RiskLow,
 
 	// RiskMedium — file mutations
 	"Write":        RiskMedium,
 	"FileWrite":    RiskMedium,
 	"Edit":         RiskMedium,
 	"FileEdit":     RiskMedium,
+	"ApplyPatch":   RiskMedium,
 	"NotebookEdit": RiskMedium,
 
 	// RiskHigh — shell execution, network access
@@ -70,12 +75,27 @@ func ToolRiskWithAnnotations(toolName string, annotations *MCPAnnotations) ToolR
 	return RiskHigh // unknown tools default to high
 }
 
+// toolCallRisk is ToolRiskWithAnnotations refined by the call's input: a
+// Config call that writes a setting changes how the session runs, so it is
+// gated like a file edit rather than allowed like a read.
+func toolCallRisk(toolName string, input map[string]any, annotations *MCPAnnotations) ToolRiskLevel {
+	if toolName == "Config" {
+		if _, write := input["value"]; write {
+			return RiskMedium
+		}
+	}
+	return ToolRiskWithAnnotations(toolName, annotations)
+}
+
 // DefaultBehaviorForTool returns the default permission behavior for a tool
 // given the current permission mode, based on the mode behavior matrix.
 // If annotations are provided, they are used for MCP tool risk assessment.
 func DefaultBehaviorForTool(mode types.PermissionMode, toolName string, annotations *MCPAnnotations) PermissionBehavior {
-	risk := ToolRiskWithAnnotations(toolName, annotations)
+	return behaviorForRisk(mode, toolName, ToolRiskWithAnnotations(toolName, annotations))
+}
 
+// behaviorForRisk applies the mode behavior matrix to a risk level.
+func behaviorForRisk(mode types.PermissionMode, toolName string, risk ToolRiskLevel) PermissionBehavior {
 	switch mode {
 	case types.PermissionModeDefault:
 		if risk <= RiskLow {
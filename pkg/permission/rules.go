@@ -6,12 +6,13 @@ import (
 	"sync"
 
 	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
 // matchRuleContent checks if a rule's content pattern matches the tool input.
 // Uses tool-specific field matching with substring and glob patterns.
-func matchRuleContent(ruleContent string, toolName string, input map[string]any) bool {
+func matchRuleContent(ruleContent string, toolName string, input map[string]any, cwd string) bool {
 	if ruleContent == "" {
 		return true
 	}
@@ -25,6 +26,15 @@ func matchRuleContent(ruleContent string, toolName string, input map[string]any)
 		return matchField(ruleContent, input, "command")
 	case "Write", "FileWrite", "Edit", "FileEdit":
 		return matchField(ruleContent, input, "file_path")
+	case "ApplyPatch":
+		// Match against every file the patch touches
+		patch, _ := input["patch"].(string)
+		for _, path := range tools.PatchFilePaths(patch, cwd) {
+			if matchPattern(ruleContent, path) {
+				return true
+			}
+		}
+		return false
 	case "Glob":
 		return matchField(ruleContent, input, "pattern") || matchField(ruleContent, input, "path")
 	case "Grep":
@@ -60,6 +60,26 @@ func TestRule_GlobMatch_FilePath(t *testing.T) {
 	}
 }
 
+func TestRule_GlobMatch_ApplyPatchPaths(t *testing.T) {
+	rule := PermissionRule{ToolName: "ApplyPatch", RuleContent: "/src/**", Behavior: BehaviorDeny}
+	patch := func(path string) map[string]any {
+		return map[string]any{"patch": "--- " + path + "\n+++ " + path + "\n@@ -1 +1 @@\n-a\n+b\n"}
+	}
+
+	if !rule.Matches("ApplyPatch", patch("/src/main.go")) {
+		t.Error("expected glob match on a file in the patch")
+	}
+	if rule.Matches("ApplyPatch", patch("/tmp/out.go")) {
+		t.Error("expected no match for /tmp path")
+	}
+
+	// Relative paths in the patch resolve against the checker's CWD.
+	c := NewChecker(CheckerConfig{Rules: []PermissionRule{rule}, CWD: "/src"})
+	if result, _ := c.Check(context.Background(), "ApplyPatch", patch("main.go")); result.Behavior != "deny" {
+		t.Errorf("relative patch path: behavior = %q, want deny", result.Behavior)
+	}
+}
+
 func TestRule_GlobMatch_SingleStar(t *testing.T) {
 	rule := PermissionRule{ToolName: "Edit", RuleContent: "*.go", Behavior: BehaviorAllow}
 
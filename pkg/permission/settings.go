@@ -0,0 +1,367 @@
+package permission
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// SettingsPermissions is the "permissions" block of a settings.json file.
+// Entries use the Tool(arg-pattern) syntax: "Bash(git commit:*)",
+// "Read(./src/**)", "WebFetch(domain:example.com)", "mcp__github".
+type SettingsPermissions struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+	Ask   []string `json:"ask"`
+}
+
+// fileToolFamilies lists the tools a Read(...) or Edit(...) entry covers.
+var fileToolFamilies = map[string][]string{
+	"Read": {"Read", "Glob", "Grep"},
+	"Edit": {"Edit", "Write", "NotebookEdit", "ApplyPatch"},
+}
+
+// LoadPermissionsFromSettings reads the permissions block of a settings.json
+// file and returns a checker for it. Relative path patterns resolve against
+// the project root: the directory holding the .claude directory, or the
+// settings file's own directory otherwise. Calls no entry matches are asked.
+func LoadPermissionsFromSettings(path string) (*agent.RuleBasedPermissionChecker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading settings: %w", err)
+	}
+	var settings struct {
+		Permissions SettingsPermissions `json:"permissions"`
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("parsing settings %s: %w", path, err)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	root := filepath.Dir(abs)
+	if filepath.Base(root) == ".claude" {
+		root = filepath.Dir(root)
+	}
+
+	rules, err := CompileSettingsRules(settings.Permissions, root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &agent.RuleBasedPermissionChecker{Rules: rules, Default: string(BehaviorAsk)}, nil
+}
+
+// CompileSettingsRules compiles settings entries into ordered rules. Deny
+// entries come first, then ask, then allow, so a deny wins over an allow
+// for the same call regardless of how specific either is.
+func CompileSettingsRules(perms SettingsPermissions, root string) ([]agent.PermissionRule, error) {
+	var rules []agent.PermissionRule
+	for _, group := range []struct {
+		entries  []string
+		behavior PermissionBehavior
+	}{
+		{perms.Deny, BehaviorDeny},
+		{perms.Ask, BehaviorAsk},
+		{perms.Allow, BehaviorAllow},
+	} {
+		for _, entry := range group.entries {
+			compiled, err := ParsePermissionEntry(entry, group.behavior, root)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, compiled...)
+		}
+	}
+	return rules, nil
+}
+
+// ParsePermissionEntry compiles one entry into rules with the given behavior.
+// A bare name matches every call of the tool; "mcp__server" matches every
+// tool of that server. Inside the parentheses:
+//   - Bash: "prefix:*" matches the command or the command followed by
+//     arguments, "*" is a wildcard, anything else must match exactly. Compound
+//     commands (&&, ||, ;, |) must match in every part to be allowed, and are
+//     denied or asked if any part matches.
+//   - Read/Edit (and the tools they cover): gitignore-style path globs, where
+//     "//" is absolute, "~/" is the home directory, and "/", "./" or a bare
+//     path is relative to root.
+//   - WebFetch: "domain:host" matches the host and its subdomains.
+//   - Other tools: a substring or glob over the string arguments.
+func ParsePermissionEntry(entry string, behavior PermissionBehavior, root string) ([]agent.PermissionRule, error) {
+	name, content, err := splitPermissionEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	toolNames := []string{name}
+	if family, ok := fileToolFamilies[name]; ok {
+		toolNames = family
+	} else if strings.HasPrefix(name, "mcp__") && strings.Count(name, "__") == 1 {
+		toolNames = []string{name + "__*"}
+	}
+
+	var match func(toolName string, input map[string]any) bool
+	switch {
+	case content == "":
+	case name == "Bash":
+		match = bashCommandMatcher(content, behavior)
+	case fileToolFamilies[name] != nil:
+		match = filePathMatcher(content, root)
+	case name == "WebFetch" && strings.HasPrefix(content, "domain:"):
+		match = domainMatcher(strings.TrimPrefix(content, "domain:"))
+	default:
+		match = func(toolName string, input map[string]any) bool {
+			return matchRuleContent(content, toolName, input, root)
+		}
+	}
+
+	msg := ""
+	switch behavior {
+	case BehaviorDeny:
+		msg = "denied by permission rule " + entry
+	case BehaviorAsk:
+		msg = "matches ask rule " + entry
+	}
+
+	rules := make([]agent.PermissionRule, 0, len(toolNames))
+	for _, toolName := range toolNames {
+		rule := agent.PermissionRule{Tool: toolName, Behavior: string(behavior), Message: msg}
+		if match != nil {
+			tn := toolName
+			rule.Match = func(input map[string]any) bool { return match(tn, input) }
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// splitPermissionEntry splits "Tool(content)" into its tool name and
+// content, dropping quotes around the whole content.
+func splitPermissionEntry(entry string) (name, content string, err error) {
+	entry = strings.TrimSpace(entry)
+	open := strings.IndexByte(entry, '(')
+	if open < 0 {
+		name = entry
+	} else {
+		if !strings.HasSuffix(entry, ")") {
+			return "", "", fmt.Errorf("permission entry %q: missing closing parenthesis", entry)
+		}
+		name = strings.TrimSpace(entry[:open])
+		content = strings.TrimSpace(entry[open+1 : len(entry)-1])
+		if len(content) >= 2 && (content[0] == '"' || content[0] == '\'') && content[len(content)-1] == content[0] {
+			content = content[1 : len(content)-1]
+		}
+	}
+	if name == "" || strings.ContainsAny(name, " ()") {
+		return "", "", fmt.Errorf("permission entry %q: invalid tool name", entry)
+	}
+	if content == "*" {
+		content = "" // Tool(*) is the same as a bare Tool
+	}
+	return name, content, nil
+}
+
+// bashCommandMatcher matches the command input against a Bash pattern.
+func bashCommandMatcher(pattern string, behavior PermissionBehavior) func(string, map[string]any) bool {
+	matchOne := func(cmd string) bool {
+		switch {
+		case strings.HasSuffix(pattern, ":*"):
+			prefix := strings.TrimSuffix(pattern, ":*")
+			return cmd == prefix || strings.HasPrefix(cmd, prefix+" ")
+		case strings.Contains(pattern, "*"):
+			return wildcardMatch(pattern, cmd)
+		default:
+			return cmd == pattern
+		}
+	}
+
+	return func(_ string, input map[string]any) bool {
+		cmd, ok := input["command"].(string)
+		if !ok {
+			return false
+		}
+		cmd = strings.TrimSpace(cmd)
+		parts, substitution := splitShellCommand(cmd)
+		if behavior != BehaviorAllow {
+			if matchOne(cmd) {
+				return true
+			}
+			for _, part := range parts {
+				if matchOne(part) {
+					return true
+				}
+			}
+			return false
+		}
+		// Command substitution can run anything, so no allow rule covers it.
+		if substitution || len(parts) == 0 {
+			return false
+		}
+		for _, part := range parts {
+			if !matchOne(part) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// wildcardMatch matches value against pattern where each "*" matches any run
+// of characters, including spaces and slashes.
+func wildcardMatch(pattern, value string) bool {
+	pieces := strings.Split(pattern, "*")
+	if !strings.HasPrefix(value, pieces[0]) {
+		return false
+	}
+	value = value[len(pieces[0]):]
+	for i, piece := range pieces[1:] {
+		if i == len(pieces)-2 {
+			return strings.HasSuffix(value, piece)
+		}
+		idx := strings.Index(value, piece)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(piece):]
+	}
+	return true
+}
+
+// splitShellCommand splits cmd on the control operators &&, ||, ;, |, |&, a
+// background & and newlines outside quotes, and reports whether it uses
+// command substitution ($(...) or backticks) outside single quotes or
+// process substitution (<(...), >(...)) outside quotes. The & of the
+// redirections >&, <& and &> is not a separator.
+func splitShellCommand(cmd string) (parts []string, substitution bool) {
+	var cur strings.Builder
+	flush := func() {
+		if part := strings.TrimSpace(cur.String()); part != "" {
+			parts = append(parts, part)
+		}
+		cur.Reset()
+	}
+
+	var quote byte
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if quote == '"' && (c == '`' || c == '$' && i+1 < len(cmd) && cmd[i+1] == '(') {
+				substitution = true
+			}
+			cur.WriteByte(c)
+		case c == '\'' || c == '"':
+			quote = c
+			cur.WriteByte(c)
+		case c == '\\' && i+1 < len(cmd):
+			cur.WriteByte(c)
+			cur.WriteByte(cmd[i+1])
+			i++
+		case c == '`' || (c == '$' || c == '<' || c == '>') && i+1 < len(cmd) && cmd[i+1] == '(':
+			substitution = true
+			cur.WriteByte(c)
+		case c == '&' && (i > 0 && (cmd[i-1] == '>' || cmd[i-1] == '<') || i+1 < len(cmd) && cmd[i+1] == '>'):
+			cur.WriteByte(c) // redirection: 2>&1, <&3, &>file
+		case c == ';' || c == '\n' || c == '|' || c == '&':
+			flush()
+			if i+1 < len(cmd) && (c == '|' || c == '&') && (cmd[i+1] == c || cmd[i+1] == '&') {
+				i++
+			}
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return parts, substitution
+}
+
+// filePathMatcher matches the paths a file tool call touches against a
+// gitignore-style pattern.
+func filePathMatcher(pattern, root string) func(string, map[string]any) bool {
+	glob := resolvePathPattern(pattern, root)
+	return func(toolName string, input map[string]any) bool {
+		for _, p := range toolCallPaths(toolName, input, root) {
+			if ok, _ := doublestar.Match(glob, p); ok {
+				return true
+			}
+			// A directory pattern also covers everything under it.
+			if ok, _ := doublestar.Match(glob+"/**", p); ok {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// resolvePathPattern turns a settings path pattern into an absolute glob.
+func resolvePathPattern(pattern, root string) string {
+	switch {
+	case strings.HasPrefix(pattern, "//"):
+		return filepath.ToSlash(filepath.Clean(pattern[1:]))
+	case strings.HasPrefix(pattern, "~/"):
+		home, _ := os.UserHomeDir()
+		return filepath.ToSlash(filepath.Join(home, pattern[2:]))
+	case strings.HasPrefix(pattern, "/"), strings.HasPrefix(pattern, "./"):
+		return filepath.ToSlash(filepath.Join(root, pattern))
+	case !strings.Contains(pattern, "/"):
+		// Like .gitignore, a bare name matches at any depth.
+		return filepath.ToSlash(filepath.Join(root, "**", pattern))
+	default:
+		return filepath.ToSlash(filepath.Join(root, pattern))
+	}
+}
+
+// toolCallPaths returns the absolute paths a file tool call reads or writes.
+func toolCallPaths(toolName string, input map[string]any, root string) []string {
+	var paths []string
+	switch toolName {
+	case "ApplyPatch":
+		patch, _ := input["patch"].(string)
+		paths = tools.PatchFilePaths(patch, root)
+	case "Glob", "Grep":
+		p, _ := input["path"].(string)
+		if p == "" {
+			p = root
+		}
+		paths = []string{p}
+	default:
+		for _, key := range []string{"file_path", "notebook_path", "path"} {
+			if p, ok := input[key].(string); ok && p != "" {
+				paths = append(paths, p)
+				break
+			}
+		}
+	}
+	for i, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		paths[i] = filepath.ToSlash(filepath.Clean(p))
+	}
+	return paths
+}
+
+// domainMatcher matches a WebFetch url whose host is domain or a subdomain of it.
+func domainMatcher(domain string) func(string, map[string]any) bool {
+	domain = strings.ToLower(domain)
+	return func(_ string, input map[string]any) bool {
+		raw, _ := input["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil {
+			return false
+		}
+		host := strings.ToLower(u.Hostname())
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+}
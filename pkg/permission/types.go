@@ -58,13 +58,18 @@ type PermissionRule struct {
 // Exact tool name match required. If RuleContent is empty, matches all invocations.
 // If RuleContent is non-empty, rule matching is delegated to matchRuleContent (Phase 3).
 func (r *PermissionRule) Matches(toolName string, input map[string]any) bool {
+	return r.matchesIn(toolName, input, "")
+}
+
+// matchesIn is Matches with relative paths in the input resolved against cwd.
+func (r *PermissionRule) matchesIn(toolName string, input map[string]any, cwd string) bool {
 	if r.ToolName != toolName {
 		return false
 	}
 	if r.RuleContent == "" {
 		return true // matches all invocations of this tool
 	}
-	return matchRuleContent(r.RuleContent, toolName, input)
+	return matchRuleContent(r.RuleContent, toolName, input, cwd)
 }
 
 // CheckerConfig holds all configuration for constructing a Checker.
@@ -79,4 +84,5 @@ type CheckerConfig struct {
 	CanUseTool                      types.CanUseToolFunc
 	UserPrompter                    UserPrompter
 	ToolAnnotationLookup            func(string) *MCPAnnotations // optional: resolves MCP annotations for a tool name
+	CWD                             string                       // working directory relative tool paths resolve against
 }
@@ -2,6 +2,7 @@ package prompt
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/jg-phare/goat/pkg/agent"
@@ -99,6 +100,16 @@ func (a *Assembler) Assemble(config *agent.AgentConfig) string {
 		parts = append(parts, formatClaudeMDSection(config.ClaudeMDContent))
 	}
 
+	// 15a. Project memory files (conditional: ProjectMemoryFiles found from CWD).
+	// CLAUDE.md is skipped when the host pre-loaded it into ClaudeMDContent.
+	memoryFiles := config.ProjectMemoryFiles
+	if config.ClaudeMDContent != "" {
+		memoryFiles = slices.DeleteFunc(slices.Clone(memoryFiles), func(name string) bool { return name == "CLAUDE.md" })
+	}
+	if memory := LoadProjectMemory(config.CWD, memoryFiles); memory != "" {
+		parts = append(parts, formatProjectMemorySection(memory))
+	}
+
 	// 15b. Rules injection (conditional: rules loaded)
 	if rulesContent := formatRulesSection(config.ProjectRules, config.UserRules, config.ActiveFilePaths); rulesContent != "" {
 		parts = append(parts, rulesContent)
@@ -344,6 +355,11 @@ func formatClaudeMDSection(content string) string {
 	return "# CLAUDE.md\n\nCLAUDE.md is a file that the user places in the project root to provide instructions to Claude. Below are the combined contents of all CLAUDE.md files found in the project directory hierarchy.\n\n" + content
 }
 
+// formatProjectMemorySection wraps project memory file contents in a section header.
+func formatProjectMemorySection(content string) string {
+	return "# Project memory\n\nThe following project memory files hold the conventions and instructions for this codebase. Follow them; where they conflict, files nearer the working directory take precedence.\n\n" + content
+}
+
 // formatOutputStyleSection wraps output style content in a section header.
 func formatOutputStyleSection(style string) string {
 	return "# Output Style\n\n" + style
@@ -2,6 +2,8 @@ package prompt
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -131,6 +133,32 @@ func TestAssembler_WithClaudeMD(t *testing.T) {
 	mustContain(t, result, "Always run tests before committing.")
 }
 
+func TestAssembler_WithProjectMemory(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, ".git"), 0o755)
+	writeFile(t, filepath.Join(dir, "CLAUDE.md"), "from CLAUDE.md on disk")
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "from AGENTS.md on disk")
+
+	a := &Assembler{}
+	config := &agent.AgentConfig{
+		CWD:                dir,
+		ProjectMemoryFiles: []string{"CLAUDE.md", "AGENTS.md"},
+		PromptVersion:      "2.1.37",
+	}
+	result := a.Assemble(config)
+	mustContain(t, result, "# Project memory")
+	mustContain(t, result, "from CLAUDE.md on disk")
+	mustContain(t, result, "from AGENTS.md on disk")
+
+	// A pre-loaded CLAUDE.md is not loaded a second time.
+	config.ClaudeMDContent = "pre-loaded"
+	result = a.Assemble(config)
+	mustContain(t, result, "from AGENTS.md on disk")
+	if strings.Contains(result, "from CLAUDE.md on disk") {
+		t.Error("CLAUDE.md loaded despite ClaudeMDContent")
+	}
+}
+
 func TestAssembler_WithScratchpadDir(t *testing.T) {
 	a := &Assembler{}
 	config := &agent.AgentConfig{
@@ -1,12 +1,19 @@
 package prompt
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
+// maxProjectMemoryBytes bounds the combined project memory files, including
+// their @path imports, so a large include can't crowd out the conversation.
+const maxProjectMemoryBytes = 40_000
+
 // LoadClaudeMD discovers and loads CLAUDE.md files from the directory hierarchy.
 // It searches CWD and parent directories, returning the combined content
 // with files separated by "\n\n---\n\n".
@@ -44,6 +51,62 @@ func appendClaudeMDFiles(sections []string, dir string) []string {
 	return sections
 }
 
+// LoadProjectMemory loads the named project memory files (e.g. CLAUDE.md,
+// AGENTS.md) from cwd and each parent up to the repository root, the nearest
+// ancestor containing .git. Outside a repository only cwd is searched.
+// Files are ordered outermost first, so instructions nearer cwd come last,
+// and each is prefixed with its path. @path imports are resolved relative to
+// the importing file. The result is truncated at maxProjectMemoryBytes.
+// Returns "" if no files are found.
+func LoadProjectMemory(cwd string, names []string) string {
+	if cwd == "" || len(names) == 0 {
+		return ""
+	}
+
+	var sections []string
+	size := 0
+	for _, dir := range projectMemoryDirs(cwd) {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			loaded := appendIfExists(nil, path)
+			if len(loaded) == 0 {
+				continue
+			}
+			section := fmt.Sprintf("Contents of %s:\n\n%s", path, loaded[0])
+			if size+len(section) > maxProjectMemoryBytes {
+				cut := maxProjectMemoryBytes - size
+				for cut > 0 && !utf8.RuneStart(section[cut]) {
+					cut-- // don't split a multi-byte character
+				}
+				section = section[:cut]
+				sections = append(sections, section+fmt.Sprintf("\n\n[project memory truncated at %d bytes]", maxProjectMemoryBytes))
+				return strings.Join(sections, "\n\n")
+			}
+			sections = append(sections, section)
+			size += len(section)
+		}
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// projectMemoryDirs returns the directories from the repository root down to
+// cwd, or just cwd when it isn't inside a repository.
+func projectMemoryDirs(cwd string) []string {
+	dirs := []string{cwd}
+	for dir := cwd; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			slices.Reverse(dirs)
+			return dirs
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return []string{cwd}
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+}
+
 // LoadManagedPolicy loads the OS-level managed CLAUDE.md policy file.
 // Returns empty string if no managed policy exists or on error.
 //
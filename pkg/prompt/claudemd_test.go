@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestLoadClaudeMD_AtCWD(t *testing.T) {
@@ -185,6 +186,84 @@ func TestLoadManagedPolicy_NoFile(t *testing.T) {
 	_ = result
 }
 
+func TestLoadProjectMemory_WalksToRepoRoot(t *testing.T) {
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "CLAUDE.md"), "not in the repo")
+	root := filepath.Join(outside, "repo")
+	sub := filepath.Join(root, "services", "api")
+	os.MkdirAll(filepath.Join(root, ".git"), 0o755)
+	os.MkdirAll(sub, 0o755)
+	writeFile(t, filepath.Join(root, "CLAUDE.md"), "repo conventions")
+	writeFile(t, filepath.Join(sub, "AGENTS.md"), "api notes\n@docs/style.md")
+	os.MkdirAll(filepath.Join(sub, "docs"), 0o755)
+	writeFile(t, filepath.Join(sub, "docs", "style.md"), "use table tests")
+
+	result := LoadProjectMemory(sub, []string{"CLAUDE.md", "AGENTS.md"})
+
+	if strings.Contains(result, "not in the repo") {
+		t.Error("loaded a file above the repository root")
+	}
+	idxRoot := strings.Index(result, "repo conventions")
+	idxSub := strings.Index(result, "api notes")
+	if idxRoot < 0 || idxSub < 0 || idxRoot > idxSub {
+		t.Errorf("want root file before nested file, got %q", result)
+	}
+	if !strings.Contains(result, "use table tests") {
+		t.Errorf("@path import not resolved: %q", result)
+	}
+	if !strings.Contains(result, "Contents of "+filepath.Join(sub, "AGENTS.md")) {
+		t.Errorf("missing file path header: %q", result)
+	}
+}
+
+func TestLoadProjectMemory_OutsideRepo(t *testing.T) {
+	parent := t.TempDir()
+	writeFile(t, filepath.Join(parent, "AGENTS.md"), "parent notes")
+	cwd := filepath.Join(parent, "work")
+	os.MkdirAll(cwd, 0o755)
+	writeFile(t, filepath.Join(cwd, "AGENTS.md"), "work notes")
+
+	result := LoadProjectMemory(cwd, []string{"AGENTS.md"})
+	if !strings.Contains(result, "work notes") || strings.Contains(result, "parent notes") {
+		t.Errorf("outside a repo only cwd should be searched, got %q", result)
+	}
+	if LoadProjectMemory("", []string{"AGENTS.md"}) != "" || LoadProjectMemory(cwd, nil) != "" {
+		t.Error("expected no memory without a cwd or file names")
+	}
+}
+
+func TestLoadProjectMemory_Truncated(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, ".git"), 0o755)
+	writeFile(t, filepath.Join(dir, "CLAUDE.md"), "@big.md")
+	writeFile(t, filepath.Join(dir, "big.md"), strings.Repeat("x", maxProjectMemoryBytes))
+	writeFile(t, filepath.Join(dir, "AGENTS.md"), "never reached")
+
+	result := LoadProjectMemory(dir, []string{"CLAUDE.md", "AGENTS.md"})
+	if !strings.HasSuffix(result, "[project memory truncated at 40000 bytes]") {
+		t.Errorf("missing truncation marker: ...%q", result[len(result)-80:])
+	}
+	if strings.Contains(result, "never reached") {
+		t.Error("files past the size limit should not be loaded")
+	}
+}
+
+func TestLoadProjectMemory_TruncatedAtRuneBoundary(t *testing.T) {
+	// Shift the content so the limit falls on each byte of a 3-byte rune
+	for pad := range 3 {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "CLAUDE.md"), strings.Repeat("x", pad)+strings.Repeat("€", maxProjectMemoryBytes/3))
+
+		result := LoadProjectMemory(dir, []string{"CLAUDE.md"})
+		if !utf8.ValidString(result) {
+			t.Errorf("pad %d: truncated memory is not valid UTF-8", pad)
+		}
+		if !strings.HasSuffix(result, "[project memory truncated at 40000 bytes]") {
+			t.Errorf("pad %d: missing truncation marker", pad)
+		}
+	}
+}
+
 func writeFile(t *testing.T, path, content string) {
 	t.Helper()
 	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
@@ -0,0 +1,112 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/agent"
+)
+
+const (
+	gitContextDefaultStatusLines = 30
+	gitContextDefaultCommits     = 5
+	gitContextDefaultTimeout     = 5 * time.Second
+)
+
+// GitContext reads a repository's branch, short status and recent commit
+// subjects for the system prompt. It implements agent.GitContextProvider;
+// set it as AgentConfig.GitContextProvider to opt in. Output is bounded so a
+// repository with thousands of changes doesn't flood the prompt.
+type GitContext struct {
+	MaxStatusLines int           // changed paths listed (0 = default 30)
+	MaxCommits     int           // recent commits listed (0 = default 5)
+	Timeout        time.Duration // for all git commands together (0 = default 5s)
+}
+
+var _ agent.GitContextProvider = (*GitContext)(nil)
+
+func (g *GitContext) GitContext(ctx context.Context, cwd string) (agent.GitContext, bool, error) {
+	timeout := g.Timeout
+	if timeout <= 0 {
+		timeout = gitContextDefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if _, err := runGit(ctx, cwd, "rev-parse", "--git-dir"); err != nil {
+		return agent.GitContext{}, false, nil // not a repository
+	}
+
+	var info agent.GitContext
+	branch, err := runGit(ctx, cwd, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		// A repository with no commits yet has no HEAD to resolve
+		branch, err = runGit(ctx, cwd, "symbolic-ref", "--short", "HEAD")
+		if err != nil {
+			return agent.GitContext{}, true, err
+		}
+	}
+	if branch != "HEAD" { // detached
+		info.Branch = branch
+	}
+	info.MainBranch = gitMainBranch(ctx, cwd)
+
+	status, err := runGit(ctx, cwd, "status", "--short")
+	if err != nil {
+		return agent.GitContext{}, true, err
+	}
+	maxLines := g.MaxStatusLines
+	if maxLines <= 0 {
+		maxLines = gitContextDefaultStatusLines
+	}
+	info.Status = limitLines(status, maxLines, "changed files")
+
+	commits := g.MaxCommits
+	if commits <= 0 {
+		commits = gitContextDefaultCommits
+	}
+	// Fails harmlessly in a repository with no commits yet
+	info.RecentCommits, _ = runGit(ctx, cwd, "log", "-n", strconv.Itoa(commits), "--format=%h %s")
+	return info, true, nil
+}
+
+// gitMainBranch guesses the branch PRs target: the remote's default branch,
+// else a local main or master.
+func gitMainBranch(ctx context.Context, cwd string) string {
+	if ref, err := runGit(ctx, cwd, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/")
+	}
+	for _, name := range []string{"main", "master"} {
+		if _, err := runGit(ctx, cwd, "rev-parse", "--verify", "--quiet", "refs/heads/"+name); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// runGit runs a git command in dir and returns its trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\n"), nil
+}
+
+// limitLines keeps the first max lines of s, noting how many more there were.
+func limitLines(s string, max int, what string) string {
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	if len(lines) <= max {
+		return s
+	}
+	return strings.Join(lines[:max], "\n") + fmt.Sprintf("\n... and %d more %s", len(lines)-max, what)
+}
@@ -0,0 +1,121 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/agent"
+)
+
+// initGitRepo creates a repository on branch main with the given commits.
+func initGitRepo(t *testing.T, commits ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@example.com",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	for i, subject := range commits {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte(subject), 0o644)
+		git("add", "-A")
+		git("commit", "-q", "-m", subject)
+	}
+	return dir
+}
+
+func TestGitContext(t *testing.T) {
+	dir := initGitRepo(t, "first commit", "second commit", "third commit")
+	for i := range 8 {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("new%d.txt", i)), nil, 0o644)
+	}
+
+	g := &GitContext{MaxStatusLines: 3, MaxCommits: 2}
+	info, ok, err := g.GitContext(context.Background(), dir)
+	if err != nil || !ok {
+		t.Fatalf("GitContext() ok=%v err=%v", ok, err)
+	}
+	if info.Branch != "main" || info.MainBranch != "main" {
+		t.Errorf("branch = %q, main branch = %q", info.Branch, info.MainBranch)
+	}
+	status := strings.Split(info.Status, "\n")
+	if len(status) != 4 || status[0] != "?? new0.txt" || status[3] != "... and 5 more changed files" {
+		t.Errorf("status not bounded: %q", info.Status)
+	}
+	commits := strings.Split(info.RecentCommits, "\n")
+	if len(commits) != 2 || !strings.HasSuffix(commits[0], " third commit") || !strings.HasSuffix(commits[1], " second commit") {
+		t.Errorf("recent commits = %q", info.RecentCommits)
+	}
+}
+
+func TestGitContext_EmptyRepo(t *testing.T) {
+	dir := initGitRepo(t)
+	info, ok, err := (&GitContext{}).GitContext(context.Background(), dir)
+	if err != nil || !ok {
+		t.Fatalf("GitContext() ok=%v err=%v", ok, err)
+	}
+	if info.Branch != "main" || info.Status != "" || info.RecentCommits != "" {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestGitContext_DetachedHead(t *testing.T) {
+	dir := initGitRepo(t, "first commit", "second commit")
+	cmd := exec.Command("git", "checkout", "-q", "--detach", "HEAD~1")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git checkout: %v\n%s", err, out)
+	}
+
+	info, ok, err := (&GitContext{}).GitContext(context.Background(), dir)
+	if err != nil || !ok {
+		t.Fatalf("GitContext() ok=%v err=%v", ok, err)
+	}
+	if info.Branch != "" || info.MainBranch != "main" {
+		t.Errorf("branch = %q, main branch = %q; want no branch on a detached HEAD", info.Branch, info.MainBranch)
+	}
+}
+
+func TestGitContext_NotARepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	if _, ok, err := (&GitContext{}).GitContext(context.Background(), dir); ok || err != nil {
+		t.Errorf("GitContext() outside a repository: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestAssembler_GitContextSection(t *testing.T) {
+	dir := initGitRepo(t, "add feature")
+	info, _, err := (&GitContext{}).GitContext(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &agent.AgentConfig{
+		PromptVersion:    "2.1.37",
+		GitBranch:        info.Branch,
+		GitMainBranch:    info.MainBranch,
+		GitStatus:        info.Status,
+		GitRecentCommits: info.RecentCommits,
+	}
+	result := (&Assembler{}).Assemble(config)
+	mustContain(t, result, "Current branch: main")
+	mustContain(t, result, "(clean)")
+	mustContain(t, result, " add feature")
+}
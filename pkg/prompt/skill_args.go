@@ -1,7 +1,12 @@
 package prompt
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/jg-phare/goat/pkg/types"
 )
 
 // SubstituteArgs replaces $argName placeholders in a skill body with provided arguments.
@@ -74,3 +79,131 @@ func splitArgs(s string, n int) []string {
 	}
 	return tokens
 }
+
+// ArgsError reports skill arguments that failed schema validation.
+type ArgsError struct {
+	Missing []string // required arguments with no value
+	Invalid []string // "name: reason" for values that don't match their type
+	Usage   string   // the expected arguments, e.g. "<env> [count=1]"
+}
+
+func (e *ArgsError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing required "+strings.Join(e.Missing, ", "))
+	}
+	parts = append(parts, e.Invalid...)
+	return fmt.Sprintf("%s (expected: %s)", strings.Join(parts, "; "), e.Usage)
+}
+
+// SubstituteTypedArgs validates argsStr against a skill's args: schema and
+// substitutes the values into body. Values are assigned positionally as in
+// SubstituteArgs. Omitted arguments take their default, or the empty string
+// if optional, so no $name or ${name} placeholder survives. Missing required
+// and mistyped arguments are reported together as an *ArgsError.
+func SubstituteTypedArgs(body string, schema []types.SkillArgument, argsStr string) (string, error) {
+	if len(schema) == 0 {
+		return body, nil
+	}
+
+	values := splitArgs(strings.TrimSpace(argsStr), len(schema))
+	argErr := &ArgsError{Usage: argsUsage(schema)}
+	bound := make(map[string]string, len(schema))
+	for i, arg := range schema {
+		if i >= len(values) {
+			if arg.Required {
+				argErr.Missing = append(argErr.Missing, arg.Name)
+			}
+			bound[arg.Name] = arg.Default
+			continue
+		}
+		if err := checkArgType(arg.Type, values[i]); err != nil {
+			argErr.Invalid = append(argErr.Invalid, fmt.Sprintf("%s: %v", arg.Name, err))
+		}
+		bound[arg.Name] = values[i]
+	}
+	if len(argErr.Missing) > 0 || len(argErr.Invalid) > 0 {
+		return "", argErr
+	}
+
+	// Replace longer names first so $target doesn't clobber $target_dir.
+	names := make([]string, 0, len(bound))
+	for name := range bound {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	result := body
+	for _, name := range names {
+		result = strings.ReplaceAll(result, "${"+name+"}", bound[name])
+		result = strings.ReplaceAll(result, "$"+name, bound[name])
+	}
+	return result, nil
+}
+
+// validateArgSchema checks an args: schema from skill frontmatter.
+func validateArgSchema(schema []types.SkillArgument) error {
+	seen := make(map[string]bool, len(schema))
+	for i, arg := range schema {
+		if strings.TrimSpace(arg.Name) == "" {
+			return fmt.Errorf("empty argument name at index %d", i)
+		}
+		if seen[arg.Name] {
+			return fmt.Errorf("duplicate argument %q", arg.Name)
+		}
+		seen[arg.Name] = true
+		switch arg.Type {
+		case "", "string", "number", "integer", "boolean":
+		default:
+			return fmt.Errorf("argument %q has unknown type %q; must be string, number, integer or boolean", arg.Name, arg.Type)
+		}
+		if arg.Required && arg.Default != "" {
+			return fmt.Errorf("argument %q is required and cannot have a default", arg.Name)
+		}
+		if arg.Default != "" {
+			if err := checkArgType(arg.Type, arg.Default); err != nil {
+				return fmt.Errorf("default for argument %q: %w", arg.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkArgType reports whether value parses as the schema type.
+func checkArgType(typ, value string) error {
+	var err error
+	switch typ {
+	case "number":
+		_, err = strconv.ParseFloat(value, 64)
+	case "integer":
+		_, err = strconv.ParseInt(value, 10, 64)
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("must be %s %s, got %q", article(typ), typ, value)
+	}
+	return nil
+}
+
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// argsUsage renders a schema as "<required> [optional] [with=default]".
+func argsUsage(schema []types.SkillArgument) string {
+	parts := make([]string, len(schema))
+	for i, arg := range schema {
+		switch {
+		case arg.Required:
+			parts[i] = "<" + arg.Name + ">"
+		case arg.Default != "":
+			parts[i] = "[" + arg.Name + "=" + arg.Default + "]"
+		default:
+			parts[i] = "[" + arg.Name + "]"
+		}
+	}
+	return strings.Join(parts, " ")
+}
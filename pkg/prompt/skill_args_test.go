@@ -1,7 +1,11 @@
 package prompt
 
 import (
+	"errors"
+	"strings"
 	"testing"
+
+	"github.com/jg-phare/goat/pkg/types"
 )
 
 func TestSubstituteArgs_Single(t *testing.T) {
@@ -119,3 +123,82 @@ func TestSplitArgs(t *testing.T) {
 		}
 	}
 }
+
+var deploySchema = []types.SkillArgument{
+	{Name: "env", Required: true},
+	{Name: "replicas", Type: "integer", Default: "2"},
+	{Name: "notes"},
+}
+
+func TestSubstituteTypedArgs_AppliesDefaults(t *testing.T) {
+	body := "Deploy to ${env} with $replicas replicas. $notes"
+	result, err := SubstituteTypedArgs(body, deploySchema, "staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "Deploy to staging with 2 replicas. " {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestSubstituteTypedArgs_AllProvided(t *testing.T) {
+	body := "Deploy to $env with ${replicas} replicas: $notes"
+	result, err := SubstituteTypedArgs(body, deploySchema, "prod 5 roll out slowly")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "Deploy to prod with 5 replicas: roll out slowly" {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestSubstituteTypedArgs_LongerNameFirst(t *testing.T) {
+	schema := []types.SkillArgument{{Name: "dir"}, {Name: "dir_mode"}}
+	result, err := SubstituteTypedArgs("$dir $dir_mode", schema, "src 0755")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != "src 0755" {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestSubstituteTypedArgs_Errors(t *testing.T) {
+	_, err := SubstituteTypedArgs("$env", deploySchema, "")
+	var argErr *ArgsError
+	if !errors.As(err, &argErr) || len(argErr.Missing) != 1 || argErr.Missing[0] != "env" {
+		t.Fatalf("err = %v, want env missing", err)
+	}
+	if !strings.Contains(err.Error(), "<env> [replicas=2] [notes]") {
+		t.Errorf("error %q should show the expected arguments", err)
+	}
+
+	_, err = SubstituteTypedArgs("$env", deploySchema, "prod many")
+	if !errors.As(err, &argErr) || len(argErr.Invalid) != 1 {
+		t.Fatalf("err = %v, want replicas invalid", err)
+	}
+	if !strings.Contains(err.Error(), `replicas: must be an integer, got "many"`) {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestCheckArgType(t *testing.T) {
+	tests := []struct {
+		typ, value string
+		ok         bool
+	}{
+		{"", "anything", true},
+		{"string", "anything", true},
+		{"number", "1.5", true},
+		{"number", "x", false},
+		{"integer", "-3", true},
+		{"integer", "1.5", false},
+		{"boolean", "true", true},
+		{"boolean", "yes", false},
+	}
+	for _, tt := range tests {
+		if err := checkArgType(tt.typ, tt.value); (err == nil) != tt.ok {
+			t.Errorf("checkArgType(%q, %q) = %v, want ok=%v", tt.typ, tt.value, err, tt.ok)
+		}
+	}
+}
@@ -12,13 +12,14 @@ import (
 
 // skillFrontmatter represents the YAML frontmatter fields in a SKILL.md file.
 type skillFrontmatter struct {
-	Name         string   `yaml:"name"`
-	Description  string   `yaml:"description"`
-	AllowedTools []string `yaml:"allowed-tools"`
-	WhenToUse    string   `yaml:"when_to_use"`
-	ArgumentHint string   `yaml:"argument-hint"`
-	Arguments    []string `yaml:"arguments"`
-	Context      string   `yaml:"context"`
+	Name         string                `yaml:"name"`
+	Description  string                `yaml:"description"`
+	AllowedTools []string              `yaml:"allowed-tools"`
+	WhenToUse    string                `yaml:"when_to_use"`
+	ArgumentHint string                `yaml:"argument-hint"`
+	Arguments    []string              `yaml:"arguments"`
+	Args         []types.SkillArgument `yaml:"args"`
+	Context      string                `yaml:"context"`
 }
 
 // knownSkillKeys is the set of valid YAML field names in skill frontmatter.
@@ -29,6 +30,7 @@ var knownSkillKeys = map[string]bool{
 	"when_to_use":   true,
 	"argument-hint": true,
 	"arguments":     true,
+	"args":          true,
 	"context":       true,
 }
 
@@ -79,6 +81,19 @@ func ParseSkillContent(data []byte, filePath string) (*types.SkillEntry, error)
 		}
 	}
 
+	// Validate the typed argument schema
+	if len(fm.Args) > 0 {
+		if len(fm.Arguments) > 0 {
+			return nil, fmt.Errorf("both 'arguments' and 'args' set in %s; use one", filePath)
+		}
+		if err := validateArgSchema(fm.Args); err != nil {
+			return nil, fmt.Errorf("invalid args in %s: %w", filePath, err)
+		}
+		for _, arg := range fm.Args {
+			fm.Arguments = append(fm.Arguments, arg.Name)
+		}
+	}
+
 	entry := &types.SkillEntry{
 		SkillDefinition: types.SkillDefinition{
 			Name:         fm.Name,
@@ -87,6 +102,7 @@ func ParseSkillContent(data []byte, filePath string) (*types.SkillEntry, error)
 			WhenToUse:    fm.WhenToUse,
 			ArgumentHint: fm.ArgumentHint,
 			Arguments:    fm.Arguments,
+			Args:         fm.Args,
 			Context:      fm.Context,
 			Body:         strings.TrimSpace(body),
 		},
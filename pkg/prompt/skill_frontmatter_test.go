@@ -160,6 +160,53 @@ Body text.
 	}
 }
 
+func TestParseSkillContent_ArgsSchema(t *testing.T) {
+	data := []byte(`---
+name: scale
+description: Scale a service
+args:
+  - name: service
+    required: true
+  - name: replicas
+    type: integer
+    default: 3
+---
+Scale ${service} to $replicas.
+`)
+
+	entry, err := ParseSkillContent(data, "/test/SKILL.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []types.SkillArgument{
+		{Name: "service", Required: true},
+		{Name: "replicas", Type: "integer", Default: "3"},
+	}
+	if len(entry.Args) != len(want) || entry.Args[0] != want[0] || entry.Args[1] != want[1] {
+		t.Errorf("Args = %+v, want %+v", entry.Args, want)
+	}
+	if len(entry.Arguments) != 2 || entry.Arguments[0] != "service" || entry.Arguments[1] != "replicas" {
+		t.Errorf("Arguments = %v, want the schema names", entry.Arguments)
+	}
+}
+
+func TestParseSkillContent_InvalidArgsSchema(t *testing.T) {
+	tests := map[string]string{
+		"unknown type":       "args:\n  - name: n\n    type: float\n",
+		"duplicate name":     "args:\n  - name: n\n  - name: n\n",
+		"empty name":         "args:\n  - type: string\n",
+		"required default":   "args:\n  - name: n\n    required: true\n    default: x\n",
+		"mistyped default":   "args:\n  - name: n\n    type: boolean\n    default: maybe\n",
+		"both argument keys": "arguments:\n  - n\nargs:\n  - name: n\n",
+	}
+	for name, args := range tests {
+		data := []byte("---\ndescription: d\n" + args + "---\nBody.\n")
+		if _, err := ParseSkillContent(data, "/test/SKILL.md"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
 func TestParseSkillContent_BodyExtraction(t *testing.T) {
 	data := []byte(`---
 name: body-test
@@ -0,0 +1,276 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/agent"
+)
+
+// TemplateContext is the data a TemplateAssembler template is rendered with,
+// e.g. "You are working in {{.CWD}} on branch {{.GitBranch}}".
+type TemplateContext struct {
+	CWD       string
+	OS        string
+	OSVersion string
+	Shell     string
+	Date      string // YYYY-MM-DD
+	Model     string
+
+	// Git state: from AgentConfig when set, otherwise the branch is read
+	// from the repository at CWD on each run.
+	GitBranch        string
+	GitMainBranch    string
+	GitStatus        string
+	GitRecentCommits string
+
+	Tools []string // enabled tool names, sorted
+
+	// Vars holds the variables passed to NewTemplateAssembler, for values
+	// that differ per environment ({{.Vars.TEAM}}).
+	Vars map[string]string
+}
+
+// HasTool reports whether the named tool is enabled: {{if .HasTool "Bash"}}.
+func (c TemplateContext) HasTool(name string) bool {
+	return slices.Contains(c.Tools, name)
+}
+
+// TemplateAssembler renders the system prompt from a text/template on every
+// run. It implements agent.SystemPromptAssembler. A template that refers to a
+// field or variable that doesn't exist is rejected when it is loaded, rather
+// than rendering as empty text.
+type TemplateAssembler struct {
+	tmpl *template.Template
+	vars map[string]string
+}
+
+// NewTemplateAssembler parses text as a prompt template. vars are available
+// to it as {{.Vars.NAME}}.
+func NewTemplateAssembler(text string, vars map[string]string) (*TemplateAssembler, error) {
+	return newTemplateAssembler("system-prompt", text, vars)
+}
+
+// LoadTemplateAssembler is NewTemplateAssembler for a template file.
+func LoadTemplateAssembler(path string, vars map[string]string) (*TemplateAssembler, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading prompt template: %w", err)
+	}
+	return newTemplateAssembler(path, string(data), vars)
+}
+
+func newTemplateAssembler(name, text string, vars map[string]string) (*TemplateAssembler, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing prompt template: %w", err)
+	}
+	root := reflect.ValueOf(TemplateContext{Vars: vars})
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		c := &fieldChecker{tree: t.Tree, root: root}
+		if err := c.check(t.Tree.Root, root); err != nil {
+			return nil, fmt.Errorf("prompt template: %w", err)
+		}
+	}
+	return &TemplateAssembler{tmpl: tmpl, vars: vars}, nil
+}
+
+// Assemble renders the template for config, followed by SystemPrompt.Append.
+// SystemPrompt.Raw still overrides it. Should rendering fail anyway, the
+// built-in prompt is used rather than sending none.
+func (a *TemplateAssembler) Assemble(config *agent.AgentConfig) string {
+	if config.SystemPrompt.Raw != "" {
+		return config.SystemPrompt.Raw
+	}
+	var sb strings.Builder
+	if err := a.tmpl.Execute(&sb, a.context(config)); err != nil {
+		return (&Assembler{}).Assemble(config)
+	}
+	result := strings.TrimSpace(sb.String())
+	if config.SystemPrompt.Append != "" {
+		result += "\n\n" + config.SystemPrompt.Append
+	}
+	return result
+}
+
+func (a *TemplateAssembler) context(config *agent.AgentConfig) TemplateContext {
+	ctx := TemplateContext{
+		CWD:              config.CWD,
+		OS:               config.OS,
+		OSVersion:        config.OSVersion,
+		Shell:            config.Shell,
+		Date:             config.CurrentDate,
+		Model:            config.Model,
+		GitBranch:        config.GitBranch,
+		GitMainBranch:    config.GitMainBranch,
+		GitStatus:        config.GitStatus,
+		GitRecentCommits: config.GitRecentCommits,
+		Vars:             a.vars,
+	}
+	if ctx.Date == "" {
+		ctx.Date = time.Now().Format(time.DateOnly)
+	}
+	if ctx.GitBranch == "" && config.CWD != "" {
+		ctx.GitBranch = gitCurrentBranch(config.CWD)
+	}
+	if config.ToolRegistry != nil {
+		ctx.Tools = config.ToolRegistry.Names()
+	}
+	return ctx
+}
+
+// gitCurrentBranch returns the branch checked out at cwd, or "" outside a
+// repository or on a detached HEAD.
+func gitCurrentBranch(cwd string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), gitContextDefaultTimeout)
+	defer cancel()
+	branch, err := runGit(ctx, cwd, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
+
+// fieldChecker walks a parsed template checking that every field it names
+// exists, tracking what dot refers to inside range and with. Dot is a sample
+// value of that type, or invalid where the type can't be known (e.g. the
+// result of a method call), in which case its fields are not checked.
+type fieldChecker struct {
+	tree *parse.Tree
+	root reflect.Value // $
+}
+
+func (c *fieldChecker) check(node parse.Node, dot reflect.Value) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.check(child, dot); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return c.check(n.Pipe, dot)
+	case *parse.TemplateNode:
+		return c.check(n.Pipe, dot)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err := c.check(arg, dot); err != nil {
+					return err
+				}
+			}
+		}
+	case *parse.IfNode:
+		return c.checkBranch(&n.BranchNode, dot, dot)
+	case *parse.WithNode:
+		inner, err := c.pipeValue(n.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		return c.checkBranch(&n.BranchNode, inner, dot)
+	case *parse.RangeNode:
+		coll, err := c.pipeValue(n.Pipe, dot)
+		if err != nil {
+			return err
+		}
+		elem := reflect.Value{}
+		if coll.IsValid() {
+			switch coll.Kind() {
+			case reflect.Slice, reflect.Array, reflect.Map:
+				elem = reflect.Zero(coll.Type().Elem())
+			}
+		}
+		return c.checkBranch(&n.BranchNode, elem, dot)
+	case *parse.FieldNode, *parse.VariableNode:
+		_, err := c.resolve(n, dot)
+		return err
+	case *parse.ChainNode:
+		return c.check(n.Node, dot)
+	}
+	return nil
+}
+
+// checkBranch checks an if, with or range body with inner as dot; the else
+// branch keeps the enclosing dot.
+func (c *fieldChecker) checkBranch(n *parse.BranchNode, inner, dot reflect.Value) error {
+	if err := c.check(n.Pipe, dot); err != nil {
+		return err
+	}
+	if err := c.check(n.List, inner); err != nil {
+		return err
+	}
+	return c.check(n.ElseList, dot)
+}
+
+// pipeValue returns a sample of what a with or range pipeline yields, when
+// it is a plain field reference.
+func (c *fieldChecker) pipeValue(pipe *parse.PipeNode, dot reflect.Value) (reflect.Value, error) {
+	if err := c.check(pipe, dot); err != nil {
+		return reflect.Value{}, err
+	}
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return reflect.Value{}, nil
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode, *parse.VariableNode:
+		return c.resolve(arg, dot)
+	case *parse.DotNode:
+		return dot, nil
+	}
+	return reflect.Value{}, nil
+}
+
+// resolve follows a field chain (.A.B, or $.A.B from the root), failing on
+// the first name that doesn't exist. Other variables aren't followed.
+func (c *fieldChecker) resolve(node parse.Node, dot reflect.Value) (reflect.Value, error) {
+	var idents []string
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		idents = n.Ident
+	case *parse.VariableNode:
+		if n.Ident[0] != "$" {
+			return reflect.Value{}, nil
+		}
+		dot, idents = c.root, n.Ident[1:]
+	}
+	v := dot
+	for _, name := range idents {
+		if !v.IsValid() {
+			return v, nil
+		}
+		if reflect.New(v.Type()).MethodByName(name).IsValid() {
+			return reflect.Value{}, nil // method results aren't followed
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(name)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(name))
+		default:
+			v = reflect.Value{}
+		}
+		if !v.IsValid() {
+			location, _ := c.tree.ErrorContext(node)
+			return v, fmt.Errorf("%s: %s is not defined", location, node)
+		}
+	}
+	return v, nil
+}
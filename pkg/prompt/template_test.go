@@ -0,0 +1,116 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/tools"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+func TestTemplateAssembler_Render(t *testing.T) {
+	a, err := NewTemplateAssembler(`You are the {{.Vars.TEAM}} assistant, running {{.Model}} on {{.OS}}.
+Working directory: {{.CWD}} (branch {{.GitBranch}}), date {{.Date}}.
+Tools: {{join .Tools ", "}}
+{{if .HasTool "Bash"}}Prefer dedicated tools over Bash.{{end}}
+{{range .Tools}}- {{.}}
+{{end}}`, map[string]string{"TEAM": "payments"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := tools.NewRegistry()
+	registry.Register(&tools.BashTool{})
+	registry.Register(&tools.GlobTool{})
+	result := a.Assemble(&agent.AgentConfig{
+		Model:        "claude-sonnet-4-5-20250929",
+		OS:           "linux",
+		CWD:          "/work",
+		GitBranch:    "main",
+		CurrentDate:  "2026-02-09",
+		ToolRegistry: registry,
+		SystemPrompt: types.SystemPromptConfig{Append: "APPENDED"},
+	})
+
+	mustContain(t, result, "You are the payments assistant, running claude-sonnet-4-5-20250929 on linux.")
+	mustContain(t, result, "Working directory: /work (branch main), date 2026-02-09.")
+	mustContain(t, result, "Tools: Bash, Glob")
+	mustContain(t, result, "Prefer dedicated tools over Bash.")
+	mustContain(t, result, "- Glob\n")
+	if !strings.HasSuffix(result, "\n\nAPPENDED") {
+		t.Errorf("SystemPrompt.Append not appended: %q", result)
+	}
+}
+
+func TestTemplateAssembler_UnknownVariables(t *testing.T) {
+	vars := map[string]string{"TEAM": "payments"}
+	for _, text := range []string{
+		"{{.Branch}}",
+		"{{.Vars.REGION}}",
+		"{{if .GitStatus}}{{.Stauts}}{{end}}",      // branches that wouldn't render
+		"{{if not .CWD}}{{else}}{{.Modle}}{{end}}", // still fail
+		"{{range .Tools}}{{.Name}}{{end}}",         // dot is a tool name string
+		"{{with .Vars}}{{.REGION}}{{end}}",
+		"{{range .Tools}}{{$.Nope}}{{end}}",
+	} {
+		if _, err := NewTemplateAssembler(text, vars); err == nil {
+			t.Errorf("%q: loaded without error", text)
+		}
+	}
+
+	for _, text := range []string{
+		"{{.CWD}} {{.Vars.TEAM}} {{$.Model}}",
+		"{{range $i, $tool := .Tools}}{{$i}}={{$tool}}{{end}}",
+		"{{with .Vars}}{{.TEAM}}{{end}}",
+		"{{range .Tools}}{{$.GitBranch}}{{end}}",
+		`{{if .HasTool "Bash"}}bash{{end}}`,
+	} {
+		if _, err := NewTemplateAssembler(text, vars); err != nil {
+			t.Errorf("%q: %v", text, err)
+		}
+	}
+}
+
+func TestTemplateAssembler_UnknownVariableError(t *testing.T) {
+	_, err := NewTemplateAssembler("line one\n{{.Brnach}}", nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), ":2:") || !strings.Contains(err.Error(), ".Brnach") {
+		t.Errorf("error %q should name the variable and its line", err)
+	}
+}
+
+func TestLoadTemplateAssembler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	os.WriteFile(path, []byte("Deployed in {{.Vars.ENV}}."), 0o644)
+
+	a, err := LoadTemplateAssembler(path, map[string]string{"ENV": "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Assemble(&agent.AgentConfig{}); got != "Deployed in staging." {
+		t.Errorf("Assemble() = %q", got)
+	}
+
+	if _, err := LoadTemplateAssembler(path, nil); err == nil {
+		t.Error("expected error for template variable missing from vars")
+	}
+	if _, err := LoadTemplateAssembler(filepath.Join(t.TempDir(), "missing.tmpl"), nil); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestTemplateAssembler_RawOverride(t *testing.T) {
+	a, err := NewTemplateAssembler("templated", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &agent.AgentConfig{SystemPrompt: types.SystemPromptConfig{Raw: "raw prompt"}}
+	if got := a.Assemble(config); got != "raw prompt" {
+		t.Errorf("Assemble() = %q, want the Raw override", got)
+	}
+}
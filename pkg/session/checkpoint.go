@@ -4,9 +4,11 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/jg-phare/goat/pkg/agent"
@@ -25,6 +27,8 @@ type FileSnapshot struct {
 	Exists bool   `json:"exists"`
 	Hash   string `json:"hash,omitempty"`
 	Size   int    `json:"size,omitempty"`
+	// Mode holds the permission bits; zero in manifests written before modes were recorded.
+	Mode os.FileMode `json:"mode,omitempty"`
 }
 
 // CheckpointManager handles file snapshotting and rewind for a session.
@@ -53,12 +57,132 @@ func (cm *CheckpointManager) manifestPath(userMsgUUID string) string {
 }
 
 // CreateCheckpoint snapshots the given files, storing content-addressed copies.
+// Calling it again for the same checkpoint adds paths not yet recorded; files
+// already in the manifest keep their original snapshot.
 func (cm *CheckpointManager) CreateCheckpoint(userMsgUUID string, filePaths []string) error {
 	filesDir := cm.filesDir(userMsgUUID)
 	if err := os.MkdirAll(filesDir, 0755); err != nil {
 		return fmt.Errorf("create checkpoint dir: %w", err)
 	}
 
+	existing, err := cm.loadManifest(userMsgUUID)
+	if err != nil && err != ErrCheckpointMissing {
+		return err
+	}
+	manifest, err := snapshotFiles(userMsgUUID, unrecordedPaths(existing, filePaths), func(hash string, data []byte) error {
+		hashPath := filepath.Join(filesDir, hash)
+		// Only write if not already present (deduplication)
+		if _, err := os.Stat(hashPath); os.IsNotExist(err) {
+			return os.WriteFile(hashPath, data, 0644)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	manifest = mergeManifest(existing, manifest)
+
+	// Write manifest
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(cm.manifestPath(userMsgUUID), data, 0644); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+
+	return nil
+}
+
+// loadManifest reads the manifest for a checkpoint, returning ErrCheckpointMissing if absent.
+func (cm *CheckpointManager) loadManifest(userMsgUUID string) (CheckpointManifest, error) {
+	var manifest CheckpointManifest
+	data, err := os.ReadFile(cm.manifestPath(userMsgUUID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, ErrCheckpointMissing
+		}
+		return manifest, fmt.Errorf("read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// RewindFiles restores files to the state captured at the given checkpoint.
+// If dryRun is true, it returns stats without modifying files.
+func (cm *CheckpointManager) RewindFiles(userMsgUUID string, dryRun bool) (*agent.RewindFilesResult, error) {
+	manifest, err := cm.loadManifest(userMsgUUID)
+	if err != nil {
+		return nil, err
+	}
+	return rewindManifest(manifest, cm.loadBlob(userMsgUUID), dryRun), nil
+}
+
+// loadManifests reads the manifests of every checkpoint in the session.
+func (cm *CheckpointManager) loadManifests() ([]CheckpointManifest, error) {
+	dirs, err := os.ReadDir(cm.checkpointsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read checkpoints: %w", err)
+	}
+	var manifests []CheckpointManifest
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		manifest, err := cm.loadManifest(d.Name())
+		if err == ErrCheckpointMissing {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, nil
+}
+
+// removeCheckpoints deletes the given checkpoints and their snapshots.
+func (cm *CheckpointManager) removeCheckpoints(userMsgUUIDs []string) error {
+	var errs []error
+	for _, id := range userMsgUUIDs {
+		if err := os.RemoveAll(cm.checkpointDir(id)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loadBlob returns a loader for the content-addressed files of a checkpoint.
+func (cm *CheckpointManager) loadBlob(userMsgUUID string) func(hash string) ([]byte, error) {
+	filesDir := cm.filesDir(userMsgUUID)
+	return func(hash string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(filesDir, hash))
+	}
+}
+
+// loadBlobFrom returns a loader that looks a hash up in each of the given
+// checkpoints in turn.
+func (cm *CheckpointManager) loadBlobFrom(userMsgUUIDs []string) func(hash string) ([]byte, error) {
+	return func(hash string) ([]byte, error) {
+		var err error
+		for _, id := range userMsgUUIDs {
+			var data []byte
+			if data, err = cm.loadBlob(id)(hash); err == nil {
+				return data, nil
+			}
+		}
+		return nil, err
+	}
+}
+
+// snapshotFiles records the current state of each path, handing the content of
+// existing files to save under its SHA256 hash.
+func snapshotFiles(userMsgUUID string, filePaths []string, save func(hash string, data []byte) error) (CheckpointManifest, error) {
 	manifest := CheckpointManifest{
 		UserMessageUUID: userMsgUUID,
 		CreatedAt:       time.Now(),
@@ -67,122 +191,288 @@ func (cm *CheckpointManager) CreateCheckpoint(userMsgUUID string, filePaths []st
 	for _, path := range filePaths {
 		snapshot := FileSnapshot{Path: path}
 
-		data, err := os.ReadFile(path)
+		info, err := os.Stat(path)
 		if err != nil {
 			if os.IsNotExist(err) {
 				snapshot.Exists = false
 				manifest.Files = append(manifest.Files, snapshot)
 				continue
 			}
-			return fmt.Errorf("read file %q: %w", path, err)
+			return manifest, fmt.Errorf("stat file %q: %w", path, err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return manifest, fmt.Errorf("read file %q: %w", path, err)
 		}
 
 		snapshot.Exists = true
 		snapshot.Size = len(data)
+		snapshot.Mode = info.Mode().Perm()
 
 		// Content-addressed storage via SHA256
 		hash := sha256.Sum256(data)
 		snapshot.Hash = hex.EncodeToString(hash[:])
 
-		hashPath := filepath.Join(filesDir, snapshot.Hash)
-		// Only write if not already present (deduplication)
-		if _, err := os.Stat(hashPath); os.IsNotExist(err) {
-			if err := os.WriteFile(hashPath, data, 0644); err != nil {
-				return fmt.Errorf("write checkpoint file %q: %w", path, err)
-			}
+		if err := save(snapshot.Hash, data); err != nil {
+			return manifest, fmt.Errorf("write checkpoint file %q: %w", path, err)
 		}
 
 		manifest.Files = append(manifest.Files, snapshot)
 	}
+	return manifest, nil
+}
 
-	// Write manifest
-	data, err := json.MarshalIndent(manifest, "", "  ")
-	if err != nil {
-		return fmt.Errorf("marshal manifest: %w", err)
+// unrecordedPaths returns the paths not already snapshotted in existing.
+func unrecordedPaths(existing CheckpointManifest, paths []string) []string {
+	recorded := make(map[string]bool, len(existing.Files))
+	for _, f := range existing.Files {
+		recorded[f.Path] = true
 	}
-	if err := os.WriteFile(cm.manifestPath(userMsgUUID), data, 0644); err != nil {
-		return fmt.Errorf("write manifest: %w", err)
+	var out []string
+	for _, p := range paths {
+		if !recorded[p] {
+			recorded[p] = true
+			out = append(out, p)
+		}
 	}
+	return out
+}
 
-	return nil
+// mergeManifest appends the snapshots in added to existing, keeping the
+// original creation time when existing is non-empty.
+func mergeManifest(existing, added CheckpointManifest) CheckpointManifest {
+	if existing.UserMessageUUID == "" {
+		return added
+	}
+	existing.Files = append(existing.Files, added.Files...)
+	return existing
 }
 
-// RewindFiles restores files to the state captured at the given checkpoint.
-// If dryRun is true, it returns stats without modifying files.
-func (cm *CheckpointManager) RewindFiles(userMsgUUID string, dryRun bool) (*agent.RewindFilesResult, error) {
-	manifestData, err := os.ReadFile(cm.manifestPath(userMsgUUID))
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, ErrCheckpointMissing
+// rewindFrom merges target with every checkpoint in all created after it,
+// keeping the earliest snapshot of each path. Rewinding to the result also
+// undoes changes made in later turns to files target never recorded. The
+// returned IDs are those of the merged checkpoints, oldest first.
+func rewindFrom(target CheckpointManifest, all []CheckpointManifest) (CheckpointManifest, []string) {
+	later := make([]CheckpointManifest, 0, len(all))
+	for _, m := range all {
+		if m.UserMessageUUID != target.UserMessageUUID && !m.CreatedAt.Before(target.CreatedAt) {
+			later = append(later, m)
 		}
-		return nil, fmt.Errorf("read manifest: %w", err)
 	}
+	sort.SliceStable(later, func(i, j int) bool { return later[i].CreatedAt.Before(later[j].CreatedAt) })
 
-	var manifest CheckpointManifest
-	if err := json.Unmarshal(manifestData, &manifest); err != nil {
-		return nil, fmt.Errorf("parse manifest: %w", err)
+	merged := CheckpointManifest{UserMessageUUID: target.UserMessageUUID, CreatedAt: target.CreatedAt}
+	ids := []string{target.UserMessageUUID}
+	seen := make(map[string]bool)
+	for i, m := range append([]CheckpointManifest{target}, later...) {
+		if i > 0 {
+			ids = append(ids, m.UserMessageUUID)
+		}
+		for _, f := range m.Files {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				merged.Files = append(merged.Files, f)
+			}
+		}
 	}
+	return merged, ids
+}
 
-	result := &agent.RewindFilesResult{CanRewind: true}
-	filesDir := cm.filesDir(userMsgUUID)
+// rewindOp is a single file change needed to return to a checkpoint.
+type rewindOp struct {
+	snap   FileSnapshot
+	exists bool // the file exists now
+}
 
+// planRewind compares each snapshot against the working tree and returns the
+// files that differ in existence, content or mode.
+func planRewind(manifest CheckpointManifest) []rewindOp {
+	var ops []rewindOp
 	for _, snap := range manifest.Files {
+		info, err := os.Stat(snap.Path)
+		exists := err == nil
 		if !snap.Exists {
 			// File didn't exist at checkpoint — delete it if it now exists
-			if _, err := os.Stat(snap.Path); err == nil {
-				result.FilesChanged = append(result.FilesChanged, snap.Path)
-				result.Deletions++
-				if !dryRun {
-					if err := os.Remove(snap.Path); err != nil {
-						result.Error = fmt.Sprintf("failed to delete %q: %s", snap.Path, err)
-						result.CanRewind = false
-					}
-				}
+			if exists {
+				ops = append(ops, rewindOp{snap: snap, exists: true})
 			}
 			continue
 		}
-
-		// File existed at checkpoint — restore it
-		currentData, err := os.ReadFile(snap.Path)
-		currentExists := err == nil
-
-		// Check if restoration is needed
-		if currentExists {
-			currentHash := sha256.Sum256(currentData)
-			if hex.EncodeToString(currentHash[:]) == snap.Hash {
-				continue // file unchanged, skip
+		if exists {
+			currentData, err := os.ReadFile(snap.Path)
+			if err == nil {
+				currentHash := sha256.Sum256(currentData)
+				sameMode := snap.Mode == 0 || info.Mode().Perm() == snap.Mode
+				if hex.EncodeToString(currentHash[:]) == snap.Hash && sameMode {
+					continue // file unchanged, skip
+				}
 			}
 		}
+		ops = append(ops, rewindOp{snap: snap, exists: exists})
+	}
+	return ops
+}
 
-		result.FilesChanged = append(result.FilesChanged, snap.Path)
-		if currentExists {
-			result.Insertions++ // file modified — counting as insertion (overwrite)
+// rewindManifest restores the files in manifest, reading snapshot content via load.
+func rewindManifest(manifest CheckpointManifest, load func(hash string) ([]byte, error), dryRun bool) *agent.RewindFilesResult {
+	ops := planRewind(manifest)
+	result := &agent.RewindFilesResult{CanRewind: true}
+	for _, op := range ops {
+		result.FilesChanged = append(result.FilesChanged, op.snap.Path)
+		if op.snap.Exists {
+			result.Insertions++ // modified or deleted since the checkpoint — rewritten
 		} else {
-			result.Insertions++ // file was deleted — re-creating
+			result.Deletions++
+		}
+	}
+	if !dryRun {
+		if err := applyRewind(ops, load); err != nil {
+			result.Error = err.Error()
+			result.CanRewind = false
 		}
+	}
+	return result
+}
 
-		if !dryRun {
-			// Read snapshot content from content-addressed store
-			snapData, err := os.ReadFile(filepath.Join(filesDir, snap.Hash))
-			if err != nil {
-				result.Error = fmt.Sprintf("failed to read checkpoint content for %q: %s", snap.Path, err)
-				result.CanRewind = false
-				continue
-			}
+// rewindStage tracks one file through applyRewind so it can be rolled back.
+type rewindStage struct {
+	op     rewindOp
+	temp   string // restored content staged next to the target
+	backup string // current file moved aside
+	placed bool   // temp has been renamed onto the target
+}
 
-			// Ensure parent directory exists
-			if err := os.MkdirAll(filepath.Dir(snap.Path), 0755); err != nil {
-				result.Error = fmt.Sprintf("failed to create dir for %q: %s", snap.Path, err)
-				result.CanRewind = false
+// applyRewind performs ops all-or-nothing. Restored content is first staged in
+// temporary files beside each target; only once every file is staged are the
+// current files moved aside and the staged ones renamed into place. Any
+// failure puts the moved files back, so the tree is never left half-reverted.
+func applyRewind(ops []rewindOp, load func(hash string) ([]byte, error)) (err error) {
+	stages := make([]*rewindStage, len(ops))
+	defer func() {
+		for _, st := range stages {
+			if st == nil {
 				continue
 			}
+			if st.temp != "" && !st.placed {
+				os.Remove(st.temp)
+			}
+			if err == nil && st.backup != "" {
+				os.Remove(st.backup)
+			}
+		}
+	}()
 
-			if err := os.WriteFile(snap.Path, snapData, 0644); err != nil {
-				result.Error = fmt.Sprintf("failed to restore %q: %s", snap.Path, err)
-				result.CanRewind = false
+	// Phase 1: stage restored content without touching the targets
+	for i, op := range ops {
+		st := &rewindStage{op: op}
+		stages[i] = st
+		if !op.snap.Exists {
+			continue
+		}
+		data, err := load(op.snap.Hash)
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint content for %q: %w", op.snap.Path, err)
+		}
+		if st.temp, err = stageFile(op.snap, data); err != nil {
+			return fmt.Errorf("failed to restore %q: %w", op.snap.Path, err)
+		}
+	}
+
+	// Phase 2: swap files into place, undoing completed swaps on failure
+	for i, st := range stages {
+		if err := swapFile(st); err != nil {
+			for j := i; j >= 0; j-- {
+				unswapFile(stages[j])
 			}
+			return fmt.Errorf("failed to restore %q: %w", st.op.snap.Path, err)
+		}
+	}
+	return nil
+}
+
+// stageFile writes data to a temporary file in the target's directory (so the
+// final rename stays on one filesystem) with the snapshot's mode.
+func stageFile(snap FileSnapshot, data []byte) (string, error) {
+	dir := filepath.Dir(snap.Path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, ".rewind-*")
+	if err != nil {
+		return "", err
+	}
+	mode := snap.Mode
+	if mode == 0 {
+		mode = 0644
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), mode)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// swapFile moves the current file (if any) aside and the staged file into place.
+func swapFile(st *rewindStage) error {
+	if st.op.exists {
+		backup := filepath.Join(filepath.Dir(st.op.snap.Path), ".rewind-bak-"+filepath.Base(st.op.snap.Path))
+		if err := os.Rename(st.op.snap.Path, backup); err != nil {
+			return err
+		}
+		st.backup = backup
+	}
+	if st.temp != "" {
+		if err := os.Rename(st.temp, st.op.snap.Path); err != nil {
+			return err
+		}
+		st.placed = true
+	}
+	return nil
+}
+
+// unswapFile reverses swapFile, restoring the file that was moved aside.
+func unswapFile(st *rewindStage) {
+	if st.placed {
+		os.Remove(st.op.snap.Path)
+		st.placed = false
+	}
+	if st.backup != "" {
+		if os.Rename(st.backup, st.op.snap.Path) == nil {
+			st.backup = ""
 		}
 	}
+}
 
-	return result, nil
+// discardedCheckpoints returns the IDs whose messages are not in kept: the
+// checkpoints of turns a rewind truncated.
+func discardedCheckpoints(ids []string, kept []agent.MessageEntry) []string {
+	live := make(map[string]bool, len(kept))
+	for _, e := range kept {
+		live[e.UUID] = true
+	}
+	var out []string
+	for _, id := range ids {
+		if !live[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// truncateBefore returns the entries preceding the message with the given UUID,
+// or all entries if it is not found.
+func truncateBefore(entries []agent.MessageEntry, messageUUID string) []agent.MessageEntry {
+	for i, e := range entries {
+		if e.UUID == messageUUID {
+			return entries[:i]
+		}
+	}
+	return entries
 }
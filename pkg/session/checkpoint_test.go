@@ -4,8 +4,10 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -308,3 +310,200 @@ func TestStore_CreateCheckpointAndRewind(t *testing.T) {
 		t.Errorf("restored content = %q, want 'before checkpoint'", data)
 	}
 }
+
+func TestCheckpoint_AdditiveKeepsFirstSnapshot(t *testing.T) {
+	cm, baseDir := newTestCheckpointManager(t)
+
+	a := filepath.Join(baseDir, "a.txt")
+	b := filepath.Join(baseDir, "b.txt")
+	writeTestFile(t, a, "a original")
+	writeTestFile(t, b, "b original")
+
+	cm.CreateCheckpoint("msg-uuid-9", []string{a})
+	writeTestFile(t, a, "a modified")
+	cm.CreateCheckpoint("msg-uuid-9", []string{a, b})
+
+	manifest, err := cm.loadManifest("msg-uuid-9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("files = %d, want 2", len(manifest.Files))
+	}
+	hash := sha256.Sum256([]byte("a original"))
+	if manifest.Files[0].Hash != hex.EncodeToString(hash[:]) {
+		t.Error("re-checkpointing should keep the first snapshot of a.txt")
+	}
+}
+
+func TestCheckpoint_RewindRestoresModeAndDeletedFiles(t *testing.T) {
+	cm, baseDir := newTestCheckpointManager(t)
+
+	script := filepath.Join(baseDir, "run.sh")
+	gone := filepath.Join(baseDir, "sub", "gone.txt")
+	writeTestFile(t, script, "#!/bin/sh\n")
+	os.Chmod(script, 0755)
+	writeTestFile(t, gone, "keep me")
+
+	cm.CreateCheckpoint("msg-uuid-10", []string{script, gone})
+	os.Chmod(script, 0644)
+	os.RemoveAll(filepath.Dir(gone))
+
+	result, err := cm.RewindFiles("msg-uuid-10", false)
+	if err != nil || !result.CanRewind {
+		t.Fatalf("RewindFiles: %v %+v", err, result)
+	}
+	if info, _ := os.Stat(script); info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(gone); string(data) != "keep me" {
+		t.Errorf("deleted file content = %q, want restored", data)
+	}
+}
+
+func TestCheckpoint_RewindIsAtomic(t *testing.T) {
+	cm, baseDir := newTestCheckpointManager(t)
+
+	first := filepath.Join(baseDir, "first.txt")
+	second := filepath.Join(baseDir, "second.txt")
+	writeTestFile(t, first, "first original")
+	writeTestFile(t, second, "second original")
+	cm.CreateCheckpoint("msg-uuid-11", []string{first, second})
+
+	writeTestFile(t, first, "first modified")
+	writeTestFile(t, second, "second modified")
+
+	// Corrupt the stored content of the second file so restoring it fails
+	manifest, _ := cm.loadManifest("msg-uuid-11")
+	os.Remove(filepath.Join(cm.filesDir("msg-uuid-11"), manifest.Files[1].Hash))
+
+	result, err := cm.RewindFiles("msg-uuid-11", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.CanRewind || result.Error == "" {
+		t.Errorf("expected failure, got %+v", result)
+	}
+	if data, _ := os.ReadFile(first); string(data) != "first modified" {
+		t.Errorf("first.txt = %q; a failed rewind must not change any file", data)
+	}
+	entries, _ := os.ReadDir(baseDir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".rewind") {
+			t.Errorf("leftover temp file %s", e.Name())
+		}
+	}
+}
+
+func TestStore_Rewind(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+	s.Create(testMetadata("sess-rw", "/tmp"))
+
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "main.go")
+	created := filepath.Join(dir, "new.go")
+	writeTestFile(t, filePath, "v1")
+
+	s.AppendMessage("sess-rw", testMessageEntry("u1", "user", "first"))
+	s.AppendMessage("sess-rw", testMessageEntry("a1", "assistant", "ok"))
+	s.AppendMessage("sess-rw", testMessageEntry("u2", "user", "change it"))
+	s.CreateCheckpoint("sess-rw", "u2", []string{filePath, created})
+	writeTestFile(t, filePath, "v2")
+	writeTestFile(t, created, "new")
+	s.AppendMessage("sess-rw", testMessageEntry("a2", "assistant", "changed"))
+
+	kept, err := s.Rewind("sess-rw", "u2")
+	if err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	if len(kept) != 2 || kept[1].UUID != "a1" {
+		t.Errorf("kept = %v, want messages before u2", kept)
+	}
+	if data, _ := os.ReadFile(filePath); string(data) != "v1" {
+		t.Errorf("main.go = %q, want v1", data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Error("new.go should be removed")
+	}
+
+	// The log is truncated on disk and further appends still land in it
+	s.AppendMessage("sess-rw", testMessageEntry("u3", "user", "again"))
+	msgs, _ := s.LoadMessages("sess-rw")
+	if len(msgs) != 3 || msgs[2].UUID != "u3" {
+		t.Errorf("messages after rewind = %v", msgs)
+	}
+
+	if _, err := s.Rewind("sess-rw", "missing"); err != ErrCheckpointMissing {
+		t.Errorf("err = %v, want ErrCheckpointMissing", err)
+	}
+}
+
+func TestStore_RewindAcrossTurns(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+	s.Create(testMetadata("sess-rw", "/tmp"))
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	writeTestFile(t, a, "a1")
+	writeTestFile(t, b, "b1")
+
+	// Turn 1 edits a.txt; turn 2 edits b.txt and a.txt again.
+	s.AppendMessage("sess-rw", testMessageEntry("u1", "user", "edit a"))
+	s.CreateCheckpoint("sess-rw", "u1", []string{a})
+	writeTestFile(t, a, "a2")
+	s.AppendMessage("sess-rw", testMessageEntry("u2", "user", "edit b"))
+	s.CreateCheckpoint("sess-rw", "u2", []string{a, b})
+	writeTestFile(t, a, "a3")
+	writeTestFile(t, b, "b2")
+
+	if _, err := s.Rewind("sess-rw", "u1"); err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a1" {
+		t.Errorf("a.txt = %q, want a1 (the earliest snapshot)", data)
+	}
+	if data, _ := os.ReadFile(b); string(data) != "b1" {
+		t.Errorf("b.txt = %q, want b1 (restored from the later checkpoint)", data)
+	}
+}
+
+func TestStore_RewindDropsDiscardedCheckpoints(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+	s.Create(testMetadata("sess-rw", "/tmp"))
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	writeTestFile(t, a, "a1")
+	writeTestFile(t, b, "b1")
+
+	s.AppendMessage("sess-rw", testMessageEntry("u1", "user", "edit a"))
+	s.CreateCheckpoint("sess-rw", "u1", []string{a})
+	writeTestFile(t, a, "a2")
+	s.AppendMessage("sess-rw", testMessageEntry("u2", "user", "edit b"))
+	s.CreateCheckpoint("sess-rw", "u2", []string{b})
+	writeTestFile(t, b, "b2")
+
+	if _, err := s.Rewind("sess-rw", "u2"); err != nil {
+		t.Fatalf("Rewind u2: %v", err)
+	}
+	if _, err := s.RewindFiles("sess-rw", "u2", true); !errors.Is(err, ErrCheckpointMissing) {
+		t.Errorf("RewindFiles(u2) error = %v, want ErrCheckpointMissing", err)
+	}
+
+	// The discarded turn must not be replayed by a rewind to an earlier one.
+	writeTestFile(t, b, "b-user")
+	if _, err := s.Rewind("sess-rw", "u1"); err != nil {
+		t.Fatalf("Rewind u1: %v", err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a1" {
+		t.Errorf("a.txt = %q, want a1", data)
+	}
+	if data, _ := os.ReadFile(b); string(data) != "b-user" {
+		t.Errorf("b.txt = %q, want b-user (untouched)", data)
+	}
+}
@@ -5,5 +5,6 @@ import "errors"
 var (
 	ErrSessionNotFound   = errors.New("session not found")
 	ErrCheckpointMissing = errors.New("checkpoint not found")
+	ErrMessageNotFound   = errors.New("message not found")
 	ErrLockTimeout       = errors.New("lock acquisition timeout")
 )
@@ -0,0 +1,223 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+// exportTimeFormat is used for message timestamps in Markdown exports.
+const exportTimeFormat = "2006-01-02 15:04:05 MST"
+
+// ExportOptions controls Markdown transcript rendering.
+type ExportOptions struct {
+	IncludeReplay bool // render entries replayed from a parent session on resume
+}
+
+// ExportMarkdown renders a stored session as a Markdown transcript.
+func (s *Store) ExportMarkdown(sessionID string, w io.Writer, opts ExportOptions) error {
+	state, err := s.Load(sessionID)
+	if err != nil {
+		return err
+	}
+	return RenderMarkdown(w, state.Metadata, state.Messages, opts)
+}
+
+// ExportMarkdown renders a stored session as a Markdown transcript.
+func (s *SQLiteStore) ExportMarkdown(sessionID string, w io.Writer, opts ExportOptions) error {
+	state, err := s.Load(sessionID)
+	if err != nil {
+		return err
+	}
+	return RenderMarkdown(w, state.Metadata, state.Messages, opts)
+}
+
+// RenderMarkdown writes entries as a Markdown transcript: a heading per
+// user/assistant message with its timestamp, thinking as a quoted section,
+// tool calls and results as collapsible <details> blocks, and a footer with
+// the turn, token and cost totals from meta. System messages are omitted.
+func RenderMarkdown(w io.Writer, meta agent.SessionMetadata, entries []agent.MessageEntry, opts ExportOptions) error {
+	var b strings.Builder
+
+	title := meta.LeafTitle
+	if title == "" {
+		title = "Session " + meta.ID
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	writeField(&b, "Session", meta.ID)
+	writeField(&b, "Model", meta.Model)
+	writeField(&b, "Working directory", meta.CWD)
+	if !meta.CreatedAt.IsZero() {
+		writeField(&b, "Started", meta.CreatedAt.Format(exportTimeFormat))
+	}
+	writeField(&b, "Forked from", meta.ParentSessionID)
+	writeField(&b, "Agent", meta.AgentName)
+
+	// Tool results only carry the call ID; resolve names from earlier calls
+	toolNames := make(map[string]string)
+	for _, e := range entries {
+		if e.IsReplay && !opts.IncludeReplay {
+			continue
+		}
+		msg := e.Message
+		switch msg.Role {
+		case "user":
+			writeHeading(&b, "User", e.Timestamp)
+			if text := contentText(msg.Content); text != "" {
+				b.WriteString(text + "\n\n")
+			}
+		case "assistant":
+			writeHeading(&b, "Assistant", e.Timestamp)
+			if e.Thinking != "" {
+				writeThinking(&b, e.Thinking)
+			}
+			if text := contentText(msg.Content); text != "" {
+				b.WriteString(text + "\n\n")
+			}
+			for _, tc := range msg.ToolCalls {
+				toolNames[tc.ID] = tc.Function.Name
+				writeDetails(&b, "Tool call: <code>"+tc.Function.Name+"</code>", "json", prettyJSON(tc.Function.Arguments))
+			}
+		case "tool":
+			name := toolNames[msg.ToolCallID]
+			if name == "" {
+				name = msg.Name
+			}
+			summary := "Tool result"
+			if name != "" {
+				summary += ": <code>" + name + "</code>"
+			}
+			writeDetails(&b, summary, "", toolResultText(msg.Content))
+		}
+	}
+
+	b.WriteString("---\n\n")
+	fmt.Fprintf(&b, "%d turn(s), %d message(s)", meta.TurnCount, meta.MessageCount)
+	if meta.InputTokens > 0 || meta.OutputTokens > 0 {
+		fmt.Fprintf(&b, " · %d input / %d output tokens", meta.InputTokens, meta.OutputTokens)
+	}
+	fmt.Fprintf(&b, " · $%.4f\n", meta.TotalCostUSD)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeField(b *strings.Builder, label, value string) {
+	if value != "" {
+		fmt.Fprintf(b, "- **%s:** %s\n", label, value)
+	}
+}
+
+func writeHeading(b *strings.Builder, role string, ts time.Time) {
+	if ts.IsZero() {
+		fmt.Fprintf(b, "\n## %s\n\n", role)
+		return
+	}
+	fmt.Fprintf(b, "\n## %s\n\n_%s_\n\n", role, ts.Format(exportTimeFormat))
+}
+
+func writeThinking(b *strings.Builder, thinking string) {
+	b.WriteString("> **Thinking**\n>\n")
+	for _, line := range strings.Split(strings.TrimRight(thinking, "\n"), "\n") {
+		if line == "" {
+			b.WriteString(">\n")
+			continue
+		}
+		b.WriteString("> " + line + "\n")
+	}
+	b.WriteString("\n")
+}
+
+// writeDetails writes a collapsible block with body fenced as code. The fence
+// is lengthened when body itself contains backtick runs.
+func writeDetails(b *strings.Builder, summary, lang, body string) {
+	fence := "```"
+	for strings.Contains(body, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n%s%s\n%s\n%s\n\n</details>\n\n",
+		summary, fence, lang, strings.TrimRight(body, "\n"), fence)
+}
+
+// contentText flattens message content (a string or a list of parts) into
+// Markdown. Images are shown as placeholders rather than inlined.
+func contentText(content any) string {
+	switch c := content.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(c)
+	case []llm.ContentPart:
+		var parts []string
+		for _, p := range c {
+			parts = appendPart(parts, p.Type, p.Text, "")
+		}
+		return strings.Join(parts, "\n\n")
+	case []any:
+		var parts []string
+		for _, item := range c {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			typ, _ := m["type"].(string)
+			text, _ := m["text"].(string)
+			thinking, _ := m["thinking"].(string)
+			parts = appendPart(parts, typ, text, thinking)
+		}
+		return strings.Join(parts, "\n\n")
+	default:
+		data, _ := json.Marshal(c)
+		return string(data)
+	}
+}
+
+func appendPart(parts []string, typ, text, thinking string) []string {
+	switch typ {
+	case "text":
+		if t := strings.TrimSpace(text); t != "" {
+			parts = append(parts, t)
+		}
+	case "thinking":
+		if thinking != "" {
+			var b strings.Builder
+			writeThinking(&b, thinking)
+			parts = append(parts, strings.TrimRight(b.String(), "\n"))
+		}
+	case "image", "image_url":
+		parts = append(parts, "_[image]_")
+	}
+	return parts
+}
+
+// toolResultText returns the plain text of tool_result content, which is a
+// string for local tools but may be structured (a list of text blocks) when
+// it came from MCP or was loaded from a transcript.
+func toolResultText(content any) string {
+	switch c := content.(type) {
+	case string:
+		return c
+	case []llm.ContentPart, []any:
+		return contentText(c)
+	case map[string]any:
+		if inner, ok := c["content"]; ok {
+			return toolResultText(inner)
+		}
+	}
+	return contentText(content)
+}
+
+// prettyJSON indents a JSON argument string, returning it unchanged if it is not valid JSON.
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
@@ -0,0 +1,119 @@
+package session
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+func exportTestEntries() []agent.MessageEntry {
+	ts := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	return []agent.MessageEntry{
+		{UUID: "r1", Timestamp: ts, IsReplay: true, Message: llm.ChatMessage{Role: "user", Content: "replayed prompt"}},
+		{UUID: "s1", Timestamp: ts, Message: llm.ChatMessage{Role: "system", Content: "system prompt"}},
+		{UUID: "u1", Timestamp: ts, Message: llm.ChatMessage{Role: "user", Content: "list the files"}},
+		{UUID: "a1", Timestamp: ts, Thinking: "need to run ls\n\nthen summarize", Message: llm.ChatMessage{
+			Role:    "assistant",
+			Content: "Let me check.",
+			ToolCalls: []llm.ToolCall{{
+				ID:       "call_1",
+				Type:     "function",
+				Function: llm.FunctionCall{Name: "Bash", Arguments: `{"command":"ls"}`},
+			}},
+		}},
+		{UUID: "t1", Timestamp: ts, Message: llm.ChatMessage{Role: "tool", ToolCallID: "call_1", Content: "a.go\nb.go"}},
+		{UUID: "t2", Timestamp: ts, Message: llm.ChatMessage{Role: "tool", ToolCallID: "call_2", Name: "mcp__docs__search", Content: []any{
+			map[string]any{"type": "text", "text": "structured result"},
+		}}},
+		{UUID: "a2", Timestamp: ts, Message: llm.ChatMessage{Role: "assistant", Content: []llm.ContentPart{
+			{Type: "text", Text: "Two files."},
+		}}},
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	meta := testMetadata("sess-1", "/tmp/project")
+	meta.TurnCount = 2
+	meta.MessageCount = 7
+	meta.InputTokens = 1200
+	meta.OutputTokens = 340
+	meta.TotalCostUSD = 0.0123
+
+	var buf bytes.Buffer
+	if err := RenderMarkdown(&buf, meta, exportTestEntries(), ExportOptions{}); err != nil {
+		t.Fatalf("RenderMarkdown: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"# Session sess-1",
+		"- **Working directory:** /tmp/project",
+		"## User\n\n_2025-03-01 12:30:00 UTC_\n\nlist the files",
+		"> **Thinking**\n>\n> need to run ls\n>\n> then summarize\n",
+		"<summary>Tool call: <code>Bash</code></summary>\n\n```json\n{\n  \"command\": \"ls\"\n}\n```",
+		"<summary>Tool result: <code>Bash</code></summary>\n\n```\na.go\nb.go\n```",
+		"<summary>Tool result: <code>mcp__docs__search</code></summary>\n\n```\nstructured result\n```",
+		"Two files.",
+		"2 turn(s), 7 message(s) · 1200 input / 340 output tokens · $0.0123",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n---\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"replayed prompt", "system prompt"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output should not contain %q", unwanted)
+		}
+	}
+}
+
+func TestRenderMarkdown_IncludeReplay(t *testing.T) {
+	var buf bytes.Buffer
+	RenderMarkdown(&buf, testMetadata("sess-1", "/tmp"), exportTestEntries(), ExportOptions{IncludeReplay: true})
+	if !strings.Contains(buf.String(), "replayed prompt") {
+		t.Error("IncludeReplay should render replayed entries")
+	}
+}
+
+func TestRenderMarkdown_FenceInToolOutput(t *testing.T) {
+	entries := []agent.MessageEntry{
+		{Message: llm.ChatMessage{Role: "tool", Content: "```go\nfunc main() {}\n```"}},
+	}
+	var buf bytes.Buffer
+	RenderMarkdown(&buf, testMetadata("sess-1", "/tmp"), entries, ExportOptions{})
+	if !strings.Contains(buf.String(), "````\n```go\nfunc main() {}\n```\n````") {
+		t.Errorf("expected a longer fence around nested code blocks:\n%s", buf.String())
+	}
+}
+
+func TestExportMarkdown_Stores(t *testing.T) {
+	type exporter interface {
+		agent.SessionStore
+		ExportMarkdown(sessionID string, w io.Writer, opts ExportOptions) error
+	}
+	fileStore := newTestStore(t)
+	defer fileStore.Close()
+
+	for name, s := range map[string]exporter{"file": fileStore, "sqlite": newTestSQLiteStore(t)} {
+		t.Run(name, func(t *testing.T) {
+			s.Create(testMetadata("sess-1", "/tmp"))
+			s.AppendMessage("sess-1", testMessageEntry("m1", "user", "hello there"))
+
+			var buf bytes.Buffer
+			if err := s.ExportMarkdown("sess-1", &buf, ExportOptions{}); err != nil {
+				t.Fatalf("ExportMarkdown: %v", err)
+			}
+			if !strings.Contains(buf.String(), "hello there") {
+				t.Errorf("export missing message:\n%s", buf.String())
+			}
+			if err := s.ExportMarkdown("missing", &buf, ExportOptions{}); err == nil {
+				t.Error("expected error for unknown session")
+			}
+		})
+	}
+}
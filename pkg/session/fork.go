@@ -0,0 +1,88 @@
+package session
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+// branchEntries returns the messages of a branch cut at messageUUID: every
+// message up to it, marked as replayed, with the branch point swapped for
+// replacement when given. The cut never splits a tool exchange. Results already recorded for the
+// branch point's tool calls are carried over; if some are missing, the cut
+// moves back to before the assistant message that made the calls.
+func branchEntries(entries []agent.MessageEntry, messageUUID string, replacement *llm.ChatMessage) ([]agent.MessageEntry, error) {
+	idx := slices.IndexFunc(entries, func(e agent.MessageEntry) bool { return e.UUID == messageUUID })
+	if idx < 0 {
+		return nil, ErrMessageNotFound
+	}
+	kept := markReplayed(slices.Clone(entries[:idx+1]))
+	if replacement != nil {
+		kept[idx] = agent.MessageEntry{UUID: uuid.New().String(), Timestamp: time.Now(), Message: *replacement}
+	}
+
+	call, pending := pendingToolCalls(kept)
+	for _, e := range entries[idx+1:] {
+		if len(pending) == 0 || e.Message.Role != "tool" || !pending[e.Message.ToolCallID] {
+			break
+		}
+		delete(pending, e.Message.ToolCallID)
+		e.IsReplay = true
+		kept = append(kept, e)
+	}
+	if len(pending) == 0 {
+		return kept, nil
+	}
+	if replacement != nil && call == idx {
+		return nil, errors.New("replacement makes tool calls that have no results")
+	}
+	return kept[:call], nil
+}
+
+// markReplayed flags entries as re-appended from a parent session and
+// returns them.
+func markReplayed(entries []agent.MessageEntry) []agent.MessageEntry {
+	for i := range entries {
+		entries[i].IsReplay = true
+	}
+	return entries
+}
+
+// pendingToolCalls finds the last assistant message in entries and the IDs
+// of its tool calls that no later message answers.
+func pendingToolCalls(entries []agent.MessageEntry) (int, map[string]bool) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		msg := entries[i].Message
+		if msg.Role != "assistant" {
+			continue
+		}
+		pending := make(map[string]bool)
+		for _, call := range msg.ToolCalls {
+			pending[call.ID] = true
+		}
+		for _, e := range entries[i+1:] {
+			delete(pending, e.Message.ToolCallID)
+		}
+		return i, pending
+	}
+	return -1, nil
+}
+
+// forkAtMetadata derives a branch's metadata from its source session.
+func forkAtMetadata(source agent.SessionMetadata, messageUUID string, entries []agent.MessageEntry) agent.SessionMetadata {
+	now := time.Now()
+	meta := source
+	meta.ID = uuid.New().String()
+	meta.ParentSessionID = source.ID
+	meta.ForkPointUUID = messageUUID
+	meta.CreatedAt = now
+	meta.UpdatedAt = now
+	meta.MessageCount = len(entries)
+	meta.ExitReason = ""
+	meta.Incomplete = false
+	return meta
+}
@@ -0,0 +1,131 @@
+package session
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+)
+
+// toolExchange is a conversation with a two-call tool exchange in the middle.
+func toolExchange() []agent.MessageEntry {
+	calls := []llm.ToolCall{{ID: "tc1", Type: "function"}, {ID: "tc2", Type: "function"}}
+	return []agent.MessageEntry{
+		{UUID: "u1", Message: llm.ChatMessage{Role: "user", Content: "read both"}},
+		{UUID: "a1", Message: llm.ChatMessage{Role: "assistant", ToolCalls: calls}},
+		{UUID: "t1", Message: llm.ChatMessage{Role: "tool", ToolCallID: "tc1", Content: "one"}},
+		{UUID: "t2", Message: llm.ChatMessage{Role: "tool", ToolCallID: "tc2", Content: "two"}},
+		{UUID: "a2", Message: llm.ChatMessage{Role: "assistant", Content: "done"}},
+		{UUID: "u2", Message: llm.ChatMessage{Role: "user", Content: "thanks"}},
+	}
+}
+
+func uuids(entries []agent.MessageEntry) string {
+	var s string
+	for _, e := range entries {
+		s += e.UUID + " "
+	}
+	return s
+}
+
+func TestBranchEntries(t *testing.T) {
+	edited := &llm.ChatMessage{Role: "user", Content: "read one"}
+	tests := []struct {
+		name        string
+		entries     []agent.MessageEntry
+		at          string
+		replacement *llm.ChatMessage
+		want        string
+	}{
+		{"plain cut", toolExchange(), "a2", nil, "u1 a1 t1 t2 a2 "},
+		{"cut at tool calls keeps results", toolExchange(), "a1", nil, "u1 a1 t1 t2 "},
+		{"cut mid-results keeps the rest", toolExchange(), "t1", nil, "u1 a1 t1 t2 "},
+		{"replace tool result", toolExchange(), "t1", &llm.ChatMessage{Role: "tool", ToolCallID: "tc1", Content: "edited"}, "u1 a1 * t2 "},
+		{"replace tool calls with text", toolExchange(), "a1", &llm.ChatMessage{Role: "assistant", Content: "no tools"}, "u1 * "},
+		{"replace user message", toolExchange(), "u2", edited, "u1 a1 t1 t2 a2 * "},
+		{"missing results snap back", toolExchange()[:3], "t1", nil, "u1 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := branchEntries(tt.entries, tt.at, tt.replacement)
+			if err != nil {
+				t.Fatalf("branchEntries: %v", err)
+			}
+			for i := range got {
+				if tt.replacement != nil && got[i].Message.Content == tt.replacement.Content {
+					got[i].UUID = "*"
+				}
+			}
+			if s := uuids(got); s != tt.want {
+				t.Errorf("branch = %q, want %q", s, tt.want)
+			}
+		})
+	}
+
+	if _, err := branchEntries(toolExchange(), "nope", nil); !errors.Is(err, ErrMessageNotFound) {
+		t.Errorf("unknown UUID err = %v, want ErrMessageNotFound", err)
+	}
+	newCalls := &llm.ChatMessage{Role: "assistant", ToolCalls: []llm.ToolCall{{ID: "tc9"}}}
+	if _, err := branchEntries(toolExchange(), "a2", newCalls); err == nil {
+		t.Error("replacement with unanswered tool calls accepted")
+	}
+}
+
+// testForkAt checks ForkAt against any store.
+func testForkAt(t *testing.T, s agent.SessionStore) {
+	t.Helper()
+	source := testMetadata("src", "/proj")
+	source.TurnCount = 2
+	source.ExitReason = "end_turn"
+	s.Create(source)
+	for _, e := range toolExchange() {
+		s.AppendMessage("src", e)
+	}
+	if f, ok := s.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	replacement := &llm.ChatMessage{Role: "user", Content: "read just one"}
+	branch, err := s.ForkAt("src", "u1", replacement)
+	if err != nil {
+		t.Fatalf("ForkAt: %v", err)
+	}
+	meta := branch.Metadata
+	if meta.ID == "" || meta.ID == "src" || meta.ParentSessionID != "src" || meta.ForkPointUUID != "u1" ||
+		meta.MessageCount != 1 || meta.ExitReason != "" {
+		t.Errorf("branch metadata = %+v", meta)
+	}
+
+	loaded, err := s.Load(meta.ID)
+	if err != nil {
+		t.Fatalf("Load branch: %v", err)
+	}
+	if len(loaded.Messages) != 1 || loaded.Messages[0].Message.Content != "read just one" || loaded.Messages[0].UUID == "u1" {
+		t.Errorf("branch messages = %+v", loaded.Messages)
+	}
+	if loaded.Messages[0].IsReplay {
+		t.Error("the replacement is new to the branch, not replayed")
+	}
+	if orig, _ := s.LoadMessages("src"); len(orig) != 6 {
+		t.Errorf("source has %d messages after ForkAt, want 6", len(orig))
+	}
+
+	plain, err := s.ForkAt("src", "u1", nil)
+	if err != nil {
+		t.Fatalf("ForkAt without replacement: %v", err)
+	}
+	if msgs, _ := s.LoadMessages(plain.Metadata.ID); len(msgs) != 1 || !msgs[0].IsReplay {
+		t.Errorf("copied branch messages = %+v, want marked as replayed", msgs)
+	}
+
+	if _, err := s.ForkAt("missing", "u1", nil); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("ForkAt(missing) err = %v", err)
+	}
+}
+
+func TestStore_ForkAt(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+	testForkAt(t, s)
+}
@@ -2,9 +2,13 @@ package session
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
 	"os"
+	"time"
 
+	"github.com/gofrs/flock"
 	"github.com/jg-phare/goat/pkg/agent"
 )
 
@@ -32,6 +36,37 @@ func appendJSONL(path string, v any) error {
 	return err
 }
 
+// rewriteJSONL atomically replaces the file at path with entries, one per line.
+func rewriteJSONL(path string, entries []agent.MessageEntry) error {
+	fl := flock.New(path + ".lock")
+	ctx, cancel := context.WithTimeout(context.Background(), lockTimeout)
+	defer cancel()
+	if locked, err := fl.TryLockContext(ctx, 50*time.Millisecond); err != nil || !locked {
+		return ErrLockTimeout
+	}
+	defer fl.Unlock()
+
+	var buf bytes.Buffer
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
 // loadMessageEntries reads all MessageEntry records from a JSONL file.
 // Corrupt lines are skipped.
 func loadMessageEntries(path string) ([]agent.MessageEntry, error) {
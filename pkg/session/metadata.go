@@ -18,6 +18,18 @@ func saveMetadata(dir string, meta agent.SessionMetadata) error {
 	return os.WriteFile(filepath.Join(dir, metadataFile), data, 0644)
 }
 
+// recoverMetadata brings a crashed session's metadata up to date with its
+// message log, which is written per message and so outlives the crash.
+func recoverMetadata(meta *agent.SessionMetadata, entries []agent.MessageEntry) {
+	if !meta.Incomplete {
+		return
+	}
+	meta.MessageCount = len(entries)
+	if n := len(entries); n > 0 && entries[n-1].Timestamp.After(meta.UpdatedAt) {
+		meta.UpdatedAt = entries[n-1].Timestamp
+	}
+}
+
 func loadMetadata(dir string) (agent.SessionMetadata, error) {
 	var meta agent.SessionMetadata
 	data, err := os.ReadFile(filepath.Join(dir, metadataFile))
@@ -0,0 +1,689 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go driver, registers "sqlite"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+// sqliteMigrations are applied in order; PRAGMA user_version records how many
+// have run. Append new migrations — never edit existing ones.
+var sqliteMigrations = []string{
+	`CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		cwd        TEXT NOT NULL,
+		updated_at INTEGER NOT NULL,
+		metadata   TEXT NOT NULL
+	);
+	CREATE INDEX sessions_cwd_updated ON sessions (cwd, updated_at);
+
+	CREATE TABLE messages (
+		seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		uuid       TEXT,
+		data       TEXT NOT NULL,
+		UNIQUE (session_id, uuid)
+	);
+	CREATE INDEX messages_session ON messages (session_id, seq);
+
+	CREATE TABLE transcript (
+		seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id TEXT NOT NULL,
+		data       TEXT NOT NULL
+	);
+	CREATE INDEX transcript_session ON transcript (session_id, seq);
+
+	CREATE TABLE checkpoints (
+		session_id    TEXT NOT NULL,
+		user_msg_uuid TEXT NOT NULL,
+		manifest      TEXT NOT NULL,
+		PRIMARY KEY (session_id, user_msg_uuid)
+	);
+
+	CREATE TABLE checkpoint_blobs (
+		session_id TEXT NOT NULL,
+		hash       TEXT NOT NULL,
+		data       BLOB NOT NULL,
+		PRIMARY KEY (session_id, hash)
+	);`,
+	`CREATE TABLE web_resources (
+		session_id TEXT NOT NULL,
+		kind       TEXT NOT NULL,
+		key        TEXT NOT NULL,
+		data       TEXT NOT NULL,
+		PRIMARY KEY (session_id, kind, key)
+	);`,
+}
+
+// SQLiteStore implements agent.SessionStore on a single SQLite database.
+// Messages live in an append-only table ordered by insertion; metadata is
+// kept as JSON alongside indexed columns for lookups. The database runs in
+// WAL mode so readers (e.g. a session browser) can query a live session.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the database at path and applies any
+// pending migrations. Transactions begin IMMEDIATE, taking the write lock up
+// front, so one that reads before it writes sees no concurrent appends.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := url.URL{
+		Scheme: "file",
+		Opaque: url.PathEscape(path), // "?", "#" and "%" in a directory name
+		RawQuery: url.Values{
+			"_pragma": {"journal_mode(WAL)", "busy_timeout(5000)", "synchronous(NORMAL)"},
+			"_txlock": {"immediate"},
+		}.Encode(),
+	}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("open session db: %w", err)
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SQLiteStore) migrate() error {
+	var version int
+	if err := s.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("apply migration %d: %w", i+1, err)
+		}
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit migration %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func putMetadata(db execer, meta agent.SessionMetadata) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO sessions (id, cwd, updated_at, metadata) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET cwd = excluded.cwd, updated_at = excluded.updated_at, metadata = excluded.metadata`,
+		meta.ID, meta.CWD, meta.UpdatedAt.UnixNano(), string(data))
+	return err
+}
+
+func getMetadata(db execer, sessionID string) (agent.SessionMetadata, error) {
+	var meta agent.SessionMetadata
+	var data string
+	err := db.QueryRow(`SELECT metadata FROM sessions WHERE id = ?`, sessionID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return meta, ErrSessionNotFound
+	}
+	if err != nil {
+		return meta, err
+	}
+	err = json.Unmarshal([]byte(data), &meta)
+	return meta, err
+}
+
+// Create persists a new session with its metadata.
+func (s *SQLiteStore) Create(meta agent.SessionMetadata) error {
+	if err := putMetadata(s.db, meta); err != nil {
+		return fmt.Errorf("create session: %w", err)
+	}
+	return nil
+}
+
+// Load retrieves a session by ID with all its messages.
+func (s *SQLiteStore) Load(sessionID string) (*agent.SessionState, error) {
+	return loadSession(s.db, sessionID)
+}
+
+func loadSession(db execer, sessionID string) (*agent.SessionState, error) {
+	meta, err := getMetadata(db, sessionID)
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("load metadata: %w", err)
+	}
+
+	entries, err := loadMessages(db, `SELECT data FROM messages WHERE session_id = ? ORDER BY seq`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("load messages: %w", err)
+	}
+	recoverMetadata(&meta, entries)
+
+	return &agent.SessionState{
+		Metadata: meta,
+		Messages: entries,
+	}, nil
+}
+
+// LoadLatest finds the most recently updated session for the given CWD.
+func (s *SQLiteStore) LoadLatest(cwd string) (*agent.SessionState, error) {
+	var id string
+	err := s.db.QueryRow(`SELECT id FROM sessions WHERE cwd = ? ORDER BY updated_at DESC LIMIT 1`, cwd).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Load(id)
+}
+
+// Delete removes a session and everything recorded for it.
+func (s *SQLiteStore) Delete(sessionID string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`DELETE FROM sessions WHERE id = ?`, sessionID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrSessionNotFound
+	}
+	for _, table := range []string{"messages", "transcript", "checkpoints", "checkpoint_blobs", "web_resources"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE session_id = ?`, sessionID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// List returns metadata for all sessions, most recently updated first.
+func (s *SQLiteStore) List() ([]agent.SessionMetadata, error) {
+	return s.ListSessions(agent.SessionFilter{})
+}
+
+// sqliteSessionOrder maps a SessionSort to its ORDER BY expression.
+var sqliteSessionOrder = map[agent.SessionSort]string{
+	agent.SessionSortCreated: `julianday(json_extract(metadata, '$.created_at'))`,
+	agent.SessionSortCost:    `json_extract(metadata, '$.total_cost_usd')`,
+	agent.SessionSortTurns:   `json_extract(metadata, '$.turn_count')`,
+}
+
+// ListSessions returns the sessions matching filter, filtered, sorted and
+// limited in a single query.
+func (s *SQLiteStore) ListSessions(filter agent.SessionFilter) ([]agent.SessionMetadata, error) {
+	var where []string
+	var args []any
+	cond := func(clause string, arg any) {
+		where = append(where, clause)
+		args = append(args, arg)
+	}
+	if filter.CWD != "" {
+		cond(`cwd = ?`, filter.CWD)
+	}
+	if filter.AgentName != "" {
+		cond(`json_extract(metadata, '$.agent_name') = ?`, filter.AgentName)
+	}
+	if !filter.Since.IsZero() {
+		cond(`updated_at >= ?`, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		cond(`updated_at < ?`, filter.Until.UnixNano())
+	}
+	if filter.MinCostUSD > 0 {
+		cond(`json_extract(metadata, '$.total_cost_usd') >= ?`, filter.MinCostUSD)
+	}
+	if filter.MaxCostUSD > 0 {
+		cond(`json_extract(metadata, '$.total_cost_usd') <= ?`, filter.MaxCostUSD)
+	}
+
+	query := `SELECT metadata FROM sessions`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, ` AND `)
+	}
+	dir := ` DESC`
+	if filter.Ascending {
+		dir = ` ASC`
+	}
+	query += ` ORDER BY `
+	if order, ok := sqliteSessionOrder[filter.SortBy]; ok {
+		query += order + dir + `, `
+	}
+	query += `updated_at` + dir
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []agent.SessionMetadata
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var meta agent.SessionMetadata
+		if err := json.Unmarshal([]byte(data), &meta); err != nil {
+			continue // skip corrupt sessions
+		}
+		sessions = append(sessions, meta)
+	}
+	return sessions, rows.Err()
+}
+
+// Fork creates a new session as a copy of an existing one. Messages are
+// copied with a single INSERT ... SELECT inside the same transaction.
+func (s *SQLiteStore) Fork(sourceID, newID string) (*agent.SessionState, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	source, err := getMetadata(tx, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("load source session: %w", err)
+	}
+
+	now := time.Now()
+	newMeta := source
+	newMeta.ID = newID
+	newMeta.ParentSessionID = sourceID
+	newMeta.CreatedAt = now
+	newMeta.UpdatedAt = now
+
+	if err := putMetadata(tx, newMeta); err != nil {
+		return nil, fmt.Errorf("create forked session: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO messages (session_id, uuid, data)
+		SELECT ?, uuid, json_set(data, '$.isReplay', json('true')) FROM messages WHERE session_id = ? ORDER BY seq`, newID, sourceID); err != nil {
+		return nil, fmt.Errorf("copy messages to fork: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.LoadMessages(newID)
+	if err != nil {
+		return nil, err
+	}
+	return &agent.SessionState{
+		Metadata: newMeta,
+		Messages: entries,
+	}, nil
+}
+
+// ForkAt creates a new session branched from sourceID at messageUUID,
+// optionally replacing the branch point. The source is read in the same
+// transaction, so an append racing the fork is either in it or after it.
+func (s *SQLiteStore) ForkAt(sourceID, messageUUID string, replacement *llm.ChatMessage) (*agent.SessionState, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	source, err := loadSession(tx, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("load source session: %w", err)
+	}
+	entries, err := branchEntries(source.Messages, messageUUID, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	newMeta := forkAtMetadata(source.Metadata, messageUUID, entries)
+	if err := putMetadata(tx, newMeta); err != nil {
+		return nil, fmt.Errorf("create forked session: %w", err)
+	}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`INSERT INTO messages (session_id, uuid, data) VALUES (?, ?, ?)`, newMeta.ID, nullableUUID(entry.UUID), string(data)); err != nil {
+			return nil, fmt.Errorf("copy message to fork: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &agent.SessionState{
+		Metadata: newMeta,
+		Messages: entries,
+	}, nil
+}
+
+// AppendMessage appends a MessageEntry to the session's message log. It also
+// advances the session's updated_at column, so LoadLatest orders a session
+// that crashed before flushing its metadata by its last message.
+func (s *SQLiteStore) AppendMessage(sessionID string, entry agent.MessageEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO messages (session_id, uuid, data) VALUES (?, ?, ?)`, sessionID, nullableUUID(entry.UUID), string(data))
+	if err != nil {
+		return fmt.Errorf("append message: %w", err)
+	}
+	if !entry.Timestamp.IsZero() {
+		_, err = s.db.Exec(`UPDATE sessions SET updated_at = max(updated_at, ?) WHERE id = ?`, entry.Timestamp.UnixNano(), sessionID)
+	}
+	return err
+}
+
+// nullableUUID stores a missing UUID as NULL, which the (session_id, uuid)
+// uniqueness constraint ignores.
+func nullableUUID(uuid string) any {
+	if uuid == "" {
+		return nil
+	}
+	return uuid
+}
+
+// AppendSDKMessage appends an SDKMessage to the session's transcript.
+func (s *SQLiteStore) AppendSDKMessage(sessionID string, msg types.SDKMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO transcript (session_id, data) VALUES (?, ?)`, sessionID, string(data))
+	return err
+}
+
+// LoadMessages reads all MessageEntry records for a session in append order.
+func (s *SQLiteStore) LoadMessages(sessionID string) ([]agent.MessageEntry, error) {
+	return loadMessages(s.db, `SELECT data FROM messages WHERE session_id = ? ORDER BY seq`, sessionID)
+}
+
+// LoadMessagesUpTo reads messages up to and including the specified UUID.
+// If the UUID is not found, all messages are returned.
+func (s *SQLiteStore) LoadMessagesUpTo(sessionID string, messageUUID string) ([]agent.MessageEntry, error) {
+	var seq int64
+	err := s.db.QueryRow(`SELECT seq FROM messages WHERE session_id = ? AND uuid = ?`, sessionID, messageUUID).Scan(&seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.LoadMessages(sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return loadMessages(s.db, `SELECT data FROM messages WHERE session_id = ? AND seq <= ? ORDER BY seq`, sessionID, seq)
+}
+
+func loadMessages(db execer, query string, args ...any) ([]agent.MessageEntry, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []agent.MessageEntry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var entry agent.MessageEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			continue // skip corrupt rows
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateMetadata atomically updates the session's metadata using fn.
+func (s *SQLiteStore) UpdateMetadata(sessionID string, fn func(*agent.SessionMetadata)) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	meta, err := getMetadata(tx, sessionID)
+	if err != nil {
+		return fmt.Errorf("load metadata for update: %w", err)
+	}
+	fn(&meta)
+	meta.UpdatedAt = time.Now()
+	if err := putMetadata(tx, meta); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CreateCheckpoint snapshots the specified files for the given session/message.
+// File contents are stored once per session, keyed by hash. Calling it again
+// for the same checkpoint adds paths not yet recorded.
+func (s *SQLiteStore) CreateCheckpoint(sessionID, userMsgUUID string, filePaths []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, err := getManifest(tx, sessionID, userMsgUUID)
+	if err != nil && err != ErrCheckpointMissing {
+		return err
+	}
+	manifest, err := snapshotFiles(userMsgUUID, unrecordedPaths(existing, filePaths), func(hash string, data []byte) error {
+		_, err := tx.Exec(`INSERT OR IGNORE INTO checkpoint_blobs (session_id, hash, data) VALUES (?, ?, ?)`, sessionID, hash, data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	manifest = mergeManifest(existing, manifest)
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO checkpoints (session_id, user_msg_uuid, manifest) VALUES (?, ?, ?)`,
+		sessionID, userMsgUUID, string(data)); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RewindFiles restores files to a previous checkpoint state.
+func (s *SQLiteStore) RewindFiles(sessionID, userMsgUUID string, dryRun bool) (*agent.RewindFilesResult, error) {
+	manifest, err := getManifest(s.db, sessionID, userMsgUUID)
+	if err != nil {
+		return nil, err
+	}
+	return rewindManifest(manifest, s.loadBlob(sessionID), dryRun), nil
+}
+
+// Rewind restores the files recorded at a checkpoint and at every later one,
+// each to its earliest snapshot, and deletes the checkpoint's message and
+// everything after it. File restoration is
+// all-or-nothing; the message log is only truncated once it succeeds, along
+// with the checkpoints of the deleted turns so a later rewind does not
+// replay them. It returns the remaining messages.
+func (s *SQLiteStore) Rewind(sessionID, checkpointID string) ([]agent.MessageEntry, error) {
+	manifest, err := getManifest(s.db, sessionID, checkpointID)
+	if err != nil {
+		return nil, err
+	}
+	all, err := listManifests(s.db, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	merged, ids := rewindFrom(manifest, all)
+	if err := applyRewind(planRewind(merged), s.loadBlob(sessionID)); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var seq int64
+	err = tx.QueryRow(`SELECT seq FROM messages WHERE session_id = ? AND uuid = ?`, sessionID, manifest.UserMessageUUID).Scan(&seq)
+	if err == nil {
+		if _, err := tx.Exec(`DELETE FROM messages WHERE session_id = ? AND seq >= ?`, sessionID, seq); err != nil {
+			return nil, fmt.Errorf("truncate messages: %w", err)
+		}
+		kept, err := loadMessages(tx, `SELECT data FROM messages WHERE session_id = ? ORDER BY seq`, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		if err := deleteCheckpoints(tx, sessionID, discardedCheckpoints(ids, kept)); err != nil {
+			return nil, fmt.Errorf("remove discarded checkpoints: %w", err)
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM messages WHERE session_id = ?`, sessionID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if meta, err := getMetadata(tx, sessionID); err == nil {
+		meta.MessageCount = count
+		meta.UpdatedAt = time.Now()
+		if err := putMetadata(tx, meta); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.LoadMessages(sessionID)
+}
+
+// deleteCheckpoints removes the given checkpoints, then the snapshot content
+// no remaining checkpoint refers to.
+func deleteCheckpoints(db execer, sessionID string, userMsgUUIDs []string) error {
+	if len(userMsgUUIDs) == 0 {
+		return nil
+	}
+	for _, id := range userMsgUUIDs {
+		if _, err := db.Exec(`DELETE FROM checkpoints WHERE session_id = ? AND user_msg_uuid = ?`, sessionID, id); err != nil {
+			return err
+		}
+	}
+	remaining, err := listManifests(db, sessionID)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, m := range remaining {
+		for _, f := range m.Files {
+			used[f.Hash] = true
+		}
+	}
+	rows, err := db.Query(`SELECT hash FROM checkpoint_blobs WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return err
+	}
+	var unused []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return err
+		}
+		if !used[hash] {
+			unused = append(unused, hash)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, hash := range unused {
+		if _, err := db.Exec(`DELETE FROM checkpoint_blobs WHERE session_id = ? AND hash = ?`, sessionID, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) loadBlob(sessionID string) func(hash string) ([]byte, error) {
+	return func(hash string) ([]byte, error) {
+		var blob []byte
+		err := s.db.QueryRow(`SELECT data FROM checkpoint_blobs WHERE session_id = ? AND hash = ?`, sessionID, hash).Scan(&blob)
+		return blob, err
+	}
+}
+
+func getManifest(db execer, sessionID, userMsgUUID string) (CheckpointManifest, error) {
+	var manifest CheckpointManifest
+	var data string
+	err := db.QueryRow(`SELECT manifest FROM checkpoints WHERE session_id = ? AND user_msg_uuid = ?`,
+		sessionID, userMsgUUID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return manifest, ErrCheckpointMissing
+	}
+	if err != nil {
+		return manifest, fmt.Errorf("read manifest: %w", err)
+	}
+	if err := json.Unmarshal([]byte(data), &manifest); err != nil {
+		return manifest, fmt.Errorf("parse manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// listManifests reads the manifests of every checkpoint in the session.
+func listManifests(db execer, sessionID string) ([]CheckpointManifest, error) {
+	rows, err := db.Query(`SELECT manifest FROM checkpoints WHERE session_id = ?`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("read manifests: %w", err)
+	}
+	defer rows.Close()
+	var manifests []CheckpointManifest
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("read manifests: %w", err)
+		}
+		var manifest CheckpointManifest
+		if err := json.Unmarshal([]byte(data), &manifest); err != nil {
+			return nil, fmt.Errorf("parse manifest: %w", err)
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests, rows.Err()
+}
+
+// Close releases the database.
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
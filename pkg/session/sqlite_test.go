@@ -0,0 +1,471 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/types"
+)
+
+var _ agent.SessionStore = (*SQLiteStore)(nil)
+
+func newTestSQLiteStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	s, err := NewSQLiteStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteStore_CreateAndLoad(t *testing.T) {
+	s := newTestSQLiteStore(t)
+
+	meta := testMetadata("sess-1", "/tmp/project")
+	meta.AgentName = "reviewer"
+	if err := s.Create(meta); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	state, err := s.Load("sess-1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if state.Metadata.ID != "sess-1" || state.Metadata.CWD != "/tmp/project" || state.Metadata.AgentName != "reviewer" {
+		t.Errorf("unexpected metadata: %+v", state.Metadata)
+	}
+	if len(state.Messages) != 0 {
+		t.Errorf("expected no messages, got %d", len(state.Messages))
+	}
+
+	if _, err := s.Load("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Load(missing) err = %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestSQLiteStore_AppendPreservesOrder(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	s.Create(testMetadata("sess-1", "/tmp"))
+
+	for i := 0; i < 5; i++ {
+		entry := testMessageEntry(fmt.Sprintf("msg-%d", i), "user", fmt.Sprintf("hello %d", i))
+		if err := s.AppendMessage("sess-1", entry); err != nil {
+			t.Fatalf("AppendMessage: %v", err)
+		}
+	}
+	if err := s.AppendMessage("sess-1", testMessageEntry("msg-0", "user", "dup")); err == nil {
+		t.Error("expected duplicate UUID in a session to be rejected")
+	}
+
+	msgs, err := s.LoadMessages("sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 5 {
+		t.Fatalf("got %d messages, want 5", len(msgs))
+	}
+	for i, m := range msgs {
+		if m.UUID != fmt.Sprintf("msg-%d", i) || m.Message.Content != fmt.Sprintf("hello %d", i) {
+			t.Errorf("message %d = %s %v", i, m.UUID, m.Message.Content)
+		}
+	}
+
+	upTo, err := s.LoadMessagesUpTo("sess-1", "msg-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upTo) != 3 || upTo[2].UUID != "msg-2" {
+		t.Errorf("LoadMessagesUpTo returned %d messages", len(upTo))
+	}
+	if all, _ := s.LoadMessagesUpTo("sess-1", "nope"); len(all) != 5 {
+		t.Errorf("unknown UUID should return all messages, got %d", len(all))
+	}
+}
+
+func TestSQLiteStore_AppendSDKMessage(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	msg := &types.ResultMessage{BaseMessage: types.BaseMessage{SessionID: "sess-1"}, Result: "done"}
+	if err := s.AppendSDKMessage("sess-1", msg); err != nil {
+		t.Fatalf("AppendSDKMessage: %v", err)
+	}
+	var n int
+	s.db.QueryRow(`SELECT COUNT(*) FROM transcript WHERE session_id = ?`, "sess-1").Scan(&n)
+	if n != 1 {
+		t.Errorf("transcript rows = %d, want 1", n)
+	}
+}
+
+func TestSQLiteStore_ListAndLoadLatest(t *testing.T) {
+	s := newTestSQLiteStore(t)
+
+	base := time.Now()
+	for i, id := range []string{"old", "new", "other"} {
+		meta := testMetadata(id, "/proj")
+		if id == "other" {
+			meta.CWD = "/elsewhere"
+		}
+		meta.UpdatedAt = base.Add(time.Duration(i) * time.Minute)
+		s.Create(meta)
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 || list[0].ID != "other" || list[2].ID != "old" {
+		t.Errorf("List not sorted by UpdatedAt desc: %v", list)
+	}
+
+	latest, err := s.LoadLatest("/proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Metadata.ID != "new" {
+		t.Errorf("LoadLatest = %s, want new", latest.Metadata.ID)
+	}
+	if _, err := s.LoadLatest("/none"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("LoadLatest(/none) err = %v", err)
+	}
+}
+
+func TestSQLiteStore_LoadLatestIncomplete(t *testing.T) {
+	s := newTestSQLiteStore(t)
+
+	clean := testMetadata("clean", "/proj")
+	clean.UpdatedAt = time.Now().Add(-30 * time.Minute)
+	s.Create(clean)
+	crashed := testMetadata("crashed", "/proj")
+	crashed.UpdatedAt = time.Now().Add(-time.Hour)
+	crashed.Incomplete = true
+	s.Create(crashed)
+	s.AppendMessage("crashed", agent.MessageEntry{UUID: "m1", Timestamp: time.Now()})
+
+	latest, err := s.LoadLatest("/proj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest.Metadata.ID != "crashed" {
+		t.Fatalf("LoadLatest = %s, want crashed", latest.Metadata.ID)
+	}
+	if latest.Metadata.MessageCount != 1 || !latest.Metadata.UpdatedAt.After(clean.UpdatedAt) {
+		t.Errorf("recovered metadata = %+v", latest.Metadata)
+	}
+}
+
+func TestSQLiteStore_ListSessions(t *testing.T) {
+	testListSessions(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteStore_Fork(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	s.Create(testMetadata("src", "/tmp"))
+	s.AppendMessage("src", testMessageEntry("m1", "user", "hi"))
+	s.AppendMessage("src", testMessageEntry("m2", "assistant", "hello"))
+
+	forked, err := s.Fork("src", "fork")
+	if err != nil {
+		t.Fatalf("Fork: %v", err)
+	}
+	if forked.Metadata.ID != "fork" || forked.Metadata.ParentSessionID != "src" {
+		t.Errorf("unexpected fork metadata: %+v", forked.Metadata)
+	}
+	if len(forked.Messages) != 2 || forked.Messages[1].UUID != "m2" {
+		t.Errorf("fork messages = %v", forked.Messages)
+	}
+
+	// The fork is independent of its source
+	s.AppendMessage("fork", testMessageEntry("m3", "user", "more"))
+	src, _ := s.LoadMessages("src")
+	if len(src) != 2 {
+		t.Errorf("source should still have 2 messages, got %d", len(src))
+	}
+	msgs, _ := s.LoadMessages("fork")
+	if !msgs[0].IsReplay || !msgs[1].IsReplay || msgs[2].IsReplay || src[0].IsReplay {
+		t.Error("only messages copied from the source should be marked as replayed")
+	}
+
+	if _, err := s.Fork("missing", "x"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Fork(missing) err = %v", err)
+	}
+}
+
+func TestSQLiteStore_ForkAt(t *testing.T) {
+	testForkAt(t, newTestSQLiteStore(t))
+}
+
+func TestSQLiteStore_UpdateMetadataAndDelete(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	meta := testMetadata("sess-1", "/tmp")
+	meta.UpdatedAt = time.Now().Add(-time.Hour)
+	s.Create(meta)
+	s.AppendMessage("sess-1", testMessageEntry("m1", "user", "hi"))
+
+	if err := s.UpdateMetadata("sess-1", func(m *agent.SessionMetadata) {
+		m.TurnCount = 3
+		m.TotalCostUSD = 0.5
+	}); err != nil {
+		t.Fatalf("UpdateMetadata: %v", err)
+	}
+	state, _ := s.Load("sess-1")
+	if state.Metadata.TurnCount != 3 || state.Metadata.TotalCostUSD != 0.5 {
+		t.Errorf("metadata not updated: %+v", state.Metadata)
+	}
+	if !state.Metadata.UpdatedAt.After(meta.UpdatedAt) {
+		t.Error("UpdatedAt should be bumped")
+	}
+	if err := s.UpdateMetadata("missing", func(*agent.SessionMetadata) {}); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("UpdateMetadata(missing) err = %v", err)
+	}
+
+	if err := s.Delete("sess-1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if msgs, _ := s.LoadMessages("sess-1"); len(msgs) != 0 {
+		t.Errorf("messages should be deleted, got %d", len(msgs))
+	}
+	if err := s.Delete("sess-1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("second Delete err = %v", err)
+	}
+}
+
+func TestSQLiteStore_CheckpointRewind(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.txt")
+	created := filepath.Join(dir, "b.txt")
+	os.WriteFile(existing, []byte("original"), 0o644)
+
+	if err := s.CreateCheckpoint("sess-1", "u1", []string{existing, created}); err != nil {
+		t.Fatalf("CreateCheckpoint: %v", err)
+	}
+	os.WriteFile(existing, []byte("modified"), 0o644)
+	os.WriteFile(created, []byte("new"), 0o644)
+
+	dry, err := s.RewindFiles("sess-1", "u1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dry.FilesChanged) != 2 || dry.Insertions != 1 || dry.Deletions != 1 {
+		t.Errorf("dry run result = %+v", dry)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "modified" {
+		t.Error("dry run should not modify files")
+	}
+
+	res, err := s.RewindFiles("sess-1", "u1", false)
+	if err != nil || !res.CanRewind {
+		t.Fatalf("RewindFiles: %v %+v", err, res)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "original" {
+		t.Errorf("a.txt = %q, want original", data)
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Error("b.txt should be removed")
+	}
+
+	if _, err := s.RewindFiles("sess-1", "missing", false); !errors.Is(err, ErrCheckpointMissing) {
+		t.Errorf("missing checkpoint err = %v", err)
+	}
+}
+
+func TestSQLiteStore_ReopenAndWAL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Create(testMetadata("sess-1", "/tmp"))
+	s.AppendMessage("sess-1", testMessageEntry("m1", "user", "hi"))
+
+	var mode string
+	s.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode)
+	if !strings.EqualFold(mode, "wal") {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+
+	// A second connection can read while the first is open
+	reader, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("open reader: %v", err)
+	}
+	if msgs, _ := reader.LoadMessages("sess-1"); len(msgs) != 1 {
+		t.Errorf("reader saw %d messages, want 1", len(msgs))
+	}
+	reader.Close()
+	s.Close()
+
+	// Reopening does not re-run migrations
+	s, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	var version int
+	s.db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	if version != len(sqliteMigrations) {
+		t.Errorf("user_version = %d, want %d", version, len(sqliteMigrations))
+	}
+	if state, err := s.Load("sess-1"); err != nil || len(state.Messages) != 1 {
+		t.Errorf("data not persisted across reopen: %v", err)
+	}
+}
+
+func TestSQLiteStore_PathWithURICharacters(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a?b#c%20d")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "sessions.db")
+	s, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer s.Close()
+	if err := s.Create(testMetadata("sess-1", "/tmp")); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at %s: %v", path, err)
+	}
+	var mode string
+	s.db.QueryRow(`PRAGMA journal_mode`).Scan(&mode)
+	if !strings.EqualFold(mode, "wal") {
+		t.Errorf("journal_mode = %q, want wal", mode)
+	}
+}
+
+func TestSQLiteStore_ConcurrentAppend(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	s.Create(testMetadata("sess-1", "/tmp"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := s.AppendMessage("sess-1", testMessageEntry(fmt.Sprintf("m%d", i), "user", "x")); err != nil {
+				t.Errorf("AppendMessage: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if msgs, _ := s.LoadMessages("sess-1"); len(msgs) != 20 {
+		t.Errorf("got %d messages, want 20", len(msgs))
+	}
+}
+
+func TestSQLiteStore_Rewind(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	s.Create(testMetadata("sess-1", "/tmp"))
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.txt")
+	os.WriteFile(file, []byte("v1"), 0o600)
+
+	s.AppendMessage("sess-1", testMessageEntry("u1", "user", "first"))
+	s.AppendMessage("sess-1", testMessageEntry("u2", "user", "second"))
+	s.CreateCheckpoint("sess-1", "u2", []string{file})
+	os.WriteFile(file, []byte("v2"), 0o644)
+	os.Chmod(file, 0o644)
+	s.AppendMessage("sess-1", testMessageEntry("a2", "assistant", "done"))
+
+	kept, err := s.Rewind("sess-1", "u2")
+	if err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	if len(kept) != 1 || kept[0].UUID != "u1" {
+		t.Errorf("kept = %v, want [u1]", kept)
+	}
+	info, _ := os.Stat(file)
+	if data, _ := os.ReadFile(file); string(data) != "v1" || info.Mode().Perm() != 0o600 {
+		t.Errorf("a.txt = %q mode %v, want v1 0600", data, info.Mode().Perm())
+	}
+	state, _ := s.Load("sess-1")
+	if state.Metadata.MessageCount != 1 {
+		t.Errorf("MessageCount = %d, want 1", state.Metadata.MessageCount)
+	}
+	if _, err := s.Rewind("sess-1", "missing"); !errors.Is(err, ErrCheckpointMissing) {
+		t.Errorf("err = %v, want ErrCheckpointMissing", err)
+	}
+}
+
+func TestSQLiteStore_RewindAcrossTurns(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	s.Create(testMetadata("sess-1", "/tmp"))
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("a1"), 0o644)
+	os.WriteFile(b, []byte("b1"), 0o644)
+
+	s.AppendMessage("sess-1", testMessageEntry("u1", "user", "edit a"))
+	s.CreateCheckpoint("sess-1", "u1", []string{a})
+	os.WriteFile(a, []byte("a2"), 0o644)
+	s.AppendMessage("sess-1", testMessageEntry("u2", "user", "edit b"))
+	s.CreateCheckpoint("sess-1", "u2", []string{a, b})
+	os.WriteFile(a, []byte("a3"), 0o644)
+	os.WriteFile(b, []byte("b2"), 0o644)
+
+	kept, err := s.Rewind("sess-1", "u1")
+	if err != nil {
+		t.Fatalf("Rewind: %v", err)
+	}
+	if len(kept) != 0 {
+		t.Errorf("kept = %v, want none", kept)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a1" {
+		t.Errorf("a.txt = %q, want a1", data)
+	}
+	if data, _ := os.ReadFile(b); string(data) != "b1" {
+		t.Errorf("b.txt = %q, want b1", data)
+	}
+}
+
+func TestSQLiteStore_RewindDropsDiscardedCheckpoints(t *testing.T) {
+	s := newTestSQLiteStore(t)
+	s.Create(testMetadata("sess-1", "/tmp"))
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("a1"), 0o644)
+	os.WriteFile(b, []byte("b1"), 0o644)
+
+	s.AppendMessage("sess-1", testMessageEntry("u1", "user", "edit a"))
+	s.CreateCheckpoint("sess-1", "u1", []string{a})
+	os.WriteFile(a, []byte("a2"), 0o644)
+	s.AppendMessage("sess-1", testMessageEntry("u2", "user", "edit b"))
+	s.CreateCheckpoint("sess-1", "u2", []string{b})
+	os.WriteFile(b, []byte("b2"), 0o644)
+
+	if _, err := s.Rewind("sess-1", "u2"); err != nil {
+		t.Fatalf("Rewind u2: %v", err)
+	}
+	if _, err := s.RewindFiles("sess-1", "u2", true); !errors.Is(err, ErrCheckpointMissing) {
+		t.Errorf("RewindFiles(u2) error = %v, want ErrCheckpointMissing", err)
+	}
+	var blobs int
+	s.db.QueryRow(`SELECT COUNT(*) FROM checkpoint_blobs WHERE session_id = ?`, "sess-1").Scan(&blobs)
+	if blobs != 1 {
+		t.Errorf("blobs = %d, want 1 (only u1's snapshot)", blobs)
+	}
+
+	os.WriteFile(b, []byte("b-user"), 0o644)
+	if _, err := s.Rewind("sess-1", "u1"); err != nil {
+		t.Fatalf("Rewind u1: %v", err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "a1" {
+		t.Errorf("a.txt = %q, want a1", data)
+	}
+	if data, _ := os.ReadFile(b); string(data) != "b-user" {
+		t.Errorf("b.txt = %q, want b-user (untouched)", data)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/types"
 )
 
@@ -81,6 +82,7 @@ func (s *Store) Load(sessionID string) (*agent.SessionState, error) {
 	if err != nil {
 		return nil, fmt.Errorf("load messages: %w", err)
 	}
+	recoverMetadata(&meta, entries)
 
 	return &agent.SessionState{
 		Metadata: meta,
@@ -120,7 +122,9 @@ func (s *Store) Delete(sessionID string) error {
 	return os.RemoveAll(dir)
 }
 
-// List returns metadata for all sessions.
+// List returns metadata for all sessions. A session that never exited
+// cleanly counts as updated when its message log last was, so LoadLatest
+// finds it even if its metadata was not flushed.
 func (s *Store) List() ([]agent.SessionMetadata, error) {
 	entries, err := os.ReadDir(s.baseDir)
 	if err != nil {
@@ -139,6 +143,11 @@ func (s *Store) List() ([]agent.SessionMetadata, error) {
 		if err != nil {
 			continue // skip corrupt sessions
 		}
+		if meta.Incomplete {
+			if info, err := os.Stat(s.messagesPath(meta.ID)); err == nil && info.ModTime().After(meta.UpdatedAt) {
+				meta.UpdatedAt = info.ModTime()
+			}
+		}
 		sessions = append(sessions, meta)
 	}
 
@@ -150,6 +159,16 @@ func (s *Store) List() ([]agent.SessionMetadata, error) {
 	return sessions, nil
 }
 
+// ListSessions returns the sessions matching filter. It reads every
+// session's metadata file.
+func (s *Store) ListSessions(filter agent.SessionFilter) ([]agent.SessionMetadata, error) {
+	sessions, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	return agent.FilterSessions(sessions, filter), nil
+}
+
 // Fork creates a new session as a copy of an existing one.
 func (s *Store) Fork(sourceID, newID string) (*agent.SessionState, error) {
 	source, err := s.Load(sourceID)
@@ -169,6 +188,7 @@ func (s *Store) Fork(sourceID, newID string) (*agent.SessionState, error) {
 	}
 
 	// Copy messages
+	markReplayed(source.Messages)
 	for _, entry := range source.Messages {
 		if err := appendJSONL(s.messagesPath(newID), entry); err != nil {
 			return nil, fmt.Errorf("copy message to fork: %w", err)
@@ -181,6 +201,34 @@ func (s *Store) Fork(sourceID, newID string) (*agent.SessionState, error) {
 	}, nil
 }
 
+// ForkAt creates a new session branched from sourceID at messageUUID,
+// optionally replacing the branch point.
+func (s *Store) ForkAt(sourceID, messageUUID string, replacement *llm.ChatMessage) (*agent.SessionState, error) {
+	source, err := s.Load(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("load source session: %w", err)
+	}
+	entries, err := branchEntries(source.Messages, messageUUID, replacement)
+	if err != nil {
+		return nil, err
+	}
+
+	newMeta := forkAtMetadata(source.Metadata, messageUUID, entries)
+	if err := s.Create(newMeta); err != nil {
+		return nil, fmt.Errorf("create forked session: %w", err)
+	}
+	for _, entry := range entries {
+		if err := appendJSONL(s.messagesPath(newMeta.ID), entry); err != nil {
+			return nil, fmt.Errorf("copy message to fork: %w", err)
+		}
+	}
+
+	return &agent.SessionState{
+		Metadata: newMeta,
+		Messages: entries,
+	}, nil
+}
+
 // AppendMessage writes a MessageEntry to the session's JSONL log via the async writer.
 func (s *Store) AppendMessage(sessionID string, entry agent.MessageEntry) error {
 	if !s.persistEnabled {
@@ -264,6 +312,56 @@ func (s *Store) RewindFiles(sessionID, userMsgUUID string, dryRun bool) (*agent.
 	return cm.RewindFiles(userMsgUUID, dryRun)
 }
 
+// Rewind restores the files recorded at a checkpoint and at every later one,
+// each to its earliest snapshot, and deletes the checkpoint's message and
+// everything after it. File restoration is
+// all-or-nothing; the message log is only rewritten once it succeeds, and
+// then the checkpoints of the deleted turns are removed so a later rewind
+// does not replay them. It returns the remaining messages.
+func (s *Store) Rewind(sessionID, checkpointID string) ([]agent.MessageEntry, error) {
+	cm := newCheckpointManager(s.sessionDir(sessionID))
+	manifest, err := cm.loadManifest(checkpointID)
+	if err != nil {
+		return nil, err
+	}
+	all, err := cm.loadManifests()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.LoadMessages(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	merged, ids := rewindFrom(manifest, all)
+	if err := applyRewind(planRewind(merged), cm.loadBlobFrom(ids)); err != nil {
+		return nil, err
+	}
+
+	kept := truncateBefore(entries, manifest.UserMessageUUID)
+	if len(kept) == len(entries) || !s.persistEnabled {
+		return kept, nil
+	}
+	path := s.messagesPath(sessionID)
+	// The writer appends through a cached handle, which would keep pointing at
+	// the replaced file
+	s.writer.release(path)
+	if err := rewriteJSONL(path, kept); err != nil {
+		return nil, fmt.Errorf("truncate messages: %w", err)
+	}
+	_ = s.UpdateMetadata(sessionID, func(m *agent.SessionMetadata) {
+		m.MessageCount = len(kept)
+	})
+	if err := cm.removeCheckpoints(discardedCheckpoints(ids, kept)); err != nil {
+		return nil, fmt.Errorf("remove discarded checkpoints: %w", err)
+	}
+	return kept, nil
+}
+
+// Flush waits for queued writes to reach disk.
+func (s *Store) Flush() error {
+	return s.writer.Flush()
+}
+
 // Close flushes the async writer and releases resources.
 func (s *Store) Close() error {
 	return s.writer.Close()
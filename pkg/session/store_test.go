@@ -245,6 +245,36 @@ func TestStore_LoadLatest(t *testing.T) {
 	}
 }
 
+func TestStore_LoadLatest_Incomplete(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+
+	clean := testMetadata("sess-clean", "/tmp/project")
+	clean.UpdatedAt = time.Now().Add(-30 * time.Minute)
+	s.Create(clean)
+
+	// Crashed before its metadata was flushed; only the message log is current
+	crashed := testMetadata("sess-crashed", "/tmp/project")
+	crashed.UpdatedAt = time.Now().Add(-time.Hour)
+	crashed.Incomplete = true
+	s.Create(crashed)
+	for _, uuid := range []string{"m1", "m2"} {
+		s.AppendMessage("sess-crashed", agent.MessageEntry{UUID: uuid, Timestamp: time.Now()})
+	}
+	s.Flush()
+
+	state, err := s.LoadLatest("/tmp/project")
+	if err != nil {
+		t.Fatalf("LoadLatest: %v", err)
+	}
+	if state.Metadata.ID != "sess-crashed" {
+		t.Fatalf("LoadLatest returned %q, want sess-crashed", state.Metadata.ID)
+	}
+	if !state.Metadata.Incomplete || state.Metadata.MessageCount != 2 || !state.Metadata.UpdatedAt.After(clean.UpdatedAt) {
+		t.Errorf("recovered metadata = %+v", state.Metadata)
+	}
+}
+
 func TestStore_LoadLatest_NotFound(t *testing.T) {
 	s := newTestStore(t)
 	defer s.Close()
@@ -255,6 +285,75 @@ func TestStore_LoadLatest_NotFound(t *testing.T) {
 	}
 }
 
+// --- ListSessions Tests ---
+
+func TestStore_ListSessions(t *testing.T) {
+	s := newTestStore(t)
+	defer s.Close()
+	testListSessions(t, s)
+}
+
+// testListSessions checks ListSessions filters against any store.
+func testListSessions(t *testing.T, s agent.SessionStore) {
+	t.Helper()
+	base := time.Now().Add(-24 * time.Hour)
+	for i, spec := range []struct {
+		id, cwd, agent string
+		cost           float64
+		turns          int
+	}{
+		{"a", "/proj", "", 0.10, 3},
+		{"b", "/proj", "reviewer", 2.50, 12},
+		{"c", "/proj", "reviewer", 0.75, 1},
+		{"d", "/other", "", 5.00, 40},
+	} {
+		meta := testMetadata(spec.id, spec.cwd)
+		meta.CreatedAt = base.Add(time.Duration(3-i) * time.Hour) // created in reverse order
+		meta.UpdatedAt = base.Add(time.Duration(i) * time.Hour)
+		meta.AgentName = spec.agent
+		meta.TotalCostUSD = spec.cost
+		meta.TurnCount = spec.turns
+		meta.ExitReason = "end_turn"
+		if err := s.Create(meta); err != nil {
+			t.Fatalf("Create %s: %v", spec.id, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter agent.SessionFilter
+		want   string
+	}{
+		{"all, newest first", agent.SessionFilter{}, "dcba"},
+		{"cwd", agent.SessionFilter{CWD: "/proj"}, "cba"},
+		{"agent", agent.SessionFilter{AgentName: "reviewer"}, "cb"},
+		{"date range", agent.SessionFilter{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, "cb"},
+		{"cost range", agent.SessionFilter{MinCostUSD: 0.5, MaxCostUSD: 3}, "cb"},
+		{"by cost", agent.SessionFilter{SortBy: agent.SessionSortCost}, "dbca"},
+		{"by turns ascending", agent.SessionFilter{SortBy: agent.SessionSortTurns, Ascending: true}, "cabd"},
+		{"by created", agent.SessionFilter{SortBy: agent.SessionSortCreated}, "abcd"},
+		{"limit", agent.SessionFilter{CWD: "/proj", Limit: 2}, "cb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessions, err := s.ListSessions(tt.filter)
+			if err != nil {
+				t.Fatalf("ListSessions: %v", err)
+			}
+			var got string
+			for _, meta := range sessions {
+				got += meta.ID
+			}
+			if got != tt.want {
+				t.Errorf("ListSessions = %q, want %q", got, tt.want)
+			}
+			if len(sessions) > 0 && sessions[0].ExitReason != "end_turn" {
+				t.Errorf("ExitReason not returned: %+v", sessions[0])
+			}
+		})
+	}
+}
+
 // --- Fork Tests ---
 
 func TestStore_Fork(t *testing.T) {
@@ -294,6 +393,12 @@ func TestStore_Fork(t *testing.T) {
 	if len(forkMsgs) != 3 {
 		t.Errorf("fork messages after append = %d, want 3", len(forkMsgs))
 	}
+	if !forkMsgs[0].IsReplay || !forkMsgs[1].IsReplay || forkMsgs[2].IsReplay {
+		t.Error("only messages copied from the source should be marked as replayed")
+	}
+	if srcMsgs[0].IsReplay {
+		t.Error("source messages should not be marked as replayed")
+	}
 }
 
 func TestStore_Fork_NotFound(t *testing.T) {
@@ -525,6 +630,21 @@ func TestWriter_FlushOnClose(t *testing.T) {
 	}
 }
 
+func TestWriter_FlushReportsWriteError(t *testing.T) {
+	w := newAsyncWriter()
+	defer w.Close()
+	dir := t.TempDir()
+
+	w.Write(filepath.Join(dir, "missing", "file.log"), []byte("lost\n"), nil)
+	w.Write(filepath.Join(dir, "ok.log"), []byte("kept\n"), nil)
+	if err := w.Flush(); err == nil {
+		t.Error("Flush should report the failed fire-and-forget write")
+	}
+	if err := w.Flush(); err != nil {
+		t.Errorf("second Flush = %v, want nil once the error was reported", err)
+	}
+}
+
 func TestWriter_MultipleFiles(t *testing.T) {
 	w := newAsyncWriter()
 	dir := t.TempDir()
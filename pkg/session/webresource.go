@@ -0,0 +1,95 @@
+package session
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+const webResourcesDir = "web"
+
+var (
+	_ agent.WebResourceStore = (*Store)(nil)
+	_ agent.WebResourceStore = (*SQLiteStore)(nil)
+)
+
+// webResourcePath names a resource's file by a hash of its kind and key,
+// which may be any URL or search query.
+func (s *Store) webResourcePath(sessionID, kind, key string) string {
+	sum := sha256.Sum256([]byte(kind + "\x00" + key))
+	return filepath.Join(s.sessionDir(sessionID), webResourcesDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// LoadWebResource returns the WebFetch or WebSearch result stored with the
+// session for kind and key.
+func (s *Store) LoadWebResource(sessionID, kind, key string) (tools.WebResource, bool, error) {
+	var res tools.WebResource
+	data, err := os.ReadFile(s.webResourcePath(sessionID, kind, key))
+	if os.IsNotExist(err) {
+		return res, false, nil
+	}
+	if err != nil {
+		return res, false, err
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return res, false, fmt.Errorf("decode web resource: %w", err)
+	}
+	return res, true, nil
+}
+
+// SaveWebResource stores a WebFetch or WebSearch result with the session,
+// replacing any earlier one for the same kind and key.
+func (s *Store) SaveWebResource(sessionID string, res tools.WebResource) error {
+	if !s.persistEnabled {
+		return nil
+	}
+	path := s.webResourcePath(sessionID, res.Kind, res.Key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create web resource dir: %w", err)
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadWebResource returns the WebFetch or WebSearch result stored with the
+// session for kind and key.
+func (s *SQLiteStore) LoadWebResource(sessionID, kind, key string) (tools.WebResource, bool, error) {
+	var res tools.WebResource
+	var data string
+	err := s.db.QueryRow(`SELECT data FROM web_resources WHERE session_id = ? AND kind = ? AND key = ?`,
+		sessionID, kind, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return res, false, nil
+	}
+	if err != nil {
+		return res, false, err
+	}
+	if err := json.Unmarshal([]byte(data), &res); err != nil {
+		return res, false, fmt.Errorf("decode web resource: %w", err)
+	}
+	return res, true, nil
+}
+
+// SaveWebResource stores a WebFetch or WebSearch result with the session,
+// replacing any earlier one for the same kind and key.
+func (s *SQLiteStore) SaveWebResource(sessionID string, res tools.WebResource) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO web_resources (session_id, kind, key, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT (session_id, kind, key) DO UPDATE SET data = excluded.data`,
+		sessionID, res.Kind, res.Key, string(data))
+	return err
+}
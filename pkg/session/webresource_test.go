@@ -0,0 +1,59 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/agent"
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+type webResourceStore interface {
+	agent.SessionStore
+	agent.WebResourceStore
+}
+
+func TestWebResources(t *testing.T) {
+	stores := map[string]func(t *testing.T) webResourceStore{
+		"file":   func(t *testing.T) webResourceStore { return newTestStore(t) },
+		"sqlite": func(t *testing.T) webResourceStore { return newTestSQLiteStore(t) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			s := newStore(t)
+			if err := s.Create(agent.SessionMetadata{ID: "s1", CWD: "/tmp"}); err != nil {
+				t.Fatal(err)
+			}
+			url := "https://example.com/docs?page=2"
+			if _, ok, err := s.LoadWebResource("s1", tools.WebResourceFetch, url); ok || err != nil {
+				t.Fatalf("LoadWebResource before save = %v, %v; want a miss", ok, err)
+			}
+
+			fetched := time.Now().Truncate(time.Second)
+			for _, content := range []string{"old", "new"} {
+				if err := s.SaveWebResource("s1", tools.WebResource{Kind: tools.WebResourceFetch, Key: url, Content: content, FetchedAt: fetched}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			res, ok, err := s.LoadWebResource("s1", tools.WebResourceFetch, url)
+			if err != nil || !ok || res.Content != "new" || !res.FetchedAt.Equal(fetched) {
+				t.Errorf("LoadWebResource = %+v, %v, %v; want the latest save", res, ok, err)
+			}
+
+			// Resources are per session and per kind.
+			if _, ok, _ := s.LoadWebResource("s2", tools.WebResourceFetch, url); ok {
+				t.Error("resource visible from another session")
+			}
+			if _, ok, _ := s.LoadWebResource("s1", tools.WebResourceSearch, url); ok {
+				t.Error("fetch resource returned for a search")
+			}
+
+			if err := s.Delete("s1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok, _ := s.LoadWebResource("s1", tools.WebResourceFetch, url); ok {
+				t.Error("resource survived session delete")
+			}
+		})
+	}
+}
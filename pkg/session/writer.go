@@ -27,6 +27,10 @@ type asyncWriter struct {
 	done  chan struct{}
 	mu    sync.Mutex
 	files map[string]*os.File
+
+	// flushErr is the first write error since the last Flush. Only the run
+	// goroutine touches it.
+	flushErr error
 }
 
 func newAsyncWriter() *asyncWriter {
@@ -88,7 +92,15 @@ func (w *asyncWriter) run() {
 
 func (w *asyncWriter) flushAll(ops []writeOp) {
 	for _, op := range ops {
+		if op.path == "" { // Flush marker: everything queued before it is written
+			op.err <- w.flushErr
+			w.flushErr = nil
+			continue
+		}
 		err := w.writeToFile(op.path, op.data)
+		if err != nil && w.flushErr == nil {
+			w.flushErr = err
+		}
 		if op.err != nil {
 			op.err <- err
 		}
@@ -131,6 +143,24 @@ func (w *asyncWriter) Write(path string, data []byte, errCh chan error) {
 	w.ch <- writeOp{path: path, data: data, err: errCh}
 }
 
+// Flush blocks until every write enqueued before it has been written and
+// returns the first error any of them hit since the previous Flush.
+func (w *asyncWriter) Flush() error {
+	errCh := make(chan error, 1)
+	w.ch <- writeOp{err: errCh}
+	return <-errCh
+}
+
+// release closes the cached handle for path so the next write reopens it.
+func (w *asyncWriter) release(path string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if f, ok := w.files[path]; ok {
+		f.Close()
+		delete(w.files, path)
+	}
+}
+
 // Close signals the writer to flush and stop, then closes all file handles.
 func (w *asyncWriter) Close() error {
 	close(w.ch)
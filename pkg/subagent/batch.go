@@ -0,0 +1,53 @@
+package subagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// SpawnBatch runs all inputs as foreground subagents, at most
+// maxConcurrentAgents at a time with the rest queued, and waits for every one
+// to finish. Results are returned in input order. A failed spawn does not stop
+// the batch: its result carries the error in Error, and all such failures are
+// joined into the returned error. Cancelling ctx interrupts running agents and
+// skips queued ones. RunInBackground is ignored.
+func (m *Manager) SpawnBatch(ctx context.Context, inputs []tools.AgentInput) ([]tools.AgentResult, error) {
+	results := make([]tools.AgentResult, len(inputs))
+	errs := make([]error, len(inputs))
+	sem := make(chan struct{}, maxConcurrentAgents)
+
+	var wg sync.WaitGroup
+	for i, input := range inputs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			input.RunInBackground = nil
+			results[i], errs[i] = m.Spawn(ctx, input)
+		}()
+	}
+	wg.Wait()
+
+	var failures []error
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		results[i].Error = err.Error()
+		failures = append(failures, fmt.Errorf("agent %d (%s): %w", i, inputs[i].SubagentType, err))
+	}
+	return results, errors.Join(failures...)
+}
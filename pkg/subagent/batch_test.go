@@ -0,0 +1,140 @@
+package subagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jg-phare/goat/pkg/llm"
+	"github.com/jg-phare/goat/pkg/tools"
+)
+
+// echoLLMClient answers each request with the text of its last user message,
+// tracking how many requests are in flight at once.
+type echoLLMClient struct {
+	delay       time.Duration
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (e *echoLLMClient) Complete(ctx context.Context, req *llm.CompletionRequest) (*llm.Stream, error) {
+	e.mu.Lock()
+	e.inFlight++
+	e.maxInFlight = max(e.maxInFlight, e.inFlight)
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.inFlight--
+		e.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(e.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	last, _ := req.Messages[len(req.Messages)-1].Content.(string)
+	return endTurnWithText("echo: " + last).toStream(ctx), nil
+}
+
+func (e *echoLLMClient) Model() string     { return "claude-sonnet-4-5-20250929" }
+func (e *echoLLMClient) SetModel(_ string) {}
+
+func newBatchManager(client llm.Client) *Manager {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.LLMClient = client
+	return mgr
+}
+
+func batchInputs(n int) []tools.AgentInput {
+	inputs := make([]tools.AgentInput, n)
+	for i := range inputs {
+		inputs[i] = tools.AgentInput{
+			Description:  "part",
+			Prompt:       fmt.Sprintf("part %d", i),
+			SubagentType: "general-purpose",
+		}
+	}
+	return inputs
+}
+
+func TestSpawnBatch_ResultsInInputOrder(t *testing.T) {
+	mgr := newBatchManager(&echoLLMClient{delay: 10 * time.Millisecond})
+
+	results, err := mgr.SpawnBatch(context.Background(), batchInputs(4))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, r := range results {
+		if want := fmt.Sprintf("echo: part %d", i); !strings.Contains(r.Output, want) {
+			t.Errorf("results[%d].Output = %q, want %q", i, r.Output, want)
+		}
+		if r.Metrics == nil {
+			t.Errorf("results[%d] has no metrics", i)
+		}
+	}
+}
+
+func TestSpawnBatch_AggregatesErrors(t *testing.T) {
+	mgr := newBatchManager(&echoLLMClient{})
+	inputs := batchInputs(3)
+	inputs[1].SubagentType = "nope"
+
+	results, err := mgr.SpawnBatch(context.Background(), inputs)
+	if err == nil || !strings.Contains(err.Error(), `agent 1 (nope): unknown agent type "nope"`) {
+		t.Fatalf("error = %v", err)
+	}
+	if !strings.Contains(results[1].Error, "unknown agent type") {
+		t.Errorf("results[1].Error = %q", results[1].Error)
+	}
+	if !strings.Contains(results[0].Output, "echo: part 0") || !strings.Contains(results[2].Output, "echo: part 2") {
+		t.Errorf("other agents should still complete: %+v", results)
+	}
+}
+
+func TestSpawnBatch_QueuesBeyondConcurrencyLimit(t *testing.T) {
+	client := &echoLLMClient{delay: 20 * time.Millisecond}
+	mgr := newBatchManager(client)
+
+	results, err := mgr.SpawnBatch(context.Background(), batchInputs(maxConcurrentAgents+5))
+	if err != nil {
+		t.Fatalf("queued spawns should not fail: %v", err)
+	}
+	if len(results) != maxConcurrentAgents+5 {
+		t.Fatalf("got %d results", len(results))
+	}
+	if client.maxInFlight > maxConcurrentAgents {
+		t.Errorf("max in flight = %d, want <= %d", client.maxInFlight, maxConcurrentAgents)
+	}
+}
+
+func TestSpawnBatch_Cancelled(t *testing.T) {
+	mgr := newBatchManager(&echoLLMClient{delay: time.Minute})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	results, err := mgr.SpawnBatch(ctx, batchInputs(maxConcurrentAgents+2))
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("batch should stop promptly on cancellation")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want queued agents skipped with context.Canceled", err)
+	}
+	if results[maxConcurrentAgents].Error == "" {
+		t.Error("queued agent should report the cancellation")
+	}
+	if len(mgr.List()) == 0 {
+		t.Error("started agents should be tracked")
+	}
+	for _, status := range mgr.List() {
+		if status.State == StateRunning {
+			t.Errorf("agent %s still running after cancellation", status.ID)
+		}
+	}
+}
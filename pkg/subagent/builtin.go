@@ -20,14 +20,14 @@ func BuiltInAgents() map[string]Definition {
 		Description:     "Fast agent specialized for exploring codebases.",
 		Prompt:          prompt.ExplorePrompt(),
 		Model:           "haiku",
-		DisallowedTools: []string{"Write", "Edit", "NotebookEdit", "Agent", "ExitPlanMode"},
+		DisallowedTools: []string{"Write", "Edit", "ApplyPatch", "NotebookEdit", "ExitPlanMode"},
 	}, SourceBuiltIn, 0)
 
 	// Plan: architecture agent, no write tools
 	defs["Plan"] = FromTypesDefinition("Plan", types.AgentDefinition{
 		Description:     "Software architect agent for designing implementation plans.",
 		Prompt:          prompt.PlanPrompt(),
-		DisallowedTools: []string{"Write", "Edit", "NotebookEdit", "Agent", "ExitPlanMode"},
+		DisallowedTools: []string{"Write", "Edit", "ApplyPatch", "NotebookEdit", "Agent", "ExitPlanMode"},
 	}, SourceBuiltIn, 0)
 
 	// Bash: command execution specialist
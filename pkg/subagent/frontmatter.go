@@ -1,9 +1,12 @@
 package subagent
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/jg-phare/goat/pkg/types"
@@ -124,27 +127,94 @@ var typoSuggestions = map[string]string{
 	"mcpservers":      "mcpServers",
 }
 
-// detectUnknownFields performs a first-pass YAML unmarshal into a raw map
-// and checks for keys not in knownFrontmatterKeys.
-func detectUnknownFields(yamlPart []byte) []string {
-	var raw map[string]interface{}
-	if err := yaml.Unmarshal(yamlPart, &raw); err != nil {
-		return nil // if we can't parse raw, the main parse will catch it
+// knownToolNames is the set of built-in tool names accepted in the tools and
+// disallowedTools fields. MCP tools (mcp__*) and Task(...) entries are
+// accepted separately.
+var knownToolNames = map[string]bool{
+	"Agent": true, "ApplyPatch": true, "AskUserQuestion": true, "Bash": true,
+	"Config": true, "Edit": true, "ExitPlanMode": true, "GetMcpPrompt": true,
+	"Glob": true, "Grep": true, "KillTask": true, "ListMcpPrompts": true,
+	"ListMcpResources": true, "ListTasks": true, "NotebookEdit": true, "Read": true,
+	"ReadMcpResource": true, "SendMessage": true, "Skill": true, "Task": true,
+	"TaskOutput": true, "TaskStop": true, "TeamCreate": true, "TeamDelete": true,
+	"TodoWrite": true, "WebFetch": true, "WebSearch": true, "Write": true,
+}
+
+// maxDefinitionBytes caps the size of an agent definition file. Larger files
+// are rejected rather than injected into every subagent's system prompt.
+const maxDefinitionBytes = 256 << 10
+
+// frontmatterLineOffset converts a YAML node line to a file line: the
+// frontmatter starts on the line after the opening "---".
+const frontmatterLineOffset = 1
+
+// fieldWarning is a non-fatal frontmatter issue.
+type fieldWarning struct {
+	line int // 1-based line in the agent file, 0 if unknown
+	msg  string
+}
+
+// lineError attaches the agent file line an error refers to.
+type lineError struct {
+	line int
+	err  error
+}
+
+func (e *lineError) Error() string { return e.err.Error() }
+func (e *lineError) Unwrap() error { return e.err }
+
+// yamlLinePattern extracts the line number from yaml.v3 error messages.
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// errorLine returns the agent file line err refers to, or 0 if unknown.
+func errorLine(err error) int {
+	var le *lineError
+	if errors.As(err, &le) {
+		return le.line
 	}
+	return 0
+}
 
-	var warnings []string
-	for key := range raw {
-		if !knownFrontmatterKeys[key] {
-			msg := fmt.Sprintf("unknown field %q", key)
-			if suggestion, ok := typoSuggestions[strings.ToLower(key)]; ok {
-				msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
-			}
-			warnings = append(warnings, msg)
+// detectUnknownFields checks the top-level keys of the frontmatter mapping
+// against knownFrontmatterKeys, in document order.
+func detectUnknownFields(mapping *yaml.Node) []fieldWarning {
+	var warnings []fieldWarning
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		if knownFrontmatterKeys[key.Value] {
+			continue
+		}
+		msg := fmt.Sprintf("unknown field %q", key.Value)
+		if suggestion, ok := typoSuggestions[strings.ToLower(key.Value)]; ok {
+			msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+		warnings = append(warnings, fieldWarning{line: key.Line + frontmatterLineOffset, msg: msg})
+	}
+	return warnings
+}
+
+// detectUnknownTools warns about tool names that are neither built in, MCP
+// tools, nor Task(...) restrictions.
+func detectUnknownTools(field string, tools []string, line int) []fieldWarning {
+	var warnings []fieldWarning
+	for _, name := range tools {
+		if knownToolNames[name] || strings.HasPrefix(name, "mcp__") || isTaskEntry(name) {
+			continue
 		}
+		warnings = append(warnings, fieldWarning{line: line, msg: fmt.Sprintf("unknown tool %q in %s", name, field)})
 	}
 	return warnings
 }
 
+// keyLines maps each top-level frontmatter key to its line in the agent file.
+func keyLines(mapping *yaml.Node) map[string]int {
+	lines := make(map[string]int, len(mapping.Content)/2)
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		lines[mapping.Content[i].Value] = mapping.Content[i].Line + frontmatterLineOffset
+	}
+	return lines
+}
+
 // ParseFile reads an agent definition from a Markdown file with YAML frontmatter.
 func ParseFile(path string) (*Definition, error) {
 	data, err := os.ReadFile(path)
@@ -165,8 +235,23 @@ func ParseContent(data []byte, filePath string) (*Definition, error) {
 }
 
 // ParseContentWithWarnings parses agent definition and also returns warnings
-// about unknown YAML fields.
+// about unknown YAML fields and unknown tool names.
 func ParseContentWithWarnings(data []byte, filePath string) (*Definition, []string, error) {
+	def, fieldWarnings, err := parseDefinition(data, filePath)
+	var warnings []string
+	for _, w := range fieldWarnings {
+		warnings = append(warnings, w.msg)
+	}
+	return def, warnings, err
+}
+
+// parseDefinition parses and validates an agent definition. Warnings and
+// errors carry the agent file line they refer to where it is known.
+func parseDefinition(data []byte, filePath string) (*Definition, []fieldWarning, error) {
+	if len(data) > maxDefinitionBytes {
+		return nil, nil, fmt.Errorf("agent definition %s is %d bytes; the limit is %d KB", filePath, len(data), maxDefinitionBytes>>10)
+	}
+
 	yamlPart, body, err := splitFrontmatter(data)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parsing frontmatter in %s: %w", filePath, err)
@@ -176,14 +261,25 @@ func ParseContentWithWarnings(data []byte, filePath string) (*Definition, []stri
 		return nil, nil, fmt.Errorf("no frontmatter found in %s", filePath)
 	}
 
-	// First pass: detect unknown fields
-	fieldWarnings := detectUnknownFields(yamlPart)
+	// First pass: parse the document to locate keys and detect unknown fields
+	var doc yaml.Node
+	if err := yaml.Unmarshal(yamlPart, &doc); err != nil {
+		return nil, nil, yamlError(err, filePath)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, &lineError{line: 1 + frontmatterLineOffset, err: fmt.Errorf("frontmatter in %s must be a mapping of fields", filePath)}
+	}
+	mapping := doc.Content[0]
+	lines := keyLines(mapping)
+	fieldWarnings := detectUnknownFields(mapping)
 
-	// Second pass: unmarshal into typed struct
+	// Second pass: decode into typed struct
 	var fm frontmatterData
-	if err := yaml.Unmarshal(yamlPart, &fm); err != nil {
-		return nil, fieldWarnings, fmt.Errorf("parsing YAML in %s: %w", filePath, err)
+	if err := mapping.Decode(&fm); err != nil {
+		return nil, fieldWarnings, yamlError(err, filePath)
 	}
+	fieldWarnings = append(fieldWarnings, detectUnknownTools("tools", fm.Tools, lines["tools"])...)
+	fieldWarnings = append(fieldWarnings, detectUnknownTools("disallowedTools", fm.DisallowedTools, lines["disallowedTools"])...)
 
 	// Validate required fields
 	if fm.Name == "" {
@@ -191,26 +287,29 @@ func ParseContentWithWarnings(data []byte, filePath string) (*Definition, []stri
 		base := filepath.Base(filePath)
 		fm.Name = strings.TrimSuffix(base, filepath.Ext(base))
 	}
+	if strings.TrimSpace(fm.Name) == "" || strings.ContainsAny(fm.Name, " \t") {
+		return nil, fieldWarnings, &lineError{line: lines["name"], err: fmt.Errorf("invalid name %q in %s; names must be non-empty with no spaces", fm.Name, filePath)}
+	}
 	if fm.Description == "" {
-		return nil, fieldWarnings, fmt.Errorf("missing required field 'description' in %s", filePath)
+		return nil, fieldWarnings, &lineError{line: lines["description"], err: fmt.Errorf("missing required field 'description' in %s", filePath)}
 	}
 
 	// Validate permissionMode
 	if fm.PermissionMode != "" {
 		if !validPermissionModes[fm.PermissionMode] {
-			return nil, fieldWarnings, fmt.Errorf("invalid permissionMode %q in %s; valid modes: default, acceptEdits, bypassPermissions, plan, delegate, dontAsk", fm.PermissionMode, filePath)
+			return nil, fieldWarnings, &lineError{line: lines["permissionMode"], err: fmt.Errorf("invalid permissionMode %q in %s; valid modes: default, acceptEdits, bypassPermissions, plan, delegate, dontAsk", fm.PermissionMode, filePath)}
 		}
 	}
 
 	// Validate maxTurns
 	if fm.MaxTurns != nil && *fm.MaxTurns <= 0 {
-		return nil, fieldWarnings, fmt.Errorf("maxTurns must be positive in %s, got %d", filePath, *fm.MaxTurns)
+		return nil, fieldWarnings, &lineError{line: lines["maxTurns"], err: fmt.Errorf("maxTurns must be positive in %s, got %d", filePath, *fm.MaxTurns)}
 	}
 
 	// Validate model
 	if fm.Model != "" {
 		if !isValidModelValue(fm.Model) {
-			return nil, fieldWarnings, fmt.Errorf("invalid model %q in %s; use a known alias (haiku, sonnet, opus) or a full model ID", fm.Model, filePath)
+			return nil, fieldWarnings, &lineError{line: lines["model"], err: fmt.Errorf("invalid model %q in %s; use a known alias (haiku, sonnet, opus) or a full model ID", fm.Model, filePath)}
 		}
 	}
 
@@ -237,6 +336,17 @@ func ParseContentWithWarnings(data []byte, filePath string) (*Definition, []stri
 	return def, fieldWarnings, nil
 }
 
+// yamlError wraps a YAML parse or decode error with the agent file line it
+// reports, shifted from frontmatter to file numbering.
+func yamlError(err error, filePath string) error {
+	wrapped := fmt.Errorf("parsing YAML in %s: %w", filePath, err)
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &lineError{line: line + frontmatterLineOffset, err: wrapped}
+	}
+	return wrapped
+}
+
 // validPermissionModes is the set of accepted permission mode values.
 var validPermissionModes = map[string]bool{
 	"default":           true,
@@ -1,6 +1,7 @@
 package subagent
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -10,10 +11,14 @@ import (
 // LoadWarning represents a non-fatal issue encountered during agent loading.
 type LoadWarning struct {
 	File  string // path to the file that caused the warning
+	Line  int    // 1-based line in File, 0 if unknown
 	Error error  // the underlying error
 }
 
 func (w LoadWarning) String() string {
+	if w.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", w.File, w.Line, w.Error)
+	}
 	return fmt.Sprintf("%s: %v", w.File, w.Error)
 }
 
@@ -125,16 +130,18 @@ func (l *Loader) scanDir(dir string, source AgentSource, priority int) (map[stri
 			})
 			continue
 		}
-		def, fieldWarnings, parseErr := ParseContentWithWarnings(data, path)
+		def, fieldWarnings, parseErr := parseDefinition(data, path)
 		for _, fw := range fieldWarnings {
 			warnings = append(warnings, LoadWarning{
 				File:  path,
-				Error: fmt.Errorf("%s", fw),
+				Line:  fw.line,
+				Error: errors.New(fw.msg),
 			})
 		}
 		if parseErr != nil {
 			warnings = append(warnings, LoadWarning{
 				File:  path,
+				Line:  errorLine(parseErr),
 				Error: parseErr,
 			})
 			continue // skip malformed file
@@ -264,3 +264,65 @@ Plugin prompt.
 		t.Errorf("Priority = %d, want 10", def.Priority)
 	}
 }
+
+func TestLoader_WarningsHaveLines(t *testing.T) {
+	dir := t.TempDir()
+	agentDir := filepath.Join(dir, ".claude", "agents")
+	writeAgentFile(t, agentDir, "typo.md", `---
+description: Typo agent
+tols: Read
+tools: Read, Grpe, mcp__github__search
+---
+Prompt.
+`)
+	writeAgentFile(t, agentDir, "bad-model.md", `---
+description: Bad model
+model: gpt4
+---
+Prompt.
+`)
+	writeAgentFile(t, agentDir, "bad-yaml.md", `---
+description: Bad YAML
+tools: [Read
+---
+Prompt.
+`)
+
+	defs, warnings, err := NewLoader(dir, filepath.Join(dir, "no-user")).LoadAll()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := defs["typo"]; !ok {
+		t.Error("unknown fields and tools should warn, not reject")
+	}
+	if _, ok := defs["bad-model"]; ok {
+		t.Error("invalid model should reject the definition")
+	}
+
+	got := make(map[string]bool)
+	for _, w := range warnings {
+		got[w.String()] = true
+	}
+	typo := filepath.Join(agentDir, "typo.md")
+	for _, w := range []string{
+		typo + `:3: unknown field "tols" (did you mean "tools"?)`,
+		typo + `:4: unknown tool "Grpe" in tools`,
+	} {
+		if !got[w] {
+			t.Errorf("missing warning %q in %v", w, warnings)
+		}
+	}
+	lines := map[string]int{}
+	for _, w := range warnings {
+		lines[filepath.Base(w.File)] = w.Line
+	}
+	if lines["bad-model.md"] != 3 {
+		t.Errorf("bad-model.md warning line = %d, want 3", lines["bad-model.md"])
+	}
+	if lines["bad-yaml.md"] == 0 {
+		t.Error("bad-yaml.md warning should carry a line")
+	}
+	if len(warnings) != 4 {
+		t.Errorf("warnings = %v, want 4", warnings)
+	}
+}
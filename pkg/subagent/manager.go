@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,8 +18,11 @@ import (
 	"github.com/jg-phare/goat/pkg/prompt"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// maxConcurrentAgents bounds the agents running at once across the whole
+// tree: nested subagents spawn through the root Manager and share its limit.
 const maxConcurrentAgents = 10
 const maxCompletedAgents = 100
 
@@ -34,6 +39,10 @@ type ManagerOpts struct {
 	ParentRegistry    *tools.Registry
 	TaskRestriction   *TaskRestriction // limits which agent types can be spawned
 	SessionStore      agent.SessionStore // pass to subagents for transcript persistence
+	MaxSubagentDepth  int              // deepest subagent nesting level (0 = ParentConfig's, <= 1 = subagents cannot spawn)
+	StreamOutput      bool             // relay foreground subagent messages to the calling loop's stream
+	QueueOnFull       bool             // wait for a free slot instead of failing at maxConcurrentAgents
+	QueueTimeout      time.Duration    // longest a queued spawn waits (0 = until its context is done; nested spawns don't queue)
 }
 
 // Manager creates, tracks, and controls subagent instances.
@@ -47,6 +56,9 @@ type Manager struct {
 	builtIn       map[string]Definition
 	cliAgents     map[string]Definition
 	opts          ManagerOpts
+	reserved      int             // spawns that passed the concurrency check but are not yet active
+	waiters       []chan struct{} // queued spawns, oldest first; closed once a slot is reserved for them
+	watching      atomic.Bool     // set while Watch is running
 }
 
 // NewManager creates a Manager with built-in agents and optional CLI/file-based agents.
@@ -68,13 +80,27 @@ func NewManager(opts ManagerOpts, cliAgents map[string]Definition) *Manager {
 
 // Spawn creates and runs a subagent. Implements tools.SubagentSpawner.
 func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.AgentResult, error) {
+	return m.spawn(ctx, input, 1, m.opts.TaskRestriction, m.opts.CostTracker)
+}
+
+// spawn creates and runs a subagent at the given nesting depth (1 = spawned
+// by the main agent), allowing only the agent types permitted by restriction.
+// The subagent's costs are tracked on their own for its budget and rolled up
+// into parentCosts.
+func (m *Manager) spawn(ctx context.Context, input tools.AgentInput, depth int, restriction *TaskRestriction, parentCosts *llm.CostTracker) (tools.AgentResult, error) {
 	// 1. Check limits
-	m.mu.RLock()
-	activeCount := len(m.active)
-	m.mu.RUnlock()
-	if activeCount >= maxConcurrentAgents {
-		return tools.AgentResult{}, fmt.Errorf("max concurrent agents (%d) reached", maxConcurrentAgents)
+	if maxDepth := m.maxDepth(); depth > maxDepth {
+		return tools.AgentResult{}, fmt.Errorf("max subagent depth (%d) exceeded: cannot spawn %q at depth %d", maxDepth, input.SubagentType, depth)
 	}
+	if err := m.reserveSlot(ctx, depth > 1); err != nil {
+		return tools.AgentResult{}, err
+	}
+	reserved := true
+	defer func() {
+		if reserved {
+			m.releaseSlot()
+		}
+	}()
 
 	// 2. Resolve definition
 	m.mu.RLock()
@@ -85,13 +111,17 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 	}
 
 	// 2b. Enforce task restriction
-	if r := m.opts.TaskRestriction; r != nil && !r.Unrestricted {
+	if r := restriction; r != nil && !r.Unrestricted {
 		allowed := toSet(r.AllowedTypes)
 		if !allowed[input.SubagentType] {
 			return tools.AgentResult{}, fmt.Errorf("agent type %q not allowed by task restriction (allowed: %v)", input.SubagentType, r.AllowedTypes)
 		}
 	}
 
+	// 2c. Trace the spawn; the subagent's loop span nests under it
+	ctx, span := agent.StartSpan(ctx, m.parentTracerProvider(), "subagent.spawn", agent.AttrAgentType.String(input.SubagentType))
+	defer span.End()
+
 	// 3. Generate ID (or use resume ID)
 	agentID := uuid.New().String()
 	if input.Resume != nil && *input.Resume != "" {
@@ -105,7 +135,8 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 			return m.resumeRunningAgent(ctx, existing, input)
 		}
 		if existsCompleted {
-			return m.resumeCompletedAgent(ctx, completed, input, def)
+			reserved = false // handed over to the resumed agent
+			return m.resumeCompletedAgent(ctx, completed, input, def, parentCosts)
 		}
 		return tools.AgentResult{}, fmt.Errorf("cannot resume unknown agent %q", agentID)
 	}
@@ -124,13 +155,16 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 
 	// 5. Resolve model
 	model := resolveModel(def.Model, input.Model, m.parentModel())
+	span.SetAttributes(agent.AttrModel.String(model))
 
 	// 6. Resolve tools
 	parentToolNames := m.parentToolNames()
 	toolNames := resolveTools(def.Tools, def.DisallowedTools, parentToolNames)
-	// Remove Agent tool from subagent registries (no nesting)
+	// The parent's Agent tool is never inherited; nesting gets its own
+	costs := llm.NewChildCostTracker(parentCosts)
+	nested := m.nestedSpawner(def, toolNames, depth, costs)
 	toolNames = filterFunc(toolNames, func(s string) bool { return s != "Agent" })
-	taskRestriction, toolNames := parseTaskRestriction(toolNames)
+	_, toolNames = parseTaskRestriction(toolNames)
 
 	// Validate tool names against parent registry
 	var spawnWarnings []string
@@ -173,12 +207,15 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 	config := agent.AgentConfig{
 		Model:             model,
 		MaxTurns:          maxTurns,
+		MaxBudgetUSD:      budgetUSD(input),
+		OutputFormat:      outputFormat(input),
 		CWD:               m.parentCWD(),
 		SessionID:         agentID,
 		PermissionMode:    permMode,
 		AgentType:         input.SubagentType,
 		BackgroundMode:    isBackground,
-		CanSpawnSubagents: false,
+		CanSpawnSubagents: nested != nil,
+		MaxSubagentDepth:  m.maxDepth() - depth,
 		Betas:             m.parentBetas(),
 		ContextLimitFunc:  m.parentContextLimitFunc(),
 		LLMClient:         m.opts.LLMClient,
@@ -186,12 +223,18 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 		Permissions:       m.resolvePermissions(isBackground),
 		Hooks:             m.resolveHooks(),
 		Compactor:         &agent.NoOpCompactor{},
-		CostTracker:       m.opts.CostTracker,
+		CostTracker:       costs,
 		SessionStore:      m.resolveSessionStore(),
+		TracerProvider:    m.parentTracerProvider(),
+	}
+	if parent := m.opts.ParentConfig; parent != nil {
+		// Output limits are per model, so they apply to the subagent's model too
+		config.MaxOutputTokens = parent.MaxOutputTokens
+		config.MaxOutputTokensFunc = parent.MaxOutputTokensFunc
 	}
 
 	// 11. Build scoped tool registry
-	config.ToolRegistry = m.buildScopedRegistry(toolNames, taskRestriction)
+	config.ToolRegistry = m.buildScopedRegistry(toolNames, nested)
 
 	// 12. Register scoped hooks if agent has hooks defined
 	if m.opts.HookRunner != nil && len(def.Hooks) > 0 {
@@ -218,6 +261,7 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 		TranscriptPath: transcriptPath,
 		Done:           make(chan struct{}),
 		Warnings:       spawnWarnings,
+		Depth:          depth,
 		cleanupFn: func() {
 			if m.opts.HookRunner != nil {
 				m.opts.HookRunner.UnregisterScoped(agentID)
@@ -225,9 +269,8 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 		},
 	}
 
-	m.mu.Lock()
-	m.active[agentID] = ra
-	m.mu.Unlock()
+	m.activate(ra)
+	reserved = false
 
 	// Launch the agentic loop
 	query := agent.RunLoop(ctx, input.Prompt, config)
@@ -244,14 +287,17 @@ func (m *Manager) Spawn(ctx context.Context, input tools.AgentInput) (tools.Agen
 	}
 
 	// Foreground: block until complete
-	dr := m.drainQuery(query)
+	dr := m.drainQuery(query, m.forwarder(ctx))
 	m.finishAgent(ra, query, dr)
 
 	return tools.AgentResult{
-		AgentID: agentID,
-		Output:  dr.output,
-		Error:   dr.errorMsg,
-		Metrics: taskMetricsToAgentMetrics(ra.Metrics),
+		AgentID:        agentID,
+		Output:         dr.output,
+		Error:          dr.errorMsg,
+		Metrics:        taskMetricsToAgentMetrics(ra.Metrics),
+		BudgetExceeded: query.GetExitReason() == agent.ExitMaxBudget,
+
+		StructuredOutput: dr.structured,
 	}, nil
 }
 
@@ -324,6 +370,29 @@ func (m *Manager) Stop(taskID string) error {
 	return nil
 }
 
+// StopAll cancels every running agent and waits for them to finish, or for
+// ctx to expire.
+func (m *Manager) StopAll(ctx context.Context) error {
+	m.mu.RLock()
+	running := make([]*RunningAgent, 0, len(m.active))
+	for _, ra := range m.active {
+		running = append(running, ra)
+	}
+	m.mu.RUnlock()
+
+	for _, ra := range running {
+		_ = m.Stop(ra.ID)
+	}
+	for _, ra := range running {
+		select {
+		case <-ra.Done:
+		case <-ctx.Done():
+			return fmt.Errorf("stopping subagents: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
 // List returns the status of all known agents (active and recently completed).
 func (m *Manager) List() []AgentStatus {
 	m.mu.RLock()
@@ -399,12 +468,13 @@ func (m *Manager) Definitions() map[string]Definition {
 
 // drainResult holds the text output and any error captured from the subagent stream.
 type drainResult struct {
-	output   string
-	errorMsg string
+	output     string
+	errorMsg   string
+	structured any // parsed final answer when an output schema was set
 }
 
 func (m *Manager) drainAndFinish(query *agent.Query, ra *RunningAgent) {
-	dr := m.drainQuery(query)
+	dr := m.drainQuery(query, nil)
 	// Write output file before finishAgent closes Done channel
 	content := dr.output
 	if dr.errorMsg != "" {
@@ -414,19 +484,32 @@ func (m *Manager) drainAndFinish(query *agent.Query, ra *RunningAgent) {
 	m.finishAgent(ra, query, dr)
 }
 
-func (m *Manager) drainQuery(query *agent.Query) drainResult {
+// drainQuery consumes the subagent's messages, collecting its text output
+// and error. Each message is also passed to forward, if non-nil.
+func (m *Manager) drainQuery(query *agent.Query, forward func(types.SDKMessage)) drainResult {
 	var textParts []string
 	var errorMsg string
+	var structured any
 	for msg := range query.Messages() {
-		// Extract text content from assistant messages (value or pointer)
+		if forward != nil {
+			forward(msg)
+		}
+		// Extract text content from assistant messages (value or pointer),
+		// skipping those relayed from the subagent's own subagents
 		switch am := msg.(type) {
 		case types.AssistantMessage:
+			if am.ParentToolUseID != nil {
+				continue
+			}
 			for _, block := range am.Message.Content {
 				if block.Type == "text" && block.Text != "" {
 					textParts = append(textParts, block.Text)
 				}
 			}
 		case *types.AssistantMessage:
+			if am.ParentToolUseID != nil {
+				continue
+			}
 			for _, block := range am.Message.Content {
 				if block.Type == "text" && block.Text != "" {
 					textParts = append(textParts, block.Text)
@@ -436,16 +519,81 @@ func (m *Manager) drainQuery(query *agent.Query) drainResult {
 			if am.IsError && len(am.Errors) > 0 {
 				errorMsg = strings.Join(am.Errors, "; ")
 			}
+			structured = am.StructuredOutput
 		case *types.ResultMessage:
 			if am.IsError && len(am.Errors) > 0 {
 				errorMsg = strings.Join(am.Errors, "; ")
 			}
+			structured = am.StructuredOutput
 		}
 	}
 	return drainResult{
-		output:   strings.Join(textParts, ""),
-		errorMsg: errorMsg,
+		output:     strings.Join(textParts, ""),
+		errorMsg:   errorMsg,
+		structured: structured,
+	}
+}
+
+// forwarder returns a func relaying a foreground subagent's assistant, stream
+// and tool progress messages to the calling loop's stream, tagged with the
+// Agent tool call's ID. It returns nil when streaming is off or the spawn did
+// not come from a loop tool call.
+func (m *Manager) forwarder(ctx context.Context) func(types.SDKMessage) {
+	if !m.opts.StreamOutput {
+		return nil
+	}
+	call, ok := agent.ToolCallFromContext(ctx)
+	if !ok || call.EmitCh == nil {
+		return nil
+	}
+	parentID := call.ToolUseID
+	return func(msg types.SDKMessage) {
+		if tagged := tagParentToolUse(msg, &parentID); tagged != nil {
+			call.EmitCh <- tagged
+		}
+	}
+}
+
+// tagParentToolUse returns a copy of msg attributed to parentID, or nil for
+// message types that are not relayed. Messages already relayed from a deeper
+// subagent keep their own parent.
+func tagParentToolUse(msg types.SDKMessage, parentID *string) types.SDKMessage {
+	switch m := msg.(type) {
+	case types.AssistantMessage:
+		if m.ParentToolUseID == nil {
+			m.ParentToolUseID = parentID
+		}
+		return m
+	case *types.AssistantMessage:
+		c := *m
+		if c.ParentToolUseID == nil {
+			c.ParentToolUseID = parentID
+		}
+		return &c
+	case types.PartialAssistantMessage:
+		if m.ParentToolUseID == nil {
+			m.ParentToolUseID = parentID
+		}
+		return m
+	case *types.PartialAssistantMessage:
+		c := *m
+		if c.ParentToolUseID == nil {
+			c.ParentToolUseID = parentID
+		}
+		return &c
+	case types.ToolProgressMessage:
+		if m.ParentToolUseID == nil {
+			m.ParentToolUseID = parentID
+		}
+		return m
+	case *types.ToolProgressMessage:
+		c := *m
+		if c.ParentToolUseID == nil {
+			c.ParentToolUseID = parentID
+		}
+		return &c
 	}
+	return nil
 }
 
 func (m *Manager) finishAgent(ra *RunningAgent, query *agent.Query, dr drainResult) {
@@ -483,11 +631,14 @@ func (m *Manager) finishAgent(ra *RunningAgent, query *agent.Query, dr drainResu
 	ra.SetState(finalState)
 	ra.Output.Append(dr.output)
 	ra.Output.SetResult(&TaskResult{
-		Content: dr.output,
-		Metrics: metrics,
-		State:   finalState,
-		AgentID: ra.ID,
-		Error:   errorMsg,
+		Content:        dr.output,
+		Metrics:        metrics,
+		State:          finalState,
+		AgentID:        ra.ID,
+		Error:          errorMsg,
+		BudgetExceeded: exitReason == agent.ExitMaxBudget,
+
+		StructuredOutput: dr.structured,
 	})
 
 	// Signal completion
@@ -496,6 +647,7 @@ func (m *Manager) finishAgent(ra *RunningAgent, query *agent.Query, dr drainResu
 	// Move from active to completed
 	m.mu.Lock()
 	delete(m.active, ra.ID)
+	m.handOffSlots()
 	m.completed[ra.ID] = ra
 	m.completedOrder = append(m.completedOrder, ra.ID)
 	// Evict oldest if over limit
@@ -534,7 +686,7 @@ func (m *Manager) resumeRunningAgent(_ context.Context, ra *RunningAgent, _ tool
 
 // resumeCompletedAgent re-launches a completed/stopped/failed agent with the new prompt,
 // prepending the previous output as conversation context.
-func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, input tools.AgentInput, def Definition) (tools.AgentResult, error) {
+func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, input tools.AgentInput, def Definition, parentCosts *llm.CostTracker) (tools.AgentResult, error) {
 	// Build the previous context message
 	previousOutput := ra.Output.String()
 	contextPrompt := input.Prompt
@@ -562,6 +714,8 @@ func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, in
 		Model:           input.Model,
 		RunInBackground: input.RunInBackground,
 		MaxTurns:        input.MaxTurns,
+		MaxBudgetUSD:    input.MaxBudgetUSD,
+		OutputSchema:    input.OutputSchema,
 		Name:            input.Name,
 		Mode:            input.Mode,
 		// Don't set Resume — we're handling it here
@@ -569,6 +723,7 @@ func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, in
 
 	// Resolve model from the original definition
 	model := resolveModel(def.Model, input.Model, m.parentModel())
+	trace.SpanFromContext(ctx).SetAttributes(agent.AttrModel.String(model))
 
 	// Build system prompt
 	systemPrompt := m.buildSystemPrompt(def, input, "")
@@ -583,30 +738,37 @@ func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, in
 	isBackground := input.RunInBackground != nil && *input.RunInBackground
 	permMode := m.resolvePermissionMode(def, resumeInput)
 
+	parentToolNames := m.parentToolNames()
+	toolNames := resolveTools(def.Tools, def.DisallowedTools, parentToolNames)
+	costs := llm.NewChildCostTracker(parentCosts)
+	nested := m.nestedSpawner(def, toolNames, ra.Depth, costs)
+
 	config := agent.AgentConfig{
 		Model:             model,
 		MaxTurns:          maxTurns,
 		CWD:               m.parentCWD(),
+		MaxBudgetUSD:      budgetUSD(input),
+		OutputFormat:      outputFormat(input),
 		SessionID:         ra.ID,
 		PermissionMode:    permMode,
 		AgentType:         ra.Type,
 		BackgroundMode:    isBackground,
-		CanSpawnSubagents: false,
+		CanSpawnSubagents: nested != nil,
+		MaxSubagentDepth:  m.maxDepth() - ra.Depth,
 		LLMClient:         m.opts.LLMClient,
 		Prompter:          &agent.StaticPromptAssembler{Prompt: systemPrompt},
 		Permissions:       m.resolvePermissions(isBackground),
 		Hooks:             m.resolveHooks(),
 		Compactor:         &agent.NoOpCompactor{},
-		CostTracker:       m.opts.CostTracker,
+		CostTracker:       costs,
 		SessionStore:      m.resolveSessionStore(),
+		TracerProvider:    m.parentTracerProvider(),
 	}
 
 	// Build scoped tool registry
-	parentToolNames := m.parentToolNames()
-	toolNames := resolveTools(def.Tools, def.DisallowedTools, parentToolNames)
 	toolNames = filterFunc(toolNames, func(s string) bool { return s != "Agent" })
 	_, toolNames = parseTaskRestriction(toolNames)
-	config.ToolRegistry = m.buildScopedRegistry(toolNames, nil)
+	config.ToolRegistry = m.buildScopedRegistry(toolNames, nested)
 
 	// Create new RunningAgent with the same ID
 	newRA := &RunningAgent{
@@ -618,6 +780,7 @@ func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, in
 		StartedAt:  time.Now(),
 		Output:     &AgentOutput{},
 		Done:       make(chan struct{}),
+		Depth:      ra.Depth,
 		cleanupFn: func() {
 			if m.opts.HookRunner != nil {
 				m.opts.HookRunner.UnregisterScoped(ra.ID)
@@ -625,9 +788,7 @@ func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, in
 		},
 	}
 
-	m.mu.Lock()
-	m.active[ra.ID] = newRA
-	m.mu.Unlock()
+	m.activate(newRA)
 
 	// Launch the agentic loop with the context-enriched prompt
 	query := agent.RunLoop(ctx, contextPrompt, config)
@@ -641,14 +802,17 @@ func (m *Manager) resumeCompletedAgent(ctx context.Context, ra *RunningAgent, in
 	}
 
 	// Foreground: block until complete
-	dr := m.drainQuery(query)
+	dr := m.drainQuery(query, m.forwarder(ctx))
 	m.finishAgent(newRA, query, dr)
 
 	return tools.AgentResult{
-		AgentID: ra.ID,
-		Output:  dr.output,
-		Error:   dr.errorMsg,
-		Metrics: taskMetricsToAgentMetrics(newRA.Metrics),
+		AgentID:        ra.ID,
+		Output:         dr.output,
+		Error:          dr.errorMsg,
+		Metrics:        taskMetricsToAgentMetrics(newRA.Metrics),
+		BudgetExceeded: query.GetExitReason() == agent.ExitMaxBudget,
+
+		StructuredOutput: dr.structured,
 	}, nil
 }
 
@@ -687,6 +851,128 @@ func (m *Manager) parentContextLimitFunc() func(string, []string) int {
 	return nil
 }
 
+// maxDepth returns the deepest nesting level a subagent may run at.
+func (m *Manager) maxDepth() int {
+	depth := m.opts.MaxSubagentDepth
+	if depth == 0 && m.opts.ParentConfig != nil {
+		depth = m.opts.ParentConfig.MaxSubagentDepth
+	}
+	return max(depth, 1)
+}
+
+// reserveSlot claims one of the maxConcurrentAgents slots for a spawn in
+// progress. It is handed over to the active map by activate, or given back
+// by releaseSlot if the spawn fails first. When all slots are taken it fails,
+// or with QueueOnFull waits its turn behind earlier queued spawns until a slot
+// frees, ctx is done or QueueTimeout elapses. A nested spawn fails instead of
+// waiting without a QueueTimeout: its parent holds a slot while it waits, so
+// a tree that fills every slot would wait on itself forever.
+func (m *Manager) reserveSlot(ctx context.Context, nested bool) error {
+	errFull := fmt.Errorf("max concurrent agents (%d) reached", maxConcurrentAgents)
+
+	m.mu.Lock()
+	if len(m.waiters) == 0 && len(m.active)+m.reserved < maxConcurrentAgents {
+		m.reserved++
+		m.mu.Unlock()
+		return nil
+	}
+	if !m.opts.QueueOnFull || (nested && m.opts.QueueTimeout <= 0) {
+		m.mu.Unlock()
+		return errFull
+	}
+	ready := make(chan struct{})
+	m.waiters = append(m.waiters, ready)
+	m.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if m.opts.QueueTimeout > 0 {
+		timer := time.NewTimer(m.opts.QueueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-timeout:
+		err = errFull
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if i := slices.Index(m.waiters, ready); i >= 0 {
+		m.waiters = slices.Delete(m.waiters, i, i+1)
+		return err
+	}
+	// A slot was handed over while giving up: pass it on
+	m.reserved--
+	m.handOffSlots()
+	return err
+}
+
+func (m *Manager) releaseSlot() {
+	m.mu.Lock()
+	m.reserved--
+	m.handOffSlots()
+	m.mu.Unlock()
+}
+
+// handOffSlots reserves free slots for queued spawns in FIFO order.
+// Callers must hold m.mu.
+func (m *Manager) handOffSlots() {
+	for len(m.waiters) > 0 && len(m.active)+m.reserved < maxConcurrentAgents {
+		m.reserved++
+		close(m.waiters[0])
+		m.waiters = m.waiters[1:]
+	}
+}
+
+// activate registers ra as active, consuming the slot reserved for it.
+func (m *Manager) activate(ra *RunningAgent) {
+	m.mu.Lock()
+	m.active[ra.ID] = ra
+	m.reserved--
+	m.mu.Unlock()
+}
+
+// nestedSpawner returns the spawner a subagent at depth uses for its own
+// children, or nil when it may not spawn: the depth budget is used up, or
+// its definition grants neither the Agent tool nor Task(...) entries.
+func (m *Manager) nestedSpawner(def Definition, toolNames []string, depth int, costs *llm.CostTracker) *nestedSpawner {
+	if depth >= m.maxDepth() {
+		return nil
+	}
+	restriction, _ := parseTaskRestriction(def.Tools)
+	if restriction == nil && !slices.Contains(toolNames, "Agent") {
+		return nil
+	}
+	return &nestedSpawner{m: m, depth: depth, restriction: restriction, costs: costs}
+}
+
+// nestedSpawner spawns children of a subagent through the root Manager, so
+// depth and the concurrency limit are tracked across the whole tree.
+type nestedSpawner struct {
+	m           *Manager
+	depth       int // depth of the subagent that owns this spawner
+	restriction *TaskRestriction
+	costs       *llm.CostTracker // the owning subagent's costs, which include its children's
+}
+
+// Spawn implements tools.SubagentSpawner.
+func (s *nestedSpawner) Spawn(ctx context.Context, input tools.AgentInput) (tools.AgentResult, error) {
+	return s.m.spawn(ctx, input, s.depth+1, s.restriction, s.costs)
+}
+
+func (m *Manager) parentTracerProvider() trace.TracerProvider {
+	if m.opts.ParentConfig != nil {
+		return m.opts.ParentConfig.TracerProvider
+	}
+	return nil
+}
+
 func (m *Manager) parentToolNames() []string {
 	if m.opts.ParentRegistry != nil {
 		return m.opts.ParentRegistry.Names()
@@ -733,6 +1019,7 @@ var backgroundPreApprovedTools = map[string]bool{
 	"FileWrite":    true,
 	"Edit":         true,
 	"FileEdit":     true,
+	"ApplyPatch":   true,
 	"WebFetch":     true,
 	"WebSearch":    true,
 	"NotebookEdit": true,
@@ -765,16 +1052,22 @@ func (m *Manager) resolveHooks() agent.HookRunner {
 	return &agent.NoOpHookRunner{}
 }
 
-func (m *Manager) buildScopedRegistry(toolNames []string, _ *TaskRestriction) *tools.Registry {
+// buildScopedRegistry copies the allowed parent tools into a new registry.
+// The parent's Agent tool is replaced by one backed by nested, if non-nil.
+func (m *Manager) buildScopedRegistry(toolNames []string, nested *nestedSpawner) *tools.Registry {
 	reg := tools.NewRegistry()
+	if nested != nil {
+		reg.Register(&tools.AgentTool{Spawner: nested})
+	}
 	if m.opts.ParentRegistry == nil {
 		return reg
 	}
+	reg.Use(m.opts.ParentRegistry.Middleware()...)
 
 	allowed := toSet(toolNames)
 	for _, name := range m.opts.ParentRegistry.Names() {
 		if name == "Agent" {
-			continue // never give subagents the Agent tool
+			continue // never give subagents the parent's Agent tool
 		}
 		if len(allowed) > 0 && !allowed[name] {
 			continue
@@ -852,6 +1145,23 @@ func convertHooks(hookMap map[types.HookEvent][]types.HookCallbackMatcher) map[t
 	return result
 }
 
+// outputFormat returns the structured-output format for the agent's input
+// schema, or nil for free-form text.
+func outputFormat(input tools.AgentInput) *types.OutputFormat {
+	if input.OutputSchema == nil {
+		return nil
+	}
+	return &types.OutputFormat{Type: "json_schema", Schema: input.OutputSchema}
+}
+
+// budgetUSD returns the agent's cost cap from its input (0 = unlimited).
+func budgetUSD(input tools.AgentInput) float64 {
+	if input.MaxBudgetUSD != nil {
+		return *input.MaxBudgetUSD
+	}
+	return 0
+}
+
 // taskMetricsToAgentMetrics converts internal TaskMetrics to the tools.AgentMetrics type.
 func taskMetricsToAgentMetrics(m TaskMetrics) *tools.AgentMetrics {
 	return &tools.AgentMetrics{
@@ -2,9 +2,11 @@ package subagent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -15,6 +17,8 @@ import (
 	"github.com/jg-phare/goat/pkg/llm"
 	"github.com/jg-phare/goat/pkg/tools"
 	"github.com/jg-phare/goat/pkg/types"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 // --- Mock LLM Client ---
@@ -814,7 +818,33 @@ func TestManager_MemoryToolsAutoEnabled(t *testing.T) {
 // --- Stub Tests for Unimplemented Features ---
 
 func TestManager_LargeAgentDefinition(t *testing.T) {
-	t.Skip("not yet implemented: large agent definition handling (250KB+)")
+	tmpDir := t.TempDir()
+	agentDir := filepath.Join(tmpDir, ".claude", "agents")
+	writeAgentFile(t, agentDir, "huge.md", "---\ndescription: Huge agent\n---\n"+strings.Repeat("x", 260<<10))
+	writeAgentFile(t, agentDir, "small.md", "---\ndescription: Small agent\n---\nPrompt.\n")
+
+	mgr := newTestManager(&mockLLMClient{})
+	warnings, err := mgr.Reload(tmpDir)
+	if err != nil {
+		t.Fatalf("Reload error: %v", err)
+	}
+
+	defs := mgr.Definitions()
+	if _, ok := defs["huge"]; ok {
+		t.Error("definition over the size limit should be rejected")
+	}
+	if _, ok := defs["small"]; !ok {
+		t.Error("small definition should still load")
+	}
+	var found bool
+	for _, w := range warnings {
+		if strings.HasSuffix(w.File, "huge.md") && strings.Contains(w.Error.Error(), "limit is 256 KB") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("warnings = %v, want a size limit warning for huge.md", warnings)
+	}
 }
 
 // --- Phase 6 Tests ---
@@ -1601,3 +1631,526 @@ func TestManager_BackgroundMetrics(t *testing.T) {
 		t.Errorf("expected positive duration in TaskResult, got %v", taskResult.Metrics.Duration)
 	}
 }
+
+func TestManager_SpawnTracing(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{endTurnWithText("traced")},
+	}
+	mgr := newTestManager(client)
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	mgr.opts.ParentConfig.TracerProvider = tp
+
+	ctx, turn := tp.Tracer("test").Start(context.Background(), "agent.tool")
+	if _, err := mgr.Spawn(ctx, tools.AgentInput{
+		Description:  "traced task",
+		Prompt:       "Do something",
+		SubagentType: "general-purpose",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	turn.End()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s
+	}
+	spawn, ok := spans["subagent.spawn"]
+	if !ok {
+		t.Fatal("no subagent.spawn span recorded")
+	}
+	if spawn.Parent.SpanID() != turn.SpanContext().SpanID() {
+		t.Error("spawn span should nest under the parent turn")
+	}
+	loop, ok := spans["agent.loop"]
+	if !ok || loop.Parent.SpanID() != spawn.SpanContext.SpanID() {
+		t.Error("subagent loop span should nest under the spawn span")
+	}
+	attrs := map[string]string{}
+	for _, kv := range spawn.Attributes {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs[string(agent.AttrAgentType)] != "general-purpose" || attrs[string(agent.AttrModel)] == "" {
+		t.Errorf("spawn attributes = %v", attrs)
+	}
+}
+
+func agentToolUse(subagentType string) *mockStreamData {
+	return toolUseData("Agent", fmt.Sprintf(`{"description":"delegate","prompt":"dig deeper","subagent_type":%q}`, subagentType))
+}
+
+func toolUseData(toolName, args string) *mockStreamData {
+	toolCalls := "tool_calls"
+	return &mockStreamData{
+		chunks: []llm.StreamChunk{
+			{
+				ID:    "msg-1",
+				Model: "claude-sonnet-4-5-20250929",
+				Choices: []llm.Choice{{Delta: llm.Delta{ToolCalls: []llm.ToolCall{{
+					ID:       "call_" + toolName,
+					Type:     "function",
+					Function: llm.FunctionCall{Name: toolName, Arguments: args},
+				}}}}},
+			},
+			{
+				ID:      "msg-1",
+				Model:   "claude-sonnet-4-5-20250929",
+				Choices: []llm.Choice{{FinishReason: &toolCalls}},
+				Usage:   &llm.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150},
+			},
+		},
+	}
+}
+
+func TestManager_NestedSpawnDisabledByDefault(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.ParentRegistry.Register(&tools.AgentTool{Spawner: mgr})
+
+	def := mgr.agents["general-purpose"]
+	if nested := mgr.nestedSpawner(def, mgr.parentToolNames(), 1, nil); nested != nil {
+		t.Error("subagents should not spawn without MaxSubagentDepth")
+	}
+	if _, ok := mgr.buildScopedRegistry(mgr.parentToolNames(), nil).Get("Agent"); ok {
+		t.Error("parent's Agent tool should not be inherited")
+	}
+}
+
+func TestManager_NestedSpawn(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{
+			agentToolUse("Explore"),
+			endTurnWithText("grandchild findings"),
+			endTurnWithText("child summary"),
+		},
+	}
+	mgr := newTestManager(client)
+	mgr.opts.MaxSubagentDepth = 2
+	mgr.opts.ParentRegistry.Register(&tools.AgentTool{Spawner: mgr})
+
+	result, err := mgr.Spawn(context.Background(), tools.AgentInput{
+		Description:  "delegating task",
+		Prompt:       "Delegate something",
+		SubagentType: "general-purpose",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Output, "child summary") {
+		t.Errorf("output = %q", result.Output)
+	}
+
+	mgr.mu.RLock()
+	defer mgr.mu.RUnlock()
+	depths := map[string]int{}
+	for _, ra := range mgr.completed {
+		depths[ra.Type] = ra.Depth
+	}
+	if depths["general-purpose"] != 1 || depths["Explore"] != 2 {
+		t.Errorf("depths = %v, want general-purpose at 1 and Explore at 2", depths)
+	}
+	if mgr.reserved != 0 {
+		t.Errorf("reserved = %d, want all slots handed over", mgr.reserved)
+	}
+}
+
+func TestManager_NestedSpawnDepthLimit(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.ParentConfig.MaxSubagentDepth = 2
+	mgr.opts.ParentRegistry.Register(&tools.AgentTool{Spawner: mgr})
+
+	def := mgr.agents["general-purpose"]
+	if mgr.nestedSpawner(def, mgr.parentToolNames(), 1, nil) == nil {
+		t.Fatal("depth-1 subagent should be able to spawn when MaxSubagentDepth is 2")
+	}
+	if mgr.nestedSpawner(def, mgr.parentToolNames(), 2, nil) != nil {
+		t.Error("depth-2 subagent should not be able to spawn when MaxSubagentDepth is 2")
+	}
+	if mgr.nestedSpawner(mgr.agents["Plan"], resolveTools(nil, mgr.agents["Plan"].DisallowedTools, mgr.parentToolNames()), 1, nil) != nil {
+		t.Error("a definition that disallows Agent should not get a nested spawner")
+	}
+
+	nested := &nestedSpawner{m: mgr, depth: 2}
+	_, err := nested.Spawn(context.Background(), tools.AgentInput{
+		Description:  "too deep",
+		Prompt:       "test",
+		SubagentType: "general-purpose",
+	})
+	if err == nil || !strings.Contains(err.Error(), "max subagent depth (2) exceeded") {
+		t.Fatalf("expected depth error, got %v", err)
+	}
+}
+
+func TestManager_NestedSpawnSharesConcurrencyLimit(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.MaxSubagentDepth = 3
+
+	mgr.mu.Lock()
+	for i := 0; i < maxConcurrentAgents; i++ {
+		mgr.active[string(rune('a'+i))] = &RunningAgent{State: StateRunning, Depth: 1}
+	}
+	mgr.mu.Unlock()
+
+	nested := &nestedSpawner{m: mgr, depth: 1}
+	_, err := nested.Spawn(context.Background(), tools.AgentInput{
+		Description:  "test",
+		Prompt:       "test",
+		SubagentType: "general-purpose",
+	})
+	if err == nil || !strings.Contains(err.Error(), "max concurrent") {
+		t.Fatalf("expected concurrency error, got %v", err)
+	}
+	if mgr.reserved != 0 {
+		t.Errorf("reserved = %d, want the failed spawn's slot released", mgr.reserved)
+	}
+}
+
+func TestManager_NestedSpawnTaskRestriction(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.MaxSubagentDepth = 2
+
+	def := Definition{AgentDefinition: types.AgentDefinition{Tools: []string{"Task(Explore)"}}}
+	nested := mgr.nestedSpawner(def, nil, 1, nil)
+	if nested == nil {
+		t.Fatal("Task(...) entries should grant a nested spawner")
+	}
+	_, err := nested.Spawn(context.Background(), tools.AgentInput{
+		Description:  "test",
+		Prompt:       "test",
+		SubagentType: "general-purpose",
+	})
+	if err == nil || !strings.Contains(err.Error(), "not allowed by task restriction") {
+		t.Fatalf("expected restriction error, got %v", err)
+	}
+}
+
+func TestManager_SubagentBudget(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{
+			toolUseData("Lookup", `{}`),
+			endTurnWithText("should not be reached"),
+		},
+	}
+	mgr := newTestManager(client, &mockTool{name: "Lookup", output: tools.ToolOutput{Content: "found"}})
+	mgr.opts.CostTracker.Add("claude-sonnet-4-5-20250929", types.BetaUsage{InputTokens: 1000})
+	before := mgr.opts.CostTracker.TotalCost()
+
+	// One turn costs 100*3/1M + 50*15/1M = $0.00105, over the cap
+	budget := 0.001
+	result, err := mgr.Spawn(context.Background(), tools.AgentInput{
+		Description:  "bounded task",
+		Prompt:       "Look it up",
+		SubagentType: "general-purpose",
+		MaxBudgetUSD: &budget,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.BudgetExceeded {
+		t.Error("expected BudgetExceeded")
+	}
+	if client.callIndex != 1 {
+		t.Errorf("LLM calls = %d, want the subagent stopped after one turn", client.callIndex)
+	}
+	turnCost := llm.CalculateCost("claude-sonnet-4-5-20250929", types.BetaUsage{InputTokens: 100, OutputTokens: 50})
+	if result.Metrics == nil || result.Metrics.CostUSD != turnCost || result.Metrics.InputTokens != 100 {
+		t.Errorf("metrics = %+v, want the subagent's own cost %v", result.Metrics, turnCost)
+	}
+	if got := mgr.opts.CostTracker.TotalCost() - before; got < turnCost-1e-12 || got > turnCost+1e-12 {
+		t.Errorf("parent tracker grew by %v, want %v", got, turnCost)
+	}
+	if taskResult, _ := mgr.GetOutput(result.AgentID, false, 0); !taskResult.BudgetExceeded {
+		t.Error("expected BudgetExceeded on the task result")
+	}
+}
+
+func TestManager_StreamOutputToParent(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{
+			agentToolUse("general-purpose"),
+			endTurnWithText("child thinking live"),
+			endTurnWithText("parent done"),
+		},
+	}
+	mgr := newTestManager(client)
+	mgr.opts.StreamOutput = true
+	registry := tools.NewRegistry()
+	registry.Register(&tools.AgentTool{Spawner: mgr})
+	mgr.opts.ParentRegistry = registry
+
+	q := agent.RunLoop(context.Background(), "Delegate", agent.AgentConfig{
+		Model:        "claude-sonnet-4-5-20250929",
+		MaxTurns:     5,
+		LLMClient:    client,
+		ToolRegistry: registry,
+		Prompter:     &agent.StaticPromptAssembler{Prompt: "parent"},
+		Permissions:  &agent.AllowAllChecker{},
+		Hooks:        &agent.NoOpHookRunner{},
+		Compactor:    &agent.NoOpCompactor{},
+	})
+
+	var relayed, own []string
+	for msg := range q.Messages() {
+		am, ok := msg.(types.AssistantMessage)
+		if !ok {
+			continue
+		}
+		for _, block := range am.Message.Content {
+			if block.Type != "text" {
+				continue
+			}
+			if am.ParentToolUseID != nil && *am.ParentToolUseID == "call_Agent" {
+				relayed = append(relayed, block.Text)
+			} else if am.ParentToolUseID == nil {
+				own = append(own, block.Text)
+			}
+		}
+	}
+	q.Wait()
+
+	if len(relayed) != 1 || relayed[0] != "child thinking live" {
+		t.Errorf("relayed = %q, want the subagent's text tagged with the Agent call ID", relayed)
+	}
+	if len(own) != 1 || own[0] != "parent done" {
+		t.Errorf("parent text = %q", own)
+	}
+}
+
+func TestTagParentToolUse(t *testing.T) {
+	parent, deeper := "call_parent", "call_deeper"
+
+	got := tagParentToolUse(&types.ToolProgressMessage{ToolName: "Bash"}, &parent).(*types.ToolProgressMessage)
+	if got.ParentToolUseID == nil || *got.ParentToolUseID != parent {
+		t.Errorf("tool progress parent = %v", got.ParentToolUseID)
+	}
+
+	relayed := types.AssistantMessage{ParentToolUseID: &deeper}
+	if am := tagParentToolUse(relayed, &parent).(types.AssistantMessage); *am.ParentToolUseID != deeper {
+		t.Errorf("already-relayed message retagged to %q", *am.ParentToolUseID)
+	}
+
+	if tagParentToolUse(types.ResultMessage{}, &parent) != nil {
+		t.Error("result messages should not be relayed")
+	}
+}
+
+// fillSlots marks every concurrency slot as taken by a placeholder agent.
+func fillSlots(mgr *Manager) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	for i := 0; i < maxConcurrentAgents; i++ {
+		mgr.active[fmt.Sprintf("busy-%d", i)] = &RunningAgent{State: StateRunning}
+	}
+}
+
+// freeSlot removes one placeholder agent, as finishAgent would.
+func freeSlot(mgr *Manager, i int) {
+	mgr.mu.Lock()
+	defer mgr.mu.Unlock()
+	delete(mgr.active, fmt.Sprintf("busy-%d", i))
+	mgr.handOffSlots()
+}
+
+func waitForWaiters(t *testing.T, mgr *Manager, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		mgr.mu.RLock()
+		got := len(mgr.waiters)
+		mgr.mu.RUnlock()
+		if got == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d queued spawns", n)
+}
+
+func TestManager_QueueOnFull(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{endTurnWithText("queued work done")},
+	}
+	mgr := newTestManager(client)
+	mgr.opts.QueueOnFull = true
+	fillSlots(mgr)
+
+	done := make(chan tools.AgentResult, 1)
+	go func() {
+		result, err := mgr.Spawn(context.Background(), tools.AgentInput{
+			Description:  "queued",
+			Prompt:       "wait your turn",
+			SubagentType: "general-purpose",
+		})
+		if err != nil {
+			t.Errorf("queued spawn failed: %v", err)
+		}
+		done <- result
+	}()
+
+	waitForWaiters(t, mgr, 1)
+	freeSlot(mgr, 0)
+
+	select {
+	case result := <-done:
+		if !strings.Contains(result.Output, "queued work done") {
+			t.Errorf("output = %q", result.Output)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued spawn did not run after a slot freed")
+	}
+}
+
+func TestManager_QueueTimeout(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.QueueOnFull = true
+	mgr.opts.QueueTimeout = 20 * time.Millisecond
+	fillSlots(mgr)
+
+	_, err := mgr.Spawn(context.Background(), tools.AgentInput{
+		Description:  "test",
+		Prompt:       "test",
+		SubagentType: "general-purpose",
+	})
+	if err == nil || !strings.Contains(err.Error(), "max concurrent agents") {
+		t.Fatalf("expected the concurrency error after the timeout, got %v", err)
+	}
+	if len(mgr.waiters) != 0 || mgr.reserved != 0 {
+		t.Errorf("waiters = %d, reserved = %d after timeout", len(mgr.waiters), mgr.reserved)
+	}
+}
+
+func TestManager_QueueNestedWithoutTimeout(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.QueueOnFull = true
+	mgr.opts.MaxSubagentDepth = 3
+	fillSlots(mgr)
+
+	// Every slot is held by an ancestor: waiting without a timeout would hang
+	_, err := mgr.spawn(context.Background(), tools.AgentInput{
+		Description:  "child",
+		Prompt:       "test",
+		SubagentType: "general-purpose",
+	}, 2, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "max concurrent agents") {
+		t.Fatalf("nested spawn error = %v, want the concurrency error at once", err)
+	}
+	if len(mgr.waiters) != 0 {
+		t.Errorf("waiters = %d, a nested spawn without QueueTimeout should not queue", len(mgr.waiters))
+	}
+
+	// With a timeout, it queues like any other spawn
+	mgr.opts.QueueTimeout = time.Minute
+	errCh := make(chan error, 1)
+	go func() { errCh <- mgr.reserveSlot(context.Background(), true) }()
+	waitForWaiters(t, mgr, 1)
+	freeSlot(mgr, 0)
+	if err := <-errCh; err != nil {
+		t.Fatalf("queued nested spawn: %v", err)
+	}
+}
+
+func TestManager_QueueCancelled(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.QueueOnFull = true
+	fillSlots(mgr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- mgr.reserveSlot(ctx, false) }()
+	waitForWaiters(t, mgr, 1)
+	cancel()
+
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	freeSlot(mgr, 0)
+	if mgr.reserved != 0 {
+		t.Errorf("reserved = %d, a cancelled waiter should not receive a slot", mgr.reserved)
+	}
+}
+
+func TestManager_QueueFIFO(t *testing.T) {
+	mgr := newTestManager(&mockLLMClient{})
+	mgr.opts.QueueOnFull = true
+	fillSlots(mgr)
+
+	first, second := make(chan error, 1), make(chan error, 1)
+	go func() { first <- mgr.reserveSlot(context.Background(), false) }()
+	waitForWaiters(t, mgr, 1)
+	go func() { second <- mgr.reserveSlot(context.Background(), false) }()
+	waitForWaiters(t, mgr, 2)
+
+	freeSlot(mgr, 0)
+	if err := <-first; err != nil {
+		t.Fatalf("first waiter: %v", err)
+	}
+	select {
+	case <-second:
+		t.Fatal("second waiter should still be queued")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	freeSlot(mgr, 1)
+	if err := <-second; err != nil {
+		t.Fatalf("second waiter: %v", err)
+	}
+}
+
+func TestManager_StructuredOutput(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{endTurnWithText(`{"callers": ["main", "run"]}`)},
+	}
+	mgr := newTestManager(client)
+
+	result, err := mgr.Spawn(context.Background(), tools.AgentInput{
+		Description:  "find callers",
+		Prompt:       "Who calls parse?",
+		SubagentType: "general-purpose",
+		OutputSchema: map[string]any{
+			"type":     "object",
+			"required": []any{"callers"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Error != "" {
+		t.Fatalf("unexpected result error: %q", result.Error)
+	}
+	obj, ok := result.StructuredOutput.(map[string]any)
+	if !ok || len(obj["callers"].([]any)) != 2 {
+		t.Fatalf("StructuredOutput = %#v", result.StructuredOutput)
+	}
+	if taskResult, _ := mgr.GetOutput(result.AgentID, false, 0); taskResult.StructuredOutput == nil {
+		t.Error("expected StructuredOutput on the task result")
+	}
+}
+
+func TestManager_StructuredOutputInvalid(t *testing.T) {
+	client := &mockLLMClient{
+		responses: []*mockStreamData{
+			endTurnWithText("not json"),
+			endTurnWithText("still not json"),
+			endTurnWithText("sorry, no json"),
+		},
+	}
+	mgr := newTestManager(client)
+
+	result, err := mgr.Spawn(context.Background(), tools.AgentInput{
+		Description:  "find callers",
+		Prompt:       "Who calls parse?",
+		SubagentType: "general-purpose",
+		OutputSchema: map[string]any{"type": "object"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result.Error, "structured output") {
+		t.Errorf("Error = %q, want a structured output failure", result.Error)
+	}
+	if result.StructuredOutput != nil {
+		t.Errorf("StructuredOutput = %#v, want nil", result.StructuredOutput)
+	}
+	if !strings.Contains(result.Output, "sorry, no json") {
+		t.Errorf("Output = %q, want the raw text kept for debugging", result.Output)
+	}
+}
@@ -45,11 +45,14 @@ type TaskMetrics struct {
 
 // TaskResult is the final output of a subagent execution.
 type TaskResult struct {
-	Content string
-	Metrics TaskMetrics
-	State   AgentState
-	AgentID string
-	Error   string // error message (empty on success)
+	Content        string
+	Metrics        TaskMetrics
+	State          AgentState
+	AgentID        string
+	Error          string // error message (empty on success)
+	BudgetExceeded bool   // stopped at the agent's MaxBudgetUSD cap
+
+	StructuredOutput any // parsed final answer when an output schema was set
 }
 
 // AgentOutput is a thread-safe accumulator for streaming subagent output.
@@ -102,6 +105,7 @@ type RunningAgent struct {
 	Done           chan struct{} // closed when the agent finishes
 	Metrics        TaskMetrics
 	Warnings       []string     // non-fatal warnings from spawn (e.g., unknown tool names)
+	Depth          int          // nesting level (1 = spawned by the main agent)
 
 	mu        sync.Mutex
 	cleanupFn func() // called on completion to unregister hooks etc.
@@ -2,9 +2,9 @@ package subagent
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -12,17 +12,31 @@ import (
 
 const watchDebounce = 200 * time.Millisecond
 
+// ErrAlreadyWatching is returned by Watch when the Manager is already watching.
+var ErrAlreadyWatching = errors.New("agent definitions already being watched")
+
 // WatchDirs configures which directories to watch for agent file changes.
 type WatchDirs struct {
 	ProjectDir string   // .claude/agents/ in the project
 	UserDir    string   // ~/.claude/agents/
 	PluginDirs []string // plugin agent directories
+
+	// OnReload, if set, receives the warnings and error from each reload so
+	// a CLI can report malformed definitions as they are saved.
+	OnReload func(warnings []LoadWarning, err error)
 }
 
 // Watch monitors agent definition directories for file changes and calls Reload
-// when .md files are created, modified, or removed. The method blocks until ctx
-// is cancelled. Running agents are NOT affected — only new spawns use reloaded definitions.
+// when .md files are created, modified, renamed, or removed. Bursts of changes
+// are debounced into a single reload. The method blocks until ctx is cancelled
+// and only one Watch may run per Manager at a time. Running agents are NOT
+// affected — only new spawns use reloaded definitions.
 func (m *Manager) Watch(ctx context.Context, dirs WatchDirs) error {
+	if !m.watching.CompareAndSwap(false, true) {
+		return ErrAlreadyWatching
+	}
+	defer m.watching.Store(false)
+
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		return err
@@ -41,12 +55,6 @@ func (m *Manager) Watch(ctx context.Context, dirs WatchDirs) error {
 		return ctx.Err()
 	}
 
-	var (
-		debounceTimer *time.Timer
-		mu            sync.Mutex
-		pending       bool
-	)
-
 	// CWD for reload — derive from ProjectDir
 	cwd := ""
 	if dirs.ProjectDir != "" {
@@ -54,14 +62,17 @@ func (m *Manager) Watch(ctx context.Context, dirs WatchDirs) error {
 		cwd = filepath.Dir(filepath.Dir(dirs.ProjectDir))
 	}
 
-	doReload := func() {
-		mu.Lock()
-		pending = false
-		mu.Unlock()
-		if cwd != "" {
-			m.Reload(cwd)
+	// The debounce timer fires into this loop rather than its own goroutine,
+	// so no reload can run after Watch returns.
+	var (
+		debounce *time.Timer
+		fire     <-chan time.Time
+	)
+	defer func() {
+		if debounce != nil {
+			debounce.Stop()
 		}
-	}
+	}()
 
 	for {
 		select {
@@ -76,20 +87,28 @@ func (m *Manager) Watch(ctx context.Context, dirs WatchDirs) error {
 			if !strings.HasSuffix(event.Name, ".md") {
 				continue
 			}
-			// Only care about create/write/remove
-			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove) == 0 {
+			// Only care about create/write/rename/remove (editors often save by rename)
+			if event.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Rename|fsnotify.Remove) == 0 {
 				continue
 			}
 
 			// Debounce: reset timer on each event
-			mu.Lock()
-			if !pending {
-				pending = true
-				debounceTimer = time.AfterFunc(watchDebounce, doReload)
+			if debounce == nil {
+				debounce = time.NewTimer(watchDebounce)
 			} else {
-				debounceTimer.Reset(watchDebounce)
+				debounce.Reset(watchDebounce)
+			}
+			fire = debounce.C
+
+		case <-fire:
+			fire = nil
+			if cwd == "" {
+				continue
+			}
+			warnings, err := m.Reload(cwd)
+			if dirs.OnReload != nil {
+				dirs.OnReload(warnings, err)
 			}
-			mu.Unlock()
 
 		case _, ok := <-watcher.Errors:
 			if !ok {
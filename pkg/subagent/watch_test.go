@@ -171,3 +171,89 @@ func TestManager_WatchCtxCancel(t *testing.T) {
 		t.Fatal("Watch did not return after context cancel")
 	}
 }
+
+func TestManager_WatchOnReloadWarnings(t *testing.T) {
+	dir := t.TempDir()
+	agentDir := filepath.Join(dir, ".claude", "agents")
+	os.MkdirAll(agentDir, 0o755)
+
+	mgr := newTestManager(&mockLLMClient{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reloads := make(chan []LoadWarning, 4)
+	go mgr.Watch(ctx, WatchDirs{
+		ProjectDir: agentDir,
+		OnReload: func(warnings []LoadWarning, err error) {
+			if err != nil {
+				t.Errorf("reload error: %v", err)
+			}
+			reloads <- warnings
+		},
+	})
+	time.Sleep(100 * time.Millisecond)
+
+	os.WriteFile(filepath.Join(agentDir, "broken.md"), []byte("---\nmodel: haiku\n---\nNo description."), 0o644)
+
+	select {
+	case warnings := <-reloads:
+		var found bool
+		for _, w := range warnings {
+			if filepath.Base(w.File) == "broken.md" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("warnings = %v, want one for broken.md", warnings)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnReload was not called")
+	}
+}
+
+func TestManager_WatchOnlyOnce(t *testing.T) {
+	dir := t.TempDir()
+	agentDir := filepath.Join(dir, ".claude", "agents")
+	os.MkdirAll(agentDir, 0o755)
+
+	mgr := newTestManager(&mockLLMClient{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reloaded := make(chan struct{}, 4)
+	dirs := WatchDirs{
+		ProjectDir: agentDir,
+		OnReload:   func([]LoadWarning, error) { reloaded <- struct{}{} },
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- mgr.Watch(ctx, dirs) }()
+	time.Sleep(100 * time.Millisecond)
+
+	if err := mgr.Watch(context.Background(), dirs); err != ErrAlreadyWatching {
+		t.Errorf("second Watch = %v, want ErrAlreadyWatching", err)
+	}
+
+	// A change still pending its debounce when ctx is cancelled must not
+	// reload after Watch returns.
+	os.WriteFile(filepath.Join(agentDir, "late.md"), []byte("---\ndescription: Late\n---\nPrompt."), 0o644)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("Watch = %v, want context.Canceled", err)
+	}
+	time.Sleep(2 * watchDebounce)
+	select {
+	case <-reloaded:
+		t.Error("reload ran after Watch returned")
+	default:
+	}
+
+	// Once stopped, the Manager can be watched again.
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	go func() { errCh <- mgr.Watch(ctx2, dirs) }()
+	time.Sleep(100 * time.Millisecond)
+	cancel2()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("restarted Watch = %v, want context.Canceled", err)
+	}
+}
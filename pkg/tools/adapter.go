@@ -23,6 +23,7 @@ var compactDescriptions = map[string]string{
 	"Read":  "Read a file from the filesystem. Returns file contents with line numbers.",
 	"Write": "Write content to a file, creating or overwriting it.",
 	"Edit":  "Replace an exact string in a file with new text. Requires reading the file first.",
+	"ApplyPatch": "Apply a unified diff to one or more files. Nothing is written if a hunk fails unless partial is true.",
 	"Glob":  "Find files matching a glob pattern (e.g. \"**/*.go\"). Returns matching file paths.",
 	"Grep":  "Search file contents using regex via ripgrep. Returns matching lines or file paths.",
 }
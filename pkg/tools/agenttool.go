@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 )
 
@@ -14,8 +15,10 @@ type AgentInput struct {
 	Resume          *string
 	RunInBackground *bool
 	MaxTurns        *int
-	Name            *string // display name for the agent
-	Mode            *string // permission mode override
+	MaxBudgetUSD    *float64       // cost cap for this agent alone (nil = unlimited)
+	Name            *string        // display name for the agent
+	Mode            *string        // permission mode override
+	OutputSchema    map[string]any // JSON Schema the agent's final answer must match (nil = free-form text)
 }
 
 // AgentMetrics contains execution metrics from a subagent run.
@@ -34,6 +37,11 @@ type AgentResult struct {
 	OutputFile string        // path to output file (background agents only)
 	Error      string        // error message from subagent (empty on success)
 	Metrics    *AgentMetrics // execution metrics (nil for background agents)
+	// BudgetExceeded is true when the agent stopped at its MaxBudgetUSD cap.
+	BudgetExceeded bool
+	// StructuredOutput is the parsed final answer when OutputSchema was set.
+	// If the agent could not produce it, Error says so and Output holds the raw text.
+	StructuredOutput any
 }
 
 // SubagentSpawner creates and runs subagent instances.
@@ -76,7 +84,8 @@ Usage notes:
 - Provide clear, detailed prompts so the agent can work autonomously and return exactly the information you need.
 - The agent's outputs should generally be trusted
 - Clearly tell the agent whether you expect it to write code or just to do research (search, file reads, web fetches, etc.), since it is not aware of the user's intent
-- If the user specifies that they want you to run agents "in parallel", you MUST send a single message with multiple Agent tool use content blocks.`
+- If the user specifies that they want you to run agents "in parallel", you MUST send a single message with multiple Agent tool use content blocks.
+- When you need machine-readable results (e.g. a list of callers or files), pass output_schema with a JSON Schema. The agent is instructed to make its final answer conform to it, and it is returned to you as JSON.`
 }
 
 func (a *AgentTool) InputSchema() map[string]any {
@@ -111,6 +120,10 @@ func (a *AgentTool) InputSchema() map[string]any {
 				"type":        "integer",
 				"description": "Maximum number of agentic turns before stopping",
 			},
+			"max_budget_usd": map[string]any{
+				"type":        "number",
+				"description": "Maximum cost in USD this agent may spend before stopping",
+			},
 			"name": map[string]any{
 				"type":        "string",
 				"description": "Optional display name for the agent",
@@ -119,6 +132,10 @@ func (a *AgentTool) InputSchema() map[string]any {
 				"type":        "string",
 				"description": "Optional permission mode override",
 			},
+			"output_schema": map[string]any{
+				"type":        "object",
+				"description": "Optional JSON Schema the agent's final answer must conform to",
+			},
 		},
 		"required": []string{"description", "prompt", "subagent_type"},
 	}
@@ -166,12 +183,18 @@ func (a *AgentTool) Execute(ctx context.Context, input map[string]any) (ToolOutp
 		turns := int(mt)
 		agentInput.MaxTurns = &turns
 	}
+	if budget, ok := input["max_budget_usd"].(float64); ok && budget > 0 {
+		agentInput.MaxBudgetUSD = &budget
+	}
 	if n, ok := input["name"].(string); ok {
 		agentInput.Name = &n
 	}
 	if mode, ok := input["mode"].(string); ok {
 		agentInput.Mode = &mode
 	}
+	if schema, ok := input["output_schema"].(map[string]any); ok && len(schema) > 0 {
+		agentInput.OutputSchema = schema
+	}
 
 	result, err := spawner.Spawn(ctx, agentInput)
 	if err != nil {
@@ -190,6 +213,11 @@ func (a *AgentTool) Execute(ctx context.Context, input map[string]any) (ToolOutp
 	}
 
 	content := result.Output
+	if result.StructuredOutput != nil {
+		if data, err := json.MarshalIndent(result.StructuredOutput, "", "  "); err == nil {
+			content = string(data)
+		}
+	}
 	if result.AgentID != "" {
 		content += fmt.Sprintf("\n\nagentId: %s", result.AgentID)
 	}
@@ -198,6 +226,9 @@ func (a *AgentTool) Execute(ctx context.Context, input map[string]any) (ToolOutp
 		m := result.Metrics
 		content += fmt.Sprintf("\n---\nDuration: %.1fs | Turns: %d | Cost: $%.4f | Tokens: %d in / %d out",
 			m.DurationSecs, m.TurnCount, m.CostUSD, m.InputTokens, m.OutputTokens)
+		if result.BudgetExceeded {
+			content += " | Budget cap reached"
+		}
 	}
 
 	if result.Error != "" {
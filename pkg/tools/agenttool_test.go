@@ -118,12 +118,13 @@ func TestAgent_OptionalFields(t *testing.T) {
 	tool := &AgentTool{Spawner: spawner}
 
 	tool.Execute(context.Background(), map[string]any{
-		"description":   "test",
-		"prompt":        "test",
-		"subagent_type": "general",
-		"model":         "sonnet",
-		"resume":        "prev-id",
-		"max_turns":     float64(10),
+		"description":    "test",
+		"prompt":         "test",
+		"subagent_type":  "general",
+		"model":          "sonnet",
+		"resume":         "prev-id",
+		"max_turns":      float64(10),
+		"max_budget_usd": 0.25,
 	})
 
 	if spawner.input.Model == nil || *spawner.input.Model != "sonnet" {
@@ -135,6 +136,9 @@ func TestAgent_OptionalFields(t *testing.T) {
 	if spawner.input.MaxTurns == nil || *spawner.input.MaxTurns != 10 {
 		t.Error("expected max_turns to be 10")
 	}
+	if spawner.input.MaxBudgetUSD == nil || *spawner.input.MaxBudgetUSD != 0.25 {
+		t.Error("expected max_budget_usd to be 0.25")
+	}
 }
 
 func TestAgent_NameAndModeFields(t *testing.T) {
@@ -234,6 +238,31 @@ func TestAgent_MetricsInOutput(t *testing.T) {
 	}
 }
 
+func TestAgent_BudgetExceededInOutput(t *testing.T) {
+	spawner := &mockSpawner{
+		result: AgentResult{
+			AgentID:        "agent-b",
+			Output:         "partial",
+			Error:          "max budget exceeded",
+			Metrics:        &AgentMetrics{TurnCount: 1, CostUSD: 0.5},
+			BudgetExceeded: true,
+		},
+	}
+	tool := &AgentTool{Spawner: spawner}
+
+	out, _ := tool.Execute(context.Background(), map[string]any{
+		"description":   "test",
+		"prompt":        "test",
+		"subagent_type": "general",
+	})
+	if !out.IsError {
+		t.Error("expected error output when the budget cap was hit")
+	}
+	if !strings.Contains(out.Content, "Budget cap reached") {
+		t.Errorf("expected budget cap note in output, got %q", out.Content)
+	}
+}
+
 func TestAgent_BackgroundNoMetrics(t *testing.T) {
 	spawner := &mockSpawner{
 		result: AgentResult{AgentID: "bg-agent", Output: "", OutputFile: "/tmp/bg.output"},
@@ -287,3 +316,34 @@ func TestAgent_InputSchema_HasNameAndMode(t *testing.T) {
 		t.Error("expected 'mode' in schema properties")
 	}
 }
+
+func TestAgent_OutputSchema(t *testing.T) {
+	spawner := &mockSpawner{
+		result: AgentResult{
+			AgentID:          "agent-s",
+			Output:           `{"files": ["a.go"]}`,
+			StructuredOutput: map[string]any{"files": []any{"a.go"}},
+		},
+	}
+	tool := &AgentTool{Spawner: spawner}
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"files": map[string]any{"type": "array"}},
+	}
+
+	out, _ := tool.Execute(context.Background(), map[string]any{
+		"description":   "find files",
+		"prompt":        "list the go files",
+		"subagent_type": "Explore",
+		"output_schema": schema,
+	})
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if spawner.input.OutputSchema["type"] != "object" {
+		t.Errorf("OutputSchema = %v, want the schema passed through", spawner.input.OutputSchema)
+	}
+	if !strings.Contains(out.Content, "\"files\": [\n    \"a.go\"\n  ]") {
+		t.Errorf("expected structured output as JSON, got %q", out.Content)
+	}
+}
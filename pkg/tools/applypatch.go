@@ -0,0 +1,496 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// applyPatchMaxFuzz is the number of leading/trailing context lines that may be
+// ignored when a hunk does not match exactly (like patch's --fuzz).
+const applyPatchMaxFuzz = 2
+
+// ApplyPatchTool applies a unified diff, possibly spanning multiple files.
+type ApplyPatchTool struct {
+	CWD string // base directory for relative paths in the patch
+}
+
+func (a *ApplyPatchTool) Name() string { return "ApplyPatch" }
+
+func (a *ApplyPatchTool) Description() string {
+	return `Applies a unified diff (as produced by "diff -u" or "git diff") to one or more files.
+
+Usage:
+- The patch parameter must contain standard unified diff text with "---"/"+++" file headers and "@@" hunks
+- Paths may be absolute or relative to the working directory; "a/" and "b/" prefixes are stripped
+- Use "--- /dev/null" to create a new file and "+++ /dev/null" to delete one
+- Different "---" and "+++" paths rename the file: hunks apply to the old path, the result is written to the new one and the old file is removed
+- Hunks are located by their context lines, so line numbers may be slightly off; whitespace at line ends is ignored when matching
+- If any hunk fails, nothing is written unless partial is true, in which case every hunk that applies is kept
+- Prefer Edit for small single-location changes; use ApplyPatch for multi-hunk or multi-file changes`
+}
+
+func (a *ApplyPatchTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "The unified diff to apply",
+			},
+			"partial": map[string]any{
+				"type":        "boolean",
+				"description": "Apply the hunks that succeed even if others fail (default false)",
+			},
+		},
+		"required": []string{"patch"},
+	}
+}
+
+func (a *ApplyPatchTool) SideEffect() SideEffectType { return SideEffectMutating }
+
+func (a *ApplyPatchTool) Execute(_ context.Context, input map[string]any) (ToolOutput, error) {
+	patchText, ok := input["patch"].(string)
+	if !ok || strings.TrimSpace(patchText) == "" {
+		return ToolOutput{Content: "Error: patch is required", IsError: true}, nil
+	}
+	partial, _ := input["partial"].(bool)
+
+	files, err := parseUnifiedDiff(patchText)
+	if err != nil {
+		return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
+	}
+	if len(files) == 0 {
+		return ToolOutput{Content: "Error: no file changes found in patch", IsError: true}, nil
+	}
+
+	type fileOutcome struct {
+		path          string
+		from          string // the old path of a rename, removed once path is written
+		newContent    string
+		remove        bool
+		added, delete int
+		failed        []string
+	}
+
+	outcomes := make([]fileOutcome, 0, len(files))
+	anyFailed := false
+
+	for _, fp := range files {
+		out := fileOutcome{path: a.resolvePath(fp.path())}
+		switch {
+		case fp.oldPath == "":
+			if _, err := os.Stat(out.path); err == nil {
+				out.failed = append(out.failed, "file already exists")
+				break
+			}
+			var lines []string
+			for _, h := range fp.hunks {
+				for _, l := range h.lines {
+					if l.op == '+' {
+						lines = append(lines, l.text)
+						out.added++
+					}
+				}
+			}
+			out.newContent = joinPatchLines(lines, !fp.noNewlineAtEnd)
+		default:
+			source := a.resolvePath(fp.oldPath)
+			if fp.renamed() {
+				out.from = source
+				if _, err := os.Stat(out.path); err == nil {
+					out.failed = append(out.failed, "rename target already exists")
+					break
+				}
+			}
+			data, err := os.ReadFile(source)
+			if err != nil {
+				out.failed = append(out.failed, fmt.Sprintf("cannot read file: %s", err))
+				break
+			}
+			content := string(data)
+			lines := strings.Split(content, "\n")
+			trailingNewline := strings.HasSuffix(content, "\n")
+			if trailingNewline {
+				lines = lines[:len(lines)-1]
+			}
+
+			offset := 0 // line shift from previously applied hunks
+			for i, h := range fp.hunks {
+				updated, pos, ok := applyHunk(lines, h, h.oldStart-1+offset)
+				if !ok {
+					out.failed = append(out.failed, fmt.Sprintf("hunk %d (@@ -%d,%d @@) does not match", i+1, h.oldStart, h.oldCount()))
+					continue
+				}
+				lines = updated
+				offset = pos - (h.oldStart - 1) + h.newCount() - h.oldCount()
+				out.added += h.newCount() - h.contextCount()
+				out.delete += h.oldCount() - h.contextCount()
+			}
+
+			if fp.newPath == "" {
+				out.remove = true
+				if len(lines) > 0 && len(out.failed) == 0 {
+					out.failed = append(out.failed, "file to delete does not match the patch")
+				}
+				break
+			}
+			if fp.noNewlineAtEnd {
+				trailingNewline = false
+			} else if fp.hasNewlineMarker {
+				trailingNewline = true
+			}
+			out.newContent = joinPatchLines(lines, trailingNewline)
+		}
+		if len(out.failed) > 0 {
+			anyFailed = true
+		}
+		outcomes = append(outcomes, out)
+	}
+
+	var b strings.Builder
+	if anyFailed && !partial {
+		b.WriteString("Error: patch not applied (no files were changed). Re-read the files and regenerate the failing hunks, or set partial=true.\n")
+		for _, o := range outcomes {
+			if len(o.failed) > 0 {
+				fmt.Fprintf(&b, "FAILED %s: %s\n", o.path, strings.Join(o.failed, "; "))
+			} else {
+				fmt.Fprintf(&b, "ok     %s\n", o.path)
+			}
+		}
+		return ToolOutput{Content: strings.TrimRight(b.String(), "\n"), IsError: true}, nil
+	}
+
+	totalAdded, totalRemoved, applied := 0, 0, 0
+	for _, o := range outcomes {
+		if o.remove && len(o.failed) > 0 {
+			fmt.Fprintf(&b, "FAILED %s: %s\n", o.path, strings.Join(o.failed, "; "))
+			continue
+		}
+		if o.remove {
+			if err := os.Remove(o.path); err != nil {
+				fmt.Fprintf(&b, "FAILED %s: %s\n", o.path, err)
+				continue
+			}
+			fmt.Fprintf(&b, "deleted %s (-%d)\n", o.path, o.delete)
+		} else {
+			if len(o.failed) > 0 && o.added == 0 && o.delete == 0 {
+				// every hunk failed, nothing to write
+				fmt.Fprintf(&b, "FAILED %s: %s\n", o.path, strings.Join(o.failed, "; "))
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(o.path), 0o755); err != nil {
+				fmt.Fprintf(&b, "FAILED %s: %s\n", o.path, err)
+				continue
+			}
+			if err := os.WriteFile(o.path, []byte(o.newContent), 0o644); err != nil {
+				fmt.Fprintf(&b, "FAILED %s: %s\n", o.path, err)
+				continue
+			}
+			status, target := "patched", o.path
+			if o.from != "" {
+				if err := os.Remove(o.from); err != nil {
+					fmt.Fprintf(&b, "FAILED %s: wrote %s but could not remove the old file: %s\n", o.from, o.path, err)
+					continue
+				}
+				status, target = "renamed", o.from+" -> "+o.path
+			}
+			if len(o.failed) > 0 {
+				status = "partially " + status
+			}
+			fmt.Fprintf(&b, "%s %s (+%d -%d)", status, target, o.added, o.delete)
+			if len(o.failed) > 0 {
+				fmt.Fprintf(&b, "; skipped: %s", strings.Join(o.failed, "; "))
+			}
+			b.WriteString("\n")
+		}
+		applied++
+		totalAdded += o.added
+		totalRemoved += o.delete
+	}
+	fmt.Fprintf(&b, "%d of %d file(s) changed, %d line(s) added, %d line(s) removed", applied, len(outcomes), totalAdded, totalRemoved)
+
+	return ToolOutput{Content: b.String(), IsError: applied == 0}, nil
+}
+
+func (a *ApplyPatchTool) resolvePath(path string) string {
+	if filepath.IsAbs(path) || a.CWD == "" {
+		return path
+	}
+	return filepath.Join(a.CWD, path)
+}
+
+// PatchFilePaths returns the target file paths named in a unified diff,
+// resolved against cwd, including the old path of a renamed file. Useful for
+// file-access tracking and permission rules.
+func PatchFilePaths(patchText, cwd string) []string {
+	files, err := parseUnifiedDiff(patchText)
+	if err != nil {
+		return nil
+	}
+	a := &ApplyPatchTool{CWD: cwd}
+	paths := make([]string, 0, len(files))
+	for _, f := range files {
+		paths = append(paths, a.resolvePath(f.path()))
+		if f.renamed() {
+			paths = append(paths, a.resolvePath(f.oldPath))
+		}
+	}
+	return paths
+}
+
+// --- unified diff parsing ---
+
+type patchLine struct {
+	op   byte // ' ', '-', '+'
+	text string
+}
+
+type patchHunk struct {
+	oldStart int
+	lines    []patchLine
+}
+
+func (h patchHunk) count(ops string) int {
+	n := 0
+	for _, l := range h.lines {
+		if strings.IndexByte(ops, l.op) >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func (h patchHunk) oldCount() int     { return h.count(" -") }
+func (h patchHunk) newCount() int     { return h.count(" +") }
+func (h patchHunk) contextCount() int { return h.count(" ") }
+
+type filePatch struct {
+	oldPath, newPath string // "" for /dev/null
+	hunks            []patchHunk
+	noNewlineAtEnd   bool // "\ No newline at end of file" after the new side's last line
+	hasNewlineMarker bool // a marker appeared only on the old side
+}
+
+func (f filePatch) path() string {
+	if f.newPath != "" {
+		return f.newPath
+	}
+	return f.oldPath
+}
+
+// renamed reports whether the patch moves an existing file to a new path.
+func (f filePatch) renamed() bool {
+	return f.oldPath != "" && f.newPath != "" && f.oldPath != f.newPath
+}
+
+func parseUnifiedDiff(text string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var files []filePatch
+	var cur *filePatch
+	var hunk *patchHunk
+	var lastOp byte
+
+	flushHunk := func() {
+		if cur != nil && hunk != nil {
+			cur.hunks = append(cur.hunks, *hunk)
+		}
+		hunk = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			flushHunk()
+			if cur != nil {
+				files = append(files, *cur)
+			}
+			cur = &filePatch{
+				oldPath: parsePatchPath(line[4:], "a/"),
+				newPath: parsePatchPath(lines[i+1][4:], "b/"),
+			}
+			if cur.oldPath == "" && cur.newPath == "" {
+				return nil, fmt.Errorf("line %d: both sides of the diff are /dev/null", i+1)
+			}
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if cur == nil {
+				return nil, fmt.Errorf("line %d: hunk without file header", i+1)
+			}
+			flushHunk()
+			oldStart, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			hunk = &patchHunk{oldStart: oldStart}
+		case hunk != nil && strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the preceding line
+			if lastOp == '-' {
+				cur.hasNewlineMarker = true
+			} else {
+				cur.noNewlineAtEnd = true
+			}
+		case hunk != nil && len(line) > 0 && (line[0] == ' ' || line[0] == '-' || line[0] == '+'):
+			hunk.lines = append(hunk.lines, patchLine{op: line[0], text: line[1:]})
+			lastOp = line[0]
+		case hunk != nil && line == "" && i+1 < len(lines) && isHunkBodyLine(lines[i+1]):
+			// Some generators drop the space on empty context lines
+			hunk.lines = append(hunk.lines, patchLine{op: ' '})
+			lastOp = ' '
+		default:
+			// diff --git, index, mode lines and trailing text end the current hunk
+			flushHunk()
+		}
+	}
+	flushHunk()
+	if cur != nil {
+		files = append(files, *cur)
+	}
+
+	for _, f := range files {
+		if len(f.hunks) == 0 {
+			return nil, fmt.Errorf("no hunks for %s", f.path())
+		}
+	}
+	return files, nil
+}
+
+func isHunkBodyLine(line string) bool {
+	return line == "" || line[0] == ' ' || line[0] == '-' || line[0] == '+' || line[0] == '\\'
+}
+
+// parsePatchPath extracts the path from a ---/+++ header, stripping the
+// git prefix and any trailing timestamp. Returns "" for /dev/null.
+func parsePatchPath(s, prefix string) string {
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimSpace(s)
+	if s == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(s, prefix)
+}
+
+// parseHunkHeader returns the 1-based old start line from "@@ -l,s +l,s @@".
+func parseHunkHeader(line string) (int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") {
+		return 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	startStr, _, _ := strings.Cut(fields[1][1:], ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, fmt.Errorf("malformed hunk header %q", line)
+	}
+	if start == 0 {
+		start = 1 // empty old side
+	}
+	return start, nil
+}
+
+// applyHunk locates h in lines near expected (0-based) and returns the updated
+// lines and the position where it matched. Matching tries exact lines, then
+// ignores trailing whitespace, then drops up to applyPatchMaxFuzz context lines
+// from each end.
+func applyHunk(lines []string, h patchHunk, expected int) ([]string, int, bool) {
+	for fuzz := 0; fuzz <= applyPatchMaxFuzz; fuzz++ {
+		body, lead, trail := trimHunkContext(h.lines, fuzz)
+		if fuzz > 0 && lead == 0 && trail == 0 {
+			break // nothing left to fuzz
+		}
+		var old []string
+		for _, l := range body {
+			if l.op != '+' {
+				old = append(old, l.text)
+			}
+		}
+		for _, loose := range []bool{false, true} {
+			pos, ok := findLines(lines, old, expected+lead, loose)
+			if !ok {
+				continue
+			}
+			updated := make([]string, 0, len(lines)+len(body))
+			updated = append(updated, lines[:pos]...)
+			k := pos
+			for _, l := range body {
+				switch l.op {
+				case ' ':
+					updated = append(updated, lines[k]) // keep the file's own context line
+					k++
+				case '-':
+					k++
+				case '+':
+					updated = append(updated, l.text)
+				}
+			}
+			updated = append(updated, lines[k:]...)
+			return updated, pos - lead, true
+		}
+	}
+	return nil, 0, false
+}
+
+// trimHunkContext drops up to n unchanged lines from each end of a hunk.
+func trimHunkContext(lines []patchLine, n int) ([]patchLine, int, int) {
+	lead, trail := 0, 0
+	for lead < n && lead < len(lines) && lines[lead].op == ' ' {
+		lead++
+	}
+	for trail < n && len(lines)-trail-1 > lead && lines[len(lines)-trail-1].op == ' ' {
+		trail++
+	}
+	return lines[lead : len(lines)-trail], lead, trail
+}
+
+// findLines searches for needle in haystack, starting at expected and moving
+// outward, so the closest match to the hunk's stated position wins.
+func findLines(haystack, needle []string, expected int, loose bool) (int, bool) {
+	maxPos := len(haystack) - len(needle)
+	if maxPos < 0 {
+		return 0, false
+	}
+	if expected < 0 {
+		expected = 0
+	}
+	if expected > maxPos {
+		expected = maxPos
+	}
+	for delta := 0; delta <= maxPos; delta++ {
+		if pos := expected - delta; pos >= 0 && linesEqual(haystack[pos:pos+len(needle)], needle, loose) {
+			return pos, true
+		}
+		if pos := expected + delta; delta > 0 && pos <= maxPos && linesEqual(haystack[pos:pos+len(needle)], needle, loose) {
+			return pos, true
+		}
+	}
+	return 0, false
+}
+
+func linesEqual(a, b []string, loose bool) bool {
+	for i := range b {
+		if loose {
+			if strings.TrimRight(a[i], " \t") != strings.TrimRight(b[i], " \t") {
+				return false
+			}
+		} else if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func joinPatchLines(lines []string, trailingNewline bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	s := strings.Join(lines, "\n")
+	if trailingNewline {
+		s += "\n"
+	}
+	return s
+}
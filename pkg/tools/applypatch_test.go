@@ -0,0 +1,271 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func runApplyPatch(t *testing.T, dir, patch string, partial bool) ToolOutput {
+	t.Helper()
+	tool := &ApplyPatchTool{CWD: dir}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"patch":   patch,
+		"partial": partial,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestApplyPatch_MultiFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("one\ntwo\nthree\nfour\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("alpha\nbeta\n"), 0o644)
+
+	patch := `diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1,4 +1,4 @@
+ one
+-two
++TWO
+ three
+ four
+diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -1,2 +1,3 @@
+ alpha
+ beta
++gamma
+`
+	out := runApplyPatch(t, dir, patch, false)
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "a.txt")); got != "one\nTWO\nthree\nfour\n" {
+		t.Errorf("a.txt = %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "b.txt")); got != "alpha\nbeta\ngamma\n" {
+		t.Errorf("b.txt = %q", got)
+	}
+	if !strings.Contains(out.Content, "2 of 2 file(s) changed, 2 line(s) added, 1 line(s) removed") {
+		t.Errorf("unexpected summary: %s", out.Content)
+	}
+}
+
+func TestApplyPatch_FuzzyMatching(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		patch   string
+		want    string
+	}{
+		{
+			name:    "shifted line numbers",
+			content: "header\nextra\nextra\nfunc a() {\n\treturn 1\n}\n",
+			patch:   "--- a/f.go\n+++ b/f.go\n@@ -1,3 +1,3 @@\n func a() {\n-\treturn 1\n+\treturn 2\n }\n",
+			want:    "header\nextra\nextra\nfunc a() {\n\treturn 2\n}\n",
+		},
+		{
+			name:    "trailing whitespace differs",
+			content: "x := 1   \ny := 2\n",
+			patch:   "--- a/f.go\n+++ b/f.go\n@@ -1,2 +1,2 @@\n x := 1\n-y := 2\n+y := 3\n",
+			want:    "x := 1   \ny := 3\n",
+		},
+		{
+			name:    "stale outer context",
+			content: "a\nb\nc\nd\ne\n",
+			patch:   "--- a/f.go\n+++ b/f.go\n@@ -1,5 +1,5 @@\n STALE\n b\n-c\n+C\n d\n STALE\n",
+			want:    "a\nb\nC\nd\ne\n",
+		},
+		{
+			name:    "closest match wins",
+			content: "x\nx\nx\nx\nx\nx\n",
+			patch:   "--- a/f.go\n+++ b/f.go\n@@ -5,1 +5,1 @@\n-x\n+y\n",
+			want:    "x\nx\nx\nx\ny\nx\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "f.go")
+			os.WriteFile(path, []byte(tt.content), 0o644)
+
+			out := runApplyPatch(t, dir, tt.patch, false)
+			if out.IsError {
+				t.Fatalf("unexpected error: %s", out.Content)
+			}
+			if got := readTestFile(t, path); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch_FailingHunkAppliesNothing(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	b := filepath.Join(dir, "b.txt")
+	os.WriteFile(a, []byte("one\ntwo\n"), 0o644)
+	os.WriteFile(b, []byte("alpha\nbeta\n"), 0o644)
+
+	patch := "--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n" +
+		"--- a/b.txt\n+++ b/b.txt\n@@ -1,2 +1,2 @@\n alpha\n-missing\n+gone\n"
+
+	out := runApplyPatch(t, dir, patch, false)
+	if !out.IsError {
+		t.Fatalf("expected error, got: %s", out.Content)
+	}
+	if !strings.Contains(out.Content, "FAILED") || !strings.Contains(out.Content, "b.txt") {
+		t.Errorf("expected per-file failure report, got: %s", out.Content)
+	}
+	if got := readTestFile(t, a); got != "one\ntwo\n" {
+		t.Errorf("a.txt should be untouched, got %q", got)
+	}
+
+	// With partial=true the good file is written and the bad one is reported
+	out = runApplyPatch(t, dir, patch, true)
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if got := readTestFile(t, a); got != "one\nTWO\n" {
+		t.Errorf("a.txt = %q", got)
+	}
+	if got := readTestFile(t, b); got != "alpha\nbeta\n" {
+		t.Errorf("b.txt should be untouched, got %q", got)
+	}
+	if !strings.Contains(out.Content, "1 of 2 file(s) changed") {
+		t.Errorf("unexpected summary: %s", out.Content)
+	}
+}
+
+func TestApplyPatch_CreateAndDelete(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.txt")
+	os.WriteFile(old, []byte("bye\n"), 0o644)
+
+	patch := "--- /dev/null\n+++ b/sub/new.txt\n@@ -0,0 +1,2 @@\n+hello\n+world\n" +
+		"--- a/old.txt\n+++ /dev/null\n@@ -1 +0,0 @@\n-bye\n"
+
+	out := runApplyPatch(t, dir, patch, false)
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "sub", "new.txt")); got != "hello\nworld\n" {
+		t.Errorf("new.txt = %q", got)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("old.txt should be deleted")
+	}
+	if !strings.Contains(out.Content, "2 line(s) added, 1 line(s) removed") {
+		t.Errorf("unexpected summary: %s", out.Content)
+	}
+
+	// Creating over an existing file fails
+	out = runApplyPatch(t, dir, "--- /dev/null\n+++ b/sub/new.txt\n@@ -0,0 +1 @@\n+x\n", false)
+	if !out.IsError || !strings.Contains(out.Content, "already exists") {
+		t.Errorf("expected already exists error, got: %s", out.Content)
+	}
+}
+
+func TestApplyPatch_Rename(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "old.txt")
+	os.WriteFile(old, []byte("one\ntwo\n"), 0o644)
+
+	patch := "diff --git a/old.txt b/sub/new.txt\nsimilarity index 50%\nrename from old.txt\nrename to sub/new.txt\n" +
+		"--- a/old.txt\n+++ b/sub/new.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+TWO\n"
+	out := runApplyPatch(t, dir, patch, false)
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "sub", "new.txt")); got != "one\nTWO\n" {
+		t.Errorf("new.txt = %q", got)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("old.txt should be removed by the rename")
+	}
+	if !strings.Contains(out.Content, "renamed "+old+" -> ") {
+		t.Errorf("unexpected summary: %s", out.Content)
+	}
+
+	// Renaming onto an existing file fails and leaves both alone
+	os.WriteFile(old, []byte("one\ntwo\n"), 0o644)
+	out = runApplyPatch(t, dir, patch, false)
+	if !out.IsError || !strings.Contains(out.Content, "rename target already exists") {
+		t.Errorf("expected rename target error, got: %s", out.Content)
+	}
+	if got := readTestFile(t, old); got != "one\ntwo\n" {
+		t.Errorf("old.txt = %q, want it untouched", got)
+	}
+}
+
+func TestApplyPatch_MissingFile(t *testing.T) {
+	dir := t.TempDir()
+	out := runApplyPatch(t, dir, "--- a/nope.txt\n+++ b/nope.txt\n@@ -1 +1 @@\n-a\n+b\n", false)
+	if !out.IsError || !strings.Contains(out.Content, "cannot read file") {
+		t.Errorf("expected missing file error, got: %s", out.Content)
+	}
+}
+
+func TestApplyPatch_NoNewlineAtEnd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	os.WriteFile(path, []byte("a\nb"), 0o644)
+
+	patch := "--- a/f.txt\n+++ b/f.txt\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"
+	out := runApplyPatch(t, dir, patch, false)
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if got := readTestFile(t, path); got != "a\nc" {
+		t.Errorf("got %q, want %q", got, "a\nc")
+	}
+}
+
+func TestApplyPatch_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"empty", "", "patch is required"},
+		{"no headers", "just some text\n", "no file changes"},
+		{"hunk without header", "@@ -1 +1 @@\n-a\n+b\n", "hunk without file header"},
+		{"bad hunk header", "--- a/f\n+++ b/f\n@@ bogus @@\n", "malformed hunk header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runApplyPatch(t, t.TempDir(), tt.patch, false)
+			if !out.IsError || !strings.Contains(out.Content, tt.want) {
+				t.Errorf("expected error containing %q, got: %s", tt.want, out.Content)
+			}
+		})
+	}
+}
+
+func TestPatchFilePaths(t *testing.T) {
+	patch := "--- a/x.go\n+++ b/x.go\n@@ -1 +1 @@\n-a\n+b\n" +
+		"--- /dev/null\n+++ b/y.go\n@@ -0,0 +1 @@\n+c\n" +
+		"--- a/old.go\n+++ b/new.go\n@@ -1 +1 @@\n-d\n+e\n"
+	got := PatchFilePaths(patch, "/repo")
+	want := []string{"/repo/x.go", "/repo/y.go", "/repo/new.go", "/repo/old.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -26,9 +26,27 @@ type UserInputHandler interface {
 	AskQuestions(ctx context.Context, questions []QuestionSpec) (map[string]string, error)
 }
 
+type userInputHandlerKey struct{}
+
+// WithUserInputHandler returns a context whose AskUserQuestion calls are
+// answered by h when the tool has no Handler of its own. The agent loop uses
+// it to route questions through an interactive session; a nil h marks the
+// context as headless.
+func WithUserInputHandler(ctx context.Context, h UserInputHandler) context.Context {
+	return context.WithValue(ctx, userInputHandlerKey{}, h)
+}
+
+func userInputHandlerFrom(ctx context.Context) UserInputHandler {
+	h, _ := ctx.Value(userInputHandlerKey{}).(UserInputHandler)
+	return h
+}
+
 // AskUserQuestionTool blocks for user input, delegating to a callback interface.
+// Without a handler (headless or eval runs), questions are answered from
+// Defaults, or the call fails so the model proceeds on its own judgment.
 type AskUserQuestionTool struct {
-	Handler UserInputHandler
+	Handler  UserInputHandler
+	Defaults map[string]string // headless answers, keyed by question text or header
 }
 
 func (a *AskUserQuestionTool) Name() string { return "AskUserQuestion" }
@@ -98,7 +116,11 @@ func (a *AskUserQuestionTool) InputSchema() map[string]any {
 func (a *AskUserQuestionTool) SideEffect() SideEffectType { return SideEffectBlocking }
 
 func (a *AskUserQuestionTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
-	if a.Handler == nil {
+	handler := a.Handler
+	if handler == nil {
+		handler = userInputHandlerFrom(ctx)
+	}
+	if handler == nil && len(a.Defaults) == 0 {
 		return ToolOutput{Content: "Error: user input not available in this context", IsError: true}, nil
 	}
 
@@ -156,19 +178,57 @@ func (a *AskUserQuestionTool) Execute(ctx context.Context, input map[string]any)
 		questions = append(questions, q)
 	}
 
-	answers, err := a.Handler.AskQuestions(ctx, questions)
+	if handler == nil {
+		return a.defaultAnswers(questions), nil
+	}
+
+	answers, err := handler.AskQuestions(ctx, questions)
 	if err != nil {
 		return ToolOutput{
 			Content: fmt.Sprintf("Error getting user input: %s", err),
 			IsError: true,
 		}, nil
 	}
+	return formatAnswers("User answers:", questions, answers), nil
+}
 
-	var b strings.Builder
-	b.WriteString("User answers:\n")
-	for k, v := range answers {
-		fmt.Fprintf(&b, "- %s: %s\n", k, v)
+// defaultAnswers answers every question from Defaults, failing if any has
+// no configured answer.
+func (a *AskUserQuestionTool) defaultAnswers(questions []QuestionSpec) ToolOutput {
+	answers := make(map[string]string, len(questions))
+	for _, q := range questions {
+		answer, ok := lookupAnswer(a.Defaults, q)
+		if !ok {
+			return ToolOutput{
+				Content: fmt.Sprintf("Error: user input not available in this context and no default answer for %q", q.Question),
+				IsError: true,
+			}
+		}
+		answers[q.Question] = answer
 	}
+	return formatAnswers("No user is available; using default answers:", questions, answers)
+}
 
-	return ToolOutput{Content: strings.TrimRight(b.String(), "\n")}, nil
+// lookupAnswer finds the answer for q, keyed by its question text or header.
+func lookupAnswer(answers map[string]string, q QuestionSpec) (string, bool) {
+	if answer, ok := answers[q.Question]; ok {
+		return answer, true
+	}
+	answer, ok := answers[q.Header]
+	return answer, ok
+}
+
+// formatAnswers lists each question with its chosen option, in question
+// order, so the transcript records what was decided.
+func formatAnswers(title string, questions []QuestionSpec, answers map[string]string) ToolOutput {
+	var b strings.Builder
+	b.WriteString(title + "\n")
+	for _, q := range questions {
+		answer, ok := lookupAnswer(answers, q)
+		if !ok {
+			answer = "(no answer)"
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", q.Header, answer)
+	}
+	return ToolOutput{Content: strings.TrimRight(b.String(), "\n")}
 }
@@ -216,3 +216,66 @@ func TestAskUser_EmptyQuestions(t *testing.T) {
 		t.Error("expected error for empty questions")
 	}
 }
+
+func askInput(questions ...map[string]any) map[string]any {
+	raw := make([]any, len(questions))
+	for i, q := range questions {
+		raw[i] = q
+	}
+	return map[string]any{"questions": raw}
+}
+
+func question(text, header string) map[string]any {
+	return map[string]any{
+		"question":    text,
+		"header":      header,
+		"multiSelect": false,
+		"options": []any{
+			map[string]any{"label": "A", "description": "Option A"},
+			map[string]any{"label": "B", "description": "Option B"},
+		},
+	}
+}
+
+func TestAskUser_AnswersInQuestionOrder(t *testing.T) {
+	tool := &AskUserQuestionTool{
+		Handler: &mockHandler{answers: map[string]string{"Second?": "B", "First?": "A"}},
+	}
+	out, _ := tool.Execute(context.Background(), askInput(question("First?", "One"), question("Second?", "Two")))
+	want := "User answers:\n- One: A\n- Two: B"
+	if out.Content != want {
+		t.Errorf("content = %q, want %q", out.Content, want)
+	}
+}
+
+func TestAskUser_ContextHandler(t *testing.T) {
+	tool := &AskUserQuestionTool{}
+	ctx := WithUserInputHandler(context.Background(), &mockHandler{answers: map[string]string{"Test?": "B"}})
+	out, _ := tool.Execute(ctx, askInput(question("Test?", "Test")))
+	if out.IsError || !strings.Contains(out.Content, "Test: B") {
+		t.Errorf("content = %q", out.Content)
+	}
+
+	// An explicit Handler takes precedence over the context.
+	tool.Handler = &mockHandler{answers: map[string]string{"Test?": "A"}}
+	out, _ = tool.Execute(ctx, askInput(question("Test?", "Test")))
+	if !strings.Contains(out.Content, "Test: A") {
+		t.Errorf("content = %q, want the tool's own handler", out.Content)
+	}
+}
+
+func TestAskUser_HeadlessDefaults(t *testing.T) {
+	tool := &AskUserQuestionTool{Defaults: map[string]string{"First?": "A", "Two": "B"}}
+	out, _ := tool.Execute(context.Background(), askInput(question("First?", "One"), question("Second?", "Two")))
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if !strings.Contains(out.Content, "default answers") || !strings.Contains(out.Content, "One: A") || !strings.Contains(out.Content, "Two: B") {
+		t.Errorf("content = %q", out.Content)
+	}
+
+	out, _ = tool.Execute(context.Background(), askInput(question("Third?", "Three")))
+	if !out.IsError || !strings.Contains(out.Content, `no default answer for "Third?"`) {
+		t.Errorf("content = %q, want missing-default error", out.Content)
+	}
+}
@@ -2,22 +2,37 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	bashDefaultTimeout = 120 * time.Second
 	bashMaxTimeout     = 600 * time.Second
-	bashMaxOutput      = 30000 // characters
+	bashMaxOutput      = 30000 // bytes
 )
 
 // BashTool executes shell commands.
 type BashTool struct {
 	CWD         string       // working directory for command execution
-	TaskManager *TaskManager // optional, for run_in_background support
+	TaskManager *TaskManager // optional, for run_in_background support and full output of truncated commands
+
+	// MaxOutputBytes caps foreground output; longer output keeps its head and
+	// tail around a truncation marker (0 = default 30000).
+	MaxOutputBytes int
+
+	// Persistent keeps one long-lived shell for foreground commands so that
+	// cd, exported variables and functions carry over between calls.
+	// Background commands always run in their own shell. List the tool in
+	// AgentConfig.Background, or call Close, to kill the shell at exit.
+	Persistent bool
+
+	shellMu sync.Mutex
+	shell   *shellSession
 }
 
 func (b *BashTool) Name() string { return "Bash" }
@@ -86,6 +101,10 @@ func (b *BashTool) InputSchema() map[string]any {
 				"type":        "boolean",
 				"description": "Set to true to run this command in the background",
 			},
+			"reset_shell": map[string]any{
+				"type":        "boolean",
+				"description": "Restart the persistent shell before running the command (clears cd and exported variables)",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -94,8 +113,16 @@ func (b *BashTool) InputSchema() map[string]any {
 func (b *BashTool) SideEffect() SideEffectType { return SideEffectMutating }
 
 func (b *BashTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
-	command, ok := input["command"].(string)
-	if !ok || command == "" {
+	command, _ := input["command"].(string)
+
+	if reset, ok := input["reset_shell"].(bool); ok && reset {
+		b.ResetShell()
+		if command == "" {
+			return ToolOutput{Content: "Shell reset."}, nil
+		}
+	}
+
+	if command == "" {
 		return ToolOutput{Content: "Error: command is required", IsError: true}, nil
 	}
 
@@ -123,7 +150,7 @@ func (b *BashTool) executeBackground(ctx context.Context, command string, input
 		}
 	}
 
-	b.TaskManager.Launch(ctx, taskID, func(taskCtx context.Context) (string, error) {
+	b.TaskManager.LaunchCommand(ctx, taskID, command, func(taskCtx context.Context) (string, error) {
 		taskCtx, cancel := context.WithTimeout(taskCtx, timeout)
 		defer cancel()
 
@@ -131,15 +158,15 @@ func (b *BashTool) executeBackground(ctx context.Context, command string, input
 		if cwd != "" {
 			cmd.Dir = cwd
 		}
+		// Stopping the task kills everything the command started
+		setProcessGroup(cmd)
 
-		output, err := cmd.CombinedOutput()
-		result := string(output)
-
-		if len(result) > bashMaxOutput {
-			result = result[:bashMaxOutput] + fmt.Sprintf(
-				"\n... (truncated, %d total characters. Consider using head/tail or piping to limit output)",
-				len(string(output)))
-		}
+		// Output is kept, up to maxTaskOutputBytes of it, so TaskOutput can return it
+		output := &taskOutput{}
+		cmd.Stdout = output.writer()
+		cmd.Stderr = cmd.Stdout
+		err := cmd.Run()
+		result := output.String()
 
 		if err != nil {
 			if taskCtx.Err() == context.DeadlineExceeded {
@@ -168,34 +195,130 @@ func (b *BashTool) executeForeground(ctx context.Context, command string, input
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	if b.Persistent {
+		return b.executePersistent(ctx, command, timeout)
+	}
+
 	cmd := exec.CommandContext(ctx, "bash", "-c", command)
 	if b.CWD != "" {
 		cmd.Dir = b.CWD
 	}
+	// Without this an interrupt kills bash but not its children, and
+	// CombinedOutput waits for them to close the output pipe
+	setProcessGroup(cmd)
 
 	output, err := cmd.CombinedOutput()
-	result := string(output)
-
-	// Truncate large output
-	if len(result) > bashMaxOutput {
-		result = result[:bashMaxOutput] + fmt.Sprintf(
-			"\n... (truncated, %d total characters. Consider using head/tail, piping to limit output, or running in background with run_in_background parameter)",
-			len(string(output)))
-	}
+	result, meta := b.truncateOutput(string(output))
 
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return ToolOutput{
-				Content: fmt.Sprintf("Error: command timed out after %s\n%s", timeout, result),
-				IsError: true,
+				Content:  fmt.Sprintf("Error: command timed out after %s\n%s", timeout, result),
+				IsError:  true,
+				Metadata: meta,
 			}, nil
 		}
 		// Non-zero exit code — include output with the error
 		return ToolOutput{
-			Content: strings.TrimRight(result, "\n"),
-			IsError: true,
+			Content:  strings.TrimRight(result, "\n"),
+			IsError:  true,
+			Metadata: meta,
 		}, nil
 	}
 
-	return ToolOutput{Content: strings.TrimRight(result, "\n")}, nil
+	return ToolOutput{Content: strings.TrimRight(result, "\n"), Metadata: meta}, nil
+}
+
+// executePersistent runs command in the long-lived shell, starting it if needed.
+// On timeout or cancellation the shell is killed and restarted on the next call.
+func (b *BashTool) executePersistent(ctx context.Context, command string, timeout time.Duration) (ToolOutput, error) {
+	b.shellMu.Lock()
+	defer b.shellMu.Unlock()
+
+	if b.shell == nil {
+		shell, err := startShellSession(b.CWD)
+		if err != nil {
+			return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
+		}
+		b.shell = shell
+	}
+
+	output, exitCode, err := b.shell.run(ctx, command)
+	result, meta := b.truncateOutput(output)
+
+	if err != nil {
+		b.shell.close()
+		b.shell = nil
+
+		switch {
+		case errors.Is(err, errShellExited):
+			return ToolOutput{
+				Content:  strings.TrimRight(result+"\n(shell exited; a new shell will be started for the next command)", "\n"),
+				IsError:  true,
+				Metadata: meta,
+			}, nil
+		case ctx.Err() == context.DeadlineExceeded:
+			return ToolOutput{
+				Content:  fmt.Sprintf("Error: command timed out after %s (shell restarted)\n%s", timeout, result),
+				IsError:  true,
+				Metadata: meta,
+			}, nil
+		default:
+			return ToolOutput{
+				Content:  fmt.Sprintf("Error: %s (shell restarted)\n%s", err, result),
+				IsError:  true,
+				Metadata: meta,
+			}, nil
+		}
+	}
+
+	return ToolOutput{Content: strings.TrimRight(result, "\n"), IsError: exitCode != 0, Metadata: meta}, nil
+}
+
+// ResetShell kills the persistent shell, if any. The next foreground command starts a fresh one.
+func (b *BashTool) ResetShell() {
+	b.shellMu.Lock()
+	defer b.shellMu.Unlock()
+	if b.shell != nil {
+		b.shell.close()
+		b.shell = nil
+	}
+}
+
+// Close kills the persistent shell and its process group, waiting for a
+// running foreground command to return first. The tool stays usable; the
+// next foreground command starts a fresh shell.
+func (b *BashTool) Close() error {
+	b.ResetShell()
+	return nil
+}
+
+// StopAll implements agent.BackgroundWork so Query.Shutdown kills the
+// persistent shell along with the other session work.
+func (b *BashTool) StopAll(context.Context) error {
+	return b.Close()
+}
+
+// truncateOutput applies head+tail truncation to foreground output. When
+// output is cut and a TaskManager is configured, the full output is stored
+// there so the model can fetch it with TaskOutput.
+func (b *BashTool) truncateOutput(output string) (string, *OutputMetadata) {
+	maxBytes := bashMaxOutput
+	if b.MaxOutputBytes > 0 {
+		maxBytes = b.MaxOutputBytes
+	}
+	result, truncated := TruncateHeadTail(output, maxBytes)
+	if !truncated {
+		return result, nil
+	}
+
+	meta := &OutputMetadata{Truncated: true, OriginalBytes: len(output)}
+	hint := "Consider using head/tail, piping to limit output, or running in background with run_in_background parameter"
+	if b.TaskManager != nil {
+		meta.FullOutputID = generateID()
+		b.TaskManager.StoreCompleted(meta.FullOutputID, output)
+		hint = fmt.Sprintf("Full output available via TaskOutput with task_id=%q. %s", meta.FullOutputID, hint)
+	}
+	result = strings.TrimRight(result, "\n") + fmt.Sprintf("\n... (truncated, %d total bytes. %s)", len(output), hint)
+	return result, meta
 }
@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// errShellExited is returned when the persistent shell dies mid-command
+// (e.g. the command ran `exit`). The session must be restarted.
+var errShellExited = errors.New("shell exited")
+
+// shellSession is a long-lived bash process that runs commands fed over stdin.
+// Each command's output is delimited by a unique sentinel line carrying its
+// exit status, so cd, exported variables and shell functions persist across calls.
+type shellSession struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output *os.File    // read end of the combined stdout/stderr pipe
+	lines  chan string // output lines, closed when the shell's output reaches EOF
+	done   chan struct{}
+}
+
+// startShellSession launches bash in cwd with stdout and stderr merged.
+func startShellSession(cwd string) (*shellSession, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("create shell pipe: %w", err)
+	}
+
+	// The shell outlives any one call; it runs in its own process group so
+	// close also kills jobs its commands left behind.
+	cmd := exec.CommandContext(context.Background(), "bash", "--noprofile", "--norc")
+	setProcessGroup(cmd)
+	if cwd != "" {
+		cmd.Dir = cwd
+	}
+	cmd.Stdout = w
+	cmd.Stderr = w
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		r.Close()
+		w.Close()
+		return nil, fmt.Errorf("create shell stdin: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		r.Close()
+		w.Close()
+		return nil, fmt.Errorf("start shell: %w", err)
+	}
+	w.Close() // the child holds its own copy
+
+	s := &shellSession{
+		cmd:    cmd,
+		stdin:  stdin,
+		output: r,
+		lines:  make(chan string, 256),
+		done:   make(chan struct{}),
+	}
+	go s.readLoop()
+	return s, nil
+}
+
+func (s *shellSession) readLoop() {
+	defer close(s.lines)
+	reader := bufio.NewReader(s.output)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			select {
+			case s.lines <- line:
+			case <-s.done:
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// run executes command in the session and returns its combined output and
+// exit code. If ctx is done first, ctx.Err() is returned and the session is
+// left mid-command; the caller must close it.
+func (s *shellSession) run(ctx context.Context, command string) (string, int, error) {
+	// Discard stray output (e.g. from jobs backgrounded with &) left between commands
+	for drained := false; !drained; {
+		select {
+		case _, ok := <-s.lines:
+			if !ok {
+				return "", -1, errShellExited
+			}
+		default:
+			drained = true
+		}
+	}
+
+	sentinel := "__GOAT_CMD_DONE_" + generateID() + "__"
+	// The command runs in a group with stdin detached so it can't consume
+	// the script stream; the leading \n guarantees the sentinel starts a line.
+	script := fmt.Sprintf("{\n%s\n} < /dev/null\nprintf '\\n%s:%%d\\n' \"$?\"\n", command, sentinel)
+	if _, err := io.WriteString(s.stdin, script); err != nil {
+		return "", -1, errShellExited
+	}
+
+	var b strings.Builder
+	for {
+		select {
+		case line, ok := <-s.lines:
+			if !ok {
+				return b.String(), -1, errShellExited
+			}
+			if rest, found := strings.CutPrefix(line, sentinel+":"); found {
+				code, _ := strconv.Atoi(strings.TrimSpace(rest))
+				return strings.TrimSuffix(b.String(), "\n"), code, nil
+			}
+			b.WriteString(line)
+		case <-ctx.Done():
+			return b.String(), -1, ctx.Err()
+		}
+	}
+}
+
+// close kills the shell's process group and releases its pipes.
+func (s *shellSession) close() {
+	close(s.done)
+	if s.cmd.Process != nil {
+		_ = killProcessGroup(s.cmd)
+	}
+	s.stdin.Close()
+	s.output.Close()
+	go s.cmd.Wait() // reap without blocking on lingering children
+}
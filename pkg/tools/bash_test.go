@@ -85,6 +85,30 @@ func TestBash_ContextCancel(t *testing.T) {
 	}
 }
 
+func TestBash_ContextCancelKillsChildren(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	// The pipeline's processes outlive bash unless the whole group is killed
+	start := time.Now()
+	tool := &BashTool{}
+	out, err := tool.Execute(ctx, map[string]any{
+		"command": "sleep 10 | cat",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.IsError {
+		t.Error("expected error on context cancel")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("cancelled command returned after %s", elapsed)
+	}
+}
+
 func TestBash_MissingCommand(t *testing.T) {
 	tool := &BashTool{}
 	out, err := tool.Execute(context.Background(), map[string]any{})
@@ -203,3 +227,174 @@ func TestBash_TruncationProgressiveDisclosure(t *testing.T) {
 		t.Error("truncation message should mention run_in_background")
 	}
 }
+
+func TestBash_PersistentShellKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	tool := &BashTool{CWD: "/", Persistent: true}
+	defer tool.ResetShell()
+
+	steps := []struct {
+		command string
+		want    string
+	}{
+		{"cd " + dir, ""},
+		{"export GOAT_TEST_VAR=kept", ""},
+		{"pwd", dir},
+		{"echo $GOAT_TEST_VAR", "kept"},
+	}
+	for _, s := range steps {
+		out, err := tool.Execute(context.Background(), map[string]any{"command": s.command})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.IsError {
+			t.Fatalf("%q: unexpected error: %s", s.command, out.Content)
+		}
+		if !strings.HasSuffix(out.Content, s.want) {
+			t.Errorf("%q: got %q, want %q", s.command, out.Content, s.want)
+		}
+	}
+}
+
+func TestBash_PersistentExitCodeAndStderr(t *testing.T) {
+	tool := &BashTool{Persistent: true}
+	defer tool.ResetShell()
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"command": "echo oops >&2; false"})
+	if !out.IsError {
+		t.Error("expected IsError for non-zero exit")
+	}
+	if out.Content != "oops" {
+		t.Errorf("got %q, want %q", out.Content, "oops")
+	}
+
+	// Commands must not be able to read the control stream
+	out, _ = tool.Execute(context.Background(), map[string]any{"command": "cat; echo after"})
+	if out.IsError || out.Content != "after" {
+		t.Errorf("stdin should be detached, got %q (error=%v)", out.Content, out.IsError)
+	}
+}
+
+func TestBash_PersistentTimeoutRestartsShell(t *testing.T) {
+	tool := &BashTool{Persistent: true}
+	defer tool.ResetShell()
+
+	tool.Execute(context.Background(), map[string]any{"command": "export MARK=1"})
+	out, _ := tool.Execute(context.Background(), map[string]any{"command": "sleep 10", "timeout": float64(100)})
+	if !out.IsError || !strings.Contains(out.Content, "timed out") {
+		t.Fatalf("expected timeout, got %q", out.Content)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"command": "echo ${MARK:-fresh}"})
+	if out.Content != "fresh" {
+		t.Errorf("expected a fresh shell after timeout, got %q", out.Content)
+	}
+}
+
+func TestBash_PersistentExitAndReset(t *testing.T) {
+	tool := &BashTool{Persistent: true}
+	defer tool.ResetShell()
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"command": "exit 3"})
+	if !out.IsError || !strings.Contains(out.Content, "shell exited") {
+		t.Errorf("expected shell exit error, got %q", out.Content)
+	}
+	out, _ = tool.Execute(context.Background(), map[string]any{"command": "echo back"})
+	if out.Content != "back" {
+		t.Errorf("expected shell to restart, got %q", out.Content)
+	}
+
+	tool.Execute(context.Background(), map[string]any{"command": "export MARK=1"})
+	out, _ = tool.Execute(context.Background(), map[string]any{"reset_shell": true})
+	if out.IsError || out.Content != "Shell reset." {
+		t.Errorf("unexpected reset output %q", out.Content)
+	}
+	out, _ = tool.Execute(context.Background(), map[string]any{"command": "echo ${MARK:-fresh}", "reset_shell": true})
+	if out.Content != "fresh" {
+		t.Errorf("expected fresh shell after reset, got %q", out.Content)
+	}
+}
+
+func TestBash_PersistentResetKillsJobs(t *testing.T) {
+	tool := &BashTool{Persistent: true}
+	defer tool.ResetShell()
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"command": "sleep 300 >/dev/null 2>&1 & echo $!"})
+	pid := strings.TrimSpace(out.Content)
+	if out.IsError || pid == "" {
+		t.Fatalf("start job: %q", out.Content)
+	}
+	tool.ResetShell()
+	time.Sleep(50 * time.Millisecond) // let the kill land
+	if processAlive(pid) {
+		t.Errorf("job %s left by the shell is still running", pid)
+	}
+}
+
+func TestBash_StopAllKillsPersistentShell(t *testing.T) {
+	tool := &BashTool{Persistent: true}
+	defer tool.Close()
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"command": "export MARK=1; echo $$"})
+	pid := strings.TrimSpace(out.Content)
+	if out.IsError || pid == "" {
+		t.Fatalf("start shell: %q", out.Content)
+	}
+	if err := tool.StopAll(context.Background()); err != nil {
+		t.Fatalf("StopAll: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // let the kill land
+	if processAlive(pid) {
+		t.Errorf("shell %s is still running after StopAll", pid)
+	}
+	out, _ = tool.Execute(context.Background(), map[string]any{"command": "echo ${MARK:-fresh}"})
+	if out.Content != "fresh" {
+		t.Errorf("expected a fresh shell after StopAll, got %q", out.Content)
+	}
+}
+
+func TestBash_PersistentWithBackground(t *testing.T) {
+	tm := NewTaskManager()
+	tool := &BashTool{Persistent: true, TaskManager: tm}
+	defer tool.ResetShell()
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"command": "echo bg", "run_in_background": true})
+	if out.IsError || !strings.Contains(out.Content, "Task started") {
+		t.Fatalf("expected background task, got %q", out.Content)
+	}
+	out, _ = tool.Execute(context.Background(), map[string]any{"command": "echo fg"})
+	if out.Content != "fg" {
+		t.Errorf("foreground shell output = %q, want fg", out.Content)
+	}
+}
+
+func TestBash_MaxOutputBytesHeadTail(t *testing.T) {
+	tm := NewTaskManager()
+	tool := &BashTool{MaxOutputBytes: 200, TaskManager: tm}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"command": "for i in $(seq 1 500); do echo line$i; done",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.Content, "line1\n") || !strings.Contains(out.Content, "line500") {
+		t.Errorf("expected head and tail, got %q", out.Content)
+	}
+	if !strings.Contains(out.Content, "bytes truncated ...]") {
+		t.Errorf("expected truncation marker, got %q", out.Content)
+	}
+	if out.Metadata == nil || !out.Metadata.Truncated || out.Metadata.FullOutputID == "" {
+		t.Fatalf("expected truncation metadata, got %+v", out.Metadata)
+	}
+
+	full, err := tm.GetOutput(out.Metadata.FullOutputID, true, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(full) != out.Metadata.OriginalBytes {
+		t.Errorf("stored %d bytes, metadata says %d", len(full), out.Metadata.OriginalBytes)
+	}
+	if !strings.Contains(full, "line250\n") {
+		t.Error("full output should contain the truncated middle")
+	}
+}
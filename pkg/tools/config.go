@@ -2,10 +2,15 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 )
 
+// ErrUnknownSetting is returned by a ConfigStore's Set for a key it does not
+// manage, letting the Config tool fall back to its own Store.
+var ErrUnknownSetting = errors.New("unknown setting")
+
 // ConfigStore provides runtime configuration get/set.
 type ConfigStore interface {
 	Get(key string) (any, bool)
@@ -37,7 +42,44 @@ func (s *InMemoryConfigStore) Set(key string, value any) error {
 	return nil
 }
 
+type configStoreKey struct{}
+
+// WithConfigStore returns a context whose Config calls use s when the tool
+// has no Store of its own. The agent loop uses it to expose the session's
+// settings (model, permission mode, thinking budget).
+func WithConfigStore(ctx context.Context, s ConfigStore) context.Context {
+	return context.WithValue(ctx, configStoreKey{}, s)
+}
+
+func configStoreFrom(ctx context.Context) ConfigStore {
+	s, _ := ctx.Value(configStoreKey{}).(ConfigStore)
+	return s
+}
+
+// layeredConfigStore consults primary first and falls back to fallback for
+// keys primary does not know.
+type layeredConfigStore struct {
+	primary, fallback ConfigStore
+}
+
+func (s layeredConfigStore) Get(key string) (any, bool) {
+	if v, ok := s.primary.Get(key); ok {
+		return v, true
+	}
+	return s.fallback.Get(key)
+}
+
+func (s layeredConfigStore) Set(key string, value any) error {
+	err := s.primary.Set(key, value)
+	if errors.Is(err, ErrUnknownSetting) {
+		return s.fallback.Set(key, value)
+	}
+	return err
+}
+
 // ConfigTool provides runtime configuration get/set.
+// A store attached to the call's context takes precedence; Store holds the
+// settings it does not manage, or all of them when there is none.
 type ConfigTool struct {
 	Store ConfigStore
 }
@@ -51,7 +93,8 @@ Usage:
 - To read a setting, provide only the "setting" parameter
 - To write a setting, provide both "setting" and "value" parameters
 - Settings are scoped to the current session and do not persist across restarts
-- Use this tool to adjust agent behavior at runtime (e.g., toggle verbose mode, change output preferences)`
+- In agent sessions the settings are "model", "permission_mode" and "max_thinking_tokens"
+- Use this tool to adjust agent behavior at runtime (e.g., switch to a cheaper model for a bulk task)`
 }
 
 func (c *ConfigTool) InputSchema() map[string]any {
@@ -70,10 +113,19 @@ func (c *ConfigTool) InputSchema() map[string]any {
 	}
 }
 
-func (c *ConfigTool) SideEffect() SideEffectType { return SideEffectNone }
+// SideEffect reports SideEffectMutating: a write changes the session's
+// settings, so Config calls are never run alongside other tools.
+func (c *ConfigTool) SideEffect() SideEffectType { return SideEffectMutating }
 
-func (c *ConfigTool) Execute(_ context.Context, input map[string]any) (ToolOutput, error) {
-	if c.Store == nil {
+func (c *ConfigTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
+	store := c.Store
+	if session := configStoreFrom(ctx); session != nil {
+		store = session
+		if c.Store != nil {
+			store = layeredConfigStore{primary: session, fallback: c.Store}
+		}
+	}
+	if store == nil {
 		return ToolOutput{Content: "Error: config store not configured", IsError: true}, nil
 	}
 
@@ -84,7 +136,7 @@ func (c *ConfigTool) Execute(_ context.Context, input map[string]any) (ToolOutpu
 
 	// If value is present, set it
 	if value, hasValue := input["value"]; hasValue {
-		if err := c.Store.Set(setting, value); err != nil {
+		if err := store.Set(setting, value); err != nil {
 			return ToolOutput{
 				Content: fmt.Sprintf("Error setting %s: %s", setting, err),
 				IsError: true,
@@ -94,7 +146,7 @@ func (c *ConfigTool) Execute(_ context.Context, input map[string]any) (ToolOutpu
 	}
 
 	// Otherwise, get it
-	value, exists := c.Store.Get(setting)
+	value, exists := store.Get(setting)
 	if !exists {
 		return ToolOutput{
 			Content: fmt.Sprintf("Error: setting %q not found", setting),
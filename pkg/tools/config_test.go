@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -113,3 +114,52 @@ func TestConfig_NilStore(t *testing.T) {
 		t.Error("expected error for nil store")
 	}
 }
+
+func TestConfig_ContextStore(t *testing.T) {
+	store := NewInMemoryConfigStore()
+	store.Set("model", "small")
+	ctx := WithConfigStore(context.Background(), store)
+
+	out, err := (&ConfigTool{}).Execute(ctx, map[string]any{"setting": "model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.IsError || out.Content != "model = small" {
+		t.Errorf("got %q, want the context store's value", out.Content)
+	}
+
+	// The context store takes precedence over the tool's own Store.
+	out, _ = (&ConfigTool{Store: NewInMemoryConfigStore()}).Execute(ctx, map[string]any{"setting": "model"})
+	if out.IsError || out.Content != "model = small" {
+		t.Errorf("got %q, want the context store's value", out.Content)
+	}
+}
+
+// sessionOnlyStore accepts only the "model" key, like the agent's session settings.
+type sessionOnlyStore struct{ InMemoryConfigStore }
+
+func (s *sessionOnlyStore) Set(key string, value any) error {
+	if key != "model" {
+		return fmt.Errorf("%w: %q", ErrUnknownSetting, key)
+	}
+	return s.InMemoryConfigStore.Set(key, value)
+}
+
+func TestConfig_ContextStoreFallsBackToOwnStore(t *testing.T) {
+	session := &sessionOnlyStore{InMemoryConfigStore{data: map[string]any{}}}
+	own := NewInMemoryConfigStore()
+	tool := &ConfigTool{Store: own}
+	ctx := WithConfigStore(context.Background(), session)
+
+	tool.Execute(ctx, map[string]any{"setting": "model", "value": "small"})
+	tool.Execute(ctx, map[string]any{"setting": "theme", "value": "dark"})
+	if v, _ := session.Get("model"); v != "small" {
+		t.Errorf("session model = %v, want small", v)
+	}
+	if v, _ := own.Get("theme"); v != "dark" {
+		t.Errorf("own theme = %v, want dark", v)
+	}
+	if out, _ := tool.Execute(ctx, map[string]any{"setting": "theme"}); out.Content != "theme = dark" {
+		t.Errorf("got %q, want the fallback store's value", out.Content)
+	}
+}
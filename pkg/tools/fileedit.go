@@ -72,6 +72,10 @@ func (f *FileEditTool) Execute(_ context.Context, input map[string]any) (ToolOut
 		return ToolOutput{Content: "Error: new_string is required", IsError: true}, nil
 	}
 
+	if oldString == "" {
+		return ToolOutput{Content: "Error: old_string must not be empty", IsError: true}, nil
+	}
+
 	if oldString == newString {
 		return ToolOutput{Content: "Error: old_string and new_string must be different", IsError: true}, nil
 	}
@@ -90,14 +94,20 @@ func (f *FileEditTool) Execute(_ context.Context, input map[string]any) (ToolOut
 	count := strings.Count(content, oldString)
 	if count == 0 {
 		return ToolOutput{
-			Content: "Error: old_string not found in file",
+			Content: notFoundMessage(content, oldString),
 			IsError: true,
 		}, nil
 	}
 
 	if !replaceAll && count > 1 {
+		lines := make([]string, 0, count)
+		for _, line := range occurrenceLines(content, oldString) {
+			lines = append(lines, fmt.Sprint(line))
+		}
 		return ToolOutput{
-			Content: fmt.Sprintf("Error: old_string found %d times in file. Use replace_all or provide a more unique string.", count),
+			Content: fmt.Sprintf("Error: old_string found %d times in file (at lines %s). "+
+				"Include more surrounding context to make it unique, or set replace_all to replace every occurrence.",
+				count, strings.Join(lines, ", ")),
 			IsError: true,
 		}, nil
 	}
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// fuzzyMinSimilarity is the lowest similarity reported as a near match.
+	fuzzyMinSimilarity = 0.5
+	// fuzzyMaxCandidates is how many near matches a not-found error lists.
+	fuzzyMaxCandidates = 3
+	// fuzzyMaxShownLines caps the text quoted from the closest match.
+	fuzzyMaxShownLines = 20
+)
+
+// editCandidate is a run of file lines resembling old_string.
+type editCandidate struct {
+	start, end int     // 1-based, inclusive
+	similarity float64 // 0..1
+}
+
+// occurrenceLines returns the 1-based line number where each occurrence of s
+// starts in content.
+func occurrenceLines(content, s string) []int {
+	var lines []int
+	offset := 0
+	for {
+		i := strings.Index(content[offset:], s)
+		if i < 0 {
+			return lines
+		}
+		offset += i
+		lines = append(lines, strings.Count(content[:offset], "\n")+1)
+		offset += len(s)
+	}
+}
+
+// closestMatches slides a window of old's line count over content and returns
+// the most similar distinct windows, best first. Lines are compared with
+// surrounding whitespace trimmed, so indentation mistakes score highly.
+func closestMatches(content, old string) []editCandidate {
+	fileLines := strings.Split(content, "\n")
+	oldLines := strings.Split(strings.Trim(old, "\n"), "\n")
+	n := len(oldLines)
+	if n == 0 || n > len(fileLines) {
+		return nil
+	}
+
+	oldBigrams := make([]map[string]int, n)
+	for i, line := range oldLines {
+		oldBigrams[i] = bigrams(strings.TrimSpace(line))
+	}
+	fileBigrams := make([]map[string]int, len(fileLines))
+	for i, line := range fileLines {
+		fileBigrams[i] = bigrams(strings.TrimSpace(line))
+	}
+
+	var candidates []editCandidate
+	for start := 0; start+n <= len(fileLines); start++ {
+		total := 0.0
+		for i := range n {
+			total += diceSimilarity(oldBigrams[i], fileBigrams[start+i])
+		}
+		if sim := total / float64(n); sim >= fuzzyMinSimilarity {
+			candidates = append(candidates, editCandidate{start: start + 1, end: start + n, similarity: sim})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b editCandidate) int {
+		return cmp.Compare(b.similarity, a.similarity)
+	})
+
+	// Keep the best window of each cluster of overlapping windows.
+	var best []editCandidate
+	for _, c := range candidates {
+		overlaps := slices.ContainsFunc(best, func(b editCandidate) bool {
+			return c.start <= b.end && b.start <= c.end
+		})
+		if !overlaps {
+			best = append(best, c)
+			if len(best) == fuzzyMaxCandidates {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// notFoundMessage explains a missing old_string, pointing at near matches.
+func notFoundMessage(content, old string) string {
+	candidates := closestMatches(content, old)
+	if len(candidates) == 0 {
+		return "Error: old_string not found in file. Read the file again and copy the exact text to replace."
+	}
+
+	var b strings.Builder
+	b.WriteString("Error: old_string not found in file. Closest matches (the file may have changed, or whitespace differs):\n")
+	for _, c := range candidates {
+		fmt.Fprintf(&b, "- %s (%.0f%% similar)\n", lineRange(c.start, c.end), 100*c.similarity)
+	}
+
+	c := candidates[0]
+	fileLines := strings.Split(content, "\n")
+	shown := fileLines[c.start-1 : min(c.end, c.start-1+fuzzyMaxShownLines)]
+	fmt.Fprintf(&b, "\nText at %s:\n%s", lineRange(c.start, c.end), strings.Join(shown, "\n"))
+	if c.end-c.start+1 > len(shown) {
+		b.WriteString("\n...")
+	}
+	return b.String()
+}
+
+func lineRange(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("line %d", start)
+	}
+	return fmt.Sprintf("lines %d-%d", start, end)
+}
+
+// bigrams counts the character pairs in s. A one-character string counts as
+// its own bigram so lines like "}" still compare.
+func bigrams(s string) map[string]int {
+	runes := []rune(s)
+	counts := make(map[string]int, len(runes))
+	if len(runes) == 1 {
+		counts[s] = 1
+	}
+	for i := 0; i+1 < len(runes); i++ {
+		counts[string(runes[i:i+2])]++
+	}
+	return counts
+}
+
+// diceSimilarity is the Sørensen–Dice coefficient of two bigram multisets.
+// Two blank lines are identical.
+func diceSimilarity(a, b map[string]int) float64 {
+	na, nb := 0, 0
+	for _, c := range a {
+		na += c
+	}
+	for _, c := range b {
+		nb += c
+	}
+	if na == 0 && nb == 0 {
+		return 1
+	}
+	shared := 0
+	for k, ca := range a {
+		shared += min(ca, b[k])
+	}
+	return 2 * float64(shared) / float64(na+nb)
+}
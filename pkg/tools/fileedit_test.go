@@ -125,3 +125,83 @@ func TestFileEdit_SameString(t *testing.T) {
 		t.Error("expected error when old_string == new_string")
 	}
 }
+
+func TestFileEdit_MultipleMatchReportsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.go")
+	os.WriteFile(path, []byte("x := 1\nreturn x\n\nreturn x\n"), 0o644)
+
+	out, _ := (&FileEditTool{}).Execute(context.Background(), map[string]any{
+		"file_path":  path,
+		"old_string": "return x",
+		"new_string": "return x + 1",
+	})
+	if !out.IsError || !strings.Contains(out.Content, "lines 2, 4") || !strings.Contains(out.Content, "surrounding context") {
+		t.Errorf("content = %q", out.Content)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "x := 1\nreturn x\n\nreturn x\n" {
+		t.Error("file should be unchanged")
+	}
+}
+
+func TestFileEdit_NotFoundSuggestsClosestMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n\nfunc main() {\n\tfmt.Println(\"hello\")\n\tos.Exit(0)\n}\n"), 0o644)
+
+	// Wrong indentation and a small typo
+	out, _ := (&FileEditTool{}).Execute(context.Background(), map[string]any{
+		"file_path":  path,
+		"old_string": "    fmt.Println(\"helo\")\n    os.Exit(0)",
+		"new_string": "\tfmt.Println(\"bye\")",
+	})
+	if !out.IsError {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(out.Content, "not found") || !strings.Contains(out.Content, "lines 4-5") {
+		t.Errorf("content = %q", out.Content)
+	}
+	if !strings.Contains(out.Content, "Text at lines 4-5:\n\tfmt.Println(\"hello\")\n\tos.Exit(0)") {
+		t.Errorf("closest text not quoted: %q", out.Content)
+	}
+}
+
+func TestFileEdit_NotFoundWithoutNearMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("alpha\nbeta\n"), 0o644)
+
+	out, _ := (&FileEditTool{}).Execute(context.Background(), map[string]any{
+		"file_path":  path,
+		"old_string": "zzzz qqqq",
+		"new_string": "x",
+	})
+	if !out.IsError || strings.Contains(out.Content, "Closest") {
+		t.Errorf("content = %q", out.Content)
+	}
+}
+
+func TestFileEdit_EmptyOldString(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(path, []byte("alpha\n"), 0o644)
+
+	out, _ := (&FileEditTool{}).Execute(context.Background(), map[string]any{
+		"file_path":  path,
+		"old_string": "",
+		"new_string": "x",
+	})
+	if !out.IsError || !strings.Contains(out.Content, "must not be empty") {
+		t.Errorf("content = %q", out.Content)
+	}
+}
+
+func TestClosestMatches_DistinctRegions(t *testing.T) {
+	content := "func a() {\n\treturn 1\n}\n\nfunc b() {\n\treturn 2\n}\n"
+	got := closestMatches(content, "func c() {\n\treturn 3\n}")
+	if len(got) != 2 || got[0].start == got[1].start {
+		t.Fatalf("closestMatches = %+v, want the two functions", got)
+	}
+	for _, c := range got {
+		if c.start != 1 && c.start != 5 {
+			t.Errorf("unexpected candidate %+v", c)
+		}
+	}
+}
@@ -4,22 +4,39 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
+	"github.com/jg-phare/goat/pkg/llm"
 	gopdf "github.com/ledongthuc/pdf"
 )
 
 const (
-	fileReadDefaultLimit   = 2000 // default max lines to read
-	fileReadMaxLineLength  = 2000 // truncate lines longer than this
-	fileReadMaxPDFPages    = 20   // max pages per PDF read
+	fileReadDefaultLimit  = 2000    // default max lines to read
+	fileReadMaxLineLength = 2000    // truncate lines longer than this (in characters)
+	fileReadMaxPDFPages   = 20      // max pages per PDF read
+	fileReadMaxImageBytes = 5 << 20 // default max image size (the API's per-image limit)
+	fileReadCancelCheck   = 4096    // lines read between checks for cancellation
 )
 
+// fileReadImageTypes maps image extensions to their media types.
+var fileReadImageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
 // FileReadTool reads file contents with line numbers.
-type FileReadTool struct{}
+type FileReadTool struct {
+	MaxLines      int   // max lines returned when no limit is given (0 = default 2000)
+	MaxImageBytes int64 // largest image returned (0 = default 5 MB)
+	DisableImages bool  // text-only models: refuse images instead of returning them
+}
 
 func (f *FileReadTool) Name() string { return "Read" }
 
@@ -69,7 +86,7 @@ func (f *FileReadTool) InputSchema() map[string]any {
 
 func (f *FileReadTool) SideEffect() SideEffectType { return SideEffectNone }
 
-func (f *FileReadTool) Execute(_ context.Context, input map[string]any) (ToolOutput, error) {
+func (f *FileReadTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
 	filePath, ok := input["file_path"].(string)
 	if !ok || filePath == "" {
 		return ToolOutput{Content: "Error: file_path is required", IsError: true}, nil
@@ -80,8 +97,14 @@ func (f *FileReadTool) Execute(_ context.Context, input map[string]any) (ToolOut
 	}
 
 	// Handle PDF files
-	if strings.ToLower(filepath.Ext(filePath)) == ".pdf" {
-		return f.readPDF(filePath, input)
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if ext == ".pdf" {
+		return f.readPDF(ctx, filePath, input)
+	}
+
+	// Handle images
+	if mediaType, ok := fileReadImageTypes[ext]; ok {
+		return f.readImage(filePath, mediaType)
 	}
 
 	file, err := os.Open(filePath)
@@ -95,46 +118,126 @@ func (f *FileReadTool) Execute(_ context.Context, input map[string]any) (ToolOut
 		offset = int(o)
 	}
 
-	limit := fileReadDefaultLimit
+	maxLines := fileReadDefaultLimit
+	if f.MaxLines > 0 {
+		maxLines = f.MaxLines
+	}
+	limit := maxLines
+	limitGiven := false
 	if l, ok := input["limit"].(float64); ok && l > 0 {
 		limit = int(l)
+		limitGiven = true
 	}
 
+	// bufio.Reader rather than Scanner so lines of any length are handled
 	var lines []string
-	scanner := bufio.NewScanner(file)
+	reader := bufio.NewReader(file)
 	lineNum := 0
-	linesRead := 0
+	truncated := false
 
-	for scanner.Scan() {
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line == "" && readErr != nil {
+			if readErr != io.EOF {
+				return ToolOutput{Content: fmt.Sprintf("Error reading file: %s", readErr), IsError: true}, nil
+			}
+			break
+		}
 		lineNum++
-		if lineNum < offset {
-			continue
+		if lineNum%fileReadCancelCheck == 0 {
+			// A large offset can mean scanning most of a huge file
+			if err := ctx.Err(); err != nil {
+				return ToolOutput{}, err
+			}
 		}
-		if linesRead >= limit {
+		if lineNum >= offset {
+			if len(lines) >= limit {
+				truncated = true
+				break
+			}
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			lines = append(lines, fmt.Sprintf("%6d\t%s", lineNum, truncateLine(line, fileReadMaxLineLength)))
+		}
+		if readErr != nil {
 			break
 		}
+	}
 
-		line := scanner.Text()
-		if len(line) > fileReadMaxLineLength {
-			line = line[:fileReadMaxLineLength]
+	if len(lines) == 0 {
+		if lineNum == 0 {
+			return ToolOutput{Content: "(empty file)"}, nil
 		}
-		lines = append(lines, fmt.Sprintf("%6d\t%s", lineNum, line))
-		linesRead++
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: offset %d is past the end of the file (%d lines)", offset, lineNum),
+			IsError: true,
+		}, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return ToolOutput{Content: fmt.Sprintf("Error reading file: %s", err), IsError: true}, nil
+	content := strings.Join(lines, "\n")
+	if truncated && !limitGiven {
+		next := offset + len(lines)
+		content += fmt.Sprintf("\n\n[File truncated: showing lines %d-%d. Re-read with offset=%d to continue.]", offset, next-1, next)
 	}
+	return ToolOutput{Content: content}, nil
+}
 
-	if len(lines) == 0 {
+// truncateLine shortens line to at most maxChars characters without
+// splitting a multi-byte UTF-8 sequence.
+func truncateLine(line string, maxChars int) string {
+	if len(line) <= maxChars {
+		return line
+	}
+	count := 0
+	for i := range line {
+		if count == maxChars {
+			return line[:i]
+		}
+		count++
+	}
+	return line
+}
+
+// readImage returns an image file as a base64 image part the model can see.
+func (f *FileReadTool) readImage(filePath, mediaType string) (ToolOutput, error) {
+	if f.DisableImages {
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: %s is an image, and the current model cannot view images", filePath),
+			IsError: true,
+		}, nil
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
+	}
+	maxBytes := int64(fileReadMaxImageBytes)
+	if f.MaxImageBytes > 0 {
+		maxBytes = f.MaxImageBytes
+	}
+	if info.Size() > maxBytes {
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: image is %.1f MB (max %.1f MB). Resize or crop it before reading.",
+				float64(info.Size())/(1<<20), float64(maxBytes)/(1<<20)),
+			IsError: true,
+		}, nil
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
+	}
+	if len(data) == 0 {
 		return ToolOutput{Content: "(empty file)"}, nil
 	}
 
-	return ToolOutput{Content: strings.Join(lines, "\n")}, nil
+	return ToolOutput{
+		Content: fmt.Sprintf("Image: %s (%s, %d bytes)", filePath, mediaType, len(data)),
+		Images:  []llm.ContentPart{llm.ImageDataPart(mediaType, data)},
+	}, nil
 }
 
 // readPDF extracts text from a PDF file with optional page range.
-func (f *FileReadTool) readPDF(filePath string, input map[string]any) (ToolOutput, error) {
+func (f *FileReadTool) readPDF(ctx context.Context, filePath string, input map[string]any) (ToolOutput, error) {
 	pdfFile, reader, err := gopdf.Open(filePath)
 	if err != nil {
 		return ToolOutput{Content: fmt.Sprintf("Error opening PDF: %s", err), IsError: true}, nil
@@ -172,6 +275,9 @@ func (f *FileReadTool) readPDF(filePath string, input map[string]any) (ToolOutpu
 	var b strings.Builder
 	lineNum := 0
 	for p := startPage; p <= endPage; p++ {
+		if err := ctx.Err(); err != nil {
+			return ToolOutput{}, err
+		}
 		page := reader.Page(p)
 		if page.V.IsNull() {
 			continue
@@ -186,9 +292,7 @@ func (f *FileReadTool) readPDF(filePath string, input map[string]any) (ToolOutpu
 		// Number each line within the page
 		for _, line := range strings.Split(text, "\n") {
 			lineNum++
-			if len(line) > fileReadMaxLineLength {
-				line = line[:fileReadMaxLineLength]
-			}
+			line = truncateLine(line, fileReadMaxLineLength)
 			b.WriteString(fmt.Sprintf("%6d\t%s\n", lineNum, line))
 		}
 	}
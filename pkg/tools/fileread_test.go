@@ -2,11 +2,14 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestFileRead_FullFile(t *testing.T) {
@@ -113,6 +116,103 @@ func TestFileRead_EmptyFile(t *testing.T) {
 	}
 }
 
+func TestFileRead_MaxLinesTruncationNotice(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "long.txt")
+	var b strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&b, "line%d\n", i)
+	}
+	os.WriteFile(path, []byte(b.String()), 0o644)
+
+	tool := &FileReadTool{MaxLines: 4}
+	out, _ := tool.Execute(context.Background(), map[string]any{"file_path": path})
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if !strings.Contains(out.Content, "4\tline4") || strings.Contains(out.Content, "line5") {
+		t.Errorf("expected first 4 lines only, got %q", out.Content)
+	}
+	if !strings.Contains(out.Content, "offset=5") {
+		t.Errorf("expected truncation notice pointing at offset 5, got %q", out.Content)
+	}
+
+	// Explicit limit: no notice
+	out, _ = tool.Execute(context.Background(), map[string]any{"file_path": path, "offset": float64(5), "limit": float64(2)})
+	if strings.Contains(out.Content, "truncated") {
+		t.Errorf("unexpected truncation notice with explicit limit: %q", out.Content)
+	}
+	if !strings.Contains(out.Content, "5\tline5") || !strings.Contains(out.Content, "6\tline6") {
+		t.Errorf("expected lines 5-6, got %q", out.Content)
+	}
+
+	// Exactly MaxLines: no notice
+	tool.MaxLines = 10
+	out, _ = tool.Execute(context.Background(), map[string]any{"file_path": path})
+	if strings.Contains(out.Content, "truncated") {
+		t.Errorf("unexpected truncation notice when file fits: %q", out.Content)
+	}
+}
+
+func TestFileRead_OffsetPastEnd(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "short.txt")
+	os.WriteFile(path, []byte("a\nb\n"), 0o644)
+
+	tool := &FileReadTool{}
+	out, _ := tool.Execute(context.Background(), map[string]any{"file_path": path, "offset": float64(10)})
+	if !out.IsError || !strings.Contains(out.Content, "2 lines") {
+		t.Errorf("expected past-end error, got %q", out.Content)
+	}
+}
+
+func TestFileRead_LongAndMultiByteLines(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wide.txt")
+	// Longer than bufio.Scanner's 64KB default, made of 3-byte runes
+	wide := strings.Repeat("世", 30000)
+	os.WriteFile(path, []byte(wide+"\r\nnext\n"), 0o644)
+
+	tool := &FileReadTool{}
+	out, _ := tool.Execute(context.Background(), map[string]any{"file_path": path})
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	lines := strings.Split(out.Content, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+	first := strings.SplitN(lines[0], "\t", 2)[1]
+	if !utf8.ValidString(first) {
+		t.Error("truncated line is not valid UTF-8")
+	}
+	if n := utf8.RuneCountInString(first); n != fileReadMaxLineLength {
+		t.Errorf("truncated line has %d runes, want %d", n, fileReadMaxLineLength)
+	}
+	if !strings.HasSuffix(lines[1], "\tnext") {
+		t.Errorf("expected CRLF to be stripped, got %q", lines[1])
+	}
+}
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		in   string
+		max  int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "hé"},
+		{"日本語", 2, "日本"},
+		{"", 5, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateLine(tt.in, tt.max); got != tt.want {
+			t.Errorf("truncateLine(%q, %d) = %q, want %q", tt.in, tt.max, got, tt.want)
+		}
+	}
+}
+
 // --- PDF Tests ---
 
 func TestFileRead_PDF_InvalidFile(t *testing.T) {
@@ -222,3 +322,67 @@ func TestFileRead_PDF_PagesSchemaPresent(t *testing.T) {
 		t.Errorf("pages type = %v, want string", pages["type"])
 	}
 }
+
+func TestFileRead_Image(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "shot.PNG")
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	os.WriteFile(path, png, 0o644)
+
+	out, _ := (&FileReadTool{}).Execute(context.Background(), map[string]any{"file_path": path})
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	if len(out.Images) != 1 {
+		t.Fatalf("expected 1 image part, got %d", len(out.Images))
+	}
+	want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	if out.Images[0].Type != "image_url" || out.Images[0].ImageURL.URL != want {
+		t.Errorf("image part = %+v", out.Images[0])
+	}
+	if !strings.HasPrefix(out.Content, "Image: "+path) {
+		t.Errorf("content should describe the image, got %q", out.Content)
+	}
+}
+
+func TestFileRead_ImageTooLarge(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.jpg")
+	os.WriteFile(path, make([]byte, 2048), 0o644)
+
+	out, _ := (&FileReadTool{MaxImageBytes: 1024}).Execute(context.Background(), map[string]any{"file_path": path})
+	if !out.IsError || !strings.Contains(out.Content, "max") {
+		t.Errorf("expected size cap error, got %q", out.Content)
+	}
+	if len(out.Images) != 0 {
+		t.Error("oversized image should not be returned")
+	}
+}
+
+func TestFileRead_ImagesDisabled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.webp")
+	os.WriteFile(path, []byte("RIFF"), 0o644)
+
+	out, _ := (&FileReadTool{DisableImages: true}).Execute(context.Background(), map[string]any{"file_path": path})
+	if !out.IsError || !strings.Contains(out.Content, "cannot view images") || len(out.Images) != 0 {
+		t.Errorf("expected images-disabled error, got %+v", out)
+	}
+}
+
+func TestFileRead_ContextCancel(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.txt")
+	os.WriteFile(path, []byte(strings.Repeat("line\n", 10*fileReadCancelCheck)), 0o644)
+
+	// Seeking to an offset past the end scans the whole file
+	ctx := newCancelAfterChecks(2)
+	tool := &FileReadTool{}
+	out, err := tool.Execute(ctx, map[string]any{"file_path": path, "offset": float64(1 << 30)})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v (output %q), want context.Canceled", err, out.Content)
+	}
+	if ctx.checks != 2 {
+		t.Errorf("read checked for cancellation %d times after cancelling, want 0", ctx.checks-2)
+	}
+}
@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// gitignoreRule is a single parsed .gitignore pattern.
+type gitignoreRule struct {
+	base     string // directory containing the .gitignore
+	pattern  string // doublestar pattern, relative to base
+	negate   bool   // "!pattern" re-includes
+	dirOnly  bool   // "pattern/" matches directories only
+	anchored bool   // pattern contains a slash, so it is relative to base rather than any depth
+}
+
+func (r gitignoreRule) match(path string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	rel, err := filepath.Rel(r.base, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	pattern := r.pattern
+	if !r.anchored {
+		pattern = "**/" + pattern
+	}
+	ok, _ := doublestar.Match(pattern, rel)
+	return ok
+}
+
+// parseGitignore reads the .gitignore in dir. A missing file yields no rules.
+func parseGitignore(dir string) []gitignoreRule {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		// Trailing spaces are ignored unless escaped with a backslash
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+			line = line[:len(line)-1]
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := gitignoreRule{base: dir}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// gitignoreMatcher evaluates .gitignore files from the enclosing repository
+// root down to each path's directory. Later (deeper) rules take precedence.
+type gitignoreMatcher struct {
+	top   string // outermost directory whose .gitignore applies
+	rules map[string][]gitignoreRule
+}
+
+// newGitignoreMatcher creates a matcher for paths under root. Parent
+// .gitignore files apply up to the nearest directory containing .git;
+// outside a repository only root and its subdirectories are consulted.
+func newGitignoreMatcher(root string) *gitignoreMatcher {
+	root, _ = filepath.Abs(root)
+	top := root
+	for dir := root; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			top = dir
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return &gitignoreMatcher{top: top, rules: make(map[string][]gitignoreRule)}
+}
+
+// rulesFor returns every rule that applies to entries of dir, outermost first.
+func (m *gitignoreMatcher) rulesFor(dir string) []gitignoreRule {
+	if rules, ok := m.rules[dir]; ok {
+		return rules
+	}
+	var rules []gitignoreRule
+	if parent := filepath.Dir(dir); dir != m.top && parent != dir && strings.HasPrefix(dir, m.top) {
+		rules = append(rules, m.rulesFor(parent)...)
+	}
+	rules = append(rules, parseGitignore(dir)...)
+	m.rules[dir] = rules
+	return rules
+}
+
+// ignored reports whether path is excluded. It does not consider whether a
+// parent directory is excluded; walkFiles handles that by pruning.
+func (m *gitignoreMatcher) ignored(path string, isDir bool) bool {
+	path, _ = filepath.Abs(path)
+	ignored := false
+	for _, r := range m.rulesFor(filepath.Dir(path)) {
+		if r.match(path, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// walkOptions controls which entries walkFiles visits.
+type walkOptions struct {
+	hidden    bool   // include dotfiles and dot-directories (.git is always skipped)
+	gitignore bool   // skip paths excluded by .gitignore
+	include   string // glob matched against the walked path (root joined) that re-includes ignored paths
+	maxDepth  int    // 0 = unlimited
+}
+
+// walkFiles walks root in lexical order and calls fn with each visible entry
+// (excluding root itself) and its slash-separated path relative to root.
+// Ignored directories are pruned unless opts.include could match inside them.
+func walkFiles(ctx context.Context, root string, opts walkOptions, fn func(path, rel string, d fs.DirEntry) error) error {
+	var matcher *gitignoreMatcher
+	if opts.gitignore {
+		matcher = newGitignoreMatcher(root)
+	}
+	includeBase := ""
+	if opts.include != "" {
+		opts.include = filepath.ToSlash(opts.include)
+		includeBase, _ = doublestar.SplitPattern(opts.include)
+	}
+	ignoredDirs := make(map[string]bool) // descended only for the include override
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil // unreadable entries are skipped, as rg does
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if path == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		name := d.Name()
+		if name == ".git" || (!opts.hidden && strings.HasPrefix(name, ".")) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ignored := ignoredDirs[filepath.Dir(path)]
+		if !ignored && matcher != nil && matcher.ignored(path, d.IsDir()) {
+			ignored = true
+		}
+		if ignored {
+			included := false
+			if opts.include != "" {
+				included, _ = doublestar.Match(opts.include, filepath.ToSlash(path))
+			}
+			if d.IsDir() && !included {
+				if opts.include == "" || !pathsOverlap(includeBase, filepath.ToSlash(path)) {
+					return filepath.SkipDir
+				}
+				// Descend without reporting; only entries matching include surface
+				ignoredDirs[path] = true
+				return nil
+			}
+			if !included {
+				return nil
+			}
+		}
+
+		if opts.maxDepth > 0 && d.IsDir() && strings.Count(rel, "/")+1 >= opts.maxDepth {
+			// Deeper entries cannot match; still report the directory itself
+			if err := fn(path, rel, d); err != nil {
+				return err
+			}
+			return filepath.SkipDir
+		}
+		return fn(path, rel, d)
+	})
+}
+
+// pathsOverlap reports whether one slash-separated path is a prefix of the
+// other, i.e. whether walking dir could reach files under base.
+func pathsOverlap(base, dir string) bool {
+	if base == "" || base == "." {
+		return true
+	}
+	return base == dir || strings.HasPrefix(base, dir+"/") || strings.HasPrefix(dir, base+"/")
+}
@@ -0,0 +1,225 @@
+package tools
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (and parent dirs) under root from a path->content map.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func walkedFiles(t *testing.T, root string, opts walkOptions) []string {
+	t.Helper()
+	var got []string
+	err := walkFiles(context.Background(), root, opts, func(_, rel string, d fs.DirEntry) error {
+		if !d.IsDir() {
+			got = append(got, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestGitignore_Semantics(t *testing.T) {
+	tests := []struct {
+		name      string
+		gitignore string
+		files     []string
+		want      []string
+	}{
+		{
+			name:      "basename at any depth",
+			gitignore: "*.log\n",
+			files:     []string{"a.log", "src/b.log", "src/c.go"},
+			want:      []string{"src/c.go"},
+		},
+		{
+			name:      "directory only",
+			gitignore: "build/\n",
+			files:     []string{"build/out.o", "src/build", "src/build2/x"},
+			want:      []string{"src/build", "src/build2/x"},
+		},
+		{
+			name:      "anchored",
+			gitignore: "/dist\nsrc/gen\n",
+			files:     []string{"dist/a.js", "lib/dist/b.js", "src/gen/c.go", "x/src/gen/d.go"},
+			want:      []string{"lib/dist/b.js", "x/src/gen/d.go"},
+		},
+		{
+			name:      "negation",
+			gitignore: "*.md\n!README.md\n",
+			files:     []string{"README.md", "NOTES.md", "docs/README.md"},
+			want:      []string{"README.md", "docs/README.md"},
+		},
+		{
+			name:      "double star",
+			gitignore: "a/**/z.txt\n**/cache\n",
+			files:     []string{"a/z.txt", "a/b/c/z.txt", "b/z.txt", "deep/er/cache/f", "cachefile"},
+			want:      []string{"b/z.txt", "cachefile"},
+		},
+		{
+			name:      "comments and escapes",
+			gitignore: "# comment\n\n\\#hash\ntrailing   \n",
+			files:     []string{"#hash", "# comment", "trailing"},
+			want:      []string{"# comment"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{".gitignore": tt.gitignore}
+			for _, f := range tt.files {
+				files[f] = ""
+			}
+			writeTree(t, dir, files)
+
+			got := walkedFiles(t, dir, walkOptions{gitignore: true})
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitignore_NestedAndParentFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.Mkdir(filepath.Join(dir, ".git"), 0o755)
+	writeTree(t, dir, map[string]string{
+		".gitignore":        "*.tmp\n",
+		"pkg/.gitignore":    "local.txt\n!keep.tmp\n",
+		"pkg/keep.tmp":      "",
+		"pkg/drop.tmp":      "",
+		"pkg/local.txt":     "",
+		"pkg/main.go":       "",
+		"other/local.txt":   "",
+		"pkg/sub/local.txt": "",
+	})
+
+	// Searching a subdirectory still applies the repository root's .gitignore
+	got := walkedFiles(t, filepath.Join(dir, "pkg"), walkOptions{gitignore: true})
+	want := []string{"keep.tmp", "main.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	got = walkedFiles(t, dir, walkOptions{gitignore: true})
+	want = []string{"other/local.txt", "pkg/keep.tmp", "pkg/main.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalkFiles_HiddenAndInclude(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":            "node_modules/\nvendor/\n",
+		".env":                  "",
+		".github/ci.yml":        "",
+		"node_modules/x/i.js":   "",
+		"vendor/lib/a.go":       "",
+		"vendor/lib/a_test.txt": "",
+		"main.go":               "",
+	})
+
+	got := walkedFiles(t, dir, walkOptions{gitignore: true})
+	if want := "main.go"; strings.Join(got, ",") != want {
+		t.Errorf("default: got %v, want %v", got, want)
+	}
+
+	got = walkedFiles(t, dir, walkOptions{gitignore: true, hidden: true})
+	want := []string{".env", ".github/ci.yml", ".gitignore", "main.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("hidden: got %v, want %v", got, want)
+	}
+
+	got = walkedFiles(t, dir, walkOptions{gitignore: true, include: filepath.Join(dir, "vendor/**/*.go")})
+	want = []string{"main.go", "vendor/lib/a.go"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("include: got %v, want %v", got, want)
+	}
+
+	got = walkedFiles(t, dir, walkOptions{})
+	want = []string{"main.go", "node_modules/x/i.js", "vendor/lib/a.go", "vendor/lib/a_test.txt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("gitignore off: got %v, want %v", got, want)
+	}
+}
+
+func TestGlob_RespectsGitignore(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":            "node_modules/\n",
+		"node_modules/pkg/a.js": "",
+		"src/b.js":              "",
+		".hidden.js":            "",
+	})
+
+	tool := &GlobTool{CWD: dir}
+	out, _ := tool.Execute(context.Background(), map[string]any{"pattern": "**/*.js"})
+	if out.Content != filepath.Join(dir, "src", "b.js") {
+		t.Errorf("got %q, want only src/b.js", out.Content)
+	}
+
+	// An explicit base directory is never ignored
+	out, _ = tool.Execute(context.Background(), map[string]any{"pattern": "node_modules/pkg/*.js"})
+	if !strings.Contains(out.Content, "a.js") {
+		t.Errorf("expected explicit node_modules path to match, got %q", out.Content)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"pattern": "**/*.js", "include": "node_modules/**", "hidden": true})
+	for _, want := range []string{"a.js", "b.js", ".hidden.js"} {
+		if !strings.Contains(out.Content, want) {
+			t.Errorf("expected %s with include+hidden, got %q", want, out.Content)
+		}
+	}
+
+	off := false
+	tool = &GlobTool{CWD: dir, RespectGitignore: &off}
+	out, _ = tool.Execute(context.Background(), map[string]any{"pattern": "**/*.js"})
+	if !strings.Contains(out.Content, "a.js") {
+		t.Errorf("expected node_modules with RespectGitignore=false, got %q", out.Content)
+	}
+}
+
+func TestGrep_RespectsGitignore(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		".gitignore":      "dist/\n",
+		"dist/bundle.js":  "needle\n",
+		"src/app.js":      "needle\n",
+		".config/app.yml": "needle\n",
+	})
+
+	tool := &GrepTool{CWD: dir}
+	out, _ := tool.Execute(context.Background(), map[string]any{"pattern": "needle"})
+	if out.Content != filepath.Join(dir, "src", "app.js") {
+		t.Errorf("got %q, want only src/app.js", out.Content)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"pattern": "needle", "include": "dist/*.js", "hidden": true})
+	got := strings.Split(out.Content, "\n")
+	if len(got) != 3 {
+		t.Errorf("expected 3 files with include+hidden, got %q", out.Content)
+	}
+}
@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"fmt"
+	"io/fs"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -14,7 +16,8 @@ const globMaxOutput = 50000 // characters
 
 // GlobTool finds files by glob pattern.
 type GlobTool struct {
-	CWD string
+	CWD              string
+	RespectGitignore *bool // skip paths excluded by .gitignore (nil = true)
 }
 
 func (g *GlobTool) Name() string { return "Glob" }
@@ -24,6 +27,7 @@ func (g *GlobTool) Description() string {
 - Supports glob patterns like "**/*.js" or "src/**/*.ts"
 - Returns matching file paths sorted by modification time
 - Use this tool when you need to find files by name patterns
+- Files excluded by .gitignore and dotfiles are skipped; set hidden=true for dotfiles, or include to re-include ignored paths (e.g. "vendor/**")
 - When you are doing an open ended search that may require multiple rounds of globbing and grepping, use the Agent tool instead
 - You can call multiple tools in a single response. It is always better to speculatively perform multiple searches in parallel if they are potentially useful.`
 }
@@ -40,6 +44,14 @@ func (g *GlobTool) InputSchema() map[string]any {
 				"type":        "string",
 				"description": "The directory to search in (default: CWD)",
 			},
+			"hidden": map[string]any{
+				"type":        "boolean",
+				"description": "Include dotfiles and dot-directories (default false)",
+			},
+			"include": map[string]any{
+				"type":        "string",
+				"description": "Glob relative to path for files to return even if .gitignore excludes them",
+			},
 		},
 		"required": []string{"pattern"},
 	}
@@ -47,7 +59,7 @@ func (g *GlobTool) InputSchema() map[string]any {
 
 func (g *GlobTool) SideEffect() SideEffectType { return SideEffectNone }
 
-func (g *GlobTool) Execute(_ context.Context, input map[string]any) (ToolOutput, error) {
+func (g *GlobTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
 	pattern, ok := input["pattern"].(string)
 	if !ok || pattern == "" {
 		return ToolOutput{Content: "Error: pattern is required", IsError: true}, nil
@@ -60,8 +72,23 @@ func (g *GlobTool) Execute(_ context.Context, input map[string]any) (ToolOutput,
 
 	// Resolve the full pattern
 	fullPattern := filepath.Join(searchDir, pattern)
+	if !doublestar.ValidatePattern(filepath.ToSlash(fullPattern)) {
+		return ToolOutput{Content: fmt.Sprintf("Error: %s", doublestar.ErrBadPattern), IsError: true}, nil
+	}
 
-	matches, err := doublestar.FilepathGlob(fullPattern)
+	hidden, _ := input["hidden"].(bool)
+	include, _ := input["include"].(string)
+	if include != "" {
+		include = filepath.Join(searchDir, include)
+	}
+	matches, err := globFiles(ctx, fullPattern, walkOptions{
+		hidden:    hidden,
+		gitignore: g.RespectGitignore == nil || *g.RespectGitignore,
+		include:   include,
+	})
+	if ctx.Err() != nil {
+		return ToolOutput{}, ctx.Err() // interrupted mid-walk
+	}
 	if err != nil {
 		return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
 	}
@@ -79,3 +106,33 @@ func (g *GlobTool) Execute(_ context.Context, input map[string]any) (ToolOutput,
 	}
 	return ToolOutput{Content: output}, nil
 }
+
+// globFiles walks the static base of fullPattern and returns every entry whose
+// path matches the rest of the pattern. Walking (rather than expanding the
+// pattern directly) lets ignored directories be pruned instead of traversed.
+// The base directory itself is never subject to .gitignore, so naming an
+// ignored directory explicitly (e.g. "node_modules/pkg/*.js") still works.
+func globFiles(ctx context.Context, fullPattern string, opts walkOptions) ([]string, error) {
+	base, rest := doublestar.SplitPattern(filepath.ToSlash(fullPattern))
+	base = filepath.FromSlash(base)
+	if info, err := os.Stat(base); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	// Patterns that name dotfiles explicitly match them without hidden=true
+	if strings.HasPrefix(rest, ".") || strings.Contains(rest, "/.") {
+		opts.hidden = true
+	}
+	if !strings.Contains(rest, "**") {
+		opts.maxDepth = strings.Count(rest, "/") + 1
+	}
+
+	var matches []string
+	err := walkFiles(ctx, base, opts, func(path, rel string, _ fs.DirEntry) error {
+		if ok, _ := doublestar.Match(rest, rel); ok {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -136,3 +137,53 @@ func TestGlob_PathOverride(t *testing.T) {
 		t.Error("root.txt should not be found when searching in sub/")
 	}
 }
+
+// cancelAfterChecks is a context that cancels itself on its nth Err call, so
+// a test can interrupt a walk deterministically partway through.
+type cancelAfterChecks struct {
+	context.Context
+	cancel context.CancelFunc
+	n      int
+	checks int
+}
+
+func newCancelAfterChecks(n int) *cancelAfterChecks {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancelAfterChecks{Context: ctx, cancel: cancel, n: n}
+}
+
+func (c *cancelAfterChecks) Err() error {
+	c.checks++
+	if c.checks == c.n {
+		c.cancel()
+	}
+	return c.Context.Err()
+}
+
+// makeWideTree creates dirs directories of files files each under root.
+func makeWideTree(t *testing.T, root string, dirs, files int) {
+	t.Helper()
+	for d := range dirs {
+		sub := filepath.Join(root, fmt.Sprintf("d%03d", d))
+		os.MkdirAll(sub, 0o755)
+		for f := range files {
+			os.WriteFile(filepath.Join(sub, fmt.Sprintf("f%03d.go", f)), []byte("package x\n"), 0o644)
+		}
+	}
+}
+
+func TestGlob_ContextCancel(t *testing.T) {
+	dir := t.TempDir()
+	makeWideTree(t, dir, 40, 50)
+
+	ctx := newCancelAfterChecks(100)
+	tool := &GlobTool{CWD: dir}
+	out, err := tool.Execute(ctx, map[string]any{"pattern": "**/*.go"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v (output %q), want context.Canceled", err, out.Content)
+	}
+	// The walk visits ~2000 entries; it must stop soon after the cancel
+	if ctx.checks > 110 {
+		t.Errorf("walk kept going for %d checks after cancellation", ctx.checks-100)
+	}
+}
@@ -4,15 +4,18 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
 const grepMaxOutput = 100000 // characters
 
-// GrepTool searches file contents using ripgrep.
+// GrepTool searches file contents using ripgrep, or a built-in searcher
+// with the same output format when rg is not installed.
 type GrepTool struct {
-	CWD string
+	CWD              string
+	RespectGitignore *bool // skip paths excluded by .gitignore (nil = true)
 }
 
 func (g *GrepTool) Name() string { return "Grep" }
@@ -25,6 +28,8 @@ func (g *GrepTool) Description() string {
   - Supports full regex syntax (e.g., "log.*Error", "function\\s+\\w+")
   - Filter files with glob parameter (e.g., "*.js", "**/*.tsx") or type parameter (e.g., "js", "py", "rust")
   - Output modes: "content" shows matching lines, "files_with_matches" shows only file paths (default), "count" shows match counts
+  - Files excluded by .gitignore and dotfiles are skipped; set hidden=true for dotfiles, or include to re-include ignored paths (e.g. "vendor/**")
+  - Context: before_context/after_context/context_lines show surrounding lines in content mode; overlapping context is merged
   - Use Agent tool for open-ended searches requiring multiple rounds
   - Pattern syntax: Uses ripgrep (not grep) - literal braces need escaping (use ` + "`interface\\{\\}`" + ` to find ` + "`interface{}`" + ` in Go code)
   - Multiline matching: By default patterns match within single lines only. For cross-line patterns like ` + "`struct \\{[\\s\\S]*?field`" + `, use ` + "`multiline: true`" + ``
@@ -60,19 +65,19 @@ func (g *GrepTool) InputSchema() map[string]any {
 			},
 			"after_context": map[string]any{
 				"type":        "number",
-				"description": "Lines to show after each match",
+				"description": "Lines to show after each match (alias: after, -A; content mode only)",
 			},
 			"before_context": map[string]any{
 				"type":        "number",
-				"description": "Lines to show before each match",
+				"description": "Lines to show before each match (alias: before, -B; content mode only)",
 			},
 			"context_lines": map[string]any{
 				"type":        "number",
-				"description": "Lines of context around each match",
+				"description": "Lines of context around each match (alias: context, -C; content mode only)",
 			},
 			"type": map[string]any{
 				"type":        "string",
-				"description": "File type filter (e.g. js, py, go)",
+				"description": "File type filter (e.g. js, py, go, ts, rust); more efficient than an equivalent glob",
 			},
 			"head_limit": map[string]any{
 				"type":        "number",
@@ -82,6 +87,14 @@ func (g *GrepTool) InputSchema() map[string]any {
 				"type":        "boolean",
 				"description": "Enable multiline mode",
 			},
+			"hidden": map[string]any{
+				"type":        "boolean",
+				"description": "Search dotfiles and dot-directories (default false)",
+			},
+			"include": map[string]any{
+				"type":        "string",
+				"description": "Glob relative to path for files to search even if .gitignore excludes them",
+			},
 		},
 		"required": []string{"pattern"},
 	}
@@ -90,34 +103,33 @@ func (g *GrepTool) InputSchema() map[string]any {
 func (g *GrepTool) SideEffect() SideEffectType { return SideEffectNone }
 
 func (g *GrepTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
-	pattern, ok := input["pattern"].(string)
-	if !ok || pattern == "" {
-		return ToolOutput{Content: "Error: pattern is required", IsError: true}, nil
-	}
-
-	args := g.buildArgs(input, pattern)
-
-	searchPath := g.CWD
-	if p, ok := input["path"].(string); ok && p != "" {
-		searchPath = p
+	opts, err := g.parseOptions(input)
+	if err != nil {
+		return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
 	}
-	args = append(args, searchPath)
-
-	cmd := exec.CommandContext(ctx, "rg", args...)
-	output, err := cmd.CombinedOutput()
-	result := strings.TrimRight(string(output), "\n")
 
-	if err != nil {
-		// rg returns exit code 1 for no matches — not an error
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return ToolOutput{Content: "No matches found."}, nil
+	var result string
+	// rg has no way to re-include individual ignored paths, so include uses the built-in searcher
+	if rg, err := lookPathRipgrep(); err == nil && opts.include == "" {
+		var errOut *ToolOutput
+		result, errOut = runRipgrep(ctx, rg, opts)
+		if ctx.Err() != nil {
+			return ToolOutput{}, ctx.Err() // rg was killed by the cancellation
 		}
-		// rg returns exit code 2 for errors
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
-			return ToolOutput{Content: fmt.Sprintf("Error: %s", result), IsError: true}, nil
+		if errOut != nil {
+			return *errOut, nil
+		}
+	} else {
+		// ripgrep not installed — fall back to the built-in searcher
+		result, err = searchFiles(ctx, opts)
+		if ctx.Err() != nil {
+			return ToolOutput{}, ctx.Err()
+		}
+		if err != nil {
+			return ToolOutput{Content: fmt.Sprintf("Error: %s", err), IsError: true}, nil
 		}
-		return ToolOutput{Content: fmt.Sprintf("Error running rg: %s", err), IsError: true}, nil
 	}
+	result = strings.TrimRight(result, "\n")
 
 	if result == "" {
 		return ToolOutput{Content: "No matches found."}, nil
@@ -135,96 +147,192 @@ func (g *GrepTool) Execute(ctx context.Context, input map[string]any) (ToolOutpu
 	// Hard output limit as safety net
 	if len(result) > grepMaxOutput {
 		totalLen := len(result)
-		result = result[:grepMaxOutput] + fmt.Sprintf("\n... (truncated, %d total characters)", totalLen)
+		cut := grepMaxOutput
+		if i := strings.LastIndexByte(result[:cut], '\n'); i > 0 {
+			cut = i // end on a whole line
+		}
+		result = result[:cut] + fmt.Sprintf("\n... (truncated, %d total characters). Narrow the search with path, glob, type or head_limit.", totalLen)
 	}
 
 	return ToolOutput{Content: result}, nil
 }
 
-// inputBool looks up a boolean value by the new key, falling back to the legacy key.
-func inputBool(input map[string]any, key, legacyKey string) (bool, bool) {
-	if v, ok := input[key].(bool); ok {
-		return v, true
+// lookPathRipgrep locates the rg binary. Overridable in tests.
+var lookPathRipgrep = func() (string, error) { return exec.LookPath("rg") }
+
+// grepOptions is the normalized form of the Grep tool input.
+type grepOptions struct {
+	pattern     string
+	path        string
+	glob        string
+	fileType    string
+	outputMode  string // content, files_with_matches, count
+	ignoreCase  bool
+	lineNumbers bool
+	before      int
+	after       int
+	multiline   bool
+	hidden      bool
+	gitignore   bool
+	include     string // glob re-including ignored paths, joined with path
+}
+
+func (g *GrepTool) parseOptions(input map[string]any) (grepOptions, error) {
+	opts := grepOptions{
+		path:        g.CWD,
+		outputMode:  "files_with_matches",
+		lineNumbers: true,
+		gitignore:   g.RespectGitignore == nil || *g.RespectGitignore,
+	}
+	pattern, ok := input["pattern"].(string)
+	if !ok || pattern == "" {
+		return opts, fmt.Errorf("pattern is required")
+	}
+	opts.pattern = pattern
+
+	if p, ok := input["path"].(string); ok && p != "" {
+		opts.path = p
 	}
-	if legacyKey != "" {
-		if v, ok := input[legacyKey].(bool); ok {
+	if om, ok := input["output_mode"].(string); ok && om != "" {
+		switch om {
+		case "content", "files_with_matches", "count":
+			opts.outputMode = om
+		default:
+			return opts, fmt.Errorf("invalid output_mode %q (expected content, files_with_matches or count)", om)
+		}
+	}
+	if n, ok := inputBool(input, "show_line_numbers", "-n"); ok {
+		opts.lineNumbers = n
+	}
+	// Case insensitive (new: case_insensitive, legacy: -i)
+	if ci, ok := inputBool(input, "case_insensitive", "-i"); ok {
+		opts.ignoreCase = ci
+	}
+
+	// Context lines (context_lines/-C/context sets both sides; before/after override)
+	if c, ok := inputFloat(input, "context_lines", "-C", "context"); ok && c > 0 {
+		opts.before, opts.after = int(c), int(c)
+	}
+	if b, ok := inputFloat(input, "before_context", "-B", "before"); ok && b > 0 {
+		opts.before = int(b)
+	}
+	if a, ok := inputFloat(input, "after_context", "-A", "after"); ok && a > 0 {
+		opts.after = int(a)
+	}
+
+	if gl, ok := input["glob"].(string); ok {
+		opts.glob = gl
+	}
+	if ft, ok := input["type"].(string); ok {
+		opts.fileType = ft
+	}
+	if ml, ok := input["multiline"].(bool); ok {
+		opts.multiline = ml
+	}
+	if h, ok := input["hidden"].(bool); ok {
+		opts.hidden = h
+	}
+	if inc, ok := input["include"].(string); ok && inc != "" {
+		opts.include = filepath.Join(opts.path, inc)
+	}
+	return opts, nil
+}
+
+// runRipgrep executes rg and returns its output, or a ToolOutput for rg errors.
+func runRipgrep(ctx context.Context, rg string, opts grepOptions) (string, *ToolOutput) {
+	args := append(buildArgs(opts), opts.path)
+	cmd := exec.CommandContext(ctx, rg, args...)
+	output, err := cmd.CombinedOutput()
+	result := strings.TrimRight(string(output), "\n")
+
+	if err != nil {
+		// rg returns exit code 1 for no matches — not an error
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		// rg returns exit code 2 for errors
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+			return "", &ToolOutput{Content: fmt.Sprintf("Error: %s", result), IsError: true}
+		}
+		return "", &ToolOutput{Content: fmt.Sprintf("Error running rg: %s", err), IsError: true}
+	}
+	return result, nil
+}
+
+// inputBool looks up a boolean value by the first key present, so the new
+// key takes precedence over legacy aliases.
+func inputBool(input map[string]any, keys ...string) (bool, bool) {
+	for _, key := range keys {
+		if v, ok := input[key].(bool); ok {
 			return v, true
 		}
 	}
 	return false, false
 }
 
-// inputFloat looks up a numeric value by the new key, falling back to the legacy key.
-func inputFloat(input map[string]any, key, legacyKey string) (float64, bool) {
-	if v, ok := input[key].(float64); ok {
-		return v, true
-	}
-	if legacyKey != "" {
-		if v, ok := input[legacyKey].(float64); ok {
+// inputFloat looks up a numeric value by the first key present, so the new
+// key takes precedence over legacy aliases.
+func inputFloat(input map[string]any, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		if v, ok := input[key].(float64); ok {
 			return v, true
 		}
 	}
 	return 0, false
 }
 
-func (g *GrepTool) buildArgs(input map[string]any, pattern string) []string {
+func buildArgs(opts grepOptions) []string {
 	var args []string
 
-	// Output mode
-	outputMode := "files_with_matches"
-	if om, ok := input["output_mode"].(string); ok && om != "" {
-		outputMode = om
-	}
-
-	switch outputMode {
+	switch opts.outputMode {
 	case "files_with_matches":
 		args = append(args, "--files-with-matches")
 	case "count":
 		args = append(args, "--count")
 	case "content":
 		// Default rg behavior — show matching lines
-		showLineNumbers := true
-		if n, ok := inputBool(input, "show_line_numbers", "-n"); ok {
-			showLineNumbers = n
-		}
-		if showLineNumbers {
+		if opts.lineNumbers {
 			args = append(args, "--line-number")
 		}
+		if opts.after > 0 {
+			args = append(args, "-A", strconv.Itoa(opts.after))
+		}
+		if opts.before > 0 {
+			args = append(args, "-B", strconv.Itoa(opts.before))
+		}
 	}
 
-	// Case insensitive (new: case_insensitive, legacy: -i)
-	if ci, ok := inputBool(input, "case_insensitive", "-i"); ok && ci {
+	if opts.ignoreCase {
 		args = append(args, "--ignore-case")
 	}
 
-	// Context lines (new: after_context/before_context/context_lines, legacy: -A/-B/-C)
-	if a, ok := inputFloat(input, "after_context", "-A"); ok && a > 0 {
-		args = append(args, "-A", strconv.Itoa(int(a)))
-	}
-	if b, ok := inputFloat(input, "before_context", "-B"); ok && b > 0 {
-		args = append(args, "-B", strconv.Itoa(int(b)))
+	// Ignore handling (.gitignore applies even outside a git repo, like the built-in searcher)
+	if opts.gitignore {
+		args = append(args, "--no-require-git")
+	} else {
+		args = append(args, "--no-ignore")
 	}
-	if c, ok := inputFloat(input, "context_lines", "-C"); ok && c > 0 {
-		args = append(args, "-C", strconv.Itoa(int(c)))
+	if opts.hidden {
+		args = append(args, "--hidden")
 	}
 
 	// Glob filter
-	if gl, ok := input["glob"].(string); ok && gl != "" {
-		args = append(args, "--glob", gl)
+	if opts.glob != "" {
+		args = append(args, "--glob", opts.glob)
 	}
 
 	// File type
-	if ft, ok := input["type"].(string); ok && ft != "" {
-		args = append(args, "--type", ft)
+	if opts.fileType != "" {
+		args = append(args, "--type", opts.fileType)
 	}
 
 	// Multiline
-	if ml, ok := input["multiline"].(bool); ok && ml {
+	if opts.multiline {
 		args = append(args, "--multiline", "--multiline-dotall")
 	}
 
 	// Pattern
-	args = append(args, "--", pattern)
+	args = append(args, "--", opts.pattern)
 
 	return args
 }
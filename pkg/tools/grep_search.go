@@ -0,0 +1,249 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// grepBinaryProbe is how many leading bytes are checked for NUL to skip binary files.
+const grepBinaryProbe = 8000
+
+// grepFileTypes maps Grep's type filter to glob include patterns, mirroring
+// ripgrep's built-in type definitions for common languages.
+var grepFileTypes = map[string][]string{
+	"c":          {"*.c", "*.h"},
+	"cpp":        {"*.cpp", "*.cc", "*.cxx", "*.hpp", "*.hh", "*.hxx", "*.h"},
+	"cs":         {"*.cs"},
+	"css":        {"*.css", "*.scss", "*.sass", "*.less"},
+	"dart":       {"*.dart"},
+	"docker":     {"Dockerfile", "*.dockerfile"},
+	"elixir":     {"*.ex", "*.exs"},
+	"go":         {"*.go"},
+	"html":       {"*.html", "*.htm"},
+	"java":       {"*.java"},
+	"js":         {"*.js", "*.jsx", "*.mjs", "*.cjs"},
+	"json":       {"*.json"},
+	"kotlin":     {"*.kt", "*.kts"},
+	"lua":        {"*.lua"},
+	"make":       {"Makefile", "makefile", "GNUmakefile", "*.mk"},
+	"markdown":   {"*.md", "*.markdown"},
+	"md":         {"*.md", "*.markdown"},
+	"php":        {"*.php"},
+	"proto":      {"*.proto"},
+	"py":         {"*.py", "*.pyi"},
+	"python":     {"*.py", "*.pyi"},
+	"rb":         {"*.rb", "Gemfile", "Rakefile"},
+	"ruby":       {"*.rb", "Gemfile", "Rakefile"},
+	"rs":         {"*.rs"},
+	"rust":       {"*.rs"},
+	"scala":      {"*.scala", "*.sc"},
+	"sh":         {"*.sh", "*.bash", "*.zsh"},
+	"sql":        {"*.sql"},
+	"swift":      {"*.swift"},
+	"tf":         {"*.tf", "*.tfvars"},
+	"toml":       {"*.toml"},
+	"ts":         {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"typescript": {"*.ts", "*.tsx", "*.mts", "*.cts"},
+	"txt":        {"*.txt"},
+	"xml":        {"*.xml"},
+	"yaml":       {"*.yaml", "*.yml"},
+}
+
+// searchFiles is the built-in fallback used when ripgrep is not installed.
+// It walks opts.path (skipping hidden, ignored and binary files, like rg) and formats
+// results the way rg does: "path:line:text" for matches, "path-line-text" for
+// context lines, and "--" between non-adjacent context groups.
+func searchFiles(ctx context.Context, opts grepOptions) (string, error) {
+	expr := opts.pattern
+	if opts.multiline {
+		expr = "(?s)" + expr
+	}
+	if opts.ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid regex: %w", err)
+	}
+
+	var typeGlobs []string
+	if opts.fileType != "" {
+		globs, ok := grepFileTypes[strings.ToLower(opts.fileType)]
+		if !ok {
+			return "", fmt.Errorf("unrecognized file type: %s", opts.fileType)
+		}
+		typeGlobs = globs
+	}
+	if opts.glob != "" && !doublestar.ValidatePattern(strings.TrimPrefix(opts.glob, "!")) {
+		return "", fmt.Errorf("invalid glob: %s", opts.glob)
+	}
+
+	info, err := os.Stat(opts.path)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	if !info.IsDir() {
+		files = []string{opts.path}
+	} else {
+		walk := walkOptions{hidden: opts.hidden, gitignore: opts.gitignore, include: opts.include}
+		err = walkFiles(ctx, opts.path, walk, func(path, rel string, d fs.DirEntry) error {
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if opts.glob != "" && !matchGrepGlob(opts.glob, rel) {
+				return nil
+			}
+			if typeGlobs != nil && !matchAnyGrepGlob(typeGlobs, rel) {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	sort.Strings(files)
+
+	withFilename := info.IsDir()
+	var out strings.Builder
+	wroteGroup := false
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || bytes.IndexByte(data[:min(len(data), grepBinaryProbe)], 0) >= 0 {
+			continue
+		}
+		lines, matched := matchLines(re, string(data), opts.multiline)
+		if len(matched) == 0 {
+			continue
+		}
+
+		switch opts.outputMode {
+		case "files_with_matches":
+			out.WriteString(path + "\n")
+		case "count":
+			if withFilename {
+				fmt.Fprintf(&out, "%s:%d\n", path, len(matched))
+			} else {
+				fmt.Fprintf(&out, "%d\n", len(matched))
+			}
+		default:
+			for _, win := range mergeContextWindows(matched, opts.before, opts.after, len(lines)) {
+				if wroteGroup && (opts.before > 0 || opts.after > 0) {
+					out.WriteString("--\n")
+				}
+				wroteGroup = true
+				for i := win[0]; i <= win[1]; i++ {
+					sep := "-"
+					if matched[i] {
+						sep = ":"
+					}
+					if withFilename {
+						out.WriteString(path + sep)
+					}
+					if opts.lineNumbers {
+						fmt.Fprintf(&out, "%d%s", i+1, sep)
+					}
+					out.WriteString(lines[i] + "\n")
+				}
+			}
+		}
+	}
+	return out.String(), nil
+}
+
+// matchLines splits content into lines and returns the set of 0-based line
+// indexes containing a match. In multiline mode every line a match spans is included.
+func matchLines(re *regexp.Regexp, content string, multiline bool) ([]string, map[int]bool) {
+	content = strings.TrimSuffix(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	lines := strings.Split(content, "\n")
+	matched := make(map[int]bool)
+
+	if !multiline {
+		for i, l := range lines {
+			if re.MatchString(l) {
+				matched[i] = true
+			}
+		}
+		return lines, matched
+	}
+
+	// Map byte offsets to line numbers
+	starts := make([]int, len(lines))
+	off := 0
+	for i := range lines {
+		starts[i] = off
+		off += len(lines[i]) + 1
+	}
+	lineAt := func(pos int) int {
+		return sort.Search(len(starts), func(i int) bool { return starts[i] > pos }) - 1
+	}
+	for _, m := range re.FindAllStringIndex(content, -1) {
+		end := m[1]
+		if end > m[0] {
+			end-- // last byte of the match
+		}
+		for i := lineAt(m[0]); i <= lineAt(end); i++ {
+			matched[i] = true
+		}
+	}
+	return lines, matched
+}
+
+// mergeContextWindows expands each matched line by before/after lines and
+// merges overlapping or adjacent windows, so no line is printed twice.
+// Returns inclusive [start, end] pairs in order.
+func mergeContextWindows(matched map[int]bool, before, after, total int) [][2]int {
+	idx := make([]int, 0, len(matched))
+	for i := range matched {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+
+	var windows [][2]int
+	for _, i := range idx {
+		start, end := max(i-before, 0), min(i+after, total-1)
+		if n := len(windows); n > 0 && start <= windows[n-1][1]+1 {
+			windows[n-1][1] = max(windows[n-1][1], end)
+			continue
+		}
+		windows = append(windows, [2]int{start, end})
+	}
+	return windows
+}
+
+// matchGrepGlob applies an rg-style --glob: patterns without a slash match the
+// file name at any depth, others match the path relative to the search root.
+// A leading "!" excludes matching files instead.
+func matchGrepGlob(glob, rel string) bool {
+	negate := strings.HasPrefix(glob, "!")
+	glob = strings.TrimPrefix(glob, "!")
+	return matchAnyGrepGlob([]string{glob}, rel) != negate
+}
+
+func matchAnyGrepGlob(globs []string, rel string) bool {
+	for _, g := range globs {
+		target := rel
+		if !strings.Contains(g, "/") {
+			target = filepath.Base(rel)
+		}
+		if ok, _ := doublestar.Match(g, target); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -196,3 +198,175 @@ func TestGrepTool_OutputTruncation(t *testing.T) {
 		t.Errorf("content before suffix should be at most %d chars, got %d", grepMaxOutput, suffixIdx)
 	}
 }
+
+// withoutRipgrep forces the built-in searcher for the duration of a test.
+func withoutRipgrep(t *testing.T) {
+	t.Helper()
+	orig := lookPathRipgrep
+	lookPathRipgrep = func() (string, error) { return "", os.ErrNotExist }
+	t.Cleanup(func() { lookPathRipgrep = orig })
+}
+
+func TestGrep_ContextMergesAdjacentMatches(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	os.WriteFile(path, []byte("a\nhit1\nb\nhit2\nc\nd\ne\nf\nhit3\ng\n"), 0o644)
+
+	tool := &GrepTool{CWD: dir}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"pattern":     "hit",
+		"path":        path,
+		"output_mode": "content",
+		"context":     float64(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1-a\n2:hit1\n3-b\n4:hit2\n5-c\n--\n8-f\n9:hit3\n10-g"
+	if out.Content != want {
+		t.Errorf("got:\n%s\nwant:\n%s", out.Content, want)
+	}
+}
+
+func TestGrep_BeforeAfterOverrideContext(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.txt")
+	os.WriteFile(path, []byte("1\n2\n3\nhit\n5\n6\n7\n"), 0o644)
+
+	tool := &GrepTool{CWD: dir}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"pattern":     "hit",
+		"path":        path,
+		"output_mode": "content",
+		"context":     float64(2),
+		"after":       float64(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2-2\n3-3\n4:hit\n5-5"; out.Content != want {
+		t.Errorf("got %q, want %q", out.Content, want)
+	}
+}
+
+func TestGrep_TypeFilterAndCount(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("TODO one\nTODO two\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "b.py"), []byte("TODO three\n"), 0o644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "c.go"), []byte("TODO four\n"), 0o644)
+
+	tool := &GrepTool{CWD: dir}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"pattern":     "TODO",
+		"type":        "go",
+		"output_mode": "count",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join(dir, "a.go") + ":2\n" + filepath.Join(dir, "sub", "c.go") + ":1"
+	if out.Content != want {
+		t.Errorf("got %q, want %q", out.Content, want)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"pattern": "TODO", "type": "cobol"})
+	if !out.IsError || !strings.Contains(out.Content, "unrecognized file type") {
+		t.Errorf("expected unknown type error, got %q", out.Content)
+	}
+}
+
+func TestGrep_InvalidOutputMode(t *testing.T) {
+	tool := &GrepTool{CWD: t.TempDir()}
+	out, _ := tool.Execute(context.Background(), map[string]any{"pattern": "x", "output_mode": "lines"})
+	if !out.IsError || !strings.Contains(out.Content, "invalid output_mode") {
+		t.Errorf("expected invalid output_mode error, got %q", out.Content)
+	}
+}
+
+func TestGrep_NativeSkipsHiddenAndBinary(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "ok.txt"), []byte("needle\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".hidden"), []byte("needle\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "bin.dat"), []byte("needle\x00\x01"), 0o644)
+	os.MkdirAll(filepath.Join(dir, ".git"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("needle\n"), 0o644)
+
+	tool := &GrepTool{CWD: dir}
+	out, err := tool.Execute(context.Background(), map[string]any{"pattern": "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != filepath.Join(dir, "ok.txt") {
+		t.Errorf("got %q, want only ok.txt", out.Content)
+	}
+}
+
+func TestGrep_NativeMultiline(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "f.go")
+	os.WriteFile(path, []byte("type T struct {\n\tA int\n\tB int\n}\n"), 0o644)
+
+	tool := &GrepTool{CWD: dir}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"pattern":     `struct \{.*?A int`,
+		"path":        path,
+		"output_mode": "content",
+		"multiline":   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1:type T struct {\n2:\tA int"; out.Content != want {
+		t.Errorf("got %q, want %q", out.Content, want)
+	}
+}
+
+func TestMergeContextWindows(t *testing.T) {
+	tests := []struct {
+		name          string
+		matched       []int
+		before, after int
+		total         int
+		want          [][2]int
+	}{
+		{"no context", []int{1, 3}, 0, 0, 5, [][2]int{{1, 1}, {3, 3}}},
+		{"overlap", []int{2, 4}, 1, 1, 10, [][2]int{{1, 5}}},
+		{"adjacent", []int{1, 4}, 0, 2, 10, [][2]int{{1, 6}}},
+		{"separate", []int{1, 8}, 1, 1, 10, [][2]int{{0, 2}, {7, 9}}},
+		{"clamped", []int{0, 9}, 3, 3, 10, [][2]int{{0, 3}, {6, 9}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := make(map[int]bool)
+			for _, i := range tt.matched {
+				m[i] = true
+			}
+			got := mergeContextWindows(m, tt.before, tt.after, tt.total)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGrep_NativeContextCancel(t *testing.T) {
+	withoutRipgrep(t)
+	dir := t.TempDir()
+	makeWideTree(t, dir, 40, 50)
+
+	ctx := newCancelAfterChecks(100)
+	tool := &GrepTool{CWD: dir}
+	out, err := tool.Execute(ctx, map[string]any{"pattern": "package"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v (output %q), want context.Canceled", err, out.Content)
+	}
+	if ctx.checks > 110 {
+		t.Errorf("search kept going for %d checks after cancellation", ctx.checks-100)
+	}
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// KillTaskTool terminates a background task, including any processes its
+// command started.
+type KillTaskTool struct {
+	TaskManager *TaskManager
+}
+
+func (t *KillTaskTool) Name() string { return "KillTask" }
+
+func (t *KillTaskTool) Description() string {
+	return `
+- Kills a running background task by its ID, along with every process its command started
+- Takes a task_id parameter (use ListTasks to find IDs)
+- Use this tool to clean up servers, watchers and other tasks you no longer need`
+}
+
+func (t *KillTaskTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"task_id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the background task to kill",
+			},
+		},
+		"required": []string{"task_id"},
+	}
+}
+
+func (t *KillTaskTool) SideEffect() SideEffectType { return SideEffectMutating }
+
+func (t *KillTaskTool) Execute(_ context.Context, input map[string]any) (ToolOutput, error) {
+	if t.TaskManager == nil {
+		return ToolOutput{Content: "Error: task manager not configured", IsError: true}, nil
+	}
+
+	taskID, ok := input["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolOutput{Content: "Error: task_id is required", IsError: true}, nil
+	}
+
+	if err := t.TaskManager.Stop(taskID); err != nil {
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: %s", err),
+			IsError: true,
+		}, nil
+	}
+
+	task, _ := t.TaskManager.Get(taskID)
+	select {
+	case <-task.Done:
+		return ToolOutput{Content: fmt.Sprintf("Task %s killed.", taskID)}, nil
+	default:
+		return ToolOutput{Content: fmt.Sprintf("Task %s was signalled but has not exited yet.", taskID)}, nil
+	}
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKillTask_KillsProcessGroup(t *testing.T) {
+	tm := NewTaskManager()
+	bash := &BashTool{TaskManager: tm}
+	pidFile := filepath.Join(t.TempDir(), "child.pid")
+
+	// The background child holds the command's output pipe open; without a
+	// process-group kill it would outlive the task.
+	bash.Execute(context.Background(), map[string]any{
+		"command":           "sleep 30 & echo $! > " + pidFile + "; wait",
+		"run_in_background": true,
+	})
+
+	var pid string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, err := os.ReadFile(pidFile); err == nil && strings.TrimSpace(string(data)) != "" {
+			pid = strings.TrimSpace(string(data))
+			break
+		}
+	}
+	if pid == "" {
+		t.Fatal("child never started")
+	}
+
+	taskID := tm.List()[0].ID
+	start := time.Now()
+	out, err := (&KillTaskTool{TaskManager: tm}).Execute(context.Background(), map[string]any{"task_id": taskID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.IsError || !strings.Contains(out.Content, "killed") {
+		t.Fatalf("content = %q", out.Content)
+	}
+	if elapsed := time.Since(start); elapsed > processWaitDelay {
+		t.Errorf("kill took %s", elapsed)
+	}
+	time.Sleep(50 * time.Millisecond) // let the kill land
+	if processAlive(pid) {
+		t.Errorf("child process %s is still running", pid)
+	}
+
+	task, _ := tm.Get(taskID)
+	if s := task.getStatus(); s != TaskStopped {
+		t.Errorf("status = %s, want stopped", s)
+	}
+}
+
+func TestKillTask_Errors(t *testing.T) {
+	tm := NewTaskManager()
+	tool := &KillTaskTool{TaskManager: tm}
+
+	for name, input := range map[string]map[string]any{
+		"missing task_id": {},
+		"unknown task":    {"task_id": "nope"},
+	} {
+		out, _ := tool.Execute(context.Background(), input)
+		if !out.IsError {
+			t.Errorf("%s: expected error, got %q", name, out.Content)
+		}
+	}
+
+	out, _ := (&KillTaskTool{}).Execute(context.Background(), map[string]any{"task_id": "t1"})
+	if !out.IsError {
+		t.Error("expected error for nil manager")
+	}
+}
+
+// processAlive reports whether pid is running, counting zombies (killed but
+// not yet reaped, common in containers) as dead.
+func processAlive(pid string) bool {
+	if stat, err := os.ReadFile("/proc/" + pid + "/stat"); err == nil {
+		fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+		return len(fields) > 0 && fields[0] != "Z"
+	} else if _, err := os.Stat("/proc/self"); err == nil {
+		return false // procfs present but no entry for pid
+	}
+	return exec.Command("kill", "-0", pid).Run() == nil
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListTasksTool lists the background tasks in the TaskManager.
+type ListTasksTool struct {
+	TaskManager *TaskManager
+}
+
+func (t *ListTasksTool) Name() string { return "ListTasks" }
+
+func (t *ListTasksTool) Description() string {
+	return `
+- Lists background tasks started with run_in_background
+- Shows each task's ID, command, status, exit code and elapsed time
+- Use this tool to find tasks that are still running, then TaskOutput to read their output or KillTask to stop them`
+}
+
+func (t *ListTasksTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type":       "object",
+		"properties": map[string]any{},
+	}
+}
+
+func (t *ListTasksTool) SideEffect() SideEffectType { return SideEffectReadOnly }
+
+func (t *ListTasksTool) Execute(_ context.Context, _ map[string]any) (ToolOutput, error) {
+	if t.TaskManager == nil {
+		return ToolOutput{Content: "Error: task manager not configured", IsError: true}, nil
+	}
+
+	tasks := t.TaskManager.List()
+	if len(tasks) == 0 {
+		return ToolOutput{Content: "No background tasks."}, nil
+	}
+
+	var b strings.Builder
+	for _, task := range tasks {
+		fmt.Fprintf(&b, "- %s [%s]", task.ID, task.Status)
+		if task.ExitCode != nil {
+			fmt.Fprintf(&b, " exit code %d", *task.ExitCode)
+		}
+		fmt.Fprintf(&b, ", %s", task.Elapsed.Round(time.Second))
+		if task.Command != "" {
+			fmt.Fprintf(&b, ": %s", truncateCommand(task.Command))
+		}
+		b.WriteString("\n")
+	}
+	return ToolOutput{Content: strings.TrimRight(b.String(), "\n")}, nil
+}
+
+// truncateCommand shortens a command to its first line, at most 200 bytes.
+func truncateCommand(command string) string {
+	const max = 200
+	command, _, multiline := strings.Cut(command, "\n")
+	if len(command) > max {
+		return command[:max] + "…"
+	}
+	if multiline {
+		return command + " …"
+	}
+	return command
+}
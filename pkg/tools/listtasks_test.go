@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListTasks_Empty(t *testing.T) {
+	tool := &ListTasksTool{TaskManager: NewTaskManager()}
+	out, err := tool.Execute(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Content != "No background tasks." {
+		t.Errorf("content = %q", out.Content)
+	}
+}
+
+func TestListTasks_ShowsStatusAndExitCode(t *testing.T) {
+	tm := NewTaskManager()
+	bash := &BashTool{TaskManager: tm}
+	bash.Execute(context.Background(), map[string]any{"command": "exit 3", "run_in_background": true})
+	bash.Execute(context.Background(), map[string]any{"command": "sleep 30", "run_in_background": true})
+	tm.StoreCompleted("stored", "truncated output")
+
+	var exited TaskInfo
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, info := range tm.List() {
+			if info.Command == "exit 3" && info.ExitCode != nil {
+				exited = info
+			}
+		}
+		if exited.ID != "" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if exited.ID == "" || *exited.ExitCode != 3 || exited.Status != TaskFailed {
+		t.Fatalf("exited task = %+v", exited)
+	}
+
+	out, _ := (&ListTasksTool{TaskManager: tm}).Execute(context.Background(), map[string]any{})
+	if strings.Contains(out.Content, "stored") {
+		t.Errorf("stored outputs should not be listed:\n%s", out.Content)
+	}
+	if !strings.Contains(out.Content, "[failed] exit code 3") || !strings.Contains(out.Content, "[running], 0s: sleep 30") {
+		t.Errorf("content:\n%s", out.Content)
+	}
+
+	for _, info := range tm.List() {
+		if info.Status == TaskRunning {
+			tm.Stop(info.ID)
+		}
+	}
+}
+
+func TestTaskManager_ListNonCommandTask(t *testing.T) {
+	tm := NewTaskManager()
+	task := tm.Launch(context.Background(), "sub", func(ctx context.Context) (string, error) {
+		return "", errors.New("boom")
+	})
+	<-task.Done
+
+	infos := tm.List()
+	if len(infos) != 1 || infos[0].ExitCode != nil || infos[0].Command != "" {
+		t.Errorf("List = %+v, want one task without an exit code", infos)
+	}
+}
+
+func TestTruncateCommand(t *testing.T) {
+	if got := truncateCommand("make test\nmake lint"); got != "make test …" {
+		t.Errorf("multi-line = %q", got)
+	}
+	if got := truncateCommand(strings.Repeat("x", 300)); len(got) != 200+len("…") {
+		t.Errorf("long command length = %d", len(got))
+	}
+}
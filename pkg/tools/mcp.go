@@ -37,11 +37,40 @@ type MCPResourceContent struct {
 	Blob     string // base64 for binary
 }
 
+// MCPPrompt describes a prompt template available from an MCP server.
+type MCPPrompt struct {
+	ServerName  string
+	Name        string
+	Description string
+	Arguments   []MCPPromptArgument
+}
+
+// MCPPromptArgument describes an argument accepted by an MCP prompt.
+type MCPPromptArgument struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// MCPPromptResult is the structured result of rendering an MCP prompt.
+type MCPPromptResult struct {
+	Description string
+	Messages    []MCPPromptMessage
+}
+
+// MCPPromptMessage is a single message of a rendered MCP prompt.
+type MCPPromptMessage struct {
+	Role    string // "user" | "assistant"
+	Content MCPContentBlock
+}
+
 // MCPClient communicates with MCP servers.
 type MCPClient interface {
 	ListResources(ctx context.Context, serverName string) ([]MCPResource, error)
 	ReadResource(ctx context.Context, serverName, uri string) (MCPResourceContent, error)
 	CallTool(ctx context.Context, serverName, toolName string, args map[string]any) (MCPToolCallResult, error)
+	ListPrompts(ctx context.Context, serverName string) ([]MCPPrompt, error)
+	GetPrompt(ctx context.Context, serverName, name string, args map[string]string) (MCPPromptResult, error)
 }
 
 // StubMCPClient returns a not-configured message for all operations.
@@ -59,6 +88,14 @@ func (s *StubMCPClient) CallTool(_ context.Context, _, _ string, _ map[string]an
 	return MCPToolCallResult{}, fmt.Errorf("MCP not configured")
 }
 
+func (s *StubMCPClient) ListPrompts(_ context.Context, _ string) ([]MCPPrompt, error) {
+	return nil, fmt.Errorf("MCP not configured")
+}
+
+func (s *StubMCPClient) GetPrompt(_ context.Context, _, _ string, _ map[string]string) (MCPPromptResult, error) {
+	return MCPPromptResult{}, fmt.Errorf("MCP not configured")
+}
+
 // ListMcpResourcesTool lists resources from MCP servers.
 type ListMcpResourcesTool struct {
 	Client MCPClient
@@ -175,3 +212,164 @@ func (r *ReadMcpResourceTool) Execute(ctx context.Context, input map[string]any)
 	}
 	return ToolOutput{Content: ""}, nil
 }
+
+// ListMcpPromptsTool lists prompt templates from MCP servers.
+type ListMcpPromptsTool struct {
+	Client MCPClient
+}
+
+func (l *ListMcpPromptsTool) Name() string { return "ListMcpPrompts" }
+
+func (l *ListMcpPromptsTool) Description() string {
+	return "Lists prompt templates available from MCP servers, with their arguments."
+}
+
+func (l *ListMcpPromptsTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"server_name": map[string]any{
+				"type":        "string",
+				"description": "MCP server name (lists all if empty)",
+			},
+		},
+	}
+}
+
+func (l *ListMcpPromptsTool) SideEffect() SideEffectType { return SideEffectNone }
+
+func (l *ListMcpPromptsTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
+	client := l.Client
+	if client == nil {
+		client = &StubMCPClient{}
+	}
+
+	serverName, _ := input["server_name"].(string)
+
+	prompts, err := client.ListPrompts(ctx, serverName)
+	if err != nil && len(prompts) == 0 {
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: %s", err),
+			IsError: true,
+		}, nil
+	}
+
+	if len(prompts) == 0 {
+		return ToolOutput{Content: "No prompts found."}, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("MCP Prompts:\n")
+	for _, p := range prompts {
+		fmt.Fprintf(&b, "- %s (server: %s)", p.Name, p.ServerName)
+		if p.Description != "" {
+			b.WriteString(": " + p.Description)
+		}
+		b.WriteString("\n")
+		for _, a := range p.Arguments {
+			req := ""
+			if a.Required {
+				req = ", required"
+			}
+			fmt.Fprintf(&b, "    - %s (string%s): %s\n", a.Name, req, a.Description)
+		}
+	}
+	if err != nil {
+		// Some servers failed; list what the others returned and say which
+		fmt.Fprintf(&b, "Errors:\n%s\n", err)
+	}
+
+	return ToolOutput{Content: strings.TrimRight(b.String(), "\n")}, nil
+}
+
+// GetMcpPromptTool renders an MCP prompt template so its messages can be
+// pulled into the conversation.
+type GetMcpPromptTool struct {
+	Client MCPClient
+}
+
+func (g *GetMcpPromptTool) Name() string { return "GetMcpPrompt" }
+
+func (g *GetMcpPromptTool) Description() string {
+	return "Gets a prompt template from an MCP server, filled in with the given arguments."
+}
+
+func (g *GetMcpPromptTool) InputSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"server_name": map[string]any{
+				"type":        "string",
+				"description": "The MCP server name",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "The prompt name",
+			},
+			"arguments": map[string]any{
+				"type":                 "object",
+				"description":          "Prompt arguments as string values",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+		},
+		"required": []string{"server_name", "name"},
+	}
+}
+
+func (g *GetMcpPromptTool) SideEffect() SideEffectType { return SideEffectNone }
+
+func (g *GetMcpPromptTool) Execute(ctx context.Context, input map[string]any) (ToolOutput, error) {
+	client := g.Client
+	if client == nil {
+		client = &StubMCPClient{}
+	}
+
+	serverName, ok := input["server_name"].(string)
+	if !ok || serverName == "" {
+		return ToolOutput{Content: "Error: server_name is required", IsError: true}, nil
+	}
+
+	name, ok := input["name"].(string)
+	if !ok || name == "" {
+		return ToolOutput{Content: "Error: name is required", IsError: true}, nil
+	}
+
+	var args map[string]string
+	if raw, ok := input["arguments"].(map[string]any); ok {
+		args = make(map[string]string, len(raw))
+		for k, v := range raw {
+			if s, ok := v.(string); ok {
+				args[k] = s
+			} else {
+				args[k] = fmt.Sprint(v)
+			}
+		}
+	}
+
+	result, err := client.GetPrompt(ctx, serverName, name, args)
+	if err != nil {
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: %s", err),
+			IsError: true,
+		}, nil
+	}
+
+	var b strings.Builder
+	if result.Description != "" {
+		b.WriteString(result.Description + "\n\n")
+	}
+	for _, m := range result.Messages {
+		fmt.Fprintf(&b, "[%s]\n", m.Role)
+		switch {
+		case m.Content.Text != "":
+			b.WriteString(m.Content.Text)
+		case m.Content.Type == "image":
+			fmt.Fprintf(&b, "[image: %s]", m.Content.MimeType)
+		case m.Content.URI != "":
+			fmt.Fprintf(&b, "[resource: %s]", m.Content.URI)
+		}
+		b.WriteString("\n\n")
+	}
+
+	return ToolOutput{Content: strings.TrimRight(b.String(), "\n")}, nil
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -10,6 +11,9 @@ type mockMCPClient struct {
 	resources       []MCPResource
 	resourceContent MCPResourceContent
 	toolResult      MCPToolCallResult
+	prompts         []MCPPrompt
+	promptResult    MCPPromptResult
+	promptArgs      map[string]string
 	err             error
 }
 
@@ -25,6 +29,15 @@ func (m *mockMCPClient) CallTool(_ context.Context, _, _ string, _ map[string]an
 	return m.toolResult, m.err
 }
 
+func (m *mockMCPClient) ListPrompts(_ context.Context, _ string) ([]MCPPrompt, error) {
+	return m.prompts, m.err
+}
+
+func (m *mockMCPClient) GetPrompt(_ context.Context, _, _ string, args map[string]string) (MCPPromptResult, error) {
+	m.promptArgs = args
+	return m.promptResult, m.err
+}
+
 func TestMCP_ListResources(t *testing.T) {
 	client := &mockMCPClient{
 		resources: []MCPResource{
@@ -106,3 +119,89 @@ func TestMCP_ReadResourceStub(t *testing.T) {
 		t.Error("expected error from stub client")
 	}
 }
+
+func TestMCP_ListPrompts(t *testing.T) {
+	client := &mockMCPClient{prompts: []MCPPrompt{{
+		ServerName:  "docs",
+		Name:        "summarize",
+		Description: "Summarize a document",
+		Arguments:   []MCPPromptArgument{{Name: "path", Description: "Document path", Required: true}},
+	}}}
+	tool := &ListMcpPromptsTool{Client: client}
+	out, err := tool.Execute(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	for _, want := range []string{"summarize (server: docs): Summarize a document", "path (string, required): Document path"} {
+		if !strings.Contains(out.Content, want) {
+			t.Errorf("output missing %q:\n%s", want, out.Content)
+		}
+	}
+}
+
+func TestMCP_ListPromptsPartial(t *testing.T) {
+	client := &mockMCPClient{
+		prompts: []MCPPrompt{{ServerName: "docs", Name: "summarize"}},
+		err:     errors.New(`server "broken": connection lost`),
+	}
+	tool := &ListMcpPromptsTool{Client: client}
+	out, err := tool.Execute(context.Background(), map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.IsError {
+		t.Fatalf("unexpected error: %s", out.Content)
+	}
+	for _, want := range []string{"summarize (server: docs)", `server "broken": connection lost`} {
+		if !strings.Contains(out.Content, want) {
+			t.Errorf("output missing %q:\n%s", want, out.Content)
+		}
+	}
+}
+
+func TestMCP_ListPromptsStub(t *testing.T) {
+	tool := &ListMcpPromptsTool{}
+	out, _ := tool.Execute(context.Background(), map[string]any{})
+	if !out.IsError || !strings.Contains(out.Content, "MCP not configured") {
+		t.Errorf("expected 'MCP not configured' error, got %q", out.Content)
+	}
+}
+
+func TestMCP_GetPrompt(t *testing.T) {
+	client := &mockMCPClient{promptResult: MCPPromptResult{
+		Description: "Review instructions",
+		Messages: []MCPPromptMessage{
+			{Role: "user", Content: MCPContentBlock{Type: "text", Text: "Review main.go"}},
+			{Role: "assistant", Content: MCPContentBlock{Type: "image", MimeType: "image/png"}},
+		},
+	}}
+	tool := &GetMcpPromptTool{Client: client}
+	out, err := tool.Execute(context.Background(), map[string]any{
+		"server_name": "docs",
+		"name":        "review",
+		"arguments":   map[string]any{"file": "main.go", "depth": float64(2)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Review instructions\n\n[user]\nReview main.go\n\n[assistant]\n[image: image/png]"
+	if out.Content != want {
+		t.Errorf("content = %q, want %q", out.Content, want)
+	}
+	if client.promptArgs["file"] != "main.go" || client.promptArgs["depth"] != "2" {
+		t.Errorf("arguments = %v", client.promptArgs)
+	}
+}
+
+func TestMCP_GetPromptMissingParams(t *testing.T) {
+	tool := &GetMcpPromptTool{Client: &mockMCPClient{}}
+	for _, input := range []map[string]any{{"name": "review"}, {"server_name": "docs"}} {
+		out, _ := tool.Execute(context.Background(), input)
+		if !out.IsError {
+			t.Errorf("input %v: expected error", input)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ToolHandler runs one tool call.
+type ToolHandler func(ctx context.Context, input map[string]any) (ToolOutput, error)
+
+// ToolMiddleware wraps the execution of tool. It may inspect or replace the
+// input before calling next, inspect or replace the output after, or return
+// without calling next to short-circuit the call.
+type ToolMiddleware func(tool Tool, next ToolHandler) ToolHandler
+
+// Use adds middleware applied to every tool the registry runs, built-in and
+// MCP alike. Middleware runs in registration order: the first added sees the
+// call first and the output last.
+func (r *Registry) Use(mw ...ToolMiddleware) {
+	r.middleware = append(r.middleware, mw...)
+}
+
+// Middleware returns the middleware added with Use, in order.
+func (r *Registry) Middleware() []ToolMiddleware {
+	return slices.Clone(r.middleware)
+}
+
+// Wrap returns execute, which runs tool, wrapped in the registry's middleware.
+func (r *Registry) Wrap(tool Tool, execute ToolHandler) ToolHandler {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		execute = r.middleware[i](tool, execute)
+	}
+	return execute
+}
+
+// ValidateInput rejects calls whose input does not match the tool's
+// InputSchema with an error output, without running the tool, so the model
+// can correct its call. Useful for MCP tools, whose schemas are not enforced
+// on this side.
+func ValidateInput() ToolMiddleware {
+	return func(tool Tool, next ToolHandler) ToolHandler {
+		return func(ctx context.Context, input map[string]any) (ToolOutput, error) {
+			if err := ValidateSchema(input, tool.InputSchema()); err != nil {
+				return ToolOutput{Content: fmt.Sprintf("Error: invalid input for %s: %s. Fix the arguments to match the tool's input schema and retry.", tool.Name(), err), IsError: true}, nil
+			}
+			return next(ctx, input)
+		}
+	}
+}
+
+// LogTiming logs each call's tool name, duration and outcome with logf
+// (nil = log.Printf).
+func LogTiming(logf func(format string, args ...any)) ToolMiddleware {
+	if logf == nil {
+		logf = log.Printf
+	}
+	return func(tool Tool, next ToolHandler) ToolHandler {
+		return func(ctx context.Context, input map[string]any) (ToolOutput, error) {
+			start := time.Now()
+			output, err := next(ctx, input)
+			elapsed := time.Since(start).Round(time.Millisecond)
+			switch {
+			case err != nil:
+				logf("tool %s failed after %s: %v", tool.Name(), elapsed, err)
+			case output.IsError:
+				logf("tool %s returned an error after %s", tool.Name(), elapsed)
+			default:
+				logf("tool %s completed in %s (%d bytes)", tool.Name(), elapsed, len(output.Content))
+			}
+			return output, err
+		}
+	}
+}
+
+// redactedSecret replaces secrets removed by RedactSecrets.
+const redactedSecret = "[REDACTED]"
+
+// DefaultSecretPatterns match common credential formats. For patterns with a
+// capture group only the group is redacted, keeping e.g. the variable name in
+// KEY=secret.
+var DefaultSecretPatterns = []*regexp.Regexp{
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// GitHub tokens
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	// OpenAI and Anthropic-style API keys
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+	// Slack tokens
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	// Authorization: Bearer <token>
+	regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]{8,})`),
+	// .env-style assignments such as GITHUB_TOKEN=... or DB_PASSWORD="..."
+	regexp.MustCompile(`\b[A-Z0-9_]*(?:SECRET|TOKEN|PASSWORD|PASSWD|API_?KEY|ACCESS_KEY)[A-Z0-9_]*=["']?([^\s"']{4,})`),
+}
+
+// RedactSecrets replaces text matching patterns (default
+// DefaultSecretPatterns) in tool output before it reaches the model,
+// transcripts or hooks.
+func RedactSecrets(patterns ...*regexp.Regexp) ToolMiddleware {
+	r := NewSecretRedactor(patterns)
+	return func(tool Tool, next ToolHandler) ToolHandler {
+		return func(ctx context.Context, input map[string]any) (ToolOutput, error) {
+			output, err := next(ctx, input)
+			output.Content, _ = r.Redact(output.Content)
+			return output, err
+		}
+	}
+}
+
+// SecretRedactor replaces secrets in text with [REDACTED]. It is safe for
+// concurrent use.
+type SecretRedactor struct {
+	patterns  []*regexp.Regexp
+	allowlist []*regexp.Regexp
+	any       *regexp.Regexp // union of patterns: text without secrets costs one scan
+}
+
+// NewSecretRedactor redacts text matching patterns (default
+// DefaultSecretPatterns). A match that any allowlist pattern matches, such as
+// a documented example key, is kept.
+func NewSecretRedactor(patterns []*regexp.Regexp, allowlist ...*regexp.Regexp) *SecretRedactor {
+	if len(patterns) == 0 {
+		patterns = DefaultSecretPatterns
+	}
+	union := make([]string, len(patterns))
+	for i, re := range patterns {
+		union[i] = "(?:" + re.String() + ")"
+	}
+	return &SecretRedactor{
+		patterns:  patterns,
+		allowlist: allowlist,
+		any:       regexp.MustCompile(strings.Join(union, "|")),
+	}
+}
+
+// Redact returns s with secrets replaced, and whether any were found.
+func (r *SecretRedactor) Redact(s string) (string, bool) {
+	if !r.any.MatchString(s) {
+		return s, false
+	}
+	redacted := false
+	for _, re := range r.patterns {
+		group := 0
+		if re.NumSubexp() > 0 {
+			group = 1 // only the secret, keeping e.g. the variable name
+		}
+		matches := re.FindAllStringSubmatchIndex(s, -1)
+		for i := len(matches) - 1; i >= 0; i-- { // back to front keeps indexes valid
+			start, end := matches[i][2*group], matches[i][2*group+1]
+			if start < 0 || s[start:end] == redactedSecret || r.allowed(s[start:end]) {
+				continue
+			}
+			s = s[:start] + redactedSecret + s[end:]
+			redacted = true
+		}
+	}
+	return s, redacted
+}
+
+func (r *SecretRedactor) allowed(secret string) bool {
+	for _, re := range r.allowlist {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
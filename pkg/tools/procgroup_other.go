@@ -0,0 +1,23 @@
+//go:build !unix
+
+package tools
+
+import (
+	"os/exec"
+	"time"
+)
+
+// processWaitDelay bounds how long a cancelled command may keep its output
+// pipes open after being killed.
+const processWaitDelay = 2 * time.Second
+
+// setProcessGroup only bounds the wait for output on platforms without
+// process groups; cancellation kills the command itself.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.WaitDelay = processWaitDelay
+}
+
+// killProcessGroup kills the command itself.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,28 @@
+//go:build unix
+
+package tools
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// processWaitDelay bounds how long a cancelled command may keep its output
+// pipes open after being killed.
+const processWaitDelay = 2 * time.Second
+
+// setProcessGroup runs cmd in its own process group and makes context
+// cancellation kill the whole group, so children the command started (e.g.
+// `server & watcher`) die with it instead of holding its output open.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+	cmd.WaitDelay = processWaitDelay
+}
+
+// killProcessGroup kills the process group of a command started with
+// setProcessGroup.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -1,16 +1,20 @@
 package tools
 
 import (
+	"path"
 	"sort"
+	"strings"
 
 	"github.com/jg-phare/goat/pkg/llm"
 )
 
 // Registry holds available tools and resolves them by name.
 type Registry struct {
-	tools    map[string]Tool
-	allowed  map[string]bool // auto-allowed tools (no permission prompt)
-	disabled map[string]bool // explicitly disallowed
+	tools            map[string]Tool
+	allowed          map[string]bool  // auto-allowed tools (no permission prompt)
+	disabled         map[string]bool  // explicitly disallowed
+	disabledPatterns []string         // disallowed name globs, e.g. "mcp__github__*"
+	middleware       []ToolMiddleware // see Use
 }
 
 // RegistryOption configures a Registry.
@@ -25,11 +29,17 @@ func WithAllowed(names ...string) RegistryOption {
 	}
 }
 
-// WithDisabled marks tool names as disabled.
+// WithDisabled marks tool names as disabled. A name containing "*" is a glob
+// (path.Match syntax) that also covers tools registered later, such as MCP
+// tools discovered on connect.
 func WithDisabled(names ...string) RegistryOption {
 	return func(r *Registry) {
 		for _, n := range names {
-			r.disabled[n] = true
+			if strings.Contains(n, "*") {
+				r.disabledPatterns = append(r.disabledPatterns, n)
+			} else {
+				r.disabled[n] = true
+			}
 		}
 	}
 }
@@ -65,14 +75,34 @@ func (r *Registry) IsAllowed(name string) bool {
 
 // IsDisabled returns true if the tool is explicitly disallowed.
 func (r *Registry) IsDisabled(name string) bool {
-	return r.disabled[name]
+	if r.disabled[name] {
+		return true
+	}
+	for _, pattern := range r.disabledPatterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
 }
 
 // Names returns all registered tool names in sorted order.
 func (r *Registry) Names() []string {
 	names := make([]string, 0, len(r.tools))
 	for name := range r.tools {
-		if !r.disabled[name] {
+		if !r.IsDisabled(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DisabledNames returns the registered tools that are disabled, sorted.
+func (r *Registry) DisabledNames() []string {
+	var names []string
+	for name := range r.tools {
+		if r.IsDisabled(name) {
 			names = append(names, name)
 		}
 	}
@@ -135,6 +135,50 @@ func TestRegistry_LLMTools(t *testing.T) {
 	}
 }
 
+func TestRegistry_DisabledPattern(t *testing.T) {
+	r := NewRegistry(WithDisabled("mcp__github__*"))
+	r.Register(&stubTool{name: "Grep"})
+	r.Register(&stubTool{name: "mcp__github__create_issue"})
+	r.Register(&stubTool{name: "mcp__gitlab__create_issue"})
+
+	if !r.IsDisabled("mcp__github__create_issue") || r.IsDisabled("mcp__gitlab__create_issue") {
+		t.Error("pattern should disable only the github server's tools")
+	}
+	names := r.Names()
+	if len(names) != 2 || names[0] != "Grep" || names[1] != "mcp__gitlab__create_issue" {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestRegistry_DisabledNames(t *testing.T) {
+	r := NewRegistry(WithDisabled("Bash", "mcp__github__*"))
+	r.Register(&stubTool{name: "Grep"})
+	r.Register(&stubTool{name: "mcp__github__create_issue"})
+	r.Register(&stubTool{name: "Bash"})
+
+	names := r.DisabledNames()
+	if len(names) != 2 || names[0] != "Bash" || names[1] != "mcp__github__create_issue" {
+		t.Errorf("DisabledNames() = %v", names)
+	}
+}
+
+func TestSideEffectType_String(t *testing.T) {
+	tests := map[SideEffectType]string{
+		SideEffectNone:     "none",
+		SideEffectReadOnly: "read_only",
+		SideEffectMutating: "mutating",
+		SideEffectNetwork:  "network",
+		SideEffectBlocking: "blocking",
+		SideEffectSpawns:   "spawns",
+		SideEffectType(99): "unknown",
+	}
+	for se, want := range tests {
+		if got := se.String(); got != want {
+			t.Errorf("SideEffectType(%d).String() = %q, want %q", se, got, want)
+		}
+	}
+}
+
 func TestRegistry_DisabledExcludedFromDefinitions(t *testing.T) {
 	r := NewRegistry(WithDisabled("Bash"))
 	r.Register(&stubTool{name: "Bash", description: "Execute commands"})
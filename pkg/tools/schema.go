@@ -0,0 +1,122 @@
+package tools
+
+import (
+	"fmt"
+	"slices"
+)
+
+// ValidateSchema checks value against the subset of JSON Schema used by tool
+// input schemas and output formats: type, enum, required, properties and
+// items. Errors name the offending path, rooted at "$".
+func ValidateSchema(value any, schema map[string]any) error {
+	return validateSchema(value, schema, "$")
+}
+
+func validateSchema(value any, schema map[string]any, path string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	if typ, ok := schema["type"].(string); ok && !matchesSchemaType(value, typ) {
+		return fmt.Errorf("%s: expected %s, got %s", path, typ, jsonTypeName(value))
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(value) }) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range schemaRequired(schema) {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		props, _ := schema["properties"].(map[string]any)
+		for name, propSchema := range props {
+			ps, _ := propSchema.(map[string]any)
+			if pv, ok := v[name]; ok {
+				if err := validateSchema(pv, ps, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i, item := range v {
+			if err := validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// schemaRequired reads "required", which may hold []string or []any.
+func schemaRequired(schema map[string]any) []string {
+	switch req := schema["required"].(type) {
+	case []string:
+		return req
+	case []any:
+		names := make([]string, 0, len(req))
+		for _, r := range req {
+			if s, ok := r.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// matchesSchemaType accepts decoded JSON values and, since hooks and callers
+// may build inputs in Go, native integer types for numbers.
+func matchesSchemaType(value any, typ string) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, isFloat := value.(float64)
+		return isFloat || isGoInt(value)
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == float64(int64(f)) || isGoInt(value)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true // unknown types are not enforced
+}
+
+func isGoInt(value any) bool {
+	switch value.(type) {
+	case int, int32, int64:
+		return true
+	}
+	return false
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64, int, int32, int64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -25,6 +25,7 @@ func (a *SkillProviderAdapter) GetSkillInfo(name string) (SkillInfo, bool) {
 		Body:         entry.Body,
 		AllowedTools: entry.AllowedTools,
 		Arguments:    entry.Arguments,
+		Args:         entry.Args,
 		Context:      entry.Context,
 	}, true
 }
@@ -3,6 +3,8 @@ package tools
 import (
 	"context"
 	"fmt"
+
+	"github.com/jg-phare/goat/pkg/types"
 )
 
 // SkillInfo holds the data the SkillTool needs to execute a skill.
@@ -12,7 +14,8 @@ type SkillInfo struct {
 	Body         string
 	AllowedTools []string
 	Arguments    []string
-	Context      string // "inline" or "fork"
+	Args         []types.SkillArgument // typed schema, validated by TypedArgSubstituter
+	Context      string                // "inline" or "fork"
 }
 
 // SkillProvider resolves skill names to their definitions.
@@ -28,6 +31,11 @@ type SkillTool struct {
 	// ArgSubstituter is an optional function for argument substitution.
 	// Signature: func(body string, argDefs []string, argsStr string) (string, error)
 	ArgSubstituter func(body string, argDefs []string, argsStr string) (string, error)
+
+	// TypedArgSubstituter validates args against a skill's args: schema and
+	// substitutes them, applying defaults. It takes precedence over
+	// ArgSubstituter for skills that declare a schema.
+	TypedArgSubstituter func(body string, schema []types.SkillArgument, argsStr string) (string, error)
 }
 
 func (s *SkillTool) Name() string { return "Skill" }
@@ -98,8 +106,18 @@ func (s *SkillTool) Execute(ctx context.Context, input map[string]any) (ToolOutp
 
 	body := skill.Body
 
-	// Argument substitution (wired in Phase 5)
-	if s.ArgSubstituter != nil && len(skill.Arguments) > 0 && args != "" {
+	// Argument substitution (wired in Phase 5). A typed schema is checked
+	// even without args so missing required arguments are reported.
+	if s.TypedArgSubstituter != nil && len(skill.Args) > 0 {
+		substituted, err := s.TypedArgSubstituter(body, skill.Args, args)
+		if err != nil {
+			return ToolOutput{
+				Content: fmt.Sprintf("Error: invalid arguments for skill %q: %v", skill.Name, err),
+				IsError: true,
+			}, nil
+		}
+		body = substituted
+	} else if s.ArgSubstituter != nil && len(skill.Arguments) > 0 && args != "" {
 		substituted, err := s.ArgSubstituter(body, skill.Arguments, args)
 		if err != nil {
 			return ToolOutput{
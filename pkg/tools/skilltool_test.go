@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"testing"
+
+	"github.com/jg-phare/goat/pkg/types"
 )
 
 // mockSkillProvider implements SkillProvider for tests.
@@ -191,6 +193,44 @@ func TestSkillTool_ArgSubstituterError(t *testing.T) {
 	}
 }
 
+func TestSkillTool_TypedArgs(t *testing.T) {
+	provider := &mockSkillProvider{
+		skills: map[string]SkillInfo{
+			"deploy": {
+				Name:      "deploy",
+				Body:      "Deploy to $environment",
+				Arguments: []string{"environment"},
+				Args:      []types.SkillArgument{{Name: "environment", Required: true}},
+			},
+		},
+	}
+
+	var gotArgs string
+	tool := &SkillTool{
+		Skills: provider,
+		ArgSubstituter: func(body string, argDefs []string, argsStr string) (string, error) {
+			t.Error("ArgSubstituter called for a skill with a typed schema")
+			return body, nil
+		},
+		TypedArgSubstituter: func(body string, schema []types.SkillArgument, argsStr string) (string, error) {
+			gotArgs = argsStr
+			return "", fmt.Errorf("missing required environment")
+		},
+	}
+
+	// Validation runs even when no args are given.
+	out, err := tool.Execute(context.Background(), map[string]any{"skill": "deploy"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.IsError || out.Content != `Error: invalid arguments for skill "deploy": missing required environment` {
+		t.Errorf("out = %+v", out)
+	}
+	if gotArgs != "" {
+		t.Errorf("argsStr = %q, want empty", gotArgs)
+	}
+}
+
 func TestSkillTool_ForkExecution(t *testing.T) {
 	provider := &mockSkillProvider{
 		skills: map[string]SkillInfo{
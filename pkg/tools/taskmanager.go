@@ -4,9 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"os/exec"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"crypto/rand"
 	"encoding/hex"
@@ -37,35 +41,82 @@ func (s TaskStatus) String() string {
 	}
 }
 
-// taskOutput accumulates output from a background task in a thread-safe way.
+const (
+	// maxTaskOutputBytes bounds the output kept per task; older output is dropped.
+	maxTaskOutputBytes = 1 << 20
+	// maxFinishedTasks bounds how many finished tasks are kept for TaskOutput;
+	// the oldest are evicted first.
+	maxFinishedTasks = 100
+)
+
+// taskOutput accumulates output from a background task in a thread-safe way,
+// keeping the last maxTaskOutputBytes unless keepAll is set.
 type taskOutput struct {
 	mu      sync.Mutex
-	content strings.Builder
+	content []byte
+	dropped int  // bytes discarded from the front
+	keepAll bool // never drop output, e.g. the full text behind a truncated tool result
 }
 
 func (o *taskOutput) Write(s string) {
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	o.content.WriteString(s)
+	o.content = append(o.content, s...)
+	// Trim in batches so a stream of small writes doesn't copy on each one
+	if len(o.content) > maxTaskOutputBytes+maxTaskOutputBytes/4 {
+		o.trim()
+	}
 }
 
 func (o *taskOutput) String() string {
 	o.mu.Lock()
 	defer o.mu.Unlock()
-	return o.content.String()
+	o.trim()
+	if o.dropped > 0 {
+		return fmt.Sprintf("[... %d earlier bytes dropped ...]\n%s", o.dropped, o.content)
+	}
+	return string(o.content)
+}
+
+// trim drops the oldest output beyond maxTaskOutputBytes, without splitting
+// a UTF-8 sequence. The caller holds o.mu.
+func (o *taskOutput) trim() {
+	if o.keepAll || len(o.content) <= maxTaskOutputBytes {
+		return
+	}
+	cut := len(o.content) - maxTaskOutputBytes
+	for cut < len(o.content) && !utf8.RuneStart(o.content[cut]) {
+		cut++
+	}
+	o.dropped += cut
+	o.content = append([]byte(nil), o.content[cut:]...)
+}
+
+// writer returns an io.Writer appending to o, for streaming command output.
+func (o *taskOutput) writer() io.Writer { return taskOutputWriter{o} }
+
+type taskOutputWriter struct{ o *taskOutput }
+
+func (w taskOutputWriter) Write(p []byte) (int, error) {
+	w.o.Write(string(p))
+	return len(p), nil
 }
 
 // BackgroundTask represents a running or completed background task.
 type BackgroundTask struct {
-	ID        string
-	Status    TaskStatus
-	Output    *taskOutput
-	Cancel    context.CancelFunc
-	Done      chan struct{}
-	StartedAt time.Time
-	Error     error
-
-	mu sync.Mutex // protects Status and Error
+	ID         string
+	Command    string // shell command, empty for non-command tasks
+	Status     TaskStatus
+	Output     *taskOutput
+	Cancel     context.CancelFunc
+	Done       chan struct{}
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ExitCode   *int // set when a command task finishes
+	Error      error
+	stored     bool // saved output from StoreCompleted, not a launched task
+
+	mu sync.Mutex // protects Status, FinishedAt, ExitCode and Error
 }
 
 func (t *BackgroundTask) setStatus(s TaskStatus) {
@@ -74,6 +125,24 @@ func (t *BackgroundTask) setStatus(s TaskStatus) {
 	t.Status = s
 }
 
+// finish records that the task's function returned with err.
+func (t *BackgroundTask) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.FinishedAt = time.Now()
+	if t.Command == "" {
+		return
+	}
+	code := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode() // -1 if killed by a signal
+	} else if err != nil {
+		code = -1
+	}
+	t.ExitCode = &code
+}
+
 func (t *BackgroundTask) setError(err error) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -86,6 +155,12 @@ func (t *BackgroundTask) getStatus() TaskStatus {
 	return t.Status
 }
 
+func (t *BackgroundTask) getFinishedAt() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.FinishedAt
+}
+
 func (t *BackgroundTask) getError() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -116,10 +191,17 @@ func generateID() string {
 // should return the task's output string (or error). The returned BackgroundTask
 // can be used to track status.
 func (tm *TaskManager) Launch(ctx context.Context, id string, fn func(ctx context.Context) (string, error)) *BackgroundTask {
+	return tm.LaunchCommand(ctx, id, "", fn)
+}
+
+// LaunchCommand is Launch for a task that runs a shell command, recording the
+// command and its exit code for ListTasks.
+func (tm *TaskManager) LaunchCommand(ctx context.Context, id, command string, fn func(ctx context.Context) (string, error)) *BackgroundTask {
 	taskCtx, cancel := context.WithCancel(ctx)
 
 	task := &BackgroundTask{
 		ID:        id,
+		Command:   command,
 		Status:    TaskRunning,
 		Output:    &taskOutput{},
 		Cancel:    cancel,
@@ -127,15 +209,15 @@ func (tm *TaskManager) Launch(ctx context.Context, id string, fn func(ctx contex
 		StartedAt: time.Now(),
 	}
 
-	tm.mu.Lock()
-	tm.tasks[id] = task
-	tm.mu.Unlock()
+	tm.add(task)
 
 	go func() {
 		defer close(task.Done)
 		result, err := fn(taskCtx)
+		task.finish(err)
 		if err != nil {
-			if errors.Is(err, context.Canceled) {
+			// A killed process reports "signal: killed", not context.Canceled
+			if errors.Is(err, context.Canceled) || errors.Is(taskCtx.Err(), context.Canceled) {
 				task.setStatus(TaskStopped)
 			} else {
 				task.setStatus(TaskFailed)
@@ -151,6 +233,51 @@ func (tm *TaskManager) Launch(ctx context.Context, id string, fn func(ctx contex
 	return task
 }
 
+// StoreCompleted records an already-finished task holding output, so that
+// content cut from a tool result stays retrievable via TaskOutput. The whole
+// output is kept, however long, so TaskOutput offsets match the original.
+func (tm *TaskManager) StoreCompleted(id, output string) *BackgroundTask {
+	now := time.Now()
+	task := &BackgroundTask{
+		ID:         id,
+		Status:     TaskCompleted,
+		Output:     &taskOutput{keepAll: true},
+		Cancel:     func() {},
+		Done:       make(chan struct{}),
+		StartedAt:  now,
+		FinishedAt: now,
+		stored:     true,
+	}
+	task.Output.Write(output)
+	close(task.Done)
+
+	tm.add(task)
+	return task
+}
+
+// add registers task, evicting the oldest finished tasks beyond maxFinishedTasks.
+func (tm *TaskManager) add(task *BackgroundTask) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.tasks[task.ID] = task
+
+	var finished []*BackgroundTask
+	for _, t := range tm.tasks {
+		if t.getStatus() != TaskRunning {
+			finished = append(finished, t)
+		}
+	}
+	if len(finished) <= maxFinishedTasks {
+		return
+	}
+	slices.SortFunc(finished, func(a, b *BackgroundTask) int {
+		return a.getFinishedAt().Compare(b.getFinishedAt())
+	})
+	for _, t := range finished[:len(finished)-maxFinishedTasks] {
+		delete(tm.tasks, t.ID)
+	}
+}
+
 // Get retrieves a background task by ID.
 func (tm *TaskManager) Get(id string) (*BackgroundTask, bool) {
 	tm.mu.RLock()
@@ -159,6 +286,59 @@ func (tm *TaskManager) Get(id string) (*BackgroundTask, bool) {
 	return t, ok
 }
 
+// TaskInfo is a snapshot of a background task for listing.
+type TaskInfo struct {
+	ID        string
+	Command   string
+	Status    TaskStatus
+	ExitCode  *int
+	StartedAt time.Time
+	Elapsed   time.Duration // run time so far, or total run time once finished
+}
+
+// Info returns a snapshot of the task's current state.
+func (t *BackgroundTask) Info() TaskInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	end := t.FinishedAt
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return TaskInfo{
+		ID:        t.ID,
+		Command:   t.Command,
+		Status:    t.Status,
+		ExitCode:  t.ExitCode,
+		StartedAt: t.StartedAt,
+		Elapsed:   end.Sub(t.StartedAt),
+	}
+}
+
+// List returns a snapshot of every launched task, oldest first. Outputs
+// saved with StoreCompleted are not included.
+func (tm *TaskManager) List() []TaskInfo {
+	tm.mu.RLock()
+	tasks := make([]*BackgroundTask, 0, len(tm.tasks))
+	for _, t := range tm.tasks {
+		if !t.stored {
+			tasks = append(tasks, t)
+		}
+	}
+	tm.mu.RUnlock()
+
+	infos := make([]TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		infos = append(infos, t.Info())
+	}
+	slices.SortFunc(infos, func(a, b TaskInfo) int {
+		if c := a.StartedAt.Compare(b.StartedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return infos
+}
+
 // GetOutput retrieves the output of a background task.
 // If block is true, waits for the task to finish (up to timeout).
 // If block is false, returns current partial output immediately.
@@ -191,6 +371,31 @@ func (tm *TaskManager) GetOutput(id string, block bool, timeout time.Duration) (
 	return output, nil
 }
 
+// StopAll cancels every running task and waits for them to exit, or for ctx
+// to expire.
+func (tm *TaskManager) StopAll(ctx context.Context) error {
+	tm.mu.RLock()
+	var running []*BackgroundTask
+	for _, task := range tm.tasks {
+		if task.getStatus() == TaskRunning {
+			running = append(running, task)
+		}
+	}
+	tm.mu.RUnlock()
+
+	for _, task := range running {
+		task.Cancel()
+	}
+	for _, task := range running {
+		select {
+		case <-task.Done:
+		case <-ctx.Done():
+			return fmt.Errorf("stopping background tasks: %w", ctx.Err())
+		}
+	}
+	return nil
+}
+
 // Stop cancels a running background task.
 func (tm *TaskManager) Stop(id string) error {
 	task, ok := tm.Get(id)
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"testing"
@@ -86,6 +87,48 @@ func TestTaskManager_Stop(t *testing.T) {
 	}
 }
 
+func TestTaskManager_StopAll(t *testing.T) {
+	tm := NewTaskManager()
+	for _, id := range []string{"t1", "t2"} {
+		tm.Launch(context.Background(), id, func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		})
+	}
+	tm.StoreCompleted("t3", "done")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tm.StopAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"t1", "t2"} {
+		task, _ := tm.Get(id)
+		if task.getStatus() != TaskStopped {
+			t.Errorf("%s: expected stopped, got %s", id, task.getStatus())
+		}
+	}
+	if task, _ := tm.Get("t3"); task.getStatus() != TaskCompleted {
+		t.Errorf("t3: expected completed, got %s", task.getStatus())
+	}
+}
+
+func TestTaskManager_StopAllTimeout(t *testing.T) {
+	tm := NewTaskManager()
+	release := make(chan struct{})
+	defer close(release)
+	tm.Launch(context.Background(), "t1", func(ctx context.Context) (string, error) {
+		<-release // ignores cancellation
+		return "", nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := tm.StopAll(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want deadline exceeded", err)
+	}
+}
+
 func TestTaskManager_TimeoutOnGetOutput(t *testing.T) {
 	tm := NewTaskManager()
 	tm.Launch(context.Background(), "t1", func(ctx context.Context) (string, error) {
@@ -202,3 +245,60 @@ func TestTaskStatus_String(t *testing.T) {
 		}
 	}
 }
+
+func TestTaskOutput_KeepsTail(t *testing.T) {
+	o := &taskOutput{}
+	chunk := strings.Repeat("x", 64*1024)
+	for range 2 * maxTaskOutputBytes / len(chunk) {
+		o.Write(chunk)
+	}
+	o.Write("é-end")
+
+	got := o.String()
+	if !strings.HasPrefix(got, "[... ") || !strings.HasSuffix(got, "é-end") {
+		t.Errorf("output should keep the tail behind a drop marker, got %q...%q", got[:40], got[len(got)-10:])
+	}
+	if len(got) > maxTaskOutputBytes+64 {
+		t.Errorf("kept %d bytes, want at most about %d", len(got), maxTaskOutputBytes)
+	}
+}
+
+func TestTaskManager_StoreCompletedKeepsAll(t *testing.T) {
+	tm := NewTaskManager()
+	output := strings.Repeat("x", 2*maxTaskOutputBytes) + "end"
+	tm.StoreCompleted("big", output)
+
+	got, err := tm.GetOutput("big", false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != output {
+		t.Errorf("stored output has %d bytes, want all %d", len(got), len(output))
+	}
+}
+
+func TestTaskManager_EvictsFinishedTasks(t *testing.T) {
+	tm := NewTaskManager()
+	running := tm.Launch(context.Background(), "running", func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	defer running.Cancel()
+
+	for i := range maxFinishedTasks + 5 {
+		tm.StoreCompleted(fmt.Sprintf("out-%d", i), "saved")
+		time.Sleep(time.Microsecond) // distinct finish times
+	}
+	if _, ok := tm.Get("out-0"); ok {
+		t.Error("oldest finished task should be evicted")
+	}
+	if _, ok := tm.Get(fmt.Sprintf("out-%d", maxFinishedTasks+4)); !ok {
+		t.Error("newest finished task should be kept")
+	}
+	if _, ok := tm.Get("running"); !ok {
+		t.Error("running task must not be evicted")
+	}
+	if n := len(tm.tasks); n != maxFinishedTasks+1 {
+		t.Errorf("tasks = %d, want %d finished plus the running one", n, maxFinishedTasks)
+	}
+}
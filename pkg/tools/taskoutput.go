@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"time"
+	"unicode/utf8"
 )
 
+// taskOutputPageBytes bounds the output returned by one TaskOutput call.
+const taskOutputPageBytes = 30000
+
 // TaskOutputTool retrieves output from a background task.
 type TaskOutputTool struct {
 	TaskManager *TaskManager
@@ -19,6 +23,7 @@ func (t *TaskOutputTool) Description() string {
 - Returns the task output along with status information
 - Use block=true (default) to wait for task completion
 - Use block=false for non-blocking check of current status
+- Long output is returned in pages; pass the offset given at the end of a page to read the next
 - Task IDs can be found using the /tasks command
 - Works with all task types: background shells, async agents, and remote sessions`
 }
@@ -39,6 +44,10 @@ func (t *TaskOutputTool) InputSchema() map[string]any {
 				"type":        "number",
 				"description": "Max wait time in ms (default 30000)",
 			},
+			"offset": map[string]any{
+				"type":        "number",
+				"description": "Byte offset in the output to start from (default 0)",
+			},
 		},
 		"required": []string{"task_id"},
 	}
@@ -66,7 +75,13 @@ func (t *TaskOutputTool) Execute(_ context.Context, input map[string]any) (ToolO
 		timeout = time.Duration(t) * time.Millisecond
 	}
 
+	offset := 0
+	if o, ok := input["offset"].(float64); ok && o > 0 {
+		offset = int(o)
+	}
+
 	output, err := t.TaskManager.GetOutput(taskID, block, timeout)
+	output = outputPage(output, offset)
 	if err != nil {
 		return ToolOutput{
 			Content: fmt.Sprintf("Error: %s\nPartial output:\n%s", err, output),
@@ -81,3 +96,23 @@ func (t *TaskOutputTool) Execute(_ context.Context, input map[string]any) (ToolO
 		Content: fmt.Sprintf("Task %s (status: %s):\n%s", taskID, status, output),
 	}, nil
 }
+
+// outputPage returns up to taskOutputPageBytes of output from offset, with a
+// note giving the offset of the next page if there is more. Page boundaries
+// never split a UTF-8 sequence.
+func outputPage(output string, offset int) string {
+	if offset > len(output) {
+		offset = len(output)
+	}
+	for offset < len(output) && !utf8.RuneStart(output[offset]) {
+		offset++
+	}
+	end := offset + taskOutputPageBytes
+	if end >= len(output) {
+		return output[offset:]
+	}
+	for end > offset && !utf8.RuneStart(output[end]) {
+		end--
+	}
+	return fmt.Sprintf("%s\n... (%d more bytes; call TaskOutput with offset=%d to continue)", output[offset:end], len(output)-end, end)
+}
@@ -113,3 +113,19 @@ func TestTaskOutput_NilManager(t *testing.T) {
 		t.Error("expected error for nil manager")
 	}
 }
+
+func TestTaskOutput_Pages(t *testing.T) {
+	tm := NewTaskManager()
+	output := strings.Repeat("a", taskOutputPageBytes-1) + "é" + strings.Repeat("b", 100)
+	tm.StoreCompleted("big", output)
+	tool := &TaskOutputTool{TaskManager: tm}
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"task_id": "big"})
+	if strings.Contains(out.Content, "é") || !strings.Contains(out.Content, "offset=29999 to continue") {
+		t.Fatalf("first page should stop before the split rune and give the next offset, got ...%q", out.Content[len(out.Content)-80:])
+	}
+	out, _ = tool.Execute(context.Background(), map[string]any{"task_id": "big", "offset": float64(29999)})
+	if !strings.HasSuffix(out.Content, "\né"+strings.Repeat("b", 100)) {
+		t.Errorf("second page = %q, want the rest of the output", out.Content)
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -15,15 +16,29 @@ var validTodoStatuses = map[string]bool{
 
 // TodoItem represents a single todo entry.
 type TodoItem struct {
-	Content    string
-	Status     string
-	ActiveForm string
+	Content    string `json:"content"`
+	Status     string `json:"status"`
+	ActiveForm string `json:"activeForm,omitempty"`
 }
 
 // TodoWriteTool manages a structured todo list in memory.
 type TodoWriteTool struct {
 	mu    sync.Mutex
-	Todos []TodoItem
+	Todos []TodoItem // use List and SetTodos while the tool may be running
+}
+
+// List returns a copy of the current todo list.
+func (t *TodoWriteTool) List() []TodoItem {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.Todos)
+}
+
+// SetTodos replaces the todo list, e.g. with one restored from a session.
+func (t *TodoWriteTool) SetTodos(items []TodoItem) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Todos = slices.Clone(items)
 }
 
 func (t *TodoWriteTool) Name() string { return "TodoWrite" }
@@ -2,7 +2,9 @@ package tools
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -125,3 +127,32 @@ func TestTodoWrite_MissingTodos(t *testing.T) {
 		t.Error("expected error for missing todos")
 	}
 }
+
+func TestTodoWrite_ListAndSetTodos(t *testing.T) {
+	tool := &TodoWriteTool{}
+	tool.SetTodos([]TodoItem{{Content: "Restored", Status: "pending"}})
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			tool.Execute(context.Background(), map[string]any{"todos": []any{
+				map[string]any{"content": fmt.Sprintf("Task %d", i), "status": "pending"},
+			}})
+		}()
+		go func() {
+			defer wg.Done()
+			if list := tool.List(); len(list) != 1 {
+				t.Errorf("List() = %+v, want one item", list)
+			}
+		}()
+	}
+	wg.Wait()
+
+	list := tool.List()
+	list[0].Content = "modified"
+	if tool.List()[0].Content == "modified" {
+		t.Error("List() returned the tool's own slice")
+	}
+}
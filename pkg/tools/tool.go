@@ -1,6 +1,10 @@
 package tools
 
-import "context"
+import (
+	"context"
+
+	"github.com/jg-phare/goat/pkg/llm"
+)
 
 // SideEffectType classifies a tool's impact on system state.
 type SideEffectType int
@@ -14,10 +18,30 @@ const (
 	SideEffectSpawns                         // Agent/Task tool
 )
 
+var sideEffectNames = [...]string{"none", "read_only", "mutating", "network", "blocking", "spawns"}
+
+// String returns the snake_case name of the side-effect type, e.g. "read_only".
+func (s SideEffectType) String() string {
+	if int(s) < len(sideEffectNames) && s >= 0 {
+		return sideEffectNames[s]
+	}
+	return "unknown"
+}
+
 // ToolOutput is the result of a tool execution.
 type ToolOutput struct {
-	Content string // text content for the tool_result
-	IsError bool   // when true, content is an error message
+	Content  string            // text content for the tool_result
+	IsError  bool              // when true, content is an error message
+	Metadata *OutputMetadata   // optional bookkeeping, not sent to the model
+	Images   []llm.ContentPart // image parts sent to the model after Content
+}
+
+// OutputMetadata records how a tool's raw output was transformed.
+type OutputMetadata struct {
+	Truncated     bool   // content was shortened
+	OriginalBytes int    // size of the raw output before truncation
+	FullOutputID  string // TaskManager ID holding the untruncated output, if saved
+	FromCache     bool   // served from a cached copy rather than fetched again
 }
 
 // Tool is the interface every tool must implement.
@@ -28,3 +52,11 @@ type Tool interface {
 	SideEffect() SideEffectType
 	Execute(ctx context.Context, input map[string]any) (ToolOutput, error)
 }
+
+// ProgressReporter is implemented by tools that report intermediate status
+// (e.g. "downloaded 40%") while they run. The loop calls ExecuteWithProgress
+// instead of Execute; each report call becomes a ToolProgressMessage. report
+// is safe for concurrent use and becomes a no-op once the call returns.
+type ProgressReporter interface {
+	ExecuteWithProgress(ctx context.Context, input map[string]any, report func(message string)) (ToolOutput, error)
+}
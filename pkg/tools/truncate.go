@@ -0,0 +1,43 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// TruncateHeadTail shortens s to roughly maxBytes by keeping its beginning
+// and end with a "[... N bytes truncated ...]" marker in between. Cut
+// points prefer line boundaries and never split a UTF-8 sequence.
+// Returns s unchanged (and false) if it already fits.
+func TruncateHeadTail(s string, maxBytes int) (string, bool) {
+	if maxBytes <= 0 || len(s) <= maxBytes {
+		return s, false
+	}
+
+	headBudget := maxBytes / 2
+	tailBudget := maxBytes - headBudget
+
+	// Head: end at the last newline in budget, unless that discards more than half of it
+	headEnd := headBudget
+	for headEnd > 0 && !utf8.RuneStart(s[headEnd]) {
+		headEnd--
+	}
+	if i := strings.LastIndexByte(s[:headEnd], '\n'); i >= headBudget/2 {
+		headEnd = i + 1
+	}
+
+	// Tail: start after the first newline in budget, with the same limit
+	tailStart := len(s) - tailBudget
+	for tailStart < len(s) && !utf8.RuneStart(s[tailStart]) {
+		tailStart++
+	}
+	if i := strings.IndexByte(s[tailStart:], '\n'); i >= 0 && i < tailBudget/2 {
+		tailStart += i + 1
+	}
+
+	omitted := tailStart - headEnd
+	head := strings.TrimSuffix(s[:headEnd], "\n")
+	tail := s[tailStart:]
+	return fmt.Sprintf("%s\n[... %d bytes truncated ...]\n%s", head, omitted, tail), true
+}
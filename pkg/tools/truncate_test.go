@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateHeadTail_Fits(t *testing.T) {
+	got, truncated := TruncateHeadTail("short", 100)
+	if truncated || got != "short" {
+		t.Errorf("got %q, %v", got, truncated)
+	}
+	if _, truncated := TruncateHeadTail(strings.Repeat("x", 10), 0); truncated {
+		t.Error("maxBytes <= 0 should disable truncation")
+	}
+}
+
+func TestTruncateHeadTail_LineAware(t *testing.T) {
+	var b strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&b, "line %03d\n", i) // 9 bytes per line
+	}
+	in := b.String()
+
+	got, truncated := TruncateHeadTail(in, 100)
+	if !truncated {
+		t.Fatal("expected truncation")
+	}
+	if !strings.HasPrefix(got, "line 001\n") || !strings.HasSuffix(got, "line 100\n") {
+		t.Errorf("expected head and tail preserved, got %q", got)
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if !strings.HasPrefix(l, "line ") && !strings.HasPrefix(l, "[... ") {
+			t.Errorf("line cut mid-way: %q", l)
+		}
+	}
+
+	// The marker reports exactly the bytes removed
+	var omitted int
+	idx := strings.Index(got, "[... ")
+	fmt.Sscanf(got[idx:], "[... %d bytes truncated ...]", &omitted)
+	kept := len(got) - len(fmt.Sprintf("\n[... %d bytes truncated ...]\n", omitted)) + 1 // +1 for the trimmed head newline
+	if kept+omitted != len(in) {
+		t.Errorf("kept %d + omitted %d != original %d", kept, omitted, len(in))
+	}
+}
+
+func TestTruncateHeadTail_UTF8(t *testing.T) {
+	in := strings.Repeat("日本語", 1000) // no newlines, 3-byte runes
+	for _, max := range []int{10, 11, 100, 1001} {
+		got, truncated := TruncateHeadTail(in, max)
+		if !truncated {
+			t.Fatalf("max %d: expected truncation", max)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("max %d: split a UTF-8 sequence", max)
+		}
+		if !strings.Contains(got, "bytes truncated") {
+			t.Errorf("max %d: missing marker", max)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of WebResource.
+const (
+	WebResourceFetch  = "fetch"  // WebFetch content, keyed by URL
+	WebResourceSearch = "search" // WebSearch results, keyed by query and domain filters
+)
+
+// WebResource is a WebFetch page or WebSearch result set held by a WebCache.
+type WebResource struct {
+	Kind      string    `json:"kind"`
+	Key       string    `json:"key"`
+	Content   string    `json:"content"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// WebCache keeps WebFetch and WebSearch results beyond a single tool
+// instance, so that repeating a request, including in a resumed session,
+// gets the same content without going to the network. Tools apply their own
+// TTL to FetchedAt.
+type WebCache interface {
+	GetWebResource(kind, key string) (WebResource, bool, error)
+	PutWebResource(res WebResource) error
+}
+
+type webCacheKey struct{}
+
+// WithWebCache returns a context whose WebFetch and WebSearch calls read
+// and store results in c. The agent loop uses it to cache them in the
+// session store.
+func WithWebCache(ctx context.Context, c WebCache) context.Context {
+	return context.WithValue(ctx, webCacheKey{}, c)
+}
+
+func webCacheFrom(ctx context.Context) WebCache {
+	c, _ := ctx.Value(webCacheKey{}).(WebCache)
+	return c
+}
+
+// cachedWebResource returns the content ctx's WebCache holds for kind and
+// key, if it was fetched within ttl. Cache errors count as misses.
+func cachedWebResource(ctx context.Context, kind, key string, ttl time.Duration) (string, bool) {
+	c := webCacheFrom(ctx)
+	if c == nil || ttl < 0 {
+		return "", false
+	}
+	res, ok, err := c.GetWebResource(kind, key)
+	if err != nil || !ok || time.Since(res.FetchedAt) > ttl {
+		return "", false
+	}
+	return res.Content, true
+}
+
+// storeWebResource saves content in ctx's WebCache, if any. A failure to
+// store only costs a later refetch, so it is ignored.
+func storeWebResource(ctx context.Context, kind, key, content string, ttl time.Duration) {
+	c := webCacheFrom(ctx)
+	if c == nil || ttl < 0 {
+		return
+	}
+	_ = c.PutWebResource(WebResource{Kind: kind, Key: key, Content: content, FetchedAt: time.Now()})
+}
+
+// cacheMetadata notes on a tool output that it was served from a cache.
+func cacheMetadata(cached bool) *OutputMetadata {
+	if !cached {
+		return nil
+	}
+	return &OutputMetadata{FromCache: true}
+}
+
+// bypassCache reports whether a tool input asks for fresh results.
+func bypassCache(input map[string]any) bool {
+	bypass, _ := input["bypass_cache"].(bool)
+	return bypass
+}
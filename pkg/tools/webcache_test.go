@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// memWebCache is a WebCache such as the agent loop attaches from a session store.
+type memWebCache map[string]WebResource
+
+func (c memWebCache) GetWebResource(kind, key string) (WebResource, bool, error) {
+	res, ok := c[kind+" "+key]
+	return res, ok, nil
+}
+
+func (c memWebCache) PutWebResource(res WebResource) error {
+	c[res.Kind+" "+res.Key] = res
+	return nil
+}
+
+func TestWebFetch_WebCache(t *testing.T) {
+	var hits int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "response %d", hits)
+	}))
+	defer srv.Close()
+
+	cache := memWebCache{}
+	ctx := WithWebCache(context.Background(), cache)
+	input := map[string]any{"url": srv.URL + "/a", "prompt": "read"}
+
+	first, _ := (&WebFetchTool{HTTPClient: srv.Client()}).Execute(ctx, input)
+	if first.Metadata != nil && first.Metadata.FromCache {
+		t.Error("first fetch reported as served from cache")
+	}
+	if res, ok := cache[WebResourceFetch+" "+srv.URL+"/a"]; !ok || res.Content != "response 1" {
+		t.Fatalf("cached resource = %+v, want the fetched content", res)
+	}
+
+	// A new tool instance, as in a resumed session, is served the stored copy.
+	second, _ := (&WebFetchTool{HTTPClient: srv.Client()}).Execute(ctx, input)
+	if hits != 1 {
+		t.Errorf("hits = %d, want 1 with the context cache", hits)
+	}
+	if second.Content != first.Content || second.Metadata == nil || !second.Metadata.FromCache {
+		t.Errorf("second fetch = %q (metadata %+v), want the cached content marked FromCache", second.Content, second.Metadata)
+	}
+
+	bypass := map[string]any{"url": srv.URL + "/a", "prompt": "read", "bypass_cache": true}
+	fresh, _ := (&WebFetchTool{HTTPClient: srv.Client()}).Execute(ctx, bypass)
+	if hits != 2 || fresh.Metadata != nil {
+		t.Errorf("bypass_cache: hits = %d, metadata %+v; want a fresh fetch", hits, fresh.Metadata)
+	}
+	if cache[WebResourceFetch+" "+srv.URL+"/a"].Content != "response 2" {
+		t.Error("bypass_cache did not refresh the cached copy")
+	}
+
+	// Entries older than the TTL are fetched again.
+	key := WebResourceFetch + " " + srv.URL + "/a"
+	res := cache[key]
+	res.FetchedAt = time.Now().Add(-time.Hour)
+	cache[key] = res
+	(&WebFetchTool{HTTPClient: srv.Client(), CacheTTL: time.Minute}).Execute(ctx, input)
+	if hits != 3 {
+		t.Errorf("hits = %d, want an expired entry refetched", hits)
+	}
+}
+
+type countingSearchProvider struct{ calls int }
+
+func (p *countingSearchProvider) Search(_ context.Context, query string, _ SearchOptions) ([]SearchResult, error) {
+	p.calls++
+	return []SearchResult{{Title: fmt.Sprintf("Result %d", p.calls), URL: "https://example.com", Snippet: query}}, nil
+}
+
+func TestWebSearch_WebCache(t *testing.T) {
+	provider := &countingSearchProvider{}
+	tool := &WebSearchTool{Provider: provider}
+	ctx := WithWebCache(context.Background(), memWebCache{})
+
+	first, _ := tool.Execute(ctx, map[string]any{"query": "golang"})
+	second, _ := tool.Execute(ctx, map[string]any{"query": "golang"})
+	if provider.calls != 1 {
+		t.Errorf("provider calls = %d, want 1", provider.calls)
+	}
+	if second.Content != first.Content || second.Metadata == nil || !second.Metadata.FromCache {
+		t.Errorf("second search = %q (metadata %+v), want the cached results marked FromCache", second.Content, second.Metadata)
+	}
+
+	// Domain filters are part of the key.
+	tool.Execute(ctx, map[string]any{"query": "golang", "allowed_domains": []any{"go.dev"}})
+	tool.Execute(ctx, map[string]any{"query": "golang", "bypass_cache": true})
+	if provider.calls != 3 {
+		t.Errorf("provider calls = %d, want 3 after a filtered search and a bypass", provider.calls)
+	}
+
+	// Without a context cache every search goes to the provider.
+	tool.Execute(context.Background(), map[string]any{"query": "golang"})
+	tool.Execute(context.Background(), map[string]any{"query": "golang"})
+	if provider.calls != 5 {
+		t.Errorf("provider calls = %d, want 5 without a cache", provider.calls)
+	}
+}
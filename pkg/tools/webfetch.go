@@ -5,17 +5,23 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"golang.org/x/net/html"
 )
 
 const (
-	webFetchTimeout    = 30 * time.Second
-	webFetchMaxBody    = 5 * 1024 * 1024 // 5MB
-	webFetchMaxContent = 50000           // chars after extraction
-	webFetchUserAgent  = "Goat/1.0 (CLI Agent)"
+	webFetchTimeout      = 30 * time.Second
+	webFetchMaxBody      = 5 * 1024 * 1024 // 5MB
+	webFetchMaxContent   = 50000           // chars after extraction
+	webFetchMaxRedirects = 10
+	webFetchCacheTTL     = 15 * time.Minute
+	webFetchUserAgent    = "Goat/1.0 (CLI Agent)"
 )
 
 // ContentSummarizer summarizes web page content using an LLM.
@@ -31,6 +37,23 @@ type WebFetchTool struct {
 	// Summarizer, when set, processes fetched content with a prompt via an LLM.
 	// When nil, raw extracted content is returned.
 	Summarizer ContentSummarizer
+
+	// AllowedHosts, when non-empty, restricts fetches to these hosts and their subdomains.
+	AllowedHosts []string
+
+	// BlockedHosts are never fetched, even if they also appear in AllowedHosts.
+	BlockedHosts []string
+
+	// MaxContentLength caps the converted content length (0 = default 50000).
+	MaxContentLength int
+
+	// CacheTTL controls how long fetched content is cached by URL
+	// (0 = default 15 minutes, negative disables caching). Content is also
+	// kept in the call context's WebCache, when there is one, for the same time.
+	CacheTTL time.Duration
+
+	cacheMu sync.Mutex
+	cache   map[string]webFetchCacheEntry
 }
 
 func (w *WebFetchTool) Name() string { return "WebFetch" }
@@ -52,7 +75,7 @@ Usage notes:
   - The prompt should describe what information you want to extract from the page
   - This tool is read-only and does not modify any files
   - Results may be summarized if the content is very large
-  - Includes a self-cleaning 15-minute cache for faster responses when repeatedly accessing the same URL
+  - Includes a self-cleaning 15-minute cache for faster responses when repeatedly accessing the same URL. Set bypass_cache to fetch a fresh copy
   - When a URL redirects to a different host, the tool will inform you and provide the redirect URL in a special format. You should then make a new WebFetch request with the redirect URL to fetch the content.
   - For GitHub URLs, prefer using the gh CLI via Bash instead (e.g., gh pr view, gh issue view, gh api).`
 }
@@ -69,6 +92,10 @@ func (w *WebFetchTool) InputSchema() map[string]any {
 				"type":        "string",
 				"description": "The prompt describing what to extract from the page",
 			},
+			"bypass_cache": map[string]any{
+				"type":        "boolean",
+				"description": "Fetch the URL again even if a cached copy exists",
+			},
 		},
 		"required": []string{"url", "prompt"},
 	}
@@ -87,24 +114,80 @@ func (w *WebFetchTool) Execute(ctx context.Context, input map[string]any) (ToolO
 		return ToolOutput{Content: "Error: prompt is required", IsError: true}, nil
 	}
 
-	// Auto-upgrade HTTP to HTTPS
-	if strings.HasPrefix(rawURL, "http://") {
-		rawURL = "https://" + rawURL[7:]
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" {
+		return ToolOutput{Content: "Error: url must start with http:// or https://", IsError: true}, nil
 	}
+	switch parsed.Scheme {
+	case "https":
+	case "http":
+		// Auto-upgrade HTTP to HTTPS
+		parsed.Scheme = "https"
+	default:
+		return ToolOutput{
+			Content: fmt.Sprintf("Error: unsupported URL scheme %q (only http and https are allowed)", parsed.Scheme),
+			IsError: true,
+		}, nil
+	}
+	if parsed.Host == "" {
+		return ToolOutput{Content: "Error: url must include a host", IsError: true}, nil
+	}
+	rawURL = parsed.String()
 
-	// Validate URL has a scheme
-	if !strings.HasPrefix(rawURL, "https://") {
-		return ToolOutput{Content: "Error: url must start with http:// or https://", IsError: true}, nil
+	if reason := w.checkHost(parsed.Hostname()); reason != "" {
+		return ToolOutput{Content: "Error: " + reason, IsError: true}, nil
 	}
 
+	var content string
+	cached := false
+	if !bypassCache(input) {
+		content, cached = w.cacheGet(rawURL)
+		if !cached {
+			content, cached = cachedWebResource(ctx, WebResourceFetch, rawURL, w.cacheTTL())
+		}
+	}
+	if !cached {
+		var errOut *ToolOutput
+		content, errOut = w.fetch(ctx, rawURL)
+		if errOut != nil {
+			return *errOut, nil
+		}
+		w.cachePut(rawURL, content)
+		storeWebResource(ctx, WebResourceFetch, rawURL, content, w.cacheTTL())
+	}
+
+	// If summarizer is available, process content through LLM
+	if w.Summarizer != nil {
+		summary, sumErr := w.Summarizer.Summarize(ctx, prompt, content)
+		if sumErr == nil && summary != "" {
+			return ToolOutput{
+				Content:  fmt.Sprintf("Fetched and summarized content from %s:\n\n%s", rawURL, summary),
+				Metadata: cacheMetadata(cached),
+			}, nil
+		}
+		// Fall back to raw content on summarizer error
+	}
+
+	return ToolOutput{
+		Content:  fmt.Sprintf("Fetched content from %s:\n\nPrompt: %s\n\n%s", rawURL, prompt, content),
+		Metadata: cacheMetadata(cached),
+	}, nil
+}
+
+// fetch performs the GET request and returns the extracted, truncated content.
+// On failure it returns the error ToolOutput to hand back to the model.
+func (w *WebFetchTool) fetch(ctx context.Context, rawURL string) (string, *ToolOutput) {
 	client := w.HTTPClient
 	if client == nil {
 		client = &http.Client{
 			Timeout: webFetchTimeout,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 10 {
+				if len(via) >= webFetchMaxRedirects {
 					return fmt.Errorf("too many redirects")
 				}
+				if reason := w.checkHost(req.URL.Hostname()); reason != "" {
+					return fmt.Errorf("redirect refused: %s", reason)
+				}
 				return nil
 			},
 		}
@@ -115,114 +198,302 @@ func (w *WebFetchTool) Execute(ctx context.Context, input map[string]any) (ToolO
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
-		return ToolOutput{
+		return "", &ToolOutput{
 			Content: fmt.Sprintf("Error creating request: %s", err),
 			IsError: true,
-		}, nil
+		}
 	}
 	req.Header.Set("User-Agent", webFetchUserAgent)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return ToolOutput{
+		return "", &ToolOutput{
 			Content: fmt.Sprintf("Error fetching URL: %s", err),
 			IsError: true,
-		}, nil
+		}
 	}
 	defer resp.Body.Close()
 
+	// Custom clients may follow redirects without the host check
+	if reason := w.checkHost(resp.Request.URL.Hostname()); reason != "" {
+		return "", &ToolOutput{Content: "Error: redirect refused: " + reason, IsError: true}
+	}
+
 	if resp.StatusCode >= 400 {
-		return ToolOutput{
+		return "", &ToolOutput{
 			Content: fmt.Sprintf("Error: HTTP %d from %s", resp.StatusCode, rawURL),
 			IsError: true,
-		}, nil
+		}
 	}
 
 	body, err := io.ReadAll(io.LimitReader(resp.Body, webFetchMaxBody))
 	if err != nil {
-		return ToolOutput{
+		return "", &ToolOutput{
 			Content: fmt.Sprintf("Error reading response: %s", err),
 			IsError: true,
-		}, nil
+		}
 	}
 
 	content := string(body)
 	contentType := resp.Header.Get("Content-Type")
 
-	// Extract text from HTML
+	// Convert HTML to markdown
 	if strings.Contains(contentType, "text/html") || strings.Contains(contentType, "application/xhtml") {
-		content = extractTextFromHTML(content)
+		content = htmlToMarkdown(content, resp.Request.URL)
 	}
 
 	// Truncate if needed
-	if len(content) > webFetchMaxContent {
-		content = content[:webFetchMaxContent] + "\n... (truncated)"
+	maxContent := webFetchMaxContent
+	if w.MaxContentLength > 0 {
+		maxContent = w.MaxContentLength
 	}
+	if len(content) > maxContent {
+		cut := maxContent
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		content = content[:cut] + "\n... (truncated)"
+	}
+	return content, nil
+}
 
-	// If summarizer is available, process content through LLM
-	if w.Summarizer != nil {
-		summary, sumErr := w.Summarizer.Summarize(ctx, prompt, content)
-		if sumErr == nil && summary != "" {
-			return ToolOutput{
-				Content: fmt.Sprintf("Fetched and summarized content from %s:\n\n%s", rawURL, summary),
-			}, nil
+// checkHost returns a non-empty reason if host is blocked or not allowlisted.
+// A host matches an entry if it equals it or is a subdomain of it.
+func (w *WebFetchTool) checkHost(host string) string {
+	host = strings.ToLower(host)
+	for _, blocked := range w.BlockedHosts {
+		if hostMatches(host, blocked) {
+			return fmt.Sprintf("host %q is blocked", host)
 		}
-		// Fall back to raw content on summarizer error
 	}
+	if len(w.AllowedHosts) == 0 {
+		return ""
+	}
+	for _, allowed := range w.AllowedHosts {
+		if hostMatches(host, allowed) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("host %q is not in the allowed hosts list", host)
+}
 
-	return ToolOutput{
-		Content: fmt.Sprintf("Fetched content from %s:\n\nPrompt: %s\n\n%s", rawURL, prompt, content),
-	}, nil
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimPrefix(pattern, "*."))
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+type webFetchCacheEntry struct {
+	content   string
+	expiresAt time.Time
+}
+
+func (w *WebFetchTool) cacheTTL() time.Duration {
+	if w.CacheTTL == 0 {
+		return webFetchCacheTTL
+	}
+	return w.CacheTTL
+}
+
+// cacheGet returns cached content for url, evicting expired entries.
+func (w *WebFetchTool) cacheGet(url string) (string, bool) {
+	if w.cacheTTL() < 0 {
+		return "", false
+	}
+	w.cacheMu.Lock()
+	defer w.cacheMu.Unlock()
+	now := time.Now()
+	for k, e := range w.cache {
+		if now.After(e.expiresAt) {
+			delete(w.cache, k)
+		}
+	}
+	e, ok := w.cache[url]
+	return e.content, ok
 }
 
-// extractTextFromHTML uses the x/net/html tokenizer to strip tags and extract visible text.
-func extractTextFromHTML(rawHTML string) string {
+func (w *WebFetchTool) cachePut(url, content string) {
+	ttl := w.cacheTTL()
+	if ttl < 0 {
+		return
+	}
+	w.cacheMu.Lock()
+	defer w.cacheMu.Unlock()
+	if w.cache == nil {
+		w.cache = make(map[string]webFetchCacheEntry)
+	}
+	w.cache[url] = webFetchCacheEntry{content: content, expiresAt: time.Now().Add(ttl)}
+}
+
+var (
+	mdSpaceRun = regexp.MustCompile(`[ \t]+`)
+	mdBlankRun = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToMarkdown uses the x/net/html tokenizer to convert HTML into readable
+// markdown: headings, paragraphs, lists, links, emphasis and code blocks are
+// preserved; script, style and other non-visible content is dropped.
+// Relative links are resolved against base when it is non-nil.
+func htmlToMarkdown(rawHTML string, base *url.URL) string {
 	tokenizer := html.NewTokenizer(strings.NewReader(rawHTML))
 	var b strings.Builder
-	var skip bool
+	var skip, inPre int
+	var hrefs []string // stack of open <a> hrefs
 
 	for {
 		tt := tokenizer.Next()
 		switch tt {
 		case html.ErrorToken:
-			return strings.TrimSpace(b.String())
-		case html.StartTagToken:
-			tn, _ := tokenizer.TagName()
+			return cleanMarkdown(b.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tn, hasAttr := tokenizer.TagName()
 			tag := string(tn)
-			// Skip script, style, and other non-visible tags
-			if tag == "script" || tag == "style" || tag == "noscript" || tag == "head" {
-				skip = true
+			if isHiddenTag(tag) {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
 			}
-			// Add newlines for block elements
-			if isBlockTag(tag) {
-				b.WriteByte('\n')
+			switch tag {
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				b.WriteString("\n\n" + strings.Repeat("#", int(tag[1]-'0')) + " ")
+			case "li":
+				b.WriteString("\n- ")
+			case "br":
+				b.WriteString("\n")
+			case "hr":
+				b.WriteString("\n\n---\n\n")
+			case "pre":
+				inPre++
+				b.WriteString("\n\n```\n")
+			case "code":
+				if inPre == 0 {
+					b.WriteString("`")
+				}
+			case "strong", "b":
+				b.WriteString("**")
+			case "em", "i":
+				b.WriteString("_")
+			case "a":
+				href := ""
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = tokenizer.TagAttr()
+					if string(key) == "href" {
+						href = resolveHref(string(val), base)
+					}
+				}
+				hrefs = append(hrefs, href)
+				if href != "" {
+					b.WriteString("[")
+				}
+			default:
+				if isBlockTag(tag) {
+					b.WriteString("\n\n")
+				}
 			}
 		case html.EndTagToken:
 			tn, _ := tokenizer.TagName()
 			tag := string(tn)
-			if tag == "script" || tag == "style" || tag == "noscript" || tag == "head" {
-				skip = false
+			if isHiddenTag(tag) {
+				if skip > 0 {
+					skip--
+				}
+				continue
 			}
-		case html.TextToken:
-			if !skip {
-				text := strings.TrimSpace(string(tokenizer.Text()))
-				if text != "" {
-					if b.Len() > 0 {
-						b.WriteByte(' ')
+			if skip > 0 {
+				continue
+			}
+			switch tag {
+			case "pre":
+				if inPre > 0 {
+					inPre--
+				}
+				b.WriteString("\n```\n\n")
+			case "code":
+				if inPre == 0 {
+					b.WriteString("`")
+				}
+			case "strong", "b":
+				b.WriteString("**")
+			case "em", "i":
+				b.WriteString("_")
+			case "a":
+				if n := len(hrefs); n > 0 {
+					if href := hrefs[n-1]; href != "" {
+						b.WriteString("](" + href + ")")
 					}
-					b.WriteString(text)
+					hrefs = hrefs[:n-1]
 				}
+			default:
+				if isBlockTag(tag) {
+					b.WriteString("\n\n")
+				}
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := string(tokenizer.Text())
+			if inPre > 0 {
+				b.WriteString(text)
+				continue
 			}
+			b.WriteString(mdSpaceRun.ReplaceAllString(strings.ReplaceAll(text, "\n", " "), " "))
+		}
+	}
+}
+
+// cleanMarkdown trims trailing spaces, leading spaces outside code blocks,
+// and collapses runs of blank lines.
+func cleanMarkdown(s string) string {
+	lines := strings.Split(s, "\n")
+	inFence := false
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "```" {
+			inFence = !inFence
+			lines[i] = "```"
+			continue
+		}
+		if !inFence {
+			line = strings.TrimSpace(line)
 		}
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(mdBlankRun.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// resolveHref resolves href against base, dropping javascript: and fragment-only links.
+func resolveHref(href string, base *url.URL) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	if base == nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
 	}
+	return base.ResolveReference(ref).String()
+}
+
+func isHiddenTag(tag string) bool {
+	switch tag {
+	case "script", "style", "noscript", "head", "template", "svg":
+		return true
+	}
+	return false
 }
 
 func isBlockTag(tag string) bool {
 	switch tag {
-	case "div", "p", "br", "h1", "h2", "h3", "h4", "h5", "h6",
-		"ul", "ol", "li", "table", "tr", "td", "th",
+	case "div", "p", "ul", "ol", "table", "tr",
 		"section", "article", "header", "footer", "nav",
-		"blockquote", "pre", "hr":
+		"blockquote", "main", "aside", "figure", "dl", "dt", "dd":
 		return true
 	}
 	return false
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"unicode/utf8"
 )
 
 func TestWebFetch_HTMLExtraction(t *testing.T) {
@@ -261,3 +263,134 @@ func TestWebFetch_NilSummarizer_RawContent(t *testing.T) {
 		t.Errorf("expected raw content, got %q", out.Content)
 	}
 }
+
+func TestWebFetch_NonHTTPScheme(t *testing.T) {
+	tool := &WebFetchTool{}
+	for _, u := range []string{"file:///etc/passwd", "ftp://example.com/file", "gopher://example.com"} {
+		out, _ := tool.Execute(context.Background(), map[string]any{"url": u, "prompt": "read"})
+		if !out.IsError || !strings.Contains(out.Content, "scheme") {
+			t.Errorf("%s: expected scheme error, got %q", u, out.Content)
+		}
+	}
+}
+
+func TestWebFetch_HostLists(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		blocked []string
+		url     string
+		wantErr string
+	}{
+		{"blocked exact", nil, []string{"evil.com"}, "https://evil.com/x", "blocked"},
+		{"blocked subdomain", nil, []string{"evil.com"}, "https://a.evil.com/x", "blocked"},
+		{"not allowlisted", []string{"docs.example.com"}, nil, "https://other.com/", "not in the allowed"},
+		{"blocked wins over allowed", []string{"example.com"}, []string{"bad.example.com"}, "https://bad.example.com/", "blocked"},
+		{"suffix is not a subdomain", nil, []string{"evil.com"}, "https://notevil.com.invalid/", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &WebFetchTool{AllowedHosts: tt.allowed, BlockedHosts: tt.blocked}
+			reason := tool.checkHost(mustHostname(t, tt.url))
+			if tt.wantErr == "" {
+				if reason != "" {
+					t.Errorf("unexpected refusal: %s", reason)
+				}
+				return
+			}
+			if !strings.Contains(reason, tt.wantErr) {
+				t.Errorf("reason = %q, want containing %q", reason, tt.wantErr)
+			}
+			out, _ := tool.Execute(context.Background(), map[string]any{"url": tt.url, "prompt": "read"})
+			if !out.IsError {
+				t.Errorf("expected Execute to refuse %s", tt.url)
+			}
+		})
+	}
+}
+
+func mustHostname(t *testing.T, raw string) string {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Hostname()
+}
+
+func TestWebFetch_CachesByURL(t *testing.T) {
+	var hits int
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprintf(w, "response %d for %s", hits, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	tool := &WebFetchTool{HTTPClient: srv.Client()}
+	first, _ := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/a", "prompt": "read"})
+	second, _ := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/a", "prompt": "read"})
+	if hits != 1 {
+		t.Errorf("expected 1 request with cache, got %d", hits)
+	}
+	if first.Content != second.Content {
+		t.Errorf("cached content differs: %q vs %q", first.Content, second.Content)
+	}
+
+	tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/b", "prompt": "read"})
+	if hits != 2 {
+		t.Errorf("expected a different URL to miss the cache, got %d hits", hits)
+	}
+
+	noCache := &WebFetchTool{HTTPClient: srv.Client(), CacheTTL: -1}
+	noCache.Execute(context.Background(), map[string]any{"url": srv.URL + "/a", "prompt": "read"})
+	noCache.Execute(context.Background(), map[string]any{"url": srv.URL + "/a", "prompt": "read"})
+	if hits != 4 {
+		t.Errorf("expected caching disabled with negative TTL, got %d hits", hits)
+	}
+}
+
+func TestWebFetch_MaxContentLength(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("é", 100)))
+	}))
+	defer srv.Close()
+
+	tool := &WebFetchTool{HTTPClient: srv.Client(), MaxContentLength: 51}
+	out, _ := tool.Execute(context.Background(), map[string]any{"url": srv.URL, "prompt": "read"})
+	if !strings.Contains(out.Content, "(truncated)") {
+		t.Fatalf("expected truncation, got %q", out.Content)
+	}
+	if !utf8.ValidString(out.Content) {
+		t.Error("truncation split a multi-byte character")
+	}
+}
+
+func TestHTMLToMarkdown(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/")
+	in := `<html><head><title>T</title><style>p{}</style></head><body>
+<h1>Title</h1>
+<p>Some <strong>bold</strong> and <em>italic</em> text with <a href="/guide">a link</a>.</p>
+<ul><li>one</li><li>two</li></ul>
+<pre><code>x := 1
+y := 2</code></pre>
+<p>Inline <code>code</code> <a href="#top">anchor</a></p>
+</body></html>`
+
+	got := htmlToMarkdown(in, base)
+	for _, want := range []string{
+		"# Title",
+		"Some **bold** and _italic_ text with [a link](https://example.com/guide).",
+		"- one\n- two",
+		"```\nx := 1\ny := 2\n```",
+		"Inline `code` anchor",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "p{}") || strings.Contains(got, "\n\n\n") {
+		t.Errorf("unexpected content in:\n%s", got)
+	}
+}
@@ -3,9 +3,13 @@ package tools
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 )
 
+const webSearchCacheTTL = 15 * time.Minute
+
 // SearchOptions configures domain filtering for web search.
 type SearchOptions struct {
 	AllowedDomains []string
@@ -28,12 +32,17 @@ type SearchProvider interface {
 type StubSearchProvider struct{}
 
 func (s *StubSearchProvider) Search(_ context.Context, _ string, _ SearchOptions) ([]SearchResult, error) {
-	return nil, fmt.Errorf("web search not configured. Set a SearchProvider on the WebSearchTool")
+	return nil, fmt.Errorf("web search not configured. Set a SearchProvider on the WebSearchTool or set BRAVE_API_KEY/TAVILY_API_KEY")
 }
 
 // WebSearchTool performs web searches via a configurable provider.
 type WebSearchTool struct {
 	Provider SearchProvider
+
+	// CacheTTL controls how long results are reused from the call context's
+	// WebCache, when there is one (0 = default 15 minutes, negative disables
+	// caching).
+	CacheTTL time.Duration
 }
 
 func (w *WebSearchTool) Name() string { return "WebSearch" }
@@ -85,6 +94,10 @@ func (w *WebSearchTool) InputSchema() map[string]any {
 				"items":       map[string]any{"type": "string"},
 				"description": "Exclude results from these domains",
 			},
+			"bypass_cache": map[string]any{
+				"type":        "boolean",
+				"description": "Search again even if cached results exist",
+			},
 		},
 		"required": []string{"query"},
 	}
@@ -119,16 +132,38 @@ func (w *WebSearchTool) Execute(ctx context.Context, input map[string]any) (Tool
 		}
 	}
 
-	results, err := provider.Search(ctx, query, opts)
+	ttl := w.CacheTTL
+	if ttl == 0 {
+		ttl = webSearchCacheTTL
+	}
+	key := webSearchCacheKey(query, opts)
+	if !bypassCache(input) {
+		if content, ok := cachedWebResource(ctx, WebResourceSearch, key, ttl); ok {
+			return ToolOutput{Content: content, Metadata: &OutputMetadata{FromCache: true}}, nil
+		}
+	}
+
+	content, err := runSearch(ctx, provider, query, opts)
 	if err != nil {
 		return ToolOutput{
 			Content: fmt.Sprintf("Error: %s", err),
 			IsError: true,
 		}, nil
 	}
+	storeWebResource(ctx, WebResourceSearch, key, content, ttl)
+	return ToolOutput{Content: content}, nil
+}
+
+// runSearch runs the query and formats the filtered results.
+func runSearch(ctx context.Context, provider SearchProvider, query string, opts SearchOptions) (string, error) {
+	results, err := provider.Search(ctx, query, opts)
+	if err != nil {
+		return "", err
+	}
 
+	results = filterSearchResults(results, opts)
 	if len(results) == 0 {
-		return ToolOutput{Content: "No results found."}, nil
+		return "No results found.", nil
 	}
 
 	var b strings.Builder
@@ -139,6 +174,17 @@ func (w *WebSearchTool) Execute(ctx context.Context, input map[string]any) (Tool
 			b.WriteByte('\n')
 		}
 	}
+	return b.String(), nil
+}
 
-	return ToolOutput{Content: b.String()}, nil
+// webSearchCacheKey identifies a search by its query and domain filters.
+func webSearchCacheKey(query string, opts SearchOptions) string {
+	key := query
+	if len(opts.AllowedDomains) > 0 {
+		key += " allowed_domains=" + strings.Join(slices.Sorted(slices.Values(opts.AllowedDomains)), ",")
+	}
+	if len(opts.BlockedDomains) > 0 {
+		key += " blocked_domains=" + strings.Join(slices.Sorted(slices.Values(opts.BlockedDomains)), ",")
+	}
+	return key
 }
@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	searchDefaultCount = 10
+	searchTimeout      = 30 * time.Second
+	braveSearchURL     = "https://api.search.brave.com/res/v1/web/search"
+	tavilySearchURL    = "https://api.tavily.com/search"
+)
+
+// SearchProviderFromEnv selects a search backend from the environment.
+// GOAT_SEARCH_PROVIDER picks "brave" or "tavily" explicitly; otherwise the
+// first provider with an API key set (BRAVE_API_KEY, then TAVILY_API_KEY)
+// is used. Returns nil when nothing is configured, in which case
+// WebSearchTool falls back to StubSearchProvider.
+func SearchProviderFromEnv() SearchProvider {
+	provider, err := NewSearchProvider(os.Getenv("GOAT_SEARCH_PROVIDER"), "")
+	if err != nil {
+		return nil
+	}
+	return provider
+}
+
+// NewSearchProvider creates a provider by name ("brave" or "tavily").
+// An empty apiKey is read from the provider's environment variable.
+// An empty name auto-detects from whichever API key is set.
+func NewSearchProvider(name, apiKey string) (SearchProvider, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		switch {
+		case apiKey != "":
+			return nil, fmt.Errorf("search provider name is required when an API key is given")
+		case os.Getenv("BRAVE_API_KEY") != "":
+			name = "brave"
+		case os.Getenv("TAVILY_API_KEY") != "":
+			name = "tavily"
+		default:
+			return nil, fmt.Errorf("no search provider configured (set BRAVE_API_KEY or TAVILY_API_KEY)")
+		}
+	}
+
+	switch name {
+	case "brave":
+		if apiKey == "" {
+			apiKey = os.Getenv("BRAVE_API_KEY")
+		}
+		return &BraveSearchProvider{APIKey: apiKey}, nil
+	case "tavily":
+		if apiKey == "" {
+			apiKey = os.Getenv("TAVILY_API_KEY")
+		}
+		return &TavilySearchProvider{APIKey: apiKey}, nil
+	default:
+		return nil, fmt.Errorf("unknown search provider %q (supported: brave, tavily)", name)
+	}
+}
+
+// BraveSearchProvider queries the Brave Search API.
+type BraveSearchProvider struct {
+	APIKey     string
+	BaseURL    string       // default: Brave web search endpoint
+	Count      int          // max results (0 = default 10)
+	HTTPClient *http.Client // nil = client with 30s timeout
+}
+
+func (b *BraveSearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	if b.APIKey == "" {
+		return nil, fmt.Errorf("brave search API key not configured (set BRAVE_API_KEY)")
+	}
+
+	// Brave has no domain parameters, so express filters as query operators
+	q := query
+	if len(opts.AllowedDomains) > 0 {
+		sites := make([]string, len(opts.AllowedDomains))
+		for i, d := range opts.AllowedDomains {
+			sites[i] = "site:" + d
+		}
+		q += " (" + strings.Join(sites, " OR ") + ")"
+	}
+	for _, d := range opts.BlockedDomains {
+		q += " -site:" + d
+	}
+
+	endpoint := b.BaseURL
+	if endpoint == "" {
+		endpoint = braveSearchURL
+	}
+	params := url.Values{}
+	params.Set("q", q)
+	params.Set("count", fmt.Sprintf("%d", searchCount(b.Count)))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", b.APIKey)
+
+	var body struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doSearchRequest(b.HTTPClient, req, "brave", &body); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(body.Web.Results))
+	for _, r := range body.Web.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return filterSearchResults(results, opts), nil
+}
+
+// TavilySearchProvider queries the Tavily Search API.
+type TavilySearchProvider struct {
+	APIKey     string
+	BaseURL    string       // default: Tavily search endpoint
+	Count      int          // max results (0 = default 10)
+	HTTPClient *http.Client // nil = client with 30s timeout
+}
+
+func (t *TavilySearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	if t.APIKey == "" {
+		return nil, fmt.Errorf("tavily search API key not configured (set TAVILY_API_KEY)")
+	}
+
+	payload := map[string]any{
+		"query":       query,
+		"max_results": searchCount(t.Count),
+	}
+	if len(opts.AllowedDomains) > 0 {
+		payload["include_domains"] = opts.AllowedDomains
+	}
+	if len(opts.BlockedDomains) > 0 {
+		payload["exclude_domains"] = opts.BlockedDomains
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := t.BaseURL
+	if endpoint == "" {
+		endpoint = tavilySearchURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.APIKey)
+
+	var body struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearchRequest(t.HTTPClient, req, "tavily", &body); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(body.Results))
+	for _, r := range body.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return filterSearchResults(results, opts), nil
+}
+
+// doSearchRequest executes req and decodes a JSON response into out.
+func doSearchRequest(client *http.Client, req *http.Request, provider string, out any) error {
+	if client == nil {
+		client = &http.Client{Timeout: searchTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s search request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s search returned HTTP %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s search response: %w", provider, err)
+	}
+	return nil
+}
+
+func searchCount(n int) int {
+	if n <= 0 {
+		return searchDefaultCount
+	}
+	return n
+}
+
+// filterSearchResults drops results outside AllowedDomains or inside BlockedDomains.
+// Providers apply filters server-side where supported; this is a safety net.
+func filterSearchResults(results []SearchResult, opts SearchOptions) []SearchResult {
+	if len(opts.AllowedDomains) == 0 && len(opts.BlockedDomains) == 0 {
+		return results
+	}
+	filtered := results[:0:0]
+	for _, r := range results {
+		u, err := url.Parse(r.URL)
+		if err != nil {
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		if matchesAnyDomain(host, opts.BlockedDomains) {
+			continue
+		}
+		if len(opts.AllowedDomains) > 0 && !matchesAnyDomain(host, opts.AllowedDomains) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+func matchesAnyDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if hostMatches(host, d) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBraveSearchProvider(t *testing.T) {
+	var gotQuery, gotToken string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query().Get("q")
+		gotToken = r.Header.Get("X-Subscription-Token")
+		w.Write([]byte(`{"web":{"results":[
+			{"title":"Go","url":"https://go.dev/doc","description":"Docs"},
+			{"title":"Spam","url":"https://spam.com/x","description":"Ads"}
+		]}}`))
+	}))
+	defer srv.Close()
+
+	p := &BraveSearchProvider{APIKey: "key", BaseURL: srv.URL}
+	results, err := p.Search(context.Background(), "golang", SearchOptions{BlockedDomains: []string{"spam.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotToken != "key" {
+		t.Errorf("token = %q, want key", gotToken)
+	}
+	if gotQuery != "golang -site:spam.com" {
+		t.Errorf("query = %q", gotQuery)
+	}
+	if len(results) != 1 || results[0].URL != "https://go.dev/doc" || results[0].Snippet != "Docs" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestTavilySearchProvider(t *testing.T) {
+	var payload map[string]any
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&payload)
+		w.Write([]byte(`{"results":[{"title":"Go","url":"https://go.dev","content":"The Go language"}]}`))
+	}))
+	defer srv.Close()
+
+	p := &TavilySearchProvider{APIKey: "tvly", BaseURL: srv.URL, Count: 3}
+	results, err := p.Search(context.Background(), "golang", SearchOptions{AllowedDomains: []string{"go.dev"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer tvly" {
+		t.Errorf("auth = %q", auth)
+	}
+	if payload["query"] != "golang" || payload["max_results"] != float64(3) {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+	if domains, _ := payload["include_domains"].([]any); len(domains) != 1 {
+		t.Errorf("include_domains = %v", payload["include_domains"])
+	}
+	if len(results) != 1 || results[0].Snippet != "The Go language" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+}
+
+func TestSearchProvider_HTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad key", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	tool := &WebSearchTool{Provider: &BraveSearchProvider{APIKey: "bad", BaseURL: srv.URL}}
+	out, err := tool.Execute(context.Background(), map[string]any{"query": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.IsError || !strings.Contains(out.Content, "401") {
+		t.Errorf("expected 401 error output, got %q", out.Content)
+	}
+}
+
+func TestSearchProvider_MissingAPIKey(t *testing.T) {
+	for _, p := range []SearchProvider{&BraveSearchProvider{}, &TavilySearchProvider{}} {
+		tool := &WebSearchTool{Provider: p}
+		out, err := tool.Execute(context.Background(), map[string]any{"query": "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !out.IsError || !strings.Contains(out.Content, "API key not configured") {
+			t.Errorf("%T: expected missing key error, got %q", p, out.Content)
+		}
+	}
+}
+
+func TestNewSearchProvider(t *testing.T) {
+	t.Setenv("BRAVE_API_KEY", "")
+	t.Setenv("TAVILY_API_KEY", "")
+	t.Setenv("GOAT_SEARCH_PROVIDER", "")
+
+	if _, err := NewSearchProvider("", ""); err == nil {
+		t.Error("expected error with nothing configured")
+	}
+	if SearchProviderFromEnv() != nil {
+		t.Error("expected nil provider from empty env")
+	}
+	if _, err := NewSearchProvider("bing", "k"); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+
+	t.Setenv("TAVILY_API_KEY", "tv")
+	p, err := NewSearchProvider("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tp, ok := p.(*TavilySearchProvider); !ok || tp.APIKey != "tv" {
+		t.Errorf("expected Tavily from env, got %#v", p)
+	}
+
+	t.Setenv("BRAVE_API_KEY", "br")
+	if _, ok := SearchProviderFromEnv().(*BraveSearchProvider); !ok {
+		t.Error("expected Brave to take precedence when both keys are set")
+	}
+
+	t.Setenv("GOAT_SEARCH_PROVIDER", "tavily")
+	if _, ok := SearchProviderFromEnv().(*TavilySearchProvider); !ok {
+		t.Error("expected explicit GOAT_SEARCH_PROVIDER to win")
+	}
+}
+
+func TestFilterSearchResults(t *testing.T) {
+	results := []SearchResult{
+		{URL: "https://go.dev/a"},
+		{URL: "https://pkg.go.dev/b"},
+		{URL: "https://example.com/c"},
+		{URL: "https://ads.example.com/d"},
+	}
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want int
+	}{
+		{"no filters", SearchOptions{}, 4},
+		{"allowed with subdomains", SearchOptions{AllowedDomains: []string{"go.dev"}}, 2},
+		{"blocked with subdomains", SearchOptions{BlockedDomains: []string{"example.com"}}, 2},
+		{"blocked beats allowed", SearchOptions{AllowedDomains: []string{"example.com"}, BlockedDomains: []string{"ads.example.com"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := filterSearchResults(results, tt.opts); len(got) != tt.want {
+				t.Errorf("got %d results, want %d: %+v", len(got), tt.want, got)
+			}
+		})
+	}
+}
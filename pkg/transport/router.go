@@ -12,7 +12,7 @@ import (
 // InputMessage is the envelope for messages sent from the consumer to the agent
 // through a transport. It is parsed from the transport's ReadMessages channel.
 type InputMessage struct {
-	Type    string          `json:"type"`    // "user_message" | "control_request"
+	Type    string          `json:"type"`    // "user_message" | "steer" | "control_request"
 	Payload json.RawMessage `json:"payload"` // raw content for the specific type
 }
 
@@ -124,6 +124,9 @@ func (r *Router) dispatchInput(msg TransportMessage) error {
 	case "user_message":
 		return r.query.SendUserMessage(input.Payload)
 
+	case "steer":
+		return r.query.Steer(input.Payload)
+
 	case "control_request":
 		var req types.ControlRequest
 		if err := json.Unmarshal(input.Payload, &req); err != nil {
@@ -82,3 +82,87 @@ func NewCompactBoundary(trigger string, preTokens int, sessionID string) *Compac
 		},
 	}
 }
+
+// NewAuthStatus creates an AuthStatusMessage with a fresh UUID.
+func NewAuthStatus(authenticating bool, output []string, errMsg string, sessionID string) *AuthStatusMessage {
+	return &AuthStatusMessage{
+		BaseMessage:      BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:             MessageTypeAuthStatus,
+		IsAuthenticating: authenticating,
+		Output:           output,
+		Error:            errMsg,
+	}
+}
+
+// NewModelChangeStatus creates a StatusMessage reporting a switch from one model to another.
+func NewModelChangeStatus(from, to, sessionID string) *StatusMessage {
+	status := StatusModelChanged
+	return &StatusMessage{
+		BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:        MessageTypeSystem,
+		Subtype:     SystemSubtypeStatus,
+		Status:      &status,
+		ModelChange: &ModelChange{From: from, To: to},
+	}
+}
+
+// NewToolCallMessage creates a ToolCallMessage for a finished tool call.
+func NewToolCallMessage(record ToolCallRecord, sessionID string) *ToolCallMessage {
+	return &ToolCallMessage{
+		BaseMessage:    BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:           MessageTypeSystem,
+		Subtype:        SystemSubtypeToolCall,
+		ToolCallRecord: record,
+	}
+}
+
+// NewRateLimitStatus creates a StatusMessage reporting that the next LLM call
+// is held by a rate limiter for waitMs milliseconds.
+func NewRateLimitStatus(waitMs int64, sessionID string) *StatusMessage {
+	status := StatusRateLimited
+	return &StatusMessage{
+		BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:        MessageTypeSystem,
+		Subtype:     SystemSubtypeStatus,
+		Status:      &status,
+		RateLimit:   &RateLimitStatus{WaitMs: waitMs},
+	}
+}
+
+// NewTurnTimeoutStatus creates a StatusMessage reporting that a turn hit its
+// timeoutMs limit during phase ("llm" or "tools").
+func NewTurnTimeoutStatus(timeoutMs int64, phase, sessionID string) *StatusMessage {
+	status := StatusTurnTimeout
+	return &StatusMessage{
+		BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:        MessageTypeSystem,
+		Subtype:     SystemSubtypeStatus,
+		Status:      &status,
+		TurnTimeout: &TurnTimeout{TimeoutMs: timeoutMs, Phase: phase},
+	}
+}
+
+// NewBudgetWarning creates a StatusMessage reporting that spend crossed threshold of limit.
+func NewBudgetWarning(threshold, spent, limit float64, sessionID string) *StatusMessage {
+	status := StatusBudgetWarning
+	return &StatusMessage{
+		BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: sessionID},
+		Type:        MessageTypeSystem,
+		Subtype:     SystemSubtypeStatus,
+		Status:      &status,
+		Budget: &BudgetStatus{
+			Threshold:    threshold,
+			SpentUSD:     spent,
+			LimitUSD:     limit,
+			RemainingUSD: max(limit-spent, 0),
+		},
+	}
+}
+
+// NewModelBudgetWarning creates a StatusMessage reporting that model's spend
+// crossed threshold of its own budget limit.
+func NewModelBudgetWarning(model string, threshold, spent, limit float64, sessionID string) *StatusMessage {
+	msg := NewBudgetWarning(threshold, spent, limit, sessionID)
+	msg.Budget.Model = model
+	return msg
+}
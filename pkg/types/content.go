@@ -75,3 +75,19 @@ type BetaUsage struct {
 	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
 	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
 }
+
+// TotalInputTokens returns all input tokens: uncached, cache reads and
+// cache writes.
+func (u BetaUsage) TotalInputTokens() int {
+	return u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens
+}
+
+// CacheHitRatio returns the fraction of input tokens served from the prompt
+// cache, from 0 to 1 (0 when there was no input).
+func (u BetaUsage) CacheHitRatio() float64 {
+	total := u.TotalInputTokens()
+	if total == 0 {
+		return 0
+	}
+	return float64(u.CacheReadInputTokens) / float64(total)
+}
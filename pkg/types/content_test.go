@@ -228,3 +228,16 @@ func TestContentBlock_RoundTrip(t *testing.T) {
 		})
 	}
 }
+
+func TestBetaUsage_CacheHitRatio(t *testing.T) {
+	u := BetaUsage{InputTokens: 150, CacheReadInputTokens: 800, CacheCreationInputTokens: 50}
+	if got := u.TotalInputTokens(); got != 1000 {
+		t.Errorf("TotalInputTokens = %d, want 1000", got)
+	}
+	if got := u.CacheHitRatio(); got != 0.8 {
+		t.Errorf("CacheHitRatio = %v, want 0.8", got)
+	}
+	if got := (BetaUsage{}).CacheHitRatio(); got != 0 {
+		t.Errorf("CacheHitRatio with no input = %v, want 0", got)
+	}
+}
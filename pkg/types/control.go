@@ -44,6 +44,17 @@ type ControlRequestInner struct {
 	// hook_callback
 	CallbackID string `json:"callback_id,omitempty"`
 	HookInput  any    `json:"hook_input,omitempty"`
+
+	// answer_question (ToolUseID identifies the AskUserQuestion call)
+	Answers map[string]string `json:"answers,omitempty"`
+
+	// answer_permission (ToolUseID identifies the tool call; Input, if set,
+	// replaces the call's input)
+	Behavior string `json:"behavior,omitempty"` // "allow"|"deny"
+	Message  string `json:"message,omitempty"`  // deny reason
+
+	// steer
+	Prompt string `json:"prompt,omitempty"`
 }
 
 // ControlRequestSubtype enumerates valid control command subtypes.
@@ -61,6 +72,9 @@ const (
 	ControlSubtypeRewindFiles          = "rewind_files"
 	ControlSubtypeHookCallback         = "hook_callback"
 	ControlSubtypeInitialize           = "initialize"
+	ControlSubtypeAnswerQuestion       = "answer_question"
+	ControlSubtypeAnswerPermission     = "answer_permission"
+	ControlSubtypeSteer                = "steer"
 )
 
 // ControlResponse is the agent's reply to a ControlRequest.
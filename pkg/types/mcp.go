@@ -12,12 +12,40 @@ type McpServerConfig struct {
 	// sse/http
 	URL     string            `json:"url,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+	Auth    *McpAuthConfig    `json:"auth,omitempty"`
 
 	// sdk
 	Name string `json:"name,omitempty"`
 
 	// claudeai-proxy
 	ID string `json:"id,omitempty"`
+
+	// Sampling lets the server request LLM completions from the host.
+	// Denied unless set and enabled.
+	Sampling *McpSamplingConfig `json:"sampling,omitempty"`
+}
+
+// McpAuthConfig configures authentication for sse/http MCP servers.
+type McpAuthConfig struct {
+	Type string `json:"type"` // "oauth"|"bearer"
+
+	// TokenEnv names an environment variable holding a pre-supplied bearer
+	// token. When set and non-empty it is used instead of the OAuth flow,
+	// for headless use; it is required for "bearer".
+	TokenEnv string `json:"tokenEnv,omitempty"`
+
+	// oauth
+	ClientID     string   `json:"clientId,omitempty"` // dynamically registered when empty
+	ClientSecret string   `json:"clientSecret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+	RedirectURI  string   `json:"redirectUri,omitempty"` // loopback callback, e.g. "http://127.0.0.1:8976/callback"; required for interactive authorization
+}
+
+// McpSamplingConfig controls sampling/createMessage requests from one MCP server.
+type McpSamplingConfig struct {
+	Enabled   bool     `json:"enabled"`
+	MaxTokens int      `json:"maxTokens,omitempty"` // total tokens the server may consume; 0 = unlimited
+	Models    []string `json:"models,omitempty"`    // models the server's hints may select; default model otherwise
 }
 
 // PluginConfig describes a plugin to load.
@@ -30,16 +30,54 @@ func (m HookProgressMessage) GetType() MessageType { return MessageTypeSystem }
 // HookResponseMessage is emitted when a hook completes.
 type HookResponseMessage struct {
 	BaseMessage
-	Type      MessageType   `json:"type"`
-	Subtype   SystemSubtype `json:"subtype"`
-	HookID    string        `json:"hook_id"`
-	HookName  string        `json:"hook_name"`
-	HookEvent string        `json:"hook_event"`
-	Output    string        `json:"output"`
-	Stdout    string        `json:"stdout"`
-	Stderr    string        `json:"stderr"`
-	ExitCode  *int          `json:"exit_code,omitempty"`
-	Outcome   string        `json:"outcome"`
+	Type       MessageType   `json:"type"`
+	Subtype    SystemSubtype `json:"subtype"`
+	HookID     string        `json:"hook_id"`
+	HookName   string        `json:"hook_name"`
+	HookEvent  string        `json:"hook_event"`
+	Output     string        `json:"output"`
+	Stdout     string        `json:"stdout"`
+	Stderr     string        `json:"stderr"`
+	ExitCode   *int          `json:"exit_code,omitempty"`
+	Outcome    string        `json:"outcome"`
+	DurationMs int64         `json:"duration_ms"`
 }
 
 func (m HookResponseMessage) GetType() MessageType { return MessageTypeSystem }
+
+// HookMetricsMessage summarizes hook timing and outcomes since the runner
+// was created, slowest hooks first.
+type HookMetricsMessage struct {
+	BaseMessage
+	Type    MessageType   `json:"type"`
+	Subtype SystemSubtype `json:"subtype"`
+	Hooks   []HookMetric  `json:"hooks"`
+}
+
+func (m HookMetricsMessage) GetType() MessageType { return MessageTypeSystem }
+
+// HookMetric aggregates the runs of one hook.
+type HookMetric struct {
+	HookEvent   string  `json:"hook_event"`
+	Matcher     string  `json:"matcher,omitempty"`      // tool name pattern of the hook's matcher
+	PathMatcher string  `json:"path_matcher,omitempty"` // file path pattern of the hook's matcher
+	HookName    string  `json:"hook_name"`              // command, webhook URL, or callback label
+	Callback    string  `json:"callback,omitempty"`     // Go function name for callback hooks
+	Calls       int     `json:"calls"`
+	Errors      int     `json:"errors"`
+	ErrorRate   float64 `json:"error_rate"`
+	TotalMs     int64   `json:"total_ms"`
+	MaxMs       int64   `json:"max_ms"`
+}
+
+// PromptBlockedMessage is emitted when a UserPromptSubmit hook denies a prompt.
+// The prompt is dropped and the loop waits for the next input.
+type PromptBlockedMessage struct {
+	BaseMessage
+	Type    MessageType   `json:"type"`
+	Subtype SystemSubtype `json:"subtype"`
+	Prompt  string        `json:"prompt"`
+	Reason  string        `json:"reason,omitempty"`
+}
+
+func (m PromptBlockedMessage) GetType() MessageType { return MessageTypeSystem }
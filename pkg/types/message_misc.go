@@ -8,6 +8,9 @@ type ToolProgressMessage struct {
 	ToolName           string      `json:"tool_name"`
 	ParentToolUseID    *string     `json:"parent_tool_use_id"`
 	ElapsedTimeSeconds float64     `json:"elapsed_time_seconds"`
+	TimedOut           bool        `json:"timed_out,omitempty"`
+	DuplicateOf        string      `json:"duplicate_of,omitempty"` // tool_use_id whose result this call reuses
+	Message            string      `json:"message,omitempty"`      // intermediate status reported by the tool
 }
 
 func (m ToolProgressMessage) GetType() MessageType { return MessageTypeToolProgress }
@@ -60,6 +63,86 @@ type FailedFile struct {
 	Error    string `json:"error"`
 }
 
+// UserQuestionMessage asks the host to answer an AskUserQuestion tool call.
+// The loop waits for an answer_question control request with the same
+// ToolUseID (see Query.AnswerQuestion).
+type UserQuestionMessage struct {
+	BaseMessage
+	Type      MessageType    `json:"type"`
+	Subtype   SystemSubtype  `json:"subtype"`
+	ToolUseID string         `json:"tool_use_id"`
+	Questions []UserQuestion `json:"questions"`
+}
+
+func (m UserQuestionMessage) GetType() MessageType { return MessageTypeSystem }
+
+// UserQuestion is one question of a UserQuestionMessage. Answers are keyed
+// by Question and hold the chosen option label(s) or free text.
+type UserQuestion struct {
+	Question    string               `json:"question"`
+	Header      string               `json:"header"`
+	Options     []UserQuestionOption `json:"options"`
+	MultiSelect bool                 `json:"multiSelect"`
+}
+
+// UserQuestionOption is a choice offered for a UserQuestion.
+type UserQuestionOption struct {
+	Label       string `json:"label"`
+	Description string `json:"description"`
+}
+
+// PermissionRequestMessage asks the host to approve a tool call that a
+// permission rule marked "ask". The loop waits for an answer_permission
+// control request with the same ToolUseID (see Query.AnswerPermission).
+type PermissionRequestMessage struct {
+	BaseMessage
+	Type      MessageType    `json:"type"`
+	Subtype   SystemSubtype  `json:"subtype"`
+	ToolUseID string         `json:"tool_use_id"`
+	ToolName  string         `json:"tool_name"`
+	Input     map[string]any `json:"input"`
+	Reason    string         `json:"reason,omitempty"`
+}
+
+func (m PermissionRequestMessage) GetType() MessageType { return MessageTypeSystem }
+
+// ToolCallRecord summarizes one tool call for the tool-call log: what ran,
+// what came back, and how permission checks and hooks treated it.
+type ToolCallRecord struct {
+	ToolUseID  string         `json:"tool_use_id"`
+	ToolName   string         `json:"tool_name"`
+	Input      map[string]any `json:"input"`            // as executed, after any rewrites
+	Output     string         `json:"output,omitempty"` // result content, truncated
+	IsError    bool           `json:"is_error,omitempty"`
+	DurationMs int64          `json:"duration_ms"`          // time spent in the tool itself (0 if it never ran)
+	FromCache  bool           `json:"from_cache,omitempty"` // output served from a cached copy (WebFetch, WebSearch)
+
+	// Permission is the checker's behavior ("allow", "deny"); empty if the
+	// call was rejected before the check.
+	Permission        string `json:"permission,omitempty"`
+	PermissionMessage string `json:"permission_message,omitempty"`
+
+	// Hook outcomes. PreToolUse is the hook decision ("allow", "deny",
+	// "ask"), empty when no hook decided.
+	PreToolUse       string `json:"pre_tool_use,omitempty"`
+	PreToolUseReason string `json:"pre_tool_use_reason,omitempty"`
+	InputUpdated     bool   `json:"input_updated,omitempty"`     // by the permission checker or a PreToolUse hook
+	OutputUpdated    bool   `json:"output_updated,omitempty"`    // by a PostToolUse hook
+	OutputSuppressed bool   `json:"output_suppressed,omitempty"` // by a PostToolUse hook
+
+	DuplicateOf string `json:"duplicate_of,omitempty"` // tool_use_id whose result this call reused
+}
+
+// ToolCallMessage streams a ToolCallRecord once the call has finished.
+type ToolCallMessage struct {
+	BaseMessage
+	Type    MessageType   `json:"type"`
+	Subtype SystemSubtype `json:"subtype"`
+	ToolCallRecord
+}
+
+func (m ToolCallMessage) GetType() MessageType { return MessageTypeSystem }
+
 // ToolUseSummaryMessage is injected during compaction to summarize tool use blocks.
 type ToolUseSummaryMessage struct {
 	BaseMessage
@@ -10,6 +10,7 @@ type ResultMessage struct {
 	IsError           bool                  `json:"is_error"`
 	NumTurns          int                   `json:"num_turns"`
 	StopReason        *string               `json:"stop_reason"`
+	StopSequence      *string               `json:"stop_sequence,omitempty"` // the matched sequence when StopReason is "stop_sequence"
 	TotalCostUSD      float64               `json:"total_cost_usd"`
 	Usage             BetaUsage             `json:"usage"`
 	ModelUsage        map[string]ModelUsage `json:"modelUsage"`
@@ -19,8 +20,56 @@ type ResultMessage struct {
 	Result           string `json:"result,omitempty"`
 	StructuredOutput any    `json:"structured_output,omitempty"`
 
-	// Error-only fields
-	Errors []string `json:"errors,omitempty"`
+	// Error-only fields. Errors is kept for older consumers; ErrorDetails
+	// carries the same failure classified for programmatic handling.
+	Errors       []string      `json:"errors,omitempty"`
+	ErrorDetails []ResultError `json:"error_details,omitempty"`
+}
+
+// ResultErrorCode classifies why a run ended in error.
+type ResultErrorCode string
+
+const (
+	ResultErrorRateLimit        ResultErrorCode = "rate_limit"
+	ResultErrorAuth             ResultErrorCode = "auth"
+	ResultErrorBilling          ResultErrorCode = "billing"
+	ResultErrorBudget           ResultErrorCode = "budget"
+	ResultErrorMaxTurns         ResultErrorCode = "max_turns"
+	ResultErrorMaxTokens        ResultErrorCode = "max_tokens"
+	ResultErrorToolError        ResultErrorCode = "tool_error"
+	ResultErrorContextOverflow  ResultErrorCode = "context_overflow"
+	ResultErrorInvalidRequest   ResultErrorCode = "invalid_request"
+	ResultErrorServerError      ResultErrorCode = "server_error"
+	ResultErrorStructuredOutput ResultErrorCode = "structured_output"
+	ResultErrorSessionRestore   ResultErrorCode = "session_restore"
+	ResultErrorAborted          ResultErrorCode = "aborted"
+	ResultErrorUnknown          ResultErrorCode = "unknown"
+)
+
+// ResultError is one classified failure of a run. Retriable means running
+// the same request again may succeed, e.g. once a rate limit resets.
+type ResultError struct {
+	Code      ResultErrorCode `json:"code"`
+	Message   string          `json:"message"`
+	Retriable bool            `json:"retriable,omitempty"`
+}
+
+// ResultErrorCodeFor maps an LLM-level AssistantError to its result code and
+// whether it is retriable.
+func ResultErrorCodeFor(err AssistantError) (ResultErrorCode, bool) {
+	switch err {
+	case ErrRateLimit:
+		return ResultErrorRateLimit, true
+	case ErrAuthenticationFailed:
+		return ResultErrorAuth, false
+	case ErrBillingError:
+		return ResultErrorBilling, false
+	case ErrInvalidRequest:
+		return ResultErrorInvalidRequest, false
+	case ErrServerError:
+		return ResultErrorServerError, true
+	}
+	return ResultErrorUnknown, false
 }
 
 func (m ResultMessage) GetType() MessageType { return MessageTypeResult }
@@ -33,6 +82,7 @@ type ModelUsage struct {
 	CacheCreationInputTokens int     `json:"cacheCreationInputTokens"`
 	WebSearchRequests        int     `json:"webSearchRequests"`
 	CostUSD                  float64 `json:"costUSD"`
+	BudgetUSD                float64 `json:"budgetUSD,omitempty"` // the model's ModelBudgets limit, if any
 	ContextWindow            int     `json:"contextWindow"`
 	MaxOutputTokens          int     `json:"maxOutputTokens"`
 }
@@ -11,6 +11,8 @@ type SystemInitMessage struct {
 	ClaudeCodeVersion string          `json:"claude_code_version"`
 	CWD               string          `json:"cwd"`
 	Tools             []string        `json:"tools"`
+	ToolDetails       []ToolInfo      `json:"tool_details,omitempty"`
+	DisabledTools     []string        `json:"disabled_tools,omitempty"`
 	McpServers        []McpServerInfo `json:"mcp_servers"`
 	Model             string          `json:"model"`
 	PermissionMode    PermissionMode  `json:"permissionMode"`
@@ -18,14 +20,27 @@ type SystemInitMessage struct {
 	OutputStyle       string          `json:"output_style"`
 	Skills            []string        `json:"skills"`
 	Plugins           []PluginInfo    `json:"plugins"`
+
+	// RestoredMessages is the number of history messages loaded when the
+	// session was resumed, continued or forked (0 for a fresh session).
+	RestoredMessages int `json:"restored_messages,omitempty"`
 }
 
 func (m SystemInitMessage) GetType() MessageType { return MessageTypeSystem }
 
+// ToolInfo describes a tool offered to the model.
+type ToolInfo struct {
+	Name       string `json:"name"`
+	SideEffect string `json:"side_effect"`          // none, read_only, mutating, network, blocking or spawns
+	McpServer  string `json:"mcp_server,omitempty"` // server an MCP tool comes from
+}
+
 // McpServerInfo describes the status of an MCP server connection.
 type McpServerInfo struct {
 	Name   string `json:"name"`
 	Status string `json:"status"`
+	Tools  int    `json:"tools"` // tools registered from the server
+	Error  string `json:"error,omitempty"`
 }
 
 // PluginInfo describes a loaded plugin.
@@ -49,10 +64,53 @@ const (
 // StatusMessage is emitted during status transitions.
 type StatusMessage struct {
 	BaseMessage
-	Type           MessageType     `json:"type"`
-	Subtype        SystemSubtype   `json:"subtype"`
-	Status         *string         `json:"status"`
-	PermissionMode *PermissionMode `json:"permissionMode,omitempty"`
+	Type           MessageType      `json:"type"`
+	Subtype        SystemSubtype    `json:"subtype"`
+	Status         *string          `json:"status"`
+	PermissionMode *PermissionMode  `json:"permissionMode,omitempty"`
+	Budget         *BudgetStatus    `json:"budget,omitempty"`
+	ModelChange    *ModelChange     `json:"modelChange,omitempty"`
+	RateLimit      *RateLimitStatus `json:"rateLimit,omitempty"`
+	TurnTimeout    *TurnTimeout     `json:"turnTimeout,omitempty"`
+}
+
+// StatusBudgetWarning is the Status value of a budget warning.
+const StatusBudgetWarning = "budget_warning"
+
+// StatusModelChanged is the Status value emitted when the loop switches models.
+const StatusModelChanged = "model_changed"
+
+// StatusRateLimited is the Status value emitted when a rate limiter delays an LLM call.
+const StatusRateLimited = "rate_limited"
+
+// StatusTurnTimeout is the Status value emitted when a turn exceeds TurnTimeout.
+const StatusTurnTimeout = "turn_timeout"
+
+// TurnTimeout reports a turn cut off by its wall-clock limit. Phase is "llm"
+// if the model was still responding, "tools" if tools were still running.
+type TurnTimeout struct {
+	TimeoutMs int64  `json:"timeout_ms"`
+	Phase     string `json:"phase"`
+}
+
+// RateLimitStatus reports how long a rate limiter is holding the next LLM call.
+type RateLimitStatus struct {
+	WaitMs int64 `json:"wait_ms"`
+}
+
+// ModelChange reports a mid-session switch of the model used for LLM calls.
+type ModelChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// BudgetStatus reports spend against MaxBudgetUSD when a warning threshold is crossed.
+type BudgetStatus struct {
+	Model        string  `json:"model,omitempty"` // set for a per-model budget (ModelBudgets)
+	Threshold    float64 `json:"threshold"`       // fraction of the budget that was crossed
+	SpentUSD     float64 `json:"spent_usd"`
+	LimitUSD     float64 `json:"limit_usd"`
+	RemainingUSD float64 `json:"remaining_usd"`
 }
 
 func (m StatusMessage) GetType() MessageType { return MessageTypeSystem }
@@ -69,6 +127,7 @@ func (m CompactBoundaryMessage) GetType() MessageType { return MessageTypeSystem
 
 // CompactMetadata describes a compaction event.
 type CompactMetadata struct {
-	Trigger   string `json:"trigger"`
-	PreTokens int    `json:"pre_tokens"`
+	Trigger    string `json:"trigger"` // "auto" | "manual" | "window"
+	PreTokens  int    `json:"pre_tokens"`
+	PostTokens int    `json:"post_tokens,omitempty"`
 }
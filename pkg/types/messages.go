@@ -26,8 +26,13 @@ const (
 	SystemSubtypeHookStarted      SystemSubtype = "hook_started"
 	SystemSubtypeHookProgress     SystemSubtype = "hook_progress"
 	SystemSubtypeHookResponse     SystemSubtype = "hook_response"
+	SystemSubtypeHookMetrics      SystemSubtype = "hook_metrics"
 	SystemSubtypeFilesPersisted   SystemSubtype = "files_persisted"
 	SystemSubtypeTaskNotification SystemSubtype = "task_notification"
+	SystemSubtypePromptBlocked    SystemSubtype = "prompt_blocked"
+	SystemSubtypeUserQuestion     SystemSubtype = "user_question"
+	SystemSubtypePermission       SystemSubtype = "permission_request"
+	SystemSubtypeToolCall         SystemSubtype = "tool_call"
 )
 
 // ResultSubtype disambiguates result message variants.
@@ -53,6 +58,7 @@ type SDKMessage interface {
 type BaseMessage struct {
 	UUID      uuid.UUID `json:"uuid"`
 	SessionID string    `json:"session_id"`
+	Redacted  []string  `json:"redacted,omitempty"` // fields secrets were scrubbed from
 }
 
 func (b BaseMessage) GetUUID() uuid.UUID  { return b.UUID }
@@ -71,12 +77,19 @@ const (
 )
 
 // AssistantMessage is a complete model response wrapping the accumulated BetaMessage.
+//
+// StopReason, StopSequence and FinishReason describe why this turn's response
+// ended, so per-turn consumers need not wait for the ResultMessage, which
+// remains the session-level summary.
 type AssistantMessage struct {
 	BaseMessage
 	Type            MessageType     `json:"type"`
 	Message         BetaMessage     `json:"message"`
 	ParentToolUseID *string         `json:"parent_tool_use_id"`
 	Error           *AssistantError `json:"error,omitempty"`
+	StopReason      string          `json:"stop_reason,omitempty"`   // "end_turn"|"tool_use"|"max_tokens"|"stop_sequence"
+	StopSequence    string          `json:"stop_sequence,omitempty"` // the matched sequence when StopReason is "stop_sequence"
+	FinishReason    string          `json:"finish_reason,omitempty"` // provider-native reason, e.g. OpenAI "length"
 }
 
 func (m AssistantMessage) GetType() MessageType { return MessageTypeAssistant }
@@ -90,3 +103,31 @@ type PartialAssistantMessage struct {
 }
 
 func (m PartialAssistantMessage) GetType() MessageType { return MessageTypeStreamEvent }
+
+// StreamEventMessageSnapshot is the event type of an assembled partial.
+const StreamEventMessageSnapshot = "message_snapshot"
+
+// MessageSnapshotEvent is the Event of a PartialAssistantMessage in assembled
+// mode: the assistant message received so far, with Index naming the content
+// block the latest chunk extended.
+type MessageSnapshotEvent struct {
+	Type    string                `json:"type"` // "message_snapshot"
+	Index   int                   `json:"index"`
+	ID      string                `json:"id,omitempty"`
+	Model   string                `json:"model,omitempty"`
+	Content []PartialContentBlock `json:"content"`
+}
+
+// PartialContentBlock is an in-progress content block. Blocks keep the index
+// they were first seen at for the rest of the stream.
+type PartialContentBlock struct {
+	Type     string `json:"type"` // "text" | "thinking" | "tool_use"
+	Text     string `json:"text,omitempty"`
+	Thinking string `json:"thinking,omitempty"`
+
+	// type="tool_use": PartialJSON is the input JSON received so far, which
+	// is a prefix of the final arguments and need not parse yet.
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+}
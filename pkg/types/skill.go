@@ -2,14 +2,24 @@ package types
 
 // SkillDefinition describes a reusable skill that can be invoked by the LLM.
 type SkillDefinition struct {
-	Name         string   `json:"name" yaml:"name"`
-	Description  string   `json:"description" yaml:"description"`
-	AllowedTools []string `json:"allowed-tools,omitempty" yaml:"allowed-tools"`
-	WhenToUse    string   `json:"when_to_use,omitempty" yaml:"when_to_use"`
-	ArgumentHint string   `json:"argument-hint,omitempty" yaml:"argument-hint"`
-	Arguments    []string `json:"arguments,omitempty" yaml:"arguments"`
-	Context      string   `json:"context,omitempty" yaml:"context"` // "inline" (default) or "fork"
-	Body         string   `json:"body,omitempty" yaml:"-"`          // markdown body after frontmatter
+	Name         string          `json:"name" yaml:"name"`
+	Description  string          `json:"description" yaml:"description"`
+	AllowedTools []string        `json:"allowed-tools,omitempty" yaml:"allowed-tools"`
+	WhenToUse    string          `json:"when_to_use,omitempty" yaml:"when_to_use"`
+	ArgumentHint string          `json:"argument-hint,omitempty" yaml:"argument-hint"`
+	Arguments    []string        `json:"arguments,omitempty" yaml:"arguments"`
+	Args         []SkillArgument `json:"args,omitempty" yaml:"args"`       // typed schema; its names also fill Arguments
+	Context      string          `json:"context,omitempty" yaml:"context"` // "inline" (default) or "fork"
+	Body         string          `json:"body,omitempty" yaml:"-"`          // markdown body after frontmatter
+}
+
+// SkillArgument declares one positional skill argument in the frontmatter
+// args: schema.
+type SkillArgument struct {
+	Name     string `json:"name" yaml:"name"`
+	Type     string `json:"type,omitempty" yaml:"type"` // "string" (default), "number", "integer" or "boolean"
+	Required bool   `json:"required,omitempty" yaml:"required"`
+	Default  string `json:"default,omitempty" yaml:"default"`
 }
 
 // SkillSource identifies where a skill definition was loaded from.
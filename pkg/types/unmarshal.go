@@ -43,8 +43,7 @@ func UnmarshalSDKMessage(data []byte) (SDKMessage, error) {
 		return unmarshalSystemMessage(data, raw.Subtype)
 
 	case MessageTypeStreamEvent:
-		var msg PartialAssistantMessage
-		return &msg, json.Unmarshal(data, &msg)
+		return unmarshalStreamEvent(data)
 
 	case MessageTypeToolProgress:
 		var msg ToolProgressMessage
@@ -86,13 +85,47 @@ func unmarshalSystemMessage(data []byte, subtype *SystemSubtype) (SDKMessage, er
 	case SystemSubtypeHookResponse:
 		var msg HookResponseMessage
 		return &msg, json.Unmarshal(data, &msg)
+	case SystemSubtypeHookMetrics:
+		var msg HookMetricsMessage
+		return &msg, json.Unmarshal(data, &msg)
 	case SystemSubtypeFilesPersisted:
 		var msg FilesPersistedEvent
 		return &msg, json.Unmarshal(data, &msg)
 	case SystemSubtypeTaskNotification:
 		var msg TaskNotificationMessage
 		return &msg, json.Unmarshal(data, &msg)
+	case SystemSubtypePromptBlocked:
+		var msg PromptBlockedMessage
+		return &msg, json.Unmarshal(data, &msg)
+	case SystemSubtypeUserQuestion:
+		var msg UserQuestionMessage
+		return &msg, json.Unmarshal(data, &msg)
+	case SystemSubtypePermission:
+		var msg PermissionRequestMessage
+		return &msg, json.Unmarshal(data, &msg)
+	case SystemSubtypeToolCall:
+		var msg ToolCallMessage
+		return &msg, json.Unmarshal(data, &msg)
 	default:
 		return nil, fmt.Errorf("unknown system subtype: %s", *subtype)
 	}
 }
+
+// unmarshalStreamEvent decodes a stream_event. Assembled snapshots are decoded
+// into a *MessageSnapshotEvent; raw chunk events stay a map[string]any.
+func unmarshalStreamEvent(data []byte) (SDKMessage, error) {
+	var msg PartialAssistantMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, err
+	}
+	if event, ok := msg.Event.(map[string]any); ok && event["type"] == StreamEventMessageSnapshot {
+		var wrapper struct {
+			Event MessageSnapshotEvent `json:"event"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, err
+		}
+		msg.Event = &wrapper.Event
+	}
+	return &msg, nil
+}
@@ -26,6 +26,9 @@ func TestUnmarshalSDKMessage_AssistantMessage(t *testing.T) {
 			Content: []ContentBlock{{Type: "text", Text: "hello"}},
 			Usage:   BetaUsage{InputTokens: 10, OutputTokens: 5},
 		},
+		StopReason:   "stop_sequence",
+		StopSequence: "</final>",
+		FinishReason: "stop",
 	}
 	data := mustMarshal(t, orig)
 
@@ -46,6 +49,9 @@ func TestUnmarshalSDKMessage_AssistantMessage(t *testing.T) {
 	if am.UUID != orig.UUID {
 		t.Errorf("UUID mismatch")
 	}
+	if am.StopReason != "stop_sequence" || am.StopSequence != "</final>" || am.FinishReason != "stop" {
+		t.Errorf("stop details = %q, %q, %q", am.StopReason, am.StopSequence, am.FinishReason)
+	}
 }
 
 func TestUnmarshalSDKMessage_UserMessage(t *testing.T) {
@@ -273,6 +279,16 @@ func TestUnmarshalSDKMessage_SystemSubtypes(t *testing.T) {
 			},
 			subtype: SystemSubtypeHookResponse,
 		},
+		{
+			name: "hook_metrics",
+			msg: &HookMetricsMessage{
+				BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: "s1"},
+				Type:        MessageTypeSystem,
+				Subtype:     SystemSubtypeHookMetrics,
+				Hooks:       []HookMetric{{HookEvent: "PreToolUse", HookName: "fmt.sh", Calls: 2, TotalMs: 40}},
+			},
+			subtype: SystemSubtypeHookMetrics,
+		},
 		{
 			name: "files_persisted",
 			msg: &FilesPersistedEvent{
@@ -294,6 +310,32 @@ func TestUnmarshalSDKMessage_SystemSubtypes(t *testing.T) {
 			},
 			subtype: SystemSubtypeTaskNotification,
 		},
+		{
+			name: "prompt_blocked",
+			msg: &PromptBlockedMessage{
+				BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: "s1"},
+				Type:        MessageTypeSystem,
+				Subtype:     SystemSubtypePromptBlocked,
+				Prompt:      "deploy with key sk-123",
+				Reason:      "contains a secret",
+			},
+			subtype: SystemSubtypePromptBlocked,
+		},
+		{
+			name: "user_question",
+			msg: &UserQuestionMessage{
+				BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: "s1"},
+				Type:        MessageTypeSystem,
+				Subtype:     SystemSubtypeUserQuestion,
+				ToolUseID:   "tu-1",
+				Questions: []UserQuestion{{
+					Question: "Which auth method?",
+					Header:   "Auth",
+					Options:  []UserQuestionOption{{Label: "JWT"}, {Label: "OAuth"}},
+				}},
+			},
+			subtype: SystemSubtypeUserQuestion,
+		},
 	}
 
 	for _, tt := range tests {
@@ -311,6 +353,34 @@ func TestUnmarshalSDKMessage_SystemSubtypes(t *testing.T) {
 	}
 }
 
+func TestUnmarshalSDKMessage_ToolCallMessage(t *testing.T) {
+	orig := NewToolCallMessage(ToolCallRecord{
+		ToolUseID:  "tu-1",
+		ToolName:   "Bash",
+		Input:      map[string]any{"command": "ls"},
+		Output:     "go.mod",
+		DurationMs: 12,
+		Permission: "allow",
+		PreToolUse: "allow",
+	}, "s1")
+	data := mustMarshal(t, orig)
+	if !strings.Contains(string(data), `"tool_name":"Bash"`) {
+		t.Errorf("record fields not inlined: %s", data)
+	}
+
+	msg, err := UnmarshalSDKMessage(data)
+	if err != nil {
+		t.Fatalf("UnmarshalSDKMessage: %v", err)
+	}
+	tc, ok := msg.(*ToolCallMessage)
+	if !ok {
+		t.Fatalf("expected *ToolCallMessage, got %T", msg)
+	}
+	if tc.ToolName != "Bash" || tc.Input["command"] != "ls" || tc.DurationMs != 12 || tc.Permission != "allow" || tc.PreToolUse != "allow" {
+		t.Errorf("record = %+v", tc.ToolCallRecord)
+	}
+}
+
 func TestUnmarshalSDKMessage_UnknownType(t *testing.T) {
 	data := []byte(`{"type":"unknown_type"}`)
 	_, err := UnmarshalSDKMessage(data)
@@ -429,7 +499,54 @@ func TestContentBlock_UnmarshalJSON_Thinking(t *testing.T) {
 // --- Stub Tests for Unimplemented Features ---
 
 func TestUnmarshalSDKMessage_PartialMessageStreaming(t *testing.T) {
-	t.Skip("not yet implemented: partial message streaming (content_block_delta)")
+	t.Run("content_block_delta", func(t *testing.T) {
+		orig := PartialAssistantMessage{
+			BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: "s1"},
+			Type:        MessageTypeStreamEvent,
+			Event: map[string]any{
+				"type":  "content_block_delta",
+				"delta": map[string]any{"type": "text_delta", "text": "Hel"},
+			},
+		}
+		msg, err := UnmarshalSDKMessage(mustMarshal(t, orig))
+		if err != nil {
+			t.Fatalf("UnmarshalSDKMessage: %v", err)
+		}
+		pm := msg.(*PartialAssistantMessage)
+		event, ok := pm.Event.(map[string]any)
+		if !ok {
+			t.Fatalf("Event = %T, want map", pm.Event)
+		}
+		if delta, _ := event["delta"].(map[string]any); delta["text"] != "Hel" {
+			t.Errorf("delta = %v", event["delta"])
+		}
+	})
+
+	t.Run("message_snapshot", func(t *testing.T) {
+		orig := PartialAssistantMessage{
+			BaseMessage: BaseMessage{UUID: uuid.New(), SessionID: "s1"},
+			Type:        MessageTypeStreamEvent,
+			Event: &MessageSnapshotEvent{
+				Type:  StreamEventMessageSnapshot,
+				Index: 1,
+				Content: []PartialContentBlock{
+					{Type: "text", Text: "Reading"},
+					{Type: "tool_use", ID: "call_1", Name: "Read", PartialJSON: `{"file_pa`},
+				},
+			},
+		}
+		msg, err := UnmarshalSDKMessage(mustMarshal(t, orig))
+		if err != nil {
+			t.Fatalf("UnmarshalSDKMessage: %v", err)
+		}
+		snap, ok := msg.(*PartialAssistantMessage).Event.(*MessageSnapshotEvent)
+		if !ok {
+			t.Fatalf("Event = %T, want *MessageSnapshotEvent", msg.(*PartialAssistantMessage).Event)
+		}
+		if snap.Index != 1 || len(snap.Content) != 2 || snap.Content[1].PartialJSON != `{"file_pa` {
+			t.Errorf("snapshot = %+v", snap)
+		}
+	})
 }
 
 // --- Test Parity: Message Parser (ported from Python Agent SDK) ---
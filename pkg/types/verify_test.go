@@ -19,4 +19,5 @@ var (
 	_ SDKMessage = (*TaskNotificationMessage)(nil)
 	_ SDKMessage = (*FilesPersistedEvent)(nil)
 	_ SDKMessage = (*ToolUseSummaryMessage)(nil)
+	_ SDKMessage = (*ToolCallMessage)(nil)
 )